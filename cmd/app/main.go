@@ -5,7 +5,8 @@ import (
 	_ "github.com/lemmego/api/logger"
 	_ "github.com/lemmego/api/providers"
 	//_ "github.com/lemmego/auth"
-	"github.com/lemmego/lemmego/internal/commands"
+	_ "github.com/lemmego/lemmego"
+	"github.com/lemmego/lemmego/framework"
 	"github.com/lemmego/lemmego/internal/configs"
 	_ "github.com/lemmego/lemmego/internal/migrations"
 	"github.com/lemmego/lemmego/internal/routes"
@@ -13,9 +14,8 @@ import (
 
 func main() {
 	// Configure application
-	webApp := app.Configure(
+	webApp := framework.Configure(
 		app.WithConfig(configs.Load()),
-		app.WithCommands(commands.Load()),
 		app.WithRoutes(routes.Load()),
 	)
 
@@ -0,0 +1,82 @@
+// Command create-lemmego creates a new application:
+//
+//	go run github.com/lemmego/lemmego/cmd/create-lemmego@latest shop --module github.com/acme/shop --preset vue
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lemmego/lemmego/internal/scaffold"
+)
+
+func main() {
+	var (
+		module  string
+		preset  string
+		from    string
+		version string
+	)
+
+	cmd := &cobra.Command{
+		Use:          "create-lemmego <dir>",
+		Short:        "Create a new Lemmego application",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			source, framework := from, ""
+			if source == "" {
+				if version == "" {
+					version = ownVersion()
+				}
+				fmt.Printf("Downloading %s@%s\n", scaffold.Module, version)
+				dir, err := scaffold.Download(version)
+				if err != nil {
+					return err
+				}
+				source = dir
+				// The framework is tagged along with the module
+				if version != "latest" {
+					framework = version
+				}
+			}
+			err := scaffold.New(&scaffold.Options{Dir: args[0], Module: module, Preset: preset, Source: source, Version: framework})
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Created %s\n\nNext:\n\n", filepath.Base(args[0]))
+			fmt.Printf("  cd %s\n", args[0])
+			fmt.Println("  go mod tidy")
+			fmt.Println("  npm install")
+			if preset == scaffold.HTMX {
+				fmt.Println("  make watch")
+			} else {
+				fmt.Println("  npm run dev & make run")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&module, "module", "", "module path of the application, the name of the directory when empty")
+	cmd.Flags().StringVar(&preset, "preset", scaffold.React, "front end: react or vue with Inertia, or htmx with Go templates")
+	cmd.Flags().StringVar(&from, "from", "", "copy a lemmego checkout instead of downloading the module")
+	cmd.Flags().StringVar(&version, "version", "", "version of lemmego to download, that of this command when empty")
+
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// ownVersion is the version this command was installed at, so
+// go run ...@v0.2.0 creates applications of v0.2.0.
+func ownVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "latest"
+}
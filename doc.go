@@ -0,0 +1,10 @@
+// Package lemmego holds the files of the application read at runtime,
+// embedded in the binary when built with the embed tag:
+//
+//	npm run build
+//	go build -tags embed -o app ./cmd/app
+//
+// The binary then runs from any directory with its .env alone. Templates,
+// public assets and translations on disk still override the embedded ones,
+// and migrations are compiled in as the rest of the code.
+package lemmego
@@ -0,0 +1,25 @@
+//go:build embed
+
+package lemmego
+
+import (
+	"embed"
+	"fmt"
+
+	"github.com/lemmego/lemmego/framework/assets"
+)
+
+//go:embed templates static all:public resources/views all:resources/lang
+var files embed.FS
+
+// The files github.com/lemmego/api reads from disk are extracted before
+// they are: its res package loads ./templates from its init. Packages are
+// initialized in the order of their import paths once their imports are,
+// and this one only imports the standard library and assets, so it runs
+// before github.com/romsar/gonertia, imported by res.
+func init() {
+	assets.Embed(files)
+	if err := assets.Extract("templates", "static", "resources/views/root.html", "public/build/manifest.json"); err != nil {
+		panic(fmt.Sprintf("lemmego: extracting embedded files: %v", err))
+	}
+}
@@ -0,0 +1,193 @@
+// Package admin scaffolds CRUD screens for models from their metadata: the
+// gorm schema gives the columns, admin tags the listing's search, sort and
+// filter fields, and validate tags both the form inputs and the checks run
+// on submission.
+//
+//	type Post struct {
+//		ID        uint
+//		Title     string `admin:"search,sort" validate:"required,max=120"`
+//		Status    string `admin:"filter" validate:"required,in=draft|published"`
+//		Body      string `admin:"textarea,nolist" validate:"required"`
+//		CreatedAt time.Time `admin:"sort"`
+//	}
+//
+//	panel := admin.New("/admin", admin.PolicyFunc(func(c *app.Context, action admin.Action, model any) bool {
+//		u, ok := c.AuthUser().(*User)
+//		return ok && u.IsAdmin
+//	}))
+//	admin.Register[Post](panel, admin.Resource{})
+//	panel.Mount(r)
+//
+// Screens are templ pages, or with Inertia set the Inertia pages
+// Admin/Dashboard, Admin/Index and Admin/Form, for apps bringing their own
+// frontend.
+package admin
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/form"
+	"github.com/lemmego/lemmego/framework/validation"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Action is what a user is trying to do, as asked of a Policy.
+type Action string
+
+const (
+	ViewAny Action = "viewAny"
+	Create  Action = "create"
+	Update  Action = "update"
+	Delete  Action = "delete"
+)
+
+// Policy decides what the current user may do. model is nil for ViewAny and
+// Create, and the row acted upon for Update and Delete.
+type Policy interface {
+	Allow(c *app.Context, action Action, model any) bool
+}
+
+type PolicyFunc func(c *app.Context, action Action, model any) bool
+
+func (f PolicyFunc) Allow(c *app.Context, action Action, model any) bool {
+	return f(c, action, model)
+}
+
+var errForbidden = errors.New("This action is unauthorized")
+
+// Resource configures the screens of a model. Every field is optional.
+type Resource struct {
+	// Name is the URL segment, the model's table by default.
+	Name string
+	// Label titles the screens, Name split into words by default.
+	Label   string
+	PerPage int
+	// Policy replaces the panel's policy for this resource.
+	Policy Policy
+	// Query narrows the rows the resource lists and edits.
+	Query func(ctx context.Context, tx *gorm.DB) *gorm.DB
+}
+
+// Panel is a set of resources mounted under a prefix.
+type Panel struct {
+	Prefix string
+	Title  string
+	// Policy guards every resource without one of its own. A panel without
+	// a policy denies everything.
+	Policy Policy
+	// Inertia renders Inertia pages instead of the built-in templ screens.
+	Inertia bool
+
+	resources []resource
+}
+
+func New(prefix string, policy Policy) *Panel {
+	return &Panel{Prefix: "/" + strings.Trim(prefix, "/"), Title: "Admin", Policy: policy}
+}
+
+// resource is the type-erased view of a registered model.
+type resource interface {
+	meta() *meta
+	index(c *app.Context) error
+	create(c *app.Context) error
+	store(c *app.Context) error
+	edit(c *app.Context) error
+	update(c *app.Context) error
+	destroy(c *app.Context) error
+}
+
+type meta struct {
+	Resource
+	panel  *Panel
+	fields []*Field
+	pk     *schema.Field
+}
+
+func (m *meta) allow(c *app.Context, action Action, model any) bool {
+	policy := cmp.Or(m.Policy, m.panel.Policy)
+	return policy != nil && policy.Allow(c, action, model)
+}
+
+func (m *meta) url(parts ...any) string {
+	u := m.panel.Prefix + "/" + m.Name
+	for _, p := range parts {
+		u += "/" + fmt.Sprint(p)
+	}
+	return u
+}
+
+// Register adds the screens of T to a panel.
+func Register[T any](p *Panel, r Resource) {
+	s, err := schema.Parse(new(T), &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		panic(fmt.Sprintf("admin: %v", err))
+	}
+	if s.PrioritizedPrimaryField == nil {
+		panic(fmt.Sprintf("admin: %s has no primary key", s.Name))
+	}
+	fs, err := fields(s)
+	if err != nil {
+		panic(err)
+	}
+	if err := validation.Compile(new(T)); err != nil {
+		panic(err)
+	}
+
+	r.Name = cmp.Or(r.Name, s.Table)
+	r.Label = cmp.Or(r.Label, form.Humanize(s.Name))
+	r.PerPage = cmp.Or(r.PerPage, 20)
+	p.resources = append(p.resources, &resourceOf[T]{m: &meta{Resource: r, panel: p, fields: fs, pk: s.PrioritizedPrimaryField}})
+}
+
+// Mount registers the panel's routes, none when it has no resources.
+func (p *Panel) Mount(r app.Router) {
+	if len(p.resources) == 0 {
+		return
+	}
+
+	r.Get(p.Prefix, p.dashboard)
+	for _, res := range p.resources {
+		base := res.meta().url()
+		r.Get(base, res.index)
+		r.Get(base+"/create", res.create)
+		r.Post(base, res.store)
+		r.Get(base+"/{id}/edit", res.edit)
+		r.Put(base+"/{id}", res.update)
+		r.Delete(base+"/{id}", res.destroy)
+	}
+}
+
+// dashboard lists the resources the user may view.
+func (p *Panel) dashboard(c *app.Context) error {
+	links := p.nav(c)
+	if len(links) == 0 {
+		return c.Forbidden(errForbidden)
+	}
+	if p.Inertia {
+		return c.Inertia("Admin/Dashboard", map[string]any{"title": p.Title, "resources": links})
+	}
+	return c.Templ(layout(p.Title, p.Title, links, dashboardPage(links)))
+}
+
+type link struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// nav lists the resources the user may view, for the layout.
+func (p *Panel) nav(c *app.Context) []link {
+	var links []link
+	for _, res := range p.resources {
+		if m := res.meta(); m.allow(c, ViewAny, nil) {
+			links = append(links, link{Label: m.Label, URL: m.url()})
+		}
+	}
+	return links
+}
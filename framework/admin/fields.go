@@ -0,0 +1,105 @@
+package admin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lemmego/lemmego/framework/form"
+	"gorm.io/gorm/schema"
+)
+
+// Field is a column of a resource: its form input, read from the model's
+// form and validate tags, and its place in the listing.
+type Field struct {
+	*form.Field
+	Column string `json:"column"`
+
+	List   bool `json:"list"`
+	Form   bool `json:"form"`
+	Search bool `json:"search"`
+	Sort   bool `json:"sort"`
+	Filter bool `json:"filter"`
+}
+
+// fields reads the fields of a model. Inputs come from the form and validate
+// tags as in package form. A field tagged admin:"-" is left out of the panel,
+// others may take a comma separated list of:
+//
+//	label=Text  the label, the field name split into words by default
+//	textarea    edits the field in a textarea
+//	readonly    lists the field but leaves it out of forms
+//	nolist      leaves the field out of the listing
+//	search      matches the field against the search box
+//	sort        lets the listing be sorted by the field
+//	filter      adds a filter on the field's value
+//
+// Primary keys and automatic timestamps are read only.
+func fields(s *schema.Schema) ([]*Field, error) {
+	var out []*Field
+	for _, sf := range s.Fields {
+		if sf.DBName == "" || sf.StructField.Anonymous {
+			continue
+		}
+		tag, ok := sf.StructField.Tag.Lookup("admin")
+		if tag == "-" {
+			continue
+		}
+
+		ff, err := form.NewField(sf.StructField)
+		if err != nil {
+			return nil, fmt.Errorf("admin: %s: %w", s.Name, err)
+		}
+		f := &Field{
+			Field:  ff,
+			Column: sf.DBName,
+			List:   true,
+			Form:   !sf.PrimaryKey && sf.AutoCreateTime == 0 && sf.AutoUpdateTime == 0 && sf.Name != "DeletedAt",
+			Sort:   sf.PrimaryKey,
+		}
+
+		if ok {
+			for _, opt := range strings.Split(tag, ",") {
+				name, arg, _ := strings.Cut(strings.TrimSpace(opt), "=")
+				switch name {
+				case "":
+				case "label":
+					f.Label = arg
+				case "textarea":
+					f.Input = form.Textarea
+				case "readonly":
+					f.Form = false
+				case "nolist":
+					f.List = false
+				case "search":
+					f.Search = true
+				case "sort":
+					f.Sort = true
+				case "filter":
+					f.Filter = true
+				default:
+					return nil, fmt.Errorf("admin: %s.%s: unknown option %q", s.Name, sf.Name, name)
+				}
+			}
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+// display returns the field of model as listed.
+func (f *Field) display(model any) string {
+	if f.Input == form.Checkbox {
+		if f.Value(model) != "" {
+			return "Yes"
+		}
+		return "No"
+	}
+	s := f.Value(model)
+	if f.Input == form.DateTime {
+		s = strings.Replace(s, "T", " ", 1)
+	}
+	if r := []rune(s); len(r) > 80 {
+		s = string(r[:79]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,378 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/shared"
+	"github.com/lemmego/lemmego/framework/form"
+	"github.com/lemmego/lemmego/framework/repo"
+	"github.com/lemmego/lemmego/framework/validation"
+)
+
+type resourceOf[T any] struct {
+	m *meta
+}
+
+func (r *resourceOf[T]) meta() *meta {
+	return r.m
+}
+
+func (r *resourceOf[T]) repo(c *app.Context) *repo.Repo[T] {
+	if r.m.Query != nil {
+		ctx := c.RequestContext()
+		return repo.From[T](r.m.Query(ctx, repo.DB(ctx)))
+	}
+	return repo.New[T](c.RequestContext())
+}
+
+// row is a line of the listing.
+type row struct {
+	ID     string   `json:"id"`
+	Cells  []string `json:"cells"`
+	Edit   bool     `json:"edit"`
+	Delete bool     `json:"delete"`
+}
+
+type option struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+// filter is a select narrowing the listing to a value of a field.
+type filter struct {
+	Field   *Field   `json:"field"`
+	Options []option `json:"options"`
+	Value   string   `json:"value"`
+}
+
+// listing is the state of the index screen.
+type listing struct {
+	Query   string    `json:"query"`
+	Sort    string    `json:"sort"`
+	Desc    bool      `json:"desc"`
+	Page    int       `json:"page"`
+	Pages   int       `json:"pages"`
+	Total   int64     `json:"total"`
+	Filters []*filter `json:"filters"`
+	Search  bool      `json:"search"`
+}
+
+// url returns the listing's URL with the given parameters changed.
+func (l *listing) url(base string, set ...string) string {
+	q := url.Values{}
+	if l.Query != "" {
+		q.Set("q", l.Query)
+	}
+	if l.Sort != "" {
+		q.Set("sort", l.Sort)
+		if l.Desc {
+			q.Set("dir", "desc")
+		}
+	}
+	for _, f := range l.Filters {
+		if f.Value != "" {
+			q.Set("filter["+f.Field.Name+"]", f.Value)
+		}
+	}
+	if l.Page > 1 {
+		q.Set("page", strconv.Itoa(l.Page))
+	}
+	for i := 0; i+1 < len(set); i += 2 {
+		if set[i+1] == "" {
+			q.Del(set[i])
+		} else {
+			q.Set(set[i], set[i+1])
+		}
+	}
+	if len(q) == 0 {
+		return base
+	}
+	return base + "?" + q.Encode()
+}
+
+// index lists the rows, searched, filtered, sorted and paginated by the
+// q, filter[name], sort, dir and page query parameters.
+func (r *resourceOf[T]) index(c *app.Context) error {
+	if !r.m.allow(c, ViewAny, nil) {
+		return c.Forbidden(errForbidden)
+	}
+
+	l := &listing{Query: strings.TrimSpace(c.Query("q")), Page: 1}
+	l.Page, _ = strconv.Atoi(c.Query("page"))
+	l.Page = max(l.Page, 1)
+
+	rp := r.repo(c)
+	quote := rp.Query().Statement.Quote
+	var columns []*Field
+	var search []string
+	var args []any
+	for _, f := range r.m.fields {
+		if f.List {
+			columns = append(columns, f)
+		}
+		if f.Search {
+			l.Search = true
+			search = append(search, "LOWER("+quote(f.Column)+") LIKE ?")
+			args = append(args, "%"+strings.ToLower(l.Query)+"%")
+		}
+		if f.Sort && f.Name == c.Query("sort") {
+			l.Sort, l.Desc = f.Name, c.Query("dir") == "desc"
+		}
+		if f.Filter {
+			flt, err := r.filter(c, f)
+			if err != nil {
+				return err
+			}
+			if flt.Value != "" {
+				v, err := f.Parse(flt.Value)
+				if err != nil {
+					return c.BadRequest(fmt.Errorf("Invalid filter on %s", f.Label))
+				}
+				rp = rp.Where(quote(f.Column)+" = ?", v)
+			}
+			l.Filters = append(l.Filters, flt)
+		}
+	}
+	if l.Query != "" && len(search) > 0 {
+		rp = rp.Where("("+strings.Join(search, " OR ")+")", args...)
+	}
+
+	var err error
+	if l.Total, err = rp.Count(); err != nil {
+		return err
+	}
+	l.Pages = max(int(math.Ceil(float64(l.Total)/float64(r.m.PerPage))), 1)
+	l.Page = min(l.Page, l.Pages)
+
+	order := r.m.pk.DBName + " DESC"
+	if l.Sort != "" {
+		for _, f := range r.m.fields {
+			if f.Name == l.Sort {
+				order = f.Column
+			}
+		}
+		if l.Desc {
+			order += " DESC"
+		}
+	}
+	models, err := rp.Order(order).Offset((l.Page - 1) * r.m.PerPage).Limit(r.m.PerPage).Find()
+	if err != nil {
+		return err
+	}
+
+	rows := make([]row, len(models))
+	for i := range models {
+		rows[i] = row{
+			ID:     fmt.Sprint(reflect.ValueOf(&models[i]).Elem().FieldByIndex(r.m.pk.StructField.Index).Interface()),
+			Edit:   r.m.allow(c, Update, &models[i]),
+			Delete: r.m.allow(c, Delete, &models[i]),
+		}
+		for _, f := range columns {
+			rows[i].Cells = append(rows[i].Cells, f.display(&models[i]))
+		}
+	}
+
+	flash := c.PopSessionString("success")
+	canCreate := r.m.allow(c, Create, nil)
+	if r.m.panel.Inertia {
+		return c.Inertia("Admin/Index", map[string]any{
+			"title":     r.m.Label,
+			"url":       r.m.url(),
+			"columns":   columns,
+			"rows":      rows,
+			"listing":   l,
+			"canCreate": canCreate,
+			"flash":     flash,
+		})
+	}
+	return c.Templ(layout(r.m.panel.Title, r.m.Label, r.m.panel.nav(c), indexPage(r.m, columns, rows, l, canCreate, flash)))
+}
+
+// filter returns the options of a filter field: the values of an in rule,
+// yes and no, or the distinct values of the column.
+func (r *resourceOf[T]) filter(c *app.Context, f *Field) (*filter, error) {
+	flt := &filter{Field: f, Value: c.Query("filter[" + f.Name + "]")}
+	switch {
+	case f.Options != nil:
+		for _, o := range f.Options {
+			flt.Options = append(flt.Options, option{Value: o, Label: o})
+		}
+	case f.Input == form.Checkbox:
+		flt.Options = []option{{Value: "1", Label: "Yes"}, {Value: "0", Label: "No"}}
+	default:
+		var values []string
+		q := r.repo(c).Query()
+		if err := q.Distinct(f.Column).Order(f.Column).Limit(100).Pluck(f.Column, &values).Error; err != nil {
+			return nil, err
+		}
+		for _, v := range values {
+			flt.Options = append(flt.Options, option{Value: v, Label: v})
+		}
+	}
+	return flt, nil
+}
+
+// find loads the row named by the id route parameter.
+func (r *resourceOf[T]) find(c *app.Context) (*T, error) {
+	m, err := r.repo(c).Where(r.m.pk.DBName+" = ?", c.Param("id")).Limit(1).Find()
+	if err != nil {
+		return nil, err
+	}
+	if len(m) == 0 {
+		return nil, c.NotFound(fmt.Errorf("%s not found", r.m.Label))
+	}
+	return &m[0], nil
+}
+
+func (r *resourceOf[T]) create(c *app.Context) error {
+	if !r.m.allow(c, Create, nil) {
+		return c.Forbidden(errForbidden)
+	}
+	return r.form(c, new(T), "", nil, nil)
+}
+
+func (r *resourceOf[T]) store(c *app.Context) error {
+	if !r.m.allow(c, Create, nil) {
+		return c.Forbidden(errForbidden)
+	}
+	model := new(T)
+	if err := r.bind(c, model); err != nil {
+		return r.invalid(c, model, "", err)
+	}
+	if err := r.repo(c).Create(model); err != nil {
+		return err
+	}
+	return r.done(c, r.m.Label+" created")
+}
+
+func (r *resourceOf[T]) edit(c *app.Context) error {
+	model, err := r.find(c)
+	if model == nil {
+		return err
+	}
+	if !r.m.allow(c, Update, model) {
+		return c.Forbidden(errForbidden)
+	}
+	return r.form(c, model, c.Param("id"), nil, nil)
+}
+
+func (r *resourceOf[T]) update(c *app.Context) error {
+	model, err := r.find(c)
+	if model == nil {
+		return err
+	}
+	if !r.m.allow(c, Update, model) {
+		return c.Forbidden(errForbidden)
+	}
+	if err := r.bind(c, model); err != nil {
+		return r.invalid(c, model, c.Param("id"), err)
+	}
+	if err := r.repo(c).Save(model); err != nil {
+		return err
+	}
+	return r.done(c, r.m.Label+" updated")
+}
+
+func (r *resourceOf[T]) destroy(c *app.Context) error {
+	model, err := r.find(c)
+	if model == nil {
+		return err
+	}
+	if !r.m.allow(c, Delete, model) {
+		return c.Forbidden(errForbidden)
+	}
+	if _, err := r.repo(c).Delete(r.m.pk.DBName+" = ?", c.Param("id")); err != nil {
+		return err
+	}
+	return r.done(c, r.m.Label+" deleted")
+}
+
+// done flashes message and goes back to the listing.
+func (r *resourceOf[T]) done(c *app.Context, message string) error {
+	c.WithSuccess(message)
+	return c.Redirect(r.m.url())
+}
+
+// bind sets the form fields of model from the submission and validates it.
+// Invalid input is reported as shared.ValidationErrors.
+func (r *resourceOf[T]) bind(c *app.Context, model *T) error {
+	if err := c.Request().ParseForm(); err != nil {
+		return c.BadRequest(err)
+	}
+
+	errs := shared.ValidationErrors{}
+	for _, f := range r.m.fields {
+		if !f.Form {
+			continue
+		}
+		if err := f.Set(model, c.Request().PostForm.Get(f.Name)); err != nil {
+			errs[f.Name] = append(errs[f.Name], err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return validation.Struct(app.NewValidator(c.App()), model)
+}
+
+// invalid shows the form again with the errors of a submission, or returns
+// the error when it is not about the input.
+func (r *resourceOf[T]) invalid(c *app.Context, model *T, id string, err error) error {
+	var errs shared.ValidationErrors
+	if !errors.As(err, &errs) {
+		return err
+	}
+	if r.m.panel.Inertia {
+		return c.ValidationError(errs)
+	}
+	return r.form(c.Status(http.StatusUnprocessableEntity), model, id, c.Request().PostForm, errs)
+}
+
+// form renders the create form, or the edit form of the row id. old holds
+// the submitted values shown back with errors.
+func (r *resourceOf[T]) form(c *app.Context, model *T, id string, old url.Values, errs shared.ValidationErrors) error {
+	f := &form.Form{Action: r.m.url(), Method: http.MethodPost, Submit: "Save", Values: map[string]string{}, Errors: errs}
+	title := "Create " + r.m.Label
+	if id != "" {
+		title, f.Action, f.Method = "Edit "+r.m.Label, r.m.url(id), http.MethodPut
+	}
+	for _, field := range r.m.fields {
+		if !field.Form {
+			continue
+		}
+		f.Fields = append(f.Fields, field.Field)
+		f.Values[field.Name] = field.Value(model)
+		if old != nil {
+			f.Values[field.Name] = old.Get(field.Name)
+		}
+	}
+
+	if r.m.panel.Inertia {
+		return c.Inertia("Admin/Form", map[string]any{
+			"title":  title,
+			"url":    r.m.url(),
+			"action": f.Action,
+			"method": f.Method,
+			"fields": f.Fields,
+			"values": f.Values,
+		})
+	}
+	return c.Templ(layout(r.m.panel.Title, title, r.m.panel.nav(c), formPage(r.m, f)))
+}
+
+// sortURL sorts the listing by f, flipping the direction when it already is.
+func sortURL(m *meta, l *listing, f *Field) string {
+	dir := ""
+	if l.Sort == f.Name && !l.Desc {
+		dir = "desc"
+	}
+	return l.url(m.url(), "sort", f.Name, "dir", dir, "page", "")
+}
@@ -0,0 +1,234 @@
+package amqp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	frameMethod    = 1
+	frameHeader    = 2
+	frameBody      = 3
+	frameHeartbeat = 8
+	frameEnd       = 0xCE
+)
+
+// Classes and methods used, as class<<16 | method.
+const (
+	connectionStart    = 10<<16 | 10
+	connectionStartOk  = 10<<16 | 11
+	connectionTune     = 10<<16 | 30
+	connectionTuneOk   = 10<<16 | 31
+	connectionOpen     = 10<<16 | 40
+	connectionOpenOk   = 10<<16 | 41
+	connectionClose    = 10<<16 | 50
+	connectionCloseOk  = 10<<16 | 51
+	channelOpen        = 20<<16 | 10
+	channelOpenOk      = 20<<16 | 11
+	channelClose       = 20<<16 | 40
+	channelCloseOk     = 20<<16 | 41
+	queueDeclare       = 50<<16 | 10
+	queueDeclareOk     = 50<<16 | 11
+	basicQos           = 60<<16 | 10
+	basicQosOk         = 60<<16 | 11
+	basicConsume       = 60<<16 | 20
+	basicConsumeOk     = 60<<16 | 21
+	basicCancel        = 60<<16 | 30
+	basicPublish       = 60<<16 | 40
+	basicDeliver       = 60<<16 | 60
+	basicAck           = 60<<16 | 80
+	basicReject        = 60<<16 | 90
+	basicNack          = 60<<16 | 120
+	confirmSelect      = 85<<16 | 10
+	confirmSelectOk    = 85<<16 | 11
+	basicClass         = 60
+	propContentType    = 1 << 15
+	propDeliveryMode   = 1 << 12
+	propMessageID      = 1 << 7
+	persistentDelivery = 2
+)
+
+// Table is a field table, the arguments of declarations. Values are
+// strings, bools, ints, int32s, int64s, durations (sent as milliseconds) or
+// Tables.
+type Table map[string]any
+
+// encoder writes the fields of a frame payload.
+type encoder struct {
+	bytes.Buffer
+}
+
+func (e *encoder) octet(v byte) {
+	e.WriteByte(v)
+}
+
+func (e *encoder) short(v uint16) {
+	e.Write(binary.BigEndian.AppendUint16(nil, v))
+}
+
+func (e *encoder) long(v uint32) {
+	e.Write(binary.BigEndian.AppendUint32(nil, v))
+}
+
+func (e *encoder) longlong(v uint64) {
+	e.Write(binary.BigEndian.AppendUint64(nil, v))
+}
+
+func (e *encoder) shortstr(s string) {
+	n := min(len(s), 255)
+	e.octet(byte(n))
+	e.WriteString(s[:n])
+}
+
+func (e *encoder) longstr(s string) {
+	e.long(uint32(len(s)))
+	e.WriteString(s)
+}
+
+// bits packs flags into an octet, the first one in the lowest bit.
+func (e *encoder) bits(flags ...bool) {
+	var b byte
+	for i, f := range flags {
+		if f {
+			b |= 1 << i
+		}
+	}
+	e.octet(b)
+}
+
+func (e *encoder) method(id uint32) {
+	e.short(uint16(id >> 16))
+	e.short(uint16(id))
+}
+
+func (e *encoder) table(t Table) {
+	var fields encoder
+	for k, v := range t {
+		fields.shortstr(k)
+		switch v := v.(type) {
+		case string:
+			fields.octet('S')
+			fields.longstr(v)
+		case bool:
+			fields.octet('t')
+			fields.bits(v)
+		case int:
+			fields.octet('l')
+			fields.longlong(uint64(v))
+		case int32:
+			fields.octet('I')
+			fields.long(uint32(v))
+		case int64:
+			fields.octet('l')
+			fields.longlong(uint64(v))
+		case time.Duration:
+			fields.octet('l')
+			fields.longlong(uint64(v.Milliseconds()))
+		case Table:
+			fields.octet('F')
+			fields.table(v)
+		default:
+			panic(fmt.Sprintf("amqp: unsupported table value %T", v))
+		}
+	}
+	e.long(uint32(fields.Len()))
+	e.Write(fields.Bytes())
+}
+
+var errShortFrame = errors.New("amqp: malformed frame")
+
+// decoder reads the fields of a frame payload, reporting a short payload
+// through err.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil || len(d.b) < n {
+		d.err = errShortFrame
+		return make([]byte, min(n, 8))
+	}
+	v := d.b[:n]
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) octet() byte {
+	return d.next(1)[0]
+}
+
+func (d *decoder) short() uint16 {
+	return binary.BigEndian.Uint16(d.next(2))
+}
+
+func (d *decoder) long() uint32 {
+	return binary.BigEndian.Uint32(d.next(4))
+}
+
+func (d *decoder) longlong() uint64 {
+	return binary.BigEndian.Uint64(d.next(8))
+}
+
+func (d *decoder) shortstr() string {
+	return string(d.next(int(d.octet())))
+}
+
+func (d *decoder) longstr() string {
+	return string(d.next(int(d.long())))
+}
+
+// skipTable skips a field table, none of those sent by servers being used.
+func (d *decoder) skipTable() {
+	d.next(int(d.long()))
+}
+
+// method is a method frame received.
+type method struct {
+	id   uint32
+	args *decoder
+}
+
+type frame struct {
+	typ     byte
+	channel uint16
+	payload []byte
+}
+
+func readFrame(r io.Reader, frameMax int) (frame, error) {
+	var hdr [7]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return frame{}, err
+	}
+	size := int(binary.BigEndian.Uint32(hdr[3:]))
+	if frameMax > 0 && size > frameMax {
+		return frame{}, fmt.Errorf("amqp: frame of %d bytes exceeds %d", size, frameMax)
+	}
+	payload := make([]byte, size+1)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return frame{}, err
+	}
+	if payload[size] != frameEnd {
+		return frame{}, errShortFrame
+	}
+	return frame{typ: hdr[0], channel: binary.BigEndian.Uint16(hdr[1:]), payload: payload[:size]}, nil
+}
+
+func appendFrame(b []byte, typ byte, channel uint16, payload []byte) []byte {
+	b = append(b, typ)
+	b = binary.BigEndian.AppendUint16(b, channel)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(payload)))
+	b = append(b, payload...)
+	return append(b, frameEnd)
+}
+
+func parseMethod(payload []byte) method {
+	d := &decoder{b: payload}
+	class := d.short()
+	id := d.short()
+	return method{id: uint32(class)<<16 | uint32(id), args: d}
+}
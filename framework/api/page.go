@@ -0,0 +1,94 @@
+package api
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/lemmego/api/app"
+
+	"github.com/lemmego/lemmego/framework/repo"
+)
+
+// MaxPerPage caps the per_page parameter.
+const MaxPerPage = 100
+
+// Page is a page of models and where it sits in the whole result.
+type Page[T any] struct {
+	Items   []T
+	Total   int64
+	Page    int
+	PerPage int
+}
+
+// Meta describes the page of a collection.
+type Meta struct {
+	Total       int64 `json:"total"`
+	CurrentPage int   `json:"current_page"`
+	PerPage     int   `json:"per_page"`
+	LastPage    int   `json:"last_page"`
+	// From and To number the first and last items shown, 0 when empty.
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// Links point at the other pages of a collection.
+type Links struct {
+	First string `json:"first"`
+	Last  string `json:"last"`
+	Prev  string `json:"prev,omitempty"`
+	Next  string `json:"next,omitempty"`
+}
+
+// Paginate loads the page of q asked for by the page and per_page query
+// parameters, perPage items by default.
+func Paginate[T any](c *app.Context, q *repo.Repo[T], perPage int) (*Page[T], error) {
+	page, _ := strconv.Atoi(c.Query("page"))
+	if n, err := strconv.Atoi(c.Query("per_page")); err == nil && n > 0 {
+		perPage = n
+	}
+	p := &Page[T]{Page: max(page, 1), PerPage: min(max(perPage, 1), MaxPerPage)}
+
+	total, err := q.Count()
+	if err != nil {
+		return nil, err
+	}
+	p.Total = total
+	if p.Items, err = q.Offset((p.Page - 1) * p.PerPage).Limit(p.PerPage).Find(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Page[T]) lastPage() int {
+	if p.PerPage <= 0 {
+		return 1
+	}
+	return max(int((p.Total+int64(p.PerPage)-1)/int64(p.PerPage)), 1)
+}
+
+func (p *Page[T]) meta() *Meta {
+	m := &Meta{Total: p.Total, CurrentPage: p.Page, PerPage: p.PerPage, LastPage: p.lastPage()}
+	if len(p.Items) > 0 {
+		m.From = (p.Page-1)*p.PerPage + 1
+		m.To = m.From + len(p.Items) - 1
+	}
+	return m
+}
+
+// links are the URLs of the request with another page parameter.
+func (p *Page[T]) links(u *url.URL) *Links {
+	at := func(page int) string {
+		q := u.Query()
+		q.Set("page", strconv.Itoa(page))
+		return (&url.URL{Path: u.Path, RawQuery: q.Encode()}).String()
+	}
+	last := p.lastPage()
+	l := &Links{First: at(1), Last: at(last)}
+	if p.Page > 1 {
+		l.Prev = at(min(p.Page-1, last))
+	}
+	if p.Page < last {
+		l.Next = at(p.Page + 1)
+	}
+	return l
+}
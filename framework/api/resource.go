@@ -0,0 +1,131 @@
+// Package api shapes response bodies from models, so handlers stop sending
+// database structs as they are. A Resource maps a model to the fields the
+// API shows, some of them conditional or nested:
+//
+//	var UserResource api.Resource[User] = func(c *app.Context, u *User) api.M {
+//		return api.M{
+//			"id":    u.ID,
+//			"name":  u.Name,
+//			"email": api.When(isAdmin(c), u.Email),
+//			"posts": api.WhenLoaded(u.Posts, func() any { return PostResource.Many(c, u.Posts) }),
+//		}
+//	}
+//
+//	r.Get("/users/{id}", func(c *app.Context) error {
+//		return UserResource.Respond(c, http.StatusOK, user)
+//	})
+//
+//	r.Get("/users", func(c *app.Context) error {
+//		page, err := api.Paginate(c, repo.New[User](c.RequestContext()).Order("id"), 20)
+//		if err != nil {
+//			return err
+//		}
+//		return jsonx.OK(c, UserResource.Page(c, page))
+//	})
+package api
+
+import (
+	"reflect"
+
+	"github.com/lemmego/api/app"
+
+	"github.com/lemmego/lemmego/framework/jsonx"
+)
+
+// M is the body of a resource.
+type M = map[string]any
+
+// Resource maps a model to its body.
+type Resource[T any] func(c *app.Context, model *T) M
+
+// missing marks fields left out of a body.
+type missing struct{}
+
+// When is value if cond holds, otherwise the field is left out.
+func When(cond bool, value any) any {
+	if !cond {
+		return missing{}
+	}
+	return value
+}
+
+// WhenFunc is When for values only computed when shown.
+func WhenFunc(cond bool, value func() any) any {
+	if !cond {
+		return missing{}
+	}
+	return value()
+}
+
+// WhenLoaded shows value, usually nested resources, only when the
+// association field was loaded: a non nil pointer, slice or map, or a non
+// zero struct.
+func WhenLoaded(field any, value func() any) any {
+	v := reflect.ValueOf(field)
+	if !v.IsValid() || v.IsZero() {
+		return missing{}
+	}
+	return value()
+}
+
+// Make returns the body of model, nil for a nil model.
+func (r Resource[T]) Make(c *app.Context, model *T) M {
+	if model == nil {
+		return nil
+	}
+	return resolve(r(c, model))
+}
+
+// Many returns the bodies of models.
+func (r Resource[T]) Many(c *app.Context, models []T) []M {
+	out := make([]M, len(models))
+	for i := range models {
+		out[i] = r.Make(c, &models[i])
+	}
+	return out
+}
+
+// Item is the response of a single model.
+type Item struct {
+	Data M `json:"data"`
+}
+
+// Respond sends the body of model wrapped in data.
+func (r Resource[T]) Respond(c *app.Context, status int, model *T) error {
+	return jsonx.Write(c, status, &Item{Data: r.Make(c, model)})
+}
+
+// Collection is the response of several models, with the pagination of the
+// page they come from.
+type Collection struct {
+	Data  []M    `json:"data"`
+	Meta  *Meta  `json:"meta,omitempty"`
+	Links *Links `json:"links,omitempty"`
+}
+
+// Collection wraps the bodies of models.
+func (r Resource[T]) Collection(c *app.Context, models []T) *Collection {
+	return &Collection{Data: r.Many(c, models)}
+}
+
+// Page wraps the bodies of a page of models with its meta and links.
+func (r Resource[T]) Page(c *app.Context, p *Page[T]) *Collection {
+	return &Collection{Data: r.Many(c, p.Items), Meta: p.meta(), Links: p.links(c.Request().URL)}
+}
+
+// resolve drops the fields left out by When and its kind.
+func resolve(body M) M {
+	for k, v := range body {
+		switch v := v.(type) {
+		case missing:
+			delete(body, k)
+		case M:
+			body[k] = resolve(v)
+		case []M:
+			for i := range v {
+				v[i] = resolve(v[i])
+			}
+		}
+	}
+	return body
+}
@@ -0,0 +1,200 @@
+// Package apikey manages the API keys of organizations, for integrations
+// acting on behalf of an organization rather than of a user, unlike the
+// personal tokens of package auth. Keys are stored hashed, carry scopes, may
+// expire, and are rate limited each on their own:
+//
+//	plain, key, err := apikey.Issue(ctx, orgID, &apikey.Options{Name: "CI", Scopes: []string{"orders:read"}})
+//
+//	v.Get("/orders", listOrders).UseBefore(apikey.Require("orders:read"))
+//
+// Requests send the key as a bearer token or in the X-API-Key header.
+package apikey
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lemmego/lemmego/framework/repo"
+	"github.com/lemmego/lemmego/framework/str"
+)
+
+// Prefix starts every key, so leaked ones are easy to scan for.
+const Prefix = "lk_"
+
+// AllScopes grants every scope.
+const AllScopes = "*"
+
+// lastUsedInterval is how stale LastUsedAt may get before a request
+// refreshes it, sparing a write per request.
+const lastUsedInterval = time.Minute
+
+var (
+	ErrInvalidKey = errors.New("apikey: invalid or expired key")
+	ErrNotFound   = errors.New("apikey: key not found")
+)
+
+// Key is an API key of an organization. Only a hash of the key is stored,
+// the key itself being shown once, when issued.
+type Key struct {
+	ID             uint   `gorm:"primaryKey" json:"id"`
+	OrganizationID uint   `json:"organization_id"`
+	Name           string `json:"name"`
+	// Hint is the start of the key, to tell keys apart.
+	Hint   string   `json:"hint"`
+	Hash   string   `json:"-"`
+	Scopes []string `gorm:"serializer:json" json:"scopes"`
+	// RateLimit is the requests allowed per minute, the default of Require
+	// when zero.
+	RateLimit  int        `json:"rate_limit"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	// CreatedBy is the user who issued the key.
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Key) TableName() string {
+	return "api_keys"
+}
+
+// Expired reports whether the key has expired.
+func (k *Key) Expired() bool {
+	return k.ExpiresAt != nil && time.Now().After(*k.ExpiresAt)
+}
+
+// Can reports whether the key holds a scope.
+func (k *Key) Can(scope string) bool {
+	return slices.Contains(k.Scopes, AllScopes) || slices.Contains(k.Scopes, scope)
+}
+
+// Options describe a key to issue.
+type Options struct {
+	Name   string
+	Scopes []string
+	// TTL is how long the key is valid, forever when zero.
+	TTL       time.Duration
+	RateLimit int
+	CreatedBy string
+}
+
+// Issue creates a key for an organization, and returns it along with the
+// key to hand over.
+func Issue(ctx context.Context, orgID uint, o *Options) (string, *Key, error) {
+	plain, k := newKey(orgID, o)
+	if err := repo.New[Key](ctx).Create(k); err != nil {
+		return "", nil, err
+	}
+	return plain, k, nil
+}
+
+func newKey(orgID uint, o *Options) (string, *Key) {
+	plain := Prefix + str.RandomString(40)
+	k := &Key{
+		OrganizationID: orgID,
+		Name:           o.Name,
+		Hint:           plain[:len(Prefix)+6],
+		Hash:           hash(plain),
+		Scopes:         o.Scopes,
+		RateLimit:      o.RateLimit,
+		CreatedBy:      o.CreatedBy,
+	}
+	if k.Scopes == nil {
+		k.Scopes = []string{}
+	}
+	if o.TTL > 0 {
+		expires := time.Now().Add(o.TTL)
+		k.ExpiresAt = &expires
+	}
+	return plain, k
+}
+
+// Rotate issues a key replacing one of an organization, with the same name,
+// scopes, rate limit and lifetime. The old key goes on working for grace, so
+// integrations can switch without downtime.
+func Rotate(ctx context.Context, orgID, id uint, grace time.Duration, by string) (string, *Key, error) {
+	old, err := Find(ctx, orgID, id)
+	if err != nil {
+		return "", nil, err
+	}
+	o := &Options{Name: old.Name, Scopes: old.Scopes, RateLimit: old.RateLimit, CreatedBy: by}
+	if old.ExpiresAt != nil {
+		o.TTL = old.ExpiresAt.Sub(old.CreatedAt)
+	}
+
+	plain, k := newKey(orgID, o)
+	err = repo.New[Key](ctx).Transaction(func(tx *repo.Repo[Key]) error {
+		expires := time.Now().Add(grace)
+		if old.ExpiresAt == nil || old.ExpiresAt.After(expires) {
+			if _, err := tx.Where("id = ?", old.ID).Update(map[string]any{"expires_at": expires}); err != nil {
+				return err
+			}
+		}
+		return tx.Create(k)
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return plain, k, nil
+}
+
+// Revoke deletes a key of an organization.
+func Revoke(ctx context.Context, orgID, id uint) error {
+	n, err := repo.New[Key](ctx).Delete("id = ? AND organization_id = ?", id, orgID)
+	if err == nil && n == 0 {
+		err = ErrNotFound
+	}
+	return err
+}
+
+// List returns the keys of an organization, the newest first.
+func List(ctx context.Context, orgID uint) ([]Key, error) {
+	return repo.New[Key](ctx).Where("organization_id = ?", orgID).Order("id DESC").Find()
+}
+
+// Find returns a key of an organization.
+func Find(ctx context.Context, orgID, id uint) (*Key, error) {
+	keys, err := repo.New[Key](ctx).Where("id = ? AND organization_id = ?", id, orgID).Limit(1).Find()
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, ErrNotFound
+	}
+	return &keys[0], nil
+}
+
+// Authenticate returns the valid key of plain, recording its use.
+func Authenticate(ctx context.Context, plain string) (*Key, error) {
+	if !strings.HasPrefix(plain, Prefix) {
+		return nil, ErrInvalidKey
+	}
+	keys, err := repo.New[Key](ctx).Where("hash = ?", hash(plain)).Limit(1).Find()
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 || keys[0].Expired() {
+		return nil, ErrInvalidKey
+	}
+	k := &keys[0]
+
+	if now := time.Now(); k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) > lastUsedInterval {
+		_, err := repo.New[Key](ctx).Where("id = ?", k.ID).Update(map[string]any{"last_used_at": now})
+		// Use goes unrecorded while the database is read-only
+		if err != nil && !errors.Is(err, repo.ErrReadOnly) {
+			return nil, err
+		}
+		k.LastUsedAt = &now
+	}
+	return k, nil
+}
+
+func hash(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
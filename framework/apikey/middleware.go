@@ -0,0 +1,116 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lemmego/api/app"
+
+	"github.com/lemmego/lemmego/framework/repo"
+)
+
+// Header is the header keys may be sent in, besides Authorization.
+const Header = "X-API-Key"
+
+// DefaultRateLimit is the requests per minute of keys without a rate limit
+// of their own.
+var DefaultRateLimit = 600
+
+type keyContextKey struct{}
+
+var ErrScope = errors.New("apikey: key lacks the scope")
+
+// Require limits a route to requests with a valid key holding every scope,
+// within the rate limit of the key. Repositories of tenant scoped models
+// only see the rows of the key's organization, and handlers read the key
+// with FromContext.
+func Require(scopes ...string) app.Handler {
+	return func(c *app.Context) error {
+		plain := c.GetHeader(Header)
+		if bearer, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+			plain = bearer
+		}
+		if plain == "" {
+			c.SetHeader("WWW-Authenticate", "Bearer")
+			return c.Status(http.StatusUnauthorized).Unauthorized(ErrInvalidKey)
+		}
+
+		k, err := Authenticate(c.RequestContext(), plain)
+		if errors.Is(err, ErrInvalidKey) {
+			c.SetHeader("WWW-Authenticate", `Bearer error="invalid_token"`)
+			return c.Status(http.StatusUnauthorized).Unauthorized(err)
+		}
+		if err != nil {
+			return err
+		}
+		for _, scope := range scopes {
+			if !k.Can(scope) {
+				return c.Status(http.StatusForbidden).Forbidden(ErrScope)
+			}
+		}
+
+		limit := k.RateLimit
+		if limit <= 0 {
+			limit = DefaultRateLimit
+		}
+		remaining, reset := limiter.take(k.ID, limit)
+		c.SetHeader("X-RateLimit-Limit", strconv.Itoa(limit))
+		c.SetHeader("X-RateLimit-Remaining", strconv.Itoa(max(remaining, 0)))
+		if remaining < 0 {
+			c.SetHeader("Retry-After", strconv.Itoa(int(time.Until(reset).Seconds())+1))
+			return c.Status(http.StatusTooManyRequests).Error(http.StatusTooManyRequests, errors.New("rate limit exceeded"))
+		}
+
+		ctx := context.WithValue(repo.WithTenant(c.RequestContext(), k.OrganizationID), keyContextKey{}, k)
+		c.SetRequest(c.Request().WithContext(ctx))
+		return c.Next()
+	}
+}
+
+// FromContext returns the key checked by Require.
+func FromContext(ctx context.Context) (*Key, bool) {
+	k, ok := ctx.Value(keyContextKey{}).(*Key)
+	return k, ok
+}
+
+// limiter counts the requests of each key over fixed one minute windows, in
+// the memory of the process, so each instance allows the limit.
+var limiter = &windows{counts: map[uint]*window{}}
+
+type window struct {
+	start time.Time
+	count int
+}
+
+type windows struct {
+	mu     sync.Mutex
+	counts map[uint]*window
+}
+
+// take counts a request of key, returning the requests left in the window,
+// negative when over the limit, and when the window ends.
+func (w *windows) take(key uint, limit int) (int, time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := time.Now()
+	win, ok := w.counts[key]
+	if !ok || now.Sub(win.start) >= time.Minute {
+		// Drop the windows of keys no longer used
+		if len(w.counts) > 10000 {
+			for id, old := range w.counts {
+				if now.Sub(old.start) >= time.Minute {
+					delete(w.counts, id)
+				}
+			}
+		}
+		win = &window{start: now}
+		w.counts[key] = win
+	}
+	win.count++
+	return limit - win.count, win.start.Add(time.Minute)
+}
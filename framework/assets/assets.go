@@ -0,0 +1,215 @@
+// Package assets reads the files applications ship beside their binary,
+// templates, public assets and translations, from the binary itself in
+// single binary builds. Files on disk override the embedded ones, so a
+// deployment can still patch a template or a translation without a build.
+//
+// Applications embed their files when built with the embed tag, see the
+// package at the root of the application:
+//
+//	go build -tags embed -o app ./cmd/app
+//
+// The package imports the standard library alone, so that it can be used
+// from init functions running before those of the other packages.
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Manifest records the files written by Extract, relative to the working
+// directory.
+const Manifest = ".embedded"
+
+var (
+	mu       sync.RWMutex
+	embedded fs.FS
+)
+
+// Embed sets the files embedded in the binary, rooted at the directory of
+// the application.
+func Embed(fsys fs.FS) {
+	mu.Lock()
+	defer mu.Unlock()
+	embedded = fsys
+}
+
+// Embedded reports whether the binary embeds its files.
+func Embedded() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return embedded != nil
+}
+
+// Dir returns the files of dir, relative to the working directory, those on
+// disk overriding the embedded ones.
+func Dir(dir string) fs.FS {
+	disk := os.DirFS(dir)
+	mu.RLock()
+	fsys := embedded
+	mu.RUnlock()
+	if fsys == nil {
+		return disk
+	}
+	sub, err := fs.Sub(fsys, path.Clean(filepath.ToSlash(dir)))
+	if err != nil {
+		return disk
+	}
+	return overlay{disk, sub}
+}
+
+// overlay reads upper, then lower for the files missing from it.
+type overlay struct {
+	upper, lower fs.FS
+}
+
+func (o overlay) Open(name string) (fs.File, error) {
+	f, err := o.upper.Open(name)
+	if err == nil || !errors.Is(err, fs.ErrNotExist) {
+		return f, err
+	}
+	return o.lower.Open(name)
+}
+
+// ReadDir merges the entries of both, so that globs and walks see every file.
+func (o overlay) ReadDir(name string) ([]fs.DirEntry, error) {
+	upper, err := fs.ReadDir(o.upper, name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	lower, lowerErr := fs.ReadDir(o.lower, name)
+	if err != nil && lowerErr != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, e := range upper {
+		seen[e.Name()] = true
+	}
+	for _, e := range lower {
+		if !seen[e.Name()] {
+			upper = append(upper, e)
+		}
+	}
+	sort.Slice(upper, func(i, j int) bool { return upper[i].Name() < upper[j].Name() })
+	return upper, nil
+}
+
+// Extract writes the embedded files of paths, files or directories, to the
+// working directory, for the code reading them from fixed paths on disk
+// such as the templates of github.com/lemmego/api. Files on disk are left
+// alone, unless they are unchanged copies written by an earlier Extract,
+// which follow the binary: they are updated, or removed once no longer
+// embedded. The copies are recorded in Manifest.
+func Extract(paths ...string) error {
+	mu.RLock()
+	fsys := embedded
+	mu.RUnlock()
+	if fsys == nil {
+		return nil
+	}
+
+	written := map[string]string{}
+	if data, err := os.ReadFile(Manifest); err == nil {
+		if err := json.Unmarshal(data, &written); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	changed := false
+
+	want := map[string]bool{}
+	for _, root := range paths {
+		err := fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+			if errors.Is(err, fs.ErrNotExist) && p == root {
+				return nil
+			}
+			if err != nil || d.IsDir() {
+				return err
+			}
+			want[p] = true
+			data, err := fs.ReadFile(fsys, p)
+			if err != nil {
+				return err
+			}
+			sum := checksum(data)
+			switch onDisk, err := os.ReadFile(filepath.FromSlash(p)); {
+			case errors.Is(err, fs.ErrNotExist):
+			case err != nil:
+				return err
+			case checksum(onDisk) == sum:
+				return nil
+			case written[p] != checksum(onDisk):
+				// Changed on disk, the file is that of the deployment now
+				if _, ok := written[p]; ok {
+					delete(written, p)
+					changed = true
+				}
+				return nil
+			}
+			if err := write(p, data); err != nil {
+				return err
+			}
+			written[p] = sum
+			changed = true
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for p, sum := range written {
+		if want[p] || !within(p, paths) {
+			continue
+		}
+		delete(written, p)
+		changed = true
+		if onDisk, err := os.ReadFile(filepath.FromSlash(p)); err == nil && checksum(onDisk) == sum {
+			if err := os.Remove(filepath.FromSlash(p)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+	data, err := json.MarshalIndent(written, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(Manifest, append(data, '\n'), 0o644)
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// within reports whether p is one of paths or within one of them.
+func within(p string, paths []string) bool {
+	for _, root := range paths {
+		if p == root || strings.HasPrefix(p, root+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func write(p string, data []byte) error {
+	name := filepath.FromSlash(p)
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(name, data, 0o644)
+}
@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/session"
+	gonertia "github.com/romsar/gonertia"
+
+	"github.com/lemmego/lemmego/framework/ids"
+	"github.com/lemmego/lemmego/framework/middleware"
+	"github.com/lemmego/lemmego/framework/repo"
+)
+
+// deviceKey holds the id of the session's row in the user_sessions table.
+const deviceKey = "auth_device_id"
+
+// activityInterval is how stale LastActiveAt may get before a request
+// refreshes it, sparing a write per request.
+const activityInterval = time.Minute
+
+var ErrUnknownDevice = errors.New("auth: unknown session")
+
+// Device is a signed in session of a user, whatever the session store.
+// Revoking deletes its row, and TrackDevices destroys the session on its
+// next request.
+type Device struct {
+	ids.ULIDKey
+	UserID       string    `json:"-"`
+	Name         string    `json:"name"`
+	UserAgent    string    `json:"user_agent"`
+	IP           string    `json:"ip"`
+	LastActiveAt time.Time `json:"last_active_at"`
+	CreatedAt    time.Time `json:"created_at"`
+	// Current is set on the device of the request by Devices.
+	Current bool `gorm:"-" json:"current"`
+}
+
+func (Device) TableName() string {
+	return "user_sessions"
+}
+
+// TrackDevices records the sessions of signed in users, with their device,
+// address and last activity, and signs out those revoked. Sessions are the
+// impersonating staff member's while impersonating.
+func TrackDevices(c *app.Context) error {
+	user := SessionOwner(c)
+	if user == "" {
+		return c.Next()
+	}
+	ctx := c.RequestContext()
+	id := c.GetSessionString(deviceKey)
+	if id == "" {
+		if err := track(c, user); err != nil && !errors.Is(err, repo.ErrReadOnly) {
+			return err
+		}
+		return c.Next()
+	}
+
+	d, err := repo.New[Device](ctx).Where("id = ?", id).Limit(1).Find()
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(d) == 0:
+		// Revoked
+		var sess *session.Session
+		if err := c.App().Service(&sess); err != nil {
+			return err
+		}
+		if err := sess.Destroy(ctx); err != nil {
+			return err
+		}
+	case d[0].UserID != user:
+		// Another user signed in on the same session
+		if err := track(c, user); err != nil && !errors.Is(err, repo.ErrReadOnly) {
+			return err
+		}
+	case time.Since(d[0].LastActiveAt) > activityInterval:
+		_, err := repo.New[Device](ctx).Where("id = ?", id).Update(map[string]any{
+			"ip":             middleware.IP(c),
+			"last_active_at": time.Now(),
+		})
+		// Activity goes unrecorded while the database is read-only
+		if err != nil && !errors.Is(err, repo.ErrReadOnly) {
+			return err
+		}
+	}
+	return c.Next()
+}
+
+// ShareDevices gives Inertia pages the sessions of the signed in user as the
+// sessions prop, queried only by pages rendering it.
+func ShareDevices(c *app.Context) error {
+	user := SessionOwner(c)
+	if user == "" {
+		return c.Next()
+	}
+	ctx, current := c.RequestContext(), c.GetSessionString(deviceKey)
+	r := c.Request()
+	c.SetRequest(r.WithContext(gonertia.SetProp(r.Context(), "sessions", func() (any, error) {
+		return Devices(ctx, user, current)
+	})))
+	return c.Next()
+}
+
+// Devices returns the sessions of a user, the most recently active first,
+// marking the one of id current as such.
+func Devices(ctx context.Context, userID, current string) ([]Device, error) {
+	devices, err := repo.New[Device](ctx).Where("user_id = ?", userID).Order("last_active_at DESC").Find()
+	if err != nil {
+		return nil, err
+	}
+	for i := range devices {
+		devices[i].Current = devices[i].ID == current
+	}
+	return devices, nil
+}
+
+// CurrentDevice returns the id of the request's session.
+func CurrentDevice(c *app.Context) string {
+	return c.GetSessionString(deviceKey)
+}
+
+// RevokeDevice signs a session of the user out.
+func RevokeDevice(ctx context.Context, userID, id string) error {
+	n, err := repo.New[Device](ctx).Delete("id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrUnknownDevice
+	}
+	return nil
+}
+
+// RevokeOtherDevices signs the user out of every session but the request's,
+// returning how many were.
+func RevokeOtherDevices(c *app.Context) (int64, error) {
+	return repo.New[Device](c.RequestContext()).Delete("user_id = ? AND id <> ?", SessionOwner(c), CurrentDevice(c))
+}
+
+// RevokeAllDevices signs a user out everywhere, such as after a password
+// reset.
+func RevokeAllDevices(ctx context.Context, userID string) (int64, error) {
+	return repo.New[Device](ctx).Delete("user_id = ?", userID)
+}
+
+// ForgetDevice removes the request's session from the list, for the logout
+// handler.
+func ForgetDevice(c *app.Context) error {
+	id := c.PopSessionString(deviceKey)
+	if id == "" {
+		return nil
+	}
+	_, err := repo.New[Device](c.RequestContext()).Delete("id = ?", id)
+	return err
+}
+
+// PruneDevices deletes the sessions inactive for longer than idle, whose
+// session has expired from the store.
+func PruneDevices(ctx context.Context, idle time.Duration) (int64, error) {
+	return repo.New[Device](ctx).Delete("last_active_at < ?", time.Now().Add(-idle))
+}
+
+// SessionOwner returns the user the session of the request belongs to, the
+// impersonating staff member while impersonating.
+func SessionOwner(c *app.Context) string {
+	if staff, ok := Impersonator(c); ok {
+		return staff
+	}
+	return c.GetSessionString(UserKey)
+}
+
+func track(c *app.Context, user string) error {
+	ua := c.Request().UserAgent()
+	now := time.Now()
+	d := &Device{
+		UserID:       user,
+		Name:         deviceName(ua),
+		UserAgent:    ua,
+		IP:           middleware.IP(c),
+		LastActiveAt: now,
+		CreatedAt:    now,
+	}
+	if err := repo.New[Device](c.RequestContext()).Create(d); err != nil {
+		return err
+	}
+	c.PutSession(deviceKey, d.ID)
+	return nil
+}
+
+// deviceName describes a user agent as its browser and system, such as
+// "Firefox on Linux".
+func deviceName(ua string) string {
+	browser, system := "Unknown browser", "unknown system"
+	for _, b := range [][2]string{
+		{"Edg/", "Edge"}, {"OPR/", "Opera"}, {"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"}, {"CriOS/", "Chrome"}, {"Safari/", "Safari"},
+	} {
+		if strings.Contains(ua, b[0]) {
+			browser = b[1]
+			break
+		}
+	}
+	for _, s := range [][2]string{
+		{"iPhone", "iOS"}, {"iPad", "iPadOS"}, {"Android", "Android"},
+		{"Windows", "Windows"}, {"Mac OS X", "macOS"}, {"CrOS", "ChromeOS"}, {"Linux", "Linux"},
+	} {
+		if strings.Contains(ua, s[0]) {
+			system = s[1]
+			break
+		}
+	}
+	return browser + " on " + system
+}
@@ -0,0 +1,144 @@
+// Package auth checks credentials through the provider of a guard, keeps
+// track of the sessions of signed in users, and lets support staff sign in
+// as another user to see what they see. The signed in user's key lives in
+// the session under UserKey; while impersonating, the staff member's own key
+// is kept beside it and restored by StopImpersonating.
+//
+//	func (u *User) AuthID() string              { return strconv.Itoa(int(u.ID)) }
+//	func (u *User) Can(permission string) bool { return u.IsAdmin }
+//
+//	r.Post("/users/{id}/impersonate", func(c *app.Context) error {
+//		if err := auth.Impersonate(c, staff, target); err != nil {
+//			return c.Forbidden(err)
+//		}
+//		return c.Redirect("/")
+//	})
+//
+// Every start and stop is recorded in the impersonations table.
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/session"
+
+	"github.com/lemmego/lemmego/framework/middleware"
+	"github.com/lemmego/lemmego/framework/repo"
+)
+
+// Permission is the permission staff need to impersonate users.
+const Permission = "users.impersonate"
+
+// Session keys.
+const (
+	// UserKey holds the key of the signed in user, set by the login handler.
+	UserKey         = "auth_user_id"
+	impersonatorKey = "auth_impersonator_id"
+)
+
+var (
+	ErrForbidden            = errors.New("auth: not allowed to impersonate this user")
+	ErrAlreadyImpersonating = errors.New("auth: already impersonating a user")
+	ErrNotImpersonating     = errors.New("auth: not impersonating a user")
+)
+
+// User is a user that may impersonate or be impersonated.
+type User interface {
+	// AuthID is the user's key as stored in the session.
+	AuthID() string
+	// Can reports whether the user holds a permission.
+	Can(permission string) bool
+}
+
+// Impersonation is an entry of the impersonations table.
+type Impersonation struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	ImpersonatorID string    `json:"impersonator_id"`
+	UserID         string    `json:"user_id"`
+	Action         string    `json:"action"`
+	IP             string    `json:"ip"`
+	UserAgent      string    `json:"user_agent"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Audited actions.
+const (
+	ActionStarted = "started"
+	ActionStopped = "stopped"
+)
+
+// Impersonate signs staff in as target. Staff need Permission, and users
+// holding it themselves cannot be impersonated, so it never grants more
+// than staff already have.
+func Impersonate(c *app.Context, staff, target User) error {
+	if !staff.Can(Permission) || target.Can(Permission) || staff.AuthID() == target.AuthID() {
+		return ErrForbidden
+	}
+	if _, ok := Impersonator(c); ok {
+		return ErrAlreadyImpersonating
+	}
+
+	if err := record(c, staff.AuthID(), target.AuthID(), ActionStarted); err != nil {
+		return err
+	}
+	if err := renew(c); err != nil {
+		return err
+	}
+	c.PutSession(impersonatorKey, staff.AuthID())
+	c.PutSession(UserKey, target.AuthID())
+	return nil
+}
+
+// StopImpersonating signs the staff member back in as themselves.
+func StopImpersonating(c *app.Context) error {
+	staff, ok := Impersonator(c)
+	if !ok {
+		return ErrNotImpersonating
+	}
+
+	if err := record(c, staff, c.GetSessionString(UserKey), ActionStopped); err != nil {
+		return err
+	}
+	if err := renew(c); err != nil {
+		return err
+	}
+	c.PopSession(impersonatorKey)
+	c.PutSession(UserKey, staff)
+	return nil
+}
+
+// Impersonator returns the key of the staff member impersonating the
+// signed in user, if any.
+func Impersonator(c *app.Context) (string, bool) {
+	id := c.GetSessionString(impersonatorKey)
+	return id, id != ""
+}
+
+// Impersonating reports whether the signed in user is being impersonated.
+func Impersonating(c *app.Context) bool {
+	_, ok := Impersonator(c)
+	return ok
+}
+
+// renew gives the session a new token when the signed in user changes, so
+// a token seen before cannot be used as the other user.
+func renew(c *app.Context) error {
+	var sess *session.Session
+	if err := c.App().Service(&sess); err != nil {
+		return err
+	}
+	return sess.RenewToken(c.RequestContext())
+}
+
+func record(c *app.Context, staff, user, action string) error {
+	return repo.New[Impersonation](c.RequestContext()).Create(&Impersonation{
+		ImpersonatorID: staff,
+		UserID:         user,
+		Action:         action,
+		IP:             middleware.IP(c),
+		UserAgent:      c.Request().UserAgent(),
+		CreatedAt:      time.Now(),
+	})
+}
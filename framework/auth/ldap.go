@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lemmego/api/config"
+
+	"github.com/lemmego/lemmego/framework/ldap"
+)
+
+// LDAP authenticates users against a directory such as OpenLDAP or Active
+// Directory. It searches the user with a service account, then binds as
+// them with their password.
+type LDAP struct {
+	URL     string
+	Options ldap.Options
+	// BindDN and BindPassword are the service account searches run as,
+	// anonymous when empty.
+	BindDN       string
+	BindPassword string
+	BaseDN       string
+	// UserFilter finds a user, {username} standing for the name they
+	// entered, such as (sAMAccountName={username}) on Active Directory.
+	UserFilter string
+	// Attributes of the identity.
+	IDAttribute    string
+	NameAttribute  string
+	EmailAttribute string
+	GroupAttribute string
+	// GroupRoles maps groups to roles, by DN or by common name, regardless of
+	// case.
+	GroupRoles map[string]string
+	// PoolSize is the number of idle connections kept.
+	PoolSize int
+
+	once sync.Once
+	pool *ldap.Pool
+}
+
+// Authenticate implements Provider.
+func (l *LDAP) Authenticate(ctx context.Context, username, password string) (*Identity, error) {
+	// An empty password would be an anonymous bind, which succeeds
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+	l.once.Do(func() {
+		l.pool = ldap.NewPool(l.PoolSize, l.dial)
+	})
+
+	c, err := l.pool.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer l.pool.Put(c)
+
+	entries, err := c.Search(ctx, &ldap.SearchRequest{
+		BaseDN:     l.BaseDN,
+		Filter:     l.UserFilter,
+		Vars:       map[string]string{"username": username},
+		Attributes: []string{l.IDAttribute, l.NameAttribute, l.EmailAttribute, l.GroupAttribute},
+		SizeLimit:  2,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Ambiguous names are refused rather than guessed
+	if len(entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	entry := entries[0]
+
+	bindErr := c.Bind(ctx, entry.DN, password)
+	// The connection goes back to the pool bound as the service account, or
+	// is closed
+	if err := l.restore(ctx, c); err != nil {
+		c.Close()
+	}
+	if ldap.IsInvalidCredentials(bindErr) {
+		return nil, ErrInvalidCredentials
+	}
+	if bindErr != nil {
+		return nil, bindErr
+	}
+	return l.identity(entry), nil
+}
+
+func (l *LDAP) dial(ctx context.Context) (*ldap.Conn, error) {
+	c, err := ldap.Dial(ctx, l.URL, &l.Options)
+	if err != nil {
+		return nil, err
+	}
+	if l.BindDN == "" {
+		return c, nil
+	}
+	if err := c.Bind(ctx, l.BindDN, l.BindPassword); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// restore binds c as the service account.
+func (l *LDAP) restore(ctx context.Context, c *ldap.Conn) error {
+	if l.BindDN == "" {
+		// A connection cannot go back to anonymous once bound as a user
+		return errors.New("auth: anonymous ldap connection")
+	}
+	return c.Bind(ctx, l.BindDN, l.BindPassword)
+}
+
+func (l *LDAP) identity(e *ldap.Entry) *Identity {
+	id := &Identity{
+		ID:         e.Value(l.IDAttribute),
+		Name:       e.Value(l.NameAttribute),
+		Email:      e.Value(l.EmailAttribute),
+		Attributes: e.Attributes,
+	}
+	for _, group := range e.Values(l.GroupAttribute) {
+		if role, ok := l.role(group); ok && !slices.Contains(id.Roles, role) {
+			id.Roles = append(id.Roles, role)
+		}
+	}
+	slices.Sort(id.Roles)
+	return id
+}
+
+// role returns the role of a group DN, mapped by DN or by common name.
+func (l *LDAP) role(dn string) (string, bool) {
+	cn := ""
+	if first, _, _ := strings.Cut(dn, ","); len(first) > 3 && strings.EqualFold(first[:3], "cn=") {
+		cn = first[3:]
+	}
+	for group, role := range l.GroupRoles {
+		if strings.EqualFold(group, dn) || (cn != "" && strings.EqualFold(group, cn)) {
+			return role, true
+		}
+	}
+	return "", false
+}
+
+// ldapProvider builds an LDAP provider from its configuration.
+func ldapProvider(conf config.M) (*LDAP, error) {
+	str := func(key string) string {
+		s, _ := conf[key].(string)
+		return s
+	}
+	l := &LDAP{
+		URL:            str("url"),
+		BindDN:         str("bind_dn"),
+		BindPassword:   str("bind_password"),
+		BaseDN:         str("base_dn"),
+		UserFilter:     str("user_filter"),
+		IDAttribute:    str("id_attribute"),
+		NameAttribute:  str("name_attribute"),
+		EmailAttribute: str("email_attribute"),
+		GroupAttribute: str("group_attribute"),
+		GroupRoles:     map[string]string{},
+	}
+	if l.URL == "" || l.BaseDN == "" || l.UserFilter == "" {
+		return nil, errors.New("url, base_dn and user_filter are required")
+	}
+	l.PoolSize, _ = conf["pool_size"].(int)
+	if seconds, _ := conf["timeout"].(int); seconds > 0 {
+		l.Options.Timeout = time.Duration(seconds) * time.Second
+	}
+	l.Options.StartTLS, _ = conf["start_tls"].(bool)
+	if roles, ok := conf["group_roles"].(config.M); ok {
+		for group, role := range roles {
+			if s, ok := role.(string); ok {
+				l.GroupRoles[group] = s
+			}
+		}
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	tlsConfig.InsecureSkipVerify, _ = conf["insecure_skip_verify"].(bool)
+	if file := str("ca_file"); file != "" {
+		pem, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", file)
+		}
+	}
+	l.Options.TLS = tlsConfig
+	return l, nil
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/config"
+
+	"github.com/lemmego/lemmego/framework/server"
+)
+
+// clientCertUserKey is the context key of the user of a client certificate.
+const clientCertUserKey = "_client_cert_user"
+
+var (
+	ErrNoClientCert      = errors.New("auth: client certificate required")
+	ErrInvalidClientCert = errors.New("auth: client certificate not trusted")
+	ErrUnknownClientCert = errors.New("auth: client certificate not mapped to a user")
+)
+
+// ClientCertOptions configure RequireClientCert.
+type ClientCertOptions struct {
+	// Roots are the certificate authorities client certificates must chain
+	// to, see LoadCertPool.
+	Roots *x509.CertPool
+	// Users maps identities of certificates to users, an identity being a
+	// subject alternative name as dns:, email:, uri: or ip:, or the subject
+	// common name as cn:, such as "uri:spiffe://example.com/billing".
+	Users map[string]string
+	// Header, when set, holds the URL escaped PEM certificate a proxy
+	// terminating TLS forwards, such as nginx's $ssl_client_escaped_cert.
+	// It is read on requests of internal listeners only, which nothing but
+	// the proxy should reach.
+	Header string
+}
+
+// RequireClientCert limits a route to requests presenting a client
+// certificate issued by one of opts.Roots and mapped to a user, for machine
+// to machine endpoints. Handlers read the user with ClientCertUser. The TLS
+// listener only asks for certificates with server.tls.client_ca_file set.
+//
+//	r.Post("/internal/sync", sync).UseBefore(auth.RequireClientCert(opts))
+func RequireClientCert(opts *ClientCertOptions) app.Handler {
+	if opts.Roots == nil {
+		panic("auth: client certificates need roots")
+	}
+	return func(c *app.Context) error {
+		user, err := clientCertUser(c.Request(), opts)
+		switch {
+		case errors.Is(err, ErrNoClientCert), errors.Is(err, ErrInvalidClientCert):
+			return c.Status(http.StatusUnauthorized).Unauthorized(err)
+		case errors.Is(err, ErrUnknownClientCert):
+			return c.Status(http.StatusForbidden).Forbidden(err)
+		case err != nil:
+			return err
+		}
+		c.Set(clientCertUserKey, user)
+		return c.Next()
+	}
+}
+
+// ClientCertUser returns the user of the certificate of a request let through
+// by RequireClientCert.
+func ClientCertUser(c *app.Context) (string, bool) {
+	user, ok := c.Get(clientCertUserKey).(string)
+	return user, ok
+}
+
+// ConfiguredClientCerts returns the options of auth.client_certs, e.g.:
+//
+//	opts, err := auth.ConfiguredClientCerts()
+//	r.Post("/internal/sync", sync).UseBefore(auth.RequireClientCert(opts))
+func ConfiguredClientCerts() (*ClientCertOptions, error) {
+	file, _ := config.Get("auth.client_certs.ca_file", "").(string)
+	roots, err := LoadCertPool(file)
+	if err != nil {
+		return nil, err
+	}
+	users := map[string]string{}
+	if m, ok := config.Get("auth.client_certs.users", nil).(config.M); ok {
+		for id, user := range m {
+			users[id], _ = user.(string)
+		}
+	}
+	header, _ := config.Get("auth.client_certs.header", "").(string)
+	return &ClientCertOptions{Roots: roots, Users: users, Header: header}, nil
+}
+
+// LoadCertPool reads the PEM certificates of a CA bundle.
+func LoadCertPool(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("auth: no certificates in %s", file)
+	}
+	return pool, nil
+}
+
+func clientCertUser(r *http.Request, opts *ClientCertOptions) (string, error) {
+	var leaf *x509.Certificate
+	intermediates := x509.NewCertPool()
+	switch {
+	case r.TLS != nil && len(r.TLS.PeerCertificates) > 0:
+		leaf = r.TLS.PeerCertificates[0]
+		for _, cert := range r.TLS.PeerCertificates[1:] {
+			intermediates.AddCert(cert)
+		}
+	case opts.Header != "" && r.Header.Get(opts.Header) != "":
+		if l := server.ListenerFrom(r.Context()); l == nil || !l.Internal {
+			return "", ErrNoClientCert
+		}
+		cert, err := forwardedCert(r.Header.Get(opts.Header))
+		if err != nil {
+			return "", ErrInvalidClientCert
+		}
+		leaf = cert
+	default:
+		return "", ErrNoClientCert
+	}
+
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         opts.Roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return "", ErrInvalidClientCert
+	}
+
+	for _, id := range certIdentities(leaf) {
+		if user, ok := opts.Users[id]; ok {
+			return user, nil
+		}
+	}
+	return "", ErrUnknownClientCert
+}
+
+// certIdentities returns the identities of a certificate, its subject
+// alternative names first.
+func certIdentities(cert *x509.Certificate) []string {
+	var ids []string
+	for _, u := range cert.URIs {
+		ids = append(ids, "uri:"+u.String())
+	}
+	for _, name := range cert.DNSNames {
+		ids = append(ids, "dns:"+strings.ToLower(name))
+	}
+	for _, email := range cert.EmailAddresses {
+		ids = append(ids, "email:"+strings.ToLower(email))
+	}
+	for _, ip := range cert.IPAddresses {
+		ids = append(ids, "ip:"+ip.String())
+	}
+	if cn := cert.Subject.CommonName; cn != "" {
+		ids = append(ids, "cn:"+cn)
+	}
+	return ids
+}
+
+func forwardedCert(value string) (*x509.Certificate, error) {
+	decoded, err := url.QueryUnescape(value)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode([]byte(decoded))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("auth: no certificate in header")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
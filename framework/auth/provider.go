@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/lemmego/api/config"
+)
+
+var (
+	// ErrInvalidCredentials is returned by providers for unknown users and
+	// wrong passwords alike.
+	ErrInvalidCredentials = errors.New("auth: invalid credentials")
+	ErrUnknownProvider    = errors.New("auth: unknown provider")
+)
+
+// Identity is a user as a provider knows them.
+type Identity struct {
+	// ID identifies the user within the provider, such as an LDAP uid.
+	ID    string
+	Name  string
+	Email string
+	// Roles are the application roles the user holds.
+	Roles      []string
+	Attributes map[string][]string
+}
+
+// Provider checks the credentials of users.
+type Provider interface {
+	Authenticate(ctx context.Context, username, password string) (*Identity, error)
+}
+
+var (
+	providersMu sync.Mutex
+	providers   = map[string]Provider{}
+)
+
+// RegisterProvider makes p available under name, ahead of the providers
+// configured under auth.providers. Applications register the provider of
+// their own users table this way.
+func RegisterProvider(name string, p Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[name] = p
+}
+
+// Guard returns the provider of a guard, named by auth.guards.<name>.provider.
+func Guard(name string) (Provider, error) {
+	provider, _ := config.Get("auth.guards."+name+".provider", "").(string)
+	if provider == "" {
+		return nil, fmt.Errorf("auth: guard %s has no provider", name)
+	}
+	return GetProvider(provider)
+}
+
+// GetProvider returns the named provider, registered or configured under
+// auth.providers. Configured providers are built once and kept.
+func GetProvider(name string) (Provider, error) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	if p, ok := providers[name]; ok {
+		return p, nil
+	}
+
+	conf, _ := config.Get("auth.providers."+name, nil).(config.M)
+	if conf == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	var p Provider
+	switch conf["driver"] {
+	case "ldap":
+		l, err := ldapProvider(conf)
+		if err != nil {
+			return nil, fmt.Errorf("auth: provider %s: %w", name, err)
+		}
+		p = l
+	default:
+		return nil, fmt.Errorf("auth: provider %s has unknown driver %v", name, conf["driver"])
+	}
+	providers[name] = p
+	return p, nil
+}
+
+// Attempt checks credentials against the provider of a guard.
+//
+//	id, err := auth.Attempt(ctx, "staff", username, password)
+//	if errors.Is(err, auth.ErrInvalidCredentials) {
+//		return c.Unauthorized(err)
+//	}
+//	user, err := users.FirstOrCreate(id)
+//	c.PutSession(auth.UserKey, user.AuthID())
+func Attempt(ctx context.Context, guard, username, password string) (*Identity, error) {
+	p, err := Guard(guard)
+	if err != nil {
+		return nil, err
+	}
+	return p.Authenticate(ctx, username, password)
+}
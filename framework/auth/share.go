@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"github.com/lemmego/api/app"
+	gonertia "github.com/romsar/gonertia"
+)
+
+// ImpersonatingKey is the context key read by the impersonation banner templ
+// component. Inertia pages get the same flag as the impersonating prop.
+const ImpersonatingKey = "_impersonating"
+
+// ShareImpersonation tells views whether the signed in user is being
+// impersonated, so layouts can show a banner with a way back.
+func ShareImpersonation(c *app.Context) error {
+	impersonating := Impersonating(c)
+	c.Set(ImpersonatingKey, impersonating)
+	r := c.Request()
+	c.SetRequest(r.WithContext(gonertia.SetProp(r.Context(), "impersonating", impersonating)))
+	return c.Next()
+}
@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/config"
+
+	"github.com/lemmego/lemmego/framework/cache"
+	"github.com/lemmego/lemmego/framework/repo"
+	"github.com/lemmego/lemmego/framework/str"
+)
+
+// TokenPrefix starts every API token, so leaked ones are easy to scan for.
+const TokenPrefix = "lmg_"
+
+// tokenContextKey is the context key of the token of a request.
+const tokenContextKey = "_api_token"
+
+var ErrInvalidToken = errors.New("auth: invalid or expired token")
+
+// Token is an API token of a user. Only a hash of the token is stored, the
+// token itself being shown once, when issued.
+type Token struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    string     `json:"-"`
+	Name      string     `json:"name"`
+	Hash      string     `json:"-"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (Token) TableName() string {
+	return "api_tokens"
+}
+
+// Expired reports whether the token has expired.
+func (t *Token) Expired() bool {
+	return t.ExpiresAt != nil && time.Now().After(*t.ExpiresAt)
+}
+
+// IssueToken creates a token for a user, expiring after ttl unless zero,
+// and returns it along with the token to hand over.
+func IssueToken(ctx context.Context, userID, name string, ttl time.Duration) (string, *Token, error) {
+	plain := TokenPrefix + str.RandomString(40)
+	t := &Token{UserID: userID, Name: name, Hash: hashToken(plain), CreatedAt: time.Now()}
+	if ttl > 0 {
+		expires := t.CreatedAt.Add(ttl)
+		t.ExpiresAt = &expires
+	}
+	if err := repo.New[Token](ctx).Create(t); err != nil {
+		return "", nil, err
+	}
+	return plain, t, nil
+}
+
+// Introspect returns the token of plain. Lookups are cached for
+// auth.tokens.cache_seconds, and unknown tokens for
+// auth.tokens.negative_cache_seconds, so validating the token of every
+// request at high rates does not query the database each time. Revoking
+// forgets the cached lookup; with a cache that is not shared between
+// instances, other instances go on accepting the token for the rest of its
+// cache time, see the auth config.
+func Introspect(ctx context.Context, plain string) (*Token, error) {
+	if !strings.HasPrefix(plain, TokenPrefix) {
+		return nil, ErrInvalidToken
+	}
+	hash := hashToken(plain)
+	store, key := cache.Default(), tokenCacheKey(hash)
+
+	t, ok := store.Get(key).(Token)
+	if !ok {
+		found, err := repo.New[Token](ctx).Where("hash = ?", hash).Limit(1).Find()
+		if err != nil {
+			return nil, err
+		}
+		// Unknown tokens are cached as the zero Token
+		seconds := config.Get("auth.tokens.negative_cache_seconds", 0).(int)
+		if len(found) > 0 {
+			t = found[0]
+			seconds = config.Get("auth.tokens.cache_seconds", 0).(int)
+		}
+		if seconds > 0 {
+			store.Put(key, t, seconds)
+		}
+		if seconds > 0 && t.ID != 0 {
+			store.Tag(key, tokenUserTag(t.UserID))
+		}
+	}
+	if t.ID == 0 || t.Expired() {
+		return nil, ErrInvalidToken
+	}
+	return &t, nil
+}
+
+// Tokens returns the tokens of a user, the newest first.
+func Tokens(ctx context.Context, userID string) ([]Token, error) {
+	return repo.New[Token](ctx).Where("user_id = ?", userID).Order("id DESC").Find()
+}
+
+// RevokeToken deletes a token of a user.
+func RevokeToken(ctx context.Context, userID string, id uint) error {
+	found, err := repo.New[Token](ctx).Where("id = ? AND user_id = ?", id, userID).Limit(1).Find()
+	if err != nil {
+		return err
+	}
+	if len(found) == 0 {
+		return ErrInvalidToken
+	}
+	if _, err := repo.New[Token](ctx).Delete("id = ?", id); err != nil {
+		return err
+	}
+	cache.Default().Forget(tokenCacheKey(found[0].Hash))
+	return nil
+}
+
+// RevokeAllTokens deletes the tokens of a user, such as after a password
+// reset.
+func RevokeAllTokens(ctx context.Context, userID string) (int64, error) {
+	n, err := repo.New[Token](ctx).Delete("user_id = ?", userID)
+	if err != nil {
+		return n, err
+	}
+	cache.Default().FlushTags(tokenUserTag(userID))
+	return n, nil
+}
+
+// RequireToken limits a route to requests bearing a valid API token, which
+// handlers read with TokenFrom.
+//
+//	v.Get("/me", me).UseBefore(auth.RequireToken)
+func RequireToken(c *app.Context) error {
+	plain, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		c.SetHeader("WWW-Authenticate", "Bearer")
+		return c.Status(http.StatusUnauthorized).Unauthorized(ErrInvalidToken)
+	}
+	t, err := Introspect(c.RequestContext(), plain)
+	if errors.Is(err, ErrInvalidToken) {
+		c.SetHeader("WWW-Authenticate", `Bearer error="invalid_token"`)
+		return c.Status(http.StatusUnauthorized).Unauthorized(err)
+	}
+	if err != nil {
+		return err
+	}
+	c.Set(tokenContextKey, t)
+	return c.Next()
+}
+
+// TokenFrom returns the token of a request let through by RequireToken.
+func TokenFrom(c *app.Context) (*Token, bool) {
+	t, ok := c.Get(tokenContextKey).(*Token)
+	return t, ok
+}
+
+func hashToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+func tokenCacheKey(hash string) string {
+	return "auth:token:" + hash
+}
+
+func tokenUserTag(userID string) string {
+	return "auth:tokens:" + userID
+}
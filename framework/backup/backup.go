@@ -0,0 +1,439 @@
+// Package backup archives the database and selected directories into a single
+// file on a storage disk, and restores them again.
+//
+// An archive is a gzipped tar holding a manifest, the database dump and the
+// directories under files/, encrypted with the application key unless
+// disabled. Storage drivers cannot list files, so the backups on a disk are
+// tracked in an index next to them, which retention and restore rely on.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lemmego/api/db"
+	"github.com/lemmego/lemmego/framework/crypt"
+	"github.com/lemmego/lemmego/framework/storage"
+	"github.com/lemmego/lemmego/framework/str"
+)
+
+var ErrNotFound = errors.New("backup: no such backup")
+
+// Options configure where backups go and what they contain.
+type Options struct {
+	// Connection to dump, the default one when empty.
+	Connection string
+	// Directories archived next to the database, relative to the working directory.
+	Directories []string
+	// Disk the archives are written to, the default one when empty.
+	Disk string
+	// Path on the disk, "backups" when empty.
+	Path string
+	// Encrypt archives with the application key.
+	Encrypt bool
+	// Keep is the number of newest backups always kept. The newest one is
+	// never removed.
+	Keep int
+	// MaxAge removes the backups beyond Keep that are older. When 0 every
+	// backup beyond Keep is removed, or none if Keep is 0 as well.
+	MaxAge time.Duration
+	// Name of the application, used in file names.
+	App string
+}
+
+// Entry is a backup on the disk.
+type Entry struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	Encrypted bool      `json:"encrypted"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Manifest describes the contents of an archive.
+type Manifest struct {
+	App         string    `json:"app"`
+	Driver      string    `json:"driver"`
+	Database    string    `json:"database"`
+	Directories []string  `json:"directories"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+const manifestName = "manifest.json"
+
+func (o *Options) dir() string {
+	if o.Path == "" {
+		return "backups"
+	}
+	return o.Path
+}
+
+func (o *Options) disk(ctx context.Context) (*storage.Disk, error) {
+	if o.Disk == "" {
+		return storage.Get(ctx)
+	}
+	return storage.Get(ctx, o.Disk)
+}
+
+func (o *Options) connection() *db.Connection {
+	if o.Connection == "" {
+		return db.Get()
+	}
+	return db.Get(o.Connection)
+}
+
+// Run creates a backup, stores it and applies the retention policy. Disks
+// only accept whole files, so the archive is built in a temporary file and
+// read into memory once for the upload.
+func Run(ctx context.Context, o *Options) (*Entry, error) {
+	disk, err := o.disk(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	work, err := os.MkdirTemp("", "backup-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(work)
+
+	conn := o.connection()
+	dump := filepath.Join(work, dumpName(conn.Driver()))
+	if err := dumpDatabase(ctx, conn, dump); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	app := o.App
+	if app == "" {
+		app = "app"
+	}
+	entry := &Entry{
+		Name:      fmt.Sprintf("%s-%s.tar.gz", str.ASCIISlug(app), now.Format("20060102-150405")),
+		Encrypted: o.Encrypt,
+		CreatedAt: now,
+	}
+	if o.Encrypt {
+		entry.Name += ".enc"
+	}
+
+	archive := filepath.Join(work, entry.Name)
+	manifest := &Manifest{App: app, Driver: conn.Driver(), Database: conn.DBName(), Directories: o.Directories, CreatedAt: now}
+	if err := writeArchive(ctx, archive, manifest, dump, o); err != nil {
+		return nil, err
+	}
+
+	contents, err := os.ReadFile(archive)
+	if err != nil {
+		return nil, err
+	}
+	entry.Size = int64(len(contents))
+	if err := disk.Write(path.Join(o.dir(), entry.Name), contents); err != nil {
+		return nil, err
+	}
+
+	entries, err := List(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+	// A backup taken within the same second replaced the file of the previous one
+	entries = slices.DeleteFunc(entries, func(e Entry) bool { return e.Name == entry.Name })
+	entries = append([]Entry{*entry}, entries...)
+	if _, err := prune(disk, o, entries); err != nil {
+		return entry, err
+	}
+	return entry, nil
+}
+
+func writeArchive(ctx context.Context, file string, manifest *Manifest, dump string, o *Options) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var out io.Writer = f
+	var sealed io.WriteCloser
+	if o.Encrypt {
+		enc, err := crypt.Default()
+		if err != nil {
+			return fmt.Errorf("backup: encryption needs APP_KEY: %w", err)
+		}
+		sealed = enc.NewWriter(f)
+		out = sealed
+	}
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	m, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: manifestName, Mode: 0o644, Size: int64(len(m)), ModTime: manifest.CreatedAt}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(m); err != nil {
+		return err
+	}
+	if err := addFile(tw, dump, path.Base(filepath.ToSlash(dump))); err != nil {
+		return err
+	}
+
+	skip := backupFiles(o)
+	for _, dir := range o.Directories {
+		err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() || skip(p) {
+				return nil
+			}
+			return addFile(tw, p, path.Join("files", filepath.ToSlash(filepath.Clean(p))))
+		})
+		if err != nil {
+			return fmt.Errorf("backup: %s: %w", dir, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if sealed != nil {
+		if err := sealed.Close(); err != nil {
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// backupFiles matches files that must not end up in an archive: earlier
+// backups and the live SQLite database, which is dumped separately.
+func backupFiles(o *Options) func(p string) bool {
+	var live string
+	if conn := o.connection(); conn.Driver() == db.DialectSQLite {
+		live, _ = filepath.Abs(conn.DBName())
+	}
+	return func(p string) bool {
+		if strings.HasSuffix(p, ".tar.gz") || strings.HasSuffix(p, ".tar.gz.enc") {
+			return true
+		}
+		abs, _ := filepath.Abs(p)
+		return live != "" && strings.HasPrefix(abs, live)
+	}
+}
+
+func addFile(tw *tar.Writer, file, name string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: int64(info.Mode().Perm()), Size: info.Size(), ModTime: info.ModTime()}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// List returns the backups on the disk, newest first.
+func List(ctx context.Context, o *Options) ([]Entry, error) {
+	disk, err := o.disk(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return readIndex(disk, o)
+}
+
+func indexPath(o *Options) string {
+	return path.Join(o.dir(), "index.json")
+}
+
+func readIndex(disk *storage.Disk, o *Options) ([]Entry, error) {
+	exists, err := disk.Exists(indexPath(o))
+	if err != nil || !exists {
+		return nil, err
+	}
+	rc, err := disk.Read(indexPath(o))
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var entries []Entry
+	if err := json.NewDecoder(rc).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("backup: index: %w", err)
+	}
+	slices.SortFunc(entries, func(a, b Entry) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return entries, nil
+}
+
+// prune deletes the backups falling outside the retention policy and writes
+// the index of the remaining ones.
+func prune(disk *storage.Disk, o *Options, entries []Entry) ([]Entry, error) {
+	var kept, removed []Entry
+	for i, e := range entries {
+		var expired bool
+		switch {
+		case i == 0 || i < o.Keep:
+		case o.MaxAge > 0:
+			expired = time.Since(e.CreatedAt) > o.MaxAge
+		default:
+			expired = o.Keep > 0
+		}
+		if expired {
+			removed = append(removed, e)
+		} else {
+			kept = append(kept, e)
+		}
+	}
+
+	var errs []error
+	for _, e := range removed {
+		if err := disk.Delete(path.Join(o.dir(), e.Name)); err != nil {
+			// Keep it listed so the next run tries again
+			kept = append(kept, e)
+			errs = append(errs, err)
+		}
+	}
+
+	index, err := json.MarshalIndent(kept, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := disk.Write(indexPath(o), index); err != nil {
+		return nil, err
+	}
+	return removed, errors.Join(errs...)
+}
+
+// RestoreOptions select what Restore brings back.
+type RestoreOptions struct {
+	Database bool
+	Files    bool
+}
+
+// Restore loads the named backup, the newest one when name is empty, and
+// returns its manifest. Files are written back to their original paths.
+func Restore(ctx context.Context, o *Options, name string, ro RestoreOptions) (*Manifest, error) {
+	disk, err := o.disk(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		entries, err := readIndex(disk, o)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 {
+			return nil, ErrNotFound
+		}
+		name = entries[0].Name
+	}
+
+	file := path.Join(o.dir(), name)
+	if exists, err := disk.Exists(file); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	rc, err := disk.Read(file)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var in io.Reader = rc
+	if strings.HasSuffix(name, ".enc") {
+		enc, err := crypt.Default()
+		if err != nil {
+			return nil, fmt.Errorf("backup: decryption needs APP_KEY: %w", err)
+		}
+		in = enc.NewReader(rc)
+	}
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return nil, fmt.Errorf("backup: %s is not a backup archive: %w", name, err)
+	}
+	tr := tar.NewReader(gz)
+
+	var manifest *Manifest
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return manifest, err
+		}
+
+		switch {
+		case hdr.Name == manifestName:
+			manifest = &Manifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, err
+			}
+			if conn := o.connection(); ro.Database && manifest.Driver != conn.Driver() {
+				return manifest, fmt.Errorf("backup: %s holds a %s database, the connection is %s", name, manifest.Driver, conn.Driver())
+			}
+		case strings.HasPrefix(hdr.Name, "database."):
+			if !ro.Database {
+				continue
+			}
+			if err := restoreDatabase(ctx, o.connection(), tr); err != nil {
+				return manifest, err
+			}
+		case strings.HasPrefix(hdr.Name, "files/"):
+			if !ro.Files {
+				continue
+			}
+			if err := restoreFile(hdr, tr); err != nil {
+				return manifest, err
+			}
+		}
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("backup: %s has no manifest", name)
+	}
+	return manifest, nil
+}
+
+func restoreFile(hdr *tar.Header, r io.Reader) error {
+	rel := filepath.FromSlash(strings.TrimPrefix(hdr.Name, "files/"))
+	if !filepath.IsLocal(rel) {
+		return fmt.Errorf("backup: refusing to restore %s outside the working directory", hdr.Name)
+	}
+	if err := os.MkdirAll(filepath.Dir(rel), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(rel, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fs.FileMode(hdr.Mode).Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,116 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lemmego/api/db"
+)
+
+// dumpName is the archive entry holding the database dump.
+func dumpName(driver string) string {
+	if driver == db.DialectSQLite {
+		return "database.sqlite"
+	}
+	return "database.sql"
+}
+
+// dumpDatabase writes a consistent copy of the connection's database to file.
+// SQLite is copied with VACUUM INTO, MySQL and PostgreSQL are dumped as SQL
+// with their client tools, which must be on the PATH.
+func dumpDatabase(ctx context.Context, conn *db.Connection, file string) error {
+	switch conn.Driver() {
+	case db.DialectSQLite:
+		_, err := conn.SqlDB().ExecContext(ctx, "VACUUM INTO ?", file)
+		return err
+	case db.DialectMySQL:
+		return runTool(ctx, conn, nil, file, "mysqldump",
+			"--single-transaction", "--routines", "--triggers", "--no-tablespaces",
+			"--host", conn.DBHost(), "--port", strconv.Itoa(conn.DBPort()), "--user", conn.DBUser(),
+			conn.DBName())
+	case db.DialectPostgres:
+		return runTool(ctx, conn, nil, file, "pg_dump",
+			"--clean", "--if-exists", "--no-owner", "--no-privileges",
+			"--host", conn.DBHost(), "--port", strconv.Itoa(conn.DBPort()), "--username", conn.DBUser(),
+			"--dbname", conn.DBName())
+	}
+	return fmt.Errorf("backup: unsupported driver %s", conn.Driver())
+}
+
+// restoreDatabase loads a dump written by dumpDatabase. The SQLite file is
+// replaced as a whole, so the application should not be running.
+func restoreDatabase(ctx context.Context, conn *db.Connection, dump io.Reader) error {
+	switch conn.Driver() {
+	case db.DialectSQLite:
+		target := conn.DBName()
+		tmp, err := os.CreateTemp(filepath.Dir(target), ".restore-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		if _, err := io.Copy(tmp, dump); err != nil {
+			tmp.Close()
+			return err
+		}
+		if err := tmp.Close(); err != nil {
+			return err
+		}
+		// Connections still holding the old file would keep reading it
+		if err := conn.SqlDB().Close(); err != nil {
+			return err
+		}
+		for _, suffix := range []string{"-wal", "-shm"} {
+			_ = os.Remove(target + suffix)
+		}
+		return os.Rename(tmp.Name(), target)
+	case db.DialectMySQL:
+		return runTool(ctx, conn, dump, "", "mysql",
+			"--host", conn.DBHost(), "--port", strconv.Itoa(conn.DBPort()), "--user", conn.DBUser(),
+			conn.DBName())
+	case db.DialectPostgres:
+		return runTool(ctx, conn, dump, "", "psql",
+			"--quiet", "--set", "ON_ERROR_STOP=1",
+			"--host", conn.DBHost(), "--port", strconv.Itoa(conn.DBPort()), "--username", conn.DBUser(),
+			"--dbname", conn.DBName())
+	}
+	return fmt.Errorf("backup: unsupported driver %s", conn.Driver())
+}
+
+// runTool runs a database client with the password passed through the
+// environment, keeping it out of the process list.
+func runTool(ctx context.Context, conn *db.Connection, stdin io.Reader, stdoutFile string, name string, args ...string) error {
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("backup: %s is required for %s connections: %w", name, conn.Driver(), err)
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), "MYSQL_PWD="+conn.DBPassword(), "PGPASSWORD="+conn.DBPassword())
+	cmd.Stdin = stdin
+
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if stdoutFile != "" {
+		out, err := os.Create(stdoutFile)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		cmd.Stdout = out
+	}
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() > 0 {
+			return fmt.Errorf("backup: %s: %s", name, strings.TrimSpace(stderr.String()))
+		}
+		return fmt.Errorf("backup: %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+// Package barcode generates QR codes, e.g. the otpauth:// URIs of a TOTP
+// setup, and the common one dimensional barcodes, written as PNG or SVG.
+//
+//	code, err := barcode.QR(uri, barcode.LevelM)
+//	if err != nil {
+//		return err
+//	}
+//	return barcode.Send(c, code, barcode.PNG, &barcode.Options{Scale: 6})
+package barcode
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/storage"
+)
+
+// Code is a grid of dark and light modules.
+type Code interface {
+	// Size is the width and height in modules. One dimensional codes are a
+	// single row high.
+	Size() (width, height int)
+	Dark(x, y int) bool
+	// QuietZone is the margin in modules scanners need around the code.
+	QuietZone() int
+}
+
+type Format string
+
+const (
+	PNG Format = "png"
+	SVG Format = "svg"
+)
+
+func (f Format) ContentType() string {
+	if f == SVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// Options control the rendered image. Zero values use the defaults.
+type Options struct {
+	// Scale is the size of a module in pixels, 4 by default.
+	Scale int
+	// Height of one dimensional codes in pixels, 60 by default.
+	Height int
+	// NoQuietZone leaves out the margin, for layouts that provide their own.
+	NoQuietZone bool
+	// Foreground and Background default to black on white.
+	Foreground color.Color
+	Background color.Color
+}
+
+func (o *Options) withDefaults() Options {
+	v := Options{}
+	if o != nil {
+		v = *o
+	}
+	if v.Scale <= 0 {
+		v.Scale = 4
+	}
+	if v.Height <= 0 {
+		v.Height = 60
+	}
+	if v.Foreground == nil {
+		v.Foreground = color.Black
+	}
+	if v.Background == nil {
+		v.Background = color.White
+	}
+	return v
+}
+
+// layout returns the margin and the image size in pixels.
+func layout(code Code, o Options) (margin, width, height int) {
+	w, h := code.Size()
+	if !o.NoQuietZone {
+		margin = code.QuietZone() * o.Scale
+	}
+	width = w*o.Scale + 2*margin
+	height = h*o.Scale + 2*margin
+	if h == 1 {
+		height = o.Height + 2*margin
+	}
+	return margin, width, height
+}
+
+// Image renders code as an image.
+func Image(code Code, o *Options) image.Image {
+	v := o.withDefaults()
+	margin, width, height := layout(code, v)
+	img := image.NewPaletted(image.Rect(0, 0, width, height), color.Palette{v.Background, v.Foreground})
+
+	w, h := code.Size()
+	rowHeight := v.Scale
+	if h == 1 {
+		rowHeight = v.Height
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if !code.Dark(x, y) {
+				continue
+			}
+			for py := margin + y*rowHeight; py < margin+(y+1)*rowHeight; py++ {
+				row := img.Pix[py*img.Stride:]
+				for px := margin + x*v.Scale; px < margin+(x+1)*v.Scale; px++ {
+					row[px] = 1
+				}
+			}
+		}
+	}
+	return img
+}
+
+// WritePNG writes code to w as a PNG image.
+func WritePNG(w io.Writer, code Code, o *Options) error {
+	return png.Encode(w, Image(code, o))
+}
+
+// WriteSVG writes code to w as an SVG image, with the text of one dimensional
+// codes printed under the bars.
+func WriteSVG(w io.Writer, code Code, o *Options) error {
+	v := o.withDefaults()
+	margin, width, height := layout(code, v)
+	cols, rows := code.Size()
+
+	var text string
+	if l, ok := code.(*Linear); ok && l.Text != "" {
+		text = l.Text
+		height += 3 * v.Scale
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, width, height, width, height)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="%s"/><path fill="%s" d="`, hexColor(v.Background), hexColor(v.Foreground))
+	rowHeight := v.Scale
+	if rows == 1 {
+		rowHeight = v.Height
+	}
+	for y := 0; y < rows; y++ {
+		// One path segment per run of dark modules keeps the file small
+		for x := 0; x < cols; x++ {
+			if !code.Dark(x, y) {
+				continue
+			}
+			run := 1
+			for x+run < cols && code.Dark(x+run, y) {
+				run++
+			}
+			fmt.Fprintf(&b, "M%d %dh%dv%dh-%dz", margin+x*v.Scale, margin+y*rowHeight, run*v.Scale, rowHeight, run*v.Scale)
+			x += run
+		}
+	}
+	b.WriteString(`"/>`)
+	if text != "" {
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle" font-family="monospace" font-size="%d" fill="%s">%s</text>`,
+			width/2, margin+v.Height+3*v.Scale, 3*v.Scale, hexColor(v.Foreground), html.EscapeString(text))
+	}
+	b.WriteString("</svg>")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func hexColor(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
+
+// Encode writes code to w in the given format.
+func Encode(w io.Writer, code Code, format Format, o *Options) error {
+	switch format {
+	case PNG:
+		return WritePNG(w, code, o)
+	case SVG:
+		return WriteSVG(w, code, o)
+	}
+	return fmt.Errorf("barcode: unknown format %q", format)
+}
+
+// DataURL returns code as a data: URL, for an <img> src in a template.
+func DataURL(code Code, format Format, o *Options) (string, error) {
+	var out bytes.Buffer
+	if err := Encode(&out, code, format, o); err != nil {
+		return "", err
+	}
+	return "data:" + format.ContentType() + ";base64," + base64.StdEncoding.EncodeToString(out.Bytes()), nil
+}
+
+// Send writes code as the response, displayed inline.
+func Send(c *app.Context, code Code, format Format, o *Options) error {
+	return send(c, "inline", "", code, format, o)
+}
+
+// Download sends code as an attachment named filename.
+func Download(c *app.Context, filename string, code Code, format Format, o *Options) error {
+	return send(c, "attachment", filename, code, format, o)
+}
+
+func send(c *app.Context, disposition, filename string, code Code, format Format, o *Options) error {
+	var out bytes.Buffer
+	if err := Encode(&out, code, format, o); err != nil {
+		return err
+	}
+
+	w := c.ResponseWriter()
+	w.Header().Set("Content-Type", format.ContentType())
+	if filename != "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`%s; filename="%s"`, disposition, strings.ReplaceAll(filename, `"`, "")))
+	}
+	// Codes often carry secrets, like a TOTP seed
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Length", fmt.Sprint(out.Len()))
+	w.WriteHeader(http.StatusOK)
+	_, err := out.WriteTo(w)
+	return err
+}
+
+// Store writes code to path on a storage disk, the default one when diskName
+// is empty.
+func Store(ctx context.Context, diskName, path string, code Code, format Format, o *Options) error {
+	var out bytes.Buffer
+	if err := Encode(&out, code, format, o); err != nil {
+		return err
+	}
+
+	var names []string
+	if diskName != "" {
+		names = append(names, diskName)
+	}
+	disk, err := storage.Get(ctx, names...)
+	if err != nil {
+		return err
+	}
+	return disk.Write(path, out.Bytes())
+}
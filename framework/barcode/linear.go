@@ -0,0 +1,192 @@
+package barcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Linear is a one dimensional barcode, a row of bars stretched to the
+// rendered height.
+type Linear struct {
+	// Text is the human readable content printed under the bars by WriteSVG.
+	Text string
+	bars []bool
+}
+
+func (l *Linear) Size() (int, int) { return len(l.bars), 1 }
+
+func (l *Linear) Dark(x, _ int) bool { return l.bars[x] }
+
+// QuietZone is the light margin required on either side of the bars.
+func (l *Linear) QuietZone() int { return 10 }
+
+func (l *Linear) appendWidths(widths string) {
+	dark := true
+	for _, w := range widths {
+		for i := 0; i < int(w-'0'); i++ {
+			l.bars = append(l.bars, dark)
+		}
+		dark = !dark
+	}
+}
+
+func (l *Linear) appendModules(modules string) {
+	for _, m := range modules {
+		l.bars = append(l.bars, m == '1')
+	}
+}
+
+// code128 holds the bar and space widths of each symbol value, 103 to 105
+// being the start codes and 106 the stop code.
+var code128 = [107]string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232", "2331112",
+}
+
+const (
+	code128CodeC  = 99
+	code128CodeB  = 100
+	code128StartB = 104
+	code128StartC = 105
+	code128Stop   = 106
+)
+
+// Code128 encodes printable ASCII as Code 128, switching to the denser code
+// set C for runs of digits.
+func Code128(content string) (*Linear, error) {
+	if content == "" {
+		return nil, fmt.Errorf("barcode: Code 128 content is empty")
+	}
+	for i := 0; i < len(content); i++ {
+		if content[i] < 32 || content[i] > 126 {
+			return nil, fmt.Errorf("barcode: Code 128 cannot encode %q", content[i])
+		}
+	}
+
+	digits := func(i int) int {
+		n := i
+		for n < len(content) && content[n] >= '0' && content[n] <= '9' {
+			n++
+		}
+		return n - i
+	}
+
+	var values []int
+	setC := digits(0) >= 4 || digits(0) == len(content) && len(content)%2 == 0
+	if setC {
+		values = append(values, code128StartC)
+	} else {
+		values = append(values, code128StartB)
+	}
+
+	for i := 0; i < len(content); {
+		if setC {
+			if digits(i) < 2 {
+				values = append(values, code128CodeB)
+				setC = false
+				continue
+			}
+			values = append(values, int(content[i]-'0')*10+int(content[i+1]-'0'))
+			i += 2
+			continue
+		}
+		// A run of digits is worth switching for when it saves symbols,
+		// leaving an odd digit in set B
+		if n := digits(i); n >= 6 || n >= 4 && i+n == len(content) {
+			if n%2 == 1 {
+				values = append(values, int(content[i])-32)
+				i++
+			}
+			values = append(values, code128CodeC)
+			setC = true
+			continue
+		}
+		values = append(values, int(content[i])-32)
+		i++
+	}
+
+	check := values[0]
+	for i, v := range values[1:] {
+		check += (i + 1) * v
+	}
+	values = append(values, check%103, code128Stop)
+
+	l := &Linear{Text: content}
+	for _, v := range values {
+		l.appendWidths(code128[v])
+	}
+	return l, nil
+}
+
+var (
+	eanL = [10]string{"0001101", "0011001", "0010011", "0111101", "0100011", "0110001", "0101111", "0111011", "0110111", "0001011"}
+	eanG = [10]string{"0100111", "0110011", "0011011", "0100001", "0011101", "0111001", "0000101", "0010001", "0001001", "0010111"}
+	eanR = [10]string{"1110010", "1100110", "1101100", "1000010", "1011100", "1001110", "1010000", "1000100", "1001000", "1110100"}
+	// eanParity selects L or G codes for the left half, by the first digit
+	eanParity = [10]string{"LLLLLL", "LLGLGG", "LLGGLG", "LLGGGL", "LGLLGG", "LGGLLG", "LGGGLL", "LGLGLG", "LGLGGL", "LGGLGL"}
+)
+
+// EAN13 encodes a 12 digit number plus its check digit. A 13th digit is
+// accepted when it is the correct check digit.
+func EAN13(number string) (*Linear, error) {
+	if strings.Trim(number, "0123456789") != "" || len(number) != 12 && len(number) != 13 {
+		return nil, fmt.Errorf("barcode: EAN-13 needs 12 or 13 digits, got %q", number)
+	}
+	check := EANCheckDigit(number[:12])
+	if len(number) == 13 && number[12] != check {
+		return nil, fmt.Errorf("barcode: EAN-13 %q has check digit %c, expected %c", number, number[12], check)
+	}
+	number = number[:12] + string(check)
+
+	l := &Linear{Text: number}
+	l.appendModules("101")
+	for i, p := range eanParity[number[0]-'0'] {
+		d := number[i+1] - '0'
+		if p == 'L' {
+			l.appendModules(eanL[d])
+		} else {
+			l.appendModules(eanG[d])
+		}
+	}
+	l.appendModules("01010")
+	for i := 7; i < 13; i++ {
+		l.appendModules(eanR[number[i]-'0'])
+	}
+	l.appendModules("101")
+	return l, nil
+}
+
+// UPCA encodes an 11 or 12 digit UPC-A number, which is an EAN-13 starting
+// with 0.
+func UPCA(number string) (*Linear, error) {
+	l, err := EAN13("0" + number)
+	if err != nil {
+		return nil, err
+	}
+	l.Text = l.Text[1:]
+	return l, nil
+}
+
+// EANCheckDigit computes the check digit of an EAN or UPC number given
+// without it.
+func EANCheckDigit(digits string) byte {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		// Weights alternate 3, 1 from the rightmost digit
+		if (len(digits)-1-i)%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
@@ -0,0 +1,510 @@
+package barcode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Level is the share of a QR code that can be damaged and still be read.
+type Level int
+
+const (
+	LevelL Level = iota // about 7%
+	LevelM              // about 15%
+	LevelQ              // about 25%
+	LevelH              // about 30%
+)
+
+var ErrTooLong = errors.New("barcode: content does not fit")
+
+// QRCode is an encoded QR code.
+type QRCode struct {
+	Version int
+	Level   Level
+	size    int
+	modules [][]bool
+	// function marks finder, timing, alignment and format modules, which
+	// masks leave alone
+	function [][]bool
+}
+
+func (q *QRCode) Size() (int, int) { return q.size, q.size }
+
+func (q *QRCode) Dark(x, y int) bool { return q.modules[y][x] }
+
+// QuietZone is the light border scanners need around a QR code.
+func (q *QRCode) QuietZone() int { return 4 }
+
+// Error correction codewords per block and number of blocks, indexed by level
+// and version, from ISO/IEC 18004 table 9.
+var (
+	eccPerBlock = [4][41]int{
+		{-1, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+		{-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+		{-1, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+		{-1, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	}
+	eccBlocks = [4][41]int{
+		{-1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+		{-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+		{-1, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+		{-1, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+	}
+	// formatLevel is the level as written into the format information
+	formatLevel = [4]int{1, 0, 3, 2}
+)
+
+const alphanumeric = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+type mode struct {
+	indicator int
+	countBits [3]int // versions 1-9, 10-26, 27-40
+}
+
+var (
+	modeNumeric      = mode{0x1, [3]int{10, 12, 14}}
+	modeAlphanumeric = mode{0x2, [3]int{9, 11, 13}}
+	modeByte         = mode{0x4, [3]int{8, 16, 16}}
+)
+
+func (m mode) bits(version int) int {
+	switch {
+	case version <= 9:
+		return m.countBits[0]
+	case version <= 26:
+		return m.countBits[1]
+	}
+	return m.countBits[2]
+}
+
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, value>>i&1 == 1)
+	}
+}
+
+// QR encodes content in the smallest version that fits at level, using the
+// most compact of the numeric, alphanumeric and byte modes.
+func QR(content string, level Level) (*QRCode, error) {
+	if level < LevelL || level > LevelH {
+		return nil, fmt.Errorf("barcode: unknown QR level %d", level)
+	}
+
+	m := modeByte
+	switch {
+	case content != "" && strings.Trim(content, "0123456789") == "":
+		m = modeNumeric
+	case content != "" && strings.Trim(content, alphanumeric) == "":
+		m = modeAlphanumeric
+	}
+
+	var data bitBuffer
+	switch m {
+	case modeNumeric:
+		for i := 0; i < len(content); i += 3 {
+			chunk := content[i:min(i+3, len(content))]
+			n := 0
+			for _, c := range chunk {
+				n = n*10 + int(c-'0')
+			}
+			data.append(n, len(chunk)*3+1)
+		}
+	case modeAlphanumeric:
+		for i := 0; i+1 < len(content); i += 2 {
+			data.append(strings.IndexByte(alphanumeric, content[i])*45+strings.IndexByte(alphanumeric, content[i+1]), 11)
+		}
+		if len(content)%2 == 1 {
+			data.append(strings.IndexByte(alphanumeric, content[len(content)-1]), 6)
+		}
+	default:
+		for i := 0; i < len(content); i++ {
+			data.append(int(content[i]), 8)
+		}
+	}
+
+	count := len(content)
+	version := 0
+	for v := 1; v <= 40; v++ {
+		if count < 1<<m.bits(v) && 4+m.bits(v)+len(data) <= dataCodewords(v, level)*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, fmt.Errorf("%w in a QR code: %d bytes", ErrTooLong, len(content))
+	}
+
+	var bits bitBuffer
+	bits.append(m.indicator, 4)
+	bits.append(count, m.bits(version))
+	bits = append(bits, data...)
+
+	capacity := dataCodewords(version, level) * 8
+	bits.append(0, min(4, capacity-len(bits)))
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	codewords := make([]byte, len(bits)/8)
+	for i, bit := range bits {
+		if bit {
+			codewords[i>>3] |= 1 << (7 - i&7)
+		}
+	}
+
+	q := &QRCode{Version: version, Level: level, size: version*4 + 17}
+	q.modules = grid(q.size)
+	q.function = grid(q.size)
+	q.drawFunctionPatterns()
+	q.drawCodewords(q.addErrorCorrection(codewords))
+
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		q.applyMask(mask)
+		q.drawFormatBits(mask)
+		if p := q.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		q.applyMask(mask) // masks are their own inverse
+	}
+	q.applyMask(best)
+	q.drawFormatBits(best)
+	return q, nil
+}
+
+func grid(size int) [][]bool {
+	g := make([][]bool, size)
+	for i := range g {
+		g[i] = make([]bool, size)
+	}
+	return g
+}
+
+// rawDataModules is the number of modules left for data and error correction
+// codewords once the function patterns are drawn.
+func rawDataModules(version int) int {
+	n := (16*version+128)*version + 64
+	if version >= 2 {
+		align := version/7 + 2
+		n -= (25*align-10)*align - 55
+		if version >= 7 {
+			n -= 36
+		}
+	}
+	return n
+}
+
+func dataCodewords(version int, level Level) int {
+	return rawDataModules(version)/8 - eccPerBlock[level][version]*eccBlocks[level][version]
+}
+
+func (q *QRCode) set(x, y int, dark bool) {
+	q.modules[y][x] = dark
+	q.function[y][x] = true
+}
+
+func (q *QRCode) drawFunctionPatterns() {
+	for i := 0; i < q.size; i++ {
+		q.set(6, i, i%2 == 0)
+		q.set(i, 6, i%2 == 0)
+	}
+
+	for _, c := range [][2]int{{3, 3}, {q.size - 4, 3}, {3, q.size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := c[0]+dx, c[1]+dy
+				if x >= 0 && x < q.size && y >= 0 && y < q.size {
+					d := max(abs(dx), abs(dy))
+					q.set(x, y, d != 2 && d != 4)
+				}
+			}
+		}
+	}
+
+	positions := alignmentPositions(q.Version)
+	last := len(positions) - 1
+	for i, cy := range positions {
+		for j, cx := range positions {
+			// Three corners are taken by the finder patterns
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					q.set(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// Reserve the format areas, the real bits follow once the mask is known
+	q.drawFormatBits(0)
+
+	if q.Version >= 7 {
+		rem := q.Version
+		for i := 0; i < 12; i++ {
+			rem = rem<<1 ^ (rem>>11)*0x1F25
+		}
+		bits := q.Version<<12 | rem
+		for i := 0; i < 18; i++ {
+			dark := bits>>i&1 == 1
+			a, b := q.size-11+i%3, i/3
+			q.set(a, b, dark)
+			q.set(b, a, dark)
+		}
+	}
+}
+
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	n := version/7 + 2
+	step := (version*8 + n*3 + 5) / (n*4 - 4) * 2
+	positions := make([]int, n)
+	positions[0] = 6
+	for i, pos := n-1, version*4+17-7; i > 0; i, pos = i-1, pos-step {
+		positions[i] = pos
+	}
+	return positions
+}
+
+// formatBits is the 15 bit format information for level and mask.
+func formatBits(level Level, mask int) int {
+	data := formatLevel[level]<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+func (q *QRCode) drawFormatBits(mask int) {
+	bits := formatBits(q.Level, mask)
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		q.set(8, i, bit(i))
+	}
+	q.set(8, 7, bit(6))
+	q.set(8, 8, bit(7))
+	q.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.set(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		q.set(q.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.set(8, q.size-15+i, bit(i))
+	}
+	q.set(8, q.size-8, true)
+}
+
+// addErrorCorrection splits data into blocks, appends the Reed-Solomon
+// codewords of each and interleaves the result.
+func (q *QRCode) addErrorCorrection(data []byte) []byte {
+	blocks := eccBlocks[q.Level][q.Version]
+	eccLen := eccPerBlock[q.Level][q.Version]
+	raw := rawDataModules(q.Version) / 8
+	short := blocks - raw%blocks
+	shortLen := raw / blocks
+
+	divisor := rsDivisor(eccLen)
+	all := make([][]byte, blocks)
+	for i, k := 0, 0; i < blocks; i++ {
+		n := shortLen - eccLen
+		if i >= short {
+			n++
+		}
+		block := append([]byte(nil), data[k:k+n]...)
+		k += n
+		ecc := rsRemainder(block, divisor)
+		if i < short {
+			block = append(block, 0) // evens out the lengths, skipped below
+		}
+		all[i] = append(block, ecc...)
+	}
+
+	result := make([]byte, 0, raw)
+	for i := 0; i < len(all[0]); i++ {
+		for j, block := range all {
+			if i != shortLen-eccLen || j >= short {
+				result = append(result, block[i])
+			}
+		}
+	}
+	return result
+}
+
+func (q *QRCode) drawCodewords(data []byte) {
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // skip the vertical timing pattern
+		}
+		for vert := 0; vert < q.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = q.size - 1 - vert
+				}
+				if !q.function[y][x] && i < len(data)*8 {
+					q.modules[y][x] = data[i>>3]>>(7-i&7)&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+func maskBit(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	}
+	return ((x+y)%2+x*y%3)%2 == 0
+}
+
+func (q *QRCode) applyMask(mask int) {
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			if !q.function[y][x] && maskBit(mask, x, y) {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores how hard the symbol is to scan, lower is better.
+func (q *QRCode) penalty() int {
+	p, dark := 0, 0
+	line := make([]bool, q.size)
+	for _, horizontal := range []bool{true, false} {
+		for a := 0; a < q.size; a++ {
+			for b := 0; b < q.size; b++ {
+				if horizontal {
+					line[b] = q.modules[a][b]
+				} else {
+					line[b] = q.modules[b][a]
+				}
+			}
+			p += linePenalty(line)
+		}
+	}
+
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			c := q.modules[y][x]
+			if c {
+				dark++
+			}
+			if x+1 < q.size && y+1 < q.size && c == q.modules[y][x+1] && c == q.modules[y+1][x] && c == q.modules[y+1][x+1] {
+				p += 3
+			}
+		}
+	}
+
+	total := q.size * q.size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	return p + max(k, 0)*10
+}
+
+var finderLike = [][]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+func linePenalty(line []bool) int {
+	p, run := 0, 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			p += run - 2
+		}
+		run = 1
+	}
+
+	for i := 0; i+11 <= len(line); i++ {
+		for _, pattern := range finderLike {
+			match := true
+			for j, dark := range pattern {
+				if line[i+j] != dark {
+					match = false
+					break
+				}
+			}
+			if match {
+				p += 40
+			}
+		}
+	}
+	return p
+}
+
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMul(result[j], root)
+			if j+1 < degree {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 0x02)
+	}
+	return result
+}
+
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, d := range divisor {
+			result[i] ^= gfMul(d, factor)
+		}
+	}
+	return result
+}
+
+// gfMul multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1.
+func gfMul(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
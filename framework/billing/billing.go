@@ -0,0 +1,98 @@
+// Package billing keeps customers and subscriptions in sync with Stripe.
+// Checkout and the customer portal stay on Stripe; the application learns
+// about changes from webhooks, and gates features on the synced rows:
+//
+//	r.Post("/webhooks/stripe", webhook.Receive(billing.Signature(secret), billing.HandleWebhook))
+//
+//	pro := r.Group("/reports")
+//	pro.UseBefore(billing.RequirePlan("price_pro_monthly"))
+//
+//	err := billing.ReportUsage(ctx, userID, 1) // metered plans
+//
+// Billable entities, users or organizations, are identified by the string
+// form of their key.
+package billing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/lemmego/api/config"
+
+	"github.com/lemmego/lemmego/framework/httpclient"
+)
+
+var (
+	ErrNotConfigured = errors.New("billing: services.stripe.secret is not set")
+	ErrNoCustomer    = errors.New("billing: not a Stripe customer")
+	ErrNotSubscribed = errors.New("billing: no active subscription")
+)
+
+// Stripe calls the Stripe API.
+type Stripe struct {
+	Secret string
+	// BaseURL is https://api.stripe.com unless set, e.g. for stripe-mock.
+	BaseURL string
+}
+
+// Default returns a client with the secret key of services.stripe.secret.
+func Default() (*Stripe, error) {
+	secret := config.Get("services.stripe.secret", "").(string)
+	if secret == "" {
+		return nil, ErrNotConfigured
+	}
+	return &Stripe{Secret: secret, BaseURL: config.Get("services.stripe.base_url", "").(string)}, nil
+}
+
+// do sends params form encoded, as Stripe expects, and decodes the response
+// into out.
+func (s *Stripe) do(ctx context.Context, method, path string, params url.Values, out any) error {
+	base := s.BaseURL
+	if base == "" {
+		base = "https://api.stripe.com"
+	}
+	u := strings.TrimRight(base, "/") + path
+
+	var body io.Reader
+	if method == http.MethodGet {
+		if len(params) > 0 {
+			u += "?" + params.Encode()
+		}
+	} else {
+		body = strings.NewReader(params.Encode())
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(s.Secret, "")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := httpclient.Do(ctx, req)
+	if err != nil {
+		return fmt.Errorf("billing: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&e)
+		return fmt.Errorf("billing: %s %s returned %s: %s", method, path, resp.Status, e.Error.Message)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
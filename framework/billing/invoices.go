@@ -0,0 +1,101 @@
+package billing
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/lemmego/lemmego/framework/money"
+)
+
+// Invoice is a Stripe invoice of a customer.
+type Invoice struct {
+	ID     string      `json:"id"`
+	Number string      `json:"number"`
+	Status string      `json:"status"`
+	Total  money.Money `json:"total"`
+	// HostedURL is the invoice page on Stripe, PDF its download.
+	HostedURL string    `json:"hosted_url"`
+	PDF       string    `json:"pdf"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type stripeInvoice struct {
+	ID               string `json:"id"`
+	Customer         string `json:"customer"`
+	Number           string `json:"number"`
+	Status           string `json:"status"`
+	Total            int64  `json:"total"`
+	Currency         string `json:"currency"`
+	HostedInvoiceURL string `json:"hosted_invoice_url"`
+	InvoicePDF       string `json:"invoice_pdf"`
+	Created          int64  `json:"created"`
+}
+
+func (i *stripeInvoice) invoice() (*Invoice, error) {
+	total, err := money.FromMinor(i.Total, i.Currency)
+	if err != nil {
+		return nil, err
+	}
+	return &Invoice{
+		ID:        i.ID,
+		Number:    i.Number,
+		Status:    i.Status,
+		Total:     total,
+		HostedURL: i.HostedInvoiceURL,
+		PDF:       i.InvoicePDF,
+		CreatedAt: time.Unix(i.Created, 0),
+	}, nil
+}
+
+// Invoices lists the latest invoices of billableID, at most limit of them.
+func Invoices(ctx context.Context, billableID string, limit int) ([]Invoice, error) {
+	c, err := CustomerOf(ctx, billableID)
+	if err != nil {
+		return nil, err
+	}
+	s, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Data []stripeInvoice `json:"data"`
+	}
+	params := url.Values{"customer": {c.StripeID}, "limit": {strconv.Itoa(limit)}}
+	if err := s.do(ctx, http.MethodGet, "/v1/invoices", params, &list); err != nil {
+		return nil, err
+	}
+
+	invoices := make([]Invoice, 0, len(list.Data))
+	for _, si := range list.Data {
+		inv, err := si.invoice()
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, *inv)
+	}
+	return invoices, nil
+}
+
+// FindInvoice returns an invoice of billableID, or ErrNoCustomer when it
+// belongs to another customer.
+func FindInvoice(ctx context.Context, billableID, id string) (*Invoice, error) {
+	c, err := CustomerOf(ctx, billableID)
+	if err != nil {
+		return nil, err
+	}
+	s, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	var si stripeInvoice
+	if err := s.do(ctx, http.MethodGet, "/v1/invoices/"+url.PathEscape(id), nil, &si); err != nil {
+		return nil, err
+	}
+	if si.Customer != c.StripeID {
+		return nil, ErrNoCustomer
+	}
+	return si.invoice()
+}
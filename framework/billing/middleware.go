@@ -0,0 +1,45 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/lemmego/api/app"
+
+	"github.com/lemmego/lemmego/framework/auth"
+)
+
+// BillableID returns the billable of a request, the signed in user by
+// default. Applications billing organizations return the current one.
+var BillableID = func(c *app.Context) string {
+	return c.GetSessionString(auth.UserKey)
+}
+
+type subscriptionKey struct{}
+
+// RequirePlan limits a route to billables subscribed to one of prices, or to
+// any plan when none is given, answering 402 Payment Required otherwise.
+func RequirePlan(prices ...string) app.Handler {
+	return func(c *app.Context) error {
+		id := BillableID(c)
+		if id == "" {
+			return c.Unauthorized(errors.New("sign in to continue"))
+		}
+		sub, err := Subscribed(c.RequestContext(), id, prices...)
+		if errors.Is(err, ErrNotSubscribed) {
+			return c.Error(http.StatusPaymentRequired, err)
+		}
+		if err != nil {
+			return err
+		}
+		c.SetRequest(c.Request().WithContext(context.WithValue(c.RequestContext(), subscriptionKey{}, sub)))
+		return c.Next()
+	}
+}
+
+// SubscriptionFrom returns the subscription checked by RequirePlan.
+func SubscriptionFrom(ctx context.Context) (*Subscription, bool) {
+	sub, ok := ctx.Value(subscriptionKey{}).(*Subscription)
+	return sub, ok
+}
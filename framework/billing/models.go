@@ -0,0 +1,133 @@
+package billing
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/lemmego/lemmego/framework/repo"
+)
+
+// Customer links a billable to its Stripe customer.
+type Customer struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	BillableID string    `json:"billable_id"`
+	StripeID   string    `json:"stripe_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (Customer) TableName() string {
+	return "billing_customers"
+}
+
+// Subscription statuses, as named by Stripe.
+const (
+	StatusActive     = "active"
+	StatusTrialing   = "trialing"
+	StatusPastDue    = "past_due"
+	StatusCanceled   = "canceled"
+	StatusIncomplete = "incomplete"
+	StatusUnpaid     = "unpaid"
+)
+
+// Subscription mirrors a Stripe subscription, kept up to date by
+// HandleWebhook.
+type Subscription struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	BillableID string `json:"billable_id"`
+	StripeID   string `json:"stripe_id"`
+	Status     string `json:"status"`
+	// Price is the Stripe price of the plan.
+	Price    string `json:"price"`
+	Quantity int    `json:"quantity"`
+	// MeteredItem is the subscription item usage is reported to, if the
+	// plan has a metered price.
+	MeteredItem string     `json:"metered_item"`
+	TrialEndsAt *time.Time `json:"trial_ends_at"`
+	// EndsAt is set once the subscription is canceled; it stays usable until
+	// then.
+	EndsAt    *time.Time `json:"ends_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func (Subscription) TableName() string {
+	return "billing_subscriptions"
+}
+
+// OnTrial reports whether the subscription is in its trial period.
+func (s *Subscription) OnTrial() bool {
+	return s.TrialEndsAt != nil && time.Now().Before(*s.TrialEndsAt)
+}
+
+// OnGracePeriod reports whether the subscription was canceled but runs until
+// the end of the paid period.
+func (s *Subscription) OnGracePeriod() bool {
+	return s.EndsAt != nil && time.Now().Before(*s.EndsAt)
+}
+
+// Valid reports whether the subscription grants access to its plan.
+func (s *Subscription) Valid() bool {
+	if s.EndsAt != nil && !s.OnGracePeriod() {
+		return false
+	}
+	return s.Status == StatusActive || s.Status == StatusTrialing
+}
+
+// Subscribed returns a valid subscription of billableID to one of prices, or
+// to any plan when none is given.
+func Subscribed(ctx context.Context, billableID string, prices ...string) (*Subscription, error) {
+	subs, err := repo.New[Subscription](ctx).Where("billable_id = ?", billableID).Order("id DESC").Find()
+	if err != nil {
+		return nil, err
+	}
+	for i := range subs {
+		if subs[i].Valid() && (len(prices) == 0 || slices.Contains(prices, subs[i].Price)) {
+			return &subs[i], nil
+		}
+	}
+	return nil, ErrNotSubscribed
+}
+
+// CustomerOf returns the Stripe customer of billableID, or ErrNoCustomer.
+func CustomerOf(ctx context.Context, billableID string) (*Customer, error) {
+	customers, err := repo.New[Customer](ctx).Where("billable_id = ?", billableID).Limit(1).Find()
+	if err != nil {
+		return nil, err
+	}
+	if len(customers) == 0 {
+		return nil, ErrNoCustomer
+	}
+	return &customers[0], nil
+}
+
+// CreateCustomer returns the Stripe customer of billableID, creating it on
+// Stripe the first time. Pass its StripeID to Checkout sessions so their
+// subscriptions sync back to billableID.
+func CreateCustomer(ctx context.Context, billableID, email string) (*Customer, error) {
+	c, err := CustomerOf(ctx, billableID)
+	if err != ErrNoCustomer {
+		return c, err
+	}
+
+	s, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	var created struct {
+		ID string `json:"id"`
+	}
+	params := url.Values{"email": {email}, "metadata[billable_id]": {billableID}}
+	if err := s.do(ctx, http.MethodPost, "/v1/customers", params, &created); err != nil {
+		return nil, err
+	}
+
+	c = &Customer{BillableID: billableID, StripeID: created.ID}
+	if err := repo.New[Customer](ctx).Create(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,76 @@
+package billing
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ReportUsage adds quantity to the current period's usage of billableID's
+// metered plan.
+func ReportUsage(ctx context.Context, billableID string, quantity int64) error {
+	sub, err := meteredSubscription(ctx, billableID)
+	if err != nil {
+		return err
+	}
+	s, err := Default()
+	if err != nil {
+		return err
+	}
+	params := url.Values{
+		"quantity":  {strconv.FormatInt(quantity, 10)},
+		"timestamp": {strconv.FormatInt(time.Now().Unix(), 10)},
+		"action":    {"increment"},
+	}
+	return s.do(ctx, http.MethodPost, "/v1/subscription_items/"+url.PathEscape(sub.MeteredItem)+"/usage_records", params, nil)
+}
+
+// UsageSummary is the usage reported in a billing period.
+type UsageSummary struct {
+	Total       int64     `json:"total"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+}
+
+// Usage returns the usage reported so far in the current period.
+func Usage(ctx context.Context, billableID string) (*UsageSummary, error) {
+	sub, err := meteredSubscription(ctx, billableID)
+	if err != nil {
+		return nil, err
+	}
+	s, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Data []struct {
+			TotalUsage int64 `json:"total_usage"`
+			Period     struct {
+				Start int64 `json:"start"`
+				End   int64 `json:"end"`
+			} `json:"period"`
+		} `json:"data"`
+	}
+	path := "/v1/subscription_items/" + url.PathEscape(sub.MeteredItem) + "/usage_record_summaries"
+	if err := s.do(ctx, http.MethodGet, path, url.Values{"limit": {"1"}}, &list); err != nil {
+		return nil, err
+	}
+	if len(list.Data) == 0 {
+		return &UsageSummary{}, nil
+	}
+	d := list.Data[0]
+	return &UsageSummary{Total: d.TotalUsage, PeriodStart: time.Unix(d.Period.Start, 0), PeriodEnd: time.Unix(d.Period.End, 0)}, nil
+}
+
+func meteredSubscription(ctx context.Context, billableID string) (*Subscription, error) {
+	subs, err := Subscribed(ctx, billableID)
+	if err != nil {
+		return nil, err
+	}
+	if subs.MeteredItem == "" {
+		return nil, ErrNotSubscribed
+	}
+	return subs, nil
+}
@@ -0,0 +1,195 @@
+package billing
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/lemmego/lemmego/framework/events"
+	"github.com/lemmego/lemmego/framework/repo"
+	"github.com/lemmego/lemmego/framework/webhook"
+)
+
+// SignatureTolerance is how old a delivery may be, against replays.
+var SignatureTolerance = 5 * time.Minute
+
+// Signature verifies the Stripe-Signature header with the endpoint's
+// signing secret, services.stripe.webhook_secret.
+func Signature(secret string) webhook.Verifier {
+	return func(r *http.Request, body []byte) error {
+		var ts string
+		var sigs [][]byte
+		for _, part := range strings.Split(r.Header.Get("Stripe-Signature"), ",") {
+			k, v, _ := strings.Cut(part, "=")
+			switch k {
+			case "t":
+				ts = v
+			case "v1":
+				if sig, err := hex.DecodeString(v); err == nil {
+					sigs = append(sigs, sig)
+				}
+			}
+		}
+
+		sec, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil || time.Since(time.Unix(sec, 0)).Abs() > SignatureTolerance {
+			return webhook.ErrSignature
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(ts + "."))
+		mac.Write(body)
+		want := mac.Sum(nil)
+		for _, sig := range sigs {
+			if hmac.Equal(sig, want) {
+				return nil
+			}
+		}
+		return webhook.ErrSignature
+	}
+}
+
+// WebhookEvent is dispatched for every Stripe event received, named
+// "stripe." and the event type, e.g. "stripe.invoice.payment_failed", after
+// the subscription tables are synced.
+type WebhookEvent struct {
+	ID     string
+	Type   string
+	Object json.RawMessage
+}
+
+func (e *WebhookEvent) Name() string {
+	return "stripe." + e.Type
+}
+
+type stripeSubscription struct {
+	ID       string `json:"id"`
+	Customer string `json:"customer"`
+	Status   string `json:"status"`
+	TrialEnd int64  `json:"trial_end"`
+	CancelAt int64  `json:"cancel_at"`
+	EndedAt  int64  `json:"ended_at"`
+	Items    struct {
+		Data []struct {
+			ID       string `json:"id"`
+			Quantity int    `json:"quantity"`
+			Price    struct {
+				ID        string `json:"id"`
+				Recurring struct {
+					UsageType string `json:"usage_type"`
+				} `json:"recurring"`
+			} `json:"price"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+// HandleWebhook syncs subscriptions from a Stripe event and dispatches it as
+// a WebhookEvent.
+func HandleWebhook(ctx context.Context, body []byte) error {
+	var e struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			Object json.RawMessage `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &e); err != nil {
+		return err
+	}
+
+	var err error
+	switch e.Type {
+	case "customer.subscription.created", "customer.subscription.updated", "customer.subscription.deleted":
+		var s stripeSubscription
+		if err = json.Unmarshal(e.Data.Object, &s); err == nil {
+			err = syncSubscription(ctx, &s)
+		}
+	case "customer.deleted":
+		var c struct {
+			ID string `json:"id"`
+		}
+		if err = json.Unmarshal(e.Data.Object, &c); err == nil {
+			err = deleteCustomer(ctx, c.ID)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return events.Dispatch(ctx, &WebhookEvent{ID: e.ID, Type: e.Type, Object: e.Data.Object})
+}
+
+func unixTime(sec int64) *time.Time {
+	if sec == 0 {
+		return nil
+	}
+	t := time.Unix(sec, 0)
+	return &t
+}
+
+// syncSubscription upserts the subscription of a known customer. Customers
+// created outside CreateCustomer are not linked to a billable, so their
+// subscriptions are left to WebhookEvent listeners.
+func syncSubscription(ctx context.Context, s *stripeSubscription) error {
+	return repo.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		customers, err := repo.From[Customer](tx).Where("stripe_id = ?", s.Customer).Limit(1).Find()
+		if err != nil || len(customers) == 0 {
+			return err
+		}
+
+		subs := repo.From[Subscription](tx)
+		found, err := subs.Where("stripe_id = ?", s.ID).Limit(1).Find()
+		if err != nil {
+			return err
+		}
+		sub := &Subscription{BillableID: customers[0].BillableID, StripeID: s.ID}
+		if len(found) > 0 {
+			sub = &found[0]
+		}
+
+		sub.Status = s.Status
+		sub.TrialEndsAt = unixTime(s.TrialEnd)
+		sub.EndsAt = unixTime(s.EndedAt)
+		if sub.EndsAt == nil {
+			sub.EndsAt = unixTime(s.CancelAt)
+		}
+		sub.Price, sub.Quantity, sub.MeteredItem = "", 0, ""
+		for _, item := range s.Items.Data {
+			if item.Price.Recurring.UsageType == "metered" {
+				if sub.MeteredItem == "" {
+					sub.MeteredItem = item.ID
+				}
+			} else if sub.Price == "" {
+				sub.Price, sub.Quantity = item.Price.ID, item.Quantity
+			}
+		}
+		// Plans billed on usage alone
+		if sub.Price == "" && len(s.Items.Data) > 0 {
+			sub.Price = s.Items.Data[0].Price.ID
+		}
+
+		if sub.ID == 0 {
+			return subs.Create(sub)
+		}
+		return subs.Save(sub)
+	})
+}
+
+func deleteCustomer(ctx context.Context, stripeID string) error {
+	return repo.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		customers, err := repo.From[Customer](tx).Where("stripe_id = ?", stripeID).Limit(1).Find()
+		if err != nil || len(customers) == 0 {
+			return err
+		}
+		if err := tx.Where("billable_id = ?", customers[0].BillableID).Delete(&Subscription{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&customers[0]).Error
+	})
+}
@@ -0,0 +1,378 @@
+// Package bind fills request input structs from every part of a request
+// at once: path parameters, the query, headers, cookies, form fields, JSON
+// bodies and uploaded files, all declared with the `in` tag the framework's
+// inputs already use.
+//
+//	type UpdateAvatar struct {
+//		*app.BaseInput
+//		ID       uint                  `in:"path=id"`
+//		ClientID string                `in:"query=client_id;header=X-Client-Id;required"`
+//		Locale   string                `in:"cookie=locale;default=en"`
+//		Caption  string                `in:"form=caption" validate:"max=200"`
+//		Avatar   *multipart.FileHeader `in:"file=avatar;required"`
+//	}
+//
+//	r.Post("/users/{id}/avatar", func(c *app.Context) error {
+//		in := &UpdateAvatar{}
+//		if err := bind.Validate(c, in); err != nil {
+//			return err
+//		}
+//		...
+//	})
+//
+// A directive may list several keys, and a field may list several
+// directives; the first one holding a value wins. Form keys are read from
+// the JSON object when the request body is JSON, as are fields with a json
+// tag and no in tag, so one input serves both HTML forms and API clients.
+// body=json decodes the whole body into the field instead.
+//
+// Values that do not convert to the field's type, and missing required
+// ones, are returned together as shared.ValidationErrors under the names
+// validation.ErrorKey gives, which the framework renders like any other
+// validation failure. Unreadable bodies are a *req.MalformedRequest.
+package bind
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/req"
+	"github.com/lemmego/api/shared"
+
+	"github.com/lemmego/lemmego/framework/validation"
+)
+
+// MaxMemory is how much of a multipart body is kept in memory, the rest of
+// the uploaded files going to temporary files.
+var MaxMemory int64 = 32 << 20
+
+// Sources a directive may read from.
+const (
+	Path   = "path"
+	Query  = "query"
+	Header = "header"
+	Cookie = "cookie"
+	Form   = "form"
+	File   = "file"
+	Body   = "body"
+)
+
+var (
+	fileHeaderType  = reflect.TypeFor[*multipart.FileHeader]()
+	fileHeadersType = reflect.TypeFor[[]*multipart.FileHeader]()
+)
+
+type directive struct {
+	source string
+	keys   []string
+}
+
+type fieldPlan struct {
+	index      []int
+	name       string
+	directives []directive
+	def        *string
+	required   bool
+}
+
+type plan struct {
+	fields []fieldPlan
+	err    error
+}
+
+var plans sync.Map // reflect.Type -> *plan
+
+// Compile builds the plan of an input type ahead of time, so malformed tags
+// fail at startup rather than on the first request.
+func Compile(input any) error {
+	t := reflect.TypeOf(input)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return planFor(t).err
+}
+
+func planFor(t reflect.Type) *plan {
+	if p, ok := plans.Load(t); ok {
+		return p.(*plan)
+	}
+	p, _ := plans.LoadOrStore(t, compile(t))
+	return p.(*plan)
+}
+
+func compile(t reflect.Type) *plan {
+	p := &plan{}
+	for _, sf := range reflect.VisibleFields(t) {
+		if !sf.IsExported() || sf.Anonymous {
+			continue
+		}
+		fp, err := compileField(sf)
+		if err != nil {
+			p.err = fmt.Errorf("bind: %s.%s: %w", t.Name(), sf.Name, err)
+			return p
+		}
+		if fp != nil {
+			p.fields = append(p.fields, *fp)
+		}
+	}
+	return p
+}
+
+func compileField(sf reflect.StructField) (*fieldPlan, error) {
+	fp := &fieldPlan{index: sf.Index, name: validation.ErrorKey(sf)}
+
+	tag, ok := sf.Tag.Lookup("in")
+	if !ok {
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			return nil, nil
+		}
+		fp.directives = []directive{{source: Form, keys: []string{name}}}
+		return fp, nil
+	}
+	if tag == "-" {
+		return nil, nil
+	}
+
+	for _, spec := range strings.Split(tag, ";") {
+		source, arg, _ := strings.Cut(strings.TrimSpace(spec), "=")
+		switch source {
+		case "":
+		case "required":
+			fp.required = true
+		case "default":
+			fp.def = &arg
+		case Body:
+			if arg != "" && arg != "json" {
+				return nil, fmt.Errorf("unsupported body format %q", arg)
+			}
+			fp.directives = append(fp.directives, directive{source: Body})
+		case Path, Query, Header, Cookie, Form, File:
+			if arg == "" {
+				return nil, fmt.Errorf("%s needs a key", source)
+			}
+			if source == File && sf.Type != fileHeaderType && sf.Type != fileHeadersType {
+				return nil, errors.New("file fields are *multipart.FileHeader or []*multipart.FileHeader")
+			}
+			fp.directives = append(fp.directives, directive{source: source, keys: strings.Split(arg, ",")})
+		default:
+			return nil, fmt.Errorf("unknown directive %q", source)
+		}
+	}
+	return fp, nil
+}
+
+// Bind fills input, a pointer to a struct, from the request. See the
+// package documentation for the tags it reads.
+func Bind(c *app.Context, input any) error {
+	rv := reflect.ValueOf(input)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind: %T is not a pointer to a struct", input)
+	}
+	p := planFor(rv.Elem().Type())
+	if p.err != nil {
+		return p.err
+	}
+
+	src, err := read(c.Request())
+	if err != nil {
+		return err
+	}
+
+	errs := shared.ValidationErrors{}
+	for _, fp := range p.fields {
+		v := rv.Elem().FieldByIndex(fp.index)
+		found, err := src.fill(c, v, fp)
+		switch {
+		case err != nil:
+			errs[fp.name] = append(errs[fp.name], err.Error())
+		case !found && fp.def != nil:
+			if err := Text(v, *fp.def); err != nil {
+				return fmt.Errorf("bind: default of %s: %w", fp.name, err)
+			}
+		case !found && fp.required:
+			errs[fp.name] = append(errs[fp.name], "This field is required")
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Validate binds input, gives it the *app.BaseInput the framework's inputs
+// embed, and validates it: with its own Validate method when it has one, or
+// with its validate tags. Inputs implementing validation.Bagged report
+// their errors in their bag.
+func Validate(c *app.Context, input any) error {
+	err := Bind(c, input)
+	if err == nil {
+		err = validate(c, input)
+	}
+	if b, ok := input.(validation.Bagged); ok {
+		return validation.InBag(b.ErrorBag(), err)
+	}
+	return err
+}
+
+func validate(c *app.Context, input any) error {
+	base := reflect.ValueOf(input).Elem().FieldByName("BaseInput")
+	if base.IsValid() && base.CanSet() {
+		base.Set(reflect.ValueOf(&app.BaseInput{App: c.App(), Ctx: c, Validator: app.NewValidator(c.App())}))
+	}
+
+	if v, ok := input.(req.Validator); ok {
+		return v.Validate()
+	}
+	return validation.Struct(app.NewValidator(c.App()), input)
+}
+
+// source is the parsed request, read once for all fields.
+type source struct {
+	r     *http.Request
+	json  map[string]json.RawMessage
+	body  []byte
+	files map[string][]*multipart.FileHeader
+}
+
+func read(r *http.Request) (*source, error) {
+	src := &source{r: r}
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case ct == "application/json" || strings.HasSuffix(ct, "+json"):
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, &req.MalformedRequest{Status: http.StatusBadRequest, Message: "Request body could not be read"}
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		src.body = body
+		if len(bytes.TrimSpace(body)) > 0 {
+			if !json.Valid(body) {
+				return nil, &req.MalformedRequest{Status: http.StatusBadRequest, Message: "Request body contains badly-formed JSON"}
+			}
+			// Bodies other than objects are only read by body=json fields.
+			_ = json.Unmarshal(body, &src.json)
+		}
+	case ct == "multipart/form-data":
+		if err := r.ParseMultipartForm(MaxMemory); err != nil {
+			return nil, &req.MalformedRequest{Status: http.StatusBadRequest, Message: "Request body could not be read"}
+		}
+		src.files = r.MultipartForm.File
+	default:
+		if err := r.ParseForm(); err != nil {
+			return nil, &req.MalformedRequest{Status: http.StatusBadRequest, Message: "Request body could not be read"}
+		}
+	}
+	return src, nil
+}
+
+// fill sets v from the first directive holding a value, and reports
+// whether one did.
+func (s *source) fill(c *app.Context, v reflect.Value, fp fieldPlan) (bool, error) {
+	for _, d := range fp.directives {
+		if d.source == Body {
+			if len(bytes.TrimSpace(s.body)) == 0 {
+				continue
+			}
+			return true, decode(v, s.body)
+		}
+		for _, key := range d.keys {
+			if d.source == File {
+				if files := s.files[key]; len(files) > 0 {
+					if v.Type() == fileHeaderType {
+						v.Set(reflect.ValueOf(files[0]))
+					} else {
+						v.Set(reflect.ValueOf(files))
+					}
+					return true, nil
+				}
+				continue
+			}
+			if d.source == Form && s.json != nil {
+				if raw, ok := s.json[key]; ok {
+					return true, decode(v, raw)
+				}
+				continue
+			}
+			if values := s.values(c, d.source, key); len(values) > 0 {
+				return true, texts(v, values)
+			}
+		}
+	}
+	return false, nil
+}
+
+func (s *source) values(c *app.Context, source, key string) []string {
+	switch source {
+	case Path:
+		if v := c.Param(key); v != "" {
+			return []string{v}
+		}
+	case Query:
+		return s.r.URL.Query()[key]
+	case Header:
+		return s.r.Header.Values(key)
+	case Cookie:
+		if ck, err := s.r.Cookie(key); err == nil {
+			return []string{ck.Value}
+		}
+	case Form:
+		if s.r.Form != nil {
+			return s.r.Form[key]
+		}
+	}
+	return nil
+}
+
+// decode reads a JSON value into v. Strings are also accepted for scalar
+// fields, as clients often send numbers quoted.
+func decode(v reflect.Value, raw json.RawMessage) error {
+	ptr := reflect.New(v.Type())
+	err := json.Unmarshal(raw, ptr.Interface())
+	if err == nil {
+		v.Set(ptr.Elem())
+		return nil
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil && v.Kind() != reflect.Slice && v.Kind() != reflect.Map && v.Kind() != reflect.Struct {
+		return Text(v, s)
+	}
+	var ute *json.UnmarshalTypeError
+	if errors.As(err, &ute) {
+		return errors.New(typeMessage(ute.Type))
+	}
+	return errors.New("This field is not valid")
+}
+
+func typeMessage(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "This field must be a whole number"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "This field must be a positive whole number"
+	case reflect.Float32, reflect.Float64:
+		return "This field must be a number"
+	case reflect.Bool:
+		return "This field must be true or false"
+	case reflect.String:
+		return "This field must be a string"
+	case reflect.Slice, reflect.Array:
+		return "This field must be a list"
+	case reflect.Map, reflect.Struct:
+		return "This field must be an object"
+	}
+	return "This field is not valid"
+}
@@ -0,0 +1,112 @@
+package bind
+
+import (
+	"encoding"
+	"errors"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	textUnmarshaler = reflect.TypeFor[encoding.TextUnmarshaler]()
+	timeType        = reflect.TypeFor[time.Time]()
+)
+
+// Text parses raw into v, which must be settable. Times are RFC 3339 or
+// plain dates, and types implementing encoding.TextUnmarshaler parse
+// themselves. Errors are the messages shown to the user.
+func Text(v reflect.Value, raw string) error {
+	if v.Kind() == reflect.Pointer {
+		if raw == "" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		ptr := reflect.New(v.Type().Elem())
+		if err := Text(ptr.Elem(), raw); err != nil {
+			return err
+		}
+		v.Set(ptr)
+		return nil
+	}
+
+	if v.Type() == timeType {
+		if raw == "" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04", time.DateOnly} {
+			if t, err := time.ParseInLocation(layout, raw, time.Local); err == nil {
+				v.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return errors.New("This field must be a valid date")
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshaler) {
+		if raw == "" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw)); err != nil {
+			return errors.New("This field is not valid")
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		v.SetBool(raw != "" && raw != "0" && raw != "false" && raw != "off")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if raw == "" {
+			v.SetInt(0)
+			return nil
+		}
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("This field must be a whole number")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if raw == "" {
+			v.SetUint(0)
+			return nil
+		}
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("This field must be a positive whole number")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if raw == "" {
+			v.SetFloat(0)
+			return nil
+		}
+		n, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return errors.New("This field must be a number")
+		}
+		v.SetFloat(n)
+	default:
+		return errors.New("This field cannot be set")
+	}
+	return nil
+}
+
+// texts parses several values into v, a slice, or the first one into
+// anything else.
+func texts(v reflect.Value, raw []string) error {
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 || v.Addr().Type().Implements(textUnmarshaler) {
+		return Text(v, raw[0])
+	}
+	s := reflect.MakeSlice(v.Type(), len(raw), len(raw))
+	for i, r := range raw {
+		if err := Text(s.Index(i), r); err != nil {
+			return err
+		}
+	}
+	v.Set(s)
+	return nil
+}
@@ -0,0 +1,33 @@
+package bind
+
+import (
+	"context"
+
+	"github.com/lemmego/api/app"
+)
+
+type inputKey[T any] struct{}
+
+// Input binds and validates a *T before the route's handler runs, which
+// reads it with Get. Invalid requests never reach the handler.
+//
+//	r.Post("/users/{id}/avatar", updateAvatar).UseBefore(bind.Input[UpdateAvatar]())
+func Input[T any]() app.Handler {
+	if err := Compile(new(T)); err != nil {
+		panic(err)
+	}
+	return func(c *app.Context) error {
+		in := new(T)
+		if err := Validate(c, in); err != nil {
+			return err
+		}
+		c.SetRequest(c.Request().WithContext(context.WithValue(c.RequestContext(), inputKey[T]{}, in)))
+		return c.Next()
+	}
+}
+
+// Get returns the input bound by Input[T], or nil outside such a route.
+func Get[T any](c *app.Context) *T {
+	in, _ := c.RequestContext().Value(inputKey[T]{}).(*T)
+	return in
+}
@@ -0,0 +1,217 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lemmego/api/cache"
+)
+
+// Store is the framework's cache.Store with tag based invalidation, so a group
+// of entries (e.g. everything derived from the posts table) is dropped at once.
+type Store interface {
+	cache.Store
+	// Tag attaches tags to an existing key.
+	Tag(key string, tags ...string)
+	// FlushTags forgets every key carrying any of the tags.
+	FlushTags(tags ...string)
+}
+
+var (
+	mu           sync.RWMutex
+	defaultStore Store = NewMemoryStore("")
+)
+
+// Default returns the store used by the render and repo caches.
+func Default() Store {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultStore
+}
+
+// SetDefault replaces the store used by the render and repo caches.
+func SetDefault(s Store) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultStore = s
+}
+
+// Remember returns the value under key, calling fn and storing its result for
+// ttl when it is missing. Errors are returned and not cached.
+func Remember[T any](s Store, key string, ttl time.Duration, fn func() (T, error), tags ...string) (T, error) {
+	if v, ok := s.Get(key).(T); ok {
+		return v, nil
+	}
+
+	v, err := fn()
+	if err != nil {
+		return v, err
+	}
+
+	if ttl > 0 {
+		s.Put(key, v, int(ttl.Seconds()))
+	} else {
+		s.Forever(key, v)
+	}
+	if len(tags) > 0 {
+		s.Tag(key, tags...)
+	}
+	return v, nil
+}
+
+type memoryItem struct {
+	value   any
+	expires time.Time
+}
+
+func (i memoryItem) expired(now time.Time) bool {
+	return !i.expires.IsZero() && now.After(i.expires)
+}
+
+// MemoryStore keeps entries in process memory. Expired entries are dropped
+// when read or when the store is swept.
+type MemoryStore struct {
+	prefix string
+	mu     sync.Mutex
+	items  map[string]memoryItem
+	tags   map[string]map[string]struct{}
+}
+
+func NewMemoryStore(prefix string) *MemoryStore {
+	s := &MemoryStore{
+		prefix: prefix,
+		items:  make(map[string]memoryItem),
+		tags:   make(map[string]map[string]struct{}),
+	}
+	go s.sweep(time.Minute)
+	return s
+}
+
+func (s *MemoryStore) sweep(every time.Duration) {
+	for range time.Tick(every) {
+		now := time.Now()
+		s.mu.Lock()
+		for k, item := range s.items {
+			if item.expired(now) {
+				delete(s.items, k)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *MemoryStore) Get(key string) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(key)
+}
+
+func (s *MemoryStore) get(key string) interface{} {
+	item, ok := s.items[s.prefix+key]
+	if !ok {
+		return nil
+	}
+	if item.expired(time.Now()) {
+		delete(s.items, s.prefix+key)
+		return nil
+	}
+	return item.value
+}
+
+func (s *MemoryStore) Many(keys []string) map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		values[key] = s.get(key)
+	}
+	return values
+}
+
+func (s *MemoryStore) Put(key string, value interface{}, seconds int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(key, value, seconds)
+}
+
+func (s *MemoryStore) put(key string, value interface{}, seconds int) {
+	item := memoryItem{value: value}
+	if seconds > 0 {
+		item.expires = time.Now().Add(time.Duration(seconds) * time.Second)
+	}
+	s.items[s.prefix+key] = item
+}
+
+func (s *MemoryStore) PutMany(values map[string]interface{}, seconds int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, value := range values {
+		s.put(key, value, seconds)
+	}
+}
+
+func (s *MemoryStore) Increment(key string, value int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, _ := s.get(key).(int)
+	n += value
+	item, ok := s.items[s.prefix+key]
+	if !ok {
+		item = memoryItem{}
+	}
+	item.value = n
+	s.items[s.prefix+key] = item
+	return n
+}
+
+func (s *MemoryStore) Decrement(key string, value int) int {
+	return s.Increment(key, -value)
+}
+
+func (s *MemoryStore) Forever(key string, value interface{}) {
+	s.Put(key, value, 0)
+}
+
+func (s *MemoryStore) Forget(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.items[s.prefix+key]
+	delete(s.items, s.prefix+key)
+	return ok
+}
+
+func (s *MemoryStore) Flush() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = make(map[string]memoryItem)
+	s.tags = make(map[string]map[string]struct{})
+	return true
+}
+
+func (s *MemoryStore) GetPrefix() string {
+	return s.prefix
+}
+
+func (s *MemoryStore) Tag(key string, tags ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tag := range tags {
+		keys, ok := s.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			s.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+func (s *MemoryStore) FlushTags(tags ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tag := range tags {
+		for key := range s.tags[tag] {
+			delete(s.items, s.prefix+key)
+		}
+		delete(s.tags, tag)
+	}
+}
@@ -0,0 +1,145 @@
+// Package capture keeps the last requests served, with their responses, in
+// a ring buffer for debugging. They can be exported as HAR, to be opened in
+// the network panel of a browser, or replayed against the application to
+// reproduce a bug a user reported:
+//
+//	GET  /debug/requests              the captured requests, newest first
+//	GET  /debug/requests/har          every one of them as HAR, ?id= for one
+//	POST /debug/requests/{id}/replay  sends the request again
+//
+// Capturing is meant for development and staging: requests are kept as
+// sent, cookies and all, and only scrubbed when exported. Enable it with
+// server.capture.enabled and middleware.Capture.
+package capture
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lemmego/api/config"
+)
+
+// Exchange is a captured request and the response it got.
+type Exchange struct {
+	ID       string        `json:"id"`
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"`
+	Request  Request       `json:"request"`
+	Response Response      `json:"response"`
+}
+
+// Request is a captured request.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Proto  string      `json:"proto"`
+	Header http.Header `json:"header"`
+	Body   Body        `json:"body"`
+}
+
+// Response is a captured response.
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   Body        `json:"body"`
+}
+
+// Body is a captured body. Bodies past the size limit keep their start
+// only, and those of binary content types, such as images and uploads, are
+// not kept at all.
+type Body struct {
+	Data []byte `json:"data,omitempty"`
+	// Size is the size of the whole body.
+	Size      int64 `json:"size"`
+	Truncated bool  `json:"truncated,omitempty"`
+	Omitted   bool  `json:"omitted,omitempty"`
+}
+
+// Complete reports whether the body was kept whole.
+func (b Body) Complete() bool {
+	return !b.Truncated && !b.Omitted
+}
+
+// binaryTypes are the content types whose bodies are not kept.
+var binaryTypes = []string{"image/", "audio/", "video/", "font/", "multipart/", "application/octet-stream", "application/pdf", "application/zip", "application/gzip"}
+
+// Binary reports whether bodies of contentType are omitted.
+func Binary(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, t := range binaryTypes {
+		if strings.HasPrefix(contentType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Buffer keeps the last exchanges added to it.
+type Buffer struct {
+	mu    sync.Mutex
+	items []*Exchange
+	next  int
+	seq   uint64
+}
+
+// NewBuffer returns a buffer keeping size exchanges.
+func NewBuffer(size int) *Buffer {
+	if size < 1 {
+		size = 1
+	}
+	return &Buffer{items: make([]*Exchange, 0, size)}
+}
+
+// Add keeps e, numbering it, in place of the oldest exchange once full.
+func (b *Buffer) Add(e *Exchange) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	e.ID = strconv.FormatUint(b.seq, 10)
+	if len(b.items) < cap(b.items) {
+		b.items = append(b.items, e)
+		return
+	}
+	b.items[b.next] = e
+	b.next = (b.next + 1) % len(b.items)
+}
+
+// All returns the exchanges kept, newest first.
+func (b *Buffer) All() []*Exchange {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	all := make([]*Exchange, 0, len(b.items))
+	for i := len(b.items) - 1; i >= 0; i-- {
+		all = append(all, b.items[(b.next+i)%len(b.items)])
+	}
+	return all
+}
+
+// Get returns the exchange numbered id, while kept.
+func (b *Buffer) Get(id string) (*Exchange, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, e := range b.items {
+		if e.ID == id {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+var (
+	defaultOnce sync.Once
+	defaultBuf  *Buffer
+)
+
+// Default returns the buffer of middleware.Capture, keeping
+// server.capture.size exchanges.
+func Default() *Buffer {
+	defaultOnce.Do(func() {
+		defaultBuf = NewBuffer(config.Get("server.capture.size", 100).(int))
+	})
+	return defaultBuf
+}
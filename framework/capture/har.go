@@ -0,0 +1,175 @@
+package capture
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lemmego/lemmego/framework/logs"
+)
+
+// HAR 1.2, see http://www.softwareishard.com/blog/har-12-spec/
+
+type harLog struct {
+	Log struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type harRequest struct {
+	Method      string       `json:"method"`
+	URL         string       `json:"url"`
+	HTTPVersion string       `json:"httpVersion"`
+	Cookies     []harPair    `json:"cookies"`
+	Headers     []harPair    `json:"headers"`
+	QueryString []harPair    `json:"queryString"`
+	PostData    *harPostData `json:"postData,omitempty"`
+	HeadersSize int          `json:"headersSize"`
+	BodySize    int64        `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int        `json:"status"`
+	StatusText  string     `json:"statusText"`
+	HTTPVersion string     `json:"httpVersion"`
+	Cookies     []harPair  `json:"cookies"`
+	Headers     []harPair  `json:"headers"`
+	Content     harContent `json:"content"`
+	RedirectURL string     `json:"redirectURL"`
+	HeadersSize int        `json:"headersSize"`
+	BodySize    int64      `json:"bodySize"`
+}
+
+type harPair struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// HAR returns exchanges as a HAR log, oldest first. Secrets are scrubbed
+// from headers and text bodies as they are from logs, see logs.Scrub.
+func HAR(exchanges []*Exchange) any {
+	var h harLog
+	h.Log.Version = "1.2"
+	h.Log.Creator = harCreator{Name: "lemmego", Version: "1.0"}
+	h.Log.Entries = make([]harEntry, 0, len(exchanges))
+
+	sorted := append([]*Exchange(nil), exchanges...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+	for _, e := range sorted {
+		ms := float64(e.Duration) / float64(time.Millisecond)
+		entry := harEntry{
+			StartedDateTime: e.Time.Format(time.RFC3339Nano),
+			Time:            ms,
+			Request: harRequest{
+				Method:      e.Request.Method,
+				URL:         logs.Scrub("", e.Request.URL),
+				HTTPVersion: e.Request.Proto,
+				Cookies:     []harPair{},
+				Headers:     harHeaders(e.Request.Header),
+				QueryString: harQuery(e.Request.URL),
+				HeadersSize: -1,
+				BodySize:    e.Request.Body.Size,
+			},
+			Response: harResponse{
+				Status:      e.Response.Status,
+				StatusText:  http.StatusText(e.Response.Status),
+				HTTPVersion: e.Request.Proto,
+				Cookies:     []harPair{},
+				Headers:     harHeaders(e.Response.Header),
+				Content:     harBody(e.Response.Header.Get("Content-Type"), e.Response.Body),
+				RedirectURL: e.Response.Header.Get("Location"),
+				HeadersSize: -1,
+				BodySize:    e.Response.Body.Size,
+			},
+			Timings: harTimings{Wait: ms},
+			Comment: "capture " + e.ID,
+		}
+		if e.Request.Body.Size > 0 {
+			content := harBody(e.Request.Header.Get("Content-Type"), e.Request.Body)
+			entry.Request.PostData = &harPostData{MimeType: content.MimeType, Text: content.Text}
+		}
+		h.Log.Entries = append(h.Log.Entries, entry)
+	}
+	return h
+}
+
+func harHeaders(h http.Header) []harPair {
+	pairs := []harPair{}
+	for name, values := range logs.ScrubHeaders(h) {
+		for _, v := range values {
+			pairs = append(pairs, harPair{Name: name, Value: v})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Name < pairs[j].Name })
+	return pairs
+}
+
+func harQuery(raw string) []harPair {
+	pairs := []harPair{}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return pairs
+	}
+	for name, values := range u.Query() {
+		for _, v := range values {
+			pairs = append(pairs, harPair{Name: name, Value: logs.Scrub(name, v)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Name < pairs[j].Name })
+	return pairs
+}
+
+func harBody(contentType string, b Body) harContent {
+	c := harContent{Size: b.Size, MimeType: contentType}
+	switch {
+	case b.Omitted:
+		c.Comment = "binary body not captured"
+	case utf8.Valid(b.Data):
+		c.Text = logs.Scrub("", string(b.Data))
+	default:
+		c.Text, c.Encoding = base64.StdEncoding.EncodeToString(b.Data), "base64"
+	}
+	if b.Truncated {
+		c.Comment = "body truncated"
+	}
+	return c
+}
@@ -0,0 +1,134 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lemmego/lemmego/framework/httpclient"
+)
+
+// ReplayHeader marks replayed requests with the id of the captured one.
+const ReplayHeader = "X-Replay-Of"
+
+var ErrIncomplete = errors.New("capture: the request body was not captured whole")
+
+// hopHeaders are not sent again, the client setting them for the new
+// connection.
+var hopHeaders = []string{"Connection", "Content-Length", "Keep-Alive", "Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade"}
+
+// Replay sends the request of e again, as captured, to base, e.g.
+// http://127.0.0.1:8080, and returns the response.
+func Replay(ctx context.Context, base string, e *Exchange) (*http.Response, error) {
+	if !e.Request.Body.Complete() {
+		return nil, ErrIncomplete
+	}
+	u, err := url.Parse(e.Request.URL)
+	if err != nil {
+		return nil, err
+	}
+	target := strings.TrimSuffix(base, "/") + u.RequestURI()
+
+	req, err := http.NewRequestWithContext(ctx, e.Request.Method, target, bytes.NewReader(e.Request.Body.Data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = e.Request.Header.Clone()
+	for _, h := range hopHeaders {
+		req.Header.Del(h)
+	}
+	req.Host = u.Host
+	req.Header.Set(ReplayHeader, e.ID)
+	// Redirects are part of what is reproduced
+	client := *httpclient.Default
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	return client.Do(req)
+}
+
+// summary is an exchange as listed.
+type summary struct {
+	ID       string    `json:"id"`
+	Time     time.Time `json:"time"`
+	Method   string    `json:"method"`
+	URL      string    `json:"url"`
+	Status   int       `json:"status"`
+	Duration string    `json:"duration"`
+	Replay   string    `json:"replay_of,omitempty"`
+}
+
+// ListHandler serves the exchanges of b, newest first.
+func ListHandler(b *Buffer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		all := b.All()
+		list := make([]summary, len(all))
+		for i, e := range all {
+			list[i] = summary{
+				ID:       e.ID,
+				Time:     e.Time,
+				Method:   e.Request.Method,
+				URL:      e.Request.URL,
+				Status:   e.Response.Status,
+				Duration: e.Duration.String(),
+				Replay:   e.Request.Header.Get(ReplayHeader),
+			}
+		}
+		writeJSON(w, http.StatusOK, list)
+	})
+}
+
+// HARHandler serves the exchanges of b as a HAR file, only the one of the
+// id query parameter when set.
+func HARHandler(b *Buffer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		exchanges := b.All()
+		if id := r.URL.Query().Get("id"); id != "" {
+			e, ok := b.Get(id)
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			exchanges = []*Exchange{e}
+		}
+		w.Header().Set("Content-Disposition", `attachment; filename="requests.har"`)
+		writeJSON(w, http.StatusOK, HAR(exchanges))
+	})
+}
+
+// ReplayHandler replays the exchange of the id path value against base and
+// answers with the status of the new response. The replayed request is
+// captured as well, to be compared with the original.
+func ReplayHandler(b *Buffer, base string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e, ok := b.Get(r.PathValue("id"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		start := time.Now()
+		resp, err := Replay(r.Context(), base, e)
+		if err != nil {
+			writeJSON(w, http.StatusBadGateway, map[string]any{"message": err.Error()})
+			return
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		writeJSON(w, http.StatusOK, map[string]any{
+			"replay_of": e.ID,
+			"status":    resp.StatusCode,
+			"original":  e.Response.Status,
+			"duration":  time.Since(start).String(),
+		})
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/backup"
+	"github.com/spf13/cobra"
+)
+
+func backupOptions(a app.App) *backup.Options {
+	var dirs []string
+	for _, dir := range strings.Split(a.Config().Get("backup.directories", "").(string), ",") {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return &backup.Options{
+		Connection:  a.Config().Get("backup.connection", "").(string),
+		Directories: dirs,
+		Disk:        a.Config().Get("backup.disk", "").(string),
+		Path:        a.Config().Get("backup.path", "").(string),
+		Encrypt:     a.Config().Get("backup.encrypt", true).(bool),
+		Keep:        a.Config().Get("backup.keep", 0).(int),
+		MaxAge:      time.Duration(a.Config().Get("backup.max_days", 0).(int)) * 24 * time.Hour,
+		App:         a.Config().Get("app.name", "").(string),
+	}
+}
+
+var BackupRunCommand = func(a app.App) *cobra.Command {
+	var noFiles bool
+
+	cmd := &cobra.Command{
+		Use:   "backup:run",
+		Short: "Back up the database and storage directories to the backup disk",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o := backupOptions(a)
+			if noFiles {
+				o.Directories = nil
+			}
+
+			start := time.Now()
+			entry, err := backup.Run(context.Background(), o)
+			if entry != nil {
+				fmt.Printf("Backed up to %s (%d bytes) in %s\n", entry.Name, entry.Size, time.Since(start).Round(time.Millisecond))
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVar(&noFiles, "no-files", false, "only back up the database")
+	return cmd
+}
+
+var BackupListCommand = func(a app.App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "backup:list",
+		Short: "List the backups on the backup disk, newest first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := backup.List(context.Background(), backupOptions(a))
+			if err != nil {
+				return err
+			}
+			for _, e := range entries {
+				fmt.Printf("%s  %10d  %s\n", e.CreatedAt.Local().Format(time.DateTime), e.Size, e.Name)
+			}
+			return nil
+		},
+	}
+}
+
+var BackupRestoreCommand = func(a app.App) *cobra.Command {
+	var (
+		force        bool
+		databaseOnly bool
+		filesOnly    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "backup:restore [name]",
+		Short: "Restore a backup, the newest one unless named",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if databaseOnly && filesOnly {
+				return fmt.Errorf("--database-only and --files-only exclude each other")
+			}
+
+			var name string
+			if len(args) > 0 {
+				name = args[0]
+			}
+
+			if !force {
+				target := name
+				if target == "" {
+					target = "the newest backup"
+				}
+				fmt.Printf("Restoring %s overwrites the current data, stop the application first. Continue? [y/N] ", target)
+				answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+				if !strings.EqualFold(strings.TrimSpace(answer), "y") {
+					return nil
+				}
+			}
+
+			manifest, err := backup.Restore(context.Background(), backupOptions(a), name, backup.RestoreOptions{
+				Database: !filesOnly,
+				Files:    !databaseOnly,
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Restored the %s backup of %s taken %s\n", manifest.Driver, manifest.App, manifest.CreatedAt.Local().Format(time.DateTime))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "do not ask for confirmation")
+	cmd.Flags().BoolVar(&databaseOnly, "database-only", false, "only restore the database")
+	cmd.Flags().BoolVar(&filesOnly, "files-only", false, "only restore the directories")
+	return cmd
+}
@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"github.com/lemmego/api/app"
+)
+
+func Load() []app.Command {
+	return []app.Command{
+		InspireCommand,
+		KeyGenerateCommand,
+		QueueWorkCommand,
+		QueueFailedCommand,
+		QueueRetryCommand,
+		QueueForgetCommand,
+		QueuePruneFailedCommand,
+		ScheduleRunCommand,
+		ScheduleListCommand,
+		OutboxRelayCommand,
+		InboxPruneCommand,
+		PushPruneCommand,
+		PushVAPIDCommand,
+		NotificationsPruneCommand,
+		BackupRunCommand,
+		BackupListCommand,
+		BackupRestoreCommand,
+		ReportRunCommand,
+		SearchImportCommand,
+		PrivacyExportCommand,
+		PrivacyEraseCommand,
+		ViewRefreshCommand,
+		MakeMigrationCommand,
+		MigrateDataCommand,
+		StorageGCCommand,
+		SessionsPruneCommand,
+		SigningClientCreateCommand,
+		SigningClientRotateCommand,
+	}
+}
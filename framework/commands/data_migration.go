@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/datamigration"
+	"github.com/spf13/cobra"
+)
+
+var MigrateDataCommand = func(a app.App) *cobra.Command {
+	var status bool
+
+	cmd := &cobra.Command{
+		Use:   "migrate:data",
+		Short: "Queue the long running data migrations migrate up left pending",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			if status {
+				records, err := datamigration.All(ctx)
+				if err != nil {
+					return err
+				}
+				for _, r := range records {
+					line := fmt.Sprintf("%s %s... %s", r.Version, r.Name, r.Status)
+					if r.Total > 0 {
+						line += fmt.Sprintf(" (%d/%d)", r.Done, r.Total)
+					}
+					if r.Error != nil {
+						line += ": " + *r.Error
+					}
+					fmt.Println(line)
+				}
+				return nil
+			}
+
+			n, err := datamigration.DispatchPending(ctx)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Queued %d data migrations\n", n)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&status, "status", false, "show the progress of the data migrations instead")
+	return cmd
+}
@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/inbox"
+	"github.com/spf13/cobra"
+)
+
+var InboxPruneCommand = func(a app.App) *cobra.Command {
+	var olderThan time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "inbox:prune",
+		Short: "Forget the external messages processed long ago, e.g. from cron",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			n, err := inbox.Prune(context.Background(), olderThan)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Pruned %d processed messages\n", n)
+			return nil
+		},
+	}
+
+	// Longer than the retries of common webhook senders, Stripe's being 3 days
+	cmd.Flags().DurationVar(&olderThan, "older-than", 30*24*time.Hour, "only forget messages processed at least this long ago")
+	return cmd
+}
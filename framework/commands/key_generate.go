@@ -0,0 +1,25 @@
+package commands
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/lemmego/api/app"
+	"github.com/spf13/cobra"
+)
+
+var KeyGenerateCommand = func(a app.App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "key:generate",
+		Short: "Print a random application key for APP_KEY",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key := make([]byte, 32)
+			if _, err := rand.Read(key); err != nil {
+				return err
+			}
+			fmt.Println("base64:" + base64.StdEncoding.EncodeToString(key))
+			return nil
+		},
+	}
+}
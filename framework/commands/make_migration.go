@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/repo"
+	"github.com/lemmego/lemmego/framework/schema"
+	"github.com/lemmego/migration"
+	"github.com/spf13/cobra"
+)
+
+var MakeMigrationCommand = func(a app.App) *cobra.Command {
+	var diff bool
+
+	cmd := &cobra.Command{
+		Use:   "make:migration <name>",
+		Short: "Create a migration, empty or with the changes from the registered models to the database",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if !diff {
+				// The migration package defaults to cmd/migrations
+				os.Setenv("MIGRATIONS_DIR", schema.MigrationsDir())
+				return migration.CreateMigration(name)
+			}
+
+			models := schema.Models()
+			if len(models) == 0 {
+				fmt.Println("No models are registered, see schema.RegisterModel")
+				return nil
+			}
+			changes, err := schema.Diff(repo.DB(context.Background()), models...)
+			if err != nil {
+				return err
+			}
+			if len(changes) == 0 {
+				fmt.Println("The database matches the models")
+				return nil
+			}
+
+			path, err := schema.WriteMigration(schema.MigrationsDir(), name, changes)
+			if err != nil {
+				return err
+			}
+			for _, c := range changes {
+				if c.Column == "" {
+					fmt.Printf("  create table %s\n", c.Table)
+				} else {
+					fmt.Printf("  column %s.%s\n", c.Table, c.Column)
+				}
+			}
+			fmt.Printf("Created %s, review it before migrating\n", path)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&diff, "diff", false, "generate the statements bringing the database in line with the registered models")
+	return cmd
+}
@@ -0,0 +1,31 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/notifications"
+	"github.com/spf13/cobra"
+)
+
+var NotificationsPruneCommand = func(a app.App) *cobra.Command {
+	var olderThan time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "notifications:prune",
+		Short: "Delete the notifications read long ago, e.g. from cron",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			n, err := notifications.Prune(context.Background(), olderThan)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Pruned %d notifications\n", n)
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&olderThan, "older-than", 90*24*time.Hour, "only delete notifications at least this old")
+	return cmd
+}
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/outbox"
+	"github.com/spf13/cobra"
+)
+
+var OutboxRelayCommand = func(a app.App) *cobra.Command {
+	var once bool
+
+	cmd := &cobra.Command{
+		Use:   "outbox:relay",
+		Short: "Queue the jobs and dispatch the events of committed transactions until interrupted",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			opts := &outbox.RelayOptions{
+				Connection: a.Config().Get("outbox.connection", "").(string),
+				Interval:   time.Duration(a.Config().Get("outbox.interval", 1).(int)) * time.Second,
+			}
+			if once {
+				n, err := outbox.RelayOnce(ctx, opts)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Relayed %d message(s)\n", n)
+				return nil
+			}
+
+			fmt.Println("Relaying the outbox")
+			outbox.Relay(ctx, opts)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&once, "once", false, "relay the messages due and exit")
+	return cmd
+}
@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/privacy"
+	"github.com/spf13/cobra"
+)
+
+var PrivacyExportCommand = func(a app.App) *cobra.Command {
+	var disk string
+
+	cmd := &cobra.Command{
+		Use:   "privacy:export <subject>",
+		Short: "Write everything held about a user to an export bundle on a storage disk",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := privacy.WithActor(context.Background(), "console")
+			path := privacy.ExportPath(args[0], time.Now())
+			m, err := privacy.ExportToDisk(ctx, args[0], disk, path)
+			if err != nil {
+				return err
+			}
+			for _, name := range privacy.Sections() {
+				fmt.Printf("%-20s %d rows\n", name, m.Sections[name])
+			}
+			fmt.Printf("Exported to %s\n", path)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&disk, "disk", "", "storage disk, the default one when empty")
+	return cmd
+}
+
+var PrivacyEraseCommand = func(a app.App) *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "privacy:erase <subject>",
+		Short: "Anonymize or delete everything held about a user",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !force {
+				fmt.Printf("Erasing the data of %s cannot be undone. Continue? [y/N] ", args[0])
+				answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+				if !strings.EqualFold(strings.TrimSpace(answer), "y") {
+					return nil
+				}
+			}
+
+			ctx := privacy.WithActor(context.Background(), "console")
+			e, err := privacy.Erase(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			for _, name := range privacy.Sections() {
+				fmt.Printf("%-20s %d rows\n", name, e.Sections[name])
+			}
+			fmt.Printf("Erased the data of %s\n", args[0])
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "do not ask for confirmation")
+	return cmd
+}
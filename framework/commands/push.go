@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/config"
+	"github.com/lemmego/lemmego/framework/push"
+	"github.com/spf13/cobra"
+)
+
+var PushPruneCommand = func(a app.App) *cobra.Command {
+	var stale time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "push:prune",
+		Short: "Forget the push devices gone invalid or not seen for long, e.g. from cron",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if stale <= 0 {
+				stale = time.Duration(config.Get("push.stale_days", 60).(int)) * 24 * time.Hour
+			}
+			n, err := push.Prune(context.Background(), stale)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Pruned %d devices\n", n)
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&stale, "stale", 0, "also prune devices not registered again for this long, push.stale_days when unset")
+	return cmd
+}
+
+var PushVAPIDCommand = func(a app.App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "push:vapid",
+		Short: "Print a new VAPID key pair for web push",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			public, private, err := push.GenerateVAPIDKeys()
+			if err != nil {
+				return err
+			}
+			fmt.Println("VAPID_PUBLIC_KEY=" + public)
+			fmt.Println("VAPID_PRIVATE_KEY=" + private)
+			return nil
+		},
+	}
+}
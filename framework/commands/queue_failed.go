@@ -0,0 +1,140 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/queue"
+	"github.com/spf13/cobra"
+)
+
+var QueueFailedCommand = func(a app.App) *cobra.Command {
+	var q queue.FailedQuery
+
+	cmd := &cobra.Command{
+		Use:   "queue:failed [id]",
+		Short: "List the failed jobs, or show one with its payload and exception",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := queue.Default().Failed()
+			if err != nil {
+				return err
+			}
+			ctx := context.Background()
+
+			if len(args) == 1 {
+				job, err := store.FindFailed(ctx, args[0])
+				if err != nil {
+					return err
+				}
+				fmt.Printf("ID:        %s\nQueue:     %s\nJob:       %s\nAttempts:  %d\nFailed at: %s\nPayload:   %s\nException: %s\n",
+					job.ID, job.Queue, job.Job, job.Attempts, job.FailedAt.Format(time.DateTime), job.Payload, job.Exception)
+				return nil
+			}
+
+			jobs, err := store.Failed(ctx, &q)
+			if err != nil {
+				return err
+			}
+			for _, job := range jobs {
+				exception, _, _ := strings.Cut(job.Exception, "\n")
+				fmt.Printf("%s  %s  %-10s %-25s %s\n", job.ID, job.FailedAt.Format(time.DateTime), job.Queue, job.Job, exception)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&q.Queue, "queue", "", "only jobs of this queue")
+	cmd.Flags().StringVar(&q.Job, "job", "", "only jobs of this name")
+	cmd.Flags().IntVar(&q.Limit, "limit", 50, "jobs listed")
+	cmd.Flags().IntVar(&q.Offset, "offset", 0, "jobs skipped")
+	return cmd
+}
+
+var QueueRetryCommand = func(a app.App) *cobra.Command {
+	var (
+		all bool
+		q   queue.FailedQuery
+	)
+
+	cmd := &cobra.Command{
+		Use:   "queue:retry [id...]",
+		Short: "Move failed jobs back onto their queue, those given or --all matching the filters",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if all == (len(args) > 0) {
+				return errors.New("give either job ids or --all")
+			}
+			store, err := queue.Default().Failed()
+			if err != nil {
+				return err
+			}
+			ctx := context.Background()
+
+			if all {
+				n, err := queue.RetryFailed(ctx, store, &q)
+				fmt.Printf("Retried %d jobs\n", n)
+				return err
+			}
+			for _, id := range args {
+				if err := store.Retry(ctx, id); err != nil {
+					return fmt.Errorf("%s: %w", id, err)
+				}
+				fmt.Printf("Retried %s\n", id)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "retry every failed job matching --queue and --job")
+	cmd.Flags().StringVar(&q.Queue, "queue", "", "with --all, only jobs of this queue")
+	cmd.Flags().StringVar(&q.Job, "job", "", "with --all, only jobs of this name")
+	return cmd
+}
+
+var QueueForgetCommand = func(a app.App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "queue:forget [id...]",
+		Short: "Delete failed jobs without retrying them",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := queue.Default().Failed()
+			if err != nil {
+				return err
+			}
+			for _, id := range args {
+				if err := store.Forget(context.Background(), id); err != nil {
+					return fmt.Errorf("%s: %w", id, err)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+var QueuePruneFailedCommand = func(a app.App) *cobra.Command {
+	var olderThan time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "queue:prune-failed",
+		Short: "Delete old failed jobs, e.g. from cron",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := queue.Default().Failed()
+			if err != nil {
+				return err
+			}
+			n, err := store.Prune(context.Background(), time.Now().Add(-olderThan))
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Pruned %d failed jobs\n", n)
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&olderThan, "older-than", 7*24*time.Hour, "only delete jobs that failed at least this long ago")
+	return cmd
+}
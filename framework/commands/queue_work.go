@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/queue"
+	"github.com/spf13/cobra"
+)
+
+var QueueWorkCommand = func(a app.App) *cobra.Command {
+	var (
+		queues      string
+		concurrency int
+		sleep       int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "queue:work",
+		Short: "Handle queued jobs until interrupted",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			q := queue.Default()
+			if _, ok := q.Driver().(*queue.SyncDriver); ok {
+				return fmt.Errorf("queue:work needs QUEUE_DRIVER=database, the sync driver runs jobs as they are dispatched")
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			names, weights, err := queue.ParseQueues(queues)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Working queues %s with %d worker(s)\n", strings.Join(names, ", "), concurrency)
+			q.Work(ctx, &queue.WorkOptions{
+				Queues:      names,
+				Weights:     weights,
+				Concurrency: concurrency,
+				Sleep:       time.Duration(sleep) * time.Second,
+			})
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&queues, "queues", queue.DefaultQueue, "comma separated queues to poll, earlier ones first, or weighted as critical:5,default:3,bulk:1")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 1, "jobs handled at once")
+	cmd.Flags().IntVar(&sleep, "sleep", 1, "seconds to pause when all queues are empty")
+	return cmd
+}
@@ -0,0 +1,34 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/report"
+	"github.com/spf13/cobra"
+)
+
+var ReportRunCommand = func(a app.App) *cobra.Command {
+	var to []string
+
+	cmd := &cobra.Command{
+		Use:   "report:run <name>",
+		Short: "Generate a report and mail it now",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, ok := report.Get(args[0]); !ok {
+				return fmt.Errorf("%w: %s, defined are: %s", report.ErrUnknownReport, args[0], strings.Join(report.Names(), ", "))
+			}
+			if err := report.Run(context.Background(), args[0], to...); err != nil {
+				return err
+			}
+			fmt.Printf("Report %s sent\n", args[0])
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&to, "to", nil, "also mail the report to these addresses")
+	return cmd
+}
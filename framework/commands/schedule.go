@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/schedule"
+	"github.com/spf13/cobra"
+)
+
+var ScheduleRunCommand = func(a app.App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "schedule:run",
+		Short: "Run the scheduled tasks until interrupted, on the elected instance only",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			fmt.Printf("Running %d scheduled task(s)\n", len(schedule.Entries()))
+			schedule.Run(ctx, nil)
+			return nil
+		},
+	}
+}
+
+var ScheduleListCommand = func(a app.App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "schedule:list",
+		Short: "List the scheduled tasks and when they run next",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			now := time.Now()
+			for _, e := range schedule.Entries() {
+				next := "never"
+				if t := e.Cron.Next(now); !t.IsZero() {
+					next = t.Format(time.DateTime)
+				}
+				fmt.Printf("%-30s %-20s %s\n", e.Name, e.Spec, next)
+			}
+			return nil
+		},
+	}
+}
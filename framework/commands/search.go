@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/search"
+	"github.com/spf13/cobra"
+)
+
+var SearchImportCommand = func(a app.App) *cobra.Command {
+	var chunk int
+
+	cmd := &cobra.Command{
+		Use:   "search:import [index...]",
+		Short: "Configure search indexes and index every row, all registered indexes by default",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			indexes := args
+			if len(indexes) == 0 {
+				indexes = search.Indexes()
+			}
+			if len(indexes) == 0 {
+				fmt.Println("No indexes are registered, see search.Sync")
+				return nil
+			}
+
+			for _, index := range indexes {
+				start := time.Now()
+				n, err := search.Import(context.Background(), index, chunk)
+				if err != nil {
+					return fmt.Errorf("%s: %w", index, err)
+				}
+				fmt.Printf("Imported %d documents into %s in %s\n", n, index, time.Since(start).Round(time.Millisecond))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&chunk, "chunk", 500, "rows read and indexed per batch")
+	return cmd
+}
@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/auth"
+	"github.com/spf13/cobra"
+)
+
+var SessionsPruneCommand = func(a app.App) *cobra.Command {
+	var idle time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "sessions:prune",
+		Short: "Forget the devices of sessions expired from the session store, e.g. from cron",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			n, err := auth.PruneDevices(context.Background(), idle)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Pruned %d sessions\n", n)
+			return nil
+		},
+	}
+
+	// The default lifetime of sessions
+	cmd.Flags().DurationVar(&idle, "idle", 24*time.Hour, "only prune sessions inactive for at least this long")
+	return cmd
+}
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/httpsig"
+	"github.com/spf13/cobra"
+)
+
+var SigningClientCreateCommand = func(a app.App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "signing:client [name]",
+		Short: "Create a partner client signing its requests, printing its key and secret",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := httpsig.CreateClient(context.Background(), args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Client key: %s\nSecret:     %s\n", c.Key, c.Secret)
+			return nil
+		},
+	}
+}
+
+var SigningClientRotateCommand = func(a app.App) *cobra.Command {
+	var forget bool
+
+	cmd := &cobra.Command{
+		Use:   "signing:rotate [key]",
+		Short: "Give a partner client a new secret, the old one accepted until --forget",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if forget {
+				return httpsig.ForgetPreviousSecret(context.Background(), args[0])
+			}
+			c, err := httpsig.RotateSecret(context.Background(), args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Secret: %s\n", c.Secret)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&forget, "forget", false, "stop accepting the secret before the last rotation")
+	return cmd
+}
@@ -0,0 +1,31 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/storage"
+	"github.com/spf13/cobra"
+)
+
+var StorageGCCommand = func(a app.App) *cobra.Command {
+	var grace time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "storage:gc",
+		Short: "Delete deduplicated files nothing refers to anymore, e.g. from cron",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			n, err := storage.CollectGarbage(context.Background(), grace)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Collected %d unreferenced files\n", n)
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&grace, "grace", 24*time.Hour, "only collect files unreferenced for at least this long")
+	return cmd
+}
@@ -0,0 +1,34 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/schema"
+	"github.com/spf13/cobra"
+)
+
+var ViewRefreshCommand = func(a app.App) *cobra.Command {
+	var concurrently bool
+
+	cmd := &cobra.Command{
+		Use:   "view:refresh <view...>",
+		Short: "Refresh materialized views, e.g. from cron",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, view := range args {
+				start := time.Now()
+				if err := schema.Refresh(context.Background(), view, concurrently); err != nil {
+					return fmt.Errorf("%s: %w", view, err)
+				}
+				fmt.Printf("Refreshed %s in %s\n", view, time.Since(start).Round(time.Millisecond))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&concurrently, "concurrently", false, "keep serving the view while it refreshes, PostgreSQL only")
+	return cmd
+}
@@ -0,0 +1,142 @@
+// Package crypt encrypts and decrypts values with the application key using
+// AES-256-GCM, and signs them with HMAC-SHA256. Previous keys can be kept
+// around to read values written before a key rotation.
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/lemmego/api/config"
+)
+
+var (
+	ErrMissingKey = errors.New("crypt: app.key is not set")
+	ErrDecrypt    = errors.New("crypt: the value could not be decrypted")
+)
+
+// Encrypter seals values with its first key and opens them with any of them.
+type Encrypter struct {
+	aeads   []cipher.AEAD
+	macKeys [][]byte
+}
+
+// New returns an encrypter for keys, newest first. Keys are given as
+// "base64:..." for 32 random bytes, or as any other string, which is hashed
+// into a key.
+func New(keys ...string) (*Encrypter, error) {
+	e := &Encrypter{}
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		raw, err := parseKey(key)
+		if err != nil {
+			return nil, err
+		}
+		block, err := aes.NewCipher(raw)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		e.aeads = append(e.aeads, aead)
+		e.macKeys = append(e.macKeys, macKey(raw))
+	}
+	if len(e.aeads) == 0 {
+		return nil, ErrMissingKey
+	}
+	return e, nil
+}
+
+func parseKey(key string) ([]byte, error) {
+	if encoded, ok := strings.CutPrefix(key, "base64:"); ok {
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, err
+		}
+		if len(raw) != 32 {
+			return nil, errors.New("crypt: a base64 key must decode to 32 bytes")
+		}
+		return raw, nil
+	}
+	sum := sha256.Sum256([]byte(key))
+	return sum[:], nil
+}
+
+// Encrypt seals plaintext. The nonce is stored in front of the ciphertext.
+func (e *Encrypter) Encrypt(plaintext []byte) ([]byte, error) {
+	aead := e.aeads[0]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt opens a value sealed by Encrypt with the current or a previous key.
+func (e *Encrypter) Decrypt(ciphertext []byte) ([]byte, error) {
+	for _, aead := range e.aeads {
+		if len(ciphertext) < aead.NonceSize() {
+			return nil, ErrDecrypt
+		}
+		nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+		if plaintext, err := aead.Open(nil, nonce, sealed, nil); err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, ErrDecrypt
+}
+
+// EncryptString seals s and encodes the result as base64, for text columns.
+func (e *Encrypter) EncryptString(s string) (string, error) {
+	sealed, err := e.Encrypt([]byte(s))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptString opens a value produced by EncryptString.
+func (e *Encrypter) DecryptString(s string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	plaintext, err := e.Decrypt(sealed)
+	return string(plaintext), err
+}
+
+var (
+	mu         sync.Mutex
+	defaultEnc *Encrypter
+)
+
+// Default returns the encrypter for app.key and app.previous_keys.
+func Default() (*Encrypter, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if defaultEnc != nil {
+		return defaultEnc, nil
+	}
+
+	keys := []string{config.Get("app.key", "").(string)}
+	for _, key := range strings.Split(config.Get("app.previous_keys", "").(string), ",") {
+		keys = append(keys, strings.TrimSpace(key))
+	}
+
+	enc, err := New(keys...)
+	if err != nil {
+		return nil, err
+	}
+	defaultEnc = enc
+	return enc, nil
+}
@@ -0,0 +1,33 @@
+package crypt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+)
+
+// macKey derives the signing key from an encryption key, so one value never
+// serves both purposes.
+func macKey(raw []byte) []byte {
+	sum := sha256.Sum256(append([]byte("crypt.sign:"), raw...))
+	return sum[:]
+}
+
+// Sign returns the HMAC-SHA256 of data under the current key.
+func (e *Encrypter) Sign(data []byte) []byte {
+	mac := hmac.New(sha256.New, e.macKeys[0])
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// Verify reports whether sig is the signature of data under the current or
+// a previous key.
+func (e *Encrypter) Verify(data, sig []byte) bool {
+	for _, key := range e.macKeys {
+		mac := hmac.New(sha256.New, key)
+		mac.Write(data)
+		if hmac.Equal(mac.Sum(nil), sig) {
+			return true
+		}
+	}
+	return false
+}
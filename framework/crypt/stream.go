@@ -0,0 +1,136 @@
+package crypt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// streamChunk is the plaintext size of each sealed chunk of a stream.
+const streamChunk = 64 << 10
+
+// A stream is a series of chunks, each a 4 byte length followed by a value
+// sealed by Encrypt. Every chunk starts with its 8 byte sequence number and a
+// flag marking the last one, so chunks cannot be reordered, dropped or cut
+// off without Decrypt or the reader noticing.
+const chunkHeader = 9
+
+var errClosed = errors.New("crypt: write to closed stream")
+
+// NewWriter encrypts everything written to it onto w. Close must be called to
+// seal the final chunk; it does not close w.
+func (e *Encrypter) NewWriter(w io.Writer) io.WriteCloser {
+	return &streamWriter{enc: e, w: w, buf: make([]byte, chunkHeader, chunkHeader+streamChunk)}
+}
+
+type streamWriter struct {
+	enc *Encrypter
+	w   io.Writer
+	buf []byte
+	seq uint64
+	err error
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 && s.err == nil {
+		if len(s.buf) == cap(s.buf) {
+			s.err = s.seal(false)
+			continue
+		}
+		c := copy(s.buf[len(s.buf):cap(s.buf)], p)
+		s.buf = s.buf[:len(s.buf)+c]
+		p = p[c:]
+		n += c
+	}
+	return n, s.err
+}
+
+func (s *streamWriter) seal(last bool) error {
+	binary.BigEndian.PutUint64(s.buf, s.seq)
+	s.buf[8] = 0
+	if last {
+		s.buf[8] = 1
+	}
+
+	sealed, err := s.enc.Encrypt(s.buf)
+	if err != nil {
+		return err
+	}
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(sealed)))
+	if _, err := s.w.Write(size[:]); err != nil {
+		return err
+	}
+	if _, err := s.w.Write(sealed); err != nil {
+		return err
+	}
+
+	s.seq++
+	s.buf = s.buf[:chunkHeader]
+	return nil
+}
+
+func (s *streamWriter) Close() error {
+	if s.err != nil {
+		return s.err
+	}
+	if s.err = s.seal(true); s.err != nil {
+		return s.err
+	}
+	s.err = errClosed
+	return nil
+}
+
+// NewReader decrypts a stream written by NewWriter.
+func (e *Encrypter) NewReader(r io.Reader) io.Reader {
+	return &streamReader{enc: e, r: bufio.NewReader(r)}
+}
+
+type streamReader struct {
+	enc  *Encrypter
+	r    *bufio.Reader
+	buf  []byte
+	seq  uint64
+	done bool
+}
+
+func (s *streamReader) Read(p []byte) (int, error) {
+	for len(s.buf) == 0 {
+		if s.done {
+			return 0, io.EOF
+		}
+		if err := s.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+func (s *streamReader) next() error {
+	var size [4]byte
+	if _, err := io.ReadFull(s.r, size[:]); err != nil {
+		// The last chunk was never seen, the stream was cut off
+		return ErrDecrypt
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > streamChunk+chunkHeader+64 {
+		return ErrDecrypt
+	}
+	sealed := make([]byte, n)
+	if _, err := io.ReadFull(s.r, sealed); err != nil {
+		return ErrDecrypt
+	}
+
+	chunk, err := s.enc.Decrypt(sealed)
+	if err != nil || len(chunk) < chunkHeader || binary.BigEndian.Uint64(chunk) != s.seq {
+		return ErrDecrypt
+	}
+	s.seq++
+	s.done = chunk[8] == 1
+	s.buf = chunk[chunkHeader:]
+	return nil
+}
@@ -0,0 +1,261 @@
+// Package datamigration runs changes to rows, rather than to the schema, as
+// part of the migration pipeline. They are versioned and applied in order
+// with the schema migrations, and may be kept out of some environments:
+//
+//	func init() {
+//		datamigration.Add(&datamigration.Migration{
+//			Version: "20261020090000",
+//			Name:    "seed_demo_projects",
+//			Except:  []string{"production"},
+//			Run: func(ctx context.Context, tx *gorm.DB, p *datamigration.Progress) error {
+//				return tx.Create(&demoProjects).Error
+//			},
+//		})
+//	}
+//
+// Long running migrations, such as backfilling a column of a large table,
+// are not run by migrate up, which only records them as pending. The
+// migrate:data command queues them, and workers run them outside of any
+// migration transaction, reporting their progress in the data_migrations
+// table as they go.
+package datamigration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/lemmego/api/config"
+	"github.com/lemmego/migration"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/lemmego/lemmego/framework/queue"
+	"github.com/lemmego/lemmego/framework/repo"
+	"github.com/lemmego/lemmego/framework/schema"
+)
+
+func init() {
+	queue.Register[Job]("datamigration.run")
+}
+
+// Statuses of a data migration.
+const (
+	StatusPending = "pending"
+	StatusQueued  = "queued"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+var ErrUnknown = errors.New("datamigration: unknown data migration")
+
+// Migration changes rows of the database.
+type Migration struct {
+	Version string
+	Name    string
+	// Run applies the migration. Short migrations get the migration's
+	// transaction, long running ones a connection of their own.
+	Run func(ctx context.Context, tx *gorm.DB, p *Progress) error
+	// Down optionally undoes the migration, in the migration's transaction.
+	Down func(ctx context.Context, tx *gorm.DB) error
+	// LongRunning migrations are queued by migrate:data instead of run.
+	LongRunning bool
+	// Only and Except restrict the environments, by app.env, the migration
+	// runs in. Elsewhere it is recorded as applied without running.
+	Only   []string
+	Except []string
+}
+
+var registered = map[string]*Migration{}
+
+// Add registers m with the migrator.
+func Add(m *Migration) {
+	registered[m.Version] = m
+	migration.GetMigrator().AddMigration(&migration.Migration{
+		Version: m.Version,
+		Up:      Only(m.Only, m.Except, m.up),
+		Down:    Only(m.Only, m.Except, m.down),
+	})
+}
+
+// Only guards a step of a schema migration like the Only and Except fields
+// of a data migration.
+//
+//	Up: datamigration.Only(nil, []string{"production"}, mig_20261020090000_seed_up),
+func Only(only, except []string, step func(tx *sql.Tx) error) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		env := Env()
+		if len(only) > 0 && !slices.Contains(only, env) || slices.Contains(except, env) {
+			slog.Info(fmt.Sprintf("datamigration: skipped a migration step in %s", env))
+			return nil
+		}
+		return step(tx)
+	}
+}
+
+// Env is the environment migrations run in, app.env or APP_ENV.
+func Env() string {
+	if env, _ := config.Get("app.env", "").(string); env != "" {
+		return env
+	}
+	if env := os.Getenv("APP_ENV"); env != "" {
+		return env
+	}
+	return "development"
+}
+
+func (m *Migration) up(tx *sql.Tx) error {
+	db, err := open(tx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	rec := &Record{Version: m.Version, Name: m.Name, Status: StatusPending, CreatedAt: now, UpdatedAt: now}
+	if m.LongRunning {
+		return db.Create(rec).Error
+	}
+
+	rec.Status, rec.StartedAt = StatusRunning, &now
+	if err := db.Create(rec).Error; err != nil {
+		return err
+	}
+	if err := m.Run(context.Background(), db, &Progress{db: db, version: m.Version}); err != nil {
+		return fmt.Errorf("datamigration: %s: %w", m.Name, err)
+	}
+	finished := time.Now()
+	return db.Model(rec).Updates(map[string]any{"status": StatusDone, "finished_at": finished, "updated_at": finished}).Error
+}
+
+func (m *Migration) down(tx *sql.Tx) error {
+	db, err := open(tx)
+	if err != nil {
+		return err
+	}
+	if m.Down != nil {
+		if err := m.Down(context.Background(), db); err != nil {
+			return fmt.Errorf("datamigration: %s: %w", m.Name, err)
+		}
+	}
+	return db.Where("version = ?", m.Version).Delete(&Record{}).Error
+}
+
+// open wraps the migration's transaction for gorm.
+func open(tx *sql.Tx) (*gorm.DB, error) {
+	var d gorm.Dialector
+	switch schema.Dialect() {
+	case migration.DriverPostgres:
+		d = postgres.New(postgres.Config{Conn: tx})
+	case migration.DriverMySQL:
+		d = mysql.New(mysql.Config{Conn: tx, SkipInitializeWithVersion: true})
+	default:
+		d = &sqlite.Dialector{Conn: tx}
+	}
+	return gorm.Open(d, &gorm.Config{SkipDefaultTransaction: true})
+}
+
+// Record is the state of a data migration, in the data_migrations table.
+type Record struct {
+	Version    string     `gorm:"primaryKey" json:"version"`
+	Name       string     `json:"name"`
+	Status     string     `json:"status"`
+	Total      int64      `json:"total"`
+	Done       int64      `json:"done"`
+	Error      *string    `json:"error"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func (Record) TableName() string {
+	return "data_migrations"
+}
+
+// Progress reports how far a migration got.
+type Progress struct {
+	db      *gorm.DB
+	version string
+}
+
+// SetTotal sets the number of items, e.g. rows, the migration goes through.
+func (p *Progress) SetTotal(n int64) error {
+	return p.db.Model(&Record{}).Where("version = ?", p.version).
+		Updates(map[string]any{"total": n, "updated_at": time.Now()}).Error
+}
+
+// Advance adds n items to those done.
+func (p *Progress) Advance(n int64) error {
+	return p.db.Model(&Record{}).Where("version = ?", p.version).
+		Updates(map[string]any{"done": gorm.Expr("done + ?", n), "updated_at": time.Now()}).Error
+}
+
+// All lists the data migrations the database has recorded, oldest first.
+func All(ctx context.Context) ([]Record, error) {
+	return repo.New[Record](ctx).Order("version").Find()
+}
+
+// DispatchPending queues the long running migrations migrate up recorded,
+// and those that failed, returning how many it queued.
+func DispatchPending(ctx context.Context) (int, error) {
+	pending, err := repo.New[Record](ctx).Where("status IN ?", []string{StatusPending, StatusFailed}).Order("version").Find()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, rec := range pending {
+		if _, ok := registered[rec.Version]; !ok {
+			return n, fmt.Errorf("%w: %s", ErrUnknown, rec.Version)
+		}
+		if err := repo.DB(ctx).Model(&rec).Updates(map[string]any{"status": StatusQueued, "updated_at": time.Now()}).Error; err != nil {
+			return n, err
+		}
+		// Migrations pick up where they left off rather than being retried
+		if _, err := queue.Dispatch(ctx, &Job{Version: rec.Version}, &queue.Options{MaxAttempts: 1}); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Job runs a long running data migration on a worker.
+type Job struct {
+	Version string `json:"version"`
+}
+
+func (j *Job) Handle(ctx context.Context) error {
+	m, ok := registered[j.Version]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknown, j.Version)
+	}
+
+	db := repo.DB(ctx)
+	now := time.Now()
+	err := db.Model(&Record{}).Where("version = ?", m.Version).
+		Updates(map[string]any{"status": StatusRunning, "error": nil, "started_at": now, "updated_at": now}).Error
+	if err != nil {
+		return err
+	}
+
+	status, values := StatusDone, map[string]any{}
+	if err := m.Run(ctx, db, &Progress{db: db, version: m.Version}); err != nil {
+		status, values["error"] = StatusFailed, err.Error()
+	}
+	finished := time.Now()
+	values["status"], values["finished_at"], values["updated_at"] = status, finished, finished
+	if err := db.Model(&Record{}).Where("version = ?", m.Version).Updates(values).Error; err != nil {
+		return err
+	}
+	if status == StatusFailed {
+		return fmt.Errorf("datamigration: %s: %s", m.Name, values["error"])
+	}
+	return nil
+}
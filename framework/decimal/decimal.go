@@ -0,0 +1,341 @@
+// Package decimal is an exact decimal number for amounts that must never pass
+// through float64, stored in decimal columns:
+//
+//	t.Decimal("price", 12, 2)
+//
+//	type Product struct {
+//		ID    uint
+//		Price decimal.Decimal `gorm:"precision:12;scale:2"`
+//	}
+//
+// Values are immutable; arithmetic returns a new Decimal. Sums, differences
+// and products are exact, quotients and rounding take the number of decimal
+// places to keep.
+package decimal
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	ErrSyntax         = errors.New("decimal: invalid syntax")
+	ErrDivisionByZero = errors.New("decimal: division by zero")
+)
+
+// Decimal is coef / 10^scale. The zero value is 0.
+type Decimal struct {
+	coef  *big.Int
+	scale int32
+}
+
+var (
+	Zero = Decimal{}
+	one  = big.NewInt(1)
+	ten  = big.NewInt(10)
+)
+
+// New returns unscaled / 10^scale, e.g. New(1234, 2) is 12.34.
+func New(unscaled int64, scale int32) Decimal {
+	if scale < 0 {
+		return Decimal{coef: new(big.Int).Mul(big.NewInt(unscaled), pow10(-scale))}
+	}
+	return Decimal{coef: big.NewInt(unscaled), scale: scale}
+}
+
+func NewFromInt(n int64) Decimal {
+	return New(n, 0)
+}
+
+// NewFromFloat converts f through its shortest decimal representation, so
+// NewFromFloat(0.1) is exactly 0.1. Meant for literals, not for amounts
+// already computed in floating point.
+func NewFromFloat(f float64) Decimal {
+	d, err := Parse(strconv.FormatFloat(f, 'f', -1, 64))
+	if err != nil {
+		// Only NaN and infinities fail to format as digits
+		panic(fmt.Sprintf("decimal: cannot convert %v", f))
+	}
+	return d
+}
+
+// Parse reads "-12.340", "+3", ".5" or "1.5e3". Trailing zeros are kept as
+// scale, so "12.340" prints back as is.
+func Parse(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+	mantissa, exp := s, int64(0)
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		var err error
+		if exp, err = strconv.ParseInt(s[i+1:], 10, 32); err != nil {
+			return Zero, fmt.Errorf("%w: %q", ErrSyntax, s)
+		}
+		mantissa = s[:i]
+	}
+
+	sign := ""
+	if mantissa != "" && (mantissa[0] == '-' || mantissa[0] == '+') {
+		sign, mantissa = mantissa[:1], mantissa[1:]
+	}
+	intPart, frac, _ := strings.Cut(mantissa, ".")
+	digits := intPart + frac
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return Zero, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+
+	coef, _ := new(big.Int).SetString(sign+digits, 10)
+	scale := int64(len(frac)) - exp
+	if scale < 0 {
+		coef.Mul(coef, pow10(int32(-scale)))
+		scale = 0
+	}
+	if scale > 1<<20 {
+		return Zero, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+	return Decimal{coef: coef, scale: int32(scale)}, nil
+}
+
+// MustParse is Parse for literals known to be valid.
+func MustParse(s string) Decimal {
+	d, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(ten, big.NewInt(int64(n)), nil)
+}
+
+func (d Decimal) int() *big.Int {
+	if d.coef == nil {
+		return new(big.Int)
+	}
+	return d.coef
+}
+
+// Scale is the number of decimal places d carries.
+func (d Decimal) Scale() int32 {
+	return d.scale
+}
+
+// rescale returns d's coefficient at a scale of at least d's own.
+func (d Decimal) rescale(scale int32) *big.Int {
+	if scale <= d.scale {
+		return d.int()
+	}
+	return new(big.Int).Mul(d.int(), pow10(scale-d.scale))
+}
+
+func (d Decimal) Add(e Decimal) Decimal {
+	scale := max(d.scale, e.scale)
+	return Decimal{coef: new(big.Int).Add(d.rescale(scale), e.rescale(scale)), scale: scale}
+}
+
+func (d Decimal) Sub(e Decimal) Decimal {
+	return d.Add(e.Neg())
+}
+
+func (d Decimal) Mul(e Decimal) Decimal {
+	return Decimal{coef: new(big.Int).Mul(d.int(), e.int()), scale: d.scale + e.scale}
+}
+
+// Div returns d / e rounded half away from zero to places decimal places. It
+// panics with ErrDivisionByZero when e is zero, as integer division does.
+func (d Decimal) Div(e Decimal, places int32) Decimal {
+	if e.IsZero() {
+		panic(ErrDivisionByZero)
+	}
+	// d/e at the wanted scale is d.coef * 10^k / e.coef
+	num, den := new(big.Int).Set(d.int()), new(big.Int).Set(e.int())
+	if k := places + e.scale - d.scale; k >= 0 {
+		num.Mul(num, pow10(k))
+	} else {
+		den.Mul(den, pow10(-k))
+	}
+	return Decimal{coef: roundQuo(num, den, false), scale: places}
+}
+
+// roundQuo returns num / den rounded half away from zero, or half to even.
+func roundQuo(num, den *big.Int, even bool) *big.Int {
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+	twice := new(big.Int).Abs(r)
+	twice.Lsh(twice, 1)
+	c := twice.Cmp(new(big.Int).Abs(den))
+	if c > 0 || c == 0 && (!even || q.Bit(0) == 1) {
+		if num.Sign() == den.Sign() {
+			q.Add(q, one)
+		} else {
+			q.Sub(q, one)
+		}
+	}
+	return q
+}
+
+// Round rounds half away from zero to places decimal places, the way
+// amounts are usually rounded on invoices.
+func (d Decimal) Round(places int32) Decimal {
+	return d.round(places, false)
+}
+
+// RoundBank rounds half to even, which does not bias sums of many rounded
+// values upwards.
+func (d Decimal) RoundBank(places int32) Decimal {
+	return d.round(places, true)
+}
+
+func (d Decimal) round(places int32, even bool) Decimal {
+	if places >= d.scale {
+		return Decimal{coef: d.rescale(places), scale: places}
+	}
+	return Decimal{coef: roundQuo(d.int(), pow10(d.scale-places), even), scale: places}
+}
+
+// Truncate drops the digits past places decimal places.
+func (d Decimal) Truncate(places int32) Decimal {
+	if places >= d.scale {
+		return Decimal{coef: d.rescale(places), scale: places}
+	}
+	return Decimal{coef: new(big.Int).Quo(d.int(), pow10(d.scale-places)), scale: places}
+}
+
+func (d Decimal) Neg() Decimal {
+	return Decimal{coef: new(big.Int).Neg(d.int()), scale: d.scale}
+}
+
+func (d Decimal) Abs() Decimal {
+	return Decimal{coef: new(big.Int).Abs(d.int()), scale: d.scale}
+}
+
+// Sign returns -1, 0 or 1.
+func (d Decimal) Sign() int {
+	return d.int().Sign()
+}
+
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Cmp returns -1, 0 or 1 as d is less than, equal to or greater than e.
+// 1.5 and 1.50 are equal.
+func (d Decimal) Cmp(e Decimal) int {
+	scale := max(d.scale, e.scale)
+	return d.rescale(scale).Cmp(e.rescale(scale))
+}
+
+func (d Decimal) Equal(e Decimal) bool       { return d.Cmp(e) == 0 }
+func (d Decimal) LessThan(e Decimal) bool    { return d.Cmp(e) < 0 }
+func (d Decimal) GreaterThan(e Decimal) bool { return d.Cmp(e) > 0 }
+
+// Unscaled returns the coefficient as an int64, e.g. cents for an amount of
+// scale 2, and false when it does not fit.
+func (d Decimal) Unscaled() (int64, bool) {
+	c := d.int()
+	return c.Int64(), c.IsInt64()
+}
+
+// Float64 approximates d, for display or statistics, never for arithmetic.
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// String formats d without exponent, keeping its scale: "12.30".
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.int()).String()
+	sign := ""
+	if d.Sign() < 0 {
+		sign = "-"
+	}
+	if d.scale == 0 {
+		return sign + digits
+	}
+	if pad := int(d.scale) + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	i := len(digits) - int(d.scale)
+	return sign + digits[:i] + "." + digits[i:]
+}
+
+// StringFixed formats d rounded half away from zero to places decimal places.
+func (d Decimal) StringFixed(places int32) string {
+	return d.Round(places).String()
+}
+
+// MarshalJSON writes a string, which JavaScript clients cannot round through
+// a float by accident.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.String() + `"`), nil
+}
+
+// UnmarshalJSON accepts a string or a number.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	return d.UnmarshalText([]byte(s))
+}
+
+// MarshalText and UnmarshalText also let httpin bind form and query values.
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Decimal) UnmarshalText(text []byte) error {
+	v, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// Scan reads the text drivers return for decimal columns. SQLite, which has
+// no decimal type, may return its float64 approximation.
+func (d *Decimal) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*d = Zero
+		return nil
+	case []byte:
+		return d.UnmarshalText(v)
+	case string:
+		return d.UnmarshalText([]byte(v))
+	case int64:
+		*d = NewFromInt(v)
+		return nil
+	case float64:
+		*d = NewFromFloat(v)
+		return nil
+	}
+	return fmt.Errorf("decimal: cannot scan %T", src)
+}
+
+// Value writes the exact text, which every driver passes to decimal columns.
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// GormDBDataType gives AutoMigrate a decimal column, sized by the precision
+// and scale tags when set.
+func (Decimal) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	precision, scale := field.Precision, field.Scale
+	if precision == 0 {
+		precision, scale = 19, 4
+	}
+	return fmt.Sprintf("decimal(%d,%d)", precision, scale)
+}
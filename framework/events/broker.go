@@ -0,0 +1,128 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sync"
+)
+
+// Broker carries events between processes, such as the NATS and Kafka
+// brokers, so that other instances and services hear of them:
+//
+//	events.RegisterRemote[*org.InvitationEvent](org.InvitationCreated)
+//	events.Forward(broker, "app.events", org.InvitationCreated)
+//	go events.Receive(ctx, broker, "app.events", "web")
+type Broker interface {
+	// Publish sends data on a topic.
+	Publish(ctx context.Context, topic string, data []byte) error
+	// Subscribe calls fn with the messages of a topic until ctx is done.
+	// Subscribers of a group split the messages among them, each group
+	// getting every message.
+	Subscribe(ctx context.Context, topic, group string, fn func(data []byte)) error
+}
+
+// remoteEvent is an event on the wire.
+type remoteEvent struct {
+	Name    string          `json:"name"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+var (
+	remoteMu    sync.RWMutex
+	remoteTypes = make(map[string]reflect.Type)
+)
+
+// RegisterRemote makes the events named name decodable as E when received,
+// E being a pointer to a JSON encodable struct.
+func RegisterRemote[E Event](name string) {
+	t := reflect.TypeOf((*E)(nil)).Elem()
+	if t.Kind() != reflect.Pointer {
+		panic(fmt.Sprintf("events: %s must be a pointer", t))
+	}
+	remoteMu.Lock()
+	defer remoteMu.Unlock()
+	remoteTypes[name] = t.Elem()
+}
+
+type receivedKey struct{}
+
+// Received reports whether the event being handled came from a broker.
+func Received(ctx context.Context) bool {
+	return ctx.Value(receivedKey{}) != nil
+}
+
+// Forward publishes the events named names on topic as they are dispatched,
+// except those received from a broker, which would bounce back and forth.
+// Events fail to dispatch when they cannot be published.
+func Forward(b Broker, topic string, names ...string) {
+	for _, name := range names {
+		Listen(name, func(ctx context.Context, e Event) error {
+			if Received(ctx) {
+				return nil
+			}
+			payload, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			data, err := json.Marshal(&remoteEvent{Name: e.Name(), Payload: payload})
+			if err != nil {
+				return err
+			}
+			return b.Publish(ctx, topic, data)
+		})
+	}
+}
+
+// Receive dispatches the events received on topic until ctx is done. Those
+// of unregistered names are skipped, errors of listeners logged.
+func Receive(ctx context.Context, b Broker, topic, group string) error {
+	return b.Subscribe(ctx, topic, group, func(data []byte) {
+		e, err := decodeRemote(data)
+		if err != nil {
+			slog.Warn(fmt.Sprintf("events: received on %s: %s", topic, err))
+			return
+		}
+		if e == nil {
+			return
+		}
+		if err := Dispatch(context.WithValue(ctx, receivedKey{}, true), e); err != nil {
+			slog.Error(fmt.Sprintf("events: %s received on %s: %s", e.Name(), topic, err))
+		}
+	})
+}
+
+// ErrNotRemote is returned by Decode for names not registered with
+// RegisterRemote.
+var ErrNotRemote = errors.New("events: event not registered with RegisterRemote")
+
+// Decode decodes the JSON payload of an event named name, registered with
+// RegisterRemote.
+func Decode(name string, payload []byte) (Event, error) {
+	remoteMu.RLock()
+	t, ok := remoteTypes[name]
+	remoteMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotRemote, name)
+	}
+	e := reflect.New(t).Interface()
+	if err := json.Unmarshal(payload, e); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return e.(Event), nil
+}
+
+func decodeRemote(data []byte) (Event, error) {
+	var r remoteEvent
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	e, err := Decode(r.Name, r.Payload)
+	if errors.Is(err, ErrNotRemote) {
+		return nil, nil
+	}
+	return e, err
+}
@@ -0,0 +1,103 @@
+// Package events dispatches application events to the listeners registered
+// for their name.
+//
+//	events.Listen(repo.Created, func(ctx context.Context, e events.Event) error {
+//		audit.Record(ctx, e.(*repo.ModelEvent))
+//		return nil
+//	})
+package events
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// Event is anything dispatched through a Dispatcher.
+type Event interface {
+	Name() string
+}
+
+// Listener handles an event. Errors returned by listeners of synchronous
+// dispatches are passed back to the dispatcher's caller.
+type Listener func(ctx context.Context, e Event) error
+
+// Dispatcher routes events to listeners by name. A listener registered for
+// "*" receives every event.
+type Dispatcher struct {
+	mu        sync.RWMutex
+	listeners map[string][]Listener
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{listeners: make(map[string][]Listener)}
+}
+
+var defaultDispatcher = NewDispatcher()
+
+// Default returns the dispatcher used by the package level functions.
+func Default() *Dispatcher {
+	return defaultDispatcher
+}
+
+// Listen registers l for events named name.
+func (d *Dispatcher) Listen(name string, l Listener) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.listeners[name] = append(d.listeners[name], l)
+}
+
+// HasListeners reports whether an event named name would reach any listener.
+func (d *Dispatcher) HasListeners(name string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.listeners[name]) > 0 || len(d.listeners["*"]) > 0
+}
+
+func (d *Dispatcher) listenersFor(name string) []Listener {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	listeners := append([]Listener{}, d.listeners[name]...)
+	return append(listeners, d.listeners["*"]...)
+}
+
+// Dispatch calls the listeners of e in registration order and stops at the
+// first error, which is returned.
+func (d *Dispatcher) Dispatch(ctx context.Context, e Event) error {
+	for _, l := range d.listenersFor(e.Name()) {
+		if err := l(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DispatchAsync calls the listeners of e in the background. Errors are logged.
+// The listeners get a context that is not cancelled with ctx.
+func (d *Dispatcher) DispatchAsync(ctx context.Context, e Event) {
+	listeners := d.listenersFor(e.Name())
+	if len(listeners) == 0 {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		for _, l := range listeners {
+			if err := l(ctx, e); err != nil {
+				slog.Error(fmt.Sprintf("events: %s: %s", e.Name(), err))
+			}
+		}
+	}()
+}
+
+func Listen(name string, l Listener) {
+	defaultDispatcher.Listen(name, l)
+}
+
+func Dispatch(ctx context.Context, e Event) error {
+	return defaultDispatcher.Dispatch(ctx, e)
+}
+
+func DispatchAsync(ctx context.Context, e Event) {
+	defaultDispatcher.DispatchAsync(ctx, e)
+}
@@ -0,0 +1,193 @@
+package export
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/lemmego/lemmego/framework/cache"
+	"github.com/lemmego/lemmego/framework/queue"
+	"github.com/lemmego/lemmego/framework/storage"
+)
+
+// Exports too large for a request are defined by name and run by a worker,
+// which writes the file to a storage disk and reports its progress:
+//
+//	export.Define("users", func(ctx context.Context, f export.Format, params map[string]string) (export.Runner, error) {
+//		return &export.Export[User]{Name: "users", Format: f, Query: repo.New[User](ctx), Columns: userColumns}, nil
+//	})
+//
+//	s, err := export.Dispatch(ctx, "users", export.XLSX, nil, "")
+//	...
+//	s, ok := export.StatusOf(s.ID)
+
+// Runner is implemented by every Export.
+type Runner interface {
+	Filename() string
+	WriteTo(ctx context.Context, w io.Writer, progress Progress) error
+}
+
+// Definition builds a named export inside the worker from the parameters it
+// was dispatched with.
+type Definition func(ctx context.Context, format Format, params map[string]string) (Runner, error)
+
+var (
+	definitionsMu sync.RWMutex
+	definitions   = make(map[string]Definition)
+)
+
+func Define(name string, def Definition) {
+	definitionsMu.Lock()
+	defer definitionsMu.Unlock()
+	definitions[name] = def
+}
+
+const (
+	StatusQueued  = "queued"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// progressTTL is how long the status of an export can be looked up.
+const progressTTL = 24 * time.Hour
+
+// Status of a queued export. Path is set once it is done.
+type Status struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Done   int64  `json:"done"`
+	Total  int64  `json:"total"`
+	Disk   string `json:"disk,omitempty"`
+	Path   string `json:"path,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func init() {
+	queue.Register[Job]("export")
+}
+
+// Dispatch queues the named export, to be written to disk (the default one
+// when empty).
+func Dispatch(ctx context.Context, name string, format Format, params map[string]string, disk string) (*Status, error) {
+	definitionsMu.RLock()
+	_, ok := definitions[name]
+	definitionsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("export: %s is not defined", name)
+	}
+
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	s := &Status{ID: hex.EncodeToString(b), Name: name, Status: StatusQueued, Disk: disk}
+	s.save()
+
+	job := &Job{ID: s.ID, Name: name, Format: format, Params: params, Disk: disk}
+	// Tracked under the id of the export, see queue.Report
+	if _, err := queue.Dispatch(ctx, job, &queue.Options{ID: s.ID, Track: true}); err != nil {
+		return nil, err
+	}
+	// The sync driver has already run the job
+	if current, ok := StatusOf(s.ID); ok {
+		s = current
+	}
+	return s, nil
+}
+
+// StatusOf returns the status of a dispatched export.
+func StatusOf(id string) (*Status, bool) {
+	s, ok := cache.Default().Get(statusKey(id)).(Status)
+	if !ok {
+		return nil, false
+	}
+	return &s, true
+}
+
+func statusKey(id string) string {
+	return "export:" + id
+}
+
+func (s *Status) save() {
+	cache.Default().Put(statusKey(s.ID), *s, int(progressTTL.Seconds()))
+}
+
+// Job runs a named export on a worker.
+type Job struct {
+	ID     string            `json:"id"`
+	Name   string            `json:"name"`
+	Format Format            `json:"format"`
+	Params map[string]string `json:"params"`
+	Disk   string            `json:"disk"`
+}
+
+func (j *Job) Handle(ctx context.Context) error {
+	s := &Status{ID: j.ID, Name: j.Name, Status: StatusRunning, Disk: j.Disk}
+	s.save()
+
+	if err := j.run(ctx, s); err != nil {
+		s.Status, s.Error = StatusFailed, err.Error()
+		s.save()
+		return err
+	}
+
+	s.Status = StatusDone
+	s.save()
+	return nil
+}
+
+func (j *Job) run(ctx context.Context, s *Status) error {
+	definitionsMu.RLock()
+	def, ok := definitions[j.Name]
+	definitionsMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("export: %s is not defined", j.Name)
+	}
+
+	runner, err := def(ctx, j.Format, j.Params)
+	if err != nil {
+		return err
+	}
+
+	var diskName []string
+	if j.Disk != "" {
+		diskName = append(diskName, j.Disk)
+	}
+	disk, err := storage.Get(ctx, diskName...)
+	if err != nil {
+		return err
+	}
+
+	s.Path = path.Join("exports", j.ID, runner.Filename())
+	return Store(ctx, runner, disk, s.Path, func(done, total int64) {
+		s.Done, s.Total = done, total
+		s.save()
+		if total > 0 {
+			queue.Report(ctx, int(100*done/total), fmt.Sprintf("Exported %d of %d rows", done, total))
+		}
+	})
+}
+
+// Store writes the export to p on disk.
+func Store(ctx context.Context, runner Runner, disk *storage.Disk, p string, progress Progress) error {
+	// Disks only accept whole files, so the export is built in a temporary file first
+	scratch, err := storage.Temp(ctx)
+	if err != nil {
+		return err
+	}
+	tmp, err := scratch.Create("export-*")
+	if err != nil {
+		return err
+	}
+	defer tmp.Close()
+
+	if err := runner.WriteTo(ctx, tmp, progress); err != nil {
+		return err
+	}
+	return scratch.MoveToDisk(tmp.Name(), disk, p)
+}
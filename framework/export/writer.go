@@ -0,0 +1,175 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Writer writes rows in one format. Flush pushes buffered rows to the
+// underlying writer and Close finishes the file.
+type Writer interface {
+	Write(row []any) error
+	Flush() error
+	Close() error
+}
+
+func NewWriter(format Format, w io.Writer) (Writer, error) {
+	switch format {
+	case CSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case XLSX:
+		return newXLSXWriter(w)
+	}
+	return nil, fmt.Errorf("export: unsupported format %q", format)
+}
+
+// text renders a cell for formats without types.
+func text(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return text(*v)
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(v)
+}
+
+type csvWriter struct {
+	w    *csv.Writer
+	cell []string
+}
+
+func (c *csvWriter) Write(row []any) error {
+	c.cell = c.cell[:0]
+	for _, v := range row {
+		s := text(v)
+		if _, ok := v.(string); ok && len(s) > 0 {
+			switch s[0] {
+			case '=', '+', '-', '@', '\t', '\r':
+				// Spreadsheets would evaluate the cell as a formula
+				s = "'" + s
+			}
+		}
+		c.cell = append(c.cell, s)
+	}
+	return c.w.Write(c.cell)
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	return c.Flush()
+}
+
+// xlsxWriter writes a single sheet workbook. Strings are stored inline, so
+// no shared string table has to be held in memory until the end.
+type xlsxWriter struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+}
+
+var xlsxParts = []struct{ name, body string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+}
+
+func newXLSXWriter(w io.Writer) (*xlsxWriter, error) {
+	zw := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	// The sheet must be the last entry, it stays open while rows stream in
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(f)
+	_, err = sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	if err != nil {
+		return nil, err
+	}
+	return &xlsxWriter{zip: zw, sheet: sheet}, nil
+}
+
+func (x *xlsxWriter) Write(row []any) error {
+	x.sheet.WriteString("<row>")
+	for _, v := range row {
+		switch v := v.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			fmt.Fprintf(x.sheet, "<c><v>%d</v></c>", v)
+		case float32:
+			x.sheet.WriteString("<c><v>" + strconv.FormatFloat(float64(v), 'g', -1, 32) + "</v></c>")
+		case float64:
+			x.sheet.WriteString("<c><v>" + strconv.FormatFloat(v, 'g', -1, 64) + "</v></c>")
+		case bool:
+			b := "0"
+			if v {
+				b = "1"
+			}
+			x.sheet.WriteString(`<c t="b"><v>` + b + "</v></c>")
+		default:
+			x.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+			// EscapeText also replaces characters XML cannot carry
+			if err := xml.EscapeText(x.sheet, []byte(text(v))); err != nil {
+				return err
+			}
+			x.sheet.WriteString("</t></is></c>")
+		}
+	}
+	_, err := x.sheet.WriteString("</row>")
+	return err
+}
+
+func (x *xlsxWriter) Flush() error {
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zip.Flush()
+}
+
+func (x *xlsxWriter) Close() error {
+	if _, err := x.sheet.WriteString("</sheetData></worksheet>"); err != nil {
+		return err
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zip.Close()
+}
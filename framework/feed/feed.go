@@ -0,0 +1,369 @@
+// Package feed renders RSS 2.0 and Atom feeds from items provided by the app.
+//
+//	feed.Mount(r, "/feed", time.Hour, func(ctx context.Context) (*feed.Feed, error) {
+//		posts, err := repo.New[Post](ctx).Order("published_at desc").Limit(20).Find()
+//		if err != nil {
+//			return nil, err
+//		}
+//		return &feed.Feed{
+//			Title: "ACME blog",
+//			Link:  "https://example.com/blog",
+//			Items: feed.FromSlice(posts, func(p *Post) feed.Item {
+//				return feed.Item{Title: p.Title, Link: "https://example.com/posts/" + p.Slug, Published: p.PublishedAt}
+//			}),
+//		}, nil
+//	})
+package feed
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/config"
+	"github.com/lemmego/lemmego/framework/cache"
+)
+
+// cacheTag marks every cached feed, see Flush.
+const cacheTag = "feed"
+
+type Format string
+
+const (
+	RSS  Format = "rss"
+	Atom Format = "atom"
+)
+
+func (f Format) ContentType() string {
+	if f == Atom {
+		return "application/atom+xml; charset=utf-8"
+	}
+	return "application/rss+xml; charset=utf-8"
+}
+
+// Item is a feed entry.
+type Item struct {
+	// ID is a permanent identifier, Link by default.
+	ID    string
+	Title string
+	Link  string
+	// Summary is plain text, Content may be HTML.
+	Summary    string
+	Content    string
+	Author     string
+	Categories []string
+	Published  time.Time
+	// Updated defaults to Published.
+	Updated time.Time
+}
+
+// Feed describes the channel and yields its items, newest first.
+type Feed struct {
+	Title       string
+	Link        string
+	Description string
+	// ID is the Atom feed id, Link by default.
+	ID string
+	// Self is the URL the feed is served at, filled in by Mount.
+	Self     string
+	Author   string
+	Language string
+	// Updated defaults to the most recent item.
+	Updated time.Time
+	Items   iter.Seq2[Item, error]
+}
+
+// FromSlice yields an Item for each row.
+func FromSlice[T any](rows []T, item func(*T) Item) iter.Seq2[Item, error] {
+	return func(yield func(Item, error) bool) {
+		for i := range rows {
+			if !yield(item(&rows[i]), nil) {
+				return
+			}
+		}
+	}
+}
+
+func (f *Feed) items() ([]Item, time.Time, error) {
+	var items []Item
+	updated := f.Updated
+	if f.Items != nil {
+		for item, err := range f.Items {
+			if err != nil {
+				return nil, time.Time{}, err
+			}
+			if item.ID == "" {
+				item.ID = item.Link
+			}
+			if item.Updated.IsZero() {
+				item.Updated = item.Published
+			}
+			if f.Updated.IsZero() && item.Updated.After(updated) {
+				updated = item.Updated
+			}
+			items = append(items, item)
+		}
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	return items, updated.UTC(), nil
+}
+
+type rssDoc struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          *atomLink `xml:"atom:link,omitempty"`
+	Language      string    `xml:"language,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title,omitempty"`
+	Link        string   `xml:"link,omitempty"`
+	GUID        rssGUID  `xml:"guid"`
+	Description string   `xml:"description,omitempty"`
+	Author      string   `xml:"author,omitempty"`
+	Categories  []string `xml:"category"`
+	PubDate     string   `xml:"pubDate,omitempty"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// WriteRSS writes the feed as RSS 2.0.
+func (f *Feed) WriteRSS(w io.Writer) error {
+	items, updated, err := f.items()
+	if err != nil {
+		return err
+	}
+
+	doc := rssDoc{Version: "2.0", Atom: "http://www.w3.org/2005/Atom", Channel: rssChannel{
+		Title:         f.Title,
+		Link:          f.Link,
+		Description:   f.Description,
+		Language:      f.Language,
+		LastBuildDate: updated.Format(time.RFC1123Z),
+	}}
+	if doc.Channel.Description == "" {
+		// Required by the specification
+		doc.Channel.Description = f.Title
+	}
+	if f.Self != "" {
+		doc.Channel.Self = &atomLink{Href: f.Self, Rel: "self", Type: RSS.mediaType()}
+	}
+	for _, item := range items {
+		description := item.Content
+		if description == "" {
+			description = item.Summary
+		}
+		ri := rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			GUID:        rssGUID{Value: item.ID, IsPermaLink: item.ID == item.Link},
+			Description: description,
+			Author:      item.Author,
+			Categories:  item.Categories,
+		}
+		if !item.Published.IsZero() {
+			ri.PubDate = item.Published.UTC().Format(time.RFC1123Z)
+		}
+		doc.Channel.Items = append(doc.Channel.Items, ri)
+	}
+	return encode(w, doc)
+}
+
+type atomDoc struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Lang    string      `xml:"xml:lang,attr,omitempty"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Links   []atomLink  `xml:"link"`
+	Updated string      `xml:"updated"`
+	Author  *atomPerson `xml:"author,omitempty"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Links      []atomLink     `xml:"link"`
+	Published  string         `xml:"published,omitempty"`
+	Updated    string         `xml:"updated"`
+	Author     *atomPerson    `xml:"author,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary,omitempty"`
+	Content    *atomText      `xml:"content,omitempty"`
+}
+
+// WriteAtom writes the feed as Atom 1.0.
+func (f *Feed) WriteAtom(w io.Writer) error {
+	items, updated, err := f.items()
+	if err != nil {
+		return err
+	}
+
+	doc := atomDoc{Lang: f.Language, ID: f.ID, Title: f.Title, Updated: updated.Format(time.RFC3339)}
+	if doc.ID == "" {
+		doc.ID = f.Link
+	}
+	if f.Link != "" {
+		doc.Links = append(doc.Links, atomLink{Href: f.Link, Rel: "alternate", Type: "text/html"})
+	}
+	if f.Self != "" {
+		doc.Links = append(doc.Links, atomLink{Href: f.Self, Rel: "self", Type: Atom.mediaType()})
+	}
+	if f.Author != "" {
+		doc.Author = &atomPerson{Name: f.Author}
+	}
+	for _, item := range items {
+		e := atomEntry{ID: item.ID, Title: item.Title, Updated: updated.Format(time.RFC3339)}
+		if !item.Updated.IsZero() {
+			e.Updated = item.Updated.UTC().Format(time.RFC3339)
+		}
+		if !item.Published.IsZero() {
+			e.Published = item.Published.UTC().Format(time.RFC3339)
+		}
+		if item.Link != "" {
+			e.Links = []atomLink{{Href: item.Link, Rel: "alternate"}}
+		}
+		if item.Author != "" {
+			e.Author = &atomPerson{Name: item.Author}
+		}
+		for _, c := range item.Categories {
+			e.Categories = append(e.Categories, atomCategory{Term: c})
+		}
+		if item.Summary != "" {
+			e.Summary = &atomText{Value: item.Summary}
+		}
+		if item.Content != "" {
+			e.Content = &atomText{Type: "html", Value: item.Content}
+		}
+		doc.Entries = append(doc.Entries, e)
+	}
+	return encode(w, doc)
+}
+
+func (f Format) mediaType() string {
+	return strings.TrimSuffix(f.ContentType(), "; charset=utf-8")
+}
+
+func encode(w io.Writer, doc any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Write writes the feed in the given format.
+func (f *Feed) Write(w io.Writer, format Format) error {
+	switch format {
+	case RSS:
+		return f.WriteRSS(w)
+	case Atom:
+		return f.WriteAtom(w)
+	}
+	return fmt.Errorf("feed: unknown format %q", format)
+}
+
+// Mount serves the feed as RSS at path and as Atom at path/atom, building it
+// at most once per ttl.
+func Mount(r app.Router, path string, ttl time.Duration, build func(ctx context.Context) (*Feed, error)) {
+	path = "/" + strings.Trim(path, "/")
+	r.Handle("GET "+path, Handler(RSS, ttl, build))
+	r.Handle("GET "+path+"/atom", Handler(Atom, ttl, build))
+}
+
+// Handler serves the feed in one format, caching the rendered document for ttl.
+func Handler(format Format, ttl time.Duration, build func(ctx context.Context) (*Feed, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		key := "feed:" + string(format) + ":" + req.URL.Path
+		body, err := cache.Remember(cache.Default(), key, ttl, func() ([]byte, error) {
+			f, err := build(req.Context())
+			if err != nil {
+				return nil, err
+			}
+			if f.Self == "" {
+				f.Self = requestURL(req)
+			}
+			var out bytes.Buffer
+			if err := f.Write(&out, format); err != nil {
+				return nil, err
+			}
+			return out.Bytes(), nil
+		}, cacheTag)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		sum := sha256.Sum256(body)
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(ttl.Seconds())))
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:8])+`"`)
+		http.ServeContent(w, req, "", time.Time{}, bytes.NewReader(body))
+	})
+}
+
+// requestURL is the absolute URL of req, on app.url when it is set.
+func requestURL(req *http.Request) string {
+	if base := config.Get("app.url", "").(string); base != "" {
+		return strings.TrimRight(base, "/") + req.URL.Path
+	}
+	scheme := "http"
+	if req.TLS != nil || req.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + req.Host + req.URL.Path
+}
+
+// Flush drops the cached feeds, so the next request rebuilds them after
+// content changed.
+func Flush() {
+	cache.Default().FlushTags(cacheTag)
+}
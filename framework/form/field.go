@@ -0,0 +1,353 @@
+package form
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/a-h/templ"
+	"github.com/lemmego/lemmego/framework/validation"
+)
+
+// Input types of fields.
+const (
+	Text     = "text"
+	Textarea = "textarea"
+	Password = "password"
+	Hidden   = "hidden"
+	Number   = "number"
+	Email    = "email"
+	URL      = "url"
+	Checkbox = "checkbox"
+	Date     = "date"
+	DateTime = "datetime-local"
+	Select   = "select"
+)
+
+const (
+	dateLayout     = "2006-01-02"
+	dateTimeLayout = "2006-01-02T15:04"
+)
+
+// Field is an input of a form, read from a struct field and its form and
+// validate tags.
+type Field struct {
+	// Name is the submitted key, the one validation errors are reported
+	// under.
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	Input string `json:"input"`
+	// Options of a select, from an in= validation rule.
+	Options     []string `json:"options,omitempty"`
+	Placeholder string   `json:"placeholder,omitempty"`
+	Required    bool     `json:"required"`
+	// MinLength and MaxLength come from min, max and between rules on
+	// strings, Min and Max from the same rules on numbers.
+	MinLength int  `json:"min_length,omitempty"`
+	MaxLength int  `json:"max_length,omitempty"`
+	Min       *int `json:"min,omitempty"`
+	Max       *int `json:"max,omitempty"`
+
+	index []int
+	typ   reflect.Type
+}
+
+// NewField reads a struct field. The form tag takes a comma separated list
+// of:
+//
+//	label=Text        the label, the field name split into words by default
+//	placeholder=Text  the placeholder
+//	textarea          a textarea instead of a text input
+//	password          a password input, never filled back
+//	hidden            a hidden input
+//
+// The validate tag picks the input type and adds the HTML constraints
+// matching its rules.
+func NewField(sf reflect.StructField) (*Field, error) {
+	f := &Field{
+		Name:  validation.FieldName(sf),
+		Label: Humanize(sf.Name),
+		index: sf.Index,
+		typ:   sf.Type,
+	}
+	f.Input = inputFor(f.typ)
+	applyRules(f, sf.Tag.Get("validate"))
+
+	for _, opt := range strings.Split(sf.Tag.Get("form"), ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(opt), "=")
+		switch name {
+		case "":
+		case "label":
+			f.Label = arg
+		case "placeholder":
+			f.Placeholder = arg
+		case "textarea":
+			f.Input = Textarea
+		case "password":
+			f.Input = Password
+		case "hidden":
+			f.Input = Hidden
+		default:
+			return nil, fmt.Errorf("form: %s: unknown option %q", sf.Name, name)
+		}
+	}
+	return f, nil
+}
+
+var textUnmarshaler = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+func inputFor(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeFor[time.Time]() {
+		return DateTime
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return Checkbox
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return Number
+	}
+	return Text
+}
+
+func applyRules(f *Field, tag string) {
+	numeric := f.Input == Number
+	for _, spec := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(spec), "=")
+		switch name {
+		case "required":
+			f.Required = f.Input != Checkbox
+		case "email":
+			f.Input = Email
+		case "url":
+			f.Input = URL
+		case "in":
+			f.Input, f.Options = Select, strings.Split(arg, "|")
+		case "date":
+			if arg == "" || arg == dateLayout {
+				f.Input = Date
+			}
+		case "min", "max":
+			n, err := strconv.Atoi(arg)
+			if err != nil {
+				continue
+			}
+			switch {
+			case numeric && name == "min":
+				f.Min = &n
+			case numeric:
+				f.Max = &n
+			case name == "min":
+				f.MinLength = n
+			default:
+				f.MaxLength = n
+			}
+		case "between":
+			lo, hi, _ := strings.Cut(arg, ":")
+			min, err1 := strconv.Atoi(lo)
+			max, err2 := strconv.Atoi(hi)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			if numeric {
+				f.Min, f.Max = &min, &max
+			} else {
+				f.MinLength, f.MaxLength = min, max
+			}
+		}
+	}
+}
+
+// Humanize splits a Go name into words: "CreatedAt" is "Created at".
+func Humanize(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			b.WriteByte(' ')
+			if i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+				r = unicode.ToLower(r)
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Attrs are the HTML constraints of the field's input.
+func (f *Field) Attrs() templ.Attributes {
+	a := templ.Attributes{}
+	if f.Required && f.Input != Checkbox && f.Input != Hidden {
+		a["required"] = true
+	}
+	if f.Placeholder != "" {
+		a["placeholder"] = f.Placeholder
+	}
+	if f.MinLength > 0 {
+		a["minlength"] = strconv.Itoa(f.MinLength)
+	}
+	if f.MaxLength > 0 {
+		a["maxlength"] = strconv.Itoa(f.MaxLength)
+	}
+	if f.Min != nil {
+		a["min"] = strconv.Itoa(*f.Min)
+	}
+	if f.Max != nil {
+		a["max"] = strconv.Itoa(*f.Max)
+	}
+	if f.Input == Number {
+		t := f.typ
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		a["step"] = "1"
+		if t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64 {
+			a["step"] = "any"
+		}
+	}
+	return a
+}
+
+// Value returns the field of model, a pointer to the struct the field was
+// read from, as an input value.
+func (f *Field) Value(model any) string {
+	v := reflect.ValueOf(model).Elem().FieldByIndex(f.index)
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch x := v.Interface().(type) {
+	case time.Time:
+		if x.IsZero() {
+			return ""
+		}
+		if f.Input == Date {
+			return x.Format(dateLayout)
+		}
+		return x.Format(dateTimeLayout)
+	case encoding.TextMarshaler:
+		text, _ := x.MarshalText()
+		return string(text)
+	case fmt.Stringer:
+		return x.String()
+	}
+	if v.Kind() == reflect.Bool {
+		if v.Bool() {
+			return "1"
+		}
+		return ""
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// Set parses a submitted value into the field of model. The error is the
+// message shown under the input.
+func (f *Field) Set(model any, raw string) error {
+	return f.set(reflect.ValueOf(model).Elem().FieldByIndex(f.index), raw)
+}
+
+func (f *Field) set(v reflect.Value, raw string) error {
+	if v.Kind() == reflect.Pointer {
+		if raw == "" && f.Input != Checkbox {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		ptr := reflect.New(v.Type().Elem())
+		if err := parse(ptr.Elem(), raw, f.Input); err != nil {
+			return err
+		}
+		v.Set(ptr)
+		return nil
+	}
+	return parse(v, raw, f.Input)
+}
+
+// Parse reads a submitted value as the field's Go type, e.g. to compare it
+// with a column.
+func (f *Field) Parse(raw string) (any, error) {
+	v := reflect.New(f.typ).Elem()
+	if err := f.set(v, raw); err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+func parse(v reflect.Value, raw string, input string) error {
+	if v.Type() == reflect.TypeFor[time.Time]() {
+		if raw == "" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		layout := dateTimeLayout
+		if input == Date {
+			layout = dateLayout
+		}
+		t, err := time.ParseInLocation(layout, raw, time.Local)
+		if err != nil {
+			return errors.New("This field must be a valid date")
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshaler) {
+		if raw == "" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw)); err != nil {
+			return errors.New("This field is not valid")
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		v.SetBool(raw != "" && raw != "0" && raw != "false")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if raw == "" {
+			v.SetInt(0)
+			return nil
+		}
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("This field must be a whole number")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if raw == "" {
+			v.SetUint(0)
+			return nil
+		}
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("This field must be a positive whole number")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if raw == "" {
+			v.SetFloat(0)
+			return nil
+		}
+		n, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return errors.New("This field must be a number")
+		}
+		v.SetFloat(n)
+	default:
+		return errors.New("This field cannot be edited")
+	}
+	return nil
+}
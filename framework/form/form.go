@@ -0,0 +1,219 @@
+// Package form renders forms from input structs and binds their submissions
+// back, so views stop repeating labels, old input, error messages and CSRF
+// fields by hand.
+//
+//	type LoginInput struct {
+//		Email    string `in:"form=email" validate:"required,email"`
+//		Password string `in:"form=password" form:"password" validate:"required"`
+//		Remember bool   `in:"form=remember" form:"label=Remember me"`
+//	}
+//
+//	r.Get("/login", func(c *app.Context) error {
+//		f := form.New("/login", &LoginInput{}).Load(c)
+//		f.Submit = "Sign in"
+//		return c.Templ(templates.BaseLayout(form.View(f)))
+//	})
+//
+//	r.Post("/login", func(c *app.Context) error {
+//		var in LoginInput
+//		if err := form.Bind(c, &in); err != nil {
+//			return err // back to the form with the errors and old input
+//		}
+//		...
+//	})
+//
+// Custom layouts render fields one at a time with form.Row, or the input
+// alone with form.Control and form.Errors.
+//
+// Pages showing several forms give each its own error bag, so errors and
+// old input go back to the form that was submitted:
+//
+//	login := form.New("/login", &LoginInput{}).InBag("login").Load(c)
+//	register := form.New("/register", &RegisterInput{}).InBag("register").Load(c)
+package form
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sync"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/shared"
+	"github.com/lemmego/lemmego/framework/validation"
+)
+
+// Form is a form of an input struct, with the values and errors to show.
+type Form struct {
+	Action string
+	// Method is POST by default. PUT, PATCH and DELETE are sent as a POST
+	// with a _method field.
+	Method string
+	// Submit labels the submit button, "Submit" by default.
+	Submit string
+	Fields []*Field
+	Values map[string]string
+	Errors shared.ValidationErrors
+	// Bag is the error bag of the form, posted back in BagField. Inputs
+	// implementing validation.Bagged set it.
+	Bag string
+}
+
+// BagField is the hidden field carrying the error bag of a form.
+const BagField = "_bag"
+
+var fieldCache sync.Map // reflect.Type -> []*Field
+
+// fieldsOf reads the fields of an input type: the exported fields with an
+// in, form or validate tag.
+func fieldsOf(t reflect.Type) []*Field {
+	if fs, ok := fieldCache.Load(t); ok {
+		return fs.([]*Field)
+	}
+
+	var fs []*Field
+	for _, sf := range reflect.VisibleFields(t) {
+		if !sf.IsExported() || sf.Anonymous || sf.Tag.Get("form") == "-" {
+			continue
+		}
+		_, in := sf.Tag.Lookup("in")
+		_, form := sf.Tag.Lookup("form")
+		_, validate := sf.Tag.Lookup("validate")
+		if !in && !form && !validate {
+			continue
+		}
+		f, err := NewField(sf)
+		if err != nil {
+			panic(fmt.Sprintf("form: %s: %v", t.Name(), err))
+		}
+		fs = append(fs, f)
+	}
+	fieldCache.Store(t, fs)
+	return fs
+}
+
+// New returns the form of input, a pointer to a struct, filled with its
+// current values.
+func New(action string, input any) *Form {
+	f := &Form{
+		Action: action,
+		Method: http.MethodPost,
+		Fields: fieldsOf(reflect.TypeOf(input).Elem()),
+		Values: map[string]string{},
+		Errors: shared.ValidationErrors{},
+	}
+	for _, field := range f.Fields {
+		f.Values[field.Name] = field.Value(input)
+	}
+	if b, ok := input.(validation.Bagged); ok {
+		f.Bag = b.ErrorBag()
+	}
+	return f
+}
+
+// InBag sets the error bag of the form.
+func (f *Form) InBag(bag string) *Form {
+	f.Bag = bag
+	return f
+}
+
+// Load replaces the values and errors with those of the submission that
+// was sent back to the form, if any. Forms only take the errors of their
+// bag, and the old input of a submission of their bag.
+func (f *Form) Load(c *app.Context) *Form {
+	fl := flashed(c)
+	if fl.errors != nil {
+		f.Errors = validation.Bag(fl.errors, f.Bag)
+	}
+	if fl.input != nil && fl.input.Get(BagField) == f.Bag {
+		for _, field := range f.Fields {
+			f.Values[field.Name] = fl.input.Get(field.Name)
+		}
+	}
+	return f
+}
+
+type flash struct {
+	errors shared.ValidationErrors
+	input  url.Values
+}
+
+const flashKey = "form.flash"
+
+// flashed pops the errors and old input from the session once per
+// request, so every form of a page can read them.
+func flashed(c *app.Context) *flash {
+	if fl, ok := c.Get(flashKey).(*flash); ok {
+		return fl
+	}
+	fl := &flash{}
+	fl.errors, _ = c.PopSession("errors").(shared.ValidationErrors)
+	switch input := c.PopSession("input").(type) {
+	case map[string][]string:
+		fl.input = input
+	case url.Values:
+		fl.input = input
+	}
+	c.Set(flashKey, fl)
+	return fl
+}
+
+// Field returns the field submitted as name, or nil.
+func (f *Form) Field(name string) *Field {
+	for _, field := range f.Fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// Value returns what the input of a field shows, nothing for passwords.
+func (f *Form) Value(field *Field) string {
+	if field.Input == Password {
+		return ""
+	}
+	return f.Values[field.Name]
+}
+
+func (f *Form) submit() string {
+	if f.Submit == "" {
+		return "Submit"
+	}
+	return f.Submit
+}
+
+// Bind parses a submission into input, a pointer to a struct, and validates
+// it. Invalid input is reported as shared.ValidationErrors, in the bag the
+// form posted, which handlers return to send the user back to the form.
+func Bind(c *app.Context, input any) error {
+	values, err := c.Form()
+	if err != nil {
+		return c.BadRequest(err)
+	}
+	return BindValues(c.App(), url.Values(values), input)
+}
+
+// BindValues is Bind for values read elsewhere.
+func BindValues(a app.App, values url.Values, input any) error {
+	errs := shared.ValidationErrors{}
+	for _, field := range fieldsOf(reflect.TypeOf(input).Elem()) {
+		if err := field.Set(input, values.Get(field.Name)); err != nil {
+			errs[field.Name] = append(errs[field.Name], err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return validation.InBag(values.Get(BagField), errs)
+	}
+	return validation.InBag(values.Get(BagField), validation.Struct(app.NewValidator(a), input))
+}
+
+// formMethod is the method of the form element, which only knows GET and
+// POST.
+func formMethod(f *Form) string {
+	if f.Method == http.MethodGet {
+		return http.MethodGet
+	}
+	return http.MethodPost
+}
@@ -0,0 +1,87 @@
+// Package framework is the entry point of Lemmego applications. It wires
+// the services of the framework, from the database pools to the queue and
+// the scheduler, into the application of github.com/lemmego/api, and adds
+// the commands they come with:
+//
+//	func main() {
+//		framework.Configure(
+//			app.WithConfig(configs.Load()),
+//			app.WithRoutes(routes.Load()),
+//		).Run()
+//	}
+//
+// Commands of the application itself are given with app.WithCommands.
+//
+// The packages below it are versioned as the github.com/lemmego/lemmego/framework
+// module, apart from the code of any application. Applications extend them
+// without forking through the registries of each package, such as
+// queue.Register, schedule.Register, mail.SetDefault or push.SetDriver,
+// the drivers they take, and plugins for features of their own, see Use.
+package framework
+
+import (
+	"sync"
+
+	"github.com/lemmego/api/app"
+
+	"github.com/lemmego/lemmego/framework/commands"
+	_ "github.com/lemmego/lemmego/framework/providers"
+)
+
+// Plugin is a feature added to applications, registered by Use.
+type Plugin interface {
+	// Register adds the services of the plugin, before any is booted.
+	Register(a app.App) error
+	// Boot starts the plugin once every service is registered.
+	Boot(a app.App) error
+}
+
+// Commander is a Plugin adding console commands.
+type Commander interface {
+	Commands() []app.Command
+}
+
+// Router is a Plugin adding routes.
+type Router interface {
+	Routes(r app.Router)
+}
+
+var (
+	pluginsMu sync.Mutex
+	plugins   []Plugin
+)
+
+// Use registers plugins with the application, before Configure and usually
+// from init functions.
+func Use(p ...Plugin) {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+	for _, plugin := range p {
+		app.RegisterService(plugin.Register)
+		app.BootService(plugin.Boot)
+		plugins = append(plugins, plugin)
+	}
+}
+
+// Configure configures the application as app.Configure does, along with
+// the commands of the framework and of the plugins.
+func Configure(opts ...app.OptFunc) app.AppEngine {
+	all := []app.OptFunc{app.WithCommands(commands.Load())}
+	pluginsMu.Lock()
+	for _, p := range plugins {
+		if c, ok := p.(Commander); ok {
+			all = append(all, app.WithCommands(c.Commands()))
+		}
+		if r, ok := p.(Router); ok {
+			all = append(all, app.WithRoutes(r.Routes))
+		}
+	}
+	pluginsMu.Unlock()
+
+	// app.Configure takes a single value of each option, so they are applied one by one
+	var engine app.AppEngine
+	for _, opt := range append(all, opts...) {
+		engine = app.Configure(opt)
+	}
+	return engine
+}
@@ -0,0 +1,208 @@
+// Package geo stores coordinates in spatial columns and queries them by
+// distance or area, on PostgreSQL with PostGIS and on MySQL 8.
+package geo
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// SRID is the spatial reference of every point: WGS 84 longitude and latitude.
+const SRID = 4326
+
+var ErrInvalidPoint = errors.New("geo: invalid point")
+
+// Point is a WGS 84 coordinate, usable as a model field backed by a point or
+// geography column. Use *Point for nullable columns.
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// NewPoint returns the point at lat, lng.
+func NewPoint(lat, lng float64) Point {
+	return Point{Lat: lat, Lng: lng}
+}
+
+// Valid reports whether the coordinates are within range.
+func (p Point) Valid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lng >= -180 && p.Lng <= 180
+}
+
+// WKT returns the point as well-known text, longitude first.
+func (p Point) WKT() string {
+	return "POINT(" + strconv.FormatFloat(p.Lng, 'f', -1, 64) + " " + strconv.FormatFloat(p.Lat, 'f', -1, 64) + ")"
+}
+
+func (p Point) String() string {
+	return strconv.FormatFloat(p.Lat, 'f', -1, 64) + "," + strconv.FormatFloat(p.Lng, 'f', -1, 64)
+}
+
+// Distance returns the great-circle distance to q in meters.
+func (p Point) Distance(q Point) float64 {
+	const earthRadius = 6371008.8
+	lat1, lat2 := p.Lat*math.Pi/180, q.Lat*math.Pi/180
+	dLat, dLng := lat2-lat1, (q.Lng-p.Lng)*math.Pi/180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Value stores the point as EWKT, which PostGIS parses for geometry and
+// geography columns alike, when written without gorm.
+func (p Point) Value() (driver.Value, error) {
+	if !p.Valid() {
+		return nil, ErrInvalidPoint
+	}
+	return "SRID=" + strconv.Itoa(SRID) + ";" + p.WKT(), nil
+}
+
+// GormValue converts the point with the dialect's own constructor.
+func (p Point) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	if !p.Valid() {
+		_ = db.AddError(ErrInvalidPoint)
+		return clause.Expr{SQL: "NULL"}
+	}
+	if d := dialect(db); d != sqlite {
+		return clause.Expr{SQL: fromText(d), Vars: []any{p.WKT()}}
+	}
+	// Without spatial types the point is kept as text
+	return clause.Expr{SQL: "?", Vars: []any{p.WKT()}}
+}
+
+// GormDBDataType gives AutoMigrate the column type AddColumn would use.
+func (Point) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	switch dialect(db) {
+	case postgres:
+		return fmt.Sprintf("geography(Point, %d)", SRID)
+	case mysql:
+		return fmt.Sprintf("POINT SRID %d", SRID)
+	}
+	return "text"
+}
+
+// Scan reads a point as PostGIS returns it, hex encoded EWKB, as MySQL
+// does, a 4 byte SRID followed by WKB, or as well-known text.
+func (p *Point) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*p = Point{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("geo: cannot scan %T into a point", src)
+	}
+
+	if s := strings.ToUpper(strings.TrimSpace(string(data))); strings.HasPrefix(s, "SRID=") || strings.HasPrefix(s, "POINT") {
+		return p.parseWKT(string(data))
+	}
+	if decoded, err := hex.DecodeString(string(data)); err == nil {
+		return p.parseWKB(decoded)
+	}
+	if len(data) == 25 {
+		// MySQL prefixes the WKB with its SRID
+		data = data[4:]
+	}
+	return p.parseWKB(data)
+}
+
+// parseWKB reads a WKB or EWKB point.
+func (p *Point) parseWKB(data []byte) error {
+	if len(data) < 21 {
+		return ErrInvalidPoint
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	switch data[0] {
+	case 0:
+		order = binary.BigEndian
+	case 1:
+	default:
+		return ErrInvalidPoint
+	}
+
+	typ := order.Uint32(data[1:5])
+	data = data[5:]
+	const hasSRID = 0x20000000
+	if typ&hasSRID != 0 {
+		if len(data) < 20 {
+			return ErrInvalidPoint
+		}
+		data = data[4:]
+		typ &^= hasSRID
+	}
+	if typ != 1 || len(data) < 16 {
+		return ErrInvalidPoint
+	}
+	p.Lng = math.Float64frombits(order.Uint64(data[0:8]))
+	p.Lat = math.Float64frombits(order.Uint64(data[8:16]))
+	return nil
+}
+
+// parseWKT reads "POINT(lng lat)", optionally prefixed with "SRID=n;".
+func (p *Point) parseWKT(s string) error {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, ';'); i >= 0 {
+		s = s[i+1:]
+	}
+	open, end := strings.IndexByte(s, '('), strings.LastIndexByte(s, ')')
+	if open < 0 || end < open || !strings.EqualFold(strings.TrimSpace(s[:open]), "POINT") {
+		return ErrInvalidPoint
+	}
+	coords := strings.Fields(s[open+1 : end])
+	if len(coords) != 2 {
+		return ErrInvalidPoint
+	}
+	lng, err := strconv.ParseFloat(coords[0], 64)
+	if err != nil {
+		return ErrInvalidPoint
+	}
+	lat, err := strconv.ParseFloat(coords[1], 64)
+	if err != nil {
+		return ErrInvalidPoint
+	}
+	p.Lat, p.Lng = lat, lng
+	return nil
+}
+
+// UnmarshalJSON accepts {"lat": 1, "lng": 2}, and [lng, lat] as GeoJSON
+// orders coordinates.
+func (p *Point) UnmarshalJSON(data []byte) error {
+	var pair []float64
+	if err := json.Unmarshal(data, &pair); err == nil {
+		if len(pair) != 2 {
+			return ErrInvalidPoint
+		}
+		p.Lng, p.Lat = pair[0], pair[1]
+	} else {
+		var obj struct {
+			Lat *float64 `json:"lat"`
+			Lng *float64 `json:"lng"`
+		}
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		if obj.Lat == nil || obj.Lng == nil {
+			return ErrInvalidPoint
+		}
+		p.Lat, p.Lng = *obj.Lat, *obj.Lng
+	}
+	if !p.Valid() {
+		return ErrInvalidPoint
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrUnsupported is added to queries built for a database without spatial
+// functions.
+var ErrUnsupported = errors.New("geo: spatial queries need PostgreSQL with PostGIS or MySQL")
+
+// fromText is the dialect's constructor of a point from well-known text.
+func fromText(d string) string {
+	if d == mysql {
+		// MySQL otherwise reads SRID 4326 coordinates latitude first
+		return fmt.Sprintf("ST_GeomFromText(?, %d, 'axis-order=long-lat')", SRID)
+	}
+	return fmt.Sprintf("ST_GeomFromText(?, %d)", SRID)
+}
+
+// WithinRadius matches rows whose point lies within meters of center:
+//
+//	repo.New[Place](ctx).Where(geo.WithinRadius("location", center, 5000)).Find()
+func WithinRadius(column string, center Point, meters float64) clause.Expression {
+	return expr(func(d string) (clause.Expr, bool) {
+		col := clause.Column{Name: column}
+		switch d {
+		case postgres:
+			return clause.Expr{
+				SQL:  "ST_DWithin(?, " + fromText(postgres) + "::geography, ?)",
+				Vars: []any{col, center.WKT(), meters},
+			}, true
+		case mysql:
+			distance := sphereDistance(column, center)
+			// The bounding box lets the spatial index narrow the rows down
+			// before distances are computed
+			if b, ok := around(center, meters); ok {
+				return clause.Expr{SQL: "(? AND ? <= ?)", Vars: []any{b.expr(d, column), distance, meters}}, true
+			}
+			return clause.Expr{SQL: "? <= ?", Vars: []any{distance, meters}}, true
+		}
+		return clause.Expr{}, false
+	})
+}
+
+// WithinBox matches rows whose point lies within the box from its south-west
+// corner sw to its north-east corner ne. A box crossing the antimeridian has
+// sw.Lng greater than ne.Lng.
+func WithinBox(column string, sw, ne Point) clause.Expression {
+	return expr(func(d string) (clause.Expr, bool) {
+		if d != postgres && d != mysql {
+			return clause.Expr{}, false
+		}
+		if sw.Lng <= ne.Lng {
+			return box{sw, ne}.expr(d, column), true
+		}
+		return clause.Expr{SQL: "(? OR ?)", Vars: []any{
+			box{sw, Point{Lat: ne.Lat, Lng: 180}}.expr(d, column),
+			box{Point{Lat: sw.Lat, Lng: -180}, ne}.expr(d, column),
+		}}, true
+	})
+}
+
+// Distance is the distance in meters from a row's point to center, e.g. to
+// select it with Select("*, ? AS distance", geo.Distance("location", center)).
+func Distance(column string, center Point) clause.Expression {
+	return expr(func(d string) (clause.Expr, bool) {
+		switch d {
+		case postgres:
+			return clause.Expr{
+				SQL:  "ST_Distance(?, " + fromText(postgres) + "::geography)",
+				Vars: []any{clause.Column{Name: column}, center.WKT()},
+			}, true
+		case mysql:
+			return sphereDistance(column, center), true
+		}
+		return clause.Expr{}, false
+	})
+}
+
+// OrderByDistance sorts rows nearest to center first, for Order.
+func OrderByDistance(column string, center Point) clause.OrderBy {
+	return clause.OrderBy{Expression: Distance(column, center)}
+}
+
+// expr defers building to when the statement, and so the dialect, is known.
+// Dialects without spatial functions fail the query with ErrUnsupported.
+type expr func(dialect string) (clause.Expr, bool)
+
+func (e expr) Build(builder clause.Builder) {
+	stmt := builder.(*gorm.Statement)
+	x, ok := e(dialect(stmt.DB))
+	if !ok {
+		_ = stmt.AddError(ErrUnsupported)
+		x = clause.Expr{SQL: "1 = 0"}
+	}
+	x.Build(stmt)
+}
+
+func sphereDistance(column string, center Point) clause.Expr {
+	return clause.Expr{
+		SQL:  "ST_Distance_Sphere(?, " + fromText(mysql) + ")",
+		Vars: []any{clause.Column{Name: column}, center.WKT()},
+	}
+}
+
+// box is an area that does not cross the antimeridian.
+type box struct {
+	sw, ne Point
+}
+
+// around returns the box holding every point within meters of center, or
+// false when it would cross a pole or the antimeridian.
+func around(center Point, meters float64) (box, bool) {
+	const metersPerDegree = 111320.0
+	dLat := meters / metersPerDegree
+	dLng := meters / (metersPerDegree * math.Cos(center.Lat*math.Pi/180))
+	b := box{
+		sw: Point{Lat: center.Lat - dLat, Lng: center.Lng - dLng},
+		ne: Point{Lat: center.Lat + dLat, Lng: center.Lng + dLng},
+	}
+	return b, b.sw.Valid() && b.ne.Valid() && !math.IsInf(dLng, 0)
+}
+
+func (b box) expr(d, column string) clause.Expr {
+	col := clause.Column{Name: column}
+	if d == postgres {
+		return clause.Expr{
+			SQL:  fmt.Sprintf("ST_Intersects(?, ST_MakeEnvelope(?, ?, ?, ?, %d)::geography)", SRID),
+			Vars: []any{col, b.sw.Lng, b.sw.Lat, b.ne.Lng, b.ne.Lat},
+		}
+	}
+	return clause.Expr{SQL: "MBRCovers(" + fromText(mysql) + ", ?)", Vars: []any{b.polygon(), col}}
+}
+
+// polygon returns the box as well-known text, counter-clockwise.
+func (b box) polygon() string {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	w, s, e, n := f(b.sw.Lng), f(b.sw.Lat), f(b.ne.Lng), f(b.ne.Lat)
+	return "POLYGON((" + w + " " + s + ", " + e + " " + s + ", " + e + " " + n + ", " + w + " " + n + ", " + w + " " + s + "))"
+}
@@ -0,0 +1,111 @@
+package geo
+
+import (
+	"fmt"
+	"os"
+
+	"gorm.io/gorm"
+)
+
+const (
+	postgres = "postgres"
+	mysql    = "mysql"
+	sqlite   = "sqlite"
+)
+
+// migrationDialect reads DB_DRIVER the way the migration schema builder does.
+func migrationDialect() string {
+	switch os.Getenv("DB_DRIVER") {
+	case "postgres", "pgsql":
+		return postgres
+	case mysql:
+		return mysql
+	}
+	return sqlite
+}
+
+func dialect(db *gorm.DB) string {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return postgres
+	case "mysql":
+		return mysql
+	}
+	return sqlite
+}
+
+// Column describes a point column for AddColumn.
+type Column struct {
+	Name     string
+	Nullable bool
+	// Geometry stores planar geometry instead of geography on PostgreSQL.
+	// The query helpers expect geography.
+	Geometry bool
+}
+
+// AddColumn returns the statement adding a point column to table, for use in
+// migrations next to the schema builder, which has no spatial types:
+//
+//	tx.Exec(geo.AddColumn("places", geo.Column{Name: "location"}))
+//
+// PostgreSQL needs the postgis extension, see EnablePostGIS. SQLite, which
+// has no spatial types, gets a text column holding well-known text.
+func AddColumn(table string, c Column) string {
+	null := " NOT NULL"
+	if c.Nullable {
+		null = ""
+	}
+	switch migrationDialect() {
+	case postgres:
+		typ := "geography"
+		if c.Geometry {
+			typ = "geometry"
+		}
+		return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s(Point, %d)%s", table, c.Name, typ, SRID, null)
+	case mysql:
+		// The SRID attribute lets the spatial index be used
+		return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s POINT%s SRID %d", table, c.Name, null, SRID)
+	}
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s TEXT", table, c.Name)
+}
+
+// DropColumn returns the statement removing a point column, and its index.
+func DropColumn(table, column string) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, column)
+}
+
+// CreateIndex returns the statement adding a spatial index on a point column,
+// or an empty string on SQLite. MySQL only indexes NOT NULL columns.
+func CreateIndex(table, column string) string {
+	switch migrationDialect() {
+	case postgres:
+		return fmt.Sprintf("CREATE INDEX %s ON %s USING GIST (%s)", indexName(table, column), table, column)
+	case mysql:
+		return fmt.Sprintf("CREATE SPATIAL INDEX %s ON %s (%s)", indexName(table, column), table, column)
+	}
+	return ""
+}
+
+// DropIndex undoes CreateIndex.
+func DropIndex(table, column string) string {
+	switch migrationDialect() {
+	case postgres:
+		return fmt.Sprintf("DROP INDEX IF EXISTS %s", indexName(table, column))
+	case mysql:
+		return fmt.Sprintf("DROP INDEX %s ON %s", indexName(table, column), table)
+	}
+	return ""
+}
+
+func indexName(table, column string) string {
+	return table + "_" + column + "_spatial"
+}
+
+// EnablePostGIS returns the statement installing PostGIS, which needs a role
+// allowed to create extensions, or an empty string on other dialects.
+func EnablePostGIS() string {
+	if migrationDialect() == postgres {
+		return "CREATE EXTENSION IF NOT EXISTS postgis"
+	}
+	return ""
+}
@@ -0,0 +1,52 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Default is shared by outbound calls so connections are pooled.
+var Default = &http.Client{Timeout: 30 * time.Second}
+
+// Do sends req bound to ctx, so the outbound call is aborted when the request
+// that triggered it is cancelled or times out.
+func Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	return Default.Do(req.WithContext(ctx))
+}
+
+// Get issues a GET bound to ctx.
+func Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return Default.Do(req)
+}
+
+// Post issues a POST bound to ctx.
+func Post(ctx context.Context, url string, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return Default.Do(req)
+}
+
+// GetJSON issues a GET bound to ctx and decodes a 2xx JSON response into v.
+func GetJSON(ctx context.Context, url string, v any) error {
+	resp, err := Get(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("httpclient: GET %s returned %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,275 @@
+package httpsig
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lemmego/api/app"
+
+	"github.com/lemmego/lemmego/framework/cache"
+	"github.com/lemmego/lemmego/framework/repo"
+	"github.com/lemmego/lemmego/framework/str"
+	"gorm.io/gorm/clause"
+)
+
+// clientContextKey is the context key of the client of a verified request.
+const clientContextKey = "_httpsig_client"
+
+// Client is a partner signing its requests, in the signing_clients table.
+type Client struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+	// Key identifies the client in the X-Client-Id header.
+	Key    string `gorm:"column:client_key" json:"key"`
+	Name   string `json:"name"`
+	Secret string `gorm:"serializer:encrypted" json:"-"`
+	// PreviousSecret is accepted as well while the client moves to a
+	// rotated secret.
+	PreviousSecret string    `gorm:"serializer:encrypted" json:"-"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (Client) TableName() string {
+	return "signing_clients"
+}
+
+// CreateClient adds a client, returned with its secret to hand over.
+func CreateClient(ctx context.Context, name string) (*Client, error) {
+	c := &Client{Key: "pc_" + str.RandomString(24), Name: name, Secret: str.RandomString(48)}
+	if err := repo.New[Client](ctx).Create(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// RotateSecret gives a client a new secret, returned with the client. The
+// old one is accepted until ForgetPreviousSecret, so the client can switch
+// without downtime.
+func RotateSecret(ctx context.Context, key string) (*Client, error) {
+	c, err := findClient(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	c.PreviousSecret, c.Secret = c.Secret, str.RandomString(48)
+	if err := repo.New[Client](ctx).Save(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ForgetPreviousSecret stops accepting the secret a client had before its
+// last rotation.
+func ForgetPreviousSecret(ctx context.Context, key string) error {
+	c, err := findClient(ctx, key)
+	if err != nil {
+		return err
+	}
+	c.PreviousSecret = ""
+	return repo.New[Client](ctx).Save(c)
+}
+
+// DeleteClient removes a client, refusing its requests from then on.
+func DeleteClient(ctx context.Context, key string) error {
+	n, err := repo.New[Client](ctx).Delete("client_key = ?", key)
+	if err == nil && n == 0 {
+		err = ErrUnknownClient
+	}
+	return err
+}
+
+// findClient returns the client of key. Lookups are cached, and dropped
+// whenever the table changes.
+func findClient(ctx context.Context, key string) (*Client, error) {
+	found, err := repo.New[Client](ctx).Cached(time.Minute).Where("client_key = ?", key).Limit(1).Find()
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, ErrUnknownClient
+	}
+	return &found[0], nil
+}
+
+// NonceStore remembers the nonces of verified requests.
+type NonceStore interface {
+	// Use records a nonce for ttl, reporting false when it already was.
+	Use(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+}
+
+// CacheNonces keeps nonces in the default cache store. Unless the store is
+// shared, a request can be replayed once on each instance within the
+// tolerance, so it only suits applications running a single instance.
+type CacheNonces struct {
+	mu sync.Mutex
+}
+
+func (n *CacheNonces) Use(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	key := "httpsig:nonce:" + nonce
+	if cache.Default().Get(key) != nil {
+		return false, nil
+	}
+	cache.Default().Put(key, true, int(ttl.Seconds())+1)
+	return true, nil
+}
+
+// Forget drops a nonce, for a request whose handling failed to be retried.
+func (n *CacheNonces) Forget(ctx context.Context, nonce string) error {
+	cache.Default().Forget("httpsig:nonce:" + nonce)
+	return nil
+}
+
+// usedNonce is a nonce in the used_nonces table.
+type usedNonce struct {
+	Nonce     string `gorm:"primaryKey"`
+	ExpiresAt time.Time
+}
+
+func (usedNonce) TableName() string {
+	return "used_nonces"
+}
+
+// DatabaseNonces keeps nonces in the used_nonces table, shared by every
+// instance, its primary key refusing a nonce recorded twice. Expired nonces
+// are pruned as new ones are used, once a minute at most.
+type DatabaseNonces struct {
+	connName []string
+
+	mu     sync.Mutex
+	pruned time.Time
+}
+
+func NewDatabaseNonces(connName ...string) *DatabaseNonces {
+	return &DatabaseNonces{connName: connName}
+}
+
+func (n *DatabaseNonces) Use(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	n.mu.Lock()
+	prune := now.Sub(n.pruned) >= time.Minute
+	if prune {
+		n.pruned = now
+	}
+	n.mu.Unlock()
+	if prune {
+		if err := repo.DB(ctx, n.connName...).Where("expires_at <= ?", now).Delete(&usedNonce{}).Error; err != nil {
+			return false, err
+		}
+	}
+
+	result := repo.DB(ctx, n.connName...).Clauses(clause.OnConflict{DoNothing: true}).Create(&usedNonce{
+		Nonce:     nonce,
+		ExpiresAt: now.Add(ttl),
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Forget drops a nonce, for a request whose handling failed to be retried.
+func (n *DatabaseNonces) Forget(ctx context.Context, nonce string) error {
+	return repo.DB(ctx, n.connName...).Where("nonce = ?", nonce).Delete(&usedNonce{}).Error
+}
+
+var defaultNonces = NewDatabaseNonces()
+
+type VerifyOptions struct {
+	// Tolerance is how far the timestamp of a request may be from now,
+	// 5 minutes when zero.
+	Tolerance time.Duration
+	// MaxBody is the largest body verified, 1MB when zero.
+	MaxBody int64
+	// Nonces defaults to a DatabaseNonces, shared by the instances of the
+	// application.
+	Nonces NonceStore
+}
+
+// Verify refuses requests not signed by a client of the signing_clients
+// table, with a timestamp within the tolerance and a nonce not seen before.
+// Handlers read the client with ClientFrom.
+func Verify(opts ...*VerifyOptions) app.Handler {
+	o := &VerifyOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		o = opts[0]
+	}
+	if o.Tolerance <= 0 {
+		o.Tolerance = 5 * time.Minute
+	}
+	if o.MaxBody <= 0 {
+		o.MaxBody = 1 << 20
+	}
+	if o.Nonces == nil {
+		o.Nonces = defaultNonces
+	}
+
+	return func(c *app.Context) error {
+		client, err := verify(c.Request(), o)
+		switch {
+		case errors.Is(err, ErrBodyTooLarge):
+			return c.Status(http.StatusRequestEntityTooLarge).Error(http.StatusRequestEntityTooLarge, err)
+		case errors.Is(err, ErrUnsigned), errors.Is(err, ErrSignature), errors.Is(err, ErrExpired),
+			errors.Is(err, ErrReplayed), errors.Is(err, ErrUnknownClient), errors.Is(err, ErrNonceMalformed):
+			return c.Status(http.StatusUnauthorized).Unauthorized(err)
+		case err != nil:
+			return err
+		}
+		c.Set(clientContextKey, client)
+		return c.Next()
+	}
+}
+
+// ClientFrom returns the client of a request let through by Verify.
+func ClientFrom(c *app.Context) (*Client, bool) {
+	client, ok := c.Get(clientContextKey).(*Client)
+	return client, ok
+}
+
+func verify(r *http.Request, o *VerifyOptions) (*Client, error) {
+	key, sig := r.Header.Get(ClientHeader), r.Header.Get(SignatureHeader)
+	ts, nonce := r.Header.Get(TimestampHeader), r.Header.Get(NonceHeader)
+	if key == "" || sig == "" || ts == "" || nonce == "" {
+		return nil, ErrUnsigned
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || time.Since(time.Unix(sec, 0)).Abs() > o.Tolerance {
+		return nil, ErrExpired
+	}
+	if len(nonce) < 16 || len(nonce) > 64 {
+		return nil, ErrNonceMalformed
+	}
+
+	client, err := findClient(r.Context(), key)
+	if err != nil {
+		return nil, err
+	}
+	body, err := readBody(r, o.MaxBody)
+	if err != nil {
+		return nil, err
+	}
+	valid := false
+	for _, secret := range []string{client.Secret, client.PreviousSecret} {
+		if secret != "" && hmac.Equal([]byte(sig), []byte(signature(secret, r, body))) {
+			valid = true
+		}
+	}
+	if !valid {
+		return nil, ErrSignature
+	}
+	// Only signed nonces are recorded, so others cannot use them up. They
+	// are kept as long as their timestamp is accepted.
+	fresh, err := o.Nonces.Use(r.Context(), key+":"+nonce, 2*o.Tolerance)
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		return nil, ErrReplayed
+	}
+	return client, nil
+}
@@ -0,0 +1,194 @@
+// Package i18n translates messages into the language of a request. Catalogs
+// are JSON files named after their locale, es.json or pt-BR.json, holding
+// translations keyed by the English message:
+//
+//	{
+//		"This field is required": ":attribute es obligatorio",
+//		"This field must have at least :count item|items": ":attribute necesita al menos :count elemento|elementos",
+//		"attributes": {"email": "correo electrónico"}
+//	}
+//
+// Nested objects are flattened with dots, so the attribute above is
+// "attributes.email". Messages replace :name placeholders with arguments,
+// and words written singular|plural agree with :count.
+//
+//	i18n.T(i18n.LocaleFrom(ctx), "Welcome back, :name", i18n.Args{"name": user.Name})
+package i18n
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lemmego/api/config"
+
+	"github.com/lemmego/lemmego/framework/assets"
+)
+
+// Args are the values of the placeholders of a message.
+type Args map[string]any
+
+// Catalog holds the messages of every locale.
+type Catalog struct {
+	mu       sync.RWMutex
+	messages map[string]map[string]string
+}
+
+// New returns an empty catalog.
+func New() *Catalog {
+	return &Catalog{messages: map[string]map[string]string{}}
+}
+
+var (
+	defaultOnce    sync.Once
+	defaultCatalog *Catalog
+)
+
+// Default returns the catalog loaded from app.lang_path, resources/lang by
+// default, on first use. A missing directory gives an empty catalog.
+func Default() *Catalog {
+	defaultOnce.Do(func() {
+		defaultCatalog = New()
+		dir := config.Get("app.lang_path", "resources/lang").(string)
+		if err := defaultCatalog.Load(assets.Dir(dir)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			panic(fmt.Sprintf("i18n: %v", err))
+		}
+	})
+	return defaultCatalog
+}
+
+// Add merges messages into a locale.
+func (c *Catalog) Add(locale string, messages map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m := c.messages[locale]
+	if m == nil {
+		m = map[string]string{}
+		c.messages[locale] = m
+	}
+	for k, v := range messages {
+		m[k] = v
+	}
+}
+
+// Load adds the <locale>.json files at the root of fsys.
+func (c *Catalog) Load(fsys fs.FS) error {
+	files, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		if _, err := fs.Stat(fsys, "."); err != nil {
+			return err
+		}
+	}
+	for _, name := range files {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		var tree map[string]any
+		if err := json.Unmarshal(data, &tree); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		messages := map[string]string{}
+		flatten("", tree, messages)
+		c.Add(strings.TrimSuffix(path.Base(name), ".json"), messages)
+	}
+	return nil
+}
+
+func flatten(prefix string, tree map[string]any, out map[string]string) {
+	for k, v := range tree {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch v := v.(type) {
+		case string:
+			out[key] = v
+		case map[string]any:
+			flatten(key, v, out)
+		}
+	}
+}
+
+// Locales lists the locales holding messages, sorted.
+func (c *Catalog) Locales() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	locales := make([]string, 0, len(c.messages))
+	for l := range c.messages {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Lookup returns the translation of key in locale, or in its language
+// alone, pt for pt-BR.
+func (c *Catalog) Lookup(locale, key string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if msg, ok := c.messages[locale][key]; ok {
+		return msg, true
+	}
+	if lang, _, found := strings.Cut(locale, "-"); found {
+		msg, ok := c.messages[lang][key]
+		return msg, ok
+	}
+	return "", false
+}
+
+// T translates key into locale and formats it with args. Keys without a
+// translation format as they are, so English messages need no catalog.
+func (c *Catalog) T(locale, key string, args Args) string {
+	if msg, ok := c.Lookup(locale, key); ok {
+		return Format(msg, args)
+	}
+	return Format(key, args)
+}
+
+// T translates with the Default catalog.
+func T(locale, key string, args Args) string {
+	return Default().T(locale, key, args)
+}
+
+var placeholder = regexp.MustCompile(`:[a-z_]+`)
+
+// Format replaces the :name placeholders of message with args, and picks
+// the singular or plural of words written singular|plural by the :count
+// argument.
+func Format(message string, args Args) string {
+	if count, ok := args["count"]; ok && strings.Contains(message, "|") {
+		message = plural(message, count)
+	}
+	return placeholder.ReplaceAllStringFunc(message, func(p string) string {
+		if v, ok := args[p[1:]]; ok {
+			return fmt.Sprint(v)
+		}
+		return p
+	})
+}
+
+func plural(message string, count any) string {
+	n, err := strconv.ParseFloat(fmt.Sprint(count), 64)
+	form := 1
+	if err == nil && n == 1 {
+		form = 0
+	}
+	words := strings.Split(message, " ")
+	for i, w := range words {
+		if forms := strings.Split(w, "|"); len(forms) > 1 {
+			words[i] = forms[min(form, len(forms)-1)]
+		}
+	}
+	return strings.Join(words, " ")
+}
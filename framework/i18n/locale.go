@@ -0,0 +1,103 @@
+package i18n
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/config"
+)
+
+// CookieName is the cookie holding the locale a user picked.
+const CookieName = "locale"
+
+type localeKey struct{}
+
+// WithLocale returns a copy of ctx in locale.
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// LocaleFrom returns the locale of ctx, or app.locale.
+func LocaleFrom(ctx context.Context) string {
+	if l, ok := ctx.Value(localeKey{}).(string); ok {
+		return l
+	}
+	return Fallback()
+}
+
+// Fallback is the app.locale setting, en by default.
+func Fallback() string {
+	return config.Get("app.locale", "en").(string)
+}
+
+// Detect picks the locale of a request among the Default catalog's and the
+// fallback: the locale cookie, then the Accept-Language header.
+func Detect(r *http.Request) string {
+	supported := append(Default().Locales(), Fallback())
+	if c, err := r.Cookie(CookieName); err == nil {
+		if l, ok := match(c.Value, supported); ok {
+			return l
+		}
+	}
+	for _, tag := range acceptLanguage(r.Header.Get("Accept-Language")) {
+		if l, ok := match(tag, supported); ok {
+			return l
+		}
+	}
+	return Fallback()
+}
+
+// match finds tag among supported, or a supported locale of its language.
+func match(tag string, supported []string) (string, bool) {
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+	lang, _, _ := strings.Cut(tag, "-")
+	for _, want := range []string{tag, lang} {
+		for _, s := range supported {
+			if strings.EqualFold(s, want) {
+				return s, true
+			}
+		}
+	}
+	return "", false
+}
+
+// acceptLanguage returns the tags of an Accept-Language header by
+// decreasing quality.
+func acceptLanguage(header string) []string {
+	type tag struct {
+		name string
+		q    float64
+	}
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if name == "" || name == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q > 0 {
+			tags = append(tags, tag{name, q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	names := make([]string, len(tags))
+	for i, t := range tags {
+		names[i] = t.name
+	}
+	return names
+}
+
+// Middleware puts the locale Detect picks in the request context.
+func Middleware(c *app.Context) error {
+	c.SetRequest(c.Request().WithContext(WithLocale(c.RequestContext(), Detect(c.Request()))))
+	return c.Next()
+}
@@ -0,0 +1,140 @@
+// Package ical writes and reads iCalendar (RFC 5545) files, such as the
+// invites of a booking sent by mail, and those people send back or forward:
+//
+//	cal := &ical.Calendar{Method: ical.Request, Events: []*ical.Event{{
+//		UID:       booking.UID,
+//		Start:     booking.StartsAt.In(loc),
+//		End:       booking.EndsAt.In(loc),
+//		Summary:   "Haircut with Sam",
+//		Organizer: &ical.Attendee{Email: "bookings@example.com", Name: "Example Salon"},
+//		Attendees: []ical.Attendee{{Email: customer.Email, Name: customer.Name, RSVP: true}},
+//	}}}
+//	msg.Attachments = append(msg.Attachments, cal.Attachment("invite.ics"))
+//
+// Times in a named location are written with its TZID and a VTIMEZONE of
+// its transitions, so calendars show them in the zone they were booked in.
+package ical
+
+import (
+	"strings"
+	"time"
+
+	"github.com/lemmego/api/config"
+
+	"github.com/lemmego/lemmego/framework/mail"
+)
+
+// Methods of calendars sent by mail (RFC 5546).
+const (
+	Publish = "PUBLISH"
+	Request = "REQUEST"
+	Reply   = "REPLY"
+	Cancel  = "CANCEL"
+)
+
+// Participation statuses of attendees.
+const (
+	NeedsAction = "NEEDS-ACTION"
+	Accepted    = "ACCEPTED"
+	Declined    = "DECLINED"
+	Tentative   = "TENTATIVE"
+)
+
+// Calendar is an iCalendar object holding events.
+type Calendar struct {
+	// ProdID names the product that made the calendar, the app when empty.
+	ProdID string
+	// Method is the iTIP method of calendars sent by mail, such as Request
+	// for invites and Cancel when they are called off.
+	Method string
+	// Name is shown by clients subscribing to the calendar.
+	Name   string
+	Events []*Event
+}
+
+// Event is a VEVENT.
+type Event struct {
+	// UID identifies the event across updates, which increase Sequence.
+	UID      string
+	Sequence int
+	// Stamp is when the calendar was made, now when zero.
+	Stamp time.Time
+	Start time.Time
+	End   time.Time
+	// AllDay events last from the date of Start to the date of End,
+	// excluded.
+	AllDay      bool
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	// Status is CONFIRMED, TENTATIVE or CANCELLED.
+	Status    string
+	Organizer *Attendee
+	Attendees []Attendee
+	// RRule repeats the event, as in FREQ=WEEKLY;COUNT=4.
+	RRule string
+	// Alarm reminds attendees that long before Start, none when zero.
+	Alarm        time.Duration
+	Created      time.Time
+	LastModified time.Time
+}
+
+// Attendee is the organizer or an attendee of an event.
+type Attendee struct {
+	Email string
+	Name  string
+	// Role is REQ-PARTICIPANT when empty, or OPT-PARTICIPANT, CHAIR...
+	Role string
+	// Status is the participation status, NeedsAction when empty.
+	Status string
+	// RSVP asks the attendee to reply.
+	RSVP bool
+}
+
+// Attachment returns the calendar as a mail attachment, typed with its
+// method so mail clients offer to accept or decline invites.
+func (c *Calendar) Attachment(name string) mail.Attachment {
+	ct := "text/calendar; charset=utf-8"
+	if c.Method != "" {
+		ct += "; method=" + c.Method
+	}
+	return mail.Attachment{Name: name, ContentType: ct, Data: c.Bytes()}
+}
+
+// Reply returns the reply of attendee to the invite of e, with status one
+// of Accepted, Declined or Tentative, for the organizer.
+func (e *Event) Reply(attendee Attendee, status string) *Calendar {
+	attendee.Status, attendee.RSVP = status, false
+	return &Calendar{Method: Reply, Events: []*Event{{
+		UID:       e.UID,
+		Sequence:  e.Sequence,
+		Start:     e.Start,
+		End:       e.End,
+		AllDay:    e.AllDay,
+		Summary:   e.Summary,
+		Organizer: e.Organizer,
+		Attendees: []Attendee{attendee},
+		RRule:     e.RRule,
+	}}}
+}
+
+// Attendee returns the attendee of email, the organizer included, or nil.
+func (e *Event) Attendee(email string) *Attendee {
+	for i := range e.Attendees {
+		if strings.EqualFold(e.Attendees[i].Email, email) {
+			return &e.Attendees[i]
+		}
+	}
+	if e.Organizer != nil && strings.EqualFold(e.Organizer.Email, email) {
+		return e.Organizer
+	}
+	return nil
+}
+
+func prodID(c *Calendar) string {
+	if c.ProdID != "" {
+		return c.ProdID
+	}
+	return "-//" + config.Get("app.name", "Lemmego").(string) + "//EN"
+}
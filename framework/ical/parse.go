@@ -0,0 +1,324 @@
+package ical
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lemmego/lemmego/framework/mail"
+)
+
+var (
+	ErrMalformed       = errors.New("ical: malformed calendar")
+	ErrUnknownTimezone = errors.New("ical: unknown timezone")
+)
+
+// component is a BEGIN/END block of a file with its properties.
+type component struct {
+	name     string
+	props    []prop
+	children []*component
+}
+
+type prop struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+func (c *component) get(name string) *prop {
+	for i := range c.props {
+		if c.props[i].name == name {
+			return &c.props[i]
+		}
+	}
+	return nil
+}
+
+func (c *component) text(name string) string {
+	if p := c.get(name); p != nil {
+		return unescape(p.value)
+	}
+	return ""
+}
+
+// Parse reads the first calendar of an .ics file. Times are in the
+// location of their TZID, from the time zone database or else from the
+// VTIMEZONE of the file as Outlook names zones its own way, and floating
+// ones in the local time. Dates of all-day events are at midnight UTC.
+func Parse(data []byte) (*Calendar, error) {
+	root, err := parseComponents(data)
+	if err != nil {
+		return nil, err
+	}
+	var vcal *component
+	for _, c := range root.children {
+		if c.name == "VCALENDAR" {
+			vcal = c
+			break
+		}
+	}
+	if vcal == nil {
+		return nil, fmt.Errorf("%w: no VCALENDAR", ErrMalformed)
+	}
+
+	cal := &Calendar{ProdID: vcal.text("PRODID"), Method: strings.ToUpper(vcal.text("METHOD")), Name: vcal.text("X-WR-CALNAME")}
+	zones := zones{}
+	for _, c := range vcal.children {
+		if c.name == "VTIMEZONE" {
+			zones[c.text("TZID")] = c
+		}
+	}
+	for _, c := range vcal.children {
+		if c.name != "VEVENT" {
+			continue
+		}
+		e, err := parseEvent(c, zones)
+		if err != nil {
+			return nil, err
+		}
+		cal.Events = append(cal.Events, e)
+	}
+	return cal, nil
+}
+
+// FromAttachments parses the calendars attached to a mail, such as an
+// inbound one answering an invite:
+//
+//	cals, err := ical.FromAttachments(msg.Attachments)
+//	for _, cal := range cals {
+//		if cal.Method == ical.Reply {
+//			e := cal.Events[0]
+//			a := e.Attendee(msg.From)
+//			...
+//		}
+//	}
+func FromAttachments(attachments []mail.Attachment) ([]*Calendar, error) {
+	var cals []*Calendar
+	for _, a := range attachments {
+		ct, _, _ := mime.ParseMediaType(a.ContentType)
+		if ct != "text/calendar" && ct != "application/ics" && !strings.EqualFold(path.Ext(a.Name), ".ics") {
+			continue
+		}
+		cal, err := Parse(a.Data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", a.Name, err)
+		}
+		cals = append(cals, cal)
+	}
+	return cals, nil
+}
+
+func parseEvent(c *component, zones zones) (*Event, error) {
+	e := &Event{
+		UID:         c.text("UID"),
+		Summary:     c.text("SUMMARY"),
+		Description: c.text("DESCRIPTION"),
+		Location:    c.text("LOCATION"),
+		URL:         c.text("URL"),
+		Status:      strings.ToUpper(c.text("STATUS")),
+		RRule:       c.text("RRULE"),
+	}
+	if s := c.text("SEQUENCE"); s != "" {
+		e.Sequence, _ = strconv.Atoi(s)
+	}
+	var err error
+	times := []struct {
+		name string
+		to   *time.Time
+	}{{"DTSTAMP", &e.Stamp}, {"DTEND", &e.End}, {"CREATED", &e.Created}, {"LAST-MODIFIED", &e.LastModified}}
+	if p := c.get("DTSTART"); p != nil {
+		if e.Start, e.AllDay, err = zones.parse(p); err != nil {
+			return nil, err
+		}
+	}
+	for _, t := range times {
+		if p := c.get(t.name); p != nil {
+			if *t.to, _, err = zones.parse(p); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if e.End.IsZero() && !e.Start.IsZero() {
+		switch d := c.text("DURATION"); {
+		case d != "":
+			dur, err := parseDuration(d)
+			if err != nil {
+				return nil, err
+			}
+			e.End = e.Start.Add(dur)
+		case e.AllDay:
+			// All-day events without an end last the day
+			e.End = e.Start.AddDate(0, 0, 1)
+		}
+	}
+
+	for _, p := range c.props {
+		switch p.name {
+		case "ORGANIZER":
+			a := attendee(p)
+			e.Organizer = &a
+		case "ATTENDEE":
+			e.Attendees = append(e.Attendees, attendee(p))
+		}
+	}
+	for _, alarm := range c.children {
+		trigger := alarm.get("TRIGGER")
+		if alarm.name != "VALARM" || trigger == nil || trigger.params["VALUE"] == "DATE-TIME" || trigger.params["RELATED"] == "END" {
+			continue
+		}
+		if d, err := parseDuration(trigger.value); err == nil && d <= 0 {
+			e.Alarm = -d
+			break
+		}
+	}
+	return e, nil
+}
+
+func attendee(p prop) Attendee {
+	email := p.value
+	if len(email) > 7 && strings.EqualFold(email[:7], "mailto:") {
+		email = email[7:]
+	}
+	return Attendee{
+		Email:  email,
+		Name:   p.params["CN"],
+		Role:   strings.ToUpper(p.params["ROLE"]),
+		Status: strings.ToUpper(p.params["PARTSTAT"]),
+		RSVP:   strings.EqualFold(p.params["RSVP"], "TRUE"),
+	}
+}
+
+// parseComponents unfolds the lines of data into the tree of its
+// components, under a root.
+func parseComponents(data []byte) (*component, error) {
+	s := strings.ReplaceAll(string(data), "\r\n", "\n")
+	s = strings.NewReplacer("\n ", "", "\n\t", "").Replace(s)
+	s = strings.TrimPrefix(s, "\ufeff")
+
+	root := &component{}
+	stack := []*component{root}
+	for i, line := range strings.Split(s, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+		p, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %s", ErrMalformed, i+1, err)
+		}
+		top := stack[len(stack)-1]
+		switch p.name {
+		case "BEGIN":
+			c := &component{name: strings.ToUpper(p.value)}
+			top.children = append(top.children, c)
+			stack = append(stack, c)
+		case "END":
+			if len(stack) == 1 || top.name != strings.ToUpper(p.value) {
+				return nil, fmt.Errorf("%w: line %d: unexpected END:%s", ErrMalformed, i+1, p.value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			top.props = append(top.props, p)
+		}
+	}
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("%w: %s is not ended", ErrMalformed, stack[len(stack)-1].name)
+	}
+	return root, nil
+}
+
+// parseLine parses a content line, NAME;PARAM=value;PARAM="v:a;l":value.
+func parseLine(line string) (prop, error) {
+	end := strings.IndexAny(line, ";:")
+	if end <= 0 {
+		return prop{}, errors.New("missing name")
+	}
+	p := prop{name: strings.ToUpper(line[:end]), params: map[string]string{}}
+	rest := line[end:]
+	for rest[0] == ';' {
+		rest = rest[1:]
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return prop{}, fmt.Errorf("malformed parameter of %s", p.name)
+		}
+		key := strings.ToUpper(rest[:eq])
+		rest = rest[eq+1:]
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			closing := strings.IndexByte(rest[1:], '"')
+			if closing < 0 {
+				return prop{}, fmt.Errorf("unterminated quote in %s", p.name)
+			}
+			value, rest = rest[1:closing+1], rest[closing+2:]
+		} else {
+			stop := strings.IndexAny(rest, ";:")
+			if stop < 0 {
+				return prop{}, fmt.Errorf("missing value of %s", p.name)
+			}
+			value, rest = rest[:stop], rest[stop:]
+		}
+		p.params[key] = value
+		if rest == "" {
+			return prop{}, fmt.Errorf("missing value of %s", p.name)
+		}
+	}
+	if rest[0] != ':' {
+		return prop{}, fmt.Errorf("malformed parameters of %s", p.name)
+	}
+	p.value = rest[1:]
+	return p, nil
+}
+
+var unescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+func unescape(s string) string {
+	return unescaper.Replace(s)
+}
+
+// parseDuration parses a duration such as -PT15M, P1DT2H or P2W.
+func parseDuration(s string) (time.Duration, error) {
+	orig := s
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign, s = -1, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, fmt.Errorf("%w: duration %q", ErrMalformed, orig)
+	}
+	s = s[1:]
+	var d time.Duration
+	inTime := false
+	for s != "" {
+		if s[0] == 'T' {
+			inTime, s = true, s[1:]
+			continue
+		}
+		i := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		if i == 0 || i == len(s) {
+			return 0, fmt.Errorf("%w: duration %q", ErrMalformed, orig)
+		}
+		n, _ := strconv.Atoi(s[:i])
+		unit := map[string]time.Duration{"W": 7 * 24 * time.Hour, "D": 24 * time.Hour}
+		if inTime {
+			unit = map[string]time.Duration{"H": time.Hour, "M": time.Minute, "S": time.Second}
+		}
+		u, ok := unit[s[i:i+1]]
+		if !ok {
+			return 0, fmt.Errorf("%w: duration %q", ErrMalformed, orig)
+		}
+		d += time.Duration(n) * u
+		s = s[i+1:]
+	}
+	return sign * d, nil
+}
@@ -0,0 +1,216 @@
+package ical
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// zones are the VTIMEZONEs of a file by TZID.
+type zones map[string]*component
+
+// parse parses the date or time of p, reporting whether it is a date.
+func (z zones) parse(p *prop) (time.Time, bool, error) {
+	v := p.value
+	if p.params["VALUE"] == "DATE" || len(v) == len(dateFormat) {
+		t, err := time.Parse(dateFormat, v)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("%w: date %q of %s", ErrMalformed, v, p.name)
+		}
+		return t, true, nil
+	}
+	if strings.HasSuffix(v, "Z") {
+		t, err := time.Parse(dateTimeFormat, strings.TrimSuffix(v, "Z"))
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("%w: time %q of %s", ErrMalformed, v, p.name)
+		}
+		return t, false, nil
+	}
+	wall, err := time.Parse(dateTimeFormat, v)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%w: time %q of %s", ErrMalformed, v, p.name)
+	}
+	tzid := p.params["TZID"]
+	if tzid == "" {
+		// Floating times are the same wall time wherever one is
+		return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, time.Local), false, nil
+	}
+	t, err := z.in(wall, tzid)
+	return t, false, err
+}
+
+// in returns the wall time in the zone of tzid.
+func (z zones) in(wall time.Time, tzid string) (time.Time, error) {
+	// Some producers prefix the names of the database with a slash
+	if loc, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil && tzid != "" {
+		return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, loc), nil
+	}
+	vtz := z[tzid]
+	if vtz == nil {
+		return time.Time{}, fmt.Errorf("%w: %s", ErrUnknownTimezone, tzid)
+	}
+	// The offset depends on the instant, itself depending on the offset:
+	// taking the offset at the wall time read as UTC, then at the instant
+	// it gives, settles all but the hour skipped or repeated by changes.
+	offset, err := offsetAt(vtz, wall)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if offset, err = offsetAt(vtz, wall.Add(-time.Duration(offset)*time.Second)); err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, time.FixedZone(tzid, offset)), nil
+}
+
+// offsetAt returns the offset from UTC of the observance of vtz in effect
+// at the instant t, the one with the latest onset before it.
+func offsetAt(vtz *component, t time.Time) (int, error) {
+	var (
+		latest time.Time
+		offset int
+		found  bool
+		first  *component
+	)
+	for _, o := range vtz.children {
+		if o.name != "STANDARD" && o.name != "DAYLIGHT" {
+			continue
+		}
+		from, err := parseOffset(o.text("TZOFFSETFROM"))
+		if err != nil {
+			return 0, err
+		}
+		to, err := parseOffset(o.text("TZOFFSETTO"))
+		if err != nil {
+			return 0, err
+		}
+		if first == nil {
+			first = o
+			offset = to
+		}
+		for _, onset := range onsets(o, t, from) {
+			if !onset.After(t) && (!found || onset.After(latest)) {
+				latest, offset, found = onset, to, true
+			}
+		}
+	}
+	if first == nil {
+		return 0, fmt.Errorf("%w: %s has no observances", ErrMalformed, vtz.text("TZID"))
+	}
+	return offset, nil
+}
+
+// onsets returns the instants observance o begins around the year of t,
+// its start's wall time being in the offset from.
+func onsets(o *component, t time.Time, from int) []time.Time {
+	start, err := time.Parse(dateTimeFormat, o.text("DTSTART"))
+	if err != nil {
+		return nil
+	}
+	utc := func(wall time.Time) time.Time {
+		return wall.Add(-time.Duration(from) * time.Second)
+	}
+	list := []time.Time{utc(start)}
+	for _, r := range o.props {
+		if r.name != "RDATE" {
+			continue
+		}
+		for _, v := range strings.Split(r.value, ",") {
+			if d, err := time.Parse(dateTimeFormat, v); err == nil {
+				list = append(list, utc(d))
+			}
+		}
+	}
+
+	rule := map[string]string{}
+	for _, part := range strings.Split(o.text("RRULE"), ";") {
+		if k, v, ok := strings.Cut(part, "="); ok {
+			rule[strings.ToUpper(k)] = strings.ToUpper(v)
+		}
+	}
+	// Zones change yearly on a weekday of a month, as in BYDAY=-1SU
+	if rule["FREQ"] != "YEARLY" {
+		return list
+	}
+	month, _ := strconv.Atoi(rule["BYMONTH"])
+	if month == 0 {
+		month = int(start.Month())
+	}
+	var until time.Time
+	if u := rule["UNTIL"]; u != "" {
+		until, _ = time.Parse(dateTimeFormat, strings.TrimSuffix(u, "Z"))
+	}
+	for year := t.Year() - 1; year <= t.Year(); year++ {
+		if year < start.Year() {
+			continue
+		}
+		day := start.Day()
+		if byday := rule["BYDAY"]; byday != "" {
+			d, ok := nthWeekday(year, time.Month(month), byday)
+			if !ok {
+				continue
+			}
+			day = d
+		}
+		onset := utc(time.Date(year, time.Month(month), day, start.Hour(), start.Minute(), start.Second(), 0, time.UTC))
+		if !until.IsZero() && onset.After(until) {
+			continue
+		}
+		list = append(list, onset)
+	}
+	return list
+}
+
+var weekdays = map[string]time.Weekday{"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday}
+
+// nthWeekday returns the day of the month of a BYDAY such as 2SU, the
+// second Sunday, or -1SU, the last.
+func nthWeekday(year int, month time.Month, byday string) (int, bool) {
+	if len(byday) < 2 {
+		return 0, false
+	}
+	wd, ok := weekdays[byday[len(byday)-2:]]
+	if !ok {
+		return 0, false
+	}
+	n := 1
+	if s := byday[:len(byday)-2]; s != "" {
+		var err error
+		if n, err = strconv.Atoi(strings.TrimPrefix(s, "+")); err != nil || n == 0 {
+			return 0, false
+		}
+	}
+	if n > 0 {
+		first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+		day := 1 + (int(wd)-int(first.Weekday())+7)%7 + (n-1)*7
+		return day, day <= daysIn(year, month)
+	}
+	last := daysIn(year, month)
+	lastWd := time.Date(year, month, last, 0, 0, 0, 0, time.UTC).Weekday()
+	day := last - (int(lastWd)-int(wd)+7)%7 + (n+1)*7
+	return day, day >= 1
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// parseOffset parses an offset such as -0500 or +053000 into seconds.
+func parseOffset(s string) (int, error) {
+	if (len(s) != 5 && len(s) != 7) || (s[0] != '+' && s[0] != '-') {
+		return 0, fmt.Errorf("%w: offset %q", ErrMalformed, s)
+	}
+	var parts [3]int
+	for i := 0; i*2+1 < len(s); i++ {
+		n, err := strconv.Atoi(s[i*2+1 : i*2+3])
+		if err != nil {
+			return 0, fmt.Errorf("%w: offset %q", ErrMalformed, s)
+		}
+		parts[i] = n
+	}
+	seconds := parts[0]*3600 + parts[1]*60 + parts[2]
+	if s[0] == '-' {
+		seconds = -seconds
+	}
+	return seconds, nil
+}
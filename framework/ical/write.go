@@ -0,0 +1,287 @@
+package ical
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	dateFormat     = "20060102"
+	dateTimeFormat = "20060102T150405"
+)
+
+// Bytes returns the calendar as an .ics file.
+func (c *Calendar) Bytes() []byte {
+	w := &writer{}
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:" + prodID(c))
+	w.line("CALSCALE:GREGORIAN")
+	if c.Method != "" {
+		w.line("METHOD:" + c.Method)
+	}
+	if c.Name != "" {
+		w.line("X-WR-CALNAME:" + escape(c.Name))
+	}
+	for _, tz := range timezones(c.Events) {
+		tz.write(w)
+	}
+	for _, e := range c.Events {
+		e.write(w)
+	}
+	w.line("END:VCALENDAR")
+	return w.buf.Bytes()
+}
+
+func (e *Event) write(w *writer) {
+	stamp := e.Stamp
+	if stamp.IsZero() {
+		stamp = time.Now()
+	}
+	w.line("BEGIN:VEVENT")
+	w.line("UID:" + escape(e.UID))
+	w.line("DTSTAMP:" + utc(stamp))
+	if e.Sequence > 0 {
+		w.line("SEQUENCE:" + strconv.Itoa(e.Sequence))
+	}
+	w.line(dateProp("DTSTART", e.Start, e.AllDay))
+	if !e.End.IsZero() {
+		w.line(dateProp("DTEND", e.End, e.AllDay))
+	}
+	if e.RRule != "" {
+		w.line("RRULE:" + e.RRule)
+	}
+	w.text("SUMMARY", e.Summary)
+	w.text("DESCRIPTION", e.Description)
+	w.text("LOCATION", e.Location)
+	if e.URL != "" {
+		w.line("URL:" + e.URL)
+	}
+	if e.Status != "" {
+		w.line("STATUS:" + e.Status)
+	}
+	if !e.Created.IsZero() {
+		w.line("CREATED:" + utc(e.Created))
+	}
+	if !e.LastModified.IsZero() {
+		w.line("LAST-MODIFIED:" + utc(e.LastModified))
+	}
+	if e.Organizer != nil {
+		w.line("ORGANIZER" + cn(e.Organizer.Name) + ":mailto:" + e.Organizer.Email)
+	}
+	for _, a := range e.Attendees {
+		role, status := a.Role, a.Status
+		if role == "" {
+			role = "REQ-PARTICIPANT"
+		}
+		if status == "" {
+			status = NeedsAction
+		}
+		params := cn(a.Name) + ";ROLE=" + role + ";PARTSTAT=" + status
+		if a.RSVP {
+			params += ";RSVP=TRUE"
+		}
+		w.line("ATTENDEE" + params + ":mailto:" + a.Email)
+	}
+	if e.Alarm > 0 {
+		w.line("BEGIN:VALARM")
+		w.line("ACTION:DISPLAY")
+		w.text("DESCRIPTION", e.Summary)
+		w.line("TRIGGER:" + formatDuration(-e.Alarm))
+		w.line("END:VALARM")
+	}
+	w.line("END:VEVENT")
+}
+
+// writer writes content lines, folded at 75 octets as RFC 5545 requires.
+type writer struct {
+	buf bytes.Buffer
+}
+
+func (w *writer) line(s string) {
+	// Continuations take one octet for their leading space
+	for max := 75; len(s) > max; max = 74 {
+		// Folds may not split a character
+		cut := max
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.buf.WriteString(s[:cut] + "\r\n ")
+		s = s[cut:]
+	}
+	w.buf.WriteString(s + "\r\n")
+}
+
+func (w *writer) text(name, value string) {
+	if value != "" {
+		w.line(name + ":" + escape(value))
+	}
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// cn returns the CN parameter of name, quoted as it may hold separators.
+func cn(name string) string {
+	if name == "" {
+		return ""
+	}
+	return `;CN="` + strings.ReplaceAll(name, `"`, "'") + `"`
+}
+
+func utc(t time.Time) string {
+	return t.UTC().Format(dateTimeFormat) + "Z"
+}
+
+// dateProp returns the property of a date, or a time in UTC or with the
+// TZID of its location.
+func dateProp(name string, t time.Time, allDay bool) string {
+	switch {
+	case allDay:
+		return name + ";VALUE=DATE:" + t.Format(dateFormat)
+	case zoned(t.Location()):
+		return name + ";TZID=" + t.Location().String() + ":" + t.Format(dateTimeFormat)
+	default:
+		return name + ":" + utc(t)
+	}
+}
+
+// zoned reports whether times of loc are written with its TZID, which
+// needs a name other clients know.
+func zoned(loc *time.Location) bool {
+	name := loc.String()
+	return name != "UTC" && name != "Local" && name != ""
+}
+
+func formatDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	s := sign + "P"
+	if days > 0 {
+		s += strconv.Itoa(int(days)) + "D"
+	}
+	if d > 0 {
+		s += "T"
+		for _, u := range []struct {
+			unit time.Duration
+			name string
+		}{{time.Hour, "H"}, {time.Minute, "M"}, {time.Second, "S"}} {
+			if n := d / u.unit; n > 0 {
+				s += strconv.Itoa(int(n)) + u.name
+				d -= n * u.unit
+			}
+		}
+	}
+	if s == sign+"P" {
+		s += "T0S"
+	}
+	return s
+}
+
+// timezone is a VTIMEZONE, the transitions of a location between two
+// times.
+type timezone struct {
+	loc      *time.Location
+	from, to time.Time
+}
+
+// timezones returns the zones of the times of events, each covering them
+// all.
+func timezones(events []*Event) []*timezone {
+	zones := map[string]*timezone{}
+	add := func(t time.Time) {
+		if t.IsZero() || !zoned(t.Location()) {
+			return
+		}
+		name := t.Location().String()
+		tz := zones[name]
+		if tz == nil {
+			zones[name] = &timezone{loc: t.Location(), from: t, to: t}
+			return
+		}
+		if t.Before(tz.from) {
+			tz.from = t
+		}
+		if t.After(tz.to) {
+			tz.to = t
+		}
+	}
+	for _, e := range events {
+		if e.AllDay {
+			continue
+		}
+		add(e.Start)
+		add(e.End)
+		// Repeated events need the rules of the years after
+		if e.RRule != "" {
+			add(e.Start.AddDate(2, 0, 0))
+		}
+	}
+	list := make([]*timezone, 0, len(zones))
+	for _, tz := range zones {
+		list = append(list, tz)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].loc.String() < list[j].loc.String() })
+	return list
+}
+
+// write writes the zone as one observance per transition from the one in
+// effect at from to the last before to.
+func (tz *timezone) write(w *writer) {
+	w.line("BEGIN:VTIMEZONE")
+	w.line("TZID:" + tz.loc.String())
+	at := tz.from.In(tz.loc)
+	start, _ := at.ZoneBounds()
+	if start.IsZero() {
+		// No transitions, the offset never changed
+		name, offset := at.Zone()
+		observance(w, "STANDARD", time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC), name, offset, offset)
+	}
+	for !start.IsZero() && !start.After(tz.to) {
+		name, offset := start.Zone()
+		_, before := start.Add(-time.Second).Zone()
+		kind := "STANDARD"
+		if start.IsDST() {
+			kind = "DAYLIGHT"
+		}
+		// Onsets are in the local time of the offset before
+		observance(w, kind, start.In(time.FixedZone("", before)), name, before, offset)
+		_, start = start.ZoneBounds()
+	}
+	w.line("END:VTIMEZONE")
+}
+
+func observance(w *writer, kind string, onset time.Time, name string, from, to int) {
+	w.line("BEGIN:" + kind)
+	w.line("DTSTART:" + onset.Format(dateTimeFormat))
+	w.line("TZOFFSETFROM:" + formatOffset(from))
+	w.line("TZOFFSETTO:" + formatOffset(to))
+	if name != "" && !strings.ContainsAny(name, "+-") {
+		w.line("TZNAME:" + name)
+	}
+	w.line("END:" + kind)
+}
+
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	s := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+	if seconds%60 != 0 {
+		s += fmt.Sprintf("%02d", seconds%60)
+	}
+	return s
+}
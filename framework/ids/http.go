@@ -0,0 +1,17 @@
+package ids
+
+import "github.com/lemmego/api/app"
+
+// Param answers 404 Not Found for requests whose path parameter name is not
+// an id of format f, sparing the database a lookup bound to miss. Valid ids
+// are put back in their canonical case for the handler to query with.
+func Param(name string, f Format) app.Handler {
+	return func(c *app.Context) error {
+		id, err := f.Parse(c.Param(name))
+		if err != nil {
+			return c.NotFound(err)
+		}
+		c.Request().SetPathValue(name, id)
+		return c.Next()
+	}
+}
@@ -0,0 +1,193 @@
+// Package ids generates sortable string keys, ULIDs and version 7 UUIDs, for
+// models whose primary key should not reveal how many rows a table holds.
+//
+//	migration.Create("projects", func(t *migration.Table) {
+//		ids.ULIDColumn(t, "id").Primary()
+//		ids.UUIDColumn(t, "owner_id")
+//	})
+//
+//	type Project struct {
+//		ids.ULIDKey
+//		Name string
+//	}
+//
+//	r.Get("/projects/{id}", show).UseBefore(ids.Param("id", ids.ULID))
+//
+// Both start with the time they were made at, so they index like increments.
+package ids
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalid is returned for strings that are not an id of the expected
+// format.
+var ErrInvalid = errors.New("ids: invalid id")
+
+// Format is a kind of id.
+type Format int
+
+const (
+	// ULID is 26 Crockford base32 characters, e.g. 01JAB3Q7ZK8R5X9V2M4N6P0T1W.
+	ULID Format = iota
+	// UUID is a version 7 UUID in its hyphenated form, e.g.
+	// 0192a8f4-5b6c-7d8e-9f01-23456789abcd. Any version is valid.
+	UUID
+)
+
+func (f Format) String() string {
+	if f == UUID {
+		return "uuid"
+	}
+	return "ulid"
+}
+
+// Len is the length of the format's ids.
+func (f Format) Len() int {
+	if f == UUID {
+		return 36
+	}
+	return 26
+}
+
+// New returns a new id of the format.
+func (f Format) New() string {
+	if f == UUID {
+		return NewUUID()
+	}
+	return NewULID()
+}
+
+// Valid reports whether s is an id of the format, in any letter case.
+func (f Format) Valid(s string) bool {
+	_, err := f.Parse(s)
+	return err == nil
+}
+
+// Parse checks s and returns it in its canonical case: upper for ULIDs,
+// lower for UUIDs.
+func (f Format) Parse(s string) (string, error) {
+	if f == UUID {
+		return parseUUID(s)
+	}
+	return parseULID(s)
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var (
+	mu       sync.Mutex
+	lastMS   int64
+	lastRand [10]byte
+)
+
+// NewULID returns a ULID. IDs made in the same millisecond increment the
+// random part of the previous one, so they still sort in creation order.
+func NewULID() string {
+	ms := time.Now().UnixMilli()
+
+	mu.Lock()
+	if ms > lastMS {
+		lastMS = ms
+		random(lastRand[:])
+	} else {
+		ms = lastMS
+		for i := len(lastRand) - 1; i >= 0; i-- {
+			lastRand[i]++
+			if lastRand[i] != 0 {
+				break
+			}
+		}
+	}
+	var b [16]byte
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> (40 - 8*i))
+	}
+	copy(b[6:], lastRand[:])
+	mu.Unlock()
+
+	// 128 bits in 26 characters of 5 bits, the first one holding 3
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		shift := uint(125 - 5*i)
+		var c byte
+		for bit := uint(0); bit < 5; bit++ {
+			pos := shift + bit
+			if pos < 128 && b[15-pos/8]>>(pos%8)&1 == 1 {
+				c |= 1 << bit
+			}
+		}
+		out[i] = crockford[c]
+	}
+	return string(out[:])
+}
+
+// NewUUID returns a version 7 UUID.
+func NewUUID() string {
+	var b [16]byte
+	random(b[6:])
+	ms := time.Now().UnixMilli()
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> (40 - 8*i))
+	}
+	b[6] = b[6]&0x0f | 0x70
+	b[8] = b[8]&0x3f | 0x80
+
+	var out [36]byte
+	hex.Encode(out[0:8], b[0:4])
+	out[8] = '-'
+	hex.Encode(out[9:13], b[4:6])
+	out[13] = '-'
+	hex.Encode(out[14:18], b[6:8])
+	out[18] = '-'
+	hex.Encode(out[19:23], b[8:10])
+	out[23] = '-'
+	hex.Encode(out[24:], b[10:])
+	return string(out[:])
+}
+
+func random(b []byte) {
+	if _, err := rand.Read(b); err != nil {
+		panic("ids: " + err.Error())
+	}
+}
+
+func parseULID(s string) (string, error) {
+	if len(s) != 26 {
+		return "", ErrInvalid
+	}
+	s = strings.ToUpper(s)
+	// The first character holds the top 3 bits of 128
+	if s[0] > '7' {
+		return "", ErrInvalid
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(crockford, s[i]) < 0 {
+			return "", ErrInvalid
+		}
+	}
+	return s, nil
+}
+
+func parseUUID(s string) (string, error) {
+	if len(s) != 36 {
+		return "", ErrInvalid
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case i == 8 || i == 13 || i == 18 || i == 23:
+			if c != '-' {
+				return "", ErrInvalid
+			}
+		case '0' <= c && c <= '9', 'a' <= c && c <= 'f', 'A' <= c && c <= 'F':
+		default:
+			return "", ErrInvalid
+		}
+	}
+	return strings.ToLower(s), nil
+}
@@ -0,0 +1,29 @@
+package ids
+
+import "gorm.io/gorm"
+
+// ULIDKey is embedded in models keyed by a ULID, which is generated when a
+// row is created without one.
+type ULIDKey struct {
+	ID string `gorm:"primaryKey;size:26" json:"id"`
+}
+
+func (k *ULIDKey) BeforeCreate(*gorm.DB) error {
+	if k.ID == "" {
+		k.ID = NewULID()
+	}
+	return nil
+}
+
+// UUIDKey is embedded in models keyed by a version 7 UUID, which is
+// generated when a row is created without one.
+type UUIDKey struct {
+	ID string `gorm:"primaryKey;size:36" json:"id"`
+}
+
+func (k *UUIDKey) BeforeCreate(*gorm.DB) error {
+	if k.ID == "" {
+		k.ID = NewUUID()
+	}
+	return nil
+}
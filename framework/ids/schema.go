@@ -0,0 +1,16 @@
+package ids
+
+import "github.com/lemmego/migration"
+
+// ULIDColumn adds a column holding ULIDs to a table being created or
+// altered.
+func ULIDColumn(t *migration.Table, name string) *migration.Column {
+	return t.Char(name, uint(ULID.Len()))
+}
+
+// UUIDColumn adds a column holding UUIDs to a table being created or
+// altered. The schema builder has no native uuid type, so it is CHAR(36) on
+// every driver.
+func UUIDColumn(t *migration.Table, name string) *migration.Column {
+	return t.Char(name, uint(UUID.Len()))
+}
@@ -0,0 +1,368 @@
+// Package importer loads CSV and XLSX files into models, validating every row
+// and reporting the ones it had to skip.
+//
+// Columns are matched to fields by the `import` tag, the json name or the Go
+// name, and rows are checked against the `validate` tags of the model:
+//
+//	type Contact struct {
+//		db.Model
+//		Name  string `import:"Full name" validate:"required,max=100"`
+//		Email string `import:"E-mail" validate:"required,email"`
+//		Age   int    `import:"Age" validate:"between=0:150"`
+//	}
+//
+//	imp := &importer.Import[Contact]{Repo: repo.New[Contact](ctx)}
+//	report, err := imp.Run(ctx, reader, nil)
+//
+// Valid rows are committed in chunks, each in its own transaction. A chunk the
+// database rejects is rolled back and its rows are reported as failed, while
+// the chunks before and after it are kept.
+package importer
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/shared"
+	"github.com/lemmego/lemmego/framework/repo"
+	"github.com/lemmego/lemmego/framework/validation"
+)
+
+// DefaultChunkSize is the number of rows committed per transaction.
+const DefaultChunkSize = 500
+
+// maxReportedErrors bounds the errors kept in a Report, the counts stay exact.
+const maxReportedErrors = 10000
+
+// Import loads rows into T.
+type Import[T any] struct {
+	Repo *repo.Repo[T]
+	// ChunkSize defaults to DefaultChunkSize.
+	ChunkSize int
+	// Save stores a chunk of valid rows, by default with Repo.InsertMany. Use it
+	// to upsert instead, e.g. with Repo.Upsert.
+	Save func(r *repo.Repo[T], rows []T) error
+	// Prepare runs on every decoded row before validation, e.g. to normalise
+	// values or fill columns the file does not have.
+	Prepare func(row *T) error
+}
+
+// RowError explains why a row was skipped, a row failing validation gets one
+// per field. Line counts the header as line 1.
+type RowError struct {
+	Line    int      `json:"line"`
+	Field   string   `json:"field,omitempty"`
+	Message string   `json:"message"`
+	Values  []string `json:"-"`
+}
+
+// Report summarises an import.
+type Report struct {
+	Rows     int        `json:"rows"`
+	Imported int        `json:"imported"`
+	Failed   int        `json:"failed"`
+	Errors   []RowError `json:"errors,omitempty"`
+	// Header of the file, used by WriteErrors.
+	Header []string `json:"-"`
+}
+
+func (r *Report) fail(line int, values []string, field, message string) {
+	if len(r.Errors) < maxReportedErrors {
+		r.Errors = append(r.Errors, RowError{Line: line, Field: field, Message: message, Values: values})
+	}
+}
+
+// WriteErrors writes the skipped rows as CSV: the original columns followed by
+// the line, field and message, so the file can be fixed and imported again.
+func (r *Report) WriteErrors(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append(append([]string(nil), r.Header...), "line", "field", "error")); err != nil {
+		return err
+	}
+	for _, e := range r.Errors {
+		values := make([]string, len(r.Header))
+		copy(values, e.Values)
+		if err := cw.Write(append(values, strconv.Itoa(e.Line), e.Field, e.Message)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Progress is called after every chunk with the rows read so far.
+type Progress func(report *Report)
+
+type pending[T any] struct {
+	row    T
+	line   int
+	values []string
+}
+
+// Run reads every row from r, the first one being the header.
+func (imp *Import[T]) Run(ctx context.Context, r Reader, progress Progress) (*Report, error) {
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("importer: the file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Header: append([]string(nil), header...)}
+	columns, err := columnsFor[T](report.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	chunkSize := imp.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	var (
+		chunk []pending[T]
+		line  = 1
+	)
+	flush := func() error {
+		if len(chunk) > 0 {
+			imp.commit(report, chunk)
+			chunk = chunk[:0]
+		}
+		if progress != nil {
+			progress(report)
+		}
+		return ctx.Err()
+	}
+
+	for {
+		values, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line++
+		if err != nil {
+			return report, fmt.Errorf("importer: line %d: %w", line, err)
+		}
+		if blank(values) {
+			continue
+		}
+
+		report.Rows++
+		values = append([]string(nil), values...)
+
+		var row T
+		if field, err := columns.decode(&row, values); err != nil {
+			report.Failed++
+			report.fail(line, values, field, err.Error())
+		} else if failed := imp.check(&row); len(failed) > 0 {
+			report.Failed++
+			for _, field := range slices.Sorted(maps.Keys(failed)) {
+				report.fail(line, values, field, strings.Join(failed[field], " "))
+			}
+		} else {
+			chunk = append(chunk, pending[T]{row: row, line: line, values: values})
+		}
+
+		if len(chunk) >= chunkSize {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+	return report, flush()
+}
+
+// check prepares and validates a decoded row, returning the messages of every
+// failed field.
+func (imp *Import[T]) check(row *T) map[string][]string {
+	if imp.Prepare != nil {
+		if err := imp.Prepare(row); err != nil {
+			return map[string][]string{"": {err.Error()}}
+		}
+	}
+
+	err := validation.Struct(app.NewValidator(app.Get()), row)
+	var verrs shared.ValidationErrors
+	if errors.As(err, &verrs) {
+		return verrs
+	}
+	if err != nil {
+		return map[string][]string{"": {err.Error()}}
+	}
+	return nil
+}
+
+func (imp *Import[T]) commit(report *Report, chunk []pending[T]) {
+	rows := make([]T, len(chunk))
+	for i, p := range chunk {
+		rows[i] = p.row
+	}
+
+	var err error
+	if imp.Save != nil {
+		err = imp.Repo.Transaction(func(tx *repo.Repo[T]) error {
+			return imp.Save(tx, rows)
+		})
+	} else {
+		_, err = imp.Repo.InsertMany(rows, len(rows))
+	}
+
+	if err != nil {
+		report.Failed += len(chunk)
+		for _, p := range chunk {
+			report.fail(p.line, p.values, "", "not saved: "+err.Error())
+		}
+		return
+	}
+	report.Imported += len(chunk)
+}
+
+func blank(values []string) bool {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+type column struct {
+	index []int
+	name  string
+}
+
+// columns maps file columns to fields, nil entries are ignored columns.
+type columns []*column
+
+func columnsFor[T any](header []string) (columns, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("importer: %s is not a struct", t)
+	}
+
+	byName := make(map[string]*column)
+	for _, sf := range reflect.VisibleFields(t) {
+		if !sf.IsExported() || sf.Anonymous {
+			continue
+		}
+		name := sf.Tag.Get("import")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name, _, _ = strings.Cut(sf.Tag.Get("json"), ",")
+		}
+		if name == "" || name == "-" {
+			name = sf.Name
+		}
+		byName[strings.ToLower(name)] = &column{index: sf.Index, name: name}
+	}
+
+	cols := make(columns, len(header))
+	matched := false
+	for i, h := range header {
+		if c, ok := byName[strings.ToLower(strings.TrimSpace(h))]; ok {
+			cols[i] = c
+			matched = true
+		}
+	}
+	if !matched {
+		return nil, fmt.Errorf("importer: no column of the header matches a field of %s", t.Name())
+	}
+	return cols, nil
+}
+
+// decode sets the mapped fields of row, returning the offending column on error.
+func (cols columns) decode(row any, values []string) (string, error) {
+	rv := reflect.ValueOf(row).Elem()
+	for i, c := range cols {
+		if c == nil || i >= len(values) {
+			continue
+		}
+		if err := set(rv.FieldByIndex(c.index), strings.TrimSpace(values[i])); err != nil {
+			return c.name, err
+		}
+	}
+	return "", nil
+}
+
+var timeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+
+// set converts a cell to the field's type. Empty cells leave the zero value,
+// or nil for pointers.
+func set(field reflect.Value, value string) error {
+	if value == "" {
+		return nil
+	}
+
+	if field.Kind() == reflect.Ptr {
+		ptr := reflect.New(field.Type().Elem())
+		if err := set(ptr.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+
+	if field.Type() == reflect.TypeOf(time.Time{}) {
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				field.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		// Spreadsheets store dates as days since 1899-12-30
+		if days, err := strconv.ParseFloat(value, 64); err == nil {
+			t := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).Add(time.Duration(days * 24 * float64(time.Hour)))
+			field.Set(reflect.ValueOf(t.Round(time.Second)))
+			return nil
+		}
+		return fmt.Errorf("%q is not a date", value)
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		switch strings.ToLower(value) {
+		case "1", "true", "yes", "y", "on":
+			field.SetBool(true)
+		case "0", "false", "no", "n", "off":
+			field.SetBool(false)
+		default:
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", value)
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a positive whole number", value)
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		field.SetFloat(n)
+	default:
+		return fmt.Errorf("cannot import into a %s field", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lemmego/lemmego/framework/cache"
+	"github.com/lemmego/lemmego/framework/export"
+	"github.com/lemmego/lemmego/framework/queue"
+	"github.com/lemmego/lemmego/framework/storage"
+)
+
+// Large files are uploaded to a disk first and imported by a worker, which
+// writes the rejected rows next to the upload:
+//
+//	importer.Define("contacts", func(ctx context.Context, params map[string]string) (importer.Runner, error) {
+//		return &importer.Import[Contact]{Repo: repo.New[Contact](ctx)}, nil
+//	})
+//
+//	s, err := importer.Dispatch(ctx, "contacts", "uploads/contacts.xlsx", nil, "")
+//	...
+//	s, ok := importer.StatusOf(s.ID)
+
+// Runner is implemented by every Import.
+type Runner interface {
+	Run(ctx context.Context, r Reader, progress Progress) (*Report, error)
+}
+
+// Definition builds a named import inside the worker from the parameters it
+// was dispatched with.
+type Definition func(ctx context.Context, params map[string]string) (Runner, error)
+
+var (
+	definitionsMu sync.RWMutex
+	definitions   = make(map[string]Definition)
+)
+
+func Define(name string, def Definition) {
+	definitionsMu.Lock()
+	defer definitionsMu.Unlock()
+	definitions[name] = def
+}
+
+const (
+	StatusQueued  = "queued"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// statusTTL is how long the status of an import can be looked up.
+const statusTTL = 24 * time.Hour
+
+// Status of a queued import. ErrorsPath points to the rejected rows on the
+// disk once it is done, if there were any.
+type Status struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Rows       int    `json:"rows"`
+	Imported   int    `json:"imported"`
+	Failed     int    `json:"failed"`
+	Disk       string `json:"disk,omitempty"`
+	ErrorsPath string `json:"errors_path,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+func init() {
+	queue.Register[Job]("import")
+}
+
+// Dispatch queues the named import of file on disk (the default one when
+// empty). The format follows the file extension.
+func Dispatch(ctx context.Context, name, file string, params map[string]string, disk string) (*Status, error) {
+	definitionsMu.RLock()
+	_, ok := definitions[name]
+	definitionsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("importer: %s is not defined", name)
+	}
+	if _, err := FormatOf(file); err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	s := &Status{ID: hex.EncodeToString(b), Name: name, Status: StatusQueued, Disk: disk}
+	s.save()
+
+	job := &Job{ID: s.ID, Name: name, File: file, Params: params, Disk: disk}
+	// Committed chunks stay committed, a retry would insert them twice
+	if _, err := queue.Dispatch(ctx, job, &queue.Options{ID: s.ID, MaxAttempts: 1, Track: true}); err != nil {
+		return nil, err
+	}
+	// The sync driver has already run the job
+	if current, ok := StatusOf(s.ID); ok {
+		s = current
+	}
+	return s, nil
+}
+
+// FormatOf picks the format from a file name.
+func FormatOf(file string) (export.Format, error) {
+	return export.ParseFormat(strings.TrimPrefix(path.Ext(file), "."))
+}
+
+// StatusOf returns the status of a dispatched import.
+func StatusOf(id string) (*Status, bool) {
+	s, ok := cache.Default().Get(statusKey(id)).(Status)
+	if !ok {
+		return nil, false
+	}
+	return &s, true
+}
+
+func statusKey(id string) string {
+	return "import:" + id
+}
+
+func (s *Status) save() {
+	cache.Default().Put(statusKey(s.ID), *s, int(statusTTL.Seconds()))
+}
+
+// Job runs a named import on a worker.
+type Job struct {
+	ID     string            `json:"id"`
+	Name   string            `json:"name"`
+	File   string            `json:"file"`
+	Params map[string]string `json:"params"`
+	Disk   string            `json:"disk"`
+}
+
+func (j *Job) Handle(ctx context.Context) error {
+	s := &Status{ID: j.ID, Name: j.Name, Status: StatusRunning, Disk: j.Disk}
+	s.save()
+
+	if err := j.run(ctx, s); err != nil {
+		s.Status, s.Error = StatusFailed, err.Error()
+		s.save()
+		return err
+	}
+
+	s.Status = StatusDone
+	s.save()
+	return nil
+}
+
+func (j *Job) run(ctx context.Context, s *Status) error {
+	definitionsMu.RLock()
+	def, ok := definitions[j.Name]
+	definitionsMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("importer: %s is not defined", j.Name)
+	}
+
+	format, err := FormatOf(j.File)
+	if err != nil {
+		return err
+	}
+
+	var diskName []string
+	if j.Disk != "" {
+		diskName = append(diskName, j.Disk)
+	}
+	disk, err := storage.Get(ctx, diskName...)
+	if err != nil {
+		return err
+	}
+
+	// Disks only hand out streams, XLSX needs random access
+	scratch, err := storage.Temp(ctx)
+	if err != nil {
+		return err
+	}
+	tmp, err := scratch.Create("import-*")
+	if err != nil {
+		return err
+	}
+	defer tmp.Close()
+
+	src, err := disk.Read(j.File)
+	if err != nil {
+		return err
+	}
+	size, err := io.Copy(tmp, src)
+	src.Close()
+	if err != nil {
+		return err
+	}
+
+	reader, err := NewReader(format, tmp, size)
+	if err != nil {
+		return err
+	}
+	runner, err := def(ctx, j.Params)
+	if err != nil {
+		return err
+	}
+
+	report, err := runner.Run(ctx, reader, func(r *Report) {
+		s.Rows, s.Imported, s.Failed = r.Rows, r.Imported, r.Failed
+		s.save()
+		// The number of rows is unknown until the end
+		queue.Report(ctx, -1, fmt.Sprintf("Imported %d rows", r.Imported))
+	})
+	if err != nil {
+		return err
+	}
+	s.Rows, s.Imported, s.Failed = report.Rows, report.Imported, report.Failed
+
+	if len(report.Errors) > 0 {
+		var buf bytes.Buffer
+		if err := report.WriteErrors(&buf); err != nil {
+			return err
+		}
+		s.ErrorsPath = path.Join("imports", j.ID, "errors.csv")
+		return disk.Write(s.ErrorsPath, buf.Bytes())
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package importer
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/lemmego/lemmego/framework/export"
+)
+
+// Reader returns one row per call and io.EOF after the last one.
+type Reader interface {
+	Read() ([]string, error)
+}
+
+// NewReader reads a CSV or XLSX file. XLSX needs random access, hence the
+// io.ReaderAt and size.
+func NewReader(format export.Format, r io.ReaderAt, size int64) (Reader, error) {
+	switch format {
+	case export.CSV:
+		cr := csv.NewReader(io.NewSectionReader(r, 0, size))
+		cr.FieldsPerRecord = -1
+		cr.ReuseRecord = true
+		return &csvReader{r: cr}, nil
+	case export.XLSX:
+		return newXLSXReader(r, size)
+	}
+	return nil, fmt.Errorf("importer: unsupported format %q", format)
+}
+
+type csvReader struct {
+	r     *csv.Reader
+	first bool
+}
+
+func (c *csvReader) Read() ([]string, error) {
+	row, err := c.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	if !c.first {
+		c.first = true
+		// Spreadsheets like to save a byte order mark in front of the header
+		if len(row) > 0 {
+			row[0] = strings.TrimPrefix(row[0], "\ufeff")
+		}
+	}
+	return row, nil
+}
+
+// xlsxReader streams the first worksheet. Only the shared string table is
+// held in memory, the sheet itself is decoded row by row.
+type xlsxReader struct {
+	zip     *zip.Reader
+	sheet   io.ReadCloser
+	dec     *xml.Decoder
+	strings []string
+}
+
+func newXLSXReader(r io.ReaderAt, size int64) (*xlsxReader, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("importer: not an xlsx file: %w", err)
+	}
+	x := &xlsxReader{zip: zr}
+
+	if err := x.loadSharedStrings(); err != nil {
+		return nil, err
+	}
+
+	name, err := x.firstSheet()
+	if err != nil {
+		return nil, err
+	}
+	f, err := zr.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("importer: %s: %w", name, err)
+	}
+	x.sheet = f
+	x.dec = xml.NewDecoder(f)
+	return x, nil
+}
+
+func (x *xlsxReader) decodePart(name string, v any) error {
+	f, err := x.zip.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return xml.NewDecoder(f).Decode(v)
+}
+
+// firstSheet resolves the part of the workbook's first sheet.
+func (x *xlsxReader) firstSheet() (string, error) {
+	var workbook struct {
+		Sheets []struct {
+			ID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := x.decodePart("xl/workbook.xml", &workbook); err != nil {
+		return "", fmt.Errorf("importer: workbook: %w", err)
+	}
+	if err := x.decodePart("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", fmt.Errorf("importer: workbook relationships: %w", err)
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", fmt.Errorf("importer: workbook has no sheets")
+	}
+
+	for _, rel := range rels.Relationships {
+		if rel.ID == workbook.Sheets[0].ID {
+			if strings.HasPrefix(rel.Target, "/") {
+				return strings.TrimPrefix(rel.Target, "/"), nil
+			}
+			return path.Join("xl", rel.Target), nil
+		}
+	}
+	return "", fmt.Errorf("importer: first sheet not found")
+}
+
+func (x *xlsxReader) loadSharedStrings() error {
+	f, err := x.zip.Open("xl/sharedStrings.xml")
+	if err != nil {
+		// Workbooks with inline strings only have no table
+		return nil
+	}
+	defer f.Close()
+
+	var si struct {
+		T string `xml:"t"`
+		R []struct {
+			T string `xml:"t"`
+		} `xml:"r"`
+	}
+	dec := xml.NewDecoder(f)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("importer: shared strings: %w", err)
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == "si" {
+			si.T, si.R = "", si.R[:0]
+			if err := dec.DecodeElement(&si, &start); err != nil {
+				return fmt.Errorf("importer: shared strings: %w", err)
+			}
+			// Rich text is split in runs
+			s := si.T
+			for _, r := range si.R {
+				s += r.T
+			}
+			x.strings = append(x.strings, s)
+		}
+	}
+}
+
+type xlsxCell struct {
+	Ref    string `xml:"r,attr"`
+	Type   string `xml:"t,attr"`
+	Value  string `xml:"v"`
+	Inline struct {
+		T string `xml:"t"`
+		R []struct {
+			T string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"is"`
+}
+
+func (x *xlsxReader) Read() ([]string, error) {
+	for {
+		tok, err := x.dec.Token()
+		if err == io.EOF {
+			x.sheet.Close()
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == "row" {
+			var row struct {
+				Cells []xlsxCell `xml:"c"`
+			}
+			if err := x.dec.DecodeElement(&row, &start); err != nil {
+				return nil, err
+			}
+			return x.values(row.Cells)
+		}
+	}
+}
+
+// values places cells in their columns, empty cells are left out of the file.
+func (x *xlsxReader) values(cells []xlsxCell) ([]string, error) {
+	var out []string
+	for i, c := range cells {
+		col := i
+		if c.Ref != "" {
+			col = columnIndex(c.Ref)
+		}
+		for len(out) <= col {
+			out = append(out, "")
+		}
+
+		switch c.Type {
+		case "s":
+			n, err := strconv.Atoi(c.Value)
+			if err != nil || n < 0 || n >= len(x.strings) {
+				return nil, fmt.Errorf("importer: cell %s refers to unknown string %q", c.Ref, c.Value)
+			}
+			out[col] = x.strings[n]
+		case "inlineStr":
+			s := c.Inline.T
+			for _, r := range c.Inline.R {
+				s += r.T
+			}
+			out[col] = s
+		case "b":
+			out[col] = map[string]string{"1": "true", "0": "false"}[c.Value]
+		default:
+			out[col] = c.Value
+		}
+	}
+	return out, nil
+}
+
+// columnIndex turns the letters of a cell reference like "AB12" into a zero
+// based column index.
+func columnIndex(ref string) int {
+	n := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		n = n*26 + int(r-'A'+1)
+	}
+	return n - 1
+}
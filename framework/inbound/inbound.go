@@ -0,0 +1,143 @@
+// Package inbound receives emails sent to the application, through the
+// inbound webhooks of Mailgun, Postmark or Amazon SES, and routes them to
+// handlers by the address they were sent to, e.g. for replies by email:
+//
+//	inbound.Route("reply+{token}@inbound.example.com", func(ctx context.Context, m *inbound.Message) error {
+//		thread, err := threads.FindByReplyToken(ctx, m.Params["token"])
+//		if err != nil {
+//			return err
+//		}
+//		return thread.AddComment(ctx, m.From, m.Reply)
+//	})
+//
+// Services deliver a message again when its handler fails, handlers use
+// inbox.Once with the id of the message to handle it once.
+package inbound
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lemmego/lemmego/framework/mail"
+)
+
+// MaxSize is the largest message accepted, attachments included.
+var MaxSize int64 = 25 << 20
+
+// Message is a received email, whichever service received it.
+type Message struct {
+	// ID is the Message-ID header, without its angle brackets.
+	ID      string
+	From    string
+	To      []string
+	Cc      []string
+	ReplyTo string
+	// Recipients are the addresses of the application the message was
+	// delivered to, which may be missing from To and Cc.
+	Recipients []string
+	Subject    string
+	Date       time.Time
+	// InReplyTo and References hold the ids of the messages replied to.
+	InReplyTo  string
+	References []string
+	Text       string
+	HTML       string
+	// Reply is Text without the quoted message and the signature.
+	Reply       string
+	Headers     textproto.MIMEHeader
+	Attachments []mail.Attachment
+
+	// Recipient is the address the route matched, and Params the values of
+	// the placeholders of its pattern.
+	Recipient string
+	Params    map[string]string
+}
+
+// Handler handles a message. Returning an error answers 500, so the
+// service delivers it again later.
+type Handler func(ctx context.Context, m *Message) error
+
+type route struct {
+	pattern *regexp.Regexp
+	names   []string
+	handler Handler
+}
+
+var (
+	routesMu sync.RWMutex
+	routes   []route
+)
+
+var placeholder = regexp.MustCompile(`\{(\w+)\}|\*`)
+
+// Route sends the messages delivered to an address matching pattern to h.
+// Patterns are addresses compared regardless of case, where {name} matches
+// any part of an address without an @, captured in Params, and * anything.
+// The first route matching a recipient handles the message.
+func Route(pattern string, h Handler) {
+	var names []string
+	expr := "(?i)^"
+	last := 0
+	for _, loc := range placeholder.FindAllStringSubmatchIndex(pattern, -1) {
+		expr += regexp.QuoteMeta(pattern[last:loc[0]])
+		if loc[2] >= 0 {
+			names = append(names, pattern[loc[2]:loc[3]])
+			expr += "([^@]+?)"
+		} else {
+			expr += ".*"
+		}
+		last = loc[1]
+	}
+	expr += regexp.QuoteMeta(pattern[last:]) + "$"
+
+	routesMu.Lock()
+	defer routesMu.Unlock()
+	routes = append(routes, route{pattern: regexp.MustCompile(expr), names: names, handler: h})
+}
+
+// Dispatch hands m to the first route matching one of its recipients, or
+// To and Cc when the service did not tell them. Messages no route matches
+// are dropped.
+func Dispatch(ctx context.Context, m *Message) error {
+	recipients := m.Recipients
+	if len(recipients) == 0 {
+		recipients = append(append(recipients, m.To...), m.Cc...)
+	}
+
+	routesMu.RLock()
+	defer routesMu.RUnlock()
+	for _, r := range routes {
+		for _, addr := range recipients {
+			match := r.pattern.FindStringSubmatch(address(addr))
+			if match == nil {
+				continue
+			}
+			m.Recipient = address(addr)
+			m.Params = make(map[string]string, len(r.names))
+			for i, name := range r.names {
+				m.Params[name] = match[i+1]
+			}
+			return r.handler(ctx, m)
+		}
+	}
+	slog.Warn(fmt.Sprintf("inbound: no route for the message %s from %s to %s", m.ID, m.From, strings.Join(recipients, ", ")))
+	return nil
+}
+
+// finish fills what the services leave out.
+func (m *Message) finish() {
+	if m.Reply == "" {
+		m.Reply = StripReply(m.Text)
+	}
+	m.ID = strings.Trim(strings.TrimSpace(m.ID), "<>")
+	m.InReplyTo = strings.Trim(strings.TrimSpace(m.InReplyTo), "<>")
+	if m.Headers == nil {
+		m.Headers = textproto.MIMEHeader{}
+	}
+}
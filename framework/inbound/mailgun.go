@@ -0,0 +1,133 @@
+package inbound
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lemmego/api/app"
+
+	"github.com/lemmego/lemmego/framework/jsonx"
+	appmail "github.com/lemmego/lemmego/framework/mail"
+	"github.com/lemmego/lemmego/framework/webhook"
+)
+
+// Mailgun receives the messages forwarded by Mailgun routes, parsed or as
+// MIME when the route forwards to a URL ending in "mime", signed with the
+// webhook signing key.
+func Mailgun(signingKey string) app.Handler {
+	return func(c *app.Context) error {
+		r := c.Request()
+		r.Body = http.MaxBytesReader(c.ResponseWriter(), r.Body, MaxSize)
+		if err := r.ParseMultipartForm(32 << 20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			return c.BadRequest(err)
+		}
+		if err := verifyMailgun(signingKey, r.FormValue("timestamp"), r.FormValue("token"), r.FormValue("signature")); err != nil {
+			return c.Unauthorized(err)
+		}
+
+		m, err := parseMailgun(r)
+		if err != nil {
+			return c.BadRequest(err)
+		}
+		if err := Dispatch(c.RequestContext(), m); err != nil {
+			return err
+		}
+		return jsonx.Write(c, http.StatusOK, app.M{"received": true})
+	}
+}
+
+func verifyMailgun(key, timestamp, token, signature string) error {
+	sig, err := hex.DecodeString(signature)
+	if err != nil || timestamp == "" || token == "" {
+		return webhook.ErrSignature
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(timestamp + token))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return webhook.ErrSignature
+	}
+	// Deliveries are retried for hours under their first signature, so only
+	// signatures from the future are suspicious
+	if sec, err := strconv.ParseInt(timestamp, 10, 64); err != nil || time.Until(time.Unix(sec, 0)) > 5*time.Minute {
+		return webhook.ErrSignature
+	}
+	return nil
+}
+
+func parseMailgun(r *http.Request) (*Message, error) {
+	if raw := r.FormValue("body-mime"); raw != "" {
+		m, err := Parse([]byte(raw))
+		if err != nil {
+			return nil, err
+		}
+		m.Recipients = splitAddresses(r.FormValue("recipient"))
+		return m, nil
+	}
+
+	m := &Message{
+		From:       r.FormValue("from"),
+		Subject:    r.FormValue("subject"),
+		Text:       r.FormValue("body-plain"),
+		HTML:       r.FormValue("body-html"),
+		Reply:      strings.TrimSpace(r.FormValue("stripped-text")),
+		Recipients: splitAddresses(r.FormValue("recipient")),
+		Headers:    textproto.MIMEHeader{},
+	}
+	var headers [][2]string
+	if err := json.Unmarshal([]byte(r.FormValue("message-headers")), &headers); err == nil {
+		for _, h := range headers {
+			m.Headers.Add(h[0], h[1])
+		}
+	}
+	m.ID = m.Headers.Get("Message-Id")
+	m.To = addressList(m.Headers.Get("To"))
+	m.Cc = addressList(m.Headers.Get("Cc"))
+	m.ReplyTo = m.Headers.Get("Reply-To")
+	m.InReplyTo = m.Headers.Get("In-Reply-To")
+	m.References = strings.Fields(strings.NewReplacer("<", " ", ">", " ").Replace(m.Headers.Get("References")))
+	m.Date, _ = time.Parse(time.RFC1123Z, m.Headers.Get("Date"))
+
+	if r.MultipartForm != nil {
+		n, _ := strconv.Atoi(r.FormValue("attachment-count"))
+		for i := 1; i <= n; i++ {
+			for _, fh := range r.MultipartForm.File["attachment-"+strconv.Itoa(i)] {
+				f, err := fh.Open()
+				if err != nil {
+					return nil, err
+				}
+				data, err := io.ReadAll(f)
+				f.Close()
+				if err != nil {
+					return nil, err
+				}
+				m.Attachments = append(m.Attachments, appmail.Attachment{
+					Name:        fh.Filename,
+					ContentType: fh.Header.Get("Content-Type"),
+					Data:        data,
+				})
+			}
+		}
+	}
+	m.finish()
+	return m, nil
+}
+
+// splitAddresses splits a comma separated list of bare addresses.
+func splitAddresses(s string) []string {
+	var list []string
+	for _, a := range strings.Split(s, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			list = append(list, a)
+		}
+	}
+	return list
+}
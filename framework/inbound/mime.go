@@ -0,0 +1,191 @@
+package inbound
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+
+	appmail "github.com/lemmego/lemmego/framework/mail"
+)
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// Parse reads a raw MIME message, as received by SMTP.
+func Parse(raw []byte) (*Message, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	m := &Message{Headers: textproto.MIMEHeader(msg.Header)}
+	m.ID = msg.Header.Get("Message-Id")
+	m.Subject = decodeHeader(msg.Header.Get("Subject"))
+	m.From = decodeHeader(msg.Header.Get("From"))
+	m.To = addressList(msg.Header.Get("To"))
+	m.Cc = addressList(msg.Header.Get("Cc"))
+	m.ReplyTo = decodeHeader(msg.Header.Get("Reply-To"))
+	m.InReplyTo = msg.Header.Get("In-Reply-To")
+	m.References = strings.Fields(strings.NewReplacer("<", " ", ">", " ").Replace(msg.Header.Get("References")))
+	m.Date, _ = msg.Header.Date()
+
+	if err := m.walk(textproto.MIMEHeader(msg.Header), msg.Body); err != nil {
+		return nil, err
+	}
+	m.finish()
+	return m, nil
+}
+
+// walk collects the bodies and attachments of a part and of its parts.
+func (m *Message) walk(header textproto.MIMEHeader, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := m.walk(part.Header, part); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, &newlineSkipper{r: body})
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	data, err := io.ReadAll(io.LimitReader(body, MaxSize))
+	if err != nil {
+		return err
+	}
+
+	disposition, dparams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	name := decodeHeader(dparams["filename"])
+	if name == "" {
+		name = decodeHeader(params["name"])
+	}
+	if mediaType == "message/rfc822" && name == "" {
+		name = "forwarded.eml"
+	}
+	// Invites are often an alternative of the body rather than attached
+	if mediaType == "text/calendar" && name == "" {
+		name = "invite.ics"
+	}
+	isBody := disposition != "attachment" && name == ""
+	switch {
+	case isBody && mediaType == "text/plain" && m.Text == "":
+		m.Text, err = decodeCharset(data, params["charset"])
+	case isBody && mediaType == "text/html" && m.HTML == "":
+		m.HTML, err = decodeCharset(data, params["charset"])
+	case isBody && strings.HasPrefix(mediaType, "text/"):
+		// Further text parts of mixed messages are rarely more than a footer
+	default:
+		if name == "" {
+			name = "attachment"
+		}
+		m.Attachments = append(m.Attachments, appmail.Attachment{Name: name, ContentType: mediaType, Data: data})
+	}
+	return err
+}
+
+// newlineSkipper drops the line breaks of base64 content.
+type newlineSkipper struct {
+	r io.Reader
+}
+
+func (s *newlineSkipper) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	j := 0
+	for _, c := range p[:n] {
+		if c != '\r' && c != '\n' && c != ' ' && c != '\t' {
+			p[j] = c
+			j++
+		}
+	}
+	if j == 0 && n > 0 && err == nil {
+		return s.Read(p)
+	}
+	return j, err
+}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("inbound: unknown charset %q", charset)
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+func decodeCharset(data []byte, charset string) (string, error) {
+	switch strings.ToLower(charset) {
+	case "", "utf-8", "utf8", "us-ascii":
+		return string(data), nil
+	}
+	r, err := charsetReader(charset, bytes.NewReader(data))
+	if err != nil {
+		// Better mangled accents than a lost message
+		return string(data), nil
+	}
+	decoded, err := io.ReadAll(r)
+	return string(decoded), err
+}
+
+func decodeHeader(s string) string {
+	decoded, err := wordDecoder.DecodeHeader(s)
+	if err != nil {
+		return s
+	}
+	return decoded
+}
+
+// addressList returns the addresses of a header, formatted as "Name <addr>".
+func addressList(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	parser := &mail.AddressParser{WordDecoder: wordDecoder}
+	addrs, err := parser.ParseList(s)
+	if err != nil {
+		// Keep what the sender wrote rather than nothing
+		return []string{decodeHeader(s)}
+	}
+	list := make([]string, len(addrs))
+	for i, a := range addrs {
+		list[i] = formatAddress(a)
+	}
+	return list
+}
+
+func formatAddress(a *mail.Address) string {
+	if a.Name == "" {
+		return a.Address
+	}
+	return a.Name + " <" + a.Address + ">"
+}
+
+// address returns the bare address of s, as in jane@example.com for
+// "Jane <jane@example.com>".
+func address(s string) string {
+	if a, err := mail.ParseAddress(s); err == nil {
+		return a.Address
+	}
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,64 @@
+package inbound
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// Where mail clients start quoting the message replied to
+	quoteHeaders = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)^on\b.*\bwrote:?$`),
+		regexp.MustCompile(`(?i)^-+\s*original message\s*-+$`),
+		regexp.MustCompile(`^_{10,}$`),
+		regexp.MustCompile(`(?i)^le\b.*\ba écrit\s*:$`),
+		regexp.MustCompile(`(?i)^am\b.*\bschrieb\b.*:$`),
+	}
+	outlookFrom = regexp.MustCompile(`(?i)^(from|von|de):\s`)
+	signatures  = []*regexp.Regexp{
+		regexp.MustCompile(`^--\s?$`),
+		regexp.MustCompile(`(?i)^sent from my \w+`),
+		regexp.MustCompile(`(?i)^get outlook for \w+`),
+	}
+)
+
+// StripReply returns the text of a reply without the message it quotes and
+// the signature of its sender.
+func StripReply(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var kept []string
+lines:
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		// Clients wrap long "On ... wrote:" lines
+		joined := line
+		if i+1 < len(lines) {
+			joined = line + " " + strings.TrimSpace(lines[i+1])
+		}
+		for _, re := range quoteHeaders {
+			if re.MatchString(line) || (strings.HasPrefix(strings.ToLower(line), "on ") && re.MatchString(joined)) {
+				break lines
+			}
+		}
+		// Outlook quotes under a block of headers, after a blank line
+		if outlookFrom.MatchString(line) && i > 0 && strings.TrimSpace(lines[i-1]) == "" {
+			break
+		}
+		for _, re := range signatures {
+			if re.MatchString(lines[i]) {
+				break lines
+			}
+		}
+		kept = append(kept, lines[i])
+	}
+
+	// Quoted lines left at the end belong to the message replied to
+	for len(kept) > 0 {
+		last := strings.TrimSpace(kept[len(kept)-1])
+		if last != "" && !strings.HasPrefix(last, ">") {
+			break
+		}
+		kept = kept[:len(kept)-1]
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
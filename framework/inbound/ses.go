@@ -0,0 +1,326 @@
+package inbound
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/lemmego/api/app"
+
+	"github.com/lemmego/lemmego/framework/httpclient"
+	"github.com/lemmego/lemmego/framework/httpsig"
+	"github.com/lemmego/lemmego/framework/jsonx"
+	"github.com/lemmego/lemmego/framework/webhook"
+)
+
+// snsMessage is a message of Amazon SNS.
+type snsMessage struct {
+	Type             string
+	MessageId        string
+	Token            string
+	TopicArn         string
+	Subject          string
+	Message          string
+	Timestamp        string
+	SignatureVersion string
+	Signature        string
+	SigningCertURL   string
+	SubscribeURL     string
+}
+
+// sesNotification is what a receipt rule of Amazon SES publishes.
+type sesNotification struct {
+	NotificationType string `json:"notificationType"`
+	Receipt          struct {
+		Recipients []string `json:"recipients"`
+		Action     struct {
+			Type       string `json:"type"`
+			Encoding   string `json:"encoding"`
+			BucketName string `json:"bucketName"`
+			ObjectKey  string `json:"objectKey"`
+		} `json:"action"`
+	} `json:"receipt"`
+	Content string `json:"content"`
+}
+
+var snsCertHost = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+// SeenStore records the ids of the messages received. Forget drops the id
+// of a message whose handling failed, so that its retry is handled.
+type SeenStore interface {
+	httpsig.NonceStore
+	Forget(ctx context.Context, nonce string) error
+}
+
+// SESOptions configure SES.
+type SESOptions struct {
+	// Tolerance is how old a message may be, an hour when zero, the longest
+	// SNS retries a delivery.
+	Tolerance time.Duration
+	// Seen drops the messages delivered twice, retried by SNS or replayed.
+	// It defaults to an httpsig.DatabaseNonces, shared by the instances of
+	// the application.
+	Seen SeenStore
+}
+
+var defaultSeen = httpsig.NewDatabaseNonces()
+
+// SES receives the messages of Amazon SES receipt rules through the SNS
+// topic topicARN, the subscription of the endpoint being confirmed when
+// the topic asks. The rule either publishes the message to the topic, up to
+// 150 KB, or stores it in S3 before notifying it, the object being read with
+// the default AWS credentials. Messages older than the tolerance or already
+// received are acknowledged without being handled.
+func SES(topicARN string, opts ...*SESOptions) app.Handler {
+	o := &SESOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		o = opts[0]
+	}
+	if o.Tolerance <= 0 {
+		o.Tolerance = time.Hour
+	}
+	if o.Seen == nil {
+		o.Seen = defaultSeen
+	}
+
+	return func(c *app.Context) error {
+		body, err := io.ReadAll(io.LimitReader(c.Request().Body, webhook.MaxBody))
+		if err != nil {
+			return c.BadRequest(err)
+		}
+		var msg snsMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			return c.BadRequest(err)
+		}
+		if msg.TopicArn != topicARN {
+			return c.Forbidden(fmt.Errorf("inbound: unexpected topic %s", msg.TopicArn))
+		}
+		if err := verifySNS(c.RequestContext(), &msg); err != nil {
+			return c.Unauthorized(err)
+		}
+		// The timestamp is that of the publication, kept by retries
+		sent, err := time.Parse(time.RFC3339Nano, msg.Timestamp)
+		if err != nil || time.Since(sent) > o.Tolerance || time.Until(sent) > 5*time.Minute {
+			return c.Unauthorized(fmt.Errorf("inbound: SNS message %s sent at %q, outside the tolerance", msg.MessageId, msg.Timestamp))
+		}
+		// Message ids are unique across topics
+		nonce := "sns:" + msg.MessageId
+		fresh, err := o.Seen.Use(c.RequestContext(), nonce, o.Tolerance+5*time.Minute)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return jsonx.Write(c, http.StatusOK, app.M{"received": true})
+		}
+
+		var m *Message
+		if msg.Type == "Notification" {
+			if m, err = parseSES(c.RequestContext(), msg.Message); err != nil {
+				return c.BadRequest(err)
+			}
+		}
+		if err := handleSNS(c.RequestContext(), &msg, m); err != nil {
+			// For the retry of SNS to be handled
+			if ferr := o.Seen.Forget(c.RequestContext(), nonce); ferr != nil {
+				slog.Error(fmt.Sprintf("inbound: forget SNS message %s: %s", msg.MessageId, ferr))
+			}
+			return err
+		}
+		return jsonx.Write(c, http.StatusOK, app.M{"received": true})
+	}
+}
+
+// handleSNS confirms the subscription to the topic of msg, or dispatches m,
+// the message of a notification, nil for notifications other than received
+// messages.
+func handleSNS(ctx context.Context, msg *snsMessage, m *Message) error {
+	switch msg.Type {
+	case "SubscriptionConfirmation":
+		resp, err := httpclient.Get(ctx, msg.SubscribeURL)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("inbound: confirming the subscription to %s: %s", msg.TopicArn, resp.Status)
+		}
+	case "Notification":
+		if m != nil {
+			return Dispatch(ctx, m)
+		}
+	}
+	return nil
+}
+
+// parseSES returns the message of a notification, or nil for notifications
+// other than received messages, such as the setup one.
+func parseSES(ctx context.Context, notification string) (*Message, error) {
+	var n sesNotification
+	if err := json.Unmarshal([]byte(notification), &n); err != nil {
+		return nil, err
+	}
+	if n.NotificationType != "Received" {
+		return nil, nil
+	}
+
+	var raw []byte
+	switch action := n.Receipt.Action; {
+	case n.Content != "" && action.Encoding == "BASE64":
+		var err error
+		if raw, err = base64.StdEncoding.DecodeString(n.Content); err != nil {
+			return nil, err
+		}
+	case n.Content != "":
+		raw = []byte(n.Content)
+	case action.Type == "S3":
+		var err error
+		if raw, err = readS3(ctx, action.BucketName, action.ObjectKey); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("inbound: the SES notification carries no content, the receipt rule must publish it or store it in S3")
+	}
+
+	m, err := Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	m.Recipients = n.Receipt.Recipients
+	return m, nil
+}
+
+var (
+	s3Once   sync.Once
+	s3Client *s3.S3
+	s3Err    error
+)
+
+func readS3(ctx context.Context, bucket, key string) ([]byte, error) {
+	s3Once.Do(func() {
+		var sess *session.Session
+		sess, s3Err = session.NewSessionWithOptions(session.Options{SharedConfigState: session.SharedConfigEnable})
+		if s3Err == nil {
+			s3Client = s3.New(sess)
+		}
+	})
+	if s3Err != nil {
+		return nil, s3Err
+	}
+	out, err := s3Client.GetObjectWithContext(ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	return io.ReadAll(io.LimitReader(out.Body, MaxSize))
+}
+
+var (
+	certsMu sync.Mutex
+	certs   = map[string]*x509.Certificate{}
+)
+
+// verifySNS checks the signature of msg with the certificate of SNS it
+// names.
+func verifySNS(ctx context.Context, msg *snsMessage) error {
+	u, err := url.Parse(msg.SigningCertURL)
+	if err != nil || u.Scheme != "https" || !snsCertHost.MatchString(u.Host) || !strings.HasSuffix(u.Path, ".pem") {
+		return fmt.Errorf("inbound: untrusted signing certificate %q", msg.SigningCertURL)
+	}
+	cert, err := snsCert(ctx, msg.SigningCertURL)
+	if err != nil {
+		return err
+	}
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return webhook.ErrSignature
+	}
+	sig, err := base64.StdEncoding.DecodeString(msg.Signature)
+	if err != nil {
+		return webhook.ErrSignature
+	}
+
+	var b strings.Builder
+	field := func(name, value string) {
+		b.WriteString(name + "\n" + value + "\n")
+	}
+	field("Message", msg.Message)
+	field("MessageId", msg.MessageId)
+	if msg.Type == "Notification" {
+		if msg.Subject != "" {
+			field("Subject", msg.Subject)
+		}
+	} else {
+		field("SubscribeURL", msg.SubscribeURL)
+	}
+	field("Timestamp", msg.Timestamp)
+	if msg.Type != "Notification" {
+		field("Token", msg.Token)
+	}
+	field("TopicArn", msg.TopicArn)
+	field("Type", msg.Type)
+
+	switch msg.SignatureVersion {
+	case "1":
+		sum := sha1.Sum([]byte(b.String()))
+		err = rsa.VerifyPKCS1v15(pub, crypto.SHA1, sum[:], sig)
+	case "2":
+		sum := sha256.Sum256([]byte(b.String()))
+		err = rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], sig)
+	default:
+		return webhook.ErrSignature
+	}
+	if err != nil {
+		return webhook.ErrSignature
+	}
+	return nil
+}
+
+func snsCert(ctx context.Context, certURL string) (*x509.Certificate, error) {
+	certsMu.Lock()
+	cert, ok := certs[certURL]
+	certsMu.Unlock()
+	if ok {
+		return cert, nil
+	}
+
+	resp, err := httpclient.Get(ctx, certURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("inbound: malformed SNS signing certificate")
+	}
+	cert, err = x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	certsMu.Lock()
+	certs[certURL] = cert
+	certsMu.Unlock()
+	return cert, nil
+}
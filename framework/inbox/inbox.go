@@ -0,0 +1,128 @@
+// Package inbox handles external messages, such as webhooks or messages of
+// a broker delivering them at least once, exactly once per consumer. The id
+// of a message is recorded as processed in the transaction its handler runs
+// in, so a handler failing leaves it free to be retried, and a message
+// delivered again, or to another worker at the same time, is skipped:
+//
+//	ran, err := inbox.Once(ctx, "stripe", event.ID, func(tx *gorm.DB) error {
+//		return repo.From[Payment](tx).Create(payment)
+//	})
+//
+// Queued jobs implementing Message get it for free, under the name of the
+// job, their handler reaching the transaction with Tx:
+//
+//	func (j *SyncInvoice) MessageID() string { return j.EventID }
+//
+//	func (j *SyncInvoice) Handle(ctx context.Context) error {
+//		return repo.From[Invoice](inbox.Tx(ctx)).Create(...)
+//	}
+//
+// Only the changes made in the transaction happen once. Side effects outside
+// of it, such as emails, happen again when the transaction fails to commit.
+package inbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lemmego/lemmego/framework/metrics"
+	"github.com/lemmego/lemmego/framework/queue"
+	"github.com/lemmego/lemmego/framework/repo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var duplicates = metrics.Counter("inbox_duplicates_total", "Messages skipped as already processed, by consumer.")
+
+// ProcessedMessage records a message handled by a consumer.
+type ProcessedMessage struct {
+	ID          uint `gorm:"primaryKey"`
+	Consumer    string
+	MessageID   string
+	ProcessedAt time.Time
+}
+
+func (ProcessedMessage) TableName() string {
+	return "processed_messages"
+}
+
+// Message is implemented by jobs handling an external message, to be
+// handled once per id.
+type Message interface {
+	MessageID() string
+}
+
+func init() {
+	queue.Wrap(func(ctx context.Context, handle func(ctx context.Context) error) error {
+		job, _ := queue.JobFrom(ctx)
+		m, ok := job.(Message)
+		env, _ := queue.EnvelopeFrom(ctx)
+		if !ok || env == nil || m.MessageID() == "" {
+			return handle(ctx)
+		}
+		ran, err := Once(ctx, env.Job, m.MessageID(), func(tx *gorm.DB) error {
+			return handle(context.WithValue(ctx, txKey{}, tx))
+		})
+		if err == nil && !ran {
+			slog.Info(fmt.Sprintf("inbox: %s %s skipped, message %s was already processed", env.Job, env.ID, m.MessageID()))
+		}
+		return err
+	})
+}
+
+// Once runs fn in a transaction of the named connection recording id as
+// processed by consumer, unless it already was, and reports whether fn ran.
+// A handler of the same message running concurrently waits for the
+// transaction, and runs only if it rolled back.
+func Once(ctx context.Context, consumer, id string, fn func(tx *gorm.DB) error, connName ...string) (bool, error) {
+	ran := false
+	err := repo.DB(ctx, connName...).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&ProcessedMessage{
+			Consumer:    consumer,
+			MessageID:   id,
+			ProcessedAt: time.Now(),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			duplicates.With(metrics.Labels{"consumer": consumer}).Inc()
+			return nil
+		}
+		ran = true
+		return fn(tx)
+	})
+	if err != nil {
+		return false, err
+	}
+	return ran, nil
+}
+
+// Processed reports whether consumer already processed id.
+func Processed(ctx context.Context, consumer, id string, connName ...string) (bool, error) {
+	var n int64
+	err := repo.DB(ctx, connName...).Model(&ProcessedMessage{}).
+		Where("consumer = ? AND message_id = ?", consumer, id).Count(&n).Error
+	return n > 0, err
+}
+
+// Prune forgets the messages processed more than olderThan ago, returning
+// how many it forgot. A message delivered again after that is handled again,
+// so olderThan must outlast the redeliveries of the senders.
+func Prune(ctx context.Context, olderThan time.Duration, connName ...string) (int64, error) {
+	result := repo.DB(ctx, connName...).Where("processed_at < ?", time.Now().Add(-olderThan)).Delete(&ProcessedMessage{})
+	return result.RowsAffected, result.Error
+}
+
+type txKey struct{}
+
+// Tx returns the transaction the message being handled is recorded in, or
+// the default connection outside of one.
+func Tx(ctx context.Context) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return repo.DB(ctx)
+}
@@ -0,0 +1,145 @@
+package jsonapi
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/shared"
+
+	"github.com/lemmego/lemmego/framework/jsonx"
+)
+
+// Error is an error object, and an error returned by Unmarshal for
+// documents the client got wrong.
+type Error struct {
+	Status string  `json:"status,omitempty"`
+	Code   string  `json:"code,omitempty"`
+	Title  string  `json:"title,omitempty"`
+	Detail string  `json:"detail,omitempty"`
+	Source *Source `json:"source,omitempty"`
+}
+
+type Source struct {
+	Pointer   string `json:"pointer,omitempty"`
+	Parameter string `json:"parameter,omitempty"`
+}
+
+func (e *Error) Error() string {
+	msg := "jsonapi: " + e.Title
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	if e.Source != nil && e.Source.Pointer != "" {
+		msg += " at " + e.Source.Pointer
+	}
+	return msg
+}
+
+// ParseParams reads the include and fields[type] query parameters of a
+// request for model, a pointer to the primary data's model type.
+func ParseParams(r *http.Request, model any) (*Params, error) {
+	q := r.URL.Query()
+	p := &Params{Fields: map[string][]string{}}
+	if include := q.Get("include"); include != "" {
+		p.Include = strings.Split(include, ",")
+	}
+	for key, values := range q {
+		typ, ok := strings.CutPrefix(key, "fields[")
+		if !ok || !strings.HasSuffix(typ, "]") || len(values) == 0 {
+			continue
+		}
+		var fields []string
+		if values[0] != "" {
+			fields = strings.Split(values[0], ",")
+		}
+		p.Fields[strings.TrimSuffix(typ, "]")] = fields
+	}
+
+	preloads, err := Preloads(model, p.Include)
+	if err != nil {
+		return nil, &Error{Status: "400", Title: "Invalid include", Detail: strings.TrimPrefix(err.Error(), "jsonapi: "), Source: &Source{Parameter: "include"}}
+	}
+	p.Preloads = preloads
+	return p, nil
+}
+
+// Render sends the document of data.
+func Render(c *app.Context, status int, data any, p *Params) error {
+	doc, err := Marshal(data, p)
+	if err != nil {
+		return err
+	}
+	return jsonx.WriteAs(c, status, MediaType, doc)
+}
+
+// RenderError sends an error document. Errors other than *Error and
+// shared.ValidationErrors show their message as the detail.
+func RenderError(c *app.Context, status int, err error) error {
+	var e *Error
+	var verrs shared.ValidationErrors
+	var errs []*Error
+	switch {
+	case errors.As(err, &e):
+		errs = []*Error{e}
+	case errors.As(err, &verrs):
+		errs = ValidationErrors(verrs)
+	default:
+		errs = []*Error{{Title: http.StatusText(status), Detail: err.Error()}}
+	}
+	for _, e := range errs {
+		if e.Status == "" {
+			e.Status = strconv.Itoa(status)
+		}
+	}
+	return jsonx.WriteAs(c, status, MediaType, &Document{Errors: errs})
+}
+
+// ValidationErrors turns the messages of invalid attributes into error
+// objects pointing at them.
+func ValidationErrors(errs shared.ValidationErrors) []*Error {
+	fields := make([]string, 0, len(errs))
+	for field := range errs {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+
+	var out []*Error
+	for _, field := range fields {
+		for _, msg := range errs[field] {
+			out = append(out, &Error{
+				Status: "422",
+				Title:  "Invalid attribute",
+				Detail: msg,
+				Source: &Source{Pointer: "/data/attributes/" + field},
+			})
+		}
+	}
+	return out
+}
+
+// Bind reads a request document into model, like Unmarshal, after checking
+// its content type. The error is ready for RenderError with its status.
+func Bind(c *app.Context, model any) ([]string, int, error) {
+	media, params, err := mime.ParseMediaType(c.Request().Header.Get("Content-Type"))
+	if err != nil || media != MediaType || len(params) > 0 {
+		return nil, http.StatusUnsupportedMediaType, errors.New("requests must be sent as " + MediaType)
+	}
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	columns, err := Unmarshal(body, model)
+	switch {
+	case errors.Is(err, ErrTypeMismatch):
+		return nil, http.StatusConflict, err
+	case err != nil:
+		return nil, http.StatusBadRequest, err
+	}
+	return columns, 0, nil
+}
@@ -0,0 +1,154 @@
+// Package jsonapi reads and writes JSON:API documents (https://jsonapi.org)
+// from models. Resource types are table names, ids primary keys, and
+// attributes and relationships the json names of the fields, with
+// relationships told apart by their gorm associations:
+//
+//	type Post struct {
+//		ID       uint      `gorm:"primaryKey" json:"id"`
+//		Title    string    `json:"title"`
+//		AuthorID uint      `json:"author_id"`
+//		Author   *User     `json:"author"`
+//		Comments []Comment `json:"comments"`
+//	}
+//
+//	r.Get("/posts", func(c *app.Context) error {
+//		p, err := jsonapi.ParseParams(c.Request(), &Post{})
+//		if err != nil {
+//			return jsonapi.RenderError(c, http.StatusBadRequest, err)
+//		}
+//		var posts []Post
+//		query := repo.DB(c.RequestContext())
+//		for _, preload := range p.Preloads {
+//			query = query.Preload(preload)
+//		}
+//		query.Find(&posts)
+//		return jsonapi.Render(c, http.StatusOK, posts, p)
+//	})
+//
+// ?include=author,comments.author adds the related resources to the
+// document, and ?fields[posts]=title limits what a type shows. Belongs to
+// relationships are always linked through their foreign key; the others only
+// when loaded.
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm/schema"
+)
+
+// MediaType is the content type of JSON:API documents.
+const MediaType = "application/vnd.api+json"
+
+var (
+	ErrUnknownInclude = errors.New("jsonapi: unknown relationship")
+	ErrTypeMismatch   = errors.New("jsonapi: resource type does not match the endpoint")
+)
+
+// Document is a top level JSON:API document.
+type Document struct {
+	// Data is a *Resource, a []*Resource or null.
+	Data     any            `json:"data,omitempty"`
+	Errors   []*Error       `json:"errors,omitempty"`
+	Included []*Resource    `json:"included,omitempty"`
+	Meta     map[string]any `json:"meta,omitempty"`
+	Links    map[string]any `json:"links,omitempty"`
+}
+
+// null is the data of an empty to-one document or relationship.
+var null = json.RawMessage("null")
+
+type Resource struct {
+	Type          string                   `json:"type"`
+	ID            string                   `json:"id,omitempty"`
+	Attributes    map[string]any           `json:"attributes,omitempty"`
+	Relationships map[string]*Relationship `json:"relationships,omitempty"`
+	Meta          map[string]any           `json:"meta,omitempty"`
+}
+
+// Relationship links related resources: its data is an *Identifier, an
+// []*Identifier or null.
+type Relationship struct {
+	Data any `json:"data"`
+}
+
+type Identifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// Typer overrides the resource type of a model, its table name by default.
+type Typer interface {
+	JSONAPIType() string
+}
+
+var schemas sync.Map
+
+func parse(t reflect.Type) (*schema.Schema, error) {
+	return schema.Parse(reflect.New(t).Interface(), &schemas, schema.NamingStrategy{})
+}
+
+func typeOf(s *schema.Schema) string {
+	if t, ok := reflect.New(s.ModelType).Interface().(Typer); ok {
+		return t.JSONAPIType()
+	}
+	return s.Table
+}
+
+// jsonName is the member name of a field, or "" for fields hidden from JSON.
+func jsonName(f reflect.StructField) string {
+	tag := f.Tag.Get("json")
+	name, _, _ := strings.Cut(tag, ",")
+	switch {
+	case tag == "-":
+		return ""
+	case name == "":
+		return f.Name
+	}
+	return name
+}
+
+// relations are the relationships of the fields of s. gorm also lists
+// there the has many relationships pointing at s from other models.
+func relations(s *schema.Schema) []*schema.Relationship {
+	var rels []*schema.Relationship
+	for _, rel := range s.Relationships.Relations {
+		if rel.Field.Schema == s {
+			rels = append(rels, rel)
+		}
+	}
+	return rels
+}
+
+// foreignKeys are the columns of s holding a belongs to relationship, which
+// the relationship shows instead of an attribute.
+func foreignKeys(s *schema.Schema) map[string]*schema.Relationship {
+	fks := map[string]*schema.Relationship{}
+	for _, rel := range relations(s) {
+		if rel.Type != schema.BelongsTo {
+			continue
+		}
+		for _, ref := range rel.References {
+			if !ref.OwnPrimaryKey && ref.ForeignKey.Schema == s {
+				fks[ref.ForeignKey.Name] = rel
+			}
+		}
+	}
+	return fks
+}
+
+// attributeName is the member name of a field shown as an attribute, or ""
+// for keys, relationships and hidden fields.
+func attributeName(s *schema.Schema, fks map[string]*schema.Relationship, f *schema.Field) string {
+	if f.DBName == "" || f == s.PrioritizedPrimaryField || fks[f.Name] != nil {
+		return ""
+	}
+	if rel, ok := s.Relationships.Relations[f.Name]; ok && rel.Field.Schema == s {
+		return ""
+	}
+	return jsonName(f.StructField)
+}
@@ -0,0 +1,286 @@
+package jsonapi
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+// Params shape a document: the relationships to include and the sparse
+// fieldsets.
+type Params struct {
+	// Include lists relationship paths, e.g. "comments.author".
+	Include []string
+	// Fields lists the members shown for each type, all when absent.
+	Fields map[string][]string
+	// Preloads are the association paths loading Include, e.g.
+	// "Comments.Author", for gorm's Preload.
+	Preloads []string
+}
+
+// include is the tree of the include paths.
+type include map[string]include
+
+func includeTree(paths []string) include {
+	root := include{}
+	for _, p := range paths {
+		node := root
+		for _, name := range strings.Split(p, ".") {
+			if node[name] == nil {
+				node[name] = include{}
+			}
+			node = node[name]
+		}
+	}
+	return root
+}
+
+// Preloads translates include paths of a model into association paths, and
+// fails with ErrUnknownInclude for paths that are not relationships.
+func Preloads(model any, paths []string) ([]string, error) {
+	root, err := parse(reflect.TypeOf(model).Elem())
+	if err != nil {
+		return nil, err
+	}
+	var preloads []string
+	for _, p := range paths {
+		s := root
+		var fields []string
+		for _, name := range strings.Split(p, ".") {
+			rel := relationNamed(s, name)
+			if rel == nil {
+				return nil, fmt.Errorf("%w %q", ErrUnknownInclude, p)
+			}
+			fields = append(fields, rel.Name)
+			s = rel.FieldSchema
+		}
+		preloads = append(preloads, strings.Join(fields, "."))
+	}
+	return preloads, nil
+}
+
+func relationNamed(s *schema.Schema, name string) *schema.Relationship {
+	for _, rel := range relations(s) {
+		if jsonName(rel.Field.StructField) == name {
+			return rel
+		}
+	}
+	return nil
+}
+
+type marshaler struct {
+	ctx      context.Context
+	params   *Params
+	included []*Resource
+	seen     map[Identifier]bool
+}
+
+// Marshal builds the document of data: a model, a pointer to one, or a slice
+// of either. Related resources listed in p.Include must have been loaded.
+func Marshal(data any, p *Params) (*Document, error) {
+	if p == nil {
+		p = &Params{}
+	}
+	m := &marshaler{ctx: context.Background(), params: p, seen: map[Identifier]bool{}}
+	inc := includeTree(p.Include)
+
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return &Document{Data: null}, nil
+		}
+		v = v.Elem()
+	}
+
+	doc := &Document{}
+	primary := map[Identifier]bool{}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		list := make([]*Resource, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			r, err := m.resource(v.Index(i), inc)
+			if err != nil {
+				return nil, err
+			}
+			primary[Identifier{r.Type, r.ID}] = true
+			list = append(list, r)
+		}
+		doc.Data = list
+	case reflect.Struct:
+		r, err := m.resource(v, inc)
+		if err != nil {
+			return nil, err
+		}
+		primary[Identifier{r.Type, r.ID}] = true
+		doc.Data = r
+	default:
+		return nil, fmt.Errorf("jsonapi: cannot marshal %s", v.Type())
+	}
+
+	// Resources of the primary data are never repeated in included
+	for _, r := range m.included {
+		if !primary[Identifier{r.Type, r.ID}] {
+			doc.Included = append(doc.Included, r)
+		}
+	}
+	return doc, nil
+}
+
+func (m *marshaler) shown(typ, name string) bool {
+	fields, ok := m.params.Fields[typ]
+	return !ok || slices.Contains(fields, name)
+}
+
+func (m *marshaler) resource(v reflect.Value, inc include) (*Resource, error) {
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if !v.CanAddr() {
+		addr := reflect.New(v.Type()).Elem()
+		addr.Set(v)
+		v = addr
+	}
+	s, err := parse(v.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Resource{Type: typeOf(s), ID: m.id(s, v)}
+	fks := foreignKeys(s)
+	for _, f := range s.Fields {
+		name := attributeName(s, fks, f)
+		if name == "" || !m.shown(r.Type, name) {
+			continue
+		}
+		if r.Attributes == nil {
+			r.Attributes = map[string]any{}
+		}
+		r.Attributes[name] = f.ReflectValueOf(m.ctx, v).Interface()
+	}
+
+	for _, rel := range relations(s) {
+		name := jsonName(rel.Field.StructField)
+		if name == "" || !m.shown(r.Type, name) {
+			continue
+		}
+		rv, err := m.relationship(rel, v, inc[name], inc[name] != nil)
+		if err != nil {
+			return nil, err
+		}
+		if rv == nil {
+			continue
+		}
+		if r.Relationships == nil {
+			r.Relationships = map[string]*Relationship{}
+		}
+		r.Relationships[name] = rv
+	}
+	return r, nil
+}
+
+func (m *marshaler) id(s *schema.Schema, v reflect.Value) string {
+	if s.PrioritizedPrimaryField == nil {
+		return ""
+	}
+	id, zero := s.PrioritizedPrimaryField.ValueOf(m.ctx, v)
+	if zero {
+		return ""
+	}
+	return format(id)
+}
+
+// format prints a key, which may be a pointer.
+func format(key any) string {
+	v := reflect.ValueOf(key)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// relationship links the related resources of v, including them when asked
+// to. Relationships that are neither loaded nor known from a foreign key are
+// left out.
+func (m *marshaler) relationship(rel *schema.Relationship, v reflect.Value, inc include, included bool) (*Relationship, error) {
+	fv := rel.Field.ReflectValueOf(m.ctx, v)
+	typ := typeOf(rel.FieldSchema)
+
+	switch rel.Type {
+	case schema.HasMany, schema.Many2Many:
+		if fv.IsNil() {
+			return nil, nil
+		}
+		ids := make([]*Identifier, 0, fv.Len())
+		for i := 0; i < fv.Len(); i++ {
+			id, err := m.link(fv.Index(i), inc, included)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		return &Relationship{Data: ids}, nil
+	}
+
+	loaded := !fv.IsZero()
+	if loaded {
+		id, err := m.link(fv, inc, included)
+		if err != nil {
+			return nil, err
+		}
+		return &Relationship{Data: id}, nil
+	}
+	if rel.Type != schema.BelongsTo {
+		return nil, nil
+	}
+	for _, ref := range rel.References {
+		if ref.OwnPrimaryKey || ref.ForeignKey.Schema != rel.Schema {
+			continue
+		}
+		fk, zero := ref.ForeignKey.ValueOf(m.ctx, v)
+		if zero {
+			return &Relationship{Data: null}, nil
+		}
+		return &Relationship{Data: &Identifier{Type: typ, ID: format(fk)}}, nil
+	}
+	return nil, nil
+}
+
+// link identifies a related model, adding it to included when asked to.
+func (m *marshaler) link(v reflect.Value, inc include, included bool) (*Identifier, error) {
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil, nil
+	}
+	if !included {
+		for v.Kind() == reflect.Pointer {
+			v = v.Elem()
+		}
+		s, err := parse(v.Type())
+		if err != nil {
+			return nil, err
+		}
+		if !v.CanAddr() {
+			addr := reflect.New(v.Type()).Elem()
+			addr.Set(v)
+			v = addr
+		}
+		return &Identifier{Type: typeOf(s), ID: m.id(s, v)}, nil
+	}
+
+	r, err := m.resource(v, inc)
+	if err != nil {
+		return nil, err
+	}
+	id := Identifier{Type: r.Type, ID: r.ID}
+	if !m.seen[id] {
+		m.seen[id] = true
+		m.included = append(m.included, r)
+	}
+	return &id, nil
+}
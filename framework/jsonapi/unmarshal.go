@@ -0,0 +1,180 @@
+package jsonapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+
+	"gorm.io/gorm/schema"
+)
+
+// payload is a request document, its data kept raw so to-one and to-many
+// relationships can be told apart.
+type payload struct {
+	Data *struct {
+		Type          string                     `json:"type"`
+		ID            string                     `json:"id"`
+		Attributes    map[string]json.RawMessage `json:"attributes"`
+		Relationships map[string]struct {
+			Data json.RawMessage `json:"data"`
+		} `json:"relationships"`
+	} `json:"data"`
+}
+
+// Unmarshal reads a request document into model, a pointer to a struct. It
+// returns the columns the document set, for partial updates:
+//
+//	columns, err := jsonapi.Unmarshal(body, post)
+//	tx.Model(post).Select(columns).Updates(post)
+//
+// To-one relationships set their foreign key; to-many relationships fill
+// the association with models carrying only their primary key.
+func Unmarshal(data []byte, model any) ([]string, error) {
+	var p payload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	if p.Data == nil {
+		return nil, errors.New("jsonapi: document has no primary data")
+	}
+
+	v := reflect.ValueOf(model).Elem()
+	s, err := parse(v.Type())
+	if err != nil {
+		return nil, err
+	}
+	if p.Data.Type != typeOf(s) {
+		return nil, fmt.Errorf("%w: got %q, want %q", ErrTypeMismatch, p.Data.Type, typeOf(s))
+	}
+
+	ctx := context.Background()
+	var columns []string
+	if p.Data.ID != "" && s.PrioritizedPrimaryField != nil {
+		if err := setID(ctx, s.PrioritizedPrimaryField, v, p.Data.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	fks := foreignKeys(s)
+	for _, name := range slices.Sorted(maps.Keys(p.Data.Attributes)) {
+		raw := p.Data.Attributes[name]
+		f := attributeNamed(s, fks, name)
+		if f == nil {
+			return nil, &Error{Status: "400", Title: "Unknown attribute", Source: &Source{Pointer: "/data/attributes/" + name}}
+		}
+		target := reflect.New(f.FieldType)
+		if err := json.Unmarshal(raw, target.Interface()); err != nil {
+			return nil, &Error{Status: "400", Title: "Invalid attribute", Detail: err.Error(), Source: &Source{Pointer: "/data/attributes/" + name}}
+		}
+		if err := f.Set(ctx, v, target.Elem().Interface()); err != nil {
+			return nil, err
+		}
+		columns = append(columns, f.DBName)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(p.Data.Relationships)) {
+		r := p.Data.Relationships[name]
+		rel := relationNamed(s, name)
+		if rel == nil {
+			return nil, &Error{Status: "400", Title: "Unknown relationship", Source: &Source{Pointer: "/data/relationships/" + name}}
+		}
+		cols, err := setRelationship(ctx, rel, v, r.Data)
+		if err != nil {
+			return nil, &Error{Status: "400", Title: "Invalid relationship", Detail: err.Error(), Source: &Source{Pointer: "/data/relationships/" + name}}
+		}
+		columns = append(columns, cols...)
+	}
+	return columns, nil
+}
+
+// attributeNamed returns the field shown as an attribute name.
+func attributeNamed(s *schema.Schema, fks map[string]*schema.Relationship, name string) *schema.Field {
+	for _, f := range s.Fields {
+		if attributeName(s, fks, f) == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// setID parses a resource id into a key field.
+func setID(ctx context.Context, f *schema.Field, v reflect.Value, id string) error {
+	switch f.IndirectFieldType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return fmt.Errorf("jsonapi: invalid id %q", id)
+		}
+		return f.Set(ctx, v, n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return fmt.Errorf("jsonapi: invalid id %q", id)
+		}
+		return f.Set(ctx, v, n)
+	}
+	return f.Set(ctx, v, id)
+}
+
+func setRelationship(ctx context.Context, rel *schema.Relationship, v reflect.Value, raw json.RawMessage) ([]string, error) {
+	typ := typeOf(rel.FieldSchema)
+	switch rel.Type {
+	case schema.HasMany, schema.Many2Many:
+		var ids []Identifier
+		if err := json.Unmarshal(raw, &ids); err != nil {
+			return nil, err
+		}
+		elem := rel.Field.IndirectFieldType.Elem()
+		models := reflect.MakeSlice(rel.Field.IndirectFieldType, 0, len(ids))
+		for _, id := range ids {
+			if id.Type != typ {
+				return nil, fmt.Errorf("%w: got %q, want %q", ErrTypeMismatch, id.Type, typ)
+			}
+			related := reflect.New(elem.Elem())
+			if elem.Kind() != reflect.Pointer {
+				related = reflect.New(elem)
+			}
+			if err := setID(ctx, rel.FieldSchema.PrioritizedPrimaryField, related.Elem(), id.ID); err != nil {
+				return nil, err
+			}
+			if elem.Kind() == reflect.Pointer {
+				models = reflect.Append(models, related)
+			} else {
+				models = reflect.Append(models, related.Elem())
+			}
+		}
+		return nil, rel.Field.Set(ctx, v, models.Interface())
+
+	case schema.BelongsTo:
+		var id *Identifier
+		if err := json.Unmarshal(raw, &id); err != nil {
+			return nil, err
+		}
+		if id != nil && id.Type != typ {
+			return nil, fmt.Errorf("%w: got %q, want %q", ErrTypeMismatch, id.Type, typ)
+		}
+		var columns []string
+		for _, ref := range rel.References {
+			if ref.OwnPrimaryKey || ref.ForeignKey.Schema != rel.Schema {
+				continue
+			}
+			var err error
+			if id == nil {
+				err = ref.ForeignKey.Set(ctx, v, nil)
+			} else {
+				err = setID(ctx, ref.ForeignKey, v, id.ID)
+			}
+			if err != nil {
+				return nil, err
+			}
+			columns = append(columns, ref.ForeignKey.DBName)
+		}
+		return columns, nil
+	}
+	return nil, fmt.Errorf("jsonapi: %s relationships are set from the other side", rel.Name)
+}
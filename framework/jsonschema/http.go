@@ -0,0 +1,41 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/req"
+)
+
+// Body validates the JSON body of requests against s before the route's
+// handler runs. Bodies that are not JSON are refused with 415, malformed
+// ones with 400, and invalid ones with the validation errors. The body is
+// left for the handler to read again.
+func Body(s *Schema) app.Handler {
+	return func(c *app.Context) error {
+		r := c.Request()
+		ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if ct != "application/json" && !strings.HasSuffix(ct, "+json") {
+			return &req.MalformedRequest{Status: http.StatusUnsupportedMediaType, Message: "Content-Type header is not application/json"}
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return &req.MalformedRequest{Status: http.StatusBadRequest, Message: "Request body could not be read"}
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		var value any
+		if err := json.Unmarshal(body, &value); err != nil {
+			return &req.MalformedRequest{Status: http.StatusBadRequest, Message: "Request body contains badly-formed JSON"}
+		}
+		if err := s.Validate(value); err != nil {
+			return err
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,350 @@
+// Package jsonschema validates JSON documents against JSON Schema, so the
+// payload contracts of third parties can be enforced as published instead
+// of being rewritten as validate tags.
+//
+//	s, err := jsonschema.Load("schemas/order.json")
+//
+//	r.Post("/partners/orders", createOrder).UseBefore(jsonschema.Body(s))
+//
+// Failures are shared.ValidationErrors keyed by the dotted path of the
+// offending value, "lines.2.sku", with the messages of package validation,
+// so they render and translate like any other validation failure. Errors
+// about the document as a whole are keyed RootKey.
+//
+// The keywords of draft 2020-12 that constrain values are supported, with
+// $ref to anchors inside the document: type, enum, const, the numeric,
+// string, array and object bounds, pattern, format, properties,
+// patternProperties, additionalProperties, required, dependentRequired,
+// items, prefixItems, contains, allOf, anyOf, oneOf, not and if/then/else.
+// The formats checked are email, uri, uuid, date, date-time, time, ipv4,
+// ipv6 and hostname; others are accepted as annotations.
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RootKey keys the errors about the document itself.
+const RootKey = "body"
+
+var ErrRef = errors.New("jsonschema: unresolvable $ref")
+
+// Schema is a compiled JSON Schema.
+type Schema struct {
+	always *bool // the true and false schemas
+
+	types  []string
+	enum   []any
+	cnst   any
+	hasCon bool
+
+	minimum, maximum                   *float64
+	exclusiveMinimum, exclusiveMaximum *float64
+	multipleOf                         *float64
+
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+	format               string
+
+	items, contains, additionalProperties *Schema
+	prefixItems                           []*Schema
+	minItems, maxItems                    *int
+	uniqueItems                           bool
+
+	properties        map[string]*Schema
+	patternProperties map[*regexp.Regexp]*Schema
+	required          []string
+	dependentRequired map[string][]string
+	minProperties     *int
+	maxProperties     *int
+
+	allOf, anyOf, oneOf []*Schema
+	not, ifs, then, els *Schema
+
+	ref string
+	c   *compiler
+}
+
+// Load reads and compiles the schema file at path.
+func Load(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse compiles a schema document.
+func Parse(data []byte) (*Schema, error) {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("jsonschema: %w", err)
+	}
+	c := &compiler{root: doc, refs: map[string]*Schema{}}
+	s, err := c.compile(doc, "#")
+	if err != nil {
+		return nil, err
+	}
+	// Resolve every $ref now, so a broken one fails at load time and
+	// validating only reads the compiled schemas
+	for resolved := false; !resolved; {
+		resolved = true
+		for ref := range c.pending {
+			if _, ok := c.refs[ref]; ok {
+				continue
+			}
+			resolved = false
+			if _, err := c.resolve(ref); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+// MustLoad is Load panicking on error, for schemas read at startup.
+func MustLoad(path string) *Schema {
+	s, err := Load(path)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+type compiler struct {
+	root    any
+	refs    map[string]*Schema
+	pending map[string]bool
+}
+
+func (c *compiler) compile(raw any, at string) (*Schema, error) {
+	s := &Schema{c: c}
+	switch v := raw.(type) {
+	case bool:
+		s.always = &v
+		return s, nil
+	case map[string]any:
+		return s, c.fill(s, v, at)
+	}
+	return nil, fmt.Errorf("jsonschema: %s: a schema is an object or a boolean", at)
+}
+
+func (c *compiler) fill(s *Schema, m map[string]any, at string) error {
+	var err error
+	fail := func(keyword string, e error) error {
+		return fmt.Errorf("jsonschema: %s/%s: %w", at, keyword, e)
+	}
+	sub := func(keyword string) (*Schema, error) {
+		raw, ok := m[keyword]
+		if !ok {
+			return nil, nil
+		}
+		return c.compile(raw, at+"/"+keyword)
+	}
+	list := func(keyword string) ([]*Schema, error) {
+		raw, ok := m[keyword]
+		if !ok {
+			return nil, nil
+		}
+		arr, ok := raw.([]any)
+		if !ok {
+			return nil, fail(keyword, errors.New("expected an array"))
+		}
+		out := make([]*Schema, len(arr))
+		for i, r := range arr {
+			if out[i], err = c.compile(r, at+"/"+keyword+"/"+strconv.Itoa(i)); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+
+	if ref, ok := m["$ref"].(string); ok {
+		if !strings.HasPrefix(ref, "#") {
+			return fmt.Errorf("%w %q at %s: only references inside the document are supported", ErrRef, ref, at)
+		}
+		s.ref = ref
+		if c.pending == nil {
+			c.pending = map[string]bool{}
+		}
+		c.pending[ref] = true
+	}
+
+	switch t := m["type"].(type) {
+	case string:
+		s.types = []string{t}
+	case []any:
+		for _, v := range t {
+			if name, ok := v.(string); ok {
+				s.types = append(s.types, name)
+			}
+		}
+	}
+	if e, ok := m["enum"].([]any); ok {
+		s.enum = e
+	}
+	s.cnst, s.hasCon = m["const"]
+
+	s.minimum, s.maximum, s.multipleOf = number(m["minimum"]), number(m["maximum"]), number(m["multipleOf"])
+	s.exclusiveMinimum, s.exclusiveMaximum = number(m["exclusiveMinimum"]), number(m["exclusiveMaximum"])
+	// Draft 4 writes exclusive bounds as booleans beside the bound
+	if b, _ := m["exclusiveMinimum"].(bool); b {
+		s.exclusiveMinimum, s.minimum = s.minimum, nil
+	}
+	if b, _ := m["exclusiveMaximum"].(bool); b {
+		s.exclusiveMaximum, s.maximum = s.maximum, nil
+	}
+
+	s.minLength, s.maxLength = count(m["minLength"]), count(m["maxLength"])
+	if p, ok := m["pattern"].(string); ok {
+		if s.pattern, err = regexp.Compile(p); err != nil {
+			return fail("pattern", err)
+		}
+	}
+	s.format, _ = m["format"].(string)
+
+	s.minItems, s.maxItems = count(m["minItems"]), count(m["maxItems"])
+	s.uniqueItems, _ = m["uniqueItems"].(bool)
+	if _, isList := m["items"].([]any); isList {
+		// Draft 7 tuples
+		if s.prefixItems, err = list("items"); err != nil {
+			return err
+		}
+		if s.items, err = sub("additionalItems"); err != nil {
+			return err
+		}
+	} else {
+		if s.items, err = sub("items"); err != nil {
+			return err
+		}
+		if s.prefixItems, err = list("prefixItems"); err != nil {
+			return err
+		}
+	}
+	if s.contains, err = sub("contains"); err != nil {
+		return err
+	}
+
+	if props, ok := m["properties"].(map[string]any); ok {
+		s.properties = map[string]*Schema{}
+		for name, raw := range props {
+			if s.properties[name], err = c.compile(raw, at+"/properties/"+name); err != nil {
+				return err
+			}
+		}
+	}
+	if props, ok := m["patternProperties"].(map[string]any); ok {
+		s.patternProperties = map[*regexp.Regexp]*Schema{}
+		for p, raw := range props {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return fail("patternProperties", err)
+			}
+			if s.patternProperties[re], err = c.compile(raw, at+"/patternProperties/"+p); err != nil {
+				return err
+			}
+		}
+	}
+	if s.additionalProperties, err = sub("additionalProperties"); err != nil {
+		return err
+	}
+	s.required = stringList(m["required"])
+	if deps, ok := m["dependentRequired"].(map[string]any); ok {
+		s.dependentRequired = map[string][]string{}
+		for name, raw := range deps {
+			s.dependentRequired[name] = stringList(raw)
+		}
+	}
+	s.minProperties, s.maxProperties = count(m["minProperties"]), count(m["maxProperties"])
+
+	if s.allOf, err = list("allOf"); err != nil {
+		return err
+	}
+	if s.anyOf, err = list("anyOf"); err != nil {
+		return err
+	}
+	if s.oneOf, err = list("oneOf"); err != nil {
+		return err
+	}
+	if s.not, err = sub("not"); err != nil {
+		return err
+	}
+	if s.ifs, err = sub("if"); err != nil {
+		return err
+	}
+	if s.then, err = sub("then"); err != nil {
+		return err
+	}
+	s.els, err = sub("else")
+	return err
+}
+
+// resolve compiles the schema a local $ref points to, once.
+func (c *compiler) resolve(ref string) (*Schema, error) {
+	if s, ok := c.refs[ref]; ok {
+		return s, nil
+	}
+	node := c.root
+	ptr := strings.TrimPrefix(ref, "#")
+	if ptr != "" {
+		for _, token := range strings.Split(strings.TrimPrefix(ptr, "/"), "/") {
+			token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+			switch n := node.(type) {
+			case map[string]any:
+				node = n[token]
+			case []any:
+				i, err := strconv.Atoi(token)
+				if err != nil || i < 0 || i >= len(n) {
+					return nil, fmt.Errorf("%w %q", ErrRef, ref)
+				}
+				node = n[i]
+			default:
+				node = nil
+			}
+			if node == nil {
+				return nil, fmt.Errorf("%w %q", ErrRef, ref)
+			}
+		}
+	}
+	// Registered before compiling, so recursive schemas end
+	s := &Schema{c: c}
+	c.refs[ref] = s
+	compiled, err := c.compile(node, ref)
+	if err != nil {
+		return nil, err
+	}
+	*s = *compiled
+	return s, nil
+}
+
+func number(v any) *float64 {
+	if f, ok := v.(float64); ok {
+		return &f
+	}
+	return nil
+}
+
+func count(v any) *int {
+	if f, ok := v.(float64); ok {
+		n := int(f)
+		return &n
+	}
+	return nil
+}
+
+func stringList(v any) []string {
+	arr, _ := v.([]any)
+	out := make([]string, 0, len(arr))
+	for _, s := range arr {
+		if str, ok := s.(string); ok {
+			out = append(out, str)
+		}
+	}
+	return out
+}
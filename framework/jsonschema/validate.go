@@ -0,0 +1,371 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lemmego/api/shared"
+
+	"github.com/lemmego/lemmego/framework/i18n"
+)
+
+// Validate checks a decoded JSON value, as encoding/json decodes into an
+// any, and returns the failures as shared.ValidationErrors, or nil.
+func (s *Schema) Validate(value any) error {
+	errs := shared.ValidationErrors{}
+	s.check(value, "", errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ValidateJSON decodes data and validates it. Invalid JSON is returned as
+// the decoding error.
+func (s *Schema) ValidateJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	return s.Validate(value)
+}
+
+// valid reports whether value passes without collecting errors, for the
+// applicators that only need to know.
+func (s *Schema) valid(value any) bool {
+	errs := shared.ValidationErrors{}
+	s.check(value, "", errs)
+	return len(errs) == 0
+}
+
+func key(path string) string {
+	if path == "" {
+		return RootKey
+	}
+	return path
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func add(errs shared.ValidationErrors, path, template string, args i18n.Args) {
+	k := key(path)
+	errs[k] = append(errs[k], i18n.Format(template, args))
+}
+
+func (s *Schema) check(value any, path string, errs shared.ValidationErrors) {
+	if s.always != nil {
+		if !*s.always {
+			add(errs, path, "This field is not allowed", nil)
+		}
+		return
+	}
+	if s.ref != "" {
+		s.c.refs[s.ref].check(value, path, errs)
+	}
+
+	if len(s.types) > 0 && !hasType(s.types, value) {
+		add(errs, path, "This field must be of type :type", i18n.Args{"type": strings.Join(s.types, " or ")})
+		return
+	}
+	if s.enum != nil && !containsValue(s.enum, value) {
+		add(errs, path, "This field must be one of the following: :values", i18n.Args{"values": list(s.enum)})
+	}
+	if s.hasCon && !equal(s.cnst, value) {
+		add(errs, path, "This field must be :value", i18n.Args{"value": encode(s.cnst)})
+	}
+
+	switch v := value.(type) {
+	case float64:
+		s.checkNumber(v, path, errs)
+	case string:
+		s.checkString(v, path, errs)
+	case []any:
+		s.checkArray(v, path, errs)
+	case map[string]any:
+		s.checkObject(v, path, errs)
+	}
+
+	for _, sub := range s.allOf {
+		sub.check(value, path, errs)
+	}
+	if len(s.anyOf) > 0 {
+		matched := false
+		for _, sub := range s.anyOf {
+			if sub.valid(value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			add(errs, path, "This field does not match any of the allowed forms", nil)
+		}
+	}
+	if len(s.oneOf) > 0 {
+		matches := 0
+		for _, sub := range s.oneOf {
+			if sub.valid(value) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			add(errs, path, "This field must match exactly one of the allowed forms", nil)
+		}
+	}
+	if s.not != nil && s.not.valid(value) {
+		add(errs, path, "This field is not allowed", nil)
+	}
+	if s.ifs != nil {
+		if s.ifs.valid(value) {
+			if s.then != nil {
+				s.then.check(value, path, errs)
+			}
+		} else if s.els != nil {
+			s.els.check(value, path, errs)
+		}
+	}
+}
+
+func (s *Schema) checkNumber(n float64, path string, errs shared.ValidationErrors) {
+	f := strconv.FormatFloat
+	if s.minimum != nil && n < *s.minimum {
+		add(errs, path, "This field must be at least :min", i18n.Args{"min": f(*s.minimum, 'f', -1, 64)})
+	}
+	if s.maximum != nil && n > *s.maximum {
+		add(errs, path, "This field must not exceed :max", i18n.Args{"max": f(*s.maximum, 'f', -1, 64)})
+	}
+	if s.exclusiveMinimum != nil && n <= *s.exclusiveMinimum {
+		add(errs, path, "This field must be greater than :value", i18n.Args{"value": f(*s.exclusiveMinimum, 'f', -1, 64)})
+	}
+	if s.exclusiveMaximum != nil && n >= *s.exclusiveMaximum {
+		add(errs, path, "This field must be less than :value", i18n.Args{"value": f(*s.exclusiveMaximum, 'f', -1, 64)})
+	}
+	if s.multipleOf != nil && *s.multipleOf > 0 {
+		q := n / *s.multipleOf
+		if math.Abs(q-math.Round(q)) > 1e-9 {
+			add(errs, path, "This field must be a multiple of :value", i18n.Args{"value": f(*s.multipleOf, 'f', -1, 64)})
+		}
+	}
+}
+
+func (s *Schema) checkString(str string, path string, errs shared.ValidationErrors) {
+	length := len([]rune(str))
+	if s.minLength != nil && length < *s.minLength {
+		add(errs, path, "This field must be at least :count character|characters", i18n.Args{"count": *s.minLength})
+	}
+	if s.maxLength != nil && length > *s.maxLength {
+		add(errs, path, "This field must not be longer than :count character|characters", i18n.Args{"count": *s.maxLength})
+	}
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		add(errs, path, "This field must match the pattern: :pattern", i18n.Args{"pattern": s.pattern.String()})
+	}
+	if s.format != "" && !validFormat(s.format, str) {
+		add(errs, path, "This field must be a valid :format", i18n.Args{"format": s.format})
+	}
+}
+
+func (s *Schema) checkArray(arr []any, path string, errs shared.ValidationErrors) {
+	if s.minItems != nil && len(arr) < *s.minItems {
+		add(errs, path, "This field must have at least :count item|items", i18n.Args{"count": *s.minItems})
+	}
+	if s.maxItems != nil && len(arr) > *s.maxItems {
+		add(errs, path, "This field must not have more than :count item|items", i18n.Args{"count": *s.maxItems})
+	}
+	for i, item := range arr {
+		p := join(path, strconv.Itoa(i))
+		switch {
+		case i < len(s.prefixItems):
+			s.prefixItems[i].check(item, p, errs)
+		case s.items != nil:
+			s.items.check(item, p, errs)
+		}
+	}
+	if s.uniqueItems {
+		for i := 1; i < len(arr); i++ {
+			for j := 0; j < i; j++ {
+				if equal(arr[i], arr[j]) {
+					add(errs, join(path, strconv.Itoa(i)), "This value appears more than once", nil)
+					break
+				}
+			}
+		}
+	}
+	if s.contains != nil {
+		found := false
+		for _, item := range arr {
+			if s.contains.valid(item) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			add(errs, path, "This field must contain an allowed item", nil)
+		}
+	}
+}
+
+func (s *Schema) checkObject(obj map[string]any, path string, errs shared.ValidationErrors) {
+	for _, name := range s.required {
+		if _, ok := obj[name]; !ok {
+			add(errs, join(path, name), "This field is required", nil)
+		}
+	}
+	for name, deps := range s.dependentRequired {
+		if _, ok := obj[name]; !ok {
+			continue
+		}
+		for _, dep := range deps {
+			if _, ok := obj[dep]; !ok {
+				add(errs, join(path, dep), "This field is required when :other is present", i18n.Args{"other": name})
+			}
+		}
+	}
+	if s.minProperties != nil && len(obj) < *s.minProperties {
+		add(errs, path, "This field must have at least :count property|properties", i18n.Args{"count": *s.minProperties})
+	}
+	if s.maxProperties != nil && len(obj) > *s.maxProperties {
+		add(errs, path, "This field must not have more than :count property|properties", i18n.Args{"count": *s.maxProperties})
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, p := obj[name], join(path, name)
+		matched := false
+		if sub, ok := s.properties[name]; ok {
+			sub.check(value, p, errs)
+			matched = true
+		}
+		for re, sub := range s.patternProperties {
+			if re.MatchString(name) {
+				sub.check(value, p, errs)
+				matched = true
+			}
+		}
+		if !matched && s.additionalProperties != nil {
+			s.additionalProperties.check(value, p, errs)
+		}
+	}
+}
+
+func hasType(types []string, value any) bool {
+	for _, t := range types {
+		switch t {
+		case "null":
+			if value == nil {
+				return true
+			}
+		case "boolean":
+			if _, ok := value.(bool); ok {
+				return true
+			}
+		case "number":
+			if _, ok := value.(float64); ok {
+				return true
+			}
+		case "integer":
+			if f, ok := value.(float64); ok && f == math.Trunc(f) {
+				return true
+			}
+		case "string":
+			if _, ok := value.(string); ok {
+				return true
+			}
+		case "array":
+			if _, ok := value.([]any); ok {
+				return true
+			}
+		case "object":
+			if _, ok := value.(map[string]any); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func equal(a, b any) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func containsValue(values []any, value any) bool {
+	for _, v := range values {
+		if equal(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func encode(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	var b bytes.Buffer
+	_ = json.NewEncoder(&b).Encode(v)
+	return strings.TrimSpace(b.String())
+}
+
+func list(values []any) string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = encode(v)
+	}
+	return strings.Join(out, ", ")
+}
+
+var uuidPattern = regexp.MustCompile(`^(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+var hostname = regexp.MustCompile(`^(?i:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?)(?:\.(?i:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?))*$`)
+
+func validFormat(format, s string) bool {
+	switch format {
+	case "email":
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	case "uri", "url":
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != ""
+	case "uuid":
+		return uuidPattern.MatchString(s)
+	case "date":
+		_, err := time.Parse(time.DateOnly, s)
+		return err == nil
+	case "date-time":
+		_, err := time.Parse(time.RFC3339Nano, s)
+		return err == nil
+	case "time":
+		for _, layout := range []string{"15:04:05Z07:00", "15:04:05.999999999Z07:00"} {
+			if _, err := time.Parse(layout, s); err == nil {
+				return true
+			}
+		}
+		return false
+	case "ipv4":
+		ip := net.ParseIP(s)
+		return ip != nil && ip.To4() != nil && !strings.Contains(s, ":")
+	case "ipv6":
+		ip := net.ParseIP(s)
+		return ip != nil && strings.Contains(s, ":")
+	case "hostname":
+		return len(s) <= 253 && hostname.MatchString(s)
+	}
+	return true
+}
@@ -0,0 +1,15 @@
+//go:build gojson
+
+package jsonx
+
+import json "github.com/goccy/go-json"
+
+// Engine names the encoder compiled in.
+const Engine = "goccy/go-json"
+
+var (
+	Marshal    = json.Marshal
+	Unmarshal  = json.Unmarshal
+	NewEncoder = json.NewEncoder
+	NewDecoder = json.NewDecoder
+)
@@ -0,0 +1,16 @@
+//go:build !gojson
+
+package jsonx
+
+import "encoding/json"
+
+// Engine names the encoder compiled in. Build with -tags gojson to switch to
+// github.com/goccy/go-json.
+const Engine = "encoding/json"
+
+var (
+	Marshal    = json.Marshal
+	Unmarshal  = json.Unmarshal
+	NewEncoder = json.NewEncoder
+	NewDecoder = json.NewDecoder
+)
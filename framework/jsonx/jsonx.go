@@ -0,0 +1,111 @@
+// Package jsonx writes and reads JSON for handlers with the encoder selected
+// at build time, reusing buffers between requests. Unlike c.JSON it accepts
+// any value, not only app.M, so structs are encoded without a map round trip.
+package jsonx
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+
+	"github.com/lemmego/api/app"
+)
+
+// maxPooledBuffer keeps one huge response from pinning its buffer in the pool.
+const maxPooledBuffer = 64 << 10
+
+var buffers = sync.Pool{
+	New: func() any {
+		return bytes.NewBuffer(make([]byte, 0, 4<<10))
+	},
+}
+
+// Write encodes v into a pooled buffer and sends it with status.
+func Write(c *app.Context, status int, v any) error {
+	return WriteAs(c, status, "application/json", v)
+}
+
+// WriteAs is Write for JSON based media types, e.g. application/problem+json.
+func WriteAs(c *app.Context, status int, contentType string, v any) error {
+	buf := buffers.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledBuffer {
+			buf.Reset()
+			buffers.Put(buf)
+		}
+	}()
+
+	if err := NewEncoder(buf).Encode(v); err != nil {
+		return err
+	}
+
+	w := c.ResponseWriter()
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// OK sends v with 200 OK.
+func OK(c *app.Context, v any) error {
+	return Write(c, http.StatusOK, v)
+}
+
+// Decode reads the request body into v.
+func Decode(c *app.Context, v any) error {
+	return NewDecoder(c.Request().Body).Decode(v)
+}
+
+// Stream writes a JSON array element by element, flushing as it goes, so large
+// result sets never sit in memory as a whole. next returns false once there
+// are no more elements. Encoding stops when the client goes away; as the status
+// is already sent by then, errors only end the response early.
+//
+//	tx := repo.DB(c.RequestContext()).Model(&Post{})
+//	rows, _ := tx.Rows()
+//	defer rows.Close()
+//	return jsonx.Stream(c, http.StatusOK, func() (any, bool, error) {
+//		if !rows.Next() {
+//			return nil, false, rows.Err()
+//		}
+//		var p Post
+//		return &p, true, tx.ScanRows(rows, &p)
+//	})
+func Stream(c *app.Context, status int, next func() (any, bool, error)) error {
+	w := c.ResponseWriter()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	flusher, _ := w.(http.Flusher)
+	enc := NewEncoder(w)
+	ctx := c.RequestContext()
+
+	if _, err := w.Write([]byte("[")); err != nil {
+		return err
+	}
+	for i := 0; ; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		v, ok, err := next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		if i > 0 {
+			if _, err := w.Write([]byte(",")); err != nil {
+				return err
+			}
+		}
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		if flusher != nil && i%100 == 99 {
+			flusher.Flush()
+		}
+	}
+	_, err := w.Write([]byte("]"))
+	return err
+}
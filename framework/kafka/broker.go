@@ -0,0 +1,68 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Broker carries events over Kafka topics, see events.Broker. Groups are
+// consumer groups, which resume where they left off.
+type Broker struct {
+	client *Client
+}
+
+func NewBroker(client *Client) *Broker {
+	return &Broker{client: client}
+}
+
+func (b *Broker) Publish(ctx context.Context, topic string, data []byte) error {
+	return b.client.Produce(ctx, topic, Record{Value: data})
+}
+
+// Subscribe commits the records once fn returns, creating the consumer
+// again when the proxy lost it, until ctx is done.
+func (b *Broker) Subscribe(ctx context.Context, topic, group string, fn func(data []byte)) error {
+	var consumer *Consumer
+	defer func() {
+		if consumer != nil {
+			_ = consumer.Close(context.WithoutCancel(ctx))
+		}
+	}()
+
+	for ctx.Err() == nil {
+		err := func() error {
+			if consumer == nil {
+				c, err := b.client.NewConsumer(ctx, group, topic)
+				if err != nil {
+					return err
+				}
+				consumer = c
+			}
+			records, err := consumer.Fetch(ctx, time.Second)
+			if err != nil {
+				return err
+			}
+			if len(records) == 0 {
+				return nil
+			}
+			for _, r := range records {
+				fn(r.Value)
+			}
+			return consumer.Commit(ctx, records...)
+		}()
+		if err == nil || ctx.Err() != nil {
+			continue
+		}
+		if IsConsumerGone(err) {
+			consumer = nil
+		}
+		slog.Warn(fmt.Sprintf("kafka: subscription to %s: %s", topic, err))
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+	return nil
+}
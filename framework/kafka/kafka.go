@@ -0,0 +1,223 @@
+// Package kafka talks to Kafka through a REST Proxy, the v2 API of
+// Confluent's, sparing a client of the binary protocol. It covers what the
+// queue driver and the event broker need: producing records, and consuming
+// them in consumer groups with commits made by hand.
+//
+//	c := kafka.NewClient("http://kafka-rest:8082")
+//	err := c.Produce(ctx, "orders", kafka.Record{Key: []byte(id), Value: data})
+//
+//	consumer, err := c.NewConsumer(ctx, "billing", "orders")
+//	records, err := consumer.Fetch(ctx, time.Second)
+//	...
+//	err = consumer.Commit(ctx, records...)
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lemmego/lemmego/framework/str"
+)
+
+const (
+	jsonV2   = "application/vnd.kafka.v2+json"
+	binaryV2 = "application/vnd.kafka.binary.v2+json"
+)
+
+// Error codes of the REST Proxy.
+const (
+	CodeConsumerNotFound = 40403
+)
+
+// Error is an error answered by the REST Proxy.
+type Error struct {
+	Status  int
+	Code    int    `json:"error_code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("kafka: %s (%d)", e.Message, e.Code)
+}
+
+// IsConsumerGone reports whether err is about a consumer instance the proxy
+// no longer knows, expired for being idle too long.
+func IsConsumerGone(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Code == CodeConsumerNotFound
+}
+
+// Record is a record of a topic.
+type Record struct {
+	Topic     string
+	Key       []byte
+	Value     []byte
+	Partition int32
+	Offset    int64
+}
+
+// Client is a client of a REST Proxy.
+type Client struct {
+	url string
+	// Username and Password authenticate requests with basic auth when set.
+	Username string
+	Password string
+	HTTP     *http.Client
+}
+
+func NewClient(url string) *Client {
+	return &Client{url: strings.TrimRight(url, "/"), HTTP: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Produce appends records to a topic, partitioned by their keys.
+func (c *Client) Produce(ctx context.Context, topic string, records ...Record) error {
+	type record struct {
+		Key   []byte `json:"key,omitempty"`
+		Value []byte `json:"value"`
+	}
+	body := struct {
+		Records []record `json:"records"`
+	}{}
+	for _, r := range records {
+		body.Records = append(body.Records, record{Key: r.Key, Value: r.Value})
+	}
+
+	var resp struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/topics/"+url.PathEscape(topic), binaryV2, jsonV2, body, &resp); err != nil {
+		return err
+	}
+	for _, o := range resp.Offsets {
+		if o.ErrorCode != nil {
+			return &Error{Code: *o.ErrorCode, Message: o.Error}
+		}
+	}
+	return nil
+}
+
+// Consumer is a consumer instance of a group, subscribed to topics.
+type Consumer struct {
+	client *Client
+	path   string
+}
+
+// NewConsumer creates a consumer instance in group, reading topics from
+// their start when the group has no offsets yet. Offsets are only committed
+// by Commit.
+func (c *Client) NewConsumer(ctx context.Context, group string, topics ...string) (*Consumer, error) {
+	req := map[string]string{
+		"name":               "lemmego-" + str.RandomString(12),
+		"format":             "binary",
+		"auto.offset.reset":  "earliest",
+		"auto.commit.enable": "false",
+	}
+	var resp struct {
+		InstanceID string `json:"instance_id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/consumers/"+url.PathEscape(group), jsonV2, jsonV2, req, &resp); err != nil {
+		return nil, err
+	}
+	// The base URI in the response holds the address the proxy advertises,
+	// which may not be the one it is reached at
+	consumer := &Consumer{client: c, path: "/consumers/" + url.PathEscape(group) + "/instances/" + url.PathEscape(resp.InstanceID)}
+	if err := c.do(ctx, http.MethodPost, consumer.path+"/subscription", jsonV2, jsonV2, map[string]any{"topics": topics}, nil); err != nil {
+		consumer.Close(context.WithoutCancel(ctx))
+		return nil, err
+	}
+	return consumer, nil
+}
+
+// Fetch returns the next records, waiting up to timeout for some.
+func (c *Consumer) Fetch(ctx context.Context, timeout time.Duration) ([]Record, error) {
+	var resp []struct {
+		Topic     string `json:"topic"`
+		Key       []byte `json:"key"`
+		Value     []byte `json:"value"`
+		Partition int32  `json:"partition"`
+		Offset    int64  `json:"offset"`
+	}
+	path := c.path + "/records?timeout=" + strconv.FormatInt(timeout.Milliseconds(), 10)
+	if err := c.client.do(ctx, http.MethodGet, path, "", binaryV2, nil, &resp); err != nil {
+		return nil, err
+	}
+	records := make([]Record, len(resp))
+	for i, r := range resp {
+		records[i] = Record{Topic: r.Topic, Key: r.Key, Value: r.Value, Partition: r.Partition, Offset: r.Offset}
+	}
+	return records, nil
+}
+
+// Commit commits the offsets of records for the group, the proxy storing
+// the offsets following theirs, so they are not read again.
+func (c *Consumer) Commit(ctx context.Context, records ...Record) error {
+	type offset struct {
+		Topic     string `json:"topic"`
+		Partition int32  `json:"partition"`
+		Offset    int64  `json:"offset"`
+	}
+	body := struct {
+		Offsets []offset `json:"offsets"`
+	}{}
+	for _, r := range records {
+		body.Offsets = append(body.Offsets, offset{Topic: r.Topic, Partition: r.Partition, Offset: r.Offset})
+	}
+	return c.client.do(ctx, http.MethodPost, c.path+"/offsets", jsonV2, jsonV2, body, nil)
+}
+
+// Close deletes the consumer instance, handing its partitions to the other
+// instances of the group.
+func (c *Consumer) Close(ctx context.Context) error {
+	return c.client.do(ctx, http.MethodDelete, c.path, jsonV2, jsonV2, nil, nil)
+}
+
+func (c *Client) do(ctx context.Context, method, path, contentType, accept string, body, v any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, reader)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", accept)
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		e := &Error{Status: resp.StatusCode}
+		if json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(e) != nil || e.Message == "" {
+			e.Message = resp.Status
+		}
+		return e
+	}
+	if v == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,179 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The subset of BER (X.690) LDAP messages are encoded with.
+
+const (
+	classUniversal   = 0x00
+	classApplication = 0x40
+	classContext     = 0x80
+	constructed      = 0x20
+
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x10 | constructed
+	tagSet         = 0x11 | constructed
+)
+
+// maxPacket bounds the messages read from the server.
+const maxPacket = 16 << 20
+
+var errMalformed = errors.New("ldap: malformed message")
+
+// packet is a decoded BER element.
+type packet struct {
+	tag      byte
+	value    []byte
+	children []*packet
+}
+
+func (p *packet) constructed() bool {
+	return p.tag&constructed != 0
+}
+
+func (p *packet) str() string {
+	return string(p.value)
+}
+
+func (p *packet) int() int64 {
+	var n int64
+	for i, b := range p.value {
+		if i == 0 && b&0x80 != 0 {
+			n = -1
+		}
+		n = n<<8 | int64(b)
+	}
+	return n
+}
+
+func (p *packet) child(i int) *packet {
+	if i < len(p.children) {
+		return p.children[i]
+	}
+	return &packet{}
+}
+
+// encode encodes an element of tag around value.
+func encode(tag byte, value ...[]byte) []byte {
+	n := 0
+	for _, v := range value {
+		n += len(v)
+	}
+	out := append([]byte{tag}, encodeLength(n)...)
+	for _, v := range value {
+		out = append(out, v...)
+	}
+	return out
+}
+
+func encodeLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+func encodeInt(tag byte, n int64) []byte {
+	var b []byte
+	for {
+		b = append([]byte{byte(n)}, b...)
+		n >>= 8
+		if (n == 0 && b[0]&0x80 == 0) || (n == -1 && b[0]&0x80 != 0) {
+			break
+		}
+	}
+	return encode(tag, b)
+}
+
+func encodeString(tag byte, s string) []byte {
+	return encode(tag, []byte(s))
+}
+
+func encodeBool(b bool) []byte {
+	if b {
+		return encode(tagBoolean, []byte{0xff})
+	}
+	return encode(tagBoolean, []byte{0})
+}
+
+// readPacket reads an element from r.
+func readPacket(r *bufio.Reader) (*packet, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	first, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	n := int(first)
+	if first&0x80 != 0 {
+		size := int(first & 0x7f)
+		if size == 0 || size > 4 {
+			return nil, errMalformed
+		}
+		n = 0
+		for i := 0; i < size; i++ {
+			b, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			n = n<<8 | int(b)
+		}
+	}
+	if n > maxPacket {
+		return nil, fmt.Errorf("ldap: message of %d bytes is too large", n)
+	}
+	value := make([]byte, n)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return nil, err
+	}
+	return parse(tag, value)
+}
+
+func parse(tag byte, value []byte) (*packet, error) {
+	p := &packet{tag: tag, value: value}
+	if !p.constructed() {
+		return p, nil
+	}
+	for len(value) > 0 {
+		if len(value) < 2 {
+			return nil, errMalformed
+		}
+		ctag, first := value[0], value[1]
+		n, header := int(first), 2
+		if first&0x80 != 0 {
+			size := int(first & 0x7f)
+			if size == 0 || size > 4 || len(value) < 2+size {
+				return nil, errMalformed
+			}
+			n = 0
+			for _, b := range value[2 : 2+size] {
+				n = n<<8 | int(b)
+			}
+			header += size
+		}
+		if n < 0 || len(value) < header+n {
+			return nil, errMalformed
+		}
+		child, err := parse(ctag, value[header:header+n])
+		if err != nil {
+			return nil, err
+		}
+		p.children = append(p.children, child)
+		value = value[header+n:]
+	}
+	return p, nil
+}
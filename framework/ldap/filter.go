@@ -0,0 +1,158 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// compileFilter encodes a string filter (RFC 4515), such as
+// (&(objectClass=person)(uid={username})). Placeholders in values are
+// replaced by vars once the filter is parsed, so values cannot change its
+// structure however they are written.
+func compileFilter(filter string, vars map[string]string) ([]byte, error) {
+	f := &filterParser{s: strings.TrimSpace(filter), vars: vars}
+	b, err := f.filter()
+	if err != nil {
+		return nil, err
+	}
+	if f.pos != len(f.s) {
+		return nil, fmt.Errorf("ldap: unexpected %q in filter", f.s[f.pos:])
+	}
+	return b, nil
+}
+
+type filterParser struct {
+	s    string
+	pos  int
+	vars map[string]string
+}
+
+func (f *filterParser) filter() ([]byte, error) {
+	if f.pos >= len(f.s) || f.s[f.pos] != '(' {
+		return nil, fmt.Errorf("ldap: filter must start with ( at %d", f.pos)
+	}
+	f.pos++
+	if f.pos >= len(f.s) {
+		return nil, fmt.Errorf("ldap: unterminated filter")
+	}
+
+	var out []byte
+	switch f.s[f.pos] {
+	case '&', '|':
+		tag := byte(classContext | constructed)
+		if f.s[f.pos] == '|' {
+			tag |= 1
+		}
+		f.pos++
+		var items [][]byte
+		for f.pos < len(f.s) && f.s[f.pos] == '(' {
+			item, err := f.filter()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		out = encode(tag, items...)
+	case '!':
+		f.pos++
+		item, err := f.filter()
+		if err != nil {
+			return nil, err
+		}
+		out = encode(classContext|constructed|2, item)
+	default:
+		end := strings.IndexByte(f.s[f.pos:], ')')
+		if end < 0 {
+			return nil, fmt.Errorf("ldap: unterminated filter")
+		}
+		item, err := f.item(f.s[f.pos : f.pos+end])
+		if err != nil {
+			return nil, err
+		}
+		out = item
+		f.pos += end
+	}
+
+	if f.pos >= len(f.s) || f.s[f.pos] != ')' {
+		return nil, fmt.Errorf("ldap: filter must end with ) at %d", f.pos)
+	}
+	f.pos++
+	return out, nil
+}
+
+func (f *filterParser) item(s string) ([]byte, error) {
+	eq := strings.IndexByte(s, '=')
+	if eq < 1 {
+		return nil, fmt.Errorf("ldap: invalid filter item %q", s)
+	}
+	attr, raw := s[:eq], s[eq+1:]
+	var tag byte
+	switch attr[len(attr)-1] {
+	case '>':
+		tag, attr = 5, attr[:len(attr)-1]
+	case '<':
+		tag, attr = 6, attr[:len(attr)-1]
+	case '~':
+		tag, attr = 8, attr[:len(attr)-1]
+	default:
+		tag = 3
+	}
+
+	if tag == 3 && raw == "*" {
+		return encodeString(classContext|7, attr), nil
+	}
+	if tag == 3 && strings.Contains(raw, "*") {
+		parts := strings.Split(raw, "*")
+		var subs [][]byte
+		for i, part := range parts {
+			if part == "" {
+				continue
+			}
+			value, err := f.value(part)
+			if err != nil {
+				return nil, err
+			}
+			kind := byte(1)
+			switch i {
+			case 0:
+				kind = 0
+			case len(parts) - 1:
+				kind = 2
+			}
+			subs = append(subs, encodeString(classContext|kind, value))
+		}
+		return encode(classContext|constructed|4, encodeString(tagOctetString, attr), encode(tagSequence, subs...)), nil
+	}
+
+	value, err := f.value(raw)
+	if err != nil {
+		return nil, err
+	}
+	return encode(classContext|constructed|tag, encodeString(tagOctetString, attr), encodeString(tagOctetString, value)), nil
+}
+
+// value unescapes \XX sequences and replaces placeholders.
+func (f *filterParser) value(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+3 > len(s) {
+			return "", fmt.Errorf("ldap: invalid escape in %q", s)
+		}
+		decoded, err := hex.DecodeString(s[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("ldap: invalid escape in %q", s)
+		}
+		b.Write(decoded)
+		i += 2
+	}
+	out := b.String()
+	for k, v := range f.vars {
+		out = strings.ReplaceAll(out, "{"+k+"}", v)
+	}
+	return out, nil
+}
@@ -0,0 +1,367 @@
+// Package ldap is a small LDAPv3 client (RFC 4511), enough to authenticate
+// users against a directory such as OpenLDAP or Active Directory: simple
+// binds, searches and StartTLS, over pooled connections.
+//
+//	c, err := ldap.Dial(ctx, "ldaps://dc.example.com", &ldap.Options{})
+//	if err := c.Bind(ctx, "cn=svc,dc=example,dc=com", password); err != nil {
+//		return err
+//	}
+//	entries, err := c.Search(ctx, &ldap.SearchRequest{
+//		BaseDN: "dc=example,dc=com",
+//		Filter: "(&(objectClass=person)(uid={username}))",
+//		Vars:   map[string]string{"username": username},
+//	})
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Result codes.
+const (
+	ResultSuccess            = 0
+	ResultInvalidCredentials = 49
+)
+
+// Scope is the scope of a search.
+type Scope int
+
+// Search scopes, the whole subtree under the base being the default.
+const (
+	ScopeSubtree Scope = iota
+	ScopeOne
+	ScopeBase
+)
+
+// wire returns the value of the scope in requests.
+func (s Scope) wire() int64 {
+	switch s {
+	case ScopeBase:
+		return 0
+	case ScopeOne:
+		return 1
+	}
+	return 2
+}
+
+const (
+	startTLSOID    = "1.3.6.1.4.1.1466.20037"
+	defaultTimeout = 10 * time.Second
+)
+
+// ErrClosed is returned by the operations of a broken or closed connection.
+var ErrClosed = errors.New("ldap: connection closed")
+
+// Error is a result other than success returned by the server.
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ldap: result code %d", e.Code)
+	}
+	return fmt.Sprintf("ldap: result code %d: %s", e.Code, e.Message)
+}
+
+// IsInvalidCredentials reports whether err is a bind refused for its
+// credentials.
+func IsInvalidCredentials(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Code == ResultInvalidCredentials
+}
+
+// Options configure a connection.
+type Options struct {
+	// StartTLS upgrades ldap:// connections to TLS before anything is sent.
+	StartTLS bool
+	// TLS configures ldaps:// and StartTLS, the host of the URL being the
+	// server name when unset.
+	TLS *tls.Config
+	// Timeout bounds dialing and each operation without a context deadline,
+	// 10 seconds when zero.
+	Timeout time.Duration
+}
+
+// Conn is a connection to a directory server. Operations are run one at a
+// time.
+type Conn struct {
+	mu      sync.Mutex
+	conn    net.Conn
+	r       *bufio.Reader
+	id      int64
+	timeout time.Duration
+	broken  bool
+}
+
+// Dial connects to an ldap:// or ldaps:// URL.
+func Dial(ctx context.Context, rawURL string, opts *Options) (*Conn, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: invalid url: %w", err)
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	host := u.Host
+	config := opts.TLS
+	if config == nil {
+		config = &tls.Config{}
+	}
+	if config.ServerName == "" {
+		config = config.Clone()
+		config.ServerName = u.Hostname()
+	}
+
+	d := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	switch u.Scheme {
+	case "ldap":
+		if u.Port() == "" {
+			host = net.JoinHostPort(host, "389")
+		}
+		conn, err = d.DialContext(ctx, "tcp", host)
+	case "ldaps":
+		if u.Port() == "" {
+			host = net.JoinHostPort(host, "636")
+		}
+		conn, err = (&tls.Dialer{NetDialer: d, Config: config}).DialContext(ctx, "tcp", host)
+	default:
+		return nil, fmt.Errorf("ldap: unsupported scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Conn{conn: conn, r: bufio.NewReader(conn), timeout: timeout}
+	if opts.StartTLS && u.Scheme == "ldap" {
+		if err := c.startTLS(ctx, config); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *Conn) startTLS(ctx context.Context, config *tls.Config) error {
+	if _, err := c.call(ctx, encode(classApplication|constructed|23, encodeString(classContext|0, startTLSOID)), classApplication|constructed|24); err != nil {
+		return fmt.Errorf("ldap: starttls: %w", err)
+	}
+	tlsConn := tls.Client(c.conn, config)
+	c.setDeadline(ctx)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		c.broken = true
+		return err
+	}
+	c.conn, c.r = tlsConn, bufio.NewReader(tlsConn)
+	return nil
+}
+
+// Bind authenticates the connection as dn. The directory treats an empty
+// password as an anonymous bind, which succeeds, so it is refused here.
+func (c *Conn) Bind(ctx context.Context, dn, password string) error {
+	if password == "" {
+		return &Error{Code: ResultInvalidCredentials, Message: "empty password"}
+	}
+	_, err := c.call(ctx, encode(classApplication|constructed|0,
+		encodeInt(tagInteger, 3),
+		encodeString(tagOctetString, dn),
+		encodeString(classContext|0, password),
+	), classApplication|constructed|1)
+	return err
+}
+
+// SearchRequest is a search. Placeholders such as {username} in the values
+// of Filter are replaced by Vars, escaping being unnecessary.
+type SearchRequest struct {
+	BaseDN     string
+	Scope      Scope
+	Filter     string
+	Vars       map[string]string
+	Attributes []string
+	SizeLimit  int
+}
+
+// Entry is an entry returned by a search.
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// Values returns the values of an attribute, its name matched regardless of
+// case.
+func (e *Entry) Values(name string) []string {
+	for k, v := range e.Attributes {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+// Value returns the first value of an attribute.
+func (e *Entry) Value(name string) string {
+	if v := e.Values(name); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// Search returns the entries matching req.
+func (c *Conn) Search(ctx context.Context, req *SearchRequest) ([]*Entry, error) {
+	filter, err := compileFilter(req.Filter, req.Vars)
+	if err != nil {
+		return nil, err
+	}
+	var attrs [][]byte
+	for _, a := range req.Attributes {
+		attrs = append(attrs, encodeString(tagOctetString, a))
+	}
+	op := encode(classApplication|constructed|3,
+		encodeString(tagOctetString, req.BaseDN),
+		encodeInt(tagEnumerated, req.Scope.wire()),
+		encodeInt(tagEnumerated, 0),
+		encodeInt(tagInteger, int64(req.SizeLimit)),
+		encodeInt(tagInteger, 0),
+		encodeBool(false),
+		filter,
+		encode(tagSequence, attrs...),
+	)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id, err := c.send(ctx, op)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	for {
+		p, err := c.receive(id)
+		if err != nil {
+			return nil, err
+		}
+		switch p.tag {
+		case classApplication | constructed | 4:
+			entries = append(entries, entry(p))
+		case classApplication | constructed | 19:
+			// Referrals to other servers are not followed
+		case classApplication | constructed | 5:
+			return entries, result(p)
+		default:
+			c.broken = true
+			return nil, errMalformed
+		}
+	}
+}
+
+func entry(p *packet) *Entry {
+	e := &Entry{DN: p.child(0).str(), Attributes: map[string][]string{}}
+	for _, attr := range p.child(1).children {
+		var values []string
+		for _, v := range attr.child(1).children {
+			values = append(values, v.str())
+		}
+		e.Attributes[attr.child(0).str()] = values
+	}
+	return e
+}
+
+// Close unbinds and closes the connection.
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.broken {
+		c.id++
+		c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+		c.conn.Write(encode(tagSequence, encodeInt(tagInteger, c.id), encode(classApplication|2)))
+	}
+	c.broken = true
+	return c.conn.Close()
+}
+
+// call sends op and returns the response of tag, failing on a result other
+// than success.
+func (c *Conn) call(ctx context.Context, op []byte, tag byte) (*packet, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id, err := c.send(ctx, op)
+	if err != nil {
+		return nil, err
+	}
+	p, err := c.receive(id)
+	if err != nil {
+		return nil, err
+	}
+	if p.tag != tag {
+		c.broken = true
+		return nil, errMalformed
+	}
+	return p, result(p)
+}
+
+func (c *Conn) send(ctx context.Context, op []byte) (int64, error) {
+	if c.broken {
+		return 0, ErrClosed
+	}
+	c.id++
+	c.setDeadline(ctx)
+	if _, err := c.conn.Write(encode(tagSequence, encodeInt(tagInteger, c.id), op)); err != nil {
+		c.broken = true
+		return 0, err
+	}
+	return c.id, nil
+}
+
+// receive reads the next response to message id and returns its operation.
+func (c *Conn) receive(id int64) (*packet, error) {
+	for {
+		msg, err := readPacket(c.r)
+		if err != nil {
+			c.broken = true
+			return nil, err
+		}
+		if msg.tag != tagSequence || len(msg.children) < 2 {
+			c.broken = true
+			return nil, errMalformed
+		}
+		// Unsolicited notifications have id 0, such as notices of
+		// disconnection, after which the connection is unusable
+		if msg.child(0).int() == 0 {
+			c.broken = true
+			return nil, fmt.Errorf("%w: %v", ErrClosed, result(msg.child(1)))
+		}
+		if msg.child(0).int() == id {
+			return msg.child(1), nil
+		}
+	}
+}
+
+func (c *Conn) setDeadline(ctx context.Context) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(c.timeout)
+	}
+	c.conn.SetDeadline(deadline)
+}
+
+// result returns the error of an LDAPResult, if any.
+func result(p *packet) error {
+	if code := int(p.child(0).int()); code != ResultSuccess {
+		return &Error{Code: code, Message: p.child(2).str()}
+	}
+	return nil
+}
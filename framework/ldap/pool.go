@@ -0,0 +1,75 @@
+package ldap
+
+import (
+	"context"
+	"sync"
+)
+
+// Pool keeps idle connections for reuse. Connections come from dial, which
+// typically binds them as a service account, and must be in that state again
+// when put back.
+type Pool struct {
+	dial func(ctx context.Context) (*Conn, error)
+	idle chan *Conn
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewPool returns a pool keeping up to size idle connections.
+func NewPool(size int, dial func(ctx context.Context) (*Conn, error)) *Pool {
+	return &Pool{dial: dial, idle: make(chan *Conn, max(size, 1))}
+}
+
+// Get returns an idle connection, or a new one.
+func (p *Pool) Get(ctx context.Context) (*Conn, error) {
+	for {
+		select {
+		case c := <-p.idle:
+			if c.usable() {
+				return c, nil
+			}
+			c.Close()
+		default:
+			return p.dial(ctx)
+		}
+	}
+}
+
+// Put returns a connection to the pool, closing it when broken or when the
+// pool is full.
+func (p *Pool) Put(c *Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed || !c.usable() {
+		c.Close()
+		return
+	}
+	select {
+	case p.idle <- c:
+	default:
+		c.Close()
+	}
+}
+
+// Close closes the idle connections. Connections put back afterwards are
+// closed too.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for {
+		select {
+		case c := <-p.idle:
+			c.Close()
+		default:
+			return
+		}
+	}
+}
+
+func (c *Conn) usable() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.broken
+}
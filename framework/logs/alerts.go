@@ -0,0 +1,119 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/lemmego/api/config"
+
+	"github.com/lemmego/lemmego/framework/notify"
+)
+
+// alerter is shared by the alerts sinks of every channel, so they are
+// batched and rate limited together.
+var alerter *notify.Alerter
+
+// alertsSink returns the handler of the alerts sink, and the alerter when
+// it was just started. Below the level of the environment, or with no
+// channels, it drops everything.
+func alertsSink() (slog.Handler, *notify.Alerter) {
+	level, ok := alertLevel()
+	names, _ := config.Get("notifications.alerts.channels", "").(string)
+	var channels []string
+	for _, n := range strings.Split(names, ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			channels = append(channels, n)
+		}
+	}
+	if !ok || len(channels) == 0 {
+		return &alerts{}, nil
+	}
+
+	var started *notify.Alerter
+	if alerter == nil {
+		every, _ := config.Get("notifications.alerts.batch_seconds", 0).(int)
+		dedup, _ := config.Get("notifications.alerts.dedup_minutes", 0).(int)
+		perHour, _ := config.Get("notifications.alerts.per_hour", 0).(int)
+		name, _ := config.Get("app.name", "app").(string)
+		env, _ := config.Get("app.env", "").(string)
+		alerter = notify.NewAlerter(notify.AlertOptions{
+			Channels: channels,
+			Title:    name + " (" + env + ")",
+			Every:    time.Duration(every) * time.Second,
+			Dedup:    time.Duration(dedup) * time.Minute,
+			PerHour:  perHour,
+		})
+		started = alerter
+	}
+	return &alerts{alerter: alerter, level: level}, started
+}
+
+// alertLevel is the level records are alerted from in the environment,
+// false when alerts are off.
+func alertLevel() (slog.Level, bool) {
+	s, _ := config.Get("notifications.alerts.level", "").(string)
+	if s == "" {
+		env, _ := config.Get("app.env", "").(string)
+		s, _ = config.Get("notifications.alerts.levels."+env, "off").(string)
+	}
+	var level slog.Level
+	if s == "" || s == "off" {
+		return level, false
+	}
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return level, false
+	}
+	return level, true
+}
+
+// alerts raises an alert for every record from level on. Records repeating
+// a message are folded together whatever their attributes, which are sent
+// along as details.
+type alerts struct {
+	alerter *notify.Alerter
+	level   slog.Level
+	attrs   string
+	group   string
+}
+
+func (h *alerts) Enabled(_ context.Context, level slog.Level) bool {
+	return h.alerter != nil && level >= h.level
+}
+
+func (h *alerts) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	b.WriteString(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		appendAttr(&b, h.group, a)
+		return true
+	})
+	h.alerter.Alert(r.Level, r.Message, strings.TrimSpace(b.String()))
+	return nil
+}
+
+func (h *alerts) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	b.WriteString(h.attrs)
+	for _, a := range attrs {
+		appendAttr(&b, h.group, a)
+	}
+	return &alerts{alerter: h.alerter, level: h.level, attrs: b.String(), group: h.group}
+}
+
+func (h *alerts) WithGroup(name string) slog.Handler {
+	return &alerts{alerter: h.alerter, level: h.level, attrs: h.attrs, group: h.group + name + "."}
+}
+
+func appendAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		for _, ga := range v.Group() {
+			appendAttr(b, prefix+a.Key+".", ga)
+		}
+		return
+	}
+	fmt.Fprintf(b, " %s%s=%v", prefix, a.Key, v.Any())
+}
@@ -0,0 +1,190 @@
+// Package logs writes to named channels, each with its own sinks, level and
+// sampling, configured under logging:
+//
+//	logs.Channel(logs.Audit).Info("role changed", "user", u.ID, "role", role)
+//
+// Handlers log with the request's fields, such as its method, path and id,
+// already set:
+//
+//	logs.Ctx(ctx).Warn("payment declined", "order", o.ID)
+//
+// slog's default logger writes to the default channel, so slog.Info and
+// friends keep working. Secrets are scrubbed from every record, see Scrub.
+package logs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/lemmego/api/config"
+)
+
+// Built in channels.
+const (
+	App      = "app"
+	Audit    = "audit"
+	Security = "security"
+)
+
+var (
+	mu       sync.Mutex
+	channels = map[string]*slog.Logger{}
+	closers  []io.Closer
+)
+
+// Channel returns the logger of the named channel, built from its
+// configuration on first use. Unknown channels log to stderr.
+func Channel(name string) *slog.Logger {
+	mu.Lock()
+	defer mu.Unlock()
+	if l, ok := channels[name]; ok {
+		return l
+	}
+	l := slog.New(build(name)).With("channel", name)
+	channels[name] = l
+	return l
+}
+
+// Default returns the logger of the default channel.
+func Default() *slog.Logger {
+	name, _ := config.Get("logging.default", App).(string)
+	return Channel(name)
+}
+
+// Close flushes and closes the sinks of every channel, which are built
+// again on next use.
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	var errs []string
+	for _, c := range closers {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	channels, closers, alerter = map[string]*slog.Logger{}, nil, nil
+	resetScrubbers()
+	if len(errs) > 0 {
+		return fmt.Errorf("logs: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func build(name string) slog.Handler {
+	conf, _ := config.Get("logging.channels."+name, nil).(config.M)
+	var level slog.Level
+	if s, _ := conf["level"].(string); s != "" {
+		if err := level.UnmarshalText([]byte(s)); err != nil {
+			fmt.Fprintf(os.Stderr, "logs: channel %s: %s\n", name, err)
+		}
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	sinks, _ := conf["sinks"].(string)
+	if sinks == "" {
+		sinks = "stderr"
+	}
+	var handlers []slog.Handler
+	for _, sink := range strings.Split(sinks, ",") {
+		h, c, err := newSink(strings.TrimSpace(sink), name, conf, opts)
+		if err != nil {
+			// Logging must not take the application down
+			fmt.Fprintf(os.Stderr, "logs: channel %s: %s\n", name, err)
+			continue
+		}
+		handlers = append(handlers, h)
+		if c != nil {
+			closers = append(closers, c)
+		}
+	}
+
+	var h slog.Handler = fanout(handlers)
+	if rate, ok := conf["sample"].(float64); ok && rate < 1 {
+		h = &sampled{Handler: h, rate: rate}
+	}
+	return &scrubbed{Handler: h}
+}
+
+// fanout sends records to every handler enabled for them.
+type fanout []slog.Handler
+
+func (f fanout) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range f {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f fanout) Handle(ctx context.Context, r slog.Record) error {
+	var first error
+	for _, h := range f {
+		if !h.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, r.Clone()); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (f fanout) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(fanout, len(f))
+	for i, h := range f {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (f fanout) WithGroup(name string) slog.Handler {
+	handlers := make(fanout, len(f))
+	for i, h := range f {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
+
+// sampled keeps a share of the records below warn, all of the others.
+type sampled struct {
+	slog.Handler
+	rate float64
+}
+
+func (s *sampled) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelWarn && rand.Float64() >= s.rate {
+		return nil
+	}
+	return s.Handler.Handle(ctx, r)
+}
+
+func (s *sampled) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &sampled{Handler: s.Handler.WithAttrs(attrs), rate: s.rate}
+}
+
+func (s *sampled) WithGroup(name string) slog.Handler {
+	return &sampled{Handler: s.Handler.WithGroup(name), rate: s.rate}
+}
+
+type loggerKey struct{}
+
+// WithLogger returns a context carrying l, for Ctx.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// Ctx returns the logger of ctx, set by Middleware for requests, or the
+// default one.
+func Ctx(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return Default()
+}
@@ -0,0 +1,314 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/lemmego/api/config"
+)
+
+// Records are scrubbed before any sink sees them: values of attributes
+// named after a secret, such as password or authorization, are replaced by
+// Redacted, and so are card numbers, bearer tokens, JWTs and key=value or
+// "key": "value" pairs of secret names in messages and string values. Maps
+// are scrubbed key by key, so request headers, form values and validation
+// errors can be logged as they are:
+//
+//	logs.Ctx(ctx).Info("invalid input", "errors", v.Errors, "form", r.PostForm)
+//
+// Names are matched case insensitively, by suffix, "-" and "_" alike, so
+// password covers new_password and api_key covers X-Api-Key. More names go in
+// logging.scrub_fields, more patterns in logging.scrub_patterns, and
+// AddScrubber installs code of its own.
+
+// Redacted replaces scrubbed values.
+const Redacted = "[REDACTED]"
+
+// secretNames are always scrubbed.
+var secretNames = []string{
+	"password", "passwd", "secret", "token", "api_key", "apikey", "authorization",
+	"cookie", "session", "csrf", "card_number", "cvv", "cvc", "ssn", "private_key",
+}
+
+var (
+	bearerPattern = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[\w\-.~+/]+=*`)
+	jwtPattern    = regexp.MustCompile(`\beyJ[\w-]+\.[\w-]+\.[\w-]+`)
+	cardPattern   = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+)
+
+// Scrubber rewrites a string logged under key, "" for messages.
+type Scrubber func(key, s string) string
+
+// scrubbers are the rules built from the configuration, along with those
+// added by AddScrubber.
+type scrubbers struct {
+	names []string
+	pairs *regexp.Regexp
+	extra []*regexp.Regexp
+	funcs []Scrubber
+}
+
+var (
+	scrubMu sync.Mutex
+	rules   *scrubbers
+	added   []Scrubber
+)
+
+// AddScrubber installs s after the built in scrubbers.
+func AddScrubber(s Scrubber) {
+	scrubMu.Lock()
+	defer scrubMu.Unlock()
+	added = append(added, s)
+	rules = nil
+}
+
+// current returns the scrubbers, nil when logging.scrub is off.
+func current() *scrubbers {
+	scrubMu.Lock()
+	defer scrubMu.Unlock()
+	if on, _ := config.Get("logging.scrub", true).(bool); !on {
+		return nil
+	}
+	if rules == nil {
+		rules = newScrubbers()
+	}
+	return rules
+}
+
+// resetScrubbers rebuilds the scrubbers from the configuration on next use.
+func resetScrubbers() {
+	scrubMu.Lock()
+	rules = nil
+	scrubMu.Unlock()
+}
+
+func newScrubbers() *scrubbers {
+	s := &scrubbers{names: append([]string(nil), secretNames...), funcs: added}
+	fields, _ := config.Get("logging.scrub_fields", "").(string)
+	for _, f := range strings.Split(fields, ",") {
+		if f = normalize(f); f != "" {
+			s.names = append(s.names, f)
+		}
+	}
+
+	alts := make([]string, len(s.names))
+	for i, n := range s.names {
+		alts[i] = strings.ReplaceAll(regexp.QuoteMeta(n), "_", "[-_]")
+	}
+	// password=x, "password": "x" and "Cookie":["x"], keeping the name
+	s.pairs = regexp.MustCompile(`(?i)([\w-]*(?:` + strings.Join(alts, "|") + `)["']?\s*[:=]\s*\[?\s*["']?)([^"'&\s,;\]}]+)`)
+
+	patterns, _ := config.Get("logging.scrub_patterns", []string(nil)).([]string)
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			// Logging must not take the application down
+			fmt.Fprintf(os.Stderr, "logs: scrub pattern %q: %s\n", p, err)
+			continue
+		}
+		s.extra = append(s.extra, re)
+	}
+	return s
+}
+
+func normalize(name string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "-", "_")
+}
+
+// secret reports whether values logged under key are scrubbed whole.
+func (s *scrubbers) secret(key string) bool {
+	key = normalize(key)
+	for _, n := range s.names {
+		if strings.HasSuffix(key, n) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *scrubbers) scrub(key, str string) string {
+	if key != "" && s.secret(key) {
+		return Redacted
+	}
+	str = s.pairs.ReplaceAllString(str, "${1}"+Redacted)
+	str = bearerPattern.ReplaceAllString(str, "${1} "+Redacted)
+	str = jwtPattern.ReplaceAllString(str, Redacted)
+	str = cardPattern.ReplaceAllStringFunc(str, func(m string) string {
+		if luhn(m) {
+			return Redacted
+		}
+		return m
+	})
+	for _, re := range s.extra {
+		str = re.ReplaceAllString(str, Redacted)
+	}
+	for _, f := range s.funcs {
+		str = f(key, str)
+	}
+	return str
+}
+
+// luhn reports whether the digits of s pass the Luhn check of card numbers.
+func luhn(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// Scrub returns s, logged under key, with its secrets redacted.
+func Scrub(key, s string) string {
+	if sc := current(); sc != nil {
+		return sc.scrub(key, s)
+	}
+	return s
+}
+
+// ScrubHeaders returns a copy of h with the values of secret headers, such
+// as Authorization and Cookie, redacted.
+func ScrubHeaders(h http.Header) http.Header {
+	sc := current()
+	out := make(http.Header, len(h))
+	for k, vs := range h {
+		out[k] = make([]string, len(vs))
+		for i, v := range vs {
+			if sc != nil {
+				v = sc.scrub(k, v)
+			}
+			out[k][i] = v
+		}
+	}
+	return out
+}
+
+// ScrubValue returns v, logged under key, with its secrets redacted: strings
+// and errors are scrubbed, and maps and slices element by element. Other
+// values are returned as they are.
+func ScrubValue(key string, v any) any {
+	sc := current()
+	if sc == nil {
+		return v
+	}
+	return sc.value(key, v)
+}
+
+func (s *scrubbers) value(key string, v any) any {
+	if key != "" && s.secret(key) {
+		return Redacted
+	}
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return s.scrub(key, v)
+	case error:
+		msg := v.Error()
+		if clean := s.scrub(key, msg); clean != msg {
+			return clean
+		}
+		return v
+	case fmt.Stringer:
+		return v
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return v
+		}
+		out := make(map[string]any, rv.Len())
+		for it := rv.MapRange(); it.Next(); {
+			k := it.Key().String()
+			out[k] = s.value(k, it.Value().Interface())
+		}
+		return out
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return v
+		}
+		out := make([]any, rv.Len())
+		for i := range out {
+			out[i] = s.value(key, rv.Index(i).Interface())
+		}
+		return out
+	}
+	return v
+}
+
+func (s *scrubbers) attr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, s.scrub(a.Key, v.String()))
+	case slog.KindGroup:
+		if s.secret(a.Key) {
+			return slog.String(a.Key, Redacted)
+		}
+		attrs := v.Group()
+		out := make([]any, len(attrs))
+		for i, ga := range attrs {
+			out[i] = s.attr(ga)
+		}
+		return slog.Group(a.Key, out...)
+	case slog.KindAny:
+		return slog.Any(a.Key, s.value(a.Key, v.Any()))
+	}
+	if s.secret(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+// scrubbed redacts the message and attributes of records before handing
+// them to the sinks of a channel.
+type scrubbed struct {
+	slog.Handler
+}
+
+func (h *scrubbed) Handle(ctx context.Context, r slog.Record) error {
+	s := current()
+	if s == nil {
+		return h.Handler.Handle(ctx, r)
+	}
+	out := slog.NewRecord(r.Time, r.Level, s.scrub("", r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(s.attr(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, out)
+}
+
+func (h *scrubbed) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if s := current(); s != nil {
+		out := make([]slog.Attr, len(attrs))
+		for i, a := range attrs {
+			out[i] = s.attr(a)
+		}
+		attrs = out
+	}
+	return &scrubbed{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *scrubbed) WithGroup(name string) slog.Handler {
+	return &scrubbed{Handler: h.Handler.WithGroup(name)}
+}
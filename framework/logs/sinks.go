@@ -0,0 +1,355 @@
+package logs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lemmego/api/config"
+)
+
+// newSink builds the handler of a sink of a channel, and what closes it.
+func newSink(sink, channel string, conf config.M, opts *slog.HandlerOptions) (slog.Handler, io.Closer, error) {
+	switch sink {
+	case "stderr":
+		return slog.NewTextHandler(os.Stderr, opts), nil, nil
+	case "file":
+		path, _ := conf["path"].(string)
+		if path == "" {
+			path = filepath.Join("storage", "logs", channel+".log")
+		}
+		f, err := OpenRotating(path, rotateOptions())
+		if err != nil {
+			return nil, nil, err
+		}
+		return slog.NewJSONHandler(f, opts), f, nil
+	case "syslog":
+		w, err := dialSyslog(channel)
+		if err != nil {
+			return nil, nil, err
+		}
+		return slog.NewTextHandler(w, opts), w, nil
+	case "loki":
+		url, _ := config.Get("logging.loki_url", "").(string)
+		if url == "" {
+			return nil, nil, fmt.Errorf("the loki sink needs logging.loki_url")
+		}
+		app, _ := config.Get("app.name", "app").(string)
+		w := NewLoki(url, map[string]string{"app": app, "channel": channel})
+		return slog.NewJSONHandler(w, opts), w, nil
+	case "alerts":
+		h, a := alertsSink()
+		if a == nil {
+			return h, nil, nil
+		}
+		return h, a, nil
+	}
+	return nil, nil, fmt.Errorf("unknown sink %q", sink)
+}
+
+// RotateOptions configure when a log file is rotated and how long rotated
+// files are kept.
+type RotateOptions struct {
+	// MaxSize rotates the file before it grows past this many bytes.
+	MaxSize int64
+	// Every rotates the file when a period of this length starts, e.g. at
+	// midnight, UTC, for 24 hours.
+	Every time.Duration
+	// Compress gzips rotated files.
+	Compress bool
+	// MaxFiles and MaxAge bound the rotated files kept, zero keeping all.
+	MaxFiles int
+	MaxAge   time.Duration
+}
+
+// rotateOptions reads the rotation settings of file sinks.
+func rotateOptions() RotateOptions {
+	maxSize, _ := config.Get("logging.max_size", 0).(int)
+	every, _ := config.Get("logging.rotate_hours", 0).(int)
+	compress, _ := config.Get("logging.compress", false).(bool)
+	maxFiles, _ := config.Get("logging.max_files", 0).(int)
+	maxAge, _ := config.Get("logging.max_age_days", 0).(int)
+	return RotateOptions{
+		MaxSize:  int64(maxSize) << 20,
+		Every:    time.Duration(every) * time.Hour,
+		Compress: compress,
+		MaxFiles: maxFiles,
+		MaxAge:   time.Duration(maxAge) * 24 * time.Hour,
+	}
+}
+
+// Rotating is a log file renamed to path.<time>, e.g. app.log.20261018-150405.000,
+// when rotated.
+type Rotating struct {
+	mu     sync.Mutex
+	path   string
+	opts   RotateOptions
+	f      *os.File
+	size   int64
+	period time.Time
+	// cleanup compresses and prunes rotated files in the background, one
+	// rotation at a time
+	cleanup sync.WaitGroup
+	cleanMu sync.Mutex
+}
+
+// OpenRotating opens the log file at path for appending.
+func OpenRotating(path string, opts RotateOptions) (*Rotating, error) {
+	r := &Rotating{path: path, opts: opts}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Rotating) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f, r.size = f, stat.Size()
+	// A file left by a previous run belongs to the period it was written in
+	r.period = time.Now()
+	if r.size > 0 {
+		r.period = stat.ModTime()
+	}
+	if r.opts.Every > 0 {
+		r.period = r.period.Truncate(r.opts.Every)
+	}
+	return nil
+}
+
+func (r *Rotating) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.size > 0 && r.due(int64(len(p))) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *Rotating) due(n int64) bool {
+	if r.opts.MaxSize > 0 && r.size+n > r.opts.MaxSize {
+		return true
+	}
+	return r.opts.Every > 0 && time.Now().Truncate(r.opts.Every).After(r.period)
+}
+
+func (r *Rotating) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	rotated := r.path + "." + time.Now().Format("20060102-150405.000")
+	for i := 1; exists(rotated) || exists(rotated+".gz"); i++ {
+		rotated = fmt.Sprintf("%s.%s-%d", r.path, time.Now().Format("20060102-150405.000"), i)
+	}
+	if err := os.Rename(r.path, rotated); err != nil {
+		return err
+	}
+	r.cleanup.Add(1)
+	go func() {
+		defer r.cleanup.Done()
+		r.compressAndPrune(rotated)
+	}()
+	return r.open()
+}
+
+// compressAndPrune gzips a rotated file and removes those beyond the
+// retention limits. Failures are reported on stderr, as logging them could
+// loop back here.
+func (r *Rotating) compressAndPrune(rotated string) {
+	r.cleanMu.Lock()
+	defer r.cleanMu.Unlock()
+	// A file pruned before its turn came needs no compressing
+	if r.opts.Compress && exists(rotated) {
+		if err := gzipFile(rotated); err != nil {
+			fmt.Fprintf(os.Stderr, "logs: compress %s: %s\n", rotated, err)
+		}
+	}
+
+	files, err := filepath.Glob(r.path + ".*")
+	if err != nil {
+		return
+	}
+	modTimes := make(map[string]time.Time, len(files))
+	for _, f := range files {
+		if stat, err := os.Stat(f); err == nil {
+			modTimes[f] = stat.ModTime()
+		}
+	}
+	// Oldest first, by their last write
+	sort.SliceStable(files, func(i, j int) bool { return modTimes[files[i]].Before(modTimes[files[j]]) })
+	for i, f := range files {
+		expired := r.opts.MaxFiles > 0 && i < len(files)-r.opts.MaxFiles ||
+			r.opts.MaxAge > 0 && time.Since(modTimes[f]) > r.opts.MaxAge
+		if expired {
+			if err := os.Remove(f); err != nil {
+				fmt.Fprintf(os.Stderr, "logs: remove %s: %s\n", f, err)
+			}
+		}
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(path + ".gz")
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Close closes the file once the rotated files are compressed and pruned.
+func (r *Rotating) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cleanup.Wait()
+	return r.f.Close()
+}
+
+// Loki pushes log lines to a Loki server in batches, every second or every
+// 100 lines. Lines that cannot be pushed are reported on stderr and dropped.
+type Loki struct {
+	url    string
+	labels map[string]string
+	client *http.Client
+
+	mu      sync.Mutex
+	lines   [][2]string
+	kick    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+const lokiBatch = 100
+
+// NewLoki returns a writer pushing every line written to it to the Loki
+// server at url, labelled with labels.
+func NewLoki(url string, labels map[string]string) *Loki {
+	l := &Loki{
+		url:     strings.TrimSuffix(url, "/") + "/loki/api/v1/push",
+		labels:  labels,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		kick:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+func (l *Loki) Write(p []byte) (int, error) {
+	line := strings.TrimSuffix(string(p), "\n")
+	l.mu.Lock()
+	l.lines = append(l.lines, [2]string{strconv.FormatInt(time.Now().UnixNano(), 10), line})
+	full := len(l.lines) >= lokiBatch
+	l.mu.Unlock()
+	if full {
+		select {
+		case l.kick <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+func (l *Loki) run() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.done:
+			l.flush()
+			close(l.stopped)
+			return
+		case <-ticker.C:
+		case <-l.kick:
+		}
+		l.flush()
+	}
+}
+
+func (l *Loki) flush() {
+	l.mu.Lock()
+	lines := l.lines
+	l.lines = nil
+	l.mu.Unlock()
+	if len(lines) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"streams": []map[string]any{{"stream": l.labels, "values": lines}},
+	})
+	if err == nil {
+		err = l.push(body)
+	}
+	if err != nil {
+		// Logging the failure through slog could loop back here
+		fmt.Fprintf(os.Stderr, "logs: push %d lines to loki: %s\n", len(lines), err)
+	}
+}
+
+func (l *Loki) push(body []byte) error {
+	resp, err := l.client.Post(l.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("loki answered %s", resp.Status)
+	}
+	return nil
+}
+
+// Close pushes the pending lines and stops pushing.
+func (l *Loki) Close() error {
+	l.once.Do(func() { close(l.done) })
+	<-l.stopped
+	return nil
+}
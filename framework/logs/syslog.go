@@ -0,0 +1,19 @@
+//go:build !windows && !plan9
+
+package logs
+
+import (
+	"io"
+	"log/syslog"
+
+	"github.com/lemmego/api/config"
+)
+
+// dialSyslog connects the syslog sink of channel to the configured daemon,
+// the local one by default.
+func dialSyslog(channel string) (io.WriteCloser, error) {
+	network, _ := config.Get("logging.syslog_network", "").(string)
+	address, _ := config.Get("logging.syslog_address", "").(string)
+	tag, _ := config.Get("app.name", "app").(string)
+	return syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_USER, tag+"."+channel)
+}
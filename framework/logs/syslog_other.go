@@ -0,0 +1,14 @@
+//go:build windows || plan9
+
+package logs
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// dialSyslog fails, log/syslog is not available on this platform.
+func dialSyslog(channel string) (io.WriteCloser, error) {
+	return nil, fmt.Errorf("the syslog sink is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,404 @@
+// Package mail sends emails through the driver configured by mail.driver,
+// SMTP or the log for development. Bodies are written by hand or rendered
+// from templates, markdown ones getting a responsive layout and a plain
+// text alternative:
+//
+//	m := &mail.Message{To: []string{u.Email}, Subject: "Your invoice"}
+//	if err := m.Template(ctx, "invoice", inv); err != nil {
+//		return err
+//	}
+//	return mail.Send(ctx, m)
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lemmego/api/config"
+)
+
+var ErrUnknownDriver = errors.New("mail: unknown driver")
+
+// Message is an email. Addresses are plain or with a name, as in
+// "Jane Doe <jane@example.com>".
+type Message struct {
+	// From defaults to mail.from.
+	From    string
+	To      []string
+	Cc      []string
+	Bcc     []string
+	ReplyTo string
+	Subject string
+	// HTML and Text are the bodies, sent as alternatives when both are set.
+	HTML string
+	Text string
+	// Headers are added to the generated ones, e.g. List-Unsubscribe.
+	Headers     map[string]string
+	Attachments []Attachment
+}
+
+// Attachment is a file attached to a message.
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Driver delivers messages.
+type Driver interface {
+	Send(ctx context.Context, m *Message) error
+}
+
+var (
+	mu            sync.Mutex
+	defaultDriver Driver
+)
+
+// Default returns the driver configured by mail.driver.
+func Default() (Driver, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if defaultDriver != nil {
+		return defaultDriver, nil
+	}
+
+	switch name := config.Get("mail.driver", "log").(string); name {
+	case "log":
+		defaultDriver = &Log{}
+	case "smtp":
+		defaultDriver = &SMTP{
+			Host:       config.Get("mail.host", "localhost").(string),
+			Port:       config.Get("mail.port", 587).(int),
+			Username:   config.Get("mail.username", "").(string),
+			Password:   config.Get("mail.password", "").(string),
+			Encryption: config.Get("mail.encryption", "starttls").(string),
+		}
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownDriver, name)
+	}
+	return defaultDriver, nil
+}
+
+// SetDefault replaces the configured driver, e.g. with one of a provider API.
+func SetDefault(d Driver) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultDriver = d
+}
+
+// Send delivers m through the default driver.
+func Send(ctx context.Context, m *Message) error {
+	d, err := Default()
+	if err != nil {
+		return err
+	}
+	return d.Send(ctx, m)
+}
+
+func (m *Message) from() string {
+	if m.From != "" {
+		return m.From
+	}
+	addr := &mail.Address{
+		Name:    config.Get("mail.from.name", "").(string),
+		Address: config.Get("mail.from.address", "").(string),
+	}
+	return addr.String()
+}
+
+// Recipients returns the addresses of To, Cc and Bcc.
+func (m *Message) Recipients() ([]string, error) {
+	var rcpt []string
+	for _, list := range [][]string{m.To, m.Cc, m.Bcc} {
+		for _, s := range list {
+			addr, err := mail.ParseAddress(s)
+			if err != nil {
+				return nil, fmt.Errorf("mail: recipient %q: %w", s, err)
+			}
+			rcpt = append(rcpt, addr.Address)
+		}
+	}
+	if len(rcpt) == 0 {
+		return nil, errors.New("mail: no recipients")
+	}
+	return rcpt, nil
+}
+
+// Bytes returns m encoded as a MIME message, without its Bcc header.
+func (m *Message) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(k, v string) {
+		buf.WriteString(k + ": " + v + "\r\n")
+	}
+	from, err := mail.ParseAddress(m.from())
+	if err != nil {
+		return nil, fmt.Errorf("mail: sender %q: %w", m.from(), err)
+	}
+	header("From", from.String())
+	for _, h := range []struct {
+		key  string
+		list []string
+	}{{"To", m.To}, {"Cc", m.Cc}} {
+		if len(h.list) == 0 {
+			continue
+		}
+		addrs, err := mail.ParseAddressList(strings.Join(h.list, ", "))
+		if err != nil {
+			return nil, err
+		}
+		formatted := make([]string, len(addrs))
+		for i, a := range addrs {
+			formatted[i] = a.String()
+		}
+		header(h.key, strings.Join(formatted, ", "))
+	}
+	if m.ReplyTo != "" {
+		header("Reply-To", m.ReplyTo)
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", "<"+randomHex(16)+"@"+domainOf(from.Address)+">")
+	header("MIME-Version", "1.0")
+	for k, v := range m.Headers {
+		header(textproto.CanonicalMIMEHeaderKey(k), mime.QEncoding.Encode("utf-8", v))
+	}
+
+	bodyHeader, body, err := m.body()
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Attachments) == 0 {
+		for k, v := range bodyHeader {
+			header(k, v[0])
+		}
+		buf.WriteString("\r\n")
+		buf.Write(body)
+		return buf.Bytes(), nil
+	}
+
+	mixed := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/mixed; boundary="+mixed.Boundary())
+	buf.WriteString("\r\n")
+	part, err := mixed.CreatePart(bodyHeader)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(body); err != nil {
+		return nil, err
+	}
+	for _, a := range m.Attachments {
+		// Parameters of the type are kept, such as the method of calendars
+		ct, params, err := mime.ParseMediaType(a.ContentType)
+		if err != nil {
+			ct, params = "application/octet-stream", map[string]string{}
+		}
+		params["name"] = a.Name
+		part, err := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(ct, params)},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Name})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBase64(part, a.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err := mixed.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// body returns the headers and the content of the text and HTML bodies.
+func (m *Message) body() (textproto.MIMEHeader, []byte, error) {
+	var buf bytes.Buffer
+	if m.HTML == "" || m.Text == "" {
+		ct, body := "text/plain; charset=utf-8", m.Text
+		if m.HTML != "" {
+			ct, body = "text/html; charset=utf-8", m.HTML
+		}
+		h := textproto.MIMEHeader{"Content-Type": {ct}, "Content-Transfer-Encoding": {"quoted-printable"}}
+		w := quotedprintable.NewWriter(&buf)
+		if _, err := w.Write([]byte(body)); err != nil {
+			return nil, nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, nil, err
+		}
+		return h, buf.Bytes(), nil
+	}
+
+	alt := multipart.NewWriter(&buf)
+	// Clients show the last alternative they support, so HTML goes last
+	for _, p := range []struct{ ct, body string }{{"text/plain; charset=utf-8", m.Text}, {"text/html; charset=utf-8", m.HTML}} {
+		part, err := alt.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.ct},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		w := quotedprintable.NewWriter(part)
+		if _, err := w.Write([]byte(p.body)); err != nil {
+			return nil, nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := alt.Close(); err != nil {
+		return nil, nil, err
+	}
+	return textproto.MIMEHeader{"Content-Type": {"multipart/alternative; boundary=" + alt.Boundary()}}, buf.Bytes(), nil
+}
+
+func writeBase64(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	// Lines are limited to 76 characters
+	for len(encoded) > 76 {
+		if _, err := w.Write([]byte(encoded[:76] + "\r\n")); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err := w.Write([]byte(encoded + "\r\n"))
+	return err
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func domainOf(addr string) string {
+	if i := strings.LastIndexByte(addr, '@'); i >= 0 {
+		return addr[i+1:]
+	}
+	return "localhost"
+}
+
+// Log writes messages to the log instead of sending them, for development.
+type Log struct{}
+
+func (Log) Send(ctx context.Context, m *Message) error {
+	rcpt, err := m.Recipients()
+	if err != nil {
+		return err
+	}
+	body := m.Text
+	if body == "" {
+		body = m.HTML
+	}
+	slog.Info(fmt.Sprintf("mail: to %s: %s\n%s", strings.Join(rcpt, ", "), m.Subject, body))
+	return nil
+}
+
+// SMTP sends messages to a mail server.
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// Encryption is "starttls", "tls" for implicit TLS as on port 465, or
+	// "none".
+	Encryption string
+	// Timeout bounds the whole exchange, 30 seconds when zero.
+	Timeout time.Duration
+}
+
+func (s *SMTP) Send(ctx context.Context, m *Message) error {
+	rcpt, err := m.Recipients()
+	if err != nil {
+		return err
+	}
+	from, err := mail.ParseAddress(m.from())
+	if err != nil {
+		return fmt.Errorf("mail: sender %q: %w", m.from(), err)
+	}
+	data, err := m.Bytes()
+	if err != nil {
+		return err
+	}
+
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	tlsConfig := &tls.Config{ServerName: s.Host}
+	var conn net.Conn
+	if s.Encryption == "tls" {
+		conn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	c, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if s.Encryption == "starttls" {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("mail: %s does not support STARTTLS", addr)
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if s.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, r := range rcpt {
+		if err := c.Rcpt(r); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,404 @@
+package mail
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The markdown of mail templates is a subset of CommonMark: headings,
+// paragraphs, emphasis, code, links, lists, block quotes, rules and pipe
+// tables, plus the components of the template functions, rendered both to
+// HTML styled by a theme and to plain text.
+
+type blockKind int
+
+const (
+	blockParagraph blockKind = iota
+	blockHeading
+	blockCode
+	blockQuote
+	blockRule
+	blockList
+	blockTable
+	blockComponent
+)
+
+type block struct {
+	kind  blockKind
+	level int
+	// ordered lists
+	ordered bool
+	text    string
+	items   []string
+	rows    [][]string
+	// quote holds the blocks of a block quote
+	quote []block
+}
+
+var (
+	headingRe   = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	ruleRe      = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	bulletRe    = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	orderedRe   = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	separatorRe = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	componentRe = regexp.MustCompile("^\x1a(\\d+)\x1a$")
+)
+
+func parseBlocks(src string) []block {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	var blocks []block
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			i++
+		case componentRe.MatchString(trimmed):
+			blocks = append(blocks, block{kind: blockComponent, text: trimmed})
+			i++
+		case strings.HasPrefix(trimmed, "```"):
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			i++
+			blocks = append(blocks, block{kind: blockCode, text: strings.Join(code, "\n")})
+		case headingRe.MatchString(trimmed):
+			m := headingRe.FindStringSubmatch(trimmed)
+			blocks = append(blocks, block{kind: blockHeading, level: len(m[1]), text: m[2]})
+			i++
+		case ruleRe.MatchString(trimmed):
+			blocks = append(blocks, block{kind: blockRule})
+			i++
+		case strings.HasPrefix(trimmed, ">"):
+			var quoted []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quoted = append(quoted, strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">"), " "))
+			}
+			blocks = append(blocks, block{kind: blockQuote, quote: parseBlocks(strings.Join(quoted, "\n"))})
+		case bulletRe.MatchString(line) || orderedRe.MatchString(line):
+			b := block{kind: blockList, ordered: !bulletRe.MatchString(line)}
+			re := bulletRe
+			if b.ordered {
+				re = orderedRe
+			}
+			for ; i < len(lines); i++ {
+				if m := re.FindStringSubmatch(lines[i]); m != nil {
+					b.items = append(b.items, m[1])
+					continue
+				}
+				// Indented lines continue the item above
+				if strings.TrimSpace(lines[i]) != "" && len(b.items) > 0 && strings.HasPrefix(lines[i], " ") {
+					b.items[len(b.items)-1] += " " + strings.TrimSpace(lines[i])
+					continue
+				}
+				break
+			}
+			blocks = append(blocks, b)
+		case strings.Contains(trimmed, "|") && i+1 < len(lines) && separatorRe.MatchString(lines[i+1]):
+			b := block{kind: blockTable, rows: [][]string{splitRow(trimmed)}}
+			for i += 2; i < len(lines) && strings.Contains(lines[i], "|"); i++ {
+				b.rows = append(b.rows, splitRow(strings.TrimSpace(lines[i])))
+			}
+			blocks = append(blocks, b)
+		default:
+			var para []string
+			for ; i < len(lines); i++ {
+				t := strings.TrimSpace(lines[i])
+				if t == "" || (len(para) > 0 && startsBlock(t)) {
+					break
+				}
+				// Trailing spaces are kept for hard line breaks
+				para = append(para, strings.TrimLeft(lines[i], " \t"))
+			}
+			blocks = append(blocks, block{kind: blockParagraph, text: strings.TrimRight(strings.Join(para, "\n"), " \t")})
+		}
+	}
+	return blocks
+}
+
+// startsBlock reports whether line interrupts a paragraph.
+func startsBlock(line string) bool {
+	return strings.HasPrefix(line, "```") || strings.HasPrefix(line, ">") ||
+		headingRe.MatchString(line) || componentRe.MatchString(line) ||
+		bulletRe.MatchString(line) || ruleRe.MatchString(line)
+}
+
+func splitRow(line string) []string {
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "|"), "|")
+	cells := strings.Split(line, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+// span is a piece of inline content.
+type span struct {
+	text string
+	// code, strong, em or link, plain text otherwise
+	kind string
+	href string
+	// children of strong, em and link
+	children []span
+}
+
+func parseInline(s string) []span {
+	var spans []span
+	var plain strings.Builder
+	flush := func() {
+		if plain.Len() > 0 {
+			spans = append(spans, span{text: plain.String()})
+			plain.Reset()
+		}
+	}
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("\\`*_[]()#+-.!|>", s[i+1]) >= 0:
+			plain.WriteByte(s[i+1])
+			i += 2
+			continue
+		case c == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end >= 0 {
+				flush()
+				spans = append(spans, span{kind: "code", text: s[i+1 : i+1+end]})
+				i += end + 2
+				continue
+			}
+		case c == '*' || c == '_':
+			delim := string(c)
+			kind := "em"
+			if strings.HasPrefix(s[i:], delim+delim) {
+				delim += delim
+				kind = "strong"
+			}
+			// An underscore inside a word, as in snake_case, is no emphasis
+			if c == '_' && i > 0 && isWordByte(s[i-1]) {
+				break
+			}
+			rest := s[i+len(delim):]
+			if end := strings.Index(rest, delim); end > 0 && rest[0] != ' ' {
+				flush()
+				spans = append(spans, span{kind: kind, children: parseInline(rest[:end])})
+				i += len(delim)*2 + end
+				continue
+			}
+		case c == '[':
+			if close := strings.Index(s[i:], "]("); close > 0 {
+				if end := strings.IndexByte(s[i+close:], ')'); end > 0 {
+					flush()
+					spans = append(spans, span{
+						kind:     "link",
+						href:     strings.TrimSpace(s[i+close+2 : i+close+end]),
+						children: parseInline(s[i+1 : i+close]),
+					})
+					i += close + end + 1
+					continue
+				}
+			}
+		case c == '\n':
+			// Soft line breaks are spaces, hard ones end with two spaces
+			if t := plain.String(); strings.HasSuffix(t, "  ") {
+				plain.Reset()
+				plain.WriteString(strings.TrimRight(t, " "))
+				flush()
+				spans = append(spans, span{kind: "br"})
+			} else {
+				plain.WriteByte(' ')
+			}
+			i++
+			continue
+		}
+		plain.WriteByte(s[i])
+		i++
+	}
+	flush()
+	return spans
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// htmlRenderer renders markdown as HTML styled by a theme.
+type htmlRenderer struct {
+	theme      *Theme
+	components []component
+}
+
+func (r *htmlRenderer) open(tag string, attrs ...string) string {
+	var b strings.Builder
+	b.WriteString("<" + tag)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		b.WriteString(" " + attrs[i] + `="` + html.EscapeString(attrs[i+1]) + `"`)
+	}
+	if style := r.theme.Styles[tag]; style != "" {
+		b.WriteString(` style="` + html.EscapeString(style) + `"`)
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+func (r *htmlRenderer) blocks(blocks []block) string {
+	var b strings.Builder
+	for _, bl := range blocks {
+		switch bl.kind {
+		case blockParagraph:
+			b.WriteString(r.open("p") + r.inline(parseInline(bl.text)) + "</p>\n")
+		case blockHeading:
+			tag := "h" + strconv.Itoa(bl.level)
+			b.WriteString(r.open(tag) + r.inline(parseInline(bl.text)) + "</" + tag + ">\n")
+		case blockCode:
+			b.WriteString(r.open("pre") + html.EscapeString(bl.text) + "</pre>\n")
+		case blockQuote:
+			b.WriteString(r.open("blockquote") + "\n" + r.blocks(bl.quote) + "</blockquote>\n")
+		case blockRule:
+			b.WriteString(r.open("hr") + "\n")
+		case blockList:
+			tag := "ul"
+			if bl.ordered {
+				tag = "ol"
+			}
+			b.WriteString(r.open(tag) + "\n")
+			for _, item := range bl.items {
+				b.WriteString(r.open("li") + r.inline(parseInline(item)) + "</li>\n")
+			}
+			b.WriteString("</" + tag + ">\n")
+		case blockTable:
+			b.WriteString(r.open("table", "width", "100%", "cellpadding", "0", "cellspacing", "0", "role", "presentation") + "\n")
+			for i, row := range bl.rows {
+				cell := "td"
+				if i == 0 {
+					cell = "th"
+				}
+				b.WriteString("<tr>")
+				for _, c := range row {
+					b.WriteString(r.open(cell) + r.inline(parseInline(c)) + "</" + cell + ">")
+				}
+				b.WriteString("</tr>\n")
+			}
+			b.WriteString("</table>\n")
+		case blockComponent:
+			if c, ok := r.component(bl.text); ok {
+				b.WriteString(c.html(r) + "\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+func (r *htmlRenderer) component(placeholder string) (component, bool) {
+	m := componentRe.FindStringSubmatch(placeholder)
+	if m == nil {
+		return nil, false
+	}
+	i, _ := strconv.Atoi(m[1])
+	if i >= len(r.components) {
+		return nil, false
+	}
+	return r.components[i], true
+}
+
+func (r *htmlRenderer) inline(spans []span) string {
+	var b strings.Builder
+	for _, s := range spans {
+		switch s.kind {
+		case "code":
+			b.WriteString(r.open("code") + html.EscapeString(s.text) + "</code>")
+		case "strong", "em":
+			b.WriteString(r.open(s.kind) + r.inline(s.children) + "</" + s.kind + ">")
+		case "link":
+			b.WriteString(r.open("a", "href", safeURL(s.href)) + r.inline(s.children) + "</a>")
+		case "br":
+			b.WriteString("<br>")
+		default:
+			b.WriteString(html.EscapeString(s.text))
+		}
+	}
+	return b.String()
+}
+
+// safeURL drops links to scripts, which some clients would follow.
+func safeURL(u string) string {
+	lower := strings.ToLower(strings.TrimSpace(u))
+	if strings.HasPrefix(lower, "javascript:") || strings.HasPrefix(lower, "vbscript:") || strings.HasPrefix(lower, "data:") {
+		return "#"
+	}
+	return u
+}
+
+// textRenderer renders markdown as the plain text alternative.
+type textRenderer struct {
+	components []component
+}
+
+func (r *textRenderer) blocks(blocks []block) string {
+	var parts []string
+	for _, bl := range blocks {
+		switch bl.kind {
+		case blockParagraph:
+			parts = append(parts, r.inline(parseInline(bl.text)))
+		case blockHeading:
+			parts = append(parts, r.inline(parseInline(bl.text)))
+		case blockCode:
+			parts = append(parts, bl.text)
+		case blockQuote:
+			lines := strings.Split(r.blocks(bl.quote), "\n")
+			for i := range lines {
+				lines[i] = strings.TrimRight("> "+lines[i], " ")
+			}
+			parts = append(parts, strings.Join(lines, "\n"))
+		case blockRule:
+			parts = append(parts, "----")
+		case blockList:
+			items := make([]string, len(bl.items))
+			for i, item := range bl.items {
+				marker := "-"
+				if bl.ordered {
+					marker = strconv.Itoa(i+1) + "."
+				}
+				items[i] = marker + " " + r.inline(parseInline(item))
+			}
+			parts = append(parts, strings.Join(items, "\n"))
+		case blockTable:
+			rows := make([]string, len(bl.rows))
+			for i, row := range bl.rows {
+				cells := make([]string, len(row))
+				for j, c := range row {
+					cells[j] = r.inline(parseInline(c))
+				}
+				rows[i] = strings.Join(cells, " | ")
+			}
+			parts = append(parts, strings.Join(rows, "\n"))
+		case blockComponent:
+			m := componentRe.FindStringSubmatch(bl.text)
+			if i, _ := strconv.Atoi(m[1]); i < len(r.components) {
+				parts = append(parts, r.components[i].text(r))
+			}
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func (r *textRenderer) inline(spans []span) string {
+	var b strings.Builder
+	for _, s := range spans {
+		switch s.kind {
+		case "strong", "em":
+			b.WriteString(r.inline(s.children))
+		case "link":
+			text := r.inline(s.children)
+			b.WriteString(text)
+			if text != s.href {
+				b.WriteString(" (" + s.href + ")")
+			}
+		case "br":
+			b.WriteString("\n")
+		default:
+			b.WriteString(s.text)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,212 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os/exec"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/lemmego/api/config"
+
+	"github.com/lemmego/lemmego/framework/assets"
+)
+
+// Templates are looked up by name in the mail.templates directory:
+//
+//	<name>.md    markdown, in the layout of the theme
+//	<name>.mjml  MJML, compiled by the mjml binary
+//	<name>.txt   the plain text alternative, generated when missing
+//
+// Markdown templates are text/templates whose components are placed on a
+// line of their own:
+//
+//	# Hello {{.Name}}
+//
+//	Your invoice of **{{.Amount}}** is ready.
+//
+//	{{button "View invoice" .URL}}
+//
+//	{{subcopy (printf "If the button does not work, open %s" .URL)}}
+//
+// A layout.html in the directory replaces the layout of the theme.
+
+// Templates is where templates are read from, mail.templates when nil.
+var Templates fs.FS
+
+func templates() fs.FS {
+	if Templates != nil {
+		return Templates
+	}
+	return assets.Dir(config.Get("mail.templates", "resources/views/mail").(string))
+}
+
+// Template renders the named template with data into the HTML and text
+// bodies of m, its subject being the title of the page.
+func (m *Message) Template(ctx context.Context, name string, data any) error {
+	fsys := templates()
+	var err error
+	switch src, readErr := fs.ReadFile(fsys, name+".md"); {
+	case readErr == nil:
+		m.HTML, m.Text, err = renderMarkdown(fsys, name, string(src), m.Subject, data)
+	case errors.Is(readErr, fs.ErrNotExist):
+		src, readErr := fs.ReadFile(fsys, name+".mjml")
+		if readErr != nil {
+			return fmt.Errorf("mail: template %s: %w", name, readErr)
+		}
+		if m.HTML, err = renderMJML(ctx, name, string(src), data); err == nil {
+			m.Text, err = HTMLToText(m.HTML)
+		}
+	default:
+		return readErr
+	}
+	if err != nil {
+		return err
+	}
+
+	src, err := fs.ReadFile(fsys, name+".txt")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	t, err := template.New(name + ".txt").Parse(string(src))
+	if err != nil {
+		return err
+	}
+	var text strings.Builder
+	if err := t.Execute(&text, data); err != nil {
+		return err
+	}
+	m.Text = text.String()
+	return nil
+}
+
+// component is a block of a markdown mail placed by a template function.
+type component interface {
+	html(r *htmlRenderer) string
+	text(r *textRenderer) string
+}
+
+type button struct {
+	label, url, color string
+}
+
+func (b *button) html(r *htmlRenderer) string {
+	style := r.theme.Styles["button"] + r.theme.Styles["button-"+b.color]
+	return `<table width="100%" cellpadding="0" cellspacing="0" role="presentation" style="margin:30px auto;text-align:center;"><tr><td align="center">` +
+		`<a href="` + htmltemplate.HTMLEscapeString(safeURL(b.url)) + `" target="_blank" rel="noopener" style="` + htmltemplate.HTMLEscapeString(style) + `">` +
+		htmltemplate.HTMLEscapeString(b.label) + `</a></td></tr></table>`
+}
+
+func (b *button) text(r *textRenderer) string {
+	return b.label + ": " + b.url
+}
+
+// panel and subcopy hold markdown.
+type panel struct {
+	class, content string
+}
+
+func (p *panel) html(r *htmlRenderer) string {
+	return `<div style="` + htmltemplate.HTMLEscapeString(r.theme.Styles[p.class]) + `">` + r.blocks(parseBlocks(p.content)) + `</div>`
+}
+
+func (p *panel) text(r *textRenderer) string {
+	return r.blocks(parseBlocks(p.content))
+}
+
+func renderMarkdown(fsys fs.FS, name, src, subject string, data any) (string, string, error) {
+	var components []component
+	place := func(c component) string {
+		components = append(components, c)
+		return "\n\x1a" + strconv.Itoa(len(components)-1) + "\x1a\n"
+	}
+	t, err := template.New(name + ".md").Funcs(template.FuncMap{
+		"button": func(label, url string, color ...string) string {
+			c := "primary"
+			if len(color) > 0 {
+				c = color[0]
+			}
+			return place(&button{label: label, url: url, color: c})
+		},
+		"panel": func(content string) string {
+			return place(&panel{class: "panel", content: content})
+		},
+		"subcopy": func(content string) string {
+			return place(&panel{class: "subcopy", content: content})
+		},
+	}).Parse(src)
+	if err != nil {
+		return "", "", err
+	}
+	var md strings.Builder
+	if err := t.Execute(&md, data); err != nil {
+		return "", "", err
+	}
+
+	th, err := theme(config.Get("mail.theme", "default").(string))
+	if err != nil {
+		return "", "", err
+	}
+	layoutSrc := th.Layout
+	if custom, err := fs.ReadFile(fsys, "layout.html"); err == nil {
+		layoutSrc = string(custom)
+	}
+	layout, err := htmltemplate.New("layout.html").Parse(layoutSrc)
+	if err != nil {
+		return "", "", err
+	}
+
+	blocks := parseBlocks(md.String())
+	styles := make(map[string]htmltemplate.CSS, len(th.Styles))
+	for k, v := range th.Styles {
+		styles[k] = htmltemplate.CSS(v)
+	}
+	var html bytes.Buffer
+	err = layout.Execute(&html, map[string]any{
+		"Subject": subject,
+		"Content": htmltemplate.HTML((&htmlRenderer{theme: th, components: components}).blocks(blocks)),
+		"AppName": config.Get("app.name", "").(string),
+		"AppURL":  config.Get("app.url", "").(string),
+		"Year":    time.Now().Year(),
+		"Styles":  styles,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return html.String(), (&textRenderer{components: components}).blocks(blocks) + "\n", nil
+}
+
+// renderMJML executes an MJML template, escaping data as HTML, and compiles
+// it with the mjml binary, e.g. installed with npm install -g mjml.
+func renderMJML(ctx context.Context, name, src string, data any) (string, error) {
+	t, err := htmltemplate.New(name + ".mjml").Parse(src)
+	if err != nil {
+		return "", err
+	}
+	var mjml bytes.Buffer
+	if err := t.Execute(&mjml, data); err != nil {
+		return "", err
+	}
+
+	binary := config.Get("mail.mjml", "mjml").(string)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	var out, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, binary, "-i", "-s", "--config.minify", "true")
+	cmd.Stdin = &mjml
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("mail: compiling %s.mjml: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return out.String(), nil
+}
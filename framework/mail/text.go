@@ -0,0 +1,83 @@
+package mail
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// HTMLToText turns an HTML mail into its plain text alternative: links are
+// followed by their URL, list items are dashed and blocks are separated by
+// blank lines.
+func HTMLToText(s string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			// Whitespace of the markup collapses as browsers do
+			text := strings.Join(strings.Fields(n.Data), " ")
+			if text == "" {
+				return
+			}
+			if strings.HasPrefix(n.Data, " ") || strings.HasPrefix(n.Data, "\n") {
+				if cur := b.String(); cur != "" && !strings.HasSuffix(cur, " ") && !strings.HasSuffix(cur, "\n") {
+					b.WriteByte(' ')
+				}
+			}
+			b.WriteString(text)
+			if last := n.Data[len(n.Data)-1]; last == ' ' || last == '\n' {
+				b.WriteByte(' ')
+			}
+			return
+		case html.ElementNode:
+			switch n.DataAtom {
+			case atom.Head, atom.Style, atom.Script, atom.Title:
+				return
+			case atom.Br:
+				b.WriteString("\n")
+				return
+			case atom.Hr:
+				b.WriteString("\n\n----\n\n")
+				return
+			case atom.Li:
+				b.WriteString("\n- ")
+			case atom.P, atom.Div, atom.Table, atom.Tr, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Ul, atom.Ol, atom.Blockquote, atom.Pre:
+				b.WriteString("\n\n")
+			}
+		}
+		start := b.Len()
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.A:
+				for _, a := range n.Attr {
+					if a.Key == "href" && a.Val != "" && !strings.HasPrefix(a.Val, "#") && !strings.Contains(b.String()[start:], a.Val) {
+						b.WriteString(" (" + a.Val + ")")
+					}
+				}
+			case atom.Td, atom.Th:
+				b.WriteString(" ")
+			case atom.P, atom.Div, atom.Table, atom.Tr, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Ul, atom.Ol, atom.Blockquote, atom.Pre:
+				b.WriteString("\n\n")
+			}
+		}
+	}
+	walk(doc)
+
+	lines := strings.Split(b.String(), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")) + "\n", nil
+}
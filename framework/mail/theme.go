@@ -0,0 +1,91 @@
+package mail
+
+import (
+	_ "embed"
+	"fmt"
+	"maps"
+	"sync"
+)
+
+// Theme is the look of markdown mails: the layout around their content,
+// and the inline styles of its elements, as many clients ignore style
+// sheets.
+type Theme struct {
+	// Layout is an html/template given the Subject, the rendered Content,
+	// the AppName, AppURL and Year, and the Styles.
+	Layout string
+	// Styles are inline CSS by tag name, such as "h1" or "a", and for the
+	// components: "button" and its colors "button-primary", "button-success"
+	// and "button-error", "panel" and "subcopy". The layout uses "body",
+	// "wrapper", "header", "content", "footer" and "logo".
+	Styles map[string]string
+}
+
+//go:embed themes/default.html
+var defaultLayout string
+
+var defaultStyles = map[string]string{
+	"body":           "margin:0;padding:0;width:100%;background-color:#edf2f7;color:#718096;font-family:-apple-system,BlinkMacSystemFont,'Segoe UI',Roboto,Helvetica,Arial,sans-serif;",
+	"wrapper":        "width:100%;background-color:#edf2f7;",
+	"header":         "padding:25px 0;text-align:center;",
+	"logo":           "color:#3d4852;font-size:19px;font-weight:bold;text-decoration:none;",
+	"content":        "width:570px;max-width:100%;margin:0 auto;padding:32px;background-color:#ffffff;border:1px solid #e8e5ef;border-radius:2px;",
+	"footer":         "width:570px;max-width:100%;margin:0 auto;padding:32px;text-align:center;color:#b0adc5;font-size:12px;",
+	"h1":             "margin-top:0;color:#3d4852;font-size:18px;font-weight:bold;",
+	"h2":             "margin-top:0;color:#3d4852;font-size:16px;font-weight:bold;",
+	"h3":             "margin-top:0;color:#3d4852;font-size:14px;font-weight:bold;",
+	"p":              "margin-top:0;font-size:16px;line-height:1.5em;",
+	"a":              "color:#3869d4;",
+	"ul":             "font-size:16px;line-height:1.5em;",
+	"ol":             "font-size:16px;line-height:1.5em;",
+	"blockquote":     "margin:0 0 16px;padding-left:16px;border-left:3px solid #e8e5ef;",
+	"code":           "font-family:Menlo,Consolas,monospace;font-size:14px;color:#3d4852;",
+	"pre":            "margin:0 0 16px;padding:12px;background-color:#edf2f7;font-family:Menlo,Consolas,monospace;font-size:13px;white-space:pre-wrap;",
+	"hr":             "border:0;border-top:1px solid #e8e5ef;margin:21px 0;",
+	"table":          "margin:30px auto;font-size:16px;",
+	"th":             "padding-bottom:8px;border-bottom:1px solid #edeff2;text-align:left;color:#3d4852;",
+	"td":             "padding:10px 0;color:#74787e;",
+	"button":         "display:inline-block;padding:8px 18px;border-radius:4px;color:#ffffff;font-size:16px;text-decoration:none;",
+	"button-primary": "background-color:#2d3748;border:1px solid #2d3748;",
+	"button-success": "background-color:#48bb78;border:1px solid #48bb78;",
+	"button-error":   "background-color:#e53e3e;border:1px solid #e53e3e;",
+	"panel":          "margin:21px 0;padding:16px;border-left:4px solid #2d3748;background-color:#edf2f7;color:#718096;",
+	"subcopy":        "margin-top:25px;padding-top:25px;border-top:1px solid #e8e5ef;font-size:14px;",
+}
+
+var (
+	themesMu sync.RWMutex
+	themes   = map[string]*Theme{}
+)
+
+// DefaultTheme returns a copy of the built in theme, to start a theme of
+// the application from:
+//
+//	t := mail.DefaultTheme()
+//	t.Styles["button-primary"] = "background-color:#e11d48;border:1px solid #e11d48;"
+//	mail.RegisterTheme("acme", t)
+func DefaultTheme() *Theme {
+	return &Theme{Layout: defaultLayout, Styles: maps.Clone(defaultStyles)}
+}
+
+// RegisterTheme makes t available under name, to be picked by mail.theme.
+func RegisterTheme(name string, t *Theme) {
+	themesMu.Lock()
+	defer themesMu.Unlock()
+	themes[name] = t
+}
+
+// theme returns the named theme, "default" being the built in one unless
+// registered.
+func theme(name string) (*Theme, error) {
+	themesMu.RLock()
+	t, ok := themes[name]
+	themesMu.RUnlock()
+	if ok {
+		return t, nil
+	}
+	if name == "" || name == "default" {
+		return DefaultTheme(), nil
+	}
+	return nil, fmt.Errorf("mail: unknown theme %q", name)
+}
@@ -0,0 +1,263 @@
+// Package metrics keeps counters and gauges and serves them, together with
+// values collected on demand, in the Prometheus text format.
+//
+//	requests := metrics.Counter("http_requests_total", "Handled requests.")
+//	requests.With(metrics.Labels{"route": "/api/ping"}).Inc()
+//
+//	metrics.Collect("queue_depth", "Jobs waiting.", metrics.GaugeType, func() []metrics.Sample {
+//		return []metrics.Sample{{Value: float64(queue.Len())}}
+//	})
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	CounterType = "counter"
+	GaugeType   = "gauge"
+)
+
+// Labels distinguish the series of a metric.
+type Labels map[string]string
+
+func (l Labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(l))
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(escape(l[name]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// Sample is one value reported by a collector.
+type Sample struct {
+	Labels Labels
+	Value  float64
+}
+
+// Series is a single labelled value of a metric.
+type Series struct {
+	bits atomic.Uint64
+}
+
+func (s *Series) Value() float64 {
+	return math.Float64frombits(s.bits.Load())
+}
+
+func (s *Series) Set(v float64) {
+	s.bits.Store(math.Float64bits(v))
+}
+
+func (s *Series) Add(v float64) {
+	for {
+		old := s.bits.Load()
+		if s.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+func (s *Series) Inc() {
+	s.Add(1)
+}
+
+func (s *Series) Dec() {
+	s.Add(-1)
+}
+
+// Family is a metric with all its series.
+type Family struct {
+	name    string
+	help    string
+	typ     string
+	mu      sync.RWMutex
+	series  map[string]*Series
+	labels  map[string]Labels
+	collect func() []Sample
+}
+
+// With returns the series for labels, creating it on first use.
+func (f *Family) With(labels Labels) *Series {
+	key := labels.String()
+
+	f.mu.RLock()
+	s, ok := f.series[key]
+	f.mu.RUnlock()
+	if ok {
+		return s
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, ok = f.series[key]; !ok {
+		s = &Series{}
+		f.series[key] = s
+		f.labels[key] = labels
+	}
+	return s
+}
+
+func (f *Family) Inc()          { f.With(nil).Inc() }
+func (f *Family) Add(v float64) { f.With(nil).Add(v) }
+func (f *Family) Set(v float64) { f.With(nil).Set(v) }
+
+func (f *Family) samples() []Sample {
+	if f.collect != nil {
+		return f.collect()
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	samples := make([]Sample, 0, len(f.series))
+	for key, s := range f.series {
+		samples = append(samples, Sample{Labels: f.labels[key], Value: s.Value()})
+	}
+	return samples
+}
+
+// Registry holds metric families by name.
+type Registry struct {
+	mu       sync.RWMutex
+	families map[string]*Family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*Family)}
+}
+
+var defaultRegistry = NewRegistry()
+
+// Default returns the registry served by Handler.
+func Default() *Registry {
+	return defaultRegistry
+}
+
+func (r *Registry) family(name, help, typ string, collect func() []Sample) *Family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if f, ok := r.families[name]; ok {
+		if collect != nil {
+			f.collect = collect
+		}
+		return f
+	}
+	f := &Family{
+		name:    name,
+		help:    help,
+		typ:     typ,
+		series:  make(map[string]*Series),
+		labels:  make(map[string]Labels),
+		collect: collect,
+	}
+	r.families[name] = f
+	return f
+}
+
+// Counter returns the counter named name, registering it on first use.
+func (r *Registry) Counter(name, help string) *Family {
+	return r.family(name, help, CounterType, nil)
+}
+
+// Gauge returns the gauge named name, registering it on first use.
+func (r *Registry) Gauge(name, help string) *Family {
+	return r.family(name, help, GaugeType, nil)
+}
+
+// Collect registers a metric whose samples are produced by fn on every scrape.
+// Registering the same name again replaces fn.
+func (r *Registry) Collect(name, help, typ string, fn func() []Sample) {
+	r.family(name, help, typ, fn)
+}
+
+// Unregister removes a metric.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.families, name)
+}
+
+// Write writes every metric in the Prometheus text exposition format.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.RLock()
+	families := make([]*Family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.RUnlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	for _, f := range families {
+		samples := f.samples()
+		if len(samples) == 0 {
+			continue
+		}
+		sort.Slice(samples, func(i, j int) bool {
+			return samples[i].Labels.String() < samples[j].Labels.String()
+		})
+
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.typ); err != nil {
+			return err
+		}
+		for _, s := range samples {
+			value := strconv.FormatFloat(s.Value, 'g', -1, 64)
+			if _, err := fmt.Fprintf(w, "%s%s %s\n", f.name, s.Labels, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Handler serves the registry.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.Write(w)
+	})
+}
+
+func Counter(name, help string) *Family {
+	return defaultRegistry.Counter(name, help)
+}
+
+func Gauge(name, help string) *Family {
+	return defaultRegistry.Gauge(name, help)
+}
+
+func Collect(name, help, typ string, fn func() []Sample) {
+	defaultRegistry.Collect(name, help, typ, fn)
+}
+
+func Handler() http.Handler {
+	return defaultRegistry.Handler()
+}
+
+func escape(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
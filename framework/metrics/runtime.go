@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"context"
+	"runtime"
+	"time"
+)
+
+// RecordRuntime samples the Go runtime every interval, until ctx is done,
+// into gauges and counters of the default registry: goroutines, heap usage
+// and garbage collection pauses.
+func RecordRuntime(ctx context.Context, every time.Duration) {
+	goroutines := Gauge("go_goroutines", "Goroutines that currently exist.")
+	heapAlloc := Gauge("go_memstats_heap_alloc_bytes", "Bytes of allocated heap objects.")
+	heapInuse := Gauge("go_memstats_heap_inuse_bytes", "Bytes in in-use heap spans.")
+	heapObjects := Gauge("go_memstats_heap_objects", "Allocated heap objects.")
+	sys := Gauge("go_memstats_sys_bytes", "Bytes of memory obtained from the OS.")
+	gcCycles := Counter("go_gc_cycles_total", "Completed garbage collection cycles.")
+	gcPause := Counter("go_gc_pause_seconds_total", "Time the world was stopped by garbage collections.")
+	lastPause := Gauge("go_gc_last_pause_seconds", "Duration of the last garbage collection pause.")
+
+	sample := func() {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		goroutines.Set(float64(runtime.NumGoroutine()))
+		heapAlloc.Set(float64(m.HeapAlloc))
+		heapInuse.Set(float64(m.HeapInuse))
+		heapObjects.Set(float64(m.HeapObjects))
+		sys.Set(float64(m.Sys))
+		gcCycles.Set(float64(m.NumGC))
+		gcPause.Set(time.Duration(m.PauseTotalNs).Seconds())
+		if m.NumGC > 0 {
+			lastPause.Set(time.Duration(m.PauseNs[(m.NumGC+255)%256]).Seconds())
+		}
+	}
+
+	sample()
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sample()
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/lemmego/api/app"
+)
+
+// UploadQuota lets an application enforce storage quotas, e.g. per tenant.
+// Remaining returns how many bytes the caller of the request may still upload,
+// and a negative value means unlimited. Consume is called after a successful
+// upload with the number of bytes that were read.
+type UploadQuota interface {
+	Remaining(r *http.Request) (int64, error)
+	Consume(r *http.Request, bytes int64) error
+}
+
+type BodyLimitOptions struct {
+	// MaxBytes limits non-multipart request bodies. Zero disables the limit.
+	MaxBytes int64
+	// MaxMultipartBytes limits multipart/form-data bodies. Zero disables the limit.
+	MaxMultipartBytes int64
+	// Quota is consulted for multipart uploads when set.
+	Quota UploadQuota
+}
+
+// BodyLimit rejects request bodies larger than the configured limits with a 413
+// response. Requests that declare their length are rejected before the handler
+// runs; streamed bodies are cut off once they pass the limit.
+func BodyLimit(opts ...*BodyLimitOptions) app.HTTPMiddleware {
+	o := &BodyLimitOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		o = opts[0]
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			multipart := isMultipart(r)
+			limit := o.MaxBytes
+			if multipart {
+				limit = o.MaxMultipartBytes
+			}
+			// A quota limits the body even with nothing left, a zero limit
+			limited := limit > 0
+
+			if multipart && o.Quota != nil {
+				remaining, err := o.Quota.Remaining(r)
+				if err != nil {
+					abort(w, http.StatusInternalServerError, err.Error())
+					return
+				}
+				if remaining >= 0 && (limit <= 0 || remaining < limit) {
+					if r.ContentLength > remaining {
+						abort(w, http.StatusRequestEntityTooLarge, "upload quota exceeded")
+						return
+					}
+					limit = remaining
+					limited = true
+				}
+			}
+
+			if limited {
+				if r.ContentLength > limit {
+					abort(w, http.StatusRequestEntityTooLarge, tooLargeMessage(limit))
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+
+			if !multipart || o.Quota == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			counter := &countingReader{ReadCloser: r.Body}
+			r.Body = counter
+			rec := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+
+			if rec.Status() < http.StatusBadRequest {
+				_ = o.Quota.Consume(r, counter.n)
+			}
+		})
+	}
+}
+
+// LimitBody is a route level limit, e.g. r.Post("/avatar", h).UseBefore(LimitBody(2 << 20)).
+// It can only tighten the global BodyLimit since that wraps the body first.
+func LimitBody(maxBytes int64) app.Handler {
+	return func(c *app.Context) error {
+		r := c.Request()
+		if r.Body == nil || r.Body == http.NoBody {
+			return c.Next()
+		}
+		if r.ContentLength > maxBytes {
+			return c.Status(http.StatusRequestEntityTooLarge).Error(http.StatusRequestEntityTooLarge, errors.New(tooLargeMessage(maxBytes)))
+		}
+		r.Body = http.MaxBytesReader(c.ResponseWriter(), r.Body, maxBytes)
+		c.SetRequest(r)
+		return c.Next()
+	}
+}
+
+// IsBodyTooLarge reports whether err was caused by reading past a body limit,
+// so handlers can turn it into a 413 instead of a generic error.
+func IsBodyTooLarge(err error) bool {
+	var mbe *http.MaxBytesError
+	return errors.As(err, &mbe)
+}
+
+func isMultipart(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data")
+}
+
+func tooLargeMessage(limit int64) string {
+	return fmt.Sprintf("request body must not exceed %d bytes", limit)
+}
+
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// statusRecorder captures the status code without buffering the body.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	if r.status == 0 {
+		r.status = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
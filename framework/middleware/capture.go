@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lemmego/api/app"
+
+	"github.com/lemmego/lemmego/framework/capture"
+)
+
+type CaptureOptions struct {
+	// Buffer keeps the exchanges, capture.Default when nil.
+	Buffer *capture.Buffer
+	// MaxBody is how much of each body is kept. Zero keeps none.
+	MaxBody int
+	// Except lists path prefixes not captured, such as static files.
+	Except []string
+}
+
+// Capture records requests with their responses, bodies cut to MaxBody, for
+// inspecting and replaying them, see package capture. The request body is
+// recorded as the handler reads it, so what it does not read is not kept.
+func Capture(opts ...*CaptureOptions) app.HTTPMiddleware {
+	o := &CaptureOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		o = opts[0]
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, prefix := range o.Except {
+				if strings.HasPrefix(r.URL.Path, prefix) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			scheme := "http"
+			if r.TLS != nil {
+				scheme = "https"
+			}
+			e := &capture.Exchange{
+				Time: time.Now(),
+				Request: capture.Request{
+					Method: r.Method,
+					URL:    scheme + "://" + r.Host + r.URL.RequestURI(),
+					Proto:  r.Proto,
+					Header: r.Header.Clone(),
+				},
+			}
+
+			reqBody := &capturedBody{max: o.MaxBody, omit: capture.Binary(r.Header.Get("Content-Type"))}
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = &teeBody{ReadCloser: r.Body, body: reqBody}
+			}
+			cw := &captureWriter{ResponseWriter: w, status: http.StatusOK, body: &capturedBody{max: o.MaxBody}}
+			next.ServeHTTP(cw, r)
+
+			e.Duration = time.Since(e.Time)
+			e.Request.Body = reqBody.result()
+			e.Response = capture.Response{Status: cw.status, Header: w.Header().Clone(), Body: cw.body.result()}
+			buf := o.Buffer
+			if buf == nil {
+				buf = capture.Default()
+			}
+			buf.Add(e)
+		})
+	}
+}
+
+// capturedBody keeps the first max bytes written to it, and counts all.
+type capturedBody struct {
+	max  int
+	omit bool
+	data []byte
+	size int64
+}
+
+func (b *capturedBody) write(p []byte) {
+	b.size += int64(len(p))
+	if room := b.max - len(b.data); !b.omit && room > 0 {
+		b.data = append(b.data, p[:min(room, len(p))]...)
+	}
+}
+
+func (b *capturedBody) result() capture.Body {
+	return capture.Body{
+		Data:      b.data,
+		Size:      b.size,
+		Truncated: !b.omit && b.size > int64(len(b.data)),
+		Omitted:   b.omit && b.size > 0,
+	}
+}
+
+type teeBody struct {
+	io.ReadCloser
+	body *capturedBody
+}
+
+func (t *teeBody) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	t.body.write(p[:n])
+	return n, err
+}
+
+type captureWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        *capturedBody
+}
+
+func (w *captureWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = status, true
+		w.body.omit = capture.Binary(w.Header().Get("Content-Type"))
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *captureWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.body.write(p[:n])
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach flushing and hijacking.
+func (w *captureWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
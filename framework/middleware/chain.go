@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lemmego/api/app"
+)
+
+// maxCompiled bounds the stacks a Chain keeps. A router passes the same few
+// handlers over and over; more of them means one is built per request.
+const maxCompiled = 64
+
+// Chain composes middlewares into one, the first being the outermost.
+//
+// The router wraps its mux in every registered HTTP middleware on each
+// request, so each of them allocates a fresh handler closure per request.
+// Registering a single Chain instead compiles the stack once for a given
+// next handler and reuses it afterwards. Middlewares registered after the
+// Chain must therefore return the same comparable handler for the same next,
+// as Chain itself does; otherwise a warning is logged and the stack is
+// rebuilt on every request.
+func Chain(middlewares ...app.HTTPMiddleware) app.HTTPMiddleware {
+	var (
+		compiled sync.Map // next handler -> *chained
+		count    atomic.Int64
+		warned   sync.Once
+	)
+
+	build := func(next http.Handler) http.Handler {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](next)
+		}
+		return next
+	}
+	uncached := func(next http.Handler, reason string) http.Handler {
+		warned.Do(func() {
+			slog.Warn(fmt.Sprintf("middleware: Chain rebuilds its stack on every request, %s", reason))
+		})
+		return build(next)
+	}
+
+	return func(next http.Handler) http.Handler {
+		if !reflect.ValueOf(next).Comparable() {
+			return uncached(next, fmt.Sprintf("the next handler, a %T, is not comparable", next))
+		}
+		if h, ok := compiled.Load(next); ok {
+			return h.(http.Handler)
+		}
+		if count.Load() >= maxCompiled {
+			return uncached(next, fmt.Sprintf("a new next handler, a %T, is passed to it each time", next))
+		}
+		h, loaded := compiled.LoadOrStore(next, &chained{build(next)})
+		if !loaded {
+			count.Add(1)
+		}
+		return h.(http.Handler)
+	}
+}
+
+// chained is a compiled stack, a pointer so that a Chain registered before
+// this one can cache its own stack over it.
+type chained struct {
+	http.Handler
+}
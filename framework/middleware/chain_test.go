@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lemmego/api/app"
+)
+
+// BenchmarkChain serves requests the way the router does, wrapping its mux
+// in the registered middlewares on each of them, and checks that the Chain
+// builds its stack once.
+func BenchmarkChain(b *testing.B) {
+	b.Run("mux", func(b *testing.B) {
+		benchmarkChain(b, nil)
+	})
+	// A middleware registered after the Chain, itself a Chain
+	b.Run("chained", func(b *testing.B) {
+		benchmarkChain(b, Chain(Scratch))
+	})
+}
+
+func benchmarkChain(b *testing.B, after app.HTTPMiddleware) {
+	builds := 0
+	counting := func(next http.Handler) http.Handler {
+		builds++
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+		})
+	}
+	registered := []app.HTTPMiddleware{Chain(counting, Scratch)}
+	if after != nil {
+		registered = append(registered, after)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var handler http.Handler = mux
+		for j := len(registered) - 1; j >= 0; j-- {
+			handler = registered[j](handler)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	if builds != 1 {
+		b.Fatalf("stack built %d times, want 1", builds)
+	}
+}
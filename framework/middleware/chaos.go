@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lemmego/api/app"
+)
+
+// Faults ChaosOptions may inject.
+const (
+	FaultLatency = "latency"
+	FaultError   = "error"
+	FaultDrop    = "drop"
+)
+
+// ChaosFaultHeader names the fault injected into a response, so clients
+// under test can tell them from real failures.
+const ChaosFaultHeader = "X-Chaos-Fault"
+
+type ChaosOptions struct {
+	// Rate is the share of requests a fault is injected into, from 0 to 1.
+	Rate float64
+	// Faults are picked from at random: FaultLatency, FaultError and
+	// FaultDrop. Empty means all of them.
+	Faults []string
+	// Latency is the longest delay added, each delayed request waiting a
+	// random duration up to it.
+	Latency time.Duration
+	// Status answers requests failed with FaultError, 503 by default.
+	Status int
+	// Paths restricts faults to these path prefixes when not empty.
+	Paths []string
+}
+
+// Chaos injects faults into a share of the requests: added latency, error
+// responses, or connections dropped without a response. It is meant for
+// staging, to check how clients retry and time out before production
+// finds out.
+func Chaos(opts ...*ChaosOptions) app.HTTPMiddleware {
+	o := &ChaosOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		o = opts[0]
+	}
+	faults := o.Faults
+	if len(faults) == 0 {
+		faults = []string{FaultLatency, FaultError, FaultDrop}
+	}
+	status := o.Status
+	if status == 0 {
+		status = http.StatusServiceUnavailable
+	}
+
+	return func(next http.Handler) http.Handler {
+		if o.Rate <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(o.Paths) > 0 && !hasAnyPrefix(r.URL.Path, o.Paths) || rand.Float64() >= o.Rate {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			fault := faults[rand.IntN(len(faults))]
+			w.Header().Set(ChaosFaultHeader, fault)
+			switch fault {
+			case FaultLatency:
+				if o.Latency > 0 {
+					select {
+					case <-time.After(rand.N(o.Latency)):
+					case <-r.Context().Done():
+						return
+					}
+				}
+				next.ServeHTTP(w, r)
+			case FaultError:
+				abort(w, status, "Injected fault")
+			case FaultDrop:
+				// The server closes the connection, or resets the stream, without
+				// answering or logging
+				panic(http.ErrAbortHandler)
+			default:
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+func hasAnyPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
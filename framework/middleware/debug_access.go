@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/lemmego/api/app"
+
+	"github.com/lemmego/lemmego/framework/server"
+)
+
+// DebugAccess guards profiling and debugging endpoints: requests pass when
+// they came in through an internal listener, or, when token is set, carry
+// it as a bearer token. Others get a 404, so the endpoints are not
+// advertised.
+func DebugAccess(token string) app.HTTPMiddleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if l := server.ListenerFrom(r.Context()); l != nil && l.Internal {
+				next.ServeHTTP(w, r)
+				return
+			}
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token != "" && ok && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			http.NotFound(w, r)
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/lemmego/api/app"
+)
+
+// Except runs h on every request but those whose path starts with one of
+// prefixes, e.g. to keep CSRF checks away from signed webhooks:
+//
+//	r.UseBefore(Except(middleware.VerifyCSRF, "/webhooks/"))
+func Except(h app.Handler, prefixes ...string) app.Handler {
+	return func(c *app.Context) error {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(c.Request().URL.Path, prefix) {
+				return c.Next()
+			}
+		}
+		return h(c)
+	}
+}
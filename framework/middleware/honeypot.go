@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lemmego/api/app"
+)
+
+const (
+	// HoneypotFieldKey and HoneypotTimeKey are the context keys read by the honeypot templ component.
+	HoneypotFieldKey = "_honeypot_field"
+	HoneypotTimeKey  = "_honeypot_time"
+)
+
+var errBotSubmission = errors.New("the form submission was rejected")
+
+type HoneypotOptions struct {
+	// Field is the name of the hidden input bots tend to fill. Defaults to "website".
+	Field string
+	// MinSubmitTime rejects forms submitted faster than a human could. Defaults to 2 seconds.
+	MinSubmitTime time.Duration
+	// MaxAge rejects forms rendered too long ago. Zero disables the check.
+	MaxAge time.Duration
+	// Key signs the render timestamp so it cannot be forged. A random key is used when empty.
+	Key string
+}
+
+// Honeypot protects a form endpoint from simple bots. On reading requests it
+// exposes a field name and a signed timestamp to the view, which the honeypot
+// templ component renders. On unsafe requests it rejects submissions that fill
+// the hidden field, lack a valid timestamp, or arrive too quickly.
+func Honeypot(opts ...*HoneypotOptions) app.Handler {
+	o := &HoneypotOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		o = opts[0]
+	}
+	if o.Field == "" {
+		o.Field = "website"
+	}
+	if o.MinSubmitTime == 0 {
+		o.MinSubmitTime = 2 * time.Second
+	}
+	key := []byte(o.Key)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic(err)
+		}
+	}
+
+	return func(c *app.Context) error {
+		if c.IsReading() {
+			c.Set(HoneypotFieldKey, o.Field)
+			c.Set(HoneypotTimeKey, signTimestamp(key, time.Now()))
+			return c.Next()
+		}
+
+		if c.Request().FormValue(o.Field) != "" {
+			return rejectBot(c)
+		}
+
+		renderedAt, ok := verifyTimestamp(key, c.Request().FormValue(HoneypotTimeKey))
+		if !ok {
+			return rejectBot(c)
+		}
+
+		elapsed := time.Since(renderedAt)
+		if elapsed < o.MinSubmitTime || (o.MaxAge > 0 && elapsed > o.MaxAge) {
+			return rejectBot(c)
+		}
+
+		return c.Next()
+	}
+}
+
+func rejectBot(c *app.Context) error {
+	return c.Status(http.StatusUnprocessableEntity).Error(http.StatusUnprocessableEntity, errBotSubmission)
+}
+
+func signTimestamp(key []byte, t time.Time) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(ts))
+	return ts + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+func verifyTimestamp(key []byte, value string) (time.Time, bool) {
+	ts, sig, found := strings.Cut(value, ".")
+	if !found {
+		return time.Time{}, false
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(ts))
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(sig), []byte(expected)) {
+		return time.Time{}, false
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(unix, 0), true
+}
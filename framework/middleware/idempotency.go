@@ -0,0 +1,400 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/db"
+	"gorm.io/gorm"
+)
+
+const DefaultIdempotencyHeader = "Idempotency-Key"
+
+// IdempotentResponse is the stored result of the first request made with a key.
+type IdempotentResponse struct {
+	Fingerprint string
+	Status      int
+	Header      http.Header
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+// IdempotencyStore persists responses keyed by their idempotency key. Get
+// returns nil for unknown keys, errors being those of the store.
+// Lock must atomically reserve a key so concurrent retries cannot both run the handler.
+type IdempotencyStore interface {
+	Get(key string) (*IdempotentResponse, error)
+	Lock(key string, fingerprint string, ttl time.Duration) (bool, error)
+	Put(key string, resp *IdempotentResponse) error
+	Unlock(key string) error
+}
+
+type IdempotencyOptions struct {
+	// Header is the request header carrying the key. Defaults to "Idempotency-Key".
+	Header string
+	// TTL is how long a stored response is replayed. Defaults to 24 hours.
+	TTL time.Duration
+	// LockTTL is how long a key stays reserved by a request in flight, should
+	// the process die before releasing it. Defaults to a minute.
+	LockTTL time.Duration
+	// Store holds the responses. Defaults to an in-memory store.
+	Store IdempotencyStore
+	// Principal identifies the caller, keys being scoped to it so that a
+	// client reusing the key of another never gets its response. Defaults to
+	// the Authorization header and the SessionCookie.
+	Principal func(r *http.Request) string
+	// SessionCookie is the name of the session cookie. Defaults to "session".
+	SessionCookie string
+}
+
+// Idempotency replays the first response of an unsafe request for every retry
+// carrying the same Idempotency-Key within the TTL. Reusing a key with a different
+// request body yields 422, and a retry arriving while the first is in flight yields 409.
+func Idempotency(opts ...*IdempotencyOptions) app.HTTPMiddleware {
+	o := &IdempotencyOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		o = opts[0]
+	}
+	if o.Header == "" {
+		o.Header = DefaultIdempotencyHeader
+	}
+	if o.TTL <= 0 {
+		o.TTL = 24 * time.Hour
+	}
+	if o.LockTTL <= 0 {
+		o.LockTTL = time.Minute
+	}
+	if o.Store == nil {
+		o.Store = NewMemoryIdempotencyStore()
+	}
+	if o.SessionCookie == "" {
+		o.SessionCookie = "session"
+	}
+	if o.Principal == nil {
+		o.Principal = func(r *http.Request) string {
+			principal := r.Header.Get("Authorization")
+			if cookie, err := r.Cookie(o.SessionCookie); err == nil {
+				principal += "\n" + cookie.Value
+			}
+			return principal
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(o.Header)
+			if key == "" || !isUnsafeMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > 255 {
+				abort(w, http.StatusBadRequest, "idempotency key must not exceed 255 characters")
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				abort(w, http.StatusBadRequest, "unable to read request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// Keys are scoped to the caller and the endpoint, so the same key can
+			// be reused across resources, and hashed to fit the stores
+			principal := o.Principal(r)
+			scoped := sha256.Sum256([]byte(principal + "\n" + r.Method + " " + r.URL.Path + "\n" + key))
+			scopedKey := hex.EncodeToString(scoped[:])
+			fingerprint := fingerprintRequest(r, principal, body)
+
+			stored, err := o.Store.Get(scopedKey)
+			if err != nil {
+				slog.Error(fmt.Sprintf("idempotency: reading key: %s", err))
+				abort(w, http.StatusInternalServerError, "idempotency key could not be checked")
+				return
+			}
+			if stored != nil {
+				if stored.Fingerprint != fingerprint {
+					abort(w, http.StatusUnprocessableEntity, "idempotency key was already used with a different request")
+					return
+				}
+				if stored.Status == 0 {
+					abort(w, http.StatusConflict, "a request with this idempotency key is already in progress")
+					return
+				}
+				replay(w, stored)
+				return
+			}
+
+			locked, err := o.Store.Lock(scopedKey, fingerprint, o.LockTTL)
+			if err != nil {
+				slog.Error(fmt.Sprintf("idempotency: reserving key: %s", err))
+				abort(w, http.StatusInternalServerError, "idempotency key could not be reserved")
+				return
+			}
+			if !locked {
+				abort(w, http.StatusConflict, "a request with this idempotency key is already in progress")
+				return
+			}
+
+			// The reservation is released unless a response was stored, when the
+			// handler panics or fails, so the client can retry
+			saved := false
+			defer func() {
+				if !saved {
+					_ = o.Store.Unlock(scopedKey)
+				}
+			}()
+
+			rec := &bodyRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+
+			// Server errors are not cached so the client can safely retry
+			if rec.Status() >= http.StatusInternalServerError {
+				return
+			}
+
+			// Cookies, such as a renewed session, are not handed to retries
+			header := rec.Header().Clone()
+			header.Del("Set-Cookie")
+			saved = o.Store.Put(scopedKey, &IdempotentResponse{
+				Fingerprint: fingerprint,
+				Status:      rec.Status(),
+				Header:      header,
+				Body:        rec.body.Bytes(),
+				ExpiresAt:   time.Now().Add(o.TTL),
+			}) == nil
+		})
+	}
+}
+
+func fingerprintRequest(r *http.Request, principal string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(principal + "\n" + r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	// A form parsed by an earlier middleware has already drained the body
+	if len(body) == 0 && r.PostForm != nil {
+		h.Write([]byte(r.PostForm.Encode()))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replay(w http.ResponseWriter, stored *IdempotentResponse) {
+	for k, values := range stored.Header {
+		if http.CanonicalHeaderKey(k) == "Set-Cookie" {
+			continue
+		}
+		for _, v := range values {
+			w.Header().Add(k, v)
+		}
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(stored.Status)
+	_, _ = w.Write(stored.Body)
+}
+
+// bodyRecorder passes the response through while keeping a copy of it.
+type bodyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *bodyRecorder) WriteHeader(statusCode int) {
+	if r.status == 0 {
+		r.status = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *bodyRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *bodyRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+func (r *bodyRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// MemoryIdempotencyStore keeps responses in process memory. It is suitable for
+// single instance deployments and development.
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*IdempotentResponse
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	s := &MemoryIdempotencyStore{entries: make(map[string]*IdempotentResponse)}
+	go s.sweep(time.Minute)
+	return s
+}
+
+// sweep drops the expired entries that are not read again.
+func (s *MemoryIdempotencyStore) sweep(every time.Duration) {
+	for range time.Tick(every) {
+		now := time.Now()
+		s.mu.Lock()
+		for key, entry := range s.entries {
+			if now.After(entry.ExpiresAt) {
+				delete(s.entries, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *MemoryIdempotencyStore) Get(key string) (*IdempotentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	if time.Now().After(entry.ExpiresAt) {
+		delete(s.entries, key)
+		return nil, nil
+	}
+	return entry, nil
+}
+
+func (s *MemoryIdempotencyStore) Lock(key string, fingerprint string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.entries[key]; ok && time.Now().Before(entry.ExpiresAt) {
+		return false, nil
+	}
+	s.entries[key] = &IdempotentResponse{Fingerprint: fingerprint, ExpiresAt: time.Now().Add(ttl)}
+	return true, nil
+}
+
+func (s *MemoryIdempotencyStore) Put(key string, resp *IdempotentResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = resp
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Unlock(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// idempotencyKey is the row stored by DatabaseIdempotencyStore.
+type idempotencyKey struct {
+	Key         string `gorm:"column:idempotency_key;primaryKey"`
+	Fingerprint string
+	Status      int
+	Headers     string
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+func (idempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
+
+// DatabaseIdempotencyStore keeps responses in the idempotency_keys table so
+// retries are honored across multiple instances.
+type DatabaseIdempotencyStore struct {
+	connName []string
+}
+
+func NewDatabaseIdempotencyStore(connName ...string) *DatabaseIdempotencyStore {
+	return &DatabaseIdempotencyStore{connName: connName}
+}
+
+func (s *DatabaseIdempotencyStore) Get(key string) (*IdempotentResponse, error) {
+	var row idempotencyKey
+	err := db.Get(s.connName...).DB().Where("idempotency_key = ? AND expires_at > ?", key, time.Now()).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	if row.Headers != "" {
+		if err := json.Unmarshal([]byte(row.Headers), &header); err != nil {
+			return nil, err
+		}
+	}
+
+	return &IdempotentResponse{
+		Fingerprint: row.Fingerprint,
+		Status:      row.Status,
+		Header:      header,
+		Body:        row.Body,
+		ExpiresAt:   row.ExpiresAt,
+	}, nil
+}
+
+func (s *DatabaseIdempotencyStore) Lock(key string, fingerprint string, ttl time.Duration) (bool, error) {
+	conn := db.Get(s.connName...).DB()
+
+	// Clear an expired reservation so the key becomes usable again
+	if err := conn.Where("idempotency_key = ? AND expires_at <= ?", key, time.Now()).Delete(&idempotencyKey{}).Error; err != nil {
+		return false, err
+	}
+
+	row := &idempotencyKey{Key: key, Fingerprint: fingerprint, ExpiresAt: time.Now().Add(ttl)}
+	if err := conn.Create(row).Error; err != nil {
+		// The primary key already exists when another request holds the key,
+		// any other failure is reported
+		var count int64
+		if conn.Model(&idempotencyKey{}).Where("idempotency_key = ?", key).Count(&count).Error == nil && count > 0 {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *DatabaseIdempotencyStore) Put(key string, resp *IdempotentResponse) error {
+	headers, err := json.Marshal(resp.Header)
+	if err != nil {
+		return err
+	}
+
+	result := db.Get(s.connName...).DB().Model(&idempotencyKey{}).Where("idempotency_key = ?", key).Updates(map[string]any{
+		"status":     resp.Status,
+		"headers":    string(headers),
+		"body":       resp.Body,
+		"expires_at": resp.ExpiresAt,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("idempotency: key was not reserved")
+	}
+	return nil
+}
+
+func (s *DatabaseIdempotencyStore) Unlock(key string) error {
+	return db.Get(s.connName...).DB().Where("idempotency_key = ?", key).Delete(&idempotencyKey{}).Error
+}
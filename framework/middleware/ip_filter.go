@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/lemmego/api/app"
+	"github.com/oschwald/geoip2-golang"
+)
+
+// CountryResolver maps an IP address to an ISO 3166-1 alpha-2 country code.
+type CountryResolver interface {
+	Country(addr netip.Addr) (string, error)
+}
+
+type IPFilterOptions struct {
+	// Allow restricts access to these addresses or CIDRs when not empty.
+	Allow []string
+	// Deny blocks these addresses or CIDRs, it takes precedence over Allow.
+	Deny []string
+
+	// AllowCountries restricts access to these country codes when not empty.
+	AllowCountries []string
+	// DenyCountries blocks these country codes.
+	DenyCountries []string
+	// Countries resolves the country of the client, required for country rules.
+	Countries CountryResolver
+}
+
+// IPFilter allows or denies requests by client address and, optionally, by
+// country. It resolves the address through ClientIP, so register TrustProxies
+// globally when running behind a load balancer. Being a route handler it can
+// guard single groups:
+//
+//	admin := r.Group("/admin")
+//	admin.UseBefore(IPFilter(&IPFilterOptions{Allow: []string{"10.0.0.0/8"}}))
+func IPFilter(opts ...*IPFilterOptions) app.Handler {
+	o := &IPFilterOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		o = opts[0]
+	}
+
+	allow := mustParsePrefixes(o.Allow)
+	deny := mustParsePrefixes(o.Deny)
+	allowCountries := upper(o.AllowCountries)
+	denyCountries := upper(o.DenyCountries)
+
+	if (len(allowCountries) > 0 || len(denyCountries) > 0) && o.Countries == nil {
+		panic("ip filter: country rules require a CountryResolver")
+	}
+
+	return func(c *app.Context) error {
+		if len(allow) == 0 && len(deny) == 0 && len(allowCountries) == 0 && len(denyCountries) == 0 {
+			return c.Next()
+		}
+
+		addr, err := netip.ParseAddr(IP(c))
+		if err != nil {
+			return forbidden(c)
+		}
+		addr = addr.Unmap()
+
+		if containsAddr(deny, addr) {
+			return forbidden(c)
+		}
+		if len(allow) > 0 && !containsAddr(allow, addr) {
+			return forbidden(c)
+		}
+
+		if len(allowCountries) > 0 || len(denyCountries) > 0 {
+			country, err := o.Countries.Country(addr)
+			if err != nil {
+				// Unknown locations only pass when no allow list is enforced
+				if len(allowCountries) > 0 {
+					return forbidden(c)
+				}
+				return c.Next()
+			}
+			country = strings.ToUpper(country)
+			if slices.Contains(denyCountries, country) {
+				return forbidden(c)
+			}
+			if len(allowCountries) > 0 && !slices.Contains(allowCountries, country) {
+				return forbidden(c)
+			}
+		}
+
+		return c.Next()
+	}
+}
+
+func forbidden(c *app.Context) error {
+	return c.Status(http.StatusForbidden).Forbidden(errors.New("access denied"))
+}
+
+func mustParsePrefixes(values []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		prefix, err := parsePrefix(value)
+		if err != nil {
+			panic("ip filter: " + err.Error())
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func upper(values []string) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = strings.ToUpper(v)
+	}
+	return out
+}
+
+// MaxMindResolver resolves countries from a MaxMind GeoIP2/GeoLite2 Country or City database.
+type MaxMindResolver struct {
+	reader *geoip2.Reader
+}
+
+func NewMaxMindResolver(path string) (*MaxMindResolver, error) {
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &MaxMindResolver{reader: reader}, nil
+}
+
+func (m *MaxMindResolver) Country(addr netip.Addr) (string, error) {
+	record, err := m.reader.Country(net.IP(addr.AsSlice()))
+	if err != nil {
+		return "", err
+	}
+	if record.Country.IsoCode == "" {
+		return "", errors.New("ip filter: country not found")
+	}
+	return record.Country.IsoCode, nil
+}
+
+func (m *MaxMindResolver) Close() error {
+	return m.reader.Close()
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/lemmego/lemmego/framework/logs"
+)
+
+// RequestIDHeader carries the id of a request, taken from the client or a
+// proxy when valid, and sent back with the response.
+const RequestIDHeader = "X-Request-Id"
+
+// LogContext seeds the logger of logs.Ctx with the request's id, method,
+// path and client address, so every line a handler logs can be traced back
+// to its request.
+func LogContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			b := make([]byte, 16)
+			_, _ = rand.Read(b)
+			id = hex.EncodeToString(b)
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		l := logs.Default().With(
+			"request_id", id,
+			"method", r.Method,
+			"path", r.URL.Path,
+			"ip", ClientIP(r),
+		)
+		next.ServeHTTP(w, r.WithContext(logs.WithLogger(r.Context(), l)))
+	})
+}
+
+// validRequestID keeps ids forged into log injection out.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if !(c == '-' || c == '_' || c == '.' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
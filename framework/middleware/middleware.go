@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// abort writes a JSON error response and stops the middleware chain.
+func abort(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"message": message})
+}
+
+// isUnsafeMethod reports whether the method may change server state.
+func isUnsafeMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
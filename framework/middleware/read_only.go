@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/lemmego/api/app"
+
+	"github.com/lemmego/lemmego/framework/repo"
+)
+
+// ReadOnlyRetryAfter is the Retry-After, in seconds, of requests refused
+// while the database is read-only.
+var ReadOnlyRetryAfter = 30
+
+// ReadOnly answers writes the database refused with repo.ErrReadOnly with a
+// 503, so clients retry once the primary is back, instead of a 500. Reads go
+// on being served, from the replica when one is configured.
+func ReadOnly(c *app.Context) error {
+	err := c.Next()
+	if !errors.Is(err, repo.ErrReadOnly) {
+		return err
+	}
+	c.SetHeader("Retry-After", strconv.Itoa(ReadOnlyRetryAfter))
+	return c.Status(http.StatusServiceUnavailable).Error(http.StatusServiceUnavailable,
+		errors.New("the application is in read-only mode, please try again later"))
+}
@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/lemmego/lemmego/framework/storage"
+)
+
+// Scratch removes the scratch directories handlers create with storage.Temp
+// once the request is done.
+func Scratch(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, done := storage.WithScratch(r.Context())
+		defer done()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
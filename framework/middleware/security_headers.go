@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/lemmego/api/app"
+	"github.com/romsar/gonertia"
+)
+
+type SecurityHeadersOptions struct {
+	// HSTSMaxAge enables Strict-Transport-Security on secure requests when
+	// positive: over TLS, or forwarded as https by a trusted proxy.
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+
+	// FrameOptions is the X-Frame-Options value, e.g. "DENY" or "SAMEORIGIN".
+	FrameOptions string
+	// ReferrerPolicy is the Referrer-Policy value.
+	ReferrerPolicy string
+	// DisableNoSniff omits X-Content-Type-Options: nosniff.
+	DisableNoSniff bool
+
+	// CSP is the Content-Security-Policy to send, nil sends none.
+	CSP *CSP
+	// CSPReportOnly sends the policy as Content-Security-Policy-Report-Only.
+	CSPReportOnly bool
+}
+
+// SecurityHeaders sets common security headers on every response. When the CSP
+// references CSPNonce a fresh nonce is generated per request and made available
+// to templ components (templ.GetNonce), to Inertia root templates ({{ .cspNonce }})
+// and to handlers through CSPNonceFrom.
+func SecurityHeaders(opts ...*SecurityHeadersOptions) app.HTTPMiddleware {
+	o := &SecurityHeadersOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		o = opts[0]
+	}
+	if o.FrameOptions == "" {
+		o.FrameOptions = "SAMEORIGIN"
+	}
+	if o.ReferrerPolicy == "" {
+		o.ReferrerPolicy = "strict-origin-when-cross-origin"
+	}
+
+	hsts := ""
+	if o.HSTSMaxAge > 0 {
+		hsts = fmt.Sprintf("max-age=%d", int(o.HSTSMaxAge.Seconds()))
+		if o.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if o.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+
+	cspHeader := "Content-Security-Policy"
+	if o.CSPReportOnly {
+		cspHeader = "Content-Security-Policy-Report-Only"
+	}
+	useNonce := o.CSP != nil && o.CSP.UsesNonce()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("X-Frame-Options", o.FrameOptions)
+			h.Set("Referrer-Policy", o.ReferrerPolicy)
+			if !o.DisableNoSniff {
+				h.Set("X-Content-Type-Options", "nosniff")
+			}
+			if hsts != "" && isSecureRequest(r) {
+				h.Set("Strict-Transport-Security", hsts)
+			}
+
+			if o.CSP != nil {
+				nonce := ""
+				if useNonce {
+					nonce = generateNonce()
+					ctx := templ.WithNonce(r.Context(), nonce)
+					ctx = gonertia.SetTemplateDatum(ctx, "cspNonce", nonce)
+					r = r.WithContext(ctx)
+				}
+				h.Set(cspHeader, o.CSP.String(nonce))
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CSPNonceFrom returns the nonce generated for the current request, if any.
+func CSPNonceFrom(ctx context.Context) string {
+	return templ.GetNonce(ctx)
+}
+
+// isSecureRequest only believes the X-Forwarded-Proto of trusted proxies,
+// see TrustProxies.
+func isSecureRequest(r *http.Request) bool {
+	return r.TLS != nil || FromTrustedProxy(r) && r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+func generateNonce() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
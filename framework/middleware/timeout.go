@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lemmego/api/app"
+)
+
+// RequestTimeout sets a deadline on the request context. Database queries
+// (repo.DB), storage operations (storage.Get) and outbound calls (httpclient)
+// bound to that context stop once the deadline passes or the client goes away.
+// The handler is not interrupted, it is expected to return on the context error.
+// Server-sent event streams, requested with Accept: text/event-stream, are
+// long lived by design and only end with the client.
+func RequestTimeout(timeout time.Duration) app.HTTPMiddleware {
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/lemmego/api/app"
+)
+
+type clientIPKey struct{}
+
+// proxiedKey marks requests whose peer is a trusted proxy.
+type proxiedKey struct{}
+
+type TrustedProxiesOptions struct {
+	// Proxies lists trusted proxy addresses or CIDRs, e.g. "10.0.0.0/8".
+	// The special value "*" trusts every peer and should only be used when the
+	// app is never reachable without going through a proxy.
+	Proxies []string
+	// Headers are consulted in order. Defaults to X-Forwarded-For then X-Real-IP.
+	Headers []string
+}
+
+// TrustProxies resolves the real client address from forwarding headers, but
+// only when the connecting peer is a trusted proxy. Headers sent by anyone else
+// are ignored so clients cannot spoof their IP. The resolved address replaces
+// the host part of r.RemoteAddr and is available through ClientIP and IP.
+//
+// Register it before any middleware that relies on the client address.
+func TrustProxies(opts ...*TrustedProxiesOptions) app.HTTPMiddleware {
+	o := &TrustedProxiesOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		o = opts[0]
+	}
+	if len(o.Headers) == 0 {
+		o.Headers = []string{"X-Forwarded-For", "X-Real-IP"}
+	}
+
+	trustAll := false
+	prefixes := make([]netip.Prefix, 0, len(o.Proxies))
+	for _, proxy := range o.Proxies {
+		if proxy == "*" {
+			trustAll = true
+			continue
+		}
+		prefix, err := parsePrefix(proxy)
+		if err != nil {
+			panic("trusted proxies: " + err.Error())
+		}
+		prefixes = append(prefixes, prefix)
+	}
+
+	trusted := func(addr netip.Addr) bool {
+		if trustAll {
+			return true
+		}
+		for _, prefix := range prefixes {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, port, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				host = r.RemoteAddr
+			}
+
+			client := host
+			proxied := false
+			if peer, err := netip.ParseAddr(host); err == nil && trusted(peer.Unmap()) {
+				proxied = true
+				if resolved := forwardedClient(r, o.Headers, trusted); resolved != "" {
+					client = resolved
+				}
+			}
+
+			if port != "" {
+				r.RemoteAddr = net.JoinHostPort(client, port)
+			} else {
+				r.RemoteAddr = client
+			}
+			ctx := context.WithValue(r.Context(), clientIPKey{}, client)
+			r = r.WithContext(context.WithValue(ctx, proxiedKey{}, proxied))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedClient walks the forwarding chain from the nearest hop outwards and
+// returns the first address that is not a trusted proxy.
+func forwardedClient(r *http.Request, headers []string, trusted func(netip.Addr) bool) string {
+	for _, header := range headers {
+		values := r.Header.Values(header)
+		if len(values) == 0 {
+			continue
+		}
+
+		hops := strings.Split(strings.Join(values, ","), ",")
+		leftmost := ""
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				break
+			}
+			addr = addr.Unmap()
+			leftmost = addr.String()
+			if !trusted(addr) {
+				return leftmost
+			}
+		}
+		if leftmost != "" {
+			return leftmost
+		}
+	}
+	return ""
+}
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+// FromTrustedProxy reports whether TrustProxies found the peer of the request
+// to be a trusted proxy, whose forwarding headers can be believed.
+func FromTrustedProxy(r *http.Request) bool {
+	proxied, _ := r.Context().Value(proxiedKey{}).(bool)
+	return proxied
+}
+
+// ClientIP returns the client address resolved by TrustProxies, falling back
+// to the host part of the peer address.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok && ip != "" {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// IP is a shorthand for ClientIP(c.Request()).
+func IP(c *app.Context) string {
+	return ClientIP(c.Request())
+}
@@ -0,0 +1,51 @@
+package money
+
+import "strings"
+
+// minorUnits holds the decimal places of ISO 4217 currencies. Currencies not
+// listed use 2.
+var minorUnits = map[string]int32{
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0,
+	"XAF": 0, "XOF": 0, "XPF": 0,
+	"CLF": 4, "UYW": 4,
+}
+
+// currencies are the active ISO 4217 codes.
+var currencies = strings.Fields(`
+	AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND BOB
+	BRL BSD BTN BWP BYN BZD CAD CDF CHF CLF CLP CNY COP CRC CUP CVE CZK DJF DKK
+	DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GNF GTQ GYD HKD HNL HTG
+	HUF IDR ILS INR IQD IRR ISK JMD JOD JPY KES KGS KHR KMF KPW KRW KWD KYD KZT
+	LAK LBP LKR LRD LSL LYD MAD MDL MGA MKD MMK MNT MOP MRU MUR MVR MWK MXN MYR
+	MZN NAD NGN NIO NOK NPR NZD OMR PAB PEN PGK PHP PKR PLN PYG QAR RON RSD RUB
+	RWF SAR SBD SCR SDG SEK SGD SHP SLE SOS SRD SSP STN SVC SYP SZL THB TJS TMT
+	TND TOP TRY TTD TWD TZS UAH UGX USD UYI UYU UYW UZS VES VND VUV WST XAF XCD
+	XOF XPF YER ZAR ZMW ZWL
+`)
+
+var known = func() map[string]bool {
+	m := make(map[string]bool, len(currencies))
+	for _, c := range currencies {
+		m[c] = true
+	}
+	return m
+}()
+
+// MinorUnits returns the decimal places of a currency code, and false for
+// codes that are not ISO 4217 currencies.
+func MinorUnits(currency string) (int32, bool) {
+	if !known[currency] {
+		return 0, false
+	}
+	if places, ok := minorUnits[currency]; ok {
+		return places, true
+	}
+	return 2, true
+}
+
+// Valid reports whether currency is an ISO 4217 code, in upper case.
+func Valid(currency string) bool {
+	return known[currency]
+}
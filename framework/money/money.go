@@ -0,0 +1,179 @@
+// Package money is an amount of a currency, rounded to the currency's minor
+// unit and kept exact with decimal.Decimal. Models embed it into two columns:
+//
+//	type Order struct {
+//		ID    uint
+//		Total money.Money `gorm:"embedded;embeddedPrefix:total_"`
+//	}
+//
+// with the amount in a decimal column and the currency in a char(3) one:
+//
+//	t.Decimal("total_amount", 19, 4)
+//	t.Char("total_currency", 3)
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/lemmego/lemmego/framework/decimal"
+)
+
+var (
+	ErrCurrencyMismatch = errors.New("money: currencies differ")
+	ErrUnknownCurrency  = errors.New("money: unknown currency")
+)
+
+// Money is an amount of a currency. Amounts are rounded to the currency's
+// minor unit when created and after multiplication.
+type Money struct {
+	Amount   decimal.Decimal `json:"amount"`
+	Currency string          `json:"currency" gorm:"type:char(3)"`
+}
+
+// New parses amount, e.g. New("12.30", "USD").
+func New(amount, currency string) (Money, error) {
+	d, err := decimal.Parse(amount)
+	if err != nil {
+		return Money{}, err
+	}
+	return Of(d, currency)
+}
+
+// Of rounds amount to the minor unit of currency.
+func Of(amount decimal.Decimal, currency string) (Money, error) {
+	currency = strings.ToUpper(currency)
+	places, ok := MinorUnits(currency)
+	if !ok {
+		return Money{}, fmt.Errorf("%w %q", ErrUnknownCurrency, currency)
+	}
+	return Money{Amount: amount.Round(places), Currency: currency}, nil
+}
+
+// MustNew is New for literals known to be valid.
+func MustNew(amount, currency string) Money {
+	m, err := New(amount, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// FromMinor returns an amount given in the minor unit, e.g. cents.
+func FromMinor(minor int64, currency string) (Money, error) {
+	currency = strings.ToUpper(currency)
+	places, ok := MinorUnits(currency)
+	if !ok {
+		return Money{}, fmt.Errorf("%w %q", ErrUnknownCurrency, currency)
+	}
+	return Money{Amount: decimal.New(minor, places), Currency: currency}, nil
+}
+
+// Minor returns the amount in the minor unit, e.g. cents for payment APIs,
+// and false when it does not fit an int64.
+func (m Money) Minor() (int64, bool) {
+	places, _ := MinorUnits(m.Currency)
+	return m.Amount.Round(places).Unscaled()
+}
+
+func (m Money) places() int32 {
+	places, _ := MinorUnits(m.Currency)
+	return places
+}
+
+func (m Money) Add(n Money) (Money, error) {
+	if m.Currency != n.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, n.Currency)
+	}
+	return Money{Amount: m.Amount.Add(n.Amount), Currency: m.Currency}, nil
+}
+
+func (m Money) Sub(n Money) (Money, error) {
+	return m.Add(n.Neg())
+}
+
+// Mul multiplies by a factor, e.g. a quantity or a tax rate, rounding half
+// away from zero to the minor unit.
+func (m Money) Mul(factor decimal.Decimal) Money {
+	return Money{Amount: m.Amount.Mul(factor).Round(m.places()), Currency: m.Currency}
+}
+
+func (m Money) Neg() Money {
+	return Money{Amount: m.Amount.Neg(), Currency: m.Currency}
+}
+
+func (m Money) IsZero() bool     { return m.Amount.IsZero() }
+func (m Money) IsNegative() bool { return m.Amount.Sign() < 0 }
+
+// Cmp compares two amounts of the same currency.
+func (m Money) Cmp(n Money) (int, error) {
+	if m.Currency != n.Currency {
+		return 0, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, n.Currency)
+	}
+	return m.Amount.Cmp(n.Amount), nil
+}
+
+// Allocate splits m in proportion to ratios without losing or creating a
+// minor unit: the remainder goes one unit at a time to the first parts with
+// a share.
+// Allocate(1, 1, 1) of 100.00 gives 33.34, 33.33 and 33.33.
+func (m Money) Allocate(ratios ...int) ([]Money, error) {
+	total := int64(0)
+	for _, r := range ratios {
+		if r < 0 {
+			return nil, errors.New("money: negative ratio")
+		}
+		total += int64(r)
+	}
+	if total == 0 {
+		return nil, errors.New("money: ratios sum to zero")
+	}
+
+	units, ok := m.Minor()
+	if !ok {
+		return nil, errors.New("money: amount too large to allocate")
+	}
+	places := m.places()
+	parts := make([]Money, len(ratios))
+	left := units
+	for i, r := range ratios {
+		// units * r can overflow an int64 before the division
+		share := new(big.Int).Mul(big.NewInt(units), big.NewInt(int64(r)))
+		share.Quo(share, big.NewInt(total))
+		parts[i] = Money{Amount: decimal.New(share.Int64(), places), Currency: m.Currency}
+		left -= share.Int64()
+	}
+	// Shares are truncated towards zero, what is left is less than one unit
+	// per part
+	step := int64(1)
+	if left < 0 {
+		step = -1
+	}
+	for i := 0; left != 0; i++ {
+		if ratios[i] == 0 {
+			continue
+		}
+		parts[i].Amount = parts[i].Amount.Add(decimal.New(step, places))
+		left -= step
+	}
+	return parts, nil
+}
+
+// Split divides m into n parts as equal as the minor unit allows.
+func (m Money) Split(n int) ([]Money, error) {
+	if n <= 0 {
+		return nil, errors.New("money: split into no parts")
+	}
+	ratios := make([]int, n)
+	for i := range ratios {
+		ratios[i] = 1
+	}
+	return m.Allocate(ratios...)
+}
+
+// String formats the amount at the currency's minor unit: "12.30 USD".
+func (m Money) String() string {
+	return m.Amount.StringFixed(m.places()) + " " + m.Currency
+}
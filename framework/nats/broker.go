@@ -0,0 +1,58 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Broker carries events over core NATS, see events.Broker. Events published
+// while no instance of a group listens are not kept for it.
+type Broker struct {
+	client *Client
+}
+
+func NewBroker(client *Client) *Broker {
+	return &Broker{client: client}
+}
+
+func (b *Broker) Publish(ctx context.Context, topic string, data []byte) error {
+	conn, err := b.client.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	return conn.Publish(topic, data)
+}
+
+// Subscribe subscribes again whenever the connection breaks, until ctx is
+// done.
+func (b *Broker) Subscribe(ctx context.Context, topic, group string, fn func(data []byte)) error {
+	for ctx.Err() == nil {
+		conn, err := b.client.Conn(ctx)
+		if err == nil {
+			var sub *Subscription
+			sub, err = conn.Subscribe(topic, group, func(m *Msg) { fn(m.Data) })
+			if errors.Is(err, ErrBadSubject) {
+				return err
+			}
+			if err == nil {
+				select {
+				case <-ctx.Done():
+					return sub.Unsubscribe()
+				case <-sub.Done():
+					err = conn.Err()
+				}
+			}
+		}
+		if ctx.Err() == nil {
+			slog.Warn(fmt.Sprintf("nats: subscription to %s: %s", topic, err))
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// JetStream error codes tolerated when creating what already exists.
+const (
+	errStreamNameInUse   = 10058
+	errConsumerNameInUse = 10013
+	errConsumerExists    = 10148
+)
+
+// APIError is an error returned by the JetStream API.
+type APIError struct {
+	Code        int    `json:"code"`
+	ErrCode     int    `json:"err_code"`
+	Description string `json:"description"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("nats: jetstream: %s (%d)", e.Description, e.ErrCode)
+}
+
+// StreamConfig configures a stream, the fields left out taking the defaults
+// of the server.
+type StreamConfig struct {
+	Name     string   `json:"name"`
+	Subjects []string `json:"subjects"`
+	// Retention is "limits", "interest" or "workqueue", where messages are
+	// removed once acknowledged.
+	Retention string        `json:"retention,omitempty"`
+	Storage   string        `json:"storage,omitempty"`
+	MaxAge    time.Duration `json:"max_age,omitempty"`
+}
+
+// ConsumerConfig configures a durable pull consumer.
+type ConsumerConfig struct {
+	Durable       string        `json:"durable_name"`
+	FilterSubject string        `json:"filter_subject,omitempty"`
+	AckPolicy     string        `json:"ack_policy"`
+	AckWait       time.Duration `json:"ack_wait,omitempty"`
+	MaxDeliver    int           `json:"max_deliver,omitempty"`
+}
+
+// AddStream creates a stream unless one of the same name exists.
+func (c *Conn) AddStream(ctx context.Context, cfg *StreamConfig) error {
+	err := c.api(ctx, "STREAM.CREATE."+cfg.Name, cfg)
+	if e, ok := err.(*APIError); ok && e.ErrCode == errStreamNameInUse {
+		return nil
+	}
+	return err
+}
+
+// AddConsumer creates a durable consumer of stream unless it exists.
+func (c *Conn) AddConsumer(ctx context.Context, stream string, cfg *ConsumerConfig) error {
+	if cfg.AckPolicy == "" {
+		cfg.AckPolicy = "explicit"
+	}
+	req := map[string]any{"stream_name": stream, "config": cfg}
+	err := c.api(ctx, "CONSUMER.DURABLE.CREATE."+stream+"."+cfg.Durable, req)
+	if e, ok := err.(*APIError); ok && (e.ErrCode == errConsumerNameInUse || e.ErrCode == errConsumerExists) {
+		return nil
+	}
+	return err
+}
+
+// StreamPublish publishes data to the stream of subject, waiting for it to
+// be stored. Messages published again with the same id within the
+// duplicate window of the stream are dropped.
+func (c *Conn) StreamPublish(ctx context.Context, subject, id string, data []byte) error {
+	m := &Msg{Subject: subject, Data: data}
+	if id != "" {
+		m.Header = Header{"Nats-Msg-Id": {id}}
+	}
+	reply, err := c.RequestMsg(ctx, m)
+	if err != nil {
+		return err
+	}
+	var ack struct {
+		Error *APIError `json:"error"`
+	}
+	if err := json.Unmarshal(reply.Data, &ack); err != nil {
+		return fmt.Errorf("nats: invalid publish ack: %w", err)
+	}
+	if ack.Error != nil {
+		return ack.Error
+	}
+	return nil
+}
+
+// Next pulls the next message of a durable consumer, or returns nil when
+// there is none.
+func (c *Conn) Next(ctx context.Context, stream, consumer string) (*Msg, error) {
+	req, _ := json.Marshal(map[string]any{"batch": 1, "no_wait": true})
+	m, err := c.Request(ctx, "$JS.API.CONSUMER.MSG.NEXT."+stream+"."+consumer, req)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case m.Status == 404 || m.Status == 408:
+		return nil, nil
+	case m.Status != 0:
+		return nil, fmt.Errorf("nats: pull: %d %s", m.Status, m.Description)
+	}
+	return m, nil
+}
+
+// Ack acknowledges a message pulled from a consumer.
+func (m *Msg) Ack() error {
+	return m.Respond([]byte("+ACK"))
+}
+
+// Nak has a message pulled from a consumer delivered again after delay.
+func (m *Msg) Nak(delay time.Duration) error {
+	if delay <= 0 {
+		return m.Respond([]byte("-NAK"))
+	}
+	return m.Respond([]byte(fmt.Sprintf(`-NAK {"delay": %d}`, delay.Nanoseconds())))
+}
+
+// Term stops the delivery of a message pulled from a consumer for good.
+func (m *Msg) Term() error {
+	return m.Respond([]byte("+TERM"))
+}
+
+func (c *Conn) api(ctx context.Context, subject string, req any) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	m, err := c.Request(ctx, "$JS.API."+subject, data)
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Error *APIError `json:"error"`
+	}
+	if err := json.Unmarshal(m.Data, &result); err != nil {
+		return fmt.Errorf("nats: invalid jetstream response: %w", err)
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
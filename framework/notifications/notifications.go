@@ -0,0 +1,196 @@
+// Package notifications keeps the notifications of users in the database,
+// for the notification center of the app: the bell with its unread count,
+// the list, and marking them read. New ones reach the open pages of the
+// user at once over WebSocket:
+//
+//	err := notifications.Send(ctx, user.ID, &notifications.Notification{
+//		Type:  "invoice.paid",
+//		Title: "Invoice paid",
+//		Body:  "Invoice 1042 was paid",
+//		URL:   "/invoices/1042",
+//	})
+//
+// Pages get the "notification" message with the notification and the
+// unread count, and "notifications.unread" with the unread count when it
+// changes otherwise. Notifications for people outside of the app, such as
+// alerts to a chat channel, go through notify instead.
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lemmego/api/app"
+	gonertia "github.com/romsar/gonertia"
+
+	"github.com/lemmego/lemmego/framework/auth"
+	"github.com/lemmego/lemmego/framework/ids"
+	"github.com/lemmego/lemmego/framework/queue"
+	"github.com/lemmego/lemmego/framework/repo"
+	"github.com/lemmego/lemmego/framework/ws"
+)
+
+func init() {
+	queue.Register[SendJob]("notifications.send")
+}
+
+var ErrNotFound = errors.New("notifications: unknown notification")
+
+// Notification is a notification of a user, keyed by a ULID so the newest
+// sort last.
+type Notification struct {
+	ids.ULIDKey
+	UserID string `json:"-"`
+	// Type names the kind of notification, e.g. for the page to pick an icon.
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	// URL is where clicking the notification leads.
+	URL       string         `json:"url,omitempty"`
+	Data      map[string]any `gorm:"serializer:json" json:"data,omitempty"`
+	ReadAt    *time.Time     `json:"read_at"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+func (Notification) TableName() string {
+	return "notifications"
+}
+
+// Send stores a notification of the user and pushes it to the pages the
+// user has open. Those offline find it in the list.
+func Send(ctx context.Context, userID string, n *Notification) error {
+	n.ID, n.UserID, n.ReadAt, n.CreatedAt = "", userID, nil, time.Now()
+	if err := repo.New[Notification](ctx).Create(n); err != nil {
+		return err
+	}
+	unread, err := Unread(ctx, userID)
+	if err != nil {
+		return err
+	}
+	// The notification is stored, failing to push it only delays it
+	if err := ws.Send(ctx, userID, "notification", map[string]any{"notification": n, "unread": unread}); err != nil {
+		slog.Warn(fmt.Sprintf("notifications: pushing %s to %s: %s", n.ID, userID, err))
+	}
+	return nil
+}
+
+// Queue sends a notification from a worker, e.g. to many users.
+func Queue(ctx context.Context, userID string, n *Notification, opts ...*queue.Options) error {
+	_, err := queue.Dispatch(ctx, &SendJob{UserID: userID, Notification: *n}, opts...)
+	return err
+}
+
+// SendJob sends a queued notification.
+type SendJob struct {
+	UserID       string       `json:"user_id"`
+	Notification Notification `json:"notification"`
+}
+
+func (j *SendJob) Handle(ctx context.Context) error {
+	return Send(ctx, j.UserID, &j.Notification)
+}
+
+// Query returns the notifications of a user, the newest first, only the
+// unread ones when unreadOnly.
+func Query(ctx context.Context, userID string, unreadOnly bool) *repo.Repo[Notification] {
+	q := repo.New[Notification](ctx).Where("user_id = ?", userID)
+	if unreadOnly {
+		q = q.Where("read_at IS NULL")
+	}
+	return q.Order("id DESC")
+}
+
+// Unread counts the unread notifications of a user.
+func Unread(ctx context.Context, userID string) (int64, error) {
+	return repo.New[Notification](ctx).Where("user_id = ? AND read_at IS NULL", userID).Count()
+}
+
+// MarkRead marks notifications of the user read, returning how many
+// unread are left.
+func MarkRead(ctx context.Context, userID string, keys ...string) (int64, error) {
+	if len(keys) == 0 {
+		return Unread(ctx, userID)
+	}
+	n, err := repo.New[Notification](ctx).Where("user_id = ? AND id IN ? AND read_at IS NULL", userID, keys).Update(map[string]any{"read_at": time.Now()})
+	if err != nil {
+		return 0, err
+	}
+	return changed(ctx, userID, n)
+}
+
+// MarkAllRead marks every notification of the user read.
+func MarkAllRead(ctx context.Context, userID string) error {
+	n, err := repo.New[Notification](ctx).Where("user_id = ? AND read_at IS NULL", userID).Update(map[string]any{"read_at": time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = changed(ctx, userID, n)
+	return err
+}
+
+// MarkUnread marks a notification of the user unread again.
+func MarkUnread(ctx context.Context, userID, id string) (int64, error) {
+	n, err := repo.New[Notification](ctx).Where("user_id = ? AND id = ?", userID, id).Update(map[string]any{"read_at": nil})
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, ErrNotFound
+	}
+	return changed(ctx, userID, n)
+}
+
+// Delete deletes a notification of the user.
+func Delete(ctx context.Context, userID, id string) (int64, error) {
+	n, err := repo.New[Notification](ctx).Delete("user_id = ? AND id = ?", userID, id)
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, ErrNotFound
+	}
+	return changed(ctx, userID, n)
+}
+
+// changed tells the open pages of the user the new unread count, when n
+// notifications changed, and returns it.
+func changed(ctx context.Context, userID string, n int64) (int64, error) {
+	unread, err := Unread(ctx, userID)
+	if err != nil || n == 0 {
+		return unread, err
+	}
+	if err := ws.Send(ctx, userID, "notifications.unread", map[string]any{"unread": unread}); err != nil {
+		slog.Warn(fmt.Sprintf("notifications: pushing the unread count to %s: %s", userID, err))
+	}
+	return unread, nil
+}
+
+// Prune deletes the read notifications older than olderThan.
+func Prune(ctx context.Context, olderThan time.Duration) (int64, error) {
+	return repo.New[Notification](ctx).Delete("read_at IS NOT NULL AND created_at < ?", time.Now().Add(-olderThan))
+}
+
+// ShareUnread gives Inertia pages the unread count of the signed in user,
+// the impersonated one while impersonating, as the notifications prop for
+// the bell badge, queried only by pages rendering it:
+//
+//	<Bell :count="$page.props.notifications.unread" />
+func ShareUnread(c *app.Context) error {
+	user := c.GetSessionString(auth.UserKey)
+	if user == "" {
+		return c.Next()
+	}
+	ctx := c.RequestContext()
+	r := c.Request()
+	c.SetRequest(r.WithContext(gonertia.SetProp(r.Context(), "notifications", func() (any, error) {
+		unread, err := Unread(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]int64{"unread": unread}, nil
+	})))
+	return c.Next()
+}
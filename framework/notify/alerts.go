@@ -0,0 +1,180 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxPending caps the alerts kept between two batches.
+const maxPending = 50
+
+// AlertOptions configure an Alerter.
+type AlertOptions struct {
+	// Channels the batches are sent to.
+	Channels []string
+	// Title heads every batch, e.g. the application and its environment.
+	Title string
+	// Every is how long alerts are gathered before being sent as one batch.
+	Every time.Duration
+	// Dedup folds alerts repeating a message sent within this window into
+	// the count of suppressed ones.
+	Dedup time.Duration
+	// PerHour caps the batches sent each hour, zero lifting the cap. Alerts
+	// raised past it wait for the next batch allowed.
+	PerHour int
+}
+
+// Alerter sends alerts, such as the errors logged by the application, to
+// chat channels in batches, so an outage posts a handful of messages rather
+// than one per failing request.
+type Alerter struct {
+	opts AlertOptions
+
+	mu         sync.Mutex
+	pending    []*alert
+	index      map[string]*alert
+	seen       map[string]time.Time
+	sent       []time.Time
+	suppressed int
+
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+type alert struct {
+	level   slog.Level
+	message string
+	details string
+	count   int
+}
+
+// NewAlerter starts sending the alerts raised on it every opts.Every.
+func NewAlerter(opts AlertOptions) *Alerter {
+	if opts.Every <= 0 {
+		opts.Every = 10 * time.Second
+	}
+	a := &Alerter{
+		opts:    opts,
+		index:   map[string]*alert{},
+		seen:    map[string]time.Time{},
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// Alert raises an alert, sent with the next batch. Alerts with the level and
+// message of a pending one only increase its count.
+func (a *Alerter) Alert(level slog.Level, message, details string) {
+	key := level.String() + " " + message
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if p, ok := a.index[key]; ok {
+		p.count++
+		return
+	}
+	if t, ok := a.seen[key]; ok && time.Since(t) < a.opts.Dedup || len(a.pending) >= maxPending {
+		a.suppressed++
+		return
+	}
+	p := &alert{level: level, message: message, details: details, count: 1}
+	a.pending = append(a.pending, p)
+	a.index[key] = p
+}
+
+func (a *Alerter) run() {
+	ticker := time.NewTicker(a.opts.Every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.done:
+			a.flush(true)
+			close(a.stopped)
+			return
+		case <-ticker.C:
+			a.flush(false)
+		}
+	}
+}
+
+// flush sends the pending alerts, unless the hourly cap is reached and the
+// alerter is not closing.
+func (a *Alerter) flush(closing bool) {
+	now := time.Now()
+	a.mu.Lock()
+	if len(a.pending) == 0 {
+		a.mu.Unlock()
+		return
+	}
+	for len(a.sent) > 0 && now.Sub(a.sent[0]) >= time.Hour {
+		a.sent = a.sent[1:]
+	}
+	if !closing && a.opts.PerHour > 0 && len(a.sent) >= a.opts.PerHour {
+		a.mu.Unlock()
+		return
+	}
+	batch, suppressed := a.pending, a.suppressed
+	a.pending, a.index, a.suppressed = nil, map[string]*alert{}, 0
+	a.sent = append(a.sent, now)
+	for key, t := range a.seen {
+		if now.Sub(t) >= a.opts.Dedup {
+			delete(a.seen, key)
+		}
+	}
+	for _, p := range batch {
+		a.seen[p.level.String()+" "+p.message] = now
+	}
+	a.mu.Unlock()
+
+	m := format(a.opts.Title, batch, suppressed)
+	for _, name := range a.opts.Channels {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err := Send(ctx, name, m)
+		cancel()
+		if err != nil {
+			// Logging the failure through slog could raise another alert
+			fmt.Fprintf(os.Stderr, "notify: send %d alerts to %s: %s\n", len(batch), name, err)
+		}
+	}
+}
+
+func format(title string, batch []*alert, suppressed int) Message {
+	var b strings.Builder
+	total := 0
+	for _, p := range batch {
+		total += p.count
+		fmt.Fprintf(&b, "%s %s", p.level, p.message)
+		if p.count > 1 {
+			fmt.Fprintf(&b, " (×%d)", p.count)
+		}
+		b.WriteString("\n")
+		if p.details != "" {
+			b.WriteString("    " + p.details + "\n")
+		}
+	}
+	if suppressed > 0 {
+		fmt.Fprintf(&b, "%d more suppressed as repeated or past the batch limit\n", suppressed)
+	}
+	if title != "" {
+		title += ": "
+	}
+	noun := "alerts"
+	if total == 1 {
+		noun = "alert"
+	}
+	return Message{Title: fmt.Sprintf("%s%d %s", title, total, noun), Text: strings.TrimSuffix(b.String(), "\n")}
+}
+
+// Close sends the pending alerts and stops sending.
+func (a *Alerter) Close() error {
+	a.once.Do(func() { close(a.done) })
+	<-a.stopped
+	return nil
+}
@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// Slack posts to an incoming webhook.
+type Slack struct {
+	URL string
+}
+
+func (s *Slack) Send(ctx context.Context, m Message) error {
+	return postJSON(ctx, s.URL, map[string]string{"text": truncate(fmt.Sprintf("*%s*\n%s", m.Title, m.Text), 40000)})
+}
+
+// Discord posts to a channel webhook.
+type Discord struct {
+	URL string
+}
+
+func (d *Discord) Send(ctx context.Context, m Message) error {
+	return postJSON(ctx, d.URL, map[string]string{"content": truncate(fmt.Sprintf("**%s**\n%s", m.Title, m.Text), 2000)})
+}
+
+// Telegram sends through a bot to a chat.
+type Telegram struct {
+	Token  string
+	ChatID string
+	// BaseURL defaults to https://api.telegram.org.
+	BaseURL string
+}
+
+func (t *Telegram) Send(ctx context.Context, m Message) error {
+	base := t.BaseURL
+	if base == "" {
+		base = "https://api.telegram.org"
+	}
+	return postJSON(ctx, base+"/bot"+url.PathEscape(t.Token)+"/sendMessage", map[string]string{
+		"chat_id": t.ChatID,
+		"text":    truncate(m.Title+"\n"+m.Text, 4096),
+	})
+}
@@ -0,0 +1,125 @@
+// Package notify sends messages to people through channels, such as chat
+// webhooks, configured under notifications.channels:
+//
+//	err := notify.Send(ctx, "slack", notify.Message{Title: "Deploy finished", Text: "v1.4.2 is live"})
+//
+// Drivers for Slack, Discord, Telegram, SMS and push are built in. Register adds
+// channels of other kinds.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/lemmego/api/config"
+
+	"github.com/lemmego/lemmego/framework/httpclient"
+)
+
+var ErrUnknownChannel = errors.New("notify: unknown channel")
+
+// Message is what a channel delivers, formatted as the channel allows.
+type Message struct {
+	Title string
+	Text  string
+}
+
+// Channel delivers messages.
+type Channel interface {
+	Send(ctx context.Context, m Message) error
+}
+
+var (
+	mu       sync.RWMutex
+	channels = map[string]Channel{}
+)
+
+// Register makes c available under name, ahead of the configured channels.
+func Register(name string, c Channel) {
+	mu.Lock()
+	defer mu.Unlock()
+	channels[name] = c
+}
+
+// Get returns the named channel, registered or configured under
+// notifications.channels.
+func Get(name string) (Channel, error) {
+	mu.RLock()
+	c, ok := channels[name]
+	mu.RUnlock()
+	if ok {
+		return c, nil
+	}
+
+	conf, _ := config.Get("notifications.channels."+name, nil).(config.M)
+	if conf == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownChannel, name)
+	}
+	url, _ := conf["url"].(string)
+	switch conf["driver"] {
+	case "slack":
+		return &Slack{URL: url}, nil
+	case "discord":
+		return &Discord{URL: url}, nil
+	case "telegram":
+		token, _ := conf["token"].(string)
+		chatID, _ := conf["chat_id"].(string)
+		return &Telegram{Token: token, ChatID: chatID}, nil
+	case "sms":
+		to, _ := conf["to"].(string)
+		var numbers []string
+		for _, n := range strings.Split(to, ",") {
+			if n = strings.TrimSpace(n); n != "" {
+				numbers = append(numbers, n)
+			}
+		}
+		return &SMS{To: numbers}, nil
+	case "push":
+		topic, _ := conf["topic"].(string)
+		return &Push{Topic: topic}, nil
+	}
+	return nil, fmt.Errorf("notify: channel %s has unknown driver %v", name, conf["driver"])
+}
+
+// Send delivers m through the named channel.
+func Send(ctx context.Context, name string, m Message) error {
+	c, err := Get(name)
+	if err != nil {
+		return err
+	}
+	return c.Send(ctx, m)
+}
+
+// postJSON posts payload to url and fails on non 2xx answers.
+func postJSON(ctx context.Context, url string, payload any) error {
+	if url == "" {
+		return errors.New("notify: no webhook url")
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := httpclient.Post(ctx, url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notify: webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+// truncate cuts s to at most n runes, the limit of a message on the channel.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
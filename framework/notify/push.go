@@ -0,0 +1,18 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/lemmego/lemmego/framework/push"
+)
+
+// Push sends messages to the devices subscribed to a topic, e.g. the
+// phones of the people on call.
+type Push struct {
+	Topic string
+}
+
+func (p *Push) Send(ctx context.Context, m Message) error {
+	// Bodies over a few hundred characters are cut by the devices anyway
+	return push.SendToTopic(ctx, p.Topic, &push.Notification{Title: m.Title, Body: truncate(m.Text, 1000)})
+}
@@ -0,0 +1,23 @@
+package notify
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lemmego/lemmego/framework/sms"
+)
+
+// SMS texts messages to numbers through the sms driver, e.g. for on-call
+// alerts.
+type SMS struct {
+	To []string
+}
+
+func (s *SMS) Send(ctx context.Context, m Message) error {
+	var errs []error
+	for _, to := range s.To {
+		// Long messages are split in parts, each billed
+		errs = append(errs, sms.SendMessage(ctx, &sms.Message{To: to, Text: truncate(m.Title+"\n"+m.Text, 480)}))
+	}
+	return errors.Join(errs...)
+}
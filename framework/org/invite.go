@@ -0,0 +1,168 @@
+package org
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lemmego/lemmego/framework/events"
+	"github.com/lemmego/lemmego/framework/repo"
+	"github.com/lemmego/lemmego/framework/signed"
+	"gorm.io/gorm"
+)
+
+// InvitationTTL is how long invitation links stay valid.
+var InvitationTTL = 7 * 24 * time.Hour
+
+// InvitationCreated is dispatched by Invite. Listeners mail URL to the
+// invitee.
+const InvitationCreated = "org.invitation.created"
+
+// UserEmail returns the email address of a user. Accept only lets users in
+// whose address is that of the invitation, so applications set it, e.g. to
+// read the email column of their users; until then invitations cannot be
+// accepted.
+var UserEmail func(ctx context.Context, userID string) (string, error)
+
+var (
+	ErrInvitationExpired  = errors.New("org: invitation expired")
+	ErrInvitationAccepted = errors.New("org: invitation already accepted")
+	ErrInvitationEmail    = errors.New("org: invitation sent to another email address")
+)
+
+type Invitation struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	OrganizationID uint       `json:"organization_id"`
+	Email          string     `json:"email"`
+	Role           string     `json:"role"`
+	InvitedBy      string     `json:"invited_by"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	AcceptedAt     *time.Time `json:"accepted_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func (Invitation) TableName() string {
+	return "organization_invitations"
+}
+
+// InvitationEvent carries a new invitation and its signed link.
+type InvitationEvent struct {
+	Invitation *Invitation
+	URL        string
+}
+
+func (e *InvitationEvent) Name() string {
+	return InvitationCreated
+}
+
+// Invite invites email to join an organization as role and dispatches
+// InvitationCreated with the link accepting it.
+func Invite(ctx context.Context, orgID uint, email, role, invitedBy string) (*Invitation, error) {
+	if err := validRole(role); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	inv := &Invitation{
+		OrganizationID: orgID,
+		Email:          strings.ToLower(strings.TrimSpace(email)),
+		Role:           role,
+		InvitedBy:      invitedBy,
+		ExpiresAt:      now.Add(InvitationTTL),
+		CreatedAt:      now,
+	}
+	var link string
+	err := repo.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := repo.From[Invitation](tx).Create(inv); err != nil {
+			return err
+		}
+		var err error
+		link, err = signed.Absolute(fmt.Sprintf("/invitations/%d/accept", inv.ID), InvitationTTL)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := events.Dispatch(ctx, &InvitationEvent{Invitation: inv, URL: link}); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// FindInvitation returns an invitation, gorm.ErrRecordNotFound when none has
+// the id.
+func FindInvitation(ctx context.Context, invitationID uint) (*Invitation, error) {
+	invs, err := repo.New[Invitation](ctx).Where("id = ?", invitationID).Limit(1).Find()
+	if err != nil {
+		return nil, err
+	}
+	if len(invs) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &invs[0], nil
+}
+
+// Accept makes userID a member of the organization of an invitation, when
+// the user's email address, see UserEmail, is the invited one. Users already
+// in it keep the more privileged of their role and the invited one.
+func Accept(ctx context.Context, invitationID uint, userID string) (*Membership, error) {
+	if UserEmail == nil {
+		return nil, errors.New("org: UserEmail is not set, invitations cannot be accepted")
+	}
+	email, err := UserEmail(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var m *Membership
+	err = repo.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		invs, err := repo.From[Invitation](tx).Where("id = ?", invitationID).Limit(1).Find()
+		if err != nil {
+			return err
+		}
+		if len(invs) == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		inv := &invs[0]
+		now := time.Now()
+		switch {
+		case inv.AcceptedAt != nil:
+			return ErrInvitationAccepted
+		case now.After(inv.ExpiresAt):
+			return ErrInvitationExpired
+		case !strings.EqualFold(strings.TrimSpace(email), inv.Email):
+			return ErrInvitationEmail
+		}
+
+		m, err = find(tx, inv.OrganizationID, userID)
+		switch {
+		case errors.Is(err, ErrNotMember):
+			m = &Membership{OrganizationID: inv.OrganizationID, UserID: userID, Role: inv.Role}
+			if err := repo.From[Membership](tx).Create(m); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case !m.Has(inv.Role):
+			m.Role = inv.Role
+			if err := tx.Model(m).Update("role", inv.Role).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(inv).Update("accepted_at", now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Pending lists the invitations of an organization not yet accepted nor
+// expired.
+func Pending(ctx context.Context, orgID uint) ([]Invitation, error) {
+	return repo.New[Invitation](ctx).
+		Where("organization_id = ? AND accepted_at IS NULL AND expires_at > ?", orgID, time.Now()).
+		Order("created_at DESC").Find()
+}
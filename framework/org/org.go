@@ -0,0 +1,217 @@
+// Package org groups users into organizations: memberships with a role per
+// organization, invitations accepted through signed links, and the current
+// organization of a session, which scopes the routes behind Require.
+//
+//	o, err := org.Create(ctx, "Acme", userID)
+//	inv, err := org.Invite(ctx, o.ID, "jane@example.com", org.Admin, userID)
+//
+//	g := r.Group("/orgs/{org}")
+//	g.UseBefore(org.Require(org.Member))
+//
+// Models holding organization data add the tenant scope, which Require
+// fills with the current organization:
+//
+//	repo.AddGlobalScope[Project]("tenant", repo.TenantScope("organization_id"))
+package org
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lemmego/lemmego/framework/repo"
+	"github.com/lemmego/lemmego/framework/str"
+	"gorm.io/gorm"
+)
+
+// Roles, from the most to the least privileged.
+const (
+	Owner  = "owner"
+	Admin  = "admin"
+	Member = "member"
+)
+
+var ranks = map[string]int{Owner: 3, Admin: 2, Member: 1}
+
+var (
+	ErrNotMember   = errors.New("org: not a member of the organization")
+	ErrUnknownRole = errors.New("org: unknown role")
+	ErrLastOwner   = errors.New("org: an organization keeps at least one owner")
+)
+
+type Organization struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Organization) TableName() string {
+	return "organizations"
+}
+
+// Membership is a user's role in an organization. Users are identified by
+// the string form of their key, as in the session.
+type Membership struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	OrganizationID uint      `json:"organization_id"`
+	UserID         string    `json:"user_id"`
+	Role           string    `json:"role"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (Membership) TableName() string {
+	return "organization_members"
+}
+
+// Has reports whether the member's role is role or a more privileged one.
+func (m *Membership) Has(role string) bool {
+	return ranks[m.Role] >= ranks[role]
+}
+
+func validRole(role string) error {
+	if _, ok := ranks[role]; !ok {
+		return fmt.Errorf("%w %q", ErrUnknownRole, role)
+	}
+	return nil
+}
+
+// Create makes an organization owned by ownerID, with a slug derived from its
+// name and made unique. Slugs are ASCII, to serve as subdomains. An empty
+// ownerID leaves it without members, as for groups provisioned through SCIM.
+func Create(ctx context.Context, name, ownerID string) (*Organization, error) {
+	base := str.ASCIISlug(name)
+	if base == "" {
+		base = "org"
+	}
+
+	o := &Organization{Name: name}
+	err := repo.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if o.Slug, err = str.UniqueSlug(tx, Organization{}.TableName(), "slug", base); err != nil {
+			return err
+		}
+		if err := repo.From[Organization](tx).Create(o); err != nil {
+			return err
+		}
+		if ownerID == "" {
+			return nil
+		}
+		return repo.From[Membership](tx).Create(&Membership{OrganizationID: o.ID, UserID: ownerID, Role: Owner})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Rename changes the name of an organization, keeping its slug.
+func Rename(ctx context.Context, orgID uint, name string) error {
+	_, err := repo.New[Organization](ctx).Where("id = ?", orgID).Update(map[string]any{"name": name})
+	return err
+}
+
+// Delete removes an organization with its memberships and invitations.
+func Delete(ctx context.Context, orgID uint) error {
+	return repo.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := repo.From[Membership](tx).Delete("organization_id = ?", orgID); err != nil {
+			return err
+		}
+		if _, err := repo.From[Invitation](tx).Delete("organization_id = ?", orgID); err != nil {
+			return err
+		}
+		_, err := repo.From[Organization](tx).Delete("id = ?", orgID)
+		return err
+	})
+}
+
+// Add makes userID a member of an organization with role. Members already
+// in it keep their role.
+func Add(ctx context.Context, orgID uint, userID, role string) (*Membership, error) {
+	if err := validRole(role); err != nil {
+		return nil, err
+	}
+	m, err := Find(ctx, orgID, userID)
+	if !errors.Is(err, ErrNotMember) {
+		return m, err
+	}
+	m = &Membership{OrganizationID: orgID, UserID: userID, Role: role}
+	return m, repo.New[Membership](ctx).Create(m)
+}
+
+// Find returns the membership of userID in an organization, or ErrNotMember.
+func Find(ctx context.Context, orgID uint, userID string) (*Membership, error) {
+	return find(repo.DB(ctx), orgID, userID)
+}
+
+func find(tx *gorm.DB, orgID uint, userID string) (*Membership, error) {
+	members, err := repo.From[Membership](tx).Where("organization_id = ? AND user_id = ?", orgID, userID).Limit(1).Find()
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, ErrNotMember
+	}
+	return &members[0], nil
+}
+
+// Members lists the memberships of an organization, owners first.
+func Members(ctx context.Context, orgID uint) ([]Membership, error) {
+	return repo.New[Membership](ctx).Where("organization_id = ?", orgID).
+		Order("CASE role WHEN 'owner' THEN 0 WHEN 'admin' THEN 1 ELSE 2 END, id").Find()
+}
+
+// Of lists the organizations userID belongs to.
+func Of(ctx context.Context, userID string) ([]Organization, error) {
+	return repo.New[Organization](ctx).
+		Where("id IN (?)", repo.DB(ctx).Model(&Membership{}).Select("organization_id").Where("user_id = ?", userID)).
+		Order("name").Find()
+}
+
+// SetRole changes the role of a member.
+func SetRole(ctx context.Context, orgID uint, userID, role string) error {
+	if err := validRole(role); err != nil {
+		return err
+	}
+	return repo.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		m, err := lastOwnerCheck(tx, orgID, userID, role != Owner)
+		if err != nil {
+			return err
+		}
+		return tx.Model(m).Update("role", role).Error
+	})
+}
+
+// Remove takes userID out of an organization.
+func Remove(ctx context.Context, orgID uint, userID string) error {
+	return repo.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		m, err := lastOwnerCheck(tx, orgID, userID, true)
+		if err != nil {
+			return err
+		}
+		return tx.Delete(m).Error
+	})
+}
+
+// lastOwnerCheck loads a membership and, when the change takes ownership
+// away, makes sure another owner remains.
+func lastOwnerCheck(tx *gorm.DB, orgID uint, userID string, losesOwner bool) (*Membership, error) {
+	m, err := find(tx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if m.Role != Owner || !losesOwner {
+		return m, nil
+	}
+	owners, err := repo.From[Membership](tx).Where("organization_id = ? AND role = ?", orgID, Owner).Count()
+	if err != nil {
+		return nil, err
+	}
+	if owners <= 1 {
+		return nil, ErrLastOwner
+	}
+	return m, nil
+}
@@ -0,0 +1,88 @@
+package org
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/lemmego/api/app"
+
+	"github.com/lemmego/lemmego/framework/auth"
+	"github.com/lemmego/lemmego/framework/repo"
+)
+
+// CurrentKey is the session key of the organization the user switched to.
+const CurrentKey = "org_id"
+
+type membershipKey struct{}
+
+// Switch makes orgID the current organization of the signed in user, who
+// must be a member of it.
+func Switch(c *app.Context, orgID uint) error {
+	user := c.GetSessionString(auth.UserKey)
+	if user == "" {
+		return ErrNotMember
+	}
+	if _, err := Find(c.RequestContext(), orgID, user); err != nil {
+		return err
+	}
+	c.PutSession(CurrentKey, strconv.FormatUint(uint64(orgID), 10))
+	return nil
+}
+
+// Current returns the organization the session switched to, if any.
+func Current(c *app.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.GetSessionString(CurrentKey), 10, 0)
+	if err != nil {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// Require limits a route to members of the organization holding role or a
+// more privileged one. The organization is the route's {org} parameter, or
+// the current one of the session. Handlers read the membership with
+// MembershipFrom, and repositories of tenant scoped models only see the
+// organization's rows.
+func Require(role string) app.Handler {
+	if err := validRole(role); err != nil {
+		panic(err)
+	}
+
+	return func(c *app.Context) error {
+		user := c.GetSessionString(auth.UserKey)
+		if user == "" {
+			return c.Unauthorized(errors.New("sign in to continue"))
+		}
+
+		orgID, ok := Current(c)
+		if param := c.Param("org"); param != "" {
+			id, err := strconv.ParseUint(param, 10, 0)
+			if err != nil {
+				return c.NotFound(ErrNotMember)
+			}
+			orgID, ok = uint(id), true
+		}
+		if !ok {
+			return c.Forbidden(ErrNotMember)
+		}
+
+		m, err := Find(c.RequestContext(), orgID, user)
+		if errors.Is(err, ErrNotMember) || err == nil && !m.Has(role) {
+			return c.Forbidden(ErrNotMember)
+		}
+		if err != nil {
+			return err
+		}
+
+		ctx := context.WithValue(repo.WithTenant(c.RequestContext(), orgID), membershipKey{}, m)
+		c.SetRequest(c.Request().WithContext(ctx))
+		return c.Next()
+	}
+}
+
+// MembershipFrom returns the membership checked by Require.
+func MembershipFrom(ctx context.Context) (*Membership, bool) {
+	m, ok := ctx.Value(membershipKey{}).(*Membership)
+	return m, ok
+}
@@ -0,0 +1,264 @@
+// Package outbox records jobs and events inside a database transaction and
+// relays them once it committed, so a transaction rolled back never leaves
+// a job queued or an event dispatched for changes that did not happen:
+//
+//	err := outbox.Transaction(ctx, func(tx *gorm.DB) error {
+//		if err := repo.From[Order](tx).Create(order); err != nil {
+//			return err
+//		}
+//		_, err := outbox.Dispatch(tx, &SendReceipt{OrderID: order.ID})
+//		return err
+//	})
+//
+// The relay, run by outbox:relay or inside the web process with
+// outbox.relay set, queues the jobs and dispatches the events of the outbox
+// table in the order they were recorded. Those it fails to relay are retried
+// with a backoff, without holding back the others, so a job or an event may
+// be relayed more than once. Events are decoded again by the relay, so their
+// types are registered with events.RegisterRemote.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lemmego/api/db"
+	"github.com/lemmego/lemmego/framework/events"
+	"github.com/lemmego/lemmego/framework/metrics"
+	"github.com/lemmego/lemmego/framework/queue"
+	"github.com/lemmego/lemmego/framework/repo"
+	"github.com/lemmego/lemmego/framework/str"
+	"gorm.io/gorm"
+)
+
+// Kinds of messages.
+const (
+	KindJob   = "job"
+	KindEvent = "event"
+)
+
+// claimTimeout is how long a relay may hold messages before another one
+// takes them over, its process presumed gone.
+const claimTimeout = time.Minute
+
+var relayed = metrics.Counter("outbox_relayed_total", "Outbox messages relayed, by kind and status.")
+
+// Message is a job or an event waiting in the outbox.
+type Message struct {
+	ID   uint `gorm:"primaryKey"`
+	Kind string
+	// Name is the name of the job or the event.
+	Name        string
+	Payload     string
+	Attempts    int
+	LastError   string
+	AvailableAt time.Time
+	ClaimedBy   string
+	ClaimedAt   *time.Time
+	CreatedAt   time.Time
+}
+
+func (Message) TableName() string {
+	return "outbox"
+}
+
+// wake tells the relay of this process that messages were committed.
+var wake = make(chan struct{}, 1)
+
+// Transaction runs fn in a transaction of the named connection, waking the
+// relay of this process once it committed.
+func Transaction(ctx context.Context, fn func(tx *gorm.DB) error, connName ...string) error {
+	if err := repo.DB(ctx, connName...).Transaction(fn); err != nil {
+		return err
+	}
+	Notify()
+	return nil
+}
+
+// Notify wakes the relay of this process, for messages recorded in
+// transactions other than those of Transaction.
+func Notify() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// Dispatch records job in the transaction tx, to be queued by the relay,
+// and returns its id. Its progress cannot be tracked.
+func Dispatch(tx *gorm.DB, job queue.Job, opts ...*queue.Options) (string, error) {
+	env, err := queue.Default().Envelope(job, opts...)
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return "", err
+	}
+	return env.ID, record(tx, KindJob, env.Job, payload)
+}
+
+// Publish records e in the transaction tx, to be dispatched by the relay.
+func Publish(tx *gorm.DB, e events.Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	// Decoding it now fails the transaction, rather than the relay, when
+	// the event is not registered
+	if _, err := events.Decode(e.Name(), payload); err != nil {
+		return err
+	}
+	return record(tx, KindEvent, e.Name(), payload)
+}
+
+func record(tx *gorm.DB, kind, name string, payload []byte) error {
+	now := time.Now()
+	return tx.Create(&Message{
+		Kind:        kind,
+		Name:        name,
+		Payload:     string(payload),
+		AvailableAt: now,
+		CreatedAt:   now,
+	}).Error
+}
+
+// RelayOptions configure a relay.
+type RelayOptions struct {
+	// Connection holding the outbox table, the default one when empty.
+	Connection string
+	// Interval is how often the table is polled, a second when zero.
+	Interval time.Duration
+	// Batch is how many messages are relayed at a time, 100 when zero.
+	Batch int
+}
+
+func (o *RelayOptions) db(ctx context.Context) *gorm.DB {
+	if o.Connection != "" {
+		return db.Get(o.Connection).DB().WithContext(ctx)
+	}
+	return db.Get().DB().WithContext(ctx)
+}
+
+// Relay relays the messages of the outbox until ctx is done.
+func Relay(ctx context.Context, opts *RelayOptions) {
+	if opts == nil {
+		opts = &RelayOptions{}
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	holder := str.RandomString(16)
+
+	for ctx.Err() == nil {
+		n, err := relay(ctx, opts, holder)
+		if err != nil && ctx.Err() == nil {
+			slog.Error(fmt.Sprintf("outbox: relaying: %s", err))
+		}
+		// A full batch means more are waiting
+		if err == nil && n == opts.batch() {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-wake:
+		case <-time.After(interval):
+		}
+	}
+}
+
+// RelayOnce relays the messages due, and returns how many it handled.
+func RelayOnce(ctx context.Context, opts *RelayOptions) (int, error) {
+	if opts == nil {
+		opts = &RelayOptions{}
+	}
+	return relay(ctx, opts, str.RandomString(16))
+}
+
+func (o *RelayOptions) batch() int {
+	if o.Batch <= 0 {
+		return 100
+	}
+	return o.Batch
+}
+
+// relay claims a batch of messages for holder and relays them. Claims are
+// taken with a conditional update, so concurrent relays never take the same
+// messages.
+func relay(ctx context.Context, opts *RelayOptions, holder string) (int, error) {
+	now := time.Now()
+	var ids []uint
+	err := opts.db(ctx).Model(&Message{}).
+		Where("available_at <= ? AND (claimed_at IS NULL OR claimed_at <= ?)", now, now.Add(-claimTimeout)).
+		Order("id").Limit(opts.batch()).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	err = opts.db(ctx).Model(&Message{}).
+		Where("id IN ? AND (claimed_at IS NULL OR claimed_at <= ?)", ids, now.Add(-claimTimeout)).
+		Updates(map[string]any{"claimed_by": holder, "claimed_at": now}).Error
+	if err != nil {
+		return 0, err
+	}
+	var messages []Message
+	if err := opts.db(ctx).Where("id IN ? AND claimed_by = ?", ids, holder).Order("id").Find(&messages).Error; err != nil {
+		return 0, err
+	}
+
+	for i := range messages {
+		m := &messages[i]
+		if err := deliver(ctx, m); err != nil {
+			relayed.With(metrics.Labels{"kind": m.Kind, "status": "failed"}).Inc()
+			slog.Warn(fmt.Sprintf("outbox: relaying %s %s (#%d): %s", m.Kind, m.Name, m.ID, err))
+			m.Attempts++
+			backoff := min(time.Duration(m.Attempts*m.Attempts)*time.Second, time.Hour)
+			err = opts.db(ctx).Model(m).Updates(map[string]any{
+				"attempts":     m.Attempts,
+				"last_error":   err.Error(),
+				"available_at": time.Now().Add(backoff),
+				"claimed_by":   "",
+				"claimed_at":   nil,
+			}).Error
+			if err != nil {
+				return i, err
+			}
+			continue
+		}
+		relayed.With(metrics.Labels{"kind": m.Kind, "status": "done"}).Inc()
+		if err := opts.db(ctx).Delete(m).Error; err != nil {
+			return i, err
+		}
+	}
+	return len(messages), nil
+}
+
+func deliver(ctx context.Context, m *Message) error {
+	switch m.Kind {
+	case KindJob:
+		var env queue.Envelope
+		if err := json.Unmarshal([]byte(m.Payload), &env); err != nil {
+			return err
+		}
+		q := queue.Default()
+		if _, ok := q.Driver().(*queue.SyncDriver); ok {
+			// The job ran here, its failure is not one of the relay
+			if err := q.Push(ctx, &env); err != nil {
+				slog.Error(fmt.Sprintf("outbox: %s %s: %s", env.Job, env.ID, err))
+			}
+			return nil
+		}
+		return q.Push(ctx, &env)
+	case KindEvent:
+		e, err := events.Decode(m.Name, []byte(m.Payload))
+		if err != nil {
+			return err
+		}
+		return events.Dispatch(ctx, e)
+	default:
+		return fmt.Errorf("unknown kind %q", m.Kind)
+	}
+}
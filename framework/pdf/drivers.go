@@ -0,0 +1,197 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var sides = [4]string{"top", "right", "bottom", "left"}
+
+func (o *Options) margins() [4]float64 {
+	return [4]float64{o.MarginTop, o.MarginRight, o.MarginBottom, o.MarginLeft}
+}
+
+// Wkhtmltopdf renders with wkhtmltopdf, which supports HTML headers and
+// footers with page numbers.
+type Wkhtmltopdf struct {
+	// Binary defaults to wkhtmltopdf on the PATH.
+	Binary  string
+	Timeout time.Duration
+}
+
+func (d *Wkhtmltopdf) Render(ctx context.Context, doc []byte, o *Options, w io.Writer) error {
+	binary := d.Binary
+	if binary == "" {
+		binary = "wkhtmltopdf"
+	}
+
+	args := []string{"--quiet", "--encoding", "utf-8", "--enable-local-file-access"}
+	if o.PageSize != "" {
+		args = append(args, "--page-size", o.PageSize)
+	}
+	if o.Landscape {
+		args = append(args, "--orientation", "Landscape")
+	}
+	for i, mm := range o.margins() {
+		if mm > 0 {
+			args = append(args, "--margin-"+sides[i], strconv.FormatFloat(mm, 'f', -1, 64)+"mm")
+		}
+	}
+
+	// Headers and footers are separate documents, read from files
+	if o.HeaderHTML != "" || o.FooterHTML != "" {
+		dir, err := os.MkdirTemp("", "pdf-*")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+
+		for _, part := range []struct{ name, html string }{{"header", o.HeaderHTML}, {"footer", o.FooterHTML}} {
+			if part.html == "" {
+				continue
+			}
+			file := filepath.Join(dir, part.name+".html")
+			if err := os.WriteFile(file, []byte(standalone(part.html)), 0o600); err != nil {
+				return err
+			}
+			args = append(args, "--"+part.name+"-html", file)
+		}
+	}
+	if o.PageNumbers {
+		if o.FooterHTML == "" {
+			args = append(args, "--footer-center", "[page] / [topage]", "--footer-font-size", "8")
+		} else {
+			// --footer-center is ignored next to --footer-html
+			args = append(args, "--header-right", "[page] / [topage]", "--header-font-size", "8")
+		}
+	}
+
+	args = append(args, "-", "-")
+	return run(ctx, d.Timeout, bytes.NewReader(doc), w, binary, args...)
+}
+
+// standalone wraps a header or footer fragment in a document, as wkhtmltopdf
+// requires.
+func standalone(fragment string) string {
+	return `<!DOCTYPE html><html><head><meta charset="utf-8"></head><body style="margin:0">` + fragment + `</body></html>`
+}
+
+// Chrome renders with a headless Chrome or Chromium, for documents relying on
+// modern CSS. Headers, footers and page numbers are printed in the page
+// margins, which needs Chrome 131 or later.
+type Chrome struct {
+	// Binary defaults to the first of chromium, chromium-browser and
+	// google-chrome found on the PATH.
+	Binary  string
+	Timeout time.Duration
+	// NoSandbox is required when running as root, e.g. in most containers.
+	NoSandbox bool
+}
+
+func (d *Chrome) binary() string {
+	if d.Binary != "" {
+		return d.Binary
+	}
+	for _, name := range []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
+		}
+	}
+	return "chromium"
+}
+
+func (d *Chrome) Render(ctx context.Context, doc []byte, o *Options, w io.Writer) error {
+	dir, err := os.MkdirTemp("", "pdf-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "document.html")
+	output := filepath.Join(dir, "document.pdf")
+	if err := os.WriteFile(input, withPageStyle(doc, o), 0o600); err != nil {
+		return err
+	}
+
+	args := []string{
+		"--headless", "--disable-gpu", "--no-pdf-header-footer", "--hide-scrollbars",
+		"--user-data-dir=" + filepath.Join(dir, "profile"),
+		"--print-to-pdf=" + output,
+	}
+	if d.NoSandbox {
+		args = append(args, "--no-sandbox")
+	}
+	args = append(args, "file://"+input)
+
+	if err := run(ctx, d.Timeout, nil, io.Discard, d.binary(), args...); err != nil {
+		return err
+	}
+
+	f, err := os.Open(output)
+	if err != nil {
+		return fmt.Errorf("pdf: chrome produced no output: %w", err)
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+var tags = regexp.MustCompile(`<[^>]*>`)
+
+// withPageStyle prepends an @page rule carrying the layout options, which
+// Chrome's command line has no flags for.
+func withPageStyle(doc []byte, o *Options) []byte {
+	var css bytes.Buffer
+	css.WriteString("<style>@page{")
+	if o.PageSize != "" || o.Landscape {
+		size := o.PageSize
+		if size == "" {
+			size = "A4"
+		}
+		if o.Landscape {
+			size += " landscape"
+		}
+		fmt.Fprintf(&css, "size:%s;", size)
+	}
+	for i, mm := range o.margins() {
+		if mm > 0 {
+			fmt.Fprintf(&css, "margin-%s:%smm;", sides[i], strconv.FormatFloat(mm, 'f', -1, 64))
+		}
+	}
+	if o.HeaderHTML != "" {
+		fmt.Fprintf(&css, "@top-center{content:%s;font-size:9px}", cssString(o.HeaderHTML))
+	}
+	if o.FooterHTML != "" {
+		fmt.Fprintf(&css, "@bottom-center{content:%s;font-size:9px}", cssString(o.FooterHTML))
+	}
+	if o.PageNumbers {
+		css.WriteString(`@bottom-right{content:counter(page) " / " counter(pages);font-size:9px}`)
+	}
+	css.WriteString("}</style>")
+	return withHead(doc, css.Bytes())
+}
+
+// cssString turns an HTML fragment into a quoted CSS string of its text.
+func cssString(fragment string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range html.UnescapeString(tags.ReplaceAllString(fragment, "")) {
+		if r == '"' || r == '\\' || r < 0x20 || r == 0x7f || r == '<' {
+			fmt.Fprintf(&b, "\\%x ", r)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
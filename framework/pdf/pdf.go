@@ -0,0 +1,196 @@
+// Package pdf renders HTML, usually a templ component, to PDF for invoices and
+// reports. The work is done by an external renderer: wkhtmltopdf, or a
+// headless Chrome or Chromium.
+//
+//	return pdf.Download(c, "invoice-42.pdf", views.Invoice(inv), &pdf.Options{
+//		PageSize:    "A4",
+//		FooterHTML:  "<div style='font-size:9px'>ACME Ltd.</div>",
+//		PageNumbers: true,
+//	})
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/config"
+	"github.com/lemmego/lemmego/framework/storage"
+)
+
+var ErrUnknownDriver = errors.New("pdf: unknown driver")
+
+// Options control the page layout. Zero values use the renderer's defaults.
+type Options struct {
+	// PageSize is a paper name like "A4" or "Letter".
+	PageSize  string
+	Landscape bool
+	// Margins in millimetres.
+	MarginTop, MarginRight, MarginBottom, MarginLeft float64
+	// HeaderHTML and FooterHTML are repeated on every page. Chrome only
+	// supports their text, wkhtmltopdf renders the HTML.
+	HeaderHTML string
+	FooterHTML string
+	// PageNumbers adds "page / total" to the footer, or to the header when
+	// wkhtmltopdf also renders a FooterHTML.
+	PageNumbers bool
+	// BaseURL resolves relative links to stylesheets and images.
+	BaseURL string
+}
+
+// Driver turns an HTML document into a PDF.
+type Driver interface {
+	Render(ctx context.Context, html []byte, o *Options, w io.Writer) error
+}
+
+var (
+	mu            sync.Mutex
+	defaultDriver Driver
+)
+
+// Default returns the driver configured by pdf.driver.
+func Default() (Driver, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if defaultDriver != nil {
+		return defaultDriver, nil
+	}
+
+	binary := config.Get("pdf.binary", "").(string)
+	timeout := time.Duration(config.Get("pdf.timeout", 60).(int)) * time.Second
+	switch name := config.Get("pdf.driver", "wkhtmltopdf").(string); name {
+	case "wkhtmltopdf":
+		defaultDriver = &Wkhtmltopdf{Binary: binary, Timeout: timeout}
+	case "chrome":
+		defaultDriver = &Chrome{Binary: binary, Timeout: timeout, NoSandbox: config.Get("pdf.no_sandbox", false).(bool)}
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownDriver, name)
+	}
+	return defaultDriver, nil
+}
+
+// SetDefault replaces the configured driver.
+func SetDefault(d Driver) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultDriver = d
+}
+
+// Render writes component as a PDF to w.
+func Render(ctx context.Context, component templ.Component, o *Options, w io.Writer) error {
+	var html bytes.Buffer
+	if err := component.Render(ctx, &html); err != nil {
+		return err
+	}
+	return RenderHTML(ctx, html.Bytes(), o, w)
+}
+
+// RenderHTML writes an HTML document as a PDF to w.
+func RenderHTML(ctx context.Context, html []byte, o *Options, w io.Writer) error {
+	d, err := Default()
+	if err != nil {
+		return err
+	}
+	if o == nil {
+		o = &Options{}
+	}
+	if o.BaseURL != "" {
+		html = withBase(html, o.BaseURL)
+	}
+	return d.Render(ctx, html, o, w)
+}
+
+// Download renders component and sends it as an attachment. The PDF is built
+// before anything is written, so a failure still gets a proper error response.
+func Download(c *app.Context, filename string, component templ.Component, o *Options) error {
+	return send(c, "attachment", filename, component, o)
+}
+
+// Inline renders component for display in the browser.
+func Inline(c *app.Context, filename string, component templ.Component, o *Options) error {
+	return send(c, "inline", filename, component, o)
+}
+
+func send(c *app.Context, disposition, filename string, component templ.Component, o *Options) error {
+	var out bytes.Buffer
+	if err := Render(c.RequestContext(), component, o, &out); err != nil {
+		return err
+	}
+
+	w := c.ResponseWriter()
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`%s; filename="%s"`, disposition, strings.ReplaceAll(filename, `"`, "")))
+	w.Header().Set("Content-Length", fmt.Sprint(out.Len()))
+	w.WriteHeader(http.StatusOK)
+	_, err := out.WriteTo(w)
+	return err
+}
+
+// Store renders component to path on a storage disk, the default one when
+// diskName is empty.
+func Store(ctx context.Context, diskName, path string, component templ.Component, o *Options) error {
+	var out bytes.Buffer
+	if err := Render(ctx, component, o, &out); err != nil {
+		return err
+	}
+
+	var names []string
+	if diskName != "" {
+		names = append(names, diskName)
+	}
+	disk, err := storage.Get(ctx, names...)
+	if err != nil {
+		return err
+	}
+	return disk.Write(path, out.Bytes())
+}
+
+// withBase adds a <base> element so relative URLs resolve against baseURL.
+func withBase(html []byte, baseURL string) []byte {
+	return withHead(html, []byte(`<base href="`+templ.EscapeString(baseURL)+`">`))
+}
+
+// withHead inserts extra at the start of the document's head.
+func withHead(doc, extra []byte) []byte {
+	if i := bytes.Index(bytes.ToLower(doc), []byte("<head>")); i >= 0 {
+		i += len("<head>")
+		return append(append(append([]byte(nil), doc[:i]...), extra...), doc[i:]...)
+	}
+	return append(append([]byte(nil), extra...), doc...)
+}
+
+// run executes a renderer, failing with its output when it exits with an error.
+func run(ctx context.Context, timeout time.Duration, stdin io.Reader, stdout io.Writer, name string, args ...string) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("pdf: %s is not installed: %w", name, err)
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("pdf: %s: %w", name, ctx.Err())
+		}
+		return fmt.Errorf("pdf: %s: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
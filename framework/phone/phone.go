@@ -0,0 +1,178 @@
+// Package phone parses phone numbers the way users type them into E.164,
+// the form SMS providers expect: a plus sign, the calling code and the
+// national number, without spaces.
+//
+//	n, err := phone.Parse("(415) 555-2671", "US", "CA")
+//	n.E164() // +14155552671
+//
+//	n, err = phone.Parse("01712-345678", "BD")
+//	n.E164() // +8801712345678
+//
+// Numbers written with a plus sign or the international prefix of a region
+// are read as international, others as national numbers of the regions
+// given, in order. The metadata covers the regions listed by Regions and
+// checks the length and leading digits of numbers, not whether they are
+// assigned.
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalid       = errors.New("phone: invalid number")
+	ErrUnknownRegion = errors.New("phone: unknown region")
+	// ErrRegion is returned for valid numbers outside the regions allowed.
+	ErrRegion = errors.New("phone: number outside the allowed regions")
+)
+
+// Number is a parsed phone number.
+type Number struct {
+	// Region is the ISO 3166 code of the country, the first matching one
+	// for calling codes shared by several.
+	Region      string
+	CallingCode string
+	// National is the national significant number, without trunk prefix.
+	National string
+}
+
+// E164 formats the number as +<calling code><national number>, or returns
+// "" for the zero Number.
+func (n Number) E164() string {
+	if n.National == "" {
+		return ""
+	}
+	return "+" + n.CallingCode + n.National
+}
+
+func (n Number) String() string {
+	return n.E164()
+}
+
+// Regions lists the ISO 3166 codes the package knows.
+func Regions() []string {
+	codes := make([]string, len(regions))
+	for i, rg := range regions {
+		codes[i] = rg.code
+	}
+	return codes
+}
+
+// Known reports whether the package knows a region.
+func Known(region string) bool {
+	_, ok := byCode[strings.ToUpper(region)]
+	return ok
+}
+
+// Parse reads raw, written with any spaces, dots, dashes, slashes and
+// parentheses. With regions, the number must belong to one of them; without,
+// it must be international.
+func Parse(raw string, regions ...string) (Number, error) {
+	allowed := make([]*region, 0, len(regions))
+	for _, code := range regions {
+		rg, ok := byCode[strings.ToUpper(code)]
+		if !ok {
+			return Number{}, fmt.Errorf("%w %q", ErrUnknownRegion, code)
+		}
+		allowed = append(allowed, rg)
+	}
+
+	digits, plus, ok := clean(raw)
+	if !ok {
+		return Number{}, ErrInvalid
+	}
+	if !plus {
+		for _, rg := range allowed {
+			if rest, found := strings.CutPrefix(digits, rg.intl); found && len(digits) > len(rg.intl)+7 {
+				digits, plus = rest, true
+				break
+			}
+		}
+	}
+
+	if plus {
+		n, err := international(digits)
+		if err != nil {
+			return Number{}, err
+		}
+		if len(allowed) == 0 {
+			return n, nil
+		}
+		for _, rg := range allowed {
+			if rg.calling == n.CallingCode && rg.pattern.MatchString(n.National) {
+				n.Region = rg.code
+				return n, nil
+			}
+		}
+		return Number{}, ErrRegion
+	}
+
+	for _, rg := range allowed {
+		if n, ok := national(digits, rg); ok {
+			return n, nil
+		}
+	}
+	return Number{}, ErrInvalid
+}
+
+// clean keeps the digits of raw and reports whether it started with a plus
+// sign. Letters and other symbols make it invalid.
+func clean(raw string) (digits string, plus, ok bool) {
+	raw = strings.TrimSpace(raw)
+	raw, plus = strings.CutPrefix(raw, "+")
+	var b strings.Builder
+	for _, c := range raw {
+		switch {
+		case c >= '0' && c <= '9':
+			b.WriteRune(c)
+		case strings.ContainsRune(" .-/()\u00a0", c):
+		default:
+			return "", false, false
+		}
+	}
+	digits = b.String()
+	return digits, plus, len(digits) >= 4 && len(digits) <= 17
+}
+
+// international splits digits into a calling code, which no other code is
+// a prefix of, and a national number valid in one of its regions.
+func international(digits string) (Number, error) {
+	for size := 1; size <= 3 && size < len(digits); size++ {
+		code, national := digits[:size], digits[size:]
+		matched := false
+		for _, rg := range regions {
+			if rg.calling != code {
+				continue
+			}
+			matched = true
+			if rg.pattern.MatchString(national) {
+				return Number{Region: rg.code, CallingCode: code, National: national}, nil
+			}
+		}
+		if matched {
+			return Number{}, ErrInvalid
+		}
+	}
+	return Number{}, ErrInvalid
+}
+
+// national reads digits as dialed within rg, with or without its trunk
+// prefix.
+func national(digits string, rg *region) (Number, bool) {
+	candidates := []string{digits}
+	if rest, ok := strings.CutPrefix(digits, rg.trunk); ok && rg.trunk != "" {
+		candidates = []string{rest, digits}
+	}
+	// A calling code typed without the plus sign.
+	if rest, ok := strings.CutPrefix(digits, rg.calling); ok {
+		candidates = append(candidates, rest)
+	}
+	for _, nsn := range candidates {
+		if rg.pattern.MatchString(nsn) {
+			return Number{Region: rg.code, CallingCode: rg.calling, National: nsn}, true
+		}
+	}
+	return Number{}, false
+}
@@ -0,0 +1,57 @@
+package phone
+
+import "regexp"
+
+// region describes the numbers of a country, after libphonenumber's general
+// descriptions: the pattern matches national significant numbers, those
+// without the trunk prefix dialed within the country.
+type region struct {
+	code    string
+	calling string
+	// trunk is the national prefix dropped from national numbers, such as
+	// the 0 of 020 7946 0018 in GB.
+	trunk string
+	// intl is the prefix dialed before a calling code from the region.
+	intl    string
+	pattern *regexp.Regexp
+}
+
+func r(code, calling, trunk, intl, pattern string) *region {
+	return &region{code: code, calling: calling, trunk: trunk, intl: intl, pattern: regexp.MustCompile(`^(?:` + pattern + `)$`)}
+}
+
+var regions = []*region{
+	r("US", "1", "1", "011", `[2-9]\d{2}[2-9]\d{6}`),
+	r("CA", "1", "1", "011", `[2-9]\d{2}[2-9]\d{6}`),
+	r("MX", "52", "", "00", `[1-9]\d{9}`),
+	r("BR", "55", "0", "00", `[1-9]{2}\d{8,9}`),
+	r("GB", "44", "0", "00", `[1-9]\d{8,9}`),
+	r("IE", "353", "0", "00", `[1-9]\d{6,9}`),
+	r("FR", "33", "0", "00", `[1-9]\d{8}`),
+	r("DE", "49", "0", "00", `[1-9]\d{5,13}`),
+	r("NL", "31", "0", "00", `[1-9]\d{8}`),
+	r("ES", "34", "", "00", `[5-9]\d{8}`),
+	r("IT", "39", "", "00", `0\d{5,10}|3\d{8,9}`),
+	r("SE", "46", "0", "00", `[1-9]\d{6,9}`),
+	r("ZA", "27", "0", "00", `[1-8]\d{8}`),
+	r("NG", "234", "0", "009", `[1-9]\d{7,9}`),
+	r("KE", "254", "0", "000", `[1-9]\d{7,8}`),
+	r("EG", "20", "0", "00", `1\d{9}|[2-9]\d{7,8}`),
+	r("AE", "971", "0", "00", `[2-9]\d{7,8}`),
+	r("IN", "91", "0", "00", `[1-9]\d{9}`),
+	r("PK", "92", "0", "00", `[1-9]\d{8,9}`),
+	r("BD", "880", "0", "00", `1[3-9]\d{8}|[2-9]\d{6,9}`),
+	r("CN", "86", "0", "00", `1[3-9]\d{9}|[2-9]\d{8,10}`),
+	r("JP", "81", "0", "010", `[1-9]\d{8,9}`),
+	r("SG", "65", "", "000", `[3689]\d{7}`),
+	r("AU", "61", "0", "0011", `[2-478]\d{8}`),
+	r("NZ", "64", "0", "00", `[2-9]\d{7,9}`),
+}
+
+var byCode = func() map[string]*region {
+	m := make(map[string]*region, len(regions))
+	for _, rg := range regions {
+		m[rg.code] = rg
+	}
+	return m
+}()
@@ -0,0 +1,69 @@
+package privacy
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/lemmego/lemmego/framework/repo"
+	"gorm.io/gorm"
+)
+
+// Audited actions.
+const (
+	ActionExport         = "export"
+	ActionErase          = "erase"
+	ActionConsentGranted = "consent.granted"
+	ActionConsentRevoked = "consent.revoked"
+)
+
+// Audit is an entry of the privacy_audits table, recording who exported or
+// erased a subject's data, or changed their consents.
+type Audit struct {
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	Subject string `json:"subject"`
+	Action  string `json:"action"`
+	Actor   string `json:"actor"`
+	// Details is JSON: row counts per model, or the consent purpose.
+	Details   string    `json:"details"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (Audit) TableName() string {
+	return "privacy_audits"
+}
+
+type actorKey struct{}
+
+// WithActor names who acts in the audit entries written with ctx, e.g.
+// "user:42" for a self-service request or "admin:7". Queued jobs carry the
+// actor of the context they were dispatched with.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actorOf(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return "system"
+}
+
+func record(tx *gorm.DB, subject, action string, details any) error {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&Audit{
+		Subject:   subject,
+		Action:    action,
+		Actor:     actorOf(tx.Statement.Context),
+		Details:   string(data),
+		CreatedAt: time.Now(),
+	}).Error
+}
+
+// History returns the audit entries of subject, oldest first.
+func History(ctx context.Context, subject string) ([]Audit, error) {
+	return repo.New[Audit](ctx).Where("subject = ?", subject).Order("id").Find()
+}
@@ -0,0 +1,72 @@
+package privacy
+
+import (
+	"context"
+	"time"
+
+	"github.com/lemmego/lemmego/framework/repo"
+	"gorm.io/gorm"
+)
+
+// Consent is whether a subject agreed to a purpose, e.g. "newsletter" or
+// "analytics", in the privacy_consents table. Every change is audited.
+type Consent struct {
+	ID        uint       `gorm:"primaryKey" json:"-"`
+	Subject   string     `json:"-"`
+	Purpose   string     `json:"purpose"`
+	Granted   bool       `json:"granted"`
+	GrantedAt *time.Time `json:"granted_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func (Consent) TableName() string {
+	return "privacy_consents"
+}
+
+// Grant records that subject consents to purpose.
+func Grant(ctx context.Context, subject, purpose string) error {
+	return setConsent(ctx, subject, purpose, true)
+}
+
+// Revoke records that subject withdrew their consent to purpose.
+func Revoke(ctx context.Context, subject, purpose string) error {
+	return setConsent(ctx, subject, purpose, false)
+}
+
+func setConsent(ctx context.Context, subject, purpose string, granted bool) error {
+	return repo.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		c := Consent{Subject: subject, Purpose: purpose}
+		if err := tx.Where("subject = ? AND purpose = ?", subject, purpose).Limit(1).Find(&c).Error; err != nil {
+			return err
+		}
+		if c.ID != 0 && c.Granted == granted {
+			return nil
+		}
+
+		now := time.Now()
+		c.Granted = granted
+		action := ActionConsentGranted
+		if granted {
+			c.GrantedAt, c.RevokedAt = &now, nil
+		} else {
+			c.RevokedAt = &now
+			action = ActionConsentRevoked
+		}
+		if err := tx.Save(&c).Error; err != nil {
+			return err
+		}
+		return record(tx, subject, action, map[string]string{"purpose": purpose})
+	})
+}
+
+// HasConsent reports whether subject currently consents to purpose. Purposes
+// never asked about are not consented to.
+func HasConsent(ctx context.Context, subject, purpose string) (bool, error) {
+	return repo.New[Consent](ctx).Exists("subject = ? AND purpose = ? AND granted = ?", subject, purpose, true)
+}
+
+// Consents returns every consent subject has given or withdrawn.
+func Consents(ctx context.Context, subject string) ([]Consent, error) {
+	return repo.New[Consent](ctx).Where("subject = ?", subject).Order("purpose").Find()
+}
@@ -0,0 +1,130 @@
+package privacy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/lemmego/lemmego/framework/queue"
+	"github.com/lemmego/lemmego/framework/repo"
+	"gorm.io/gorm"
+)
+
+func init() {
+	queue.Register[EraseJob]("privacy.erase")
+}
+
+// Erasure reports what Erase did.
+type Erasure struct {
+	Subject string
+	// Sections counts the rows anonymized or deleted per registered model.
+	Sections map[string]int
+}
+
+// Erase anonymizes or deletes the subject's rows of every registered model
+// and drops their consents, in one transaction. The audit trail is kept, it
+// holds keys and counts only.
+func Erase(ctx context.Context, subject string) (*Erasure, error) {
+	e := &Erasure{Subject: subject, Sections: map[string]int{}}
+	err := repo.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, s := range registered() {
+			n, err := s.erase(tx, subject)
+			if err != nil {
+				return fmt.Errorf("privacy: erasing %s: %w", s.name, err)
+			}
+			e.Sections[s.name] = n
+		}
+		if err := tx.Where("subject = ?", subject).Delete(&Consent{}).Error; err != nil {
+			return err
+		}
+		return record(tx, subject, ActionErase, e.Sections)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func eraseRows[T any](tx *gorm.DB, name string, o Options, fields []field, subject string) (int, error) {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(new(T)); err != nil {
+		return 0, err
+	}
+	pk := stmt.Schema.PrioritizedPrimaryField
+	if pk == nil {
+		return 0, fmt.Errorf("%s has no primary key", stmt.Schema.Name)
+	}
+
+	var columns []string
+	for _, f := range fields {
+		if f.strategy != Keep {
+			columns = append(columns, f.column)
+		}
+	}
+
+	count := 0
+	var batch []T
+	err := rows[T](tx, o, subject).FindInBatches(&batch, chunkSize, func(*gorm.DB, int) error {
+		count += len(batch)
+		if o.Delete {
+			return tx.Unscoped().Delete(&batch).Error
+		}
+		if len(columns) == 0 {
+			return nil
+		}
+		for i := range batch {
+			rv := reflect.ValueOf(&batch[i]).Elem()
+			key, _ := pk.ValueOf(tx.Statement.Context, rv)
+			for _, f := range fields {
+				anonymize(rv.FieldByIndex(f.index), f.strategy, fmt.Sprintf("%s-%v@erased.invalid", name, key))
+			}
+			if err := tx.Unscoped().Model(&batch[i]).Select(columns).Updates(&batch[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	return count, err
+}
+
+// anonymize overwrites a field according to its strategy.
+func anonymize(v reflect.Value, strategy, email string) {
+	var replacement string
+	switch strategy {
+	case Keep:
+		return
+	case Redact:
+		replacement = Redacted
+	case Email:
+		replacement = email
+	}
+
+	if replacement != "" {
+		if v.Kind() == reflect.String {
+			v.SetString(replacement)
+			return
+		}
+		if v.Kind() == reflect.Pointer && v.Type().Elem().Kind() == reflect.String && !v.IsNil() {
+			v.Set(reflect.ValueOf(&replacement))
+			return
+		}
+	}
+	v.Set(reflect.Zero(v.Type()))
+}
+
+// DispatchErasure queues the erasure of subject.
+func DispatchErasure(ctx context.Context, subject string) error {
+	_, err := queue.Dispatch(ctx, &EraseJob{Subject: subject, Actor: actorOf(ctx)})
+	return err
+}
+
+// EraseJob erases a subject's data on a worker.
+type EraseJob struct {
+	Subject string `json:"subject"`
+	Actor   string `json:"actor"`
+}
+
+func (j *EraseJob) Handle(ctx context.Context) error {
+	_, err := Erase(WithActor(ctx, j.Actor), j.Subject)
+	return err
+}
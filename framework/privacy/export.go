@@ -0,0 +1,140 @@
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"regexp"
+	"time"
+
+	"github.com/lemmego/lemmego/framework/queue"
+	"github.com/lemmego/lemmego/framework/repo"
+	"github.com/lemmego/lemmego/framework/storage"
+	"gorm.io/gorm"
+)
+
+// chunkSize is the number of rows loaded per query.
+const chunkSize = 500
+
+func init() {
+	queue.Register[ExportJob]("privacy.export")
+}
+
+// Manifest describes an export bundle.
+type Manifest struct {
+	Subject     string    `json:"subject"`
+	GeneratedAt time.Time `json:"generated_at"`
+	// Sections counts the rows exported per registered model.
+	Sections map[string]int `json:"sections"`
+}
+
+// Export writes everything held about subject to w as a ZIP bundle holding
+// manifest.json, a JSON file per registered model and consents.json.
+func Export(ctx context.Context, subject string, w io.Writer) (*Manifest, error) {
+	tx := repo.DB(ctx)
+	m := &Manifest{Subject: subject, GeneratedAt: time.Now().UTC(), Sections: map[string]int{}}
+
+	zw := zip.NewWriter(w)
+	for _, s := range registered() {
+		rows, err := s.export(tx, subject)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeJSON(zw, s.name+".json", rows); err != nil {
+			return nil, err
+		}
+		m.Sections[s.name] = len(rows)
+	}
+
+	consents, err := Consents(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeJSON(zw, "consents.json", consents); err != nil {
+		return nil, err
+	}
+	if err := writeJSON(zw, "manifest.json", m); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return m, record(tx, subject, ActionExport, m.Sections)
+}
+
+func writeJSON(zw *zip.Writer, name string, v any) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func exportRows[T any](tx *gorm.DB, o Options, fields []field, subject string) ([]map[string]any, error) {
+	out := []map[string]any{}
+	var batch []T
+	err := rows[T](tx, o, subject).FindInBatches(&batch, chunkSize, func(*gorm.DB, int) error {
+		for i := range batch {
+			rv := reflect.ValueOf(&batch[i]).Elem()
+			row := make(map[string]any, len(fields))
+			for _, f := range fields {
+				row[f.name] = rv.FieldByIndex(f.index).Interface()
+			}
+			out = append(out, row)
+		}
+		return nil
+	}).Error
+	return out, err
+}
+
+var unsafePath = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// ExportPath is where ExportToDisk writes the bundle of subject.
+func ExportPath(subject string, at time.Time) string {
+	return "privacy/" + unsafePath.ReplaceAllString(subject, "_") + "-" + at.UTC().Format("20060102150405") + ".zip"
+}
+
+// ExportToDisk writes the bundle of subject to path on a storage disk, the
+// default one when empty, to be handed to the user.
+func ExportToDisk(ctx context.Context, subject, disk, path string) (*Manifest, error) {
+	var buf bytes.Buffer
+	m, err := Export(ctx, subject, &buf)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	if disk != "" {
+		names = append(names, disk)
+	}
+	d, err := storage.Get(ctx, names...)
+	if err != nil {
+		return nil, err
+	}
+	return m, d.Write(path, buf.Bytes())
+}
+
+// DispatchExport queues the export of subject and returns the path its bundle
+// will be written to.
+func DispatchExport(ctx context.Context, subject, disk string) (string, error) {
+	path := ExportPath(subject, time.Now())
+	_, err := queue.Dispatch(ctx, &ExportJob{Subject: subject, Disk: disk, Path: path, Actor: actorOf(ctx)})
+	return path, err
+}
+
+// ExportJob writes an export bundle on a worker.
+type ExportJob struct {
+	Subject string `json:"subject"`
+	Disk    string `json:"disk"`
+	Path    string `json:"path"`
+	Actor   string `json:"actor"`
+}
+
+func (j *ExportJob) Handle(ctx context.Context) error {
+	_, err := ExportToDisk(WithActor(ctx, j.Actor), j.Subject, j.Disk, j.Path)
+	return err
+}
@@ -0,0 +1,175 @@
+// Package privacy answers data subject requests: it exports everything held
+// about a user as a bundle, erases it by anonymizing or deleting their rows,
+// tracks consents, and keeps an audit trail of all three.
+//
+// Models declare which fields are personal data with the personal tag, whose
+// value says what erasure does to the field:
+//
+//	type User struct {
+//		ID        uint
+//		Name      string  `personal:"redact"` // replaced by "[redacted]"
+//		Email     string  `personal:"email"`  // replaced by a unique, undeliverable address
+//		Phone     *string `personal:""`       // set to its zero value, NULL for pointers
+//		Country   string  `personal:"keep"`   // exported but kept on erasure
+//		CreatedAt time.Time
+//	}
+//
+// and are registered with the column linking their rows to the subject:
+//
+//	privacy.Register[User]("account", privacy.Options{Owner: "id"})
+//	privacy.Register[Comment]("comments", privacy.Options{Owner: "user_id", Delete: true})
+//
+// Subjects are identified by the string form of the user's key.
+package privacy
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lemmego/lemmego/framework/repo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Erasure strategies, the values of the personal tag.
+const (
+	Clear  = ""
+	Redact = "redact"
+	Email  = "email"
+	Keep   = "keep"
+)
+
+// Redacted replaces the string fields erased with Redact.
+const Redacted = "[redacted]"
+
+// Options of a registered model.
+type Options struct {
+	// Owner is the column holding the subject's key, "id" for the users
+	// table itself.
+	Owner string
+	// Delete removes the subject's rows on erasure instead of anonymizing
+	// their personal fields.
+	Delete bool
+}
+
+// section is a registered model, type-erased.
+type section struct {
+	name    string
+	options Options
+	export  func(tx *gorm.DB, subject string) ([]map[string]any, error)
+	erase   func(tx *gorm.DB, subject string) (int, error)
+}
+
+var (
+	sectionsMu sync.RWMutex
+	sections   = make(map[string]*section)
+)
+
+// field is a personal field of a model.
+type field struct {
+	index    []int
+	name     string
+	column   string
+	strategy string
+}
+
+// Register declares that T holds personal data of the subject whose key is in
+// the Owner column. name titles the model's rows in export bundles.
+func Register[T any](name string, o Options) {
+	if o.Owner == "" {
+		panic(fmt.Sprintf("privacy: %s needs an owner column", name))
+	}
+	fields, err := personalFields(reflect.TypeFor[T]())
+	if err != nil {
+		panic(err)
+	}
+
+	sectionsMu.Lock()
+	defer sectionsMu.Unlock()
+	sections[name] = &section{
+		name:    name,
+		options: o,
+		export: func(tx *gorm.DB, subject string) ([]map[string]any, error) {
+			return exportRows[T](tx, o, fields, subject)
+		},
+		erase: func(tx *gorm.DB, subject string) (int, error) {
+			return eraseRows[T](tx, name, o, fields, subject)
+		},
+	}
+}
+
+// Sections lists the registered names.
+func Sections() []string {
+	sectionsMu.RLock()
+	defer sectionsMu.RUnlock()
+	names := make([]string, 0, len(sections))
+	for name := range sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func registered() []*section {
+	sectionsMu.RLock()
+	defer sectionsMu.RUnlock()
+	list := make([]*section, 0, len(sections))
+	for _, s := range sections {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	return list
+}
+
+// personalFields reads the personal tags of a model type.
+func personalFields(t reflect.Type) ([]field, error) {
+	s, err := schema.Parse(reflect.New(t).Interface(), &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []field
+	for _, sf := range reflect.VisibleFields(t) {
+		strategy, ok := sf.Tag.Lookup("personal")
+		if !ok || !sf.IsExported() {
+			continue
+		}
+		switch strategy {
+		case Clear, Redact, Email, Keep:
+		default:
+			return nil, fmt.Errorf("privacy: %s.%s: unknown strategy %q", t.Name(), sf.Name, strategy)
+		}
+		if strategy == Email && sf.Type != reflect.TypeFor[string]() && sf.Type != reflect.TypeFor[*string]() {
+			return nil, fmt.Errorf("privacy: %s.%s: email needs a string field", t.Name(), sf.Name)
+		}
+		f := field{index: sf.Index, name: jsonName(sf), strategy: strategy}
+		if sch := s.LookUpField(sf.Name); sch != nil {
+			f.column = sch.DBName
+		}
+		if f.column == "" {
+			return nil, fmt.Errorf("privacy: %s.%s is not a column", t.Name(), sf.Name)
+		}
+		fields = append(fields, f)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("privacy: %s has no personal fields", t.Name())
+	}
+	return fields, nil
+}
+
+func jsonName(sf reflect.StructField) string {
+	if name, _, _ := strings.Cut(sf.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	return sf.Name
+}
+
+// rows selects the subject's rows of T, including those hidden by global
+// scopes or soft deleted, which hold personal data all the same.
+func rows[T any](tx *gorm.DB, o Options, subject string) *gorm.DB {
+	q := repo.From[T](tx).WithoutGlobalScope().Query().Unscoped()
+	return q.Where(q.Statement.Quote(o.Owner)+" = ?", subject)
+}
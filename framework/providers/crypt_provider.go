@@ -0,0 +1,23 @@
+package providers
+
+import (
+	"errors"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/crypt"
+)
+
+func init() {
+	app.RegisterService(func(a app.App) error {
+		enc, err := crypt.Default()
+		if errors.Is(err, crypt.ErrMissingKey) {
+			// Encrypted casts fail until APP_KEY is set, everything else works without it
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		a.AddService(enc)
+		return nil
+	})
+}
@@ -0,0 +1,73 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/config"
+	"github.com/lemmego/api/db"
+	"github.com/lemmego/lemmego/framework/reload"
+	"github.com/lemmego/lemmego/framework/repo"
+)
+
+func init() {
+	app.BootService(func(a app.App) error {
+		repo.RegisterPoolMetrics()
+
+		if name, _ := a.Config().Get("database.replica", "").(string); name != "" && !db.DM().HasConnection(name) {
+			if err := openReplica(a, name); err != nil {
+				// Reads fall back to the default connection
+				slog.Error(fmt.Sprintf("database: open replica %s: %s", name, err))
+			}
+		}
+
+		// The framework only sizes the default connection, and only once
+		tunePools := func() {
+			for name := range db.DM().All() {
+				key := fmt.Sprintf("database.connections.%s.", name)
+				maxOpen, _ := a.Config().Get(key+"max_open_conns", 0).(int)
+				maxIdle, _ := a.Config().Get(key+"max_idle_conns", 0).(int)
+				maxLifetime, _ := a.Config().Get(key+"conn_max_lifetime", time.Duration(0)).(time.Duration)
+
+				err := repo.TunePool(name, repo.PoolOptions{MaxOpen: maxOpen, MaxIdle: maxIdle, MaxLifetime: maxLifetime})
+				if err != nil {
+					slog.Error(fmt.Sprintf("database: tune pool %s: %s", name, err))
+				}
+			}
+		}
+		tunePools()
+		reload.On(tunePools)
+
+		if !a.RunningInConsole() {
+			if interval, _ := a.Config().Get("database.failover_check", time.Duration(0)).(time.Duration); interval > 0 {
+				go repo.WatchPrimary(context.Background(), interval)
+			}
+		}
+		return nil
+	})
+}
+
+// openReplica opens the connection database.replica names, configured like
+// the default one under database.connections.
+func openReplica(a app.App, name string) error {
+	conn, ok := a.Config().Get("database.connections."+name, nil).(config.M)
+	if !ok {
+		return fmt.Errorf("no connection named %s", name)
+	}
+	str := func(key string) string { s, _ := conn[key].(string); return s }
+
+	dbConfig := &db.Config{ConnName: name, Driver: str("driver"), Database: str("database")}
+	if dbConfig.Driver != db.DialectSQLite {
+		dbConfig.Host, dbConfig.User, dbConfig.Password, dbConfig.Params = str("host"), str("user"), str("password"), str("params")
+		dbConfig.Port, _ = conn["port"].(int)
+	}
+	c, err := db.NewConnection(dbConfig).Open()
+	if err != nil {
+		return err
+	}
+	db.AddConnection(c)
+	return nil
+}
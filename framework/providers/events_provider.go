@@ -0,0 +1,60 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/events"
+	"github.com/lemmego/lemmego/framework/kafka"
+	"github.com/lemmego/lemmego/framework/nats"
+	"github.com/lemmego/lemmego/framework/ws"
+)
+
+func init() {
+	app.BootService(func(a app.App) error {
+		var broker events.Broker
+		url := a.Config().Get("events.url", "").(string)
+		switch name := a.Config().Get("events.broker", "none").(string); name {
+		case "none":
+			return nil
+		case "nats":
+			broker = nats.NewBroker(nats.NewClient(url, nil))
+		case "kafka":
+			broker = kafka.NewBroker(kafka.NewClient(url))
+		default:
+			return fmt.Errorf("events: unknown broker %q", name)
+		}
+
+		topic := a.Config().Get("events.topic", "app.events").(string)
+		var names []string
+		for _, name := range strings.Split(a.Config().Get("events.forward", "").(string), ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		events.Forward(broker, topic, names...)
+		// Every web instance delivers the WebSocket messages of its connections
+		ws.Publish(broker, topic+".ws")
+
+		if a.RunningInConsole() {
+			return nil
+		}
+		ctx, _ := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		go func() {
+			if err := events.Receive(ctx, broker, topic, a.Config().Get("events.group", "web").(string)); err != nil {
+				slog.Error(fmt.Sprintf("events: receiving from %s: %s", topic, err))
+			}
+		}()
+		go func() {
+			if err := ws.Receive(ctx, broker, topic+".ws"); err != nil {
+				slog.Error(fmt.Sprintf("ws: receiving from %s.ws: %s", topic, err))
+			}
+		}()
+		return nil
+	})
+}
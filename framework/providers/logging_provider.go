@@ -0,0 +1,16 @@
+package providers
+
+import (
+	"log/slog"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/logs"
+)
+
+func init() {
+	app.RegisterService(func(a app.App) error {
+		// slog.Info and friends write to the default channel
+		slog.SetDefault(logs.Default())
+		return nil
+	})
+}
@@ -0,0 +1,22 @@
+package providers
+
+import (
+	"context"
+	"time"
+
+	"github.com/lemmego/api/app"
+
+	"github.com/lemmego/lemmego/framework/metrics"
+)
+
+func init() {
+	app.BootService(func(a app.App) error {
+		if a.RunningInConsole() || !a.Config().Get("server.metrics.enabled", false).(bool) {
+			return nil
+		}
+		if every := a.Config().Get("server.metrics.runtime_interval", 0).(int); every > 0 {
+			go metrics.RecordRuntime(context.Background(), time.Duration(every)*time.Second)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,26 @@
+package providers
+
+import (
+	"context"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/outbox"
+)
+
+func init() {
+	app.BootService(func(a app.App) error {
+		if a.RunningInConsole() || !a.Config().Get("outbox.relay", true).(bool) {
+			return nil
+		}
+
+		ctx, _ := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		go outbox.Relay(ctx, &outbox.RelayOptions{
+			Connection: a.Config().Get("outbox.connection", "").(string),
+			Interval:   time.Duration(a.Config().Get("outbox.interval", 1).(int)) * time.Second,
+		})
+		return nil
+	})
+}
@@ -0,0 +1,115 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/amqp"
+	"github.com/lemmego/lemmego/framework/kafka"
+	"github.com/lemmego/lemmego/framework/nats"
+	"github.com/lemmego/lemmego/framework/queue"
+)
+
+func init() {
+	app.RegisterService(func(a app.App) error {
+		var conn []string
+		if c := a.Config().Get("queue.connection", "").(string); c != "" {
+			conn = append(conn, c)
+		}
+
+		var driver queue.Driver
+		name := a.Config().Get("queue.driver", "sync").(string)
+		switch name {
+		case "sync":
+			driver = queue.NewSyncDriver()
+		case "memory":
+			driver = queue.NewMemoryDriver()
+		case "database":
+			driver = queue.NewDatabaseDriver(conn...)
+		case "nats":
+			client := nats.NewClient(a.Config().Get("queue.nats_url", "").(string), nil)
+			driver = queue.NewNATSDriver(client, a.Config().Get("queue.nats_stream", "JOBS").(string))
+		case "kafka":
+			driver = queue.NewKafkaDriver(
+				kafka.NewClient(a.Config().Get("queue.kafka_url", "").(string)),
+				a.Config().Get("queue.kafka_prefix", "jobs").(string),
+				a.Config().Get("queue.kafka_group", "workers").(string),
+			)
+		case "rabbitmq":
+			d := queue.NewAMQPDriver(amqp.NewClient(a.Config().Get("queue.amqp_url", "").(string), nil))
+			d.Prefetch = a.Config().Get("queue.amqp_prefetch", 10).(int)
+			driver = d
+		case "sqs":
+			cfg := aws.NewConfig().WithRegion(a.Config().Get("queue.sqs_region", "us-east-1").(string))
+			if key := a.Config().Get("queue.sqs_key", "").(string); key != "" {
+				cfg = cfg.WithCredentials(credentials.NewStaticCredentials(key, a.Config().Get("queue.sqs_secret", "").(string), ""))
+			}
+			if endpoint := a.Config().Get("queue.sqs_endpoint", "").(string); endpoint != "" {
+				cfg = cfg.WithEndpoint(endpoint)
+			}
+			sess, err := session.NewSession(cfg)
+			if err != nil {
+				return err
+			}
+			d := queue.NewSQSDriver(sqs.New(sess), a.Config().Get("queue.sqs_prefix", "").(string))
+			d.VisibilityTimeout = time.Duration(a.Config().Get("queue.sqs_visibility", 60).(int)) * time.Second
+			driver = d
+		default:
+			return fmt.Errorf("queue: unknown driver %q", name)
+		}
+
+		q := queue.New(
+			driver,
+			a.Config().Get("queue.max_attempts", 3).(int),
+			time.Duration(a.Config().Get("queue.backoff", 10).(int))*time.Second,
+		)
+		queue.SetDefault(q)
+		a.AddService(q)
+
+		progress := a.Config().Get("queue.progress", "").(string)
+		if progress == "" {
+			// The sync and memory drivers run jobs in the process dispatching them
+			progress = "cache"
+			if name != "sync" && name != "memory" && a.Config().Get("queue.workers", 0).(int) < 1 {
+				progress = "database"
+			}
+		}
+		switch progress {
+		case "cache":
+			queue.SetProgressStore(queue.CacheProgressStore{})
+		case "database":
+			queue.SetProgressStore(queue.NewDatabaseProgressStore(conn...))
+		default:
+			return fmt.Errorf("queue: unknown progress store %q", progress)
+		}
+		return nil
+	})
+
+	app.BootService(func(a app.App) error {
+		workers := a.Config().Get("queue.workers", 0).(int)
+		if a.RunningInConsole() || workers < 1 {
+			return nil
+		}
+
+		queues, weights, err := queue.ParseQueues(a.Config().Get("queue.queues", "").(string))
+		if err != nil {
+			return err
+		}
+
+		ctx, _ := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		go queue.Default().Work(ctx, &queue.WorkOptions{
+			Queues:      queues,
+			Weights:     weights,
+			Concurrency: workers,
+		})
+		return nil
+	})
+}
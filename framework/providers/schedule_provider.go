@@ -0,0 +1,51 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/schedule"
+)
+
+func init() {
+	app.RegisterService(func(a app.App) error {
+		o := &schedule.RunOptions{TTL: time.Duration(a.Config().Get("schedule.ttl", 15).(int)) * time.Second}
+		switch name := a.Config().Get("schedule.lease", "database").(string); name {
+		case "none":
+		case "database":
+			var conn []string
+			if c := a.Config().Get("schedule.connection", "").(string); c != "" {
+				conn = append(conn, c)
+			}
+			o.Lease = schedule.NewDatabaseLease(conn...)
+		case "redis":
+			addr := fmt.Sprintf("%s:%d", a.Config().Get("redis.connections.default.host").(string), a.Config().Get("redis.connections.default.port").(int))
+			password := a.Config().Get("redis.connections.default.password", "").(string)
+			o.Lease = schedule.NewRedisLease(&redis.Pool{
+				MaxIdle: 2,
+				Dial: func() (redis.Conn, error) {
+					return redis.Dial("tcp", addr, redis.DialPassword(password))
+				},
+			})
+		default:
+			return fmt.Errorf("schedule: unknown lease %q", name)
+		}
+		schedule.Configure(o)
+		return nil
+	})
+
+	app.BootService(func(a app.App) error {
+		if a.RunningInConsole() || !a.Config().Get("schedule.in_process", false).(bool) {
+			return nil
+		}
+
+		ctx, _ := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		go schedule.Run(ctx, nil)
+		return nil
+	})
+}
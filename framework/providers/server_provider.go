@@ -0,0 +1,130 @@
+package providers
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/config"
+	"github.com/lemmego/lemmego/framework/middleware"
+	"github.com/lemmego/lemmego/framework/server"
+)
+
+func init() {
+	app.BootService(func(a app.App) error {
+		if a.RunningInConsole() {
+			return nil
+		}
+
+		if internalPaths := splitList(a.Config().Get("server.internal_paths", "").(string)); len(internalPaths) > 0 {
+			a.Router().Use(server.HideInternal(internalPaths...))
+		}
+
+		listeners, _ := a.Config().Get("server.listeners").(config.M)
+		tlsEnabled := a.Config().Get("server.tls.enabled", false).(bool)
+		gracefulRestart := a.Config().Get("server.graceful_restart", false).(bool)
+		if len(listeners) == 0 && !tlsEnabled && !gracefulRestart {
+			return nil
+		}
+
+		srv := server.New(a)
+
+		if gracefulRestart {
+			// The framework binds APP_PORT itself and that socket cannot be handed to a new
+			// process, so serve the port from an inheritable listener and move the framework
+			// to a free one
+			port := a.Config().Get("app.port", 8080).(int)
+			if err := srv.Listen(&server.Listener{Name: "http", Address: fmt.Sprintf(":%d", port)}); err != nil {
+				return err
+			}
+			free, err := freePort()
+			if err != nil {
+				return err
+			}
+			a.Config().Set("app.port", free)
+		}
+
+		for name, value := range listeners {
+			l, ok := value.(config.M)
+			if !ok {
+				return fmt.Errorf("server: listener %s must be a config map", name)
+			}
+
+			listener := &server.Listener{Name: name}
+			listener.Network, _ = l["network"].(string)
+			listener.Address, _ = l["address"].(string)
+			listener.Internal, _ = l["internal"].(bool)
+			if paths, ok := l["paths"].(string); ok {
+				listener.Paths = splitList(paths)
+			}
+
+			if err := srv.Listen(listener); err != nil {
+				return err
+			}
+		}
+
+		if tlsEnabled {
+			tlsConfig, manager, err := server.NewTLSConfig(&server.TLSOptions{
+				CertFile: a.Config().Get("server.tls.cert_file", "").(string),
+				KeyFile:  a.Config().Get("server.tls.key_file", "").(string),
+				Domains:  splitList(a.Config().Get("server.tls.autocert_domains", "").(string)),
+				Email:    a.Config().Get("server.tls.autocert_email", "").(string),
+				CacheDir: a.Config().Get("server.tls.autocert_cache", "").(string),
+
+				ClientCAFile: a.Config().Get("server.tls.client_ca_file", "").(string),
+			})
+			if err != nil {
+				return err
+			}
+
+			port := strconv.Itoa(a.Config().Get("server.tls.port", 8443).(int))
+			if a.Config().Get("server.tls.redirect_http", true).(bool) {
+				// Registered after the routes of the application, so after TrustProxies,
+				// and in a Chain, so that the Chains registered before it stay compiled
+				redirect := middleware.Chain(server.RedirectHTTPS(port, manager, middleware.FromTrustedProxy))
+				routes, ok := a.(interface {
+					WithRoutes(app.RouteCallback) app.Bootstrapper
+				})
+				if !ok {
+					return fmt.Errorf("server: cannot register the HTTPS redirect with a %T", a)
+				}
+				routes.WithRoutes(func(r app.Router) { r.Use(redirect) })
+			}
+
+			if err := srv.ServeTLS(fmt.Sprintf(":%s", port), tlsConfig, a.Config().Get("server.tls.http3", false).(bool)); err != nil {
+				return err
+			}
+		}
+
+		a.AddService(srv)
+		go srv.ShutdownOnSignal()
+		if gracefulRestart {
+			go srv.RestartOnSignal()
+		}
+		srv.ServeWhenMounted(a.Config().Get("app.port", 8080).(int))
+		return nil
+	})
+}
+
+// freePort returns a port nothing listens on, for the framework to listen on.
+func freePort() (int, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port, nil
+}
+
+// splitList splits a comma separated config value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
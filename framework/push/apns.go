@@ -0,0 +1,175 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lemmego/lemmego/framework/httpclient"
+)
+
+// apnsTokenAge is how long a provider token is used. APNs rejects tokens
+// older than an hour, and refreshing them more often than every 20 minutes.
+const apnsTokenAge = 50 * time.Minute
+
+// APNsOptions configures an APNs driver.
+type APNsOptions struct {
+	// KeyFile is the .p8 signing key made in the Apple developer account.
+	KeyFile string
+	KeyID   string
+	TeamID  string
+	// Topic is the bundle id of the app.
+	Topic string
+	// Production sends to apps of the App Store and TestFlight, instead of
+	// the development ones.
+	Production bool
+}
+
+// APNs sends through the Apple Push Notification service, authenticated by
+// a token signed with the key of the team.
+type APNs struct {
+	KeyID  string
+	TeamID string
+	Topic  string
+	// BaseURL defaults to the production or development host of APNs.
+	BaseURL string
+
+	key    *ecdsa.PrivateKey
+	mu     sync.Mutex
+	token  string
+	issued time.Time
+}
+
+// NewAPNs returns an APNs driver signing with the key of o.KeyFile.
+func NewAPNs(o *APNsOptions) (*APNs, error) {
+	if o.KeyFile == "" || o.KeyID == "" || o.TeamID == "" || o.Topic == "" {
+		return nil, errors.New("push: apns needs a key file, key id, team id and topic, see push.apns")
+	}
+	data, err := os.ReadFile(o.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("push: the apns key file is not PEM")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("push: the apns key is not an ECDSA key")
+	}
+	a := &APNs{KeyID: o.KeyID, TeamID: o.TeamID, Topic: o.Topic, key: key, BaseURL: "https://api.sandbox.push.apple.com"}
+	if o.Production {
+		a.BaseURL = "https://api.push.apple.com"
+	}
+	return a, nil
+}
+
+func (a *APNs) Send(ctx context.Context, d *Device, n *Notification) error {
+	token, err := a.providerToken()
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(apnsPayload(n))
+	if err != nil {
+		return err
+	}
+	// HTTP/2, which APNs requires, is negotiated by the default transport
+	req, err := http.NewRequest(http.MethodPost, a.BaseURL+"/3/device/"+url.PathEscape(d.Token), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("apns-topic", a.Topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+	req.Header.Set("apns-expiration", strconv.FormatInt(time.Now().Add(n.ttl()).Unix(), 10))
+	if n.Collapse != "" {
+		req.Header.Set("apns-collapse-id", n.Collapse)
+	}
+	resp, err := httpclient.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var e struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&e)
+	switch {
+	case resp.StatusCode == http.StatusGone, e.Reason == "BadDeviceToken", e.Reason == "Unregistered", e.Reason == "DeviceTokenNotForTopic":
+		return fmt.Errorf("%w: apns answered %s", ErrInvalidToken, e.Reason)
+	case e.Reason == "PayloadTooLarge":
+		return fmt.Errorf("%w: apns answered %s", ErrPayloadTooLarge, e.Reason)
+	case e.Reason == "ExpiredProviderToken", e.Reason == "InvalidProviderToken":
+		a.mu.Lock()
+		a.token = ""
+		a.mu.Unlock()
+	}
+	return fmt.Errorf("push: apns answered %s: %s", resp.Status, e.Reason)
+}
+
+// providerToken returns the token authenticating requests, signed again
+// when it gets old.
+func (a *APNs) providerToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && time.Since(a.issued) < apnsTokenAge {
+		return a.token, nil
+	}
+	now := time.Now()
+	token, err := signJWT(
+		map[string]any{"alg": "ES256", "kid": a.KeyID},
+		map[string]any{"iss": a.TeamID, "iat": now.Unix()},
+		es256(a.key),
+	)
+	if err != nil {
+		return "", err
+	}
+	a.token, a.issued = token, now
+	return token, nil
+}
+
+// apnsPayload returns the payload of n, its data and url beside aps.
+func apnsPayload(n *Notification) map[string]any {
+	aps := map[string]any{"alert": map[string]string{"title": n.Title, "body": n.Body}}
+	if n.Badge != nil {
+		aps["badge"] = *n.Badge
+	}
+	if n.Sound != "" {
+		aps["sound"] = n.Sound
+	}
+	p := map[string]any{}
+	for k, v := range n.Data {
+		p[k] = v
+	}
+	if n.URL != "" {
+		p["url"] = n.URL
+	}
+	p["aps"] = aps
+	return p
+}
+
+func apnsSize(n *Notification) (int, error) {
+	b, err := json.Marshal(apnsPayload(n))
+	return len(b), err
+}
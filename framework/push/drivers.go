@@ -0,0 +1,68 @@
+package push
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// Log writes notifications to the log instead of sending them, for
+// development.
+type Log struct{}
+
+func (Log) Send(ctx context.Context, d *Device, n *Notification) error {
+	slog.Info(fmt.Sprintf("push: to %s device %d of %s: %s: %s", d.Platform, d.ID, d.UserID, n.Title, n.Body))
+	return nil
+}
+
+// Sent is a notification kept by a Fake.
+type Sent struct {
+	Device       Device
+	Notification Notification
+}
+
+// Fake keeps the notifications instead of sending them, for tests:
+//
+//	fake := &push.Fake{}
+//	push.SetDriver(push.PlatformFCM, fake)
+//	...
+//	sent := fake.Sent()
+type Fake struct {
+	// Err fails every notification when set.
+	Err error
+	// Invalid are the tokens reported invalid, as if the app was removed.
+	Invalid []string
+
+	mu   sync.Mutex
+	sent []Sent
+}
+
+func (f *Fake) Send(ctx context.Context, d *Device, n *Notification) error {
+	if f.Err != nil {
+		return f.Err
+	}
+	for _, t := range f.Invalid {
+		if t == d.Token {
+			return ErrInvalidToken
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, Sent{Device: *d, Notification: *n})
+	return nil
+}
+
+// Sent returns the notifications sent so far.
+func (f *Fake) Sent() []Sent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Sent(nil), f.sent...)
+}
+
+// Reset forgets the notifications sent.
+func (f *Fake) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = nil
+}
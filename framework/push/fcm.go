@@ -0,0 +1,231 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lemmego/lemmego/framework/httpclient"
+)
+
+// fcmScope is the OAuth scope of the FCM HTTP v1 API.
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// FCM sends through the HTTP v1 API of Firebase Cloud Messaging, as the
+// service account of a Firebase project.
+type FCM struct {
+	ProjectID   string
+	ClientEmail string
+	// TokenURL is where access tokens are exchanged, that of the service
+	// account.
+	TokenURL string
+	// BaseURL defaults to https://fcm.googleapis.com.
+	BaseURL string
+
+	key     *rsa.PrivateKey
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewFCM returns an FCM driver for the service account whose JSON key is
+// at credentialsFile, as downloaded from the Firebase console.
+func NewFCM(credentialsFile string) (*FCM, error) {
+	if credentialsFile == "" {
+		return nil, errors.New("push: fcm needs the key of a service account, see push.fcm.credentials")
+	}
+	data, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, err
+	}
+	var account struct {
+		ProjectID   string `json:"project_id"`
+		PrivateKey  string `json:"private_key"`
+		ClientEmail string `json:"client_email"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return nil, errors.New("push: the fcm service account has no private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("push: the private key of the fcm service account is not an RSA key")
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	return &FCM{ProjectID: account.ProjectID, ClientEmail: account.ClientEmail, TokenURL: account.TokenURI, key: key}, nil
+}
+
+func (f *FCM) Send(ctx context.Context, d *Device, n *Notification) error {
+	token, err := f.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	message := fcmMessage(n)
+	message["token"] = d.Token
+	body, err := json.Marshal(map[string]any{"message": message})
+	if err != nil {
+		return err
+	}
+
+	base := f.BaseURL
+	if base == "" {
+		base = "https://fcm.googleapis.com"
+	}
+	req, err := http.NewRequest(http.MethodPost, base+"/v1/projects/"+url.PathEscape(f.ProjectID)+"/messages:send", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := httpclient.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var e struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&e)
+	code := e.Error.Status
+	for _, detail := range e.Error.Details {
+		if detail.ErrorCode != "" {
+			code = detail.ErrorCode
+		}
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound, code == "UNREGISTERED", code == "SENDER_ID_MISMATCH":
+		return fmt.Errorf("%w: fcm answered %s", ErrInvalidToken, code)
+	case resp.StatusCode == http.StatusUnauthorized:
+		// Revoked or expired early, the next call gets another
+		f.mu.Lock()
+		f.token = ""
+		f.mu.Unlock()
+	}
+	return fmt.Errorf("push: fcm answered %s: %s %s", resp.Status, code, e.Error.Message)
+}
+
+// accessToken returns an access token of the service account, exchanging
+// a signed assertion for one when the last is about to expire.
+func (f *FCM) accessToken(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.token != "" && time.Until(f.expires) > time.Minute {
+		return f.token, nil
+	}
+
+	now := time.Now()
+	assertion, err := signJWT(
+		map[string]any{"alg": "RS256", "typ": "JWT"},
+		map[string]any{
+			"iss":   f.ClientEmail,
+			"scope": fcmScope,
+			"aud":   f.TokenURL,
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+		},
+		rs256(f.key),
+	)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	resp, err := httpclient.Post(ctx, f.TokenURL, "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("push: fcm token exchange answered %s", resp.Status)
+	}
+	var out struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", err
+	}
+	f.token, f.expires = out.AccessToken, now.Add(time.Duration(out.ExpiresIn)*time.Second)
+	return f.token, nil
+}
+
+// fcmMessage returns the message of n, but for the token.
+func fcmMessage(n *Notification) map[string]any {
+	data := map[string]string{}
+	for k, v := range n.Data {
+		data[k] = v
+	}
+	if n.URL != "" {
+		data["url"] = n.URL
+	}
+	ttl := n.ttl()
+
+	android := map[string]any{"ttl": strconv.Itoa(int(ttl.Seconds())) + "s"}
+	if n.Collapse != "" {
+		android["collapse_key"] = n.Collapse
+	}
+	if n.Sound != "" {
+		android["notification"] = map[string]any{"sound": n.Sound}
+	}
+
+	aps := map[string]any{}
+	if n.Badge != nil {
+		aps["badge"] = *n.Badge
+	}
+	if n.Sound != "" {
+		aps["sound"] = n.Sound
+	}
+	headers := map[string]string{"apns-expiration": strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)}
+	if n.Collapse != "" {
+		headers["apns-collapse-id"] = n.Collapse
+	}
+
+	m := map[string]any{
+		"notification": map[string]string{"title": n.Title, "body": n.Body},
+		"android":      android,
+		"apns":         map[string]any{"headers": headers, "payload": map[string]any{"aps": aps}},
+	}
+	if len(data) > 0 {
+		m["data"] = data
+	}
+	return m
+}
+
+func fcmSize(n *Notification) (int, error) {
+	b, err := json.Marshal(fcmMessage(n))
+	return len(b), err
+}
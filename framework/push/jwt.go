@@ -0,0 +1,53 @@
+package push
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+)
+
+// signJWT returns the token of claims, signed by sign with the algorithm
+// named in header.
+func signJWT(header, claims map[string]any, sign func(digest []byte) ([]byte, error)) (string, error) {
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	digest := sha256.Sum256([]byte(unsigned))
+	sig, err := sign(digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// rs256 signs with RSASSA-PKCS1-v1_5 and SHA-256.
+func rs256(key *rsa.PrivateKey) func(digest []byte) ([]byte, error) {
+	return func(digest []byte) ([]byte, error) {
+		return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest)
+	}
+}
+
+// es256 signs with ECDSA P-256 and SHA-256, the signature being r and s
+// padded to 32 bytes each rather than ASN.1.
+func es256(key *ecdsa.PrivateKey) func(digest []byte) ([]byte, error) {
+	return func(digest []byte) ([]byte, error) {
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest)
+		if err != nil {
+			return nil, err
+		}
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return sig, nil
+	}
+}
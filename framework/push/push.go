@@ -0,0 +1,578 @@
+// Package push sends notifications to the devices of users: apps through
+// Firebase Cloud Messaging or APNs, and browsers through Web Push. Devices
+// register their token, or subscription for browsers, and may subscribe to
+// topics:
+//
+//	err := push.SendToUser(ctx, user.ID, &push.Notification{Title: "New message", Body: msg.Preview, URL: "/inbox/" + msg.ID})
+//
+// Tokens the provider reports gone are marked invalid when sending, and
+// their devices deleted by a queued job.
+package push
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/lemmego/api/config"
+	"gorm.io/gorm"
+
+	"github.com/lemmego/lemmego/framework/metrics"
+	"github.com/lemmego/lemmego/framework/queue"
+	"github.com/lemmego/lemmego/framework/repo"
+)
+
+func init() {
+	queue.Register[SendJob]("push.send")
+	queue.Register[PruneJob]("push.prune")
+}
+
+// Platforms of devices, each sent to through its own provider.
+const (
+	PlatformFCM     = "fcm"
+	PlatformAPNs    = "apns"
+	PlatformWebPush = "webpush"
+)
+
+var (
+	ErrUnknownDriver   = errors.New("push: unknown driver")
+	ErrUnknownPlatform = errors.New("push: unknown platform")
+	ErrUnknownDevice   = errors.New("push: unknown device")
+	ErrInvalidDevice   = errors.New("push: invalid device")
+	ErrInvalidTopic    = errors.New("push: invalid topic")
+	// ErrInvalidToken is returned by drivers for tokens the provider no
+	// longer delivers to, such as those of uninstalled apps.
+	ErrInvalidToken = errors.New("push: invalid token")
+	// ErrPayloadTooLarge is returned for notifications above the limit of a
+	// provider, 4 KB for each.
+	ErrPayloadTooLarge = errors.New("push: payload too large")
+)
+
+var sent = metrics.Counter("push_sent_total", "Push notifications sent, by platform and result.")
+
+// concurrency bounds the notifications being sent at once by a call.
+const concurrency = 10
+
+// limits are the payload sizes providers accept, by platform.
+var limits = map[string]int{
+	PlatformFCM:     4096,
+	PlatformAPNs:    4096,
+	PlatformWebPush: recordSize - 86 - 16 - 1,
+}
+
+// Notification is what devices show, the same on every platform.
+type Notification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	// URL is opened when the notification is clicked, a path of the app or
+	// site.
+	URL string `json:"url,omitempty"`
+	// Badge is the count shown on the app icon, left as is when nil.
+	Badge *int `json:"badge,omitempty"`
+	// Sound is the sound played by apps, "default" for the system's.
+	Sound string `json:"sound,omitempty"`
+	// Data is given to the app or service worker, not shown.
+	Data map[string]string `json:"data,omitempty"`
+	// Collapse replaces a notification of the same key not yet delivered.
+	Collapse string `json:"collapse,omitempty"`
+	// TTL is how long providers keep the notification for offline devices,
+	// a day when zero.
+	TTL time.Duration `json:"ttl,omitempty"`
+}
+
+func (n *Notification) ttl() time.Duration {
+	if n.TTL <= 0 {
+		return 24 * time.Hour
+	}
+	return n.TTL
+}
+
+// Validate checks the notification fits the payload limit of every
+// provider, so none is sent to only some of the devices.
+func (n *Notification) Validate() error {
+	if n.Title == "" && n.Body == "" {
+		return errors.New("push: a notification needs a title or a body")
+	}
+	for platform, size := range map[string]func() (int, error){
+		PlatformFCM:     func() (int, error) { return fcmSize(n) },
+		PlatformAPNs:    func() (int, error) { return apnsSize(n) },
+		PlatformWebPush: func() (int, error) { return webPushSize(n) },
+	} {
+		got, err := size()
+		if err != nil {
+			return err
+		}
+		if limit := limits[platform]; got > limit {
+			return fmt.Errorf("%w: %d bytes for %s, at most %d", ErrPayloadTooLarge, got, platform, limit)
+		}
+	}
+	return nil
+}
+
+// Device is an app install or browser a user receives notifications on.
+type Device struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	UserID   string `json:"-"`
+	Platform string `json:"platform"`
+	// Token is the registration token of FCM, the device token of APNs, or
+	// the endpoint of a Web Push subscription.
+	Token string `json:"-"`
+	// P256dh and Auth are the keys of a Web Push subscription.
+	P256dh string `gorm:"column:p256dh" json:"-"`
+	Auth   string `json:"-"`
+	Name   string `json:"name"`
+	// InvalidAt is set once the provider reports the token gone, until the
+	// device is deleted.
+	InvalidAt *time.Time `json:"-"`
+	// LastSeenAt is refreshed by every registration, apps registering as
+	// they start.
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (Device) TableName() string {
+	return "push_devices"
+}
+
+// Subscription subscribes a device to a topic.
+type Subscription struct {
+	ID        uint `gorm:"primaryKey"`
+	DeviceID  uint
+	Topic     string
+	CreatedAt time.Time
+}
+
+func (Subscription) TableName() string {
+	return "push_subscriptions"
+}
+
+// Driver delivers notifications to devices of the platforms it serves.
+type Driver interface {
+	Send(ctx context.Context, d *Device, n *Notification) error
+}
+
+var (
+	mu      sync.Mutex
+	drivers = map[string]Driver{}
+)
+
+// driverFor returns the driver of platform: the provider of the platform,
+// built from push.<platform>, or the log with the log and fake drivers of
+// push.driver.
+func driverFor(platform string) (Driver, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if d := drivers[platform]; d != nil {
+		return d, nil
+	}
+	if platform != PlatformFCM && platform != PlatformAPNs && platform != PlatformWebPush {
+		return nil, fmt.Errorf("%w %q", ErrUnknownPlatform, platform)
+	}
+
+	var (
+		d   Driver
+		err error
+	)
+	switch name := config.Get("push.driver", "log").(string); name {
+	case "log":
+		d = &Log{}
+	case "fake":
+		d = &Fake{}
+	case "providers":
+		switch platform {
+		case PlatformFCM:
+			d, err = NewFCM(config.Get("push.fcm.credentials", "").(string))
+		case PlatformAPNs:
+			d, err = NewAPNs(&APNsOptions{
+				KeyFile:    config.Get("push.apns.key_file", "").(string),
+				KeyID:      config.Get("push.apns.key_id", "").(string),
+				TeamID:     config.Get("push.apns.team_id", "").(string),
+				Topic:      config.Get("push.apns.topic", "").(string),
+				Production: config.Get("push.apns.production", false).(bool),
+			})
+		case PlatformWebPush:
+			d, err = NewWebPush(
+				config.Get("push.webpush.public_key", "").(string),
+				config.Get("push.webpush.private_key", "").(string),
+				config.Get("push.webpush.subject", "").(string),
+			)
+		}
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownDriver, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	drivers[platform] = d
+	return d, nil
+}
+
+// SetDriver replaces the driver of platform, e.g. with a Fake in tests.
+func SetDriver(platform string, d Driver) {
+	mu.Lock()
+	defer mu.Unlock()
+	drivers[platform] = d
+}
+
+// Register records a device of the user, or refreshes it when its token is
+// known, moving it to the user when another one had it.
+func Register(ctx context.Context, userID string, d *Device) (*Device, error) {
+	if err := validateDevice(d); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	err := repo.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		devices := repo.From[Device](tx)
+		existing, err := devices.Where("token = ?", d.Token).Limit(1).Find()
+		if err != nil {
+			return err
+		}
+		if len(existing) == 0 {
+			d.ID, d.UserID, d.InvalidAt, d.LastSeenAt, d.CreatedAt = 0, userID, nil, now, now
+			return devices.Create(d)
+		}
+
+		e := existing[0]
+		if e.UserID != userID {
+			// The topics of the previous user are not the new one's
+			if _, err := repo.From[Subscription](tx).Delete("device_id = ?", e.ID); err != nil {
+				return err
+			}
+		}
+		e.UserID, e.Platform, e.P256dh, e.Auth, e.InvalidAt, e.LastSeenAt = userID, d.Platform, d.P256dh, d.Auth, nil, now
+		if d.Name != "" {
+			e.Name = d.Name
+		}
+		*d = e
+		return devices.Save(d)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func validateDevice(d *Device) error {
+	if d.Token == "" || len(d.Token) > 512 {
+		return fmt.Errorf("%w: a token of at most 512 characters is required", ErrInvalidDevice)
+	}
+	switch d.Platform {
+	case PlatformFCM:
+	case PlatformAPNs:
+		if _, err := hex.DecodeString(d.Token); err != nil {
+			return fmt.Errorf("%w: the token of an apns device must be hexadecimal", ErrInvalidDevice)
+		}
+	case PlatformWebPush:
+		u, err := url.Parse(d.Token)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("%w: the endpoint of a web push subscription must be an https URL", ErrInvalidDevice)
+		}
+		if d.P256dh == "" || d.Auth == "" {
+			return fmt.Errorf("%w: a web push subscription needs its p256dh and auth keys", ErrInvalidDevice)
+		}
+	default:
+		return fmt.Errorf("%w: unknown platform %q", ErrInvalidDevice, d.Platform)
+	}
+	return nil
+}
+
+// Unregister deletes a device of the user, with its subscriptions.
+func Unregister(ctx context.Context, userID string, id uint) error {
+	return repo.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		n, err := repo.From[Device](tx).Delete("id = ? AND user_id = ?", id, userID)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrUnknownDevice
+		}
+		_, err = repo.From[Subscription](tx).Delete("device_id = ?", id)
+		return err
+	})
+}
+
+// Devices returns the devices of a user, the most recently seen first.
+func Devices(ctx context.Context, userID string) ([]Device, error) {
+	return repo.New[Device](ctx).Where("user_id = ? AND invalid_at IS NULL", userID).Order("last_seen_at DESC").Find()
+}
+
+// DeviceOf returns a device of the user.
+func DeviceOf(ctx context.Context, userID string, id uint) (*Device, error) {
+	d, err := repo.New[Device](ctx).Where("id = ? AND user_id = ?", id, userID).Limit(1).Find()
+	if err != nil {
+		return nil, err
+	}
+	if len(d) == 0 {
+		return nil, ErrUnknownDevice
+	}
+	return &d[0], nil
+}
+
+var topicPattern = regexp.MustCompile(`^[a-zA-Z0-9_.~%-]{1,100}$`)
+
+var (
+	publicMu     sync.RWMutex
+	publicTopics = map[string]bool{}
+)
+
+// PublicTopic lets users subscribe their devices to the topics themselves,
+// through the account routes. Other topics are subscribed to by the app.
+func PublicTopic(topics ...string) {
+	publicMu.Lock()
+	defer publicMu.Unlock()
+	for _, t := range topics {
+		publicTopics[t] = true
+	}
+}
+
+// IsPublic reports whether users may subscribe to topic themselves.
+func IsPublic(topic string) bool {
+	publicMu.RLock()
+	defer publicMu.RUnlock()
+	return publicTopics[topic]
+}
+
+// Subscribe subscribes a device to topics, those it already is to being
+// skipped.
+func Subscribe(ctx context.Context, deviceID uint, topics ...string) error {
+	rows := make([]Subscription, 0, len(topics))
+	for _, t := range topics {
+		if !topicPattern.MatchString(t) {
+			return fmt.Errorf("%w %q", ErrInvalidTopic, t)
+		}
+		rows = append(rows, Subscription{DeviceID: deviceID, Topic: t, CreatedAt: time.Now()})
+	}
+	_, err := repo.New[Subscription](ctx).InsertIgnore(rows, 0)
+	return err
+}
+
+// Unsubscribe unsubscribes a device from topics.
+func Unsubscribe(ctx context.Context, deviceID uint, topics ...string) error {
+	if len(topics) == 0 {
+		return nil
+	}
+	_, err := repo.New[Subscription](ctx).Delete("device_id = ? AND topic IN ?", deviceID, topics)
+	return err
+}
+
+// Topics returns the topics a device is subscribed to.
+func Topics(ctx context.Context, deviceID uint) ([]string, error) {
+	subs, err := repo.New[Subscription](ctx).Where("device_id = ?", deviceID).Order("topic").Find()
+	if err != nil {
+		return nil, err
+	}
+	topics := make([]string, len(subs))
+	for i, s := range subs {
+		topics[i] = s.Topic
+	}
+	return topics, nil
+}
+
+// SendToUser sends n to every device of a user.
+func SendToUser(ctx context.Context, userID string, n *Notification) error {
+	_, err := sendToUser(ctx, userID, n)
+	return err
+}
+
+// SendToTopic sends n to every device subscribed to topic.
+func SendToTopic(ctx context.Context, topic string, n *Notification) error {
+	_, err := sendToTopic(ctx, topic, n)
+	return err
+}
+
+// SendToDevices sends n to devices. Devices whose token is invalid are
+// marked and queued for deletion rather than failing the call, which
+// returns the other failures.
+func SendToDevices(ctx context.Context, devices []Device, n *Notification) error {
+	_, err := sendToDevices(ctx, devices, n)
+	return err
+}
+
+func sendToUser(ctx context.Context, userID string, n *Notification) ([]uint, error) {
+	devices, err := repo.New[Device](ctx).Where("user_id = ? AND invalid_at IS NULL", userID).Find()
+	if err != nil {
+		return nil, err
+	}
+	return sendToDevices(ctx, devices, n)
+}
+
+// topicBatch is how many devices of a topic are loaded at once.
+const topicBatch = 500
+
+func sendToTopic(ctx context.Context, topic string, n *Notification) ([]uint, error) {
+	if err := n.Validate(); err != nil {
+		return nil, err
+	}
+	var (
+		failed []uint
+		errs   []error
+		after  uint
+	)
+	for {
+		devices, err := repo.New[Device](ctx).
+			Where("id > ? AND invalid_at IS NULL AND id IN (SELECT device_id FROM push_subscriptions WHERE topic = ?)", after, topic).
+			Order("id").Limit(topicBatch).Find()
+		if err != nil {
+			return failed, errors.Join(append(errs, err)...)
+		}
+		if len(devices) == 0 {
+			break
+		}
+		f, err := sendToDevices(ctx, devices, n)
+		failed, errs = append(failed, f...), append(errs, err)
+		after = devices[len(devices)-1].ID
+	}
+	return failed, errors.Join(errs...)
+}
+
+// sendToDevices returns the ids of the devices n failed to be sent to, for
+// retries.
+func sendToDevices(ctx context.Context, devices []Device, n *Notification) ([]uint, error) {
+	if err := n.Validate(); err != nil {
+		return nil, err
+	}
+	errs := make([]error, len(devices))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range devices {
+		d, err := driverFor(devices[i].Platform)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			errs[i] = d.Send(ctx, &devices[i], n)
+		}(i)
+	}
+	wg.Wait()
+
+	var (
+		invalid, failed []uint
+		failures        []error
+	)
+	for i, err := range errs {
+		result := "sent"
+		switch {
+		case errors.Is(err, ErrInvalidToken):
+			result = "invalid"
+			invalid = append(invalid, devices[i].ID)
+		case err != nil:
+			result = "failed"
+			failed = append(failed, devices[i].ID)
+			failures = append(failures, fmt.Errorf("push: device %d: %w", devices[i].ID, err))
+		}
+		sent.With(metrics.Labels{"platform": devices[i].Platform, "result": result}).Inc()
+	}
+	if len(invalid) > 0 {
+		failures = append(failures, forget(ctx, invalid))
+	}
+	return failed, errors.Join(failures...)
+}
+
+// forget marks devices invalid, no longer sending to them, and queues their
+// deletion.
+func forget(ctx context.Context, ids []uint) error {
+	if _, err := repo.New[Device](ctx).Where("id IN ?", ids).Update(map[string]any{"invalid_at": time.Now()}); err != nil {
+		return err
+	}
+	_, err := queue.Dispatch(ctx, &PruneJob{DeviceIDs: ids})
+	return err
+}
+
+// Queue sends n to the devices of a user from a worker.
+func Queue(ctx context.Context, userID string, n *Notification, opts ...*queue.Options) error {
+	if err := n.Validate(); err != nil {
+		return err
+	}
+	_, err := queue.Dispatch(ctx, &SendJob{UserID: userID, Notification: *n}, opts...)
+	return err
+}
+
+// QueueTopic sends n to the devices subscribed to topic from a worker.
+func QueueTopic(ctx context.Context, topic string, n *Notification, opts ...*queue.Options) error {
+	if err := n.Validate(); err != nil {
+		return err
+	}
+	_, err := queue.Dispatch(ctx, &SendJob{Topic: topic, Notification: *n}, opts...)
+	return err
+}
+
+// SendJob sends a queued notification to the devices of a user, of a topic,
+// or of ids. Devices it fails to be sent to are retried by a job of their
+// ids, so the others do not get it twice.
+type SendJob struct {
+	UserID       string       `json:"user_id,omitempty"`
+	Topic        string       `json:"topic,omitempty"`
+	DeviceIDs    []uint       `json:"device_ids,omitempty"`
+	Notification Notification `json:"notification"`
+}
+
+func (j *SendJob) Handle(ctx context.Context) error {
+	var (
+		failed []uint
+		err    error
+	)
+	switch {
+	case len(j.DeviceIDs) > 0:
+		devices, err := repo.New[Device](ctx).Where("id IN ? AND invalid_at IS NULL", j.DeviceIDs).Find()
+		if err != nil {
+			return err
+		}
+		_, err = sendToDevices(ctx, devices, &j.Notification)
+		return err
+	case j.Topic != "":
+		failed, err = sendToTopic(ctx, j.Topic, &j.Notification)
+	default:
+		failed, err = sendToUser(ctx, j.UserID, &j.Notification)
+	}
+	if len(failed) == 0 {
+		return err
+	}
+	slog.Warn(fmt.Sprintf("push: retrying %d devices: %v", len(failed), err))
+	_, err = queue.Dispatch(ctx, &SendJob{DeviceIDs: failed, Notification: j.Notification}, &queue.Options{Delay: time.Minute})
+	return err
+}
+
+// PruneJob deletes devices marked invalid, with their subscriptions. Those
+// registered again meanwhile are kept.
+type PruneJob struct {
+	DeviceIDs []uint `json:"device_ids"`
+}
+
+func (j *PruneJob) Handle(ctx context.Context) error {
+	return repo.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		ids := tx.Model(&Device{}).Select("id").Where("id IN ? AND invalid_at IS NOT NULL", j.DeviceIDs)
+		if _, err := repo.From[Subscription](tx).Delete("device_id IN (?)", ids); err != nil {
+			return err
+		}
+		_, err := repo.From[Device](tx).Delete("id IN ? AND invalid_at IS NOT NULL", j.DeviceIDs)
+		return err
+	})
+}
+
+// Prune deletes the devices marked invalid, and those not seen for longer
+// than stale, such as apps not opened since.
+func Prune(ctx context.Context, stale time.Duration) (int64, error) {
+	var n int64
+	err := repo.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		where := "invalid_at IS NOT NULL OR last_seen_at < ?"
+		cutoff := time.Now().Add(-stale)
+		ids := tx.Model(&Device{}).Select("id").Where(where, cutoff)
+		if _, err := repo.From[Subscription](tx).Delete("device_id IN (?)", ids); err != nil {
+			return err
+		}
+		var err error
+		n, err = repo.From[Device](tx).Delete(where, cutoff)
+		return err
+	})
+	return n, err
+}
@@ -0,0 +1,238 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/lemmego/lemmego/framework/httpclient"
+)
+
+// recordSize is the size of the single record a message is encrypted in.
+// Push services accept at least 4096 bytes, the header, the tag and the
+// padding delimiter taking 103 of them.
+const recordSize = 4096
+
+// webPushTopic is what push services accept as a Topic header.
+var webPushTopic = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// WebPush sends to the subscriptions of browsers, as the application of
+// the VAPID key pair, and encrypts the payload for the subscription as
+// RFC 8291 requires. Service workers receive the notification as JSON.
+type WebPush struct {
+	// Subject is a mailto: or https: URL push services may contact.
+	Subject string
+
+	publicKey string
+	key       *ecdsa.PrivateKey
+}
+
+// NewWebPush returns a Web Push driver for the VAPID key pair, as made by
+// GenerateVAPIDKeys.
+func NewWebPush(publicKey, privateKey, subject string) (*WebPush, error) {
+	if publicKey == "" || privateKey == "" || subject == "" {
+		return nil, errors.New("push: web push needs a VAPID key pair and a subject, see push.webpush")
+	}
+	d, err := base64.RawURLEncoding.DecodeString(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("push: malformed VAPID private key: %w", err)
+	}
+	priv, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, err
+	}
+	pub := priv.PublicKey().Bytes()
+	if base64.RawURLEncoding.EncodeToString(pub) != publicKey {
+		return nil, errors.New("push: the VAPID public key is not the one of the private key")
+	}
+	key := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(pub[1:33]),
+			Y:     new(big.Int).SetBytes(pub[33:]),
+		},
+		D: new(big.Int).SetBytes(d),
+	}
+	return &WebPush{Subject: subject, publicKey: publicKey, key: key}, nil
+}
+
+// GenerateVAPIDKeys returns a new VAPID key pair, in the URL safe base64
+// browsers take the public key in.
+func GenerateVAPIDKeys() (publicKey, privateKey string, err error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()), base64.RawURLEncoding.EncodeToString(key.Bytes()), nil
+}
+
+// PublicKey returns the VAPID public key, the applicationServerKey of
+// browser subscriptions.
+func (w *WebPush) PublicKey() string {
+	return w.publicKey
+}
+
+func (w *WebPush) Send(ctx context.Context, d *Device, n *Notification) error {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	body, err := encrypt(payload, d.P256dh, d.Auth)
+	if err != nil {
+		return err
+	}
+	auth, err := w.authorization(d.Token)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, d.Token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", auth)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(n.ttl().Seconds())))
+	req.Header.Set("Urgency", "high")
+	if webPushTopic.MatchString(n.Collapse) {
+		req.Header.Set("Topic", n.Collapse)
+	}
+	resp, err := httpclient.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		return nil
+	case resp.StatusCode == http.StatusNotFound, resp.StatusCode == http.StatusGone:
+		return fmt.Errorf("%w: the push service answered %s", ErrInvalidToken, resp.Status)
+	case resp.StatusCode == http.StatusRequestEntityTooLarge:
+		return fmt.Errorf("%w: the push service answered %s", ErrPayloadTooLarge, resp.Status)
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("push: the push service answered %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+}
+
+// authorization returns the VAPID header for the push service of
+// endpoint, a token of the origin of the endpoint.
+func (w *WebPush) authorization(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	token, err := signJWT(
+		map[string]any{"typ": "JWT", "alg": "ES256"},
+		map[string]any{
+			"aud": u.Scheme + "://" + u.Host,
+			"exp": time.Now().Add(12 * time.Hour).Unix(),
+			"sub": w.Subject,
+		},
+		es256(w.key),
+	)
+	if err != nil {
+		return "", err
+	}
+	return "vapid t=" + token + ", k=" + w.publicKey, nil
+}
+
+// encrypt encrypts payload for the subscription of the keys p256dh and
+// auth, in a single aes128gcm record (RFC 8188 and RFC 8291).
+func encrypt(payload []byte, p256dh, auth string) ([]byte, error) {
+	uaPublic, err := decodeKey(p256dh)
+	if err != nil {
+		return nil, fmt.Errorf("push: malformed p256dh key: %w", err)
+	}
+	secret, err := decodeKey(auth)
+	if err != nil {
+		return nil, fmt.Errorf("push: malformed auth secret: %w", err)
+	}
+	ua, err := ecdh.P256().NewPublicKey(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+	as, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := as.ECDH(ua)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := as.PublicKey().Bytes()
+
+	// The key material mixes the shared secret with the auth secret and
+	// both public keys
+	info := append(append([]byte("WebPush: info\x00"), uaPublic...), asPublic...)
+	ikm := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, secret, info), ikm); err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	prk := hkdf.Extract(sha256.New, ikm, salt)
+	cek := make([]byte, 16)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, prk, []byte("Content-Encoding: aes128gcm\x00")), cek); err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, 12)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, prk, []byte("Content-Encoding: nonce\x00")), nonce); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// The last record ends with the delimiter 2, without padding
+	plain := append(append([]byte{}, payload...), 2)
+
+	header := make([]byte, 0, 86)
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, recordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+	if len(header)+len(plain)+gcm.Overhead() > recordSize {
+		return nil, fmt.Errorf("%w: %d bytes for webpush, at most %d", ErrPayloadTooLarge, len(payload), limits[PlatformWebPush])
+	}
+	return gcm.Seal(header, nonce, plain, nil), nil
+}
+
+// decodeKey decodes keys of subscriptions, URL safe base64 with or
+// without padding.
+func decodeKey(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+func webPushSize(n *Notification) (int, error) {
+	b, err := json.Marshal(n)
+	return len(b), err
+}
@@ -0,0 +1,256 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lemmego/lemmego/framework/amqp"
+)
+
+// AMQPDriver keeps jobs in durable RabbitMQ queues of the same names. Delayed
+// jobs wait in a queue per delay, <queue>.delay.<seconds>, whose messages
+// expire into the queue, the delay queues being deleted once unused. Jobs
+// are consumed Prefetch at a time per queue, the server handing those not
+// acknowledged out again when their connection breaks, or once they outlast
+// its consumer timeout. Jobs that ran out of attempts go to FailedQueue as
+// failed jobs.
+type AMQPDriver struct {
+	client *amqp.Client
+	// Prefetch is how many jobs of a queue a process holds at a time.
+	Prefetch int
+	// FailedQueue receives the jobs that ran out of attempts.
+	FailedQueue string
+
+	mu        sync.Mutex
+	conn      *amqp.Conn
+	declared  map[string]bool
+	consumers map[string]<-chan *amqp.Delivery
+	reserved  map[string]*amqp.Delivery
+}
+
+func NewAMQPDriver(client *amqp.Client) *AMQPDriver {
+	return &AMQPDriver{
+		client:      client,
+		Prefetch:    10,
+		FailedQueue: "failed_jobs",
+		reserved:    make(map[string]*amqp.Delivery),
+	}
+}
+
+// connection returns the connection, forgetting what was set up on the
+// previous one when it broke.
+func (d *AMQPDriver) connection(ctx context.Context) (*amqp.Conn, error) {
+	conn, err := d.client.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if conn != d.conn {
+		d.conn = conn
+		d.declared = make(map[string]bool)
+		d.consumers = make(map[string]<-chan *amqp.Delivery)
+	}
+	return conn, nil
+}
+
+func (d *AMQPDriver) declare(ctx context.Context, conn *amqp.Conn, queue string) error {
+	d.mu.Lock()
+	declared := d.declared[queue]
+	d.mu.Unlock()
+	if declared {
+		return nil
+	}
+	if err := conn.DeclareQueue(ctx, queue, true, nil); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.declared[queue] = true
+	d.mu.Unlock()
+	return nil
+}
+
+func (d *AMQPDriver) Push(ctx context.Context, env *Envelope) error {
+	conn, err := d.connection(ctx)
+	if err != nil {
+		return err
+	}
+	if err := d.declare(ctx, conn, env.Queue); err != nil {
+		return err
+	}
+
+	queue := env.Queue
+	if wait := time.Until(env.AvailableAt); wait > 0 {
+		// Delay queues are declared on every use, so they do not expire
+		// while messages wait in them
+		seconds := int64((wait + time.Second - 1) / time.Second)
+		queue = env.Queue + ".delay." + strconv.FormatInt(seconds, 10)
+		err := conn.DeclareQueue(ctx, queue, true, amqp.Table{
+			"x-message-ttl":             seconds * 1000,
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": env.Queue,
+			"x-expires":                 seconds*1000 + time.Minute.Milliseconds(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return d.publish(ctx, conn, queue, env.ID, env)
+}
+
+func (d *AMQPDriver) publish(ctx context.Context, conn *amqp.Conn, queue, id string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return conn.Publish(ctx, queue, &amqp.Message{
+		ContentType: "application/json",
+		MessageID:   id,
+		Persistent:  true,
+		Body:        data,
+	})
+}
+
+// consume returns the deliveries of queue, consuming it if need be.
+func (d *AMQPDriver) consume(ctx context.Context, conn *amqp.Conn, queue string) (<-chan *amqp.Delivery, error) {
+	d.mu.Lock()
+	deliveries, ok := d.consumers[queue]
+	d.mu.Unlock()
+	if ok {
+		return deliveries, nil
+	}
+	if err := d.declare(ctx, conn, queue); err != nil {
+		return nil, err
+	}
+	deliveries, err := conn.Consume(ctx, queue, d.Prefetch)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	d.consumers[queue] = deliveries
+	d.mu.Unlock()
+	return deliveries, nil
+}
+
+func (d *AMQPDriver) Pop(ctx context.Context, queues ...string) (*Envelope, error) {
+	conn, err := d.connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range queues {
+		deliveries, err := d.consume(ctx, conn, name)
+		if err != nil {
+			return nil, err
+		}
+		for {
+			delivery, ok := d.next(name, deliveries)
+			if !ok {
+				break
+			}
+			var env Envelope
+			if err := json.Unmarshal(delivery.Body, &env); err != nil {
+				slog.Error(fmt.Sprintf("queue: dropping malformed job on %s: %s", name, err))
+				_ = delivery.Reject(false)
+				continue
+			}
+			if time.Until(env.AvailableAt) > 0 {
+				if err := d.Push(ctx, &env); err != nil {
+					return nil, err
+				}
+				_ = delivery.Ack()
+				continue
+			}
+			d.mu.Lock()
+			d.reserved[env.ID] = delivery
+			d.mu.Unlock()
+			return &env, nil
+		}
+	}
+	return nil, nil
+}
+
+// next returns a delivery of queue if one is waiting.
+func (d *AMQPDriver) next(queue string, deliveries <-chan *amqp.Delivery) (*amqp.Delivery, bool) {
+	select {
+	case delivery, ok := <-deliveries:
+		if !ok {
+			// The queue was deleted, or the connection broke
+			d.mu.Lock()
+			if d.consumers[queue] == deliveries {
+				delete(d.consumers, queue)
+			}
+			d.mu.Unlock()
+		}
+		return delivery, ok
+	default:
+		return nil, false
+	}
+}
+
+// take returns the delivery env was reserved from.
+func (d *AMQPDriver) take(env *Envelope) (*amqp.Delivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delivery, ok := d.reserved[env.ID]
+	if !ok {
+		return nil, fmt.Errorf("queue: %s %s is not reserved", env.Job, env.ID)
+	}
+	delete(d.reserved, env.ID)
+	return delivery, nil
+}
+
+// Delete acknowledges env, failing when the connection it came from broke,
+// the server then handing it out again.
+func (d *AMQPDriver) Delete(ctx context.Context, env *Envelope) error {
+	delivery, err := d.take(env)
+	if err != nil {
+		return err
+	}
+	return delivery.Ack()
+}
+
+// Release publishes a copy of env due after delay.
+func (d *AMQPDriver) Release(ctx context.Context, env *Envelope, delay time.Duration) error {
+	delivery, err := d.take(env)
+	if err != nil {
+		return err
+	}
+	env.AvailableAt = time.Now().Add(delay)
+	if err := d.Push(ctx, env); err != nil {
+		_ = delivery.Reject(true)
+		return err
+	}
+	return delivery.Ack()
+}
+
+func (d *AMQPDriver) Fail(ctx context.Context, env *Envelope, cause error) error {
+	delivery, err := d.take(env)
+	if err != nil {
+		return err
+	}
+	conn, err := d.connection(ctx)
+	if err != nil {
+		return err
+	}
+	if err := d.declare(ctx, conn, d.FailedQueue); err != nil {
+		return err
+	}
+	failed := &FailedJob{
+		ID:        env.ID,
+		Queue:     env.Queue,
+		Job:       env.Job,
+		Payload:   string(env.Payload),
+		Attempts:  env.Attempts,
+		Exception: cause.Error(),
+		FailedAt:  time.Now(),
+	}
+	if err := d.publish(ctx, conn, d.FailedQueue, env.ID, failed); err != nil {
+		return err
+	}
+	return delivery.Ack()
+}
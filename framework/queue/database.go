@@ -0,0 +1,208 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lemmego/api/db"
+	"gorm.io/gorm"
+)
+
+// jobRow is a queued job in the jobs table.
+type jobRow struct {
+	ID          string `gorm:"primaryKey"`
+	Queue       string
+	Job         string
+	Payload     string
+	Attempts    int
+	MaxAttempts int
+	AvailableAt time.Time
+	ReservedAt  *time.Time
+	CreatedAt   time.Time
+}
+
+func (jobRow) TableName() string {
+	return "jobs"
+}
+
+// FailedJob is a job that ran out of attempts, kept in the failed_jobs table
+// by the database driver.
+type FailedJob struct {
+	ID        string    `gorm:"primaryKey" json:"id"`
+	Queue     string    `json:"queue"`
+	Job       string    `json:"job"`
+	Payload   string    `json:"payload"`
+	Attempts  int       `json:"attempts"`
+	Exception string    `json:"exception"`
+	FailedAt  time.Time `json:"failed_at"`
+}
+
+func (FailedJob) TableName() string {
+	return "failed_jobs"
+}
+
+// DatabaseDriver keeps jobs in the jobs table, so they survive restarts and
+// are shared by every worker process using the same database.
+type DatabaseDriver struct {
+	connName []string
+	// RetryAfter is how long a reserved job may run before it is considered
+	// abandoned by a crashed worker and handed out again.
+	RetryAfter time.Duration
+}
+
+func NewDatabaseDriver(connName ...string) *DatabaseDriver {
+	return &DatabaseDriver{connName: connName, RetryAfter: 10 * time.Minute}
+}
+
+func (d *DatabaseDriver) db(ctx context.Context) *gorm.DB {
+	return db.Get(d.connName...).DB().WithContext(ctx)
+}
+
+func (d *DatabaseDriver) Push(ctx context.Context, env *Envelope) error {
+	return d.db(ctx).Create(&jobRow{
+		ID:          env.ID,
+		Queue:       env.Queue,
+		Job:         env.Job,
+		Payload:     string(env.Payload),
+		Attempts:    env.Attempts,
+		MaxAttempts: env.MaxAttempts,
+		AvailableAt: env.AvailableAt,
+		CreatedAt:   time.Now(),
+	}).Error
+}
+
+func (d *DatabaseDriver) Pop(ctx context.Context, queues ...string) (*Envelope, error) {
+	now := time.Now()
+	expired := now.Add(-d.RetryAfter)
+
+	for _, name := range queues {
+		// Candidates are claimed with a conditional update, so concurrent
+		// workers never reserve the same row without needing row locks
+		var candidates []jobRow
+		err := d.db(ctx).
+			Where("queue = ? AND available_at <= ? AND (reserved_at IS NULL OR reserved_at <= ?)", name, now, expired).
+			Order("available_at").Limit(5).Find(&candidates).Error
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range candidates {
+			claim := d.db(ctx).Model(&jobRow{}).Where("id = ?", row.ID)
+			if row.ReservedAt == nil {
+				claim = claim.Where("reserved_at IS NULL")
+			} else {
+				claim = claim.Where("reserved_at = ?", *row.ReservedAt)
+			}
+			result := claim.Update("reserved_at", now)
+			if result.Error != nil {
+				return nil, result.Error
+			}
+			if result.RowsAffected == 1 {
+				return &Envelope{
+					ID:          row.ID,
+					Job:         row.Job,
+					Payload:     []byte(row.Payload),
+					Queue:       row.Queue,
+					Attempts:    row.Attempts,
+					MaxAttempts: row.MaxAttempts,
+					AvailableAt: row.AvailableAt,
+				}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (d *DatabaseDriver) Delete(ctx context.Context, env *Envelope) error {
+	return d.db(ctx).Where("id = ?", env.ID).Delete(&jobRow{}).Error
+}
+
+func (d *DatabaseDriver) Release(ctx context.Context, env *Envelope, delay time.Duration) error {
+	return d.db(ctx).Model(&jobRow{}).Where("id = ?", env.ID).Updates(map[string]any{
+		"attempts":     env.Attempts,
+		"available_at": time.Now().Add(delay),
+		"reserved_at":  nil,
+	}).Error
+}
+
+func (d *DatabaseDriver) Fail(ctx context.Context, env *Envelope, cause error) error {
+	return d.db(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", env.ID).Delete(&jobRow{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&FailedJob{
+			ID:        env.ID,
+			Queue:     env.Queue,
+			Job:       env.Job,
+			Payload:   string(env.Payload),
+			Attempts:  env.Attempts,
+			Exception: cause.Error(),
+			FailedAt:  time.Now(),
+		}).Error
+	})
+}
+
+func (d *DatabaseDriver) Failed(ctx context.Context, q *FailedQuery) ([]FailedJob, error) {
+	tx := d.db(ctx)
+	if q.Queue != "" {
+		tx = tx.Where("queue = ?", q.Queue)
+	}
+	if q.Job != "" {
+		tx = tx.Where("job = ?", q.Job)
+	}
+	if !q.Before.IsZero() {
+		tx = tx.Where("failed_at < ?", q.Before)
+	}
+	var rows []FailedJob
+	err := tx.Order("failed_at DESC").Limit(q.limit()).Offset(q.Offset).Find(&rows).Error
+	return rows, err
+}
+
+func (d *DatabaseDriver) FindFailed(ctx context.Context, id string) (*FailedJob, error) {
+	var failed FailedJob
+	if err := d.db(ctx).Where("id = ?", id).Take(&failed).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFailed
+		}
+		return nil, err
+	}
+	return &failed, nil
+}
+
+func (d *DatabaseDriver) Retry(ctx context.Context, id string) error {
+	return d.db(ctx).Transaction(func(tx *gorm.DB) error {
+		var failed FailedJob
+		if err := tx.Where("id = ?", id).Take(&failed).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFailed
+			}
+			return err
+		}
+		if err := tx.Delete(&failed).Error; err != nil {
+			return err
+		}
+		return tx.Create(&jobRow{
+			ID:          failed.ID,
+			Queue:       failed.Queue,
+			Job:         failed.Job,
+			Payload:     failed.Payload,
+			MaxAttempts: max(failed.Attempts, 1),
+			AvailableAt: time.Now(),
+			CreatedAt:   time.Now(),
+		}).Error
+	})
+}
+
+func (d *DatabaseDriver) Forget(ctx context.Context, id string) error {
+	result := d.db(ctx).Where("id = ?", id).Delete(&FailedJob{})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrNotFailed
+	}
+	return result.Error
+}
+
+func (d *DatabaseDriver) Prune(ctx context.Context, before time.Time) (int64, error) {
+	result := d.db(ctx).Where("failed_at < ?", before).Delete(&FailedJob{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,172 @@
+package queue
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SyncDriver handles jobs right away in the dispatching goroutine, which is
+// handy in development and tests. Dispatch returns the job's error.
+type SyncDriver struct{}
+
+func NewSyncDriver() *SyncDriver {
+	return &SyncDriver{}
+}
+
+func (*SyncDriver) Push(context.Context, *Envelope) error             { return nil }
+func (*SyncDriver) Pop(context.Context, ...string) (*Envelope, error) { return nil, nil }
+func (*SyncDriver) Delete(context.Context, *Envelope) error           { return nil }
+func (*SyncDriver) Release(context.Context, *Envelope, time.Duration) error {
+	return nil
+}
+func (*SyncDriver) Fail(context.Context, *Envelope, error) error { return nil }
+
+// MemoryDriver keeps jobs in process memory. They are lost on exit, so it
+// suits work that may be dropped, like cache warming.
+type MemoryDriver struct {
+	mu     sync.Mutex
+	queues map[string][]*Envelope
+	failed []FailedJob
+}
+
+// maxMemoryFailed bounds the failed jobs a MemoryDriver remembers.
+const maxMemoryFailed = 1000
+
+func NewMemoryDriver() *MemoryDriver {
+	return &MemoryDriver{queues: make(map[string][]*Envelope)}
+}
+
+func (d *MemoryDriver) Push(_ context.Context, env *Envelope) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	q := append(d.queues[env.Queue], env)
+	sort.SliceStable(q, func(i, j int) bool { return q[i].AvailableAt.Before(q[j].AvailableAt) })
+	d.queues[env.Queue] = q
+	return nil
+}
+
+func (d *MemoryDriver) Pop(_ context.Context, queues ...string) (*Envelope, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	for _, name := range queues {
+		q := d.queues[name]
+		if len(q) > 0 && !q[0].AvailableAt.After(now) {
+			d.queues[name] = q[1:]
+			return q[0], nil
+		}
+	}
+	return nil, nil
+}
+
+func (d *MemoryDriver) Delete(context.Context, *Envelope) error {
+	return nil
+}
+
+func (d *MemoryDriver) Release(ctx context.Context, env *Envelope, delay time.Duration) error {
+	env.AvailableAt = time.Now().Add(delay)
+	return d.Push(ctx, env)
+}
+
+func (d *MemoryDriver) Fail(_ context.Context, env *Envelope, cause error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.failed = append(d.failed, FailedJob{
+		ID:        env.ID,
+		Queue:     env.Queue,
+		Job:       env.Job,
+		Payload:   string(env.Payload),
+		Attempts:  env.Attempts,
+		Exception: cause.Error(),
+		FailedAt:  time.Now(),
+	})
+	if len(d.failed) > maxMemoryFailed {
+		d.failed = d.failed[len(d.failed)-maxMemoryFailed:]
+	}
+	return nil
+}
+
+func (d *MemoryDriver) Failed(_ context.Context, q *FailedQuery) ([]FailedJob, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var list []FailedJob
+	skip := q.Offset
+	for i := len(d.failed) - 1; i >= 0 && len(list) < q.limit(); i-- {
+		if !q.matches(&d.failed[i]) {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		list = append(list, d.failed[i])
+	}
+	return list, nil
+}
+
+func (d *MemoryDriver) FindFailed(_ context.Context, id string) (*FailedJob, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if i := d.indexFailed(id); i >= 0 {
+		failed := d.failed[i]
+		return &failed, nil
+	}
+	return nil, ErrNotFailed
+}
+
+func (d *MemoryDriver) Retry(ctx context.Context, id string) error {
+	d.mu.Lock()
+	i := d.indexFailed(id)
+	if i < 0 {
+		d.mu.Unlock()
+		return ErrNotFailed
+	}
+	failed := d.failed[i]
+	d.failed = append(d.failed[:i], d.failed[i+1:]...)
+	d.mu.Unlock()
+
+	return d.Push(ctx, &Envelope{
+		ID:          failed.ID,
+		Job:         failed.Job,
+		Payload:     []byte(failed.Payload),
+		Queue:       failed.Queue,
+		MaxAttempts: max(failed.Attempts, 1),
+		AvailableAt: time.Now(),
+	})
+}
+
+func (d *MemoryDriver) Forget(_ context.Context, id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	i := d.indexFailed(id)
+	if i < 0 {
+		return ErrNotFailed
+	}
+	d.failed = append(d.failed[:i], d.failed[i+1:]...)
+	return nil
+}
+
+func (d *MemoryDriver) Prune(_ context.Context, before time.Time) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	kept := d.failed[:0]
+	for _, failed := range d.failed {
+		if !failed.FailedAt.Before(before) {
+			kept = append(kept, failed)
+		}
+	}
+	pruned := int64(len(d.failed) - len(kept))
+	d.failed = kept
+	return pruned, nil
+}
+
+func (d *MemoryDriver) indexFailed(id string) int {
+	for i := range d.failed {
+		if d.failed[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,90 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNoFailedStore = errors.New("queue: the driver does not keep failed jobs")
+	ErrNotFailed     = errors.New("queue: no such failed job")
+)
+
+// FailedStore is implemented by drivers keeping the jobs that ran out of
+// attempts, for operators to look into and retry.
+type FailedStore interface {
+	// Failed returns the failed jobs matching q, newest first.
+	Failed(ctx context.Context, q *FailedQuery) ([]FailedJob, error)
+	// FindFailed returns a failed job, or ErrNotFailed.
+	FindFailed(ctx context.Context, id string) (*FailedJob, error)
+	// Retry moves a failed job back onto its queue.
+	Retry(ctx context.Context, id string) error
+	// Forget deletes a failed job.
+	Forget(ctx context.Context, id string) error
+	// Prune deletes the jobs that failed before a time, returning how many.
+	Prune(ctx context.Context, before time.Time) (int64, error)
+}
+
+// FailedQuery filters failed jobs, its zero fields matching any.
+type FailedQuery struct {
+	Queue string
+	Job   string
+	// Before only matches jobs that failed before it.
+	Before time.Time
+	// Limit defaults to 50.
+	Limit  int
+	Offset int
+}
+
+func (q *FailedQuery) limit() int {
+	if q.Limit <= 0 {
+		return 50
+	}
+	return q.Limit
+}
+
+func (q *FailedQuery) matches(f *FailedJob) bool {
+	return (q.Queue == "" || f.Queue == q.Queue) &&
+		(q.Job == "" || f.Job == q.Job) &&
+		(q.Before.IsZero() || f.FailedAt.Before(q.Before))
+}
+
+// Failed returns the failed jobs of the queue's driver.
+func (q *Queue) Failed() (FailedStore, error) {
+	s, ok := q.driver.(FailedStore)
+	if !ok {
+		return nil, ErrNoFailedStore
+	}
+	return s, nil
+}
+
+// RetryFailed retries the failed jobs matching q, all of them regardless of
+// its limit and offset, returning how many were retried. Jobs failing again
+// meanwhile are left alone.
+func RetryFailed(ctx context.Context, s FailedStore, q *FailedQuery) (int, error) {
+	page := *q
+	page.Limit, page.Offset = 100, 0
+	if page.Before.IsZero() {
+		page.Before = time.Now()
+	}
+
+	retried := 0
+	for {
+		jobs, err := s.Failed(ctx, &page)
+		if err != nil || len(jobs) == 0 {
+			return retried, err
+		}
+		for _, job := range jobs {
+			err := s.Retry(ctx, job.ID)
+			switch {
+			case errors.Is(err, ErrNotFailed):
+				// Retried by someone else meanwhile
+			case err != nil:
+				return retried, err
+			default:
+				retried++
+			}
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/lemmego/lemmego/framework/kafka"
+)
+
+// KafkaDriver keeps jobs in Kafka topics, <prefix>.<queue>, read by the
+// consumer group <group>.<queue>. Offsets are committed as far as every job
+// before them is done, so jobs of a crashed worker are read again. Jobs due
+// within DelayWindow wait in memory, later ones are produced again to wait
+// at the end of the topic. Jobs that ran out of attempts are produced to
+// <prefix>.failed as failed jobs.
+type KafkaDriver struct {
+	client *kafka.Client
+	prefix string
+	group  string
+	// DelayWindow is how far ahead delayed jobs are held rather than
+	// produced again.
+	DelayWindow time.Duration
+
+	mu       sync.Mutex
+	queues   map[string]*kafkaQueue
+	reserved map[string]kafkaReservation
+}
+
+// kafkaQueue is the consumer of a queue with the records it read.
+type kafkaQueue struct {
+	mu       sync.Mutex
+	consumer *kafka.Consumer
+	// gen changes when the consumer is lost, along with every record it read.
+	gen      int
+	records  []kafka.Record
+	delayed  []kafkaDelayed
+	inflight map[int32]*partitionOffsets
+}
+
+type kafkaDelayed struct {
+	env    *Envelope
+	record kafka.Record
+}
+
+type kafkaReservation struct {
+	queue  *kafkaQueue
+	gen    int
+	record kafka.Record
+}
+
+// partitionOffsets tracks the records of a partition being handled.
+type partitionOffsets struct {
+	pending   map[int64]bool
+	done      int64
+	committed int64
+}
+
+func NewKafkaDriver(client *kafka.Client, prefix, group string) *KafkaDriver {
+	return &KafkaDriver{
+		client:      client,
+		prefix:      prefix,
+		group:       group,
+		DelayWindow: time.Minute,
+		queues:      make(map[string]*kafkaQueue),
+		reserved:    make(map[string]kafkaReservation),
+	}
+}
+
+func (d *KafkaDriver) topic(queue string) string {
+	return d.prefix + "." + queue
+}
+
+func (d *KafkaDriver) Push(ctx context.Context, env *Envelope) error {
+	return d.produce(ctx, d.topic(env.Queue), env.ID, env)
+}
+
+func (d *KafkaDriver) produce(ctx context.Context, topic, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return d.client.Produce(ctx, topic, kafka.Record{Key: []byte(key), Value: data})
+}
+
+func (d *KafkaDriver) queue(name string) *kafkaQueue {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	q, ok := d.queues[name]
+	if !ok {
+		q = &kafkaQueue{inflight: make(map[int32]*partitionOffsets)}
+		d.queues[name] = q
+	}
+	return q
+}
+
+func (d *KafkaDriver) Pop(ctx context.Context, queues ...string) (*Envelope, error) {
+	for _, name := range queues {
+		env, err := d.pop(ctx, name)
+		if env != nil || err != nil {
+			return env, err
+		}
+	}
+	return nil, nil
+}
+
+func (d *KafkaDriver) pop(ctx context.Context, name string) (*Envelope, error) {
+	q := d.queue(name)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, delayed := range q.delayed {
+		if !delayed.env.AvailableAt.After(time.Now()) {
+			q.delayed = append(q.delayed[:i], q.delayed[i+1:]...)
+			d.reserve(q, delayed.env, delayed.record)
+			return delayed.env, nil
+		}
+	}
+
+	if len(q.records) == 0 {
+		if err := d.fetch(ctx, q, name); err != nil {
+			return nil, err
+		}
+	}
+	for len(q.records) > 0 {
+		record := q.records[0]
+		q.records = q.records[1:]
+		q.start(record)
+
+		var env Envelope
+		if err := json.Unmarshal(record.Value, &env); err != nil {
+			slog.Error(fmt.Sprintf("queue: dropping malformed job on %s: %s", record.Topic, err))
+			if err := d.finish(ctx, q, q.gen, record); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		wait := time.Until(env.AvailableAt)
+		switch {
+		case wait <= 0:
+			d.reserve(q, &env, record)
+			return &env, nil
+		case wait <= d.DelayWindow:
+			q.delayed = append(q.delayed, kafkaDelayed{env: &env, record: record})
+		default:
+			if err := d.produce(ctx, record.Topic, env.ID, &env); err != nil {
+				return nil, err
+			}
+			if err := d.finish(ctx, q, q.gen, record); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nil, nil
+}
+
+// fetch reads the next records of a queue, creating its consumer as needed.
+func (d *KafkaDriver) fetch(ctx context.Context, q *kafkaQueue, name string) error {
+	if q.consumer == nil {
+		consumer, err := d.client.NewConsumer(ctx, d.group+"."+name, d.topic(name))
+		if err != nil {
+			return err
+		}
+		q.consumer = consumer
+	}
+	records, err := q.consumer.Fetch(ctx, 100*time.Millisecond)
+	if err != nil {
+		q.lost(err)
+		return err
+	}
+	q.records = records
+	return nil
+}
+
+func (d *KafkaDriver) reserve(q *kafkaQueue, env *Envelope, record kafka.Record) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.reserved[env.ID] = kafkaReservation{queue: q, gen: q.gen, record: record}
+}
+
+// take returns the record env was reserved from.
+func (d *KafkaDriver) take(env *Envelope) (kafkaReservation, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	r, ok := d.reserved[env.ID]
+	if !ok {
+		return r, fmt.Errorf("queue: %s %s is not reserved", env.Job, env.ID)
+	}
+	delete(d.reserved, env.ID)
+	return r, nil
+}
+
+// done marks the record env was reserved from as handled.
+func (d *KafkaDriver) done(ctx context.Context, env *Envelope) error {
+	r, err := d.take(env)
+	if err != nil {
+		return err
+	}
+	r.queue.mu.Lock()
+	defer r.queue.mu.Unlock()
+	return d.finish(ctx, r.queue, r.gen, r.record)
+}
+
+// finish marks record as handled, committing the offsets it completes.
+// Records read by a lost consumer are left to be read again.
+func (d *KafkaDriver) finish(ctx context.Context, q *kafkaQueue, gen int, record kafka.Record) error {
+	if gen != q.gen || q.consumer == nil {
+		return nil
+	}
+	p := q.inflight[record.Partition]
+	delete(p.pending, record.Offset)
+	p.done = max(p.done, record.Offset)
+
+	upTo := p.done
+	for offset := range p.pending {
+		upTo = min(upTo, offset-1)
+	}
+	if upTo <= p.committed {
+		return nil
+	}
+	commit := kafka.Record{Topic: record.Topic, Partition: record.Partition, Offset: upTo}
+	if err := q.consumer.Commit(ctx, commit); err != nil {
+		q.lost(err)
+		return err
+	}
+	p.committed = upTo
+	return nil
+}
+
+// start tracks record as being handled.
+func (q *kafkaQueue) start(record kafka.Record) {
+	p, ok := q.inflight[record.Partition]
+	if !ok {
+		p = &partitionOffsets{pending: make(map[int64]bool), done: -1, committed: -1}
+		q.inflight[record.Partition] = p
+	}
+	p.pending[record.Offset] = true
+}
+
+// lost drops the consumer when the proxy no longer knows it, with what it
+// read.
+func (q *kafkaQueue) lost(err error) {
+	if !kafka.IsConsumerGone(err) {
+		return
+	}
+	q.consumer = nil
+	q.gen++
+	q.records, q.delayed = nil, nil
+	q.inflight = make(map[int32]*partitionOffsets)
+}
+
+func (d *KafkaDriver) Delete(ctx context.Context, env *Envelope) error {
+	return d.done(ctx, env)
+}
+
+// Release produces a copy of env due after delay.
+func (d *KafkaDriver) Release(ctx context.Context, env *Envelope, delay time.Duration) error {
+	env.AvailableAt = time.Now().Add(delay)
+	if err := d.produce(ctx, d.topic(env.Queue), env.ID, env); err != nil {
+		return err
+	}
+	return d.done(ctx, env)
+}
+
+func (d *KafkaDriver) Fail(ctx context.Context, env *Envelope, cause error) error {
+	failed := &FailedJob{
+		ID:        env.ID,
+		Queue:     env.Queue,
+		Job:       env.Job,
+		Payload:   string(env.Payload),
+		Attempts:  env.Attempts,
+		Exception: cause.Error(),
+		FailedAt:  time.Now(),
+	}
+	if err := d.produce(ctx, d.prefix+".failed", env.ID, failed); err != nil {
+		return err
+	}
+	return d.done(ctx, env)
+}
@@ -0,0 +1,180 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/lemmego/lemmego/framework/nats"
+)
+
+// NATSDriver keeps jobs in a JetStream stream with work queue retention,
+// under a subject and a durable pull consumer per queue, created as needed.
+// Delayed jobs are handed back to the server until due. Jobs that ran out of
+// attempts are dropped, their failure only logged.
+type NATSDriver struct {
+	client *nats.Client
+	stream string
+	// RetryAfter is how long a reserved job may run before it is considered
+	// abandoned by a crashed worker and handed out again.
+	RetryAfter time.Duration
+
+	mu       sync.Mutex
+	ready    map[string]bool
+	reserved map[string]*nats.Msg
+}
+
+// NewNATSDriver keeps jobs in stream, on the subjects <stream>.<queue>.
+func NewNATSDriver(client *nats.Client, stream string) *NATSDriver {
+	return &NATSDriver{
+		client:     client,
+		stream:     stream,
+		RetryAfter: 10 * time.Minute,
+		ready:      make(map[string]bool),
+		reserved:   make(map[string]*nats.Msg),
+	}
+}
+
+var consumerName = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+func (d *NATSDriver) subject(queue string) string {
+	return d.stream + "." + queue
+}
+
+// setup creates the stream and the consumer of queue the first time around.
+func (d *NATSDriver) setup(ctx context.Context, conn *nats.Conn, queue string) error {
+	d.mu.Lock()
+	streamReady, queueReady := d.ready[""], d.ready[queue]
+	d.mu.Unlock()
+	if queueReady {
+		return nil
+	}
+	if !streamReady {
+		err := conn.AddStream(ctx, &nats.StreamConfig{
+			Name:      d.stream,
+			Subjects:  []string{d.stream + ".>"},
+			Retention: "workqueue",
+			Storage:   "file",
+		})
+		if err != nil {
+			return err
+		}
+	}
+	err := conn.AddConsumer(ctx, d.stream, &nats.ConsumerConfig{
+		Durable:       consumerName.ReplaceAllString(queue, "_"),
+		FilterSubject: d.subject(queue),
+		AckWait:       d.RetryAfter,
+	})
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.ready[""], d.ready[queue] = true, true
+	d.mu.Unlock()
+	return nil
+}
+
+func (d *NATSDriver) Push(ctx context.Context, env *Envelope) error {
+	return d.publish(ctx, env, env.ID)
+}
+
+// publish stores env, id dropping copies published within the duplicate
+// window of the stream.
+func (d *NATSDriver) publish(ctx context.Context, env *Envelope, id string) error {
+	conn, err := d.client.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	if err := d.setup(ctx, conn, env.Queue); err != nil {
+		return err
+	}
+	data, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	return conn.StreamPublish(ctx, d.subject(env.Queue), id, data)
+}
+
+func (d *NATSDriver) Pop(ctx context.Context, queues ...string) (*Envelope, error) {
+	conn, err := d.client.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range queues {
+		if err := d.setup(ctx, conn, name); err != nil {
+			return nil, err
+		}
+		msg, err := conn.Next(ctx, d.stream, consumerName.ReplaceAllString(name, "_"))
+		if err != nil {
+			return nil, err
+		}
+		if msg == nil {
+			continue
+		}
+
+		var env Envelope
+		if err := json.Unmarshal(msg.Data, &env); err != nil {
+			slog.Error(fmt.Sprintf("queue: dropping malformed job on %s: %s", msg.Subject, err))
+			_ = msg.Term()
+			continue
+		}
+		if wait := time.Until(env.AvailableAt); wait > 0 {
+			if err := msg.Nak(wait); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		d.mu.Lock()
+		d.reserved[env.ID] = msg
+		d.mu.Unlock()
+		return &env, nil
+	}
+	return nil, nil
+}
+
+// take returns the message env was reserved from.
+func (d *NATSDriver) take(env *Envelope) (*nats.Msg, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	msg, ok := d.reserved[env.ID]
+	if !ok {
+		return nil, fmt.Errorf("queue: %s %s is not reserved", env.Job, env.ID)
+	}
+	delete(d.reserved, env.ID)
+	return msg, nil
+}
+
+func (d *NATSDriver) Delete(ctx context.Context, env *Envelope) error {
+	msg, err := d.take(env)
+	if err != nil {
+		return err
+	}
+	return msg.Ack()
+}
+
+// Release publishes a copy of env carrying its attempts, as redeliveries of
+// the server would not.
+func (d *NATSDriver) Release(ctx context.Context, env *Envelope, delay time.Duration) error {
+	msg, err := d.take(env)
+	if err != nil {
+		return err
+	}
+	env.AvailableAt = time.Now().Add(delay)
+	if err := d.publish(ctx, env, ""); err != nil {
+		_ = msg.Nak(delay)
+		return err
+	}
+	return msg.Ack()
+}
+
+func (d *NATSDriver) Fail(ctx context.Context, env *Envelope, cause error) error {
+	msg, err := d.take(env)
+	if err != nil {
+		return err
+	}
+	return msg.Term()
+}
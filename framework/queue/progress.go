@@ -0,0 +1,224 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/lemmego/api/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/lemmego/lemmego/framework/cache"
+)
+
+// Jobs dispatched with Track have their progress recorded under their id in
+// the ProgressStore, from dispatch until ProgressTTL after they last
+// reported, so pages can show a progress bar while they run:
+//
+//	id, err := queue.Dispatch(ctx, &ImportContacts{File: file}, &queue.Options{Track: true, Owner: userID})
+//
+//	func (j *ImportContacts) Handle(ctx context.Context) error {
+//		for i, row := range rows {
+//			...
+//			queue.Report(ctx, 100*(i+1)/len(rows), "Importing contacts")
+//		}
+//	}
+//
+// Workers in another process, such as queue:work, share the progress through
+// the DatabaseProgressStore.
+
+// ProgressTTL is how long the progress of a tracked job can be looked up.
+var ProgressTTL = 24 * time.Hour
+
+const (
+	TaskQueued  = "queued"
+	TaskRunning = "running"
+	TaskDone    = "done"
+	TaskFailed  = "failed"
+)
+
+// Progress of a tracked job.
+type Progress struct {
+	ID     string `json:"id"`
+	Job    string `json:"job"`
+	Status string `json:"status"`
+	// Percent goes from 0 to 100.
+	Percent int    `json:"percent"`
+	Message string `json:"message,omitempty"`
+	// Error is the error of the last attempt, kept while the job is retried.
+	Error string `json:"error,omitempty"`
+	// Owner is the user the job was dispatched for, if any.
+	Owner     string    `json:"-"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Finished reports whether the job is done or has failed for good.
+func (p *Progress) Finished() bool {
+	return p.Status == TaskDone || p.Status == TaskFailed
+}
+
+// ProgressStore keeps the progress of tracked jobs.
+type ProgressStore interface {
+	// Get returns the progress of a job, nil when it is not tracked or has
+	// expired.
+	Get(ctx context.Context, id string) (*Progress, error)
+	// Save records the progress of a job for ttl.
+	Save(ctx context.Context, p *Progress, ttl time.Duration) error
+}
+
+var (
+	progressMu    sync.RWMutex
+	progressStore ProgressStore = CacheProgressStore{}
+)
+
+// SetProgressStore replaces the store of the progress of tracked jobs.
+func SetProgressStore(s ProgressStore) {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	progressStore = s
+}
+
+func progress() ProgressStore {
+	progressMu.RLock()
+	defer progressMu.RUnlock()
+	return progressStore
+}
+
+// ProgressOf returns the progress of a tracked job.
+func ProgressOf(id string) (*Progress, bool) {
+	p, err := progress().Get(context.Background(), id)
+	if err != nil {
+		slog.Error(fmt.Sprintf("queue: progress of %s: %s", id, err))
+		return nil, false
+	}
+	return p, p != nil
+}
+
+// Report records the progress of the job being handled, a negative percent
+// keeping the previous one. Jobs that are not tracked report nothing.
+func Report(ctx context.Context, percent int, message string) {
+	env, ok := EnvelopeFrom(ctx)
+	if !ok {
+		return
+	}
+	updateProgress(env.ID, func(p *Progress) {
+		if percent >= 0 {
+			p.Percent = min(percent, 100)
+		}
+		p.Message = message
+	})
+}
+
+func saveProgress(p *Progress) {
+	p.UpdatedAt = time.Now()
+	if err := progress().Save(context.Background(), p, ProgressTTL); err != nil {
+		slog.Error(fmt.Sprintf("queue: saving progress of %s: %s", p.ID, err))
+	}
+}
+
+// updateProgress applies fn to the progress of a job, if it is tracked.
+func updateProgress(id string, fn func(p *Progress)) {
+	p, ok := ProgressOf(id)
+	if !ok {
+		return
+	}
+	fn(p)
+	saveProgress(p)
+}
+
+// CacheProgressStore keeps progress in the default cache store, which only
+// the process running the workers sees unless the store is shared.
+type CacheProgressStore struct{}
+
+func (CacheProgressStore) Get(ctx context.Context, id string) (*Progress, error) {
+	p, ok := cache.Default().Get(progressKey(id)).(Progress)
+	if !ok {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+func (CacheProgressStore) Save(ctx context.Context, p *Progress, ttl time.Duration) error {
+	cache.Default().Put(progressKey(p.ID), *p, int(ttl.Seconds()))
+	return nil
+}
+
+func progressKey(id string) string {
+	return "queue:progress:" + id
+}
+
+// progressRow is the progress of a job in the job_progress table.
+type progressRow struct {
+	ID        string `gorm:"primaryKey"`
+	Job       string
+	Status    string
+	Percent   int
+	Message   string
+	Error     string
+	Owner     string
+	UpdatedAt time.Time
+	ExpiresAt time.Time
+}
+
+func (progressRow) TableName() string {
+	return "job_progress"
+}
+
+// DatabaseProgressStore keeps progress in the job_progress table, shared by
+// the web processes and the workers using the same database.
+type DatabaseProgressStore struct {
+	connName []string
+}
+
+func NewDatabaseProgressStore(connName ...string) *DatabaseProgressStore {
+	return &DatabaseProgressStore{connName: connName}
+}
+
+func (s *DatabaseProgressStore) db(ctx context.Context) *gorm.DB {
+	return db.Get(s.connName...).DB().WithContext(ctx)
+}
+
+func (s *DatabaseProgressStore) Get(ctx context.Context, id string) (*Progress, error) {
+	var row progressRow
+	err := s.db(ctx).Where("id = ? AND expires_at > ?", id, time.Now()).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Progress{
+		ID:        row.ID,
+		Job:       row.Job,
+		Status:    row.Status,
+		Percent:   row.Percent,
+		Message:   row.Message,
+		Error:     row.Error,
+		Owner:     row.Owner,
+		UpdatedAt: row.UpdatedAt,
+	}, nil
+}
+
+func (s *DatabaseProgressStore) Save(ctx context.Context, p *Progress, ttl time.Duration) error {
+	// Expired rows are pruned as jobs are dispatched, once per job
+	if p.Status == TaskQueued && p.Error == "" {
+		if err := s.db(ctx).Where("expires_at <= ?", time.Now()).Delete(&progressRow{}).Error; err != nil {
+			return err
+		}
+	}
+	return s.db(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&progressRow{
+		ID:        p.ID,
+		Job:       p.Job,
+		Status:    p.Status,
+		Percent:   p.Percent,
+		Message:   p.Message,
+		Error:     p.Error,
+		Owner:     p.Owner,
+		UpdatedAt: p.UpdatedAt,
+		ExpiresAt: p.UpdatedAt.Add(ttl),
+	}).Error
+}
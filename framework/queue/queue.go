@@ -0,0 +1,170 @@
+// Package queue runs jobs in the background.
+//
+// A job is a struct with exported, JSON encodable fields and a Handle method.
+// It is registered once under a stable name, dispatched from anywhere, and
+// decoded and handled by a worker, possibly in another process:
+//
+//	type SendWelcome struct{ UserID uint }
+//
+//	func (j *SendWelcome) Handle(ctx context.Context) error { ... }
+//
+//	func init() { queue.Register[SendWelcome]("send_welcome") }
+//
+//	queue.Dispatch(ctx, &SendWelcome{UserID: u.ID})
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// DefaultQueue is used when a job is dispatched without a queue name.
+const DefaultQueue = "default"
+
+var ErrUnknownJob = errors.New("queue: unknown job")
+
+// Job is a unit of background work.
+type Job interface {
+	Handle(ctx context.Context) error
+}
+
+// OnQueue is implemented by jobs going to a queue of their own when
+// dispatched without one, such as password reset emails to a "critical"
+// queue polled before bulk work.
+type OnQueue interface {
+	OnQueue() string
+}
+
+// Envelope is a job as it travels through a driver.
+type Envelope struct {
+	ID          string          `json:"id"`
+	Job         string          `json:"job"`
+	Payload     json.RawMessage `json:"payload"`
+	Queue       string          `json:"queue"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	AvailableAt time.Time       `json:"available_at"`
+}
+
+// Driver stores envelopes until a worker takes them.
+type Driver interface {
+	// Push stores env until it is due.
+	Push(ctx context.Context, env *Envelope) error
+	// Pop reserves the next due envelope of the first queue that has one,
+	// or returns nil when all are empty.
+	Pop(ctx context.Context, queues ...string) (*Envelope, error)
+	// Delete removes a handled envelope.
+	Delete(ctx context.Context, env *Envelope) error
+	// Release makes a reserved envelope available again after delay.
+	Release(ctx context.Context, env *Envelope, delay time.Duration) error
+	// Fail removes an envelope that ran out of attempts, keeping it for inspection.
+	Fail(ctx context.Context, env *Envelope, cause error) error
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]reflect.Type)
+	names      = make(map[reflect.Type]string)
+)
+
+// Register makes J dispatchable and handleable under name.
+func Register[J any, PJ interface {
+	*J
+	Job
+}](name string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	t := reflect.TypeOf((*J)(nil)).Elem()
+	registry[name] = t
+	names[t] = name
+}
+
+func nameOf(job Job) (string, error) {
+	t := reflect.TypeOf(job)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	name, ok := names[t]
+	if !ok {
+		return "", fmt.Errorf("%w: %s is not registered", ErrUnknownJob, t)
+	}
+	return name, nil
+}
+
+// decode turns an envelope back into its job.
+func decode(env *Envelope) (Job, error) {
+	registryMu.RLock()
+	t, ok := registry[env.Job]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownJob, env.Job)
+	}
+
+	job := reflect.New(t).Interface()
+	if err := json.Unmarshal(env.Payload, job); err != nil {
+		return nil, err
+	}
+	return job.(Job), nil
+}
+
+// Around wraps the handling of every job, e.g. to give it resources
+// released once it is done.
+type Around func(ctx context.Context, handle func(ctx context.Context) error) error
+
+var (
+	aroundMu sync.RWMutex
+	arounds  []Around
+)
+
+// Wrap adds a to the wrappers of every job, outermost first.
+func Wrap(a Around) {
+	aroundMu.Lock()
+	defer aroundMu.Unlock()
+	arounds = append(arounds, a)
+}
+
+// wrapped returns handle inside the registered wrappers.
+func wrapped(handle func(ctx context.Context) error) func(ctx context.Context) error {
+	aroundMu.RLock()
+	defer aroundMu.RUnlock()
+	for i := len(arounds) - 1; i >= 0; i-- {
+		a, next := arounds[i], handle
+		handle = func(ctx context.Context) error { return a(ctx, next) }
+	}
+	return handle
+}
+
+// Options adjust a single dispatch.
+type Options struct {
+	// ID is the id of the job instead of a random one, e.g. to follow its
+	// progress under an id of the caller.
+	ID          string
+	Queue       string
+	Delay       time.Duration
+	MaxAttempts int
+	// Track records the progress of the job, see Report.
+	Track bool
+	// Owner is the user a tracked job is dispatched for, the only one its
+	// progress is shown to.
+	Owner string
+}
+
+// Dispatch queues job on the configured driver.
+func Dispatch(ctx context.Context, job Job, opts ...*Options) (string, error) {
+	return Default().Dispatch(ctx, job, opts...)
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
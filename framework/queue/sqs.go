@@ -0,0 +1,285 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+const (
+	// sqsMaxDelay and sqsMaxVisibility are the limits of SQS on delivery
+	// delays and visibility timeouts.
+	sqsMaxDelay      = 15 * time.Minute
+	sqsMaxVisibility = 12 * time.Hour
+)
+
+// SQSDriver keeps jobs in standard SQS queues named <prefix><queue>, which
+// must exist, the prefix being either a name prefix or the URL the queue
+// names are appended to. Messages are received in batches, and a received
+// job stays invisible to other workers while it runs, its visibility timeout
+// being extended until it is deleted, released or failed. Delays longer than
+// the 15 minutes SQS allows are reached by hiding the message again once
+// received early. Jobs that ran out of attempts go to the <prefix>failed
+// queue as failed jobs when it exists, and are dropped otherwise.
+type SQSDriver struct {
+	client *sqs.SQS
+	prefix string
+	// VisibilityTimeout is how long a received job stays hidden before
+	// another worker may take it, extended while the job runs.
+	VisibilityTimeout time.Duration
+	// BatchSize is how many messages are received at once, at most 10.
+	BatchSize int
+
+	mu       sync.Mutex
+	urls     map[string]string
+	buffered map[string][]sqsMessage
+	reserved map[string]*sqsReservation
+}
+
+type sqsMessage struct {
+	env        *Envelope
+	handle     string
+	receivedAt time.Time
+}
+
+type sqsReservation struct {
+	url    string
+	handle string
+	stop   chan struct{}
+}
+
+func NewSQSDriver(client *sqs.SQS, prefix string) *SQSDriver {
+	return &SQSDriver{
+		client:            client,
+		prefix:            prefix,
+		VisibilityTimeout: time.Minute,
+		BatchSize:         10,
+		urls:              make(map[string]string),
+		buffered:          make(map[string][]sqsMessage),
+		reserved:          make(map[string]*sqsReservation),
+	}
+}
+
+// url returns the URL of the SQS queue of queue.
+func (d *SQSDriver) url(ctx context.Context, queue string) (string, error) {
+	if strings.HasPrefix(d.prefix, "https://") || strings.HasPrefix(d.prefix, "http://") {
+		return d.prefix + queue, nil
+	}
+	d.mu.Lock()
+	url, ok := d.urls[queue]
+	d.mu.Unlock()
+	if ok {
+		return url, nil
+	}
+	out, err := d.client.GetQueueUrlWithContext(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String(d.prefix + queue)})
+	if err != nil {
+		return "", err
+	}
+	d.mu.Lock()
+	d.urls[queue] = *out.QueueUrl
+	d.mu.Unlock()
+	return *out.QueueUrl, nil
+}
+
+func (d *SQSDriver) Push(ctx context.Context, env *Envelope) error {
+	url, err := d.url(ctx, env.Queue)
+	if err != nil {
+		return err
+	}
+	return d.send(ctx, url, env, time.Until(env.AvailableAt))
+}
+
+func (d *SQSDriver) send(ctx context.Context, url string, v any, delay time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	delay = min(max(delay, 0), sqsMaxDelay)
+	_, err = d.client.SendMessageWithContext(ctx, &sqs.SendMessageInput{
+		QueueUrl:     aws.String(url),
+		MessageBody:  aws.String(string(data)),
+		DelaySeconds: aws.Int64(int64((delay + time.Second - 1) / time.Second)),
+	})
+	return err
+}
+
+func (d *SQSDriver) Pop(ctx context.Context, queues ...string) (*Envelope, error) {
+	for _, name := range queues {
+		url, err := d.url(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		msg, ok := d.next(name)
+		if !ok {
+			if err := d.receive(ctx, name, url); err != nil {
+				return nil, err
+			}
+			if msg, ok = d.next(name); !ok {
+				continue
+			}
+		}
+
+		r := &sqsReservation{url: url, handle: msg.handle, stop: make(chan struct{})}
+		d.mu.Lock()
+		d.reserved[msg.env.ID] = r
+		d.mu.Unlock()
+		go d.extend(r)
+		return msg.env, nil
+	}
+	return nil, nil
+}
+
+// next takes the next buffered message of queue, skipping those received
+// long enough ago for their visibility timeout to be close to running out.
+func (d *SQSDriver) next(queue string) (sqsMessage, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for len(d.buffered[queue]) > 0 {
+		msg := d.buffered[queue][0]
+		d.buffered[queue] = d.buffered[queue][1:]
+		if time.Since(msg.receivedAt) < d.VisibilityTimeout/2 {
+			return msg, true
+		}
+	}
+	return sqsMessage{}, false
+}
+
+// receive buffers a batch of due messages of queue, hiding those not due
+// until they are.
+func (d *SQSDriver) receive(ctx context.Context, queue, url string) error {
+	out, err := d.client.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(url),
+		MaxNumberOfMessages: aws.Int64(int64(min(max(d.BatchSize, 1), 10))),
+		VisibilityTimeout:   aws.Int64(int64(d.VisibilityTimeout / time.Second)),
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var batch []sqsMessage
+	for _, m := range out.Messages {
+		var env Envelope
+		if err := json.Unmarshal([]byte(aws.StringValue(m.Body)), &env); err != nil {
+			slog.Error(fmt.Sprintf("queue: dropping malformed job on %s: %s", queue, err))
+			_ = d.delete(ctx, url, aws.StringValue(m.ReceiptHandle))
+			continue
+		}
+		if wait := env.AvailableAt.Sub(now); wait > 0 {
+			d.hide(ctx, url, aws.StringValue(m.ReceiptHandle), min(wait, sqsMaxVisibility))
+			continue
+		}
+		batch = append(batch, sqsMessage{env: &env, handle: aws.StringValue(m.ReceiptHandle), receivedAt: now})
+	}
+	d.mu.Lock()
+	d.buffered[queue] = append(d.buffered[queue], batch...)
+	d.mu.Unlock()
+	return nil
+}
+
+// extend keeps a reserved message hidden until the reservation stops.
+func (d *SQSDriver) extend(r *sqsReservation) {
+	ticker := time.NewTicker(d.VisibilityTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), d.VisibilityTimeout/2)
+			d.hide(ctx, r.url, r.handle, d.VisibilityTimeout)
+			cancel()
+		}
+	}
+}
+
+func (d *SQSDriver) hide(ctx context.Context, url, handle string, timeout time.Duration) {
+	_, err := d.client.ChangeMessageVisibilityWithContext(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(url),
+		ReceiptHandle:     aws.String(handle),
+		VisibilityTimeout: aws.Int64(int64(timeout / time.Second)),
+	})
+	if err != nil {
+		slog.Warn(fmt.Sprintf("queue: changing the visibility of a job: %s", err))
+	}
+}
+
+func (d *SQSDriver) delete(ctx context.Context, url, handle string) error {
+	_, err := d.client.DeleteMessageWithContext(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(url),
+		ReceiptHandle: aws.String(handle),
+	})
+	return err
+}
+
+// take returns the reservation of env, no longer extended.
+func (d *SQSDriver) take(env *Envelope) (*sqsReservation, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	r, ok := d.reserved[env.ID]
+	if !ok {
+		return nil, fmt.Errorf("queue: %s %s is not reserved", env.Job, env.ID)
+	}
+	delete(d.reserved, env.ID)
+	close(r.stop)
+	return r, nil
+}
+
+func (d *SQSDriver) Delete(ctx context.Context, env *Envelope) error {
+	r, err := d.take(env)
+	if err != nil {
+		return err
+	}
+	return d.delete(ctx, r.url, r.handle)
+}
+
+// Release sends a copy of env carrying its attempts, due after delay.
+func (d *SQSDriver) Release(ctx context.Context, env *Envelope, delay time.Duration) error {
+	r, err := d.take(env)
+	if err != nil {
+		return err
+	}
+	env.AvailableAt = time.Now().Add(delay)
+	if err := d.send(ctx, r.url, env, delay); err != nil {
+		return err
+	}
+	return d.delete(ctx, r.url, r.handle)
+}
+
+func (d *SQSDriver) Fail(ctx context.Context, env *Envelope, cause error) error {
+	r, err := d.take(env)
+	if err != nil {
+		return err
+	}
+	url, err := d.url(ctx, "failed")
+	var awsErr awserr.Error
+	switch {
+	case errors.As(err, &awsErr) && awsErr.Code() == sqs.ErrCodeQueueDoesNotExist:
+		slog.Warn(fmt.Sprintf("queue: dropping failed %s %s, %sfailed does not exist", env.Job, env.ID, d.prefix))
+	case err != nil:
+		return err
+	default:
+		failed := &FailedJob{
+			ID:        env.ID,
+			Queue:     env.Queue,
+			Job:       env.Job,
+			Payload:   string(env.Payload),
+			Attempts:  env.Attempts,
+			Exception: cause.Error(),
+			FailedAt:  time.Now(),
+		}
+		if err := d.send(ctx, url, failed, 0); err != nil {
+			return err
+		}
+	}
+	return d.delete(ctx, r.url, r.handle)
+}
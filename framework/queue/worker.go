@@ -0,0 +1,328 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Queue dispatches jobs to a driver and works them off.
+type Queue struct {
+	driver      Driver
+	maxAttempts int
+	backoff     time.Duration
+}
+
+// New returns a queue on driver. Jobs are tried maxAttempts times, waiting
+// backoff times the attempt number between tries.
+func New(driver Driver, maxAttempts int, backoff time.Duration) *Queue {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &Queue{driver: driver, maxAttempts: maxAttempts, backoff: backoff}
+}
+
+var (
+	defaultMu    sync.RWMutex
+	defaultQueue = New(NewSyncDriver(), 1, 0)
+)
+
+// Default returns the queue used by the package level functions, running jobs
+// synchronously until a driver is configured.
+func Default() *Queue {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultQueue
+}
+
+// SetDefault replaces the queue used by the package level functions.
+func SetDefault(q *Queue) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultQueue = q
+}
+
+func (q *Queue) Driver() Driver {
+	return q.driver
+}
+
+// Dispatch queues job and returns its id.
+func (q *Queue) Dispatch(ctx context.Context, job Job, opts ...*Options) (string, error) {
+	env, err := q.Envelope(job, opts...)
+	if err != nil {
+		return "", err
+	}
+	if len(opts) > 0 && opts[0] != nil && opts[0].Track {
+		saveProgress(&Progress{ID: env.ID, Job: env.Job, Status: TaskQueued, Owner: opts[0].Owner})
+	}
+	if err := q.Push(ctx, env); err != nil {
+		return "", err
+	}
+	return env.ID, nil
+}
+
+// Envelope encodes job the way Dispatch queues it, to be queued later with
+// Push. Tracking is left to the caller.
+func (q *Queue) Envelope(job Job, opts ...*Options) (*Envelope, error) {
+	o := &Options{}
+	if len(opts) > 0 && opts[0] != nil {
+		o = opts[0]
+	}
+
+	name, err := nameOf(job)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+
+	env := &Envelope{
+		ID:          newID(),
+		Job:         name,
+		Payload:     payload,
+		Queue:       o.Queue,
+		MaxAttempts: o.MaxAttempts,
+		AvailableAt: time.Now().Add(o.Delay),
+	}
+	if o.ID != "" {
+		env.ID = o.ID
+	}
+	if j, ok := job.(OnQueue); ok && env.Queue == "" {
+		env.Queue = j.OnQueue()
+	}
+	if env.Queue == "" {
+		env.Queue = DefaultQueue
+	}
+	if env.MaxAttempts == 0 {
+		env.MaxAttempts = q.maxAttempts
+	}
+	return env, nil
+}
+
+// Push queues env, or handles it right away on the sync driver.
+func (q *Queue) Push(ctx context.Context, env *Envelope) error {
+	if _, ok := q.driver.(*SyncDriver); ok {
+		return q.runSync(ctx, env)
+	}
+	return q.driver.Push(ctx, env)
+}
+
+// runSync handles env right away, retrying without backoff, and returns the
+// error of the last attempt.
+func (q *Queue) runSync(ctx context.Context, env *Envelope) error {
+	var err error
+	for env.Attempts < env.MaxAttempts {
+		env.Attempts++
+		if err = q.handle(ctx, env); err == nil {
+			return nil
+		}
+	}
+	failProgress(env, err)
+	return err
+}
+
+// Process handles a single reserved envelope, deleting, releasing or failing
+// it depending on the outcome.
+func (q *Queue) Process(ctx context.Context, env *Envelope) error {
+	env.Attempts++
+	err := q.handle(ctx, env)
+	if err == nil {
+		return q.driver.Delete(ctx, env)
+	}
+
+	if env.Attempts < env.MaxAttempts {
+		slog.Warn(fmt.Sprintf("queue: %s %s attempt %d failed, retrying: %s", env.Job, env.ID, env.Attempts, err))
+		return q.driver.Release(ctx, env, q.backoff*time.Duration(env.Attempts))
+	}
+
+	slog.Error(fmt.Sprintf("queue: %s %s failed: %s", env.Job, env.ID, err))
+	failProgress(env, err)
+	return q.driver.Fail(ctx, env, err)
+}
+
+func (q *Queue) handle(ctx context.Context, env *Envelope) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+		if err != nil {
+			// Queued again until the last attempt fails
+			updateProgress(env.ID, func(p *Progress) { p.Status, p.Error = TaskQueued, err.Error() })
+		} else {
+			updateProgress(env.ID, func(p *Progress) { p.Status, p.Percent, p.Error = TaskDone, 100, "" })
+		}
+	}()
+
+	job, err := decode(env)
+	if err != nil {
+		return err
+	}
+	updateProgress(env.ID, func(p *Progress) { p.Status = TaskRunning })
+	return wrapped(job.Handle)(withJob(withEnvelope(ctx, env), job))
+}
+
+func failProgress(env *Envelope, err error) {
+	updateProgress(env.ID, func(p *Progress) { p.Status, p.Error = TaskFailed, err.Error() })
+}
+
+// WorkOptions configure Work.
+type WorkOptions struct {
+	// Queues are polled in order, earlier ones first, unless weighted.
+	Queues []string
+	// Weights, when set, make polling fair rather than strict: each queue
+	// is polled first in proportion to its weight, 1 when missing, so lower
+	// queues are not starved by busy higher ones. See ParseQueues.
+	Weights map[string]int
+	// Concurrency is the number of jobs handled at once.
+	Concurrency int
+	// Sleep is the pause after finding all queues empty.
+	Sleep time.Duration
+}
+
+// Work handles jobs until ctx is cancelled, then waits for the running ones.
+func (q *Queue) Work(ctx context.Context, opts *WorkOptions) {
+	o := *opts
+	if len(o.Queues) == 0 {
+		o.Queues = []string{DefaultQueue}
+	}
+	if o.Concurrency < 1 {
+		o.Concurrency = 1
+	}
+	if o.Sleep <= 0 {
+		o.Sleep = time.Second
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < o.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			order := newPollOrder(o.Queues, o.Weights)
+			for ctx.Err() == nil {
+				env, err := q.driver.Pop(ctx, order.next()...)
+				if err != nil && ctx.Err() == nil {
+					slog.Error(fmt.Sprintf("queue: pop: %s", err))
+				}
+				if env == nil {
+					select {
+					case <-ctx.Done():
+					case <-time.After(o.Sleep):
+					}
+					continue
+				}
+				// A job that started finishes even when the worker is stopping
+				if err := q.Process(context.WithoutCancel(ctx), env); err != nil {
+					slog.Error(fmt.Sprintf("queue: %s %s: %s", env.Job, env.ID, err))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// ParseQueues parses a comma separated list of queues, each optionally
+// followed by a weight, such as "critical:5,default:3,bulk". Weights are nil
+// when none is given.
+func ParseQueues(spec string) ([]string, map[string]int, error) {
+	var (
+		names   []string
+		weights map[string]int
+	)
+	for _, item := range strings.Split(spec, ",") {
+		name, weight, weighted := strings.Cut(strings.TrimSpace(item), ":")
+		if name == "" {
+			continue
+		}
+		names = append(names, name)
+		if !weighted {
+			continue
+		}
+		w, err := strconv.Atoi(weight)
+		if err != nil || w < 1 {
+			return nil, nil, fmt.Errorf("queue: invalid weight %q of %s", weight, name)
+		}
+		if weights == nil {
+			weights = make(map[string]int)
+		}
+		weights[name] = w
+	}
+	return names, weights, nil
+}
+
+// pollOrder picks the queue polled first with a smooth weighted round
+// robin, the others following in their order as fallbacks.
+type pollOrder struct {
+	queues  []string
+	weights []int
+	current []int
+	order   []string
+}
+
+func newPollOrder(queues []string, weights map[string]int) *pollOrder {
+	p := &pollOrder{queues: queues, order: make([]string, len(queues))}
+	if weights == nil {
+		copy(p.order, queues)
+		return p
+	}
+	p.weights, p.current = make([]int, len(queues)), make([]int, len(queues))
+	for i, name := range queues {
+		p.weights[i] = max(weights[name], 1)
+	}
+	return p
+}
+
+func (p *pollOrder) next() []string {
+	if p.weights == nil {
+		return p.order
+	}
+	total, first := 0, 0
+	for i, w := range p.weights {
+		p.current[i] += w
+		total += w
+		if p.current[i] > p.current[first] {
+			first = i
+		}
+	}
+	p.current[first] -= total
+
+	p.order = append(p.order[:0], p.queues[first])
+	for i, name := range p.queues {
+		if i != first {
+			p.order = append(p.order, name)
+		}
+	}
+	return p.order
+}
+
+type envelopeKey struct{}
+
+func withEnvelope(ctx context.Context, env *Envelope) context.Context {
+	return context.WithValue(ctx, envelopeKey{}, env)
+}
+
+// EnvelopeFrom returns the envelope of the job being handled, e.g. for its id
+// or attempt number.
+func EnvelopeFrom(ctx context.Context) (*Envelope, bool) {
+	env, ok := ctx.Value(envelopeKey{}).(*Envelope)
+	return env, ok
+}
+
+type jobKey struct{}
+
+func withJob(ctx context.Context, job Job) context.Context {
+	return context.WithValue(ctx, jobKey{}, job)
+}
+
+// JobFrom returns the job being handled, e.g. for wrappers acting on jobs
+// implementing an interface of theirs.
+func JobFrom(ctx context.Context) (Job, bool) {
+	job, ok := ctx.Value(jobKey{}).(Job)
+	return job, ok
+}
@@ -0,0 +1,32 @@
+// Package reload lets services apply configuration changed at runtime. The
+// application reads its configuration again, e.g. on SIGUSR1, then calls
+// Notify for the services to pick up the new values:
+//
+//	reload.On(func() {
+//		pool.Resize(config.Get("database.connections.default.max_open_conns", 0).(int))
+//	})
+package reload
+
+import "sync"
+
+var (
+	mu    sync.Mutex
+	hooks []func()
+)
+
+// On registers fn to run on every Notify.
+func On(fn func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	hooks = append(hooks, fn)
+}
+
+// Notify runs the registered hooks, once the configuration was reloaded.
+func Notify() {
+	mu.Lock()
+	list := append([]func(){}, hooks...)
+	mu.Unlock()
+	for _, hook := range list {
+		hook()
+	}
+}
@@ -0,0 +1,83 @@
+// Package render caches the output of idempotent templ fragments and the
+// serialized form of Inertia props, so hot pages skip re-rendering and
+// re-encoding data that only changes when the underlying records do.
+package render
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/lemmego/api/app"
+	gonertia "github.com/romsar/gonertia"
+
+	"github.com/lemmego/lemmego/framework/cache"
+)
+
+const keyPrefix = "render:"
+
+// Key derives a cache key from the inputs a fragment or prop depends on.
+func Key(parts ...any) string {
+	sum := sha256.Sum256([]byte(fmt.Sprint(parts...)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Fragment renders component once per key and serves the cached HTML until
+// ttl passes or one of its tags is invalidated. Only use it for output that is
+// fully determined by the key: CSRF fields, CSP nonces and flash messages must
+// stay outside cached fragments.
+//
+//	@render.Fragment(render.Key("sidebar", user.ID), 10*time.Minute, sidebar(user), "posts")
+func Fragment(key string, ttl time.Duration, component templ.Component, tags ...string) templ.Component {
+	return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		html, err := cache.Remember(cache.Default(), keyPrefix+"fragment:"+key, ttl, func() (string, error) {
+			var buf bytes.Buffer
+			if err := component.Render(ctx, &buf); err != nil {
+				return "", err
+			}
+			return buf.String(), nil
+		}, tags...)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, html)
+		return err
+	})
+}
+
+// Prop shares an Inertia prop whose JSON is built by fn once per key and
+// reused until ttl passes or one of its tags is invalidated. It must run
+// before c.Inertia, typically from a UseBefore handler.
+func Prop(c *app.Context, name string, key string, ttl time.Duration, fn func() (any, error), tags ...string) error {
+	raw, err := cache.Remember(cache.Default(), keyPrefix+"prop:"+name+":"+key, ttl, func() (json.RawMessage, error) {
+		v, err := fn()
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(v)
+	}, tags...)
+	if err != nil {
+		return err
+	}
+
+	r := c.Request()
+	c.SetRequest(r.WithContext(gonertia.SetProp(r.Context(), name, raw)))
+	return nil
+}
+
+// Invalidate drops every cached fragment and prop carrying any of the tags.
+// Call it after writes, e.g. render.Invalidate("posts") once a post is saved.
+func Invalidate(tags ...string) {
+	cache.Default().FlushTags(tags...)
+}
+
+// Forget drops a single cached fragment.
+func Forget(key string) {
+	cache.Default().Forget(keyPrefix + "fragment:" + key)
+}
@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultChunkSize keeps a batch well below the bind parameter limits of the
+// supported databases for typical row widths.
+const DefaultChunkSize = 500
+
+// InsertMany inserts rows in multi-row statements of chunkSize rows each,
+// all within one transaction. A chunkSize <= 0 uses DefaultChunkSize.
+func (r *Repo[T]) InsertMany(rows []T, chunkSize int) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	var affected int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.CreateInBatches(&rows, chunkSize)
+		affected = result.RowsAffected
+		return result.Error
+	})
+	return affected, err
+}
+
+// Upsert inserts rows, updating the given columns of rows that conflict on
+// conflictColumns. The statement is rendered for the connection's dialect:
+// ON CONFLICT for PostgreSQL and SQLite, ON DUPLICATE KEY UPDATE for MySQL,
+// where the conflict target is implied by the table's unique keys.
+// With no update columns every non-key column is overwritten.
+//
+//	repo.New[Product](ctx).Upsert(products, []string{"sku"}, []string{"price", "stock"}, 1000)
+func (r *Repo[T]) Upsert(rows []T, conflictColumns []string, updateColumns []string, chunkSize int) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	onConflict := clause.OnConflict{}
+	for _, name := range conflictColumns {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: name})
+	}
+	if len(updateColumns) > 0 {
+		onConflict.DoUpdates = clause.AssignmentColumns(updateColumns)
+	} else {
+		onConflict.UpdateAll = true
+	}
+
+	var affected int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(onConflict).CreateInBatches(&rows, chunkSize)
+		affected = result.RowsAffected
+		return result.Error
+	})
+	return affected, err
+}
+
+// InsertIgnore inserts rows, skipping those that conflict with existing ones.
+func (r *Repo[T]) InsertIgnore(rows []T, chunkSize int) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	var affected int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&rows, chunkSize)
+		affected = result.RowsAffected
+		return result.Error
+	})
+	return affected, err
+}
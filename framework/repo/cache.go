@@ -0,0 +1,115 @@
+package repo
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/lemmego/lemmego/framework/cache"
+)
+
+type cacheOptions struct {
+	ttl  time.Duration
+	tags []string
+}
+
+// Cached returns a repository whose Find, First and Count results are kept in
+// the cache store for ttl. Entries are tagged with the model's table and
+// dropped by any create, update or delete gorm runs against that table, and
+// by Invalidate for the extra tags, e.g. when another table they join changes.
+//
+//	posts, err := repo.New[Post](ctx).Cached(time.Minute, "users").Where("published = ?", true).Find()
+func (r *Repo[T]) Cached(ttl time.Duration, tags ...string) *Repo[T] {
+	c := r.clone(r.db)
+	c.cache = &cacheOptions{ttl: ttl, tags: tags}
+	return c
+}
+
+// Invalidate drops cached query results carrying any of the tags.
+func Invalidate(tags ...string) {
+	cache.Default().FlushTags(tags...)
+}
+
+// TableTag is the tag cached results of a table carry.
+func TableTag(table string) string {
+	return "table:" + table
+}
+
+func (r *Repo[T]) cachedFind(conds ...any) ([]T, error) {
+	key, tags, err := r.cacheKey(func(tx *gorm.DB) *gorm.DB {
+		var rows []T
+		return tx.Find(&rows, conds...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := cache.Remember(cache.Default(), key, r.cache.ttl, func() ([]T, error) {
+		var rows []T
+		err := r.scoped().Find(&rows, conds...).Error
+		return rows, err
+	}, tags...)
+	// Callers may modify the slice, the cached one stays untouched
+	return append([]T(nil), rows...), err
+}
+
+func (r *Repo[T]) cachedFirst(conds ...any) (*T, error) {
+	key, tags, err := r.cacheKey(func(tx *gorm.DB) *gorm.DB {
+		var row T
+		return tx.First(&row, conds...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := cache.Remember(cache.Default(), key, r.cache.ttl, func() (T, error) {
+		var row T
+		err := r.scoped().First(&row, conds...).Error
+		return row, err
+	}, tags...)
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+func (r *Repo[T]) cachedCount() (int64, error) {
+	key, tags, err := r.cacheKey(func(tx *gorm.DB) *gorm.DB {
+		var count int64
+		return tx.Count(&count)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return cache.Remember(cache.Default(), key, r.cache.ttl, func() (int64, error) {
+		var count int64
+		err := r.scoped().Count(&count).Error
+		return count, err
+	}, tags...)
+}
+
+// cacheKey renders the statement without running it; the SQL with its bound
+// values identifies the result.
+func (r *Repo[T]) cacheKey(query func(tx *gorm.DB) *gorm.DB) (string, []string, error) {
+	stmt := &gorm.Statement{DB: r.db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return "", nil, err
+	}
+	table := stmt.Table
+	if r.db.Statement.Table != "" {
+		table = r.db.Statement.Table
+	}
+
+	sql := r.scoped().ToSQL(query)
+	tags := append([]string{TableTag(table)}, r.cache.tags...)
+	return fmt.Sprintf("repo:%s:%T:%s", r.db.Dialector.Name(), *new(T), sql), tags, nil
+}
+
+// invalidateCache drops cached results of the table a write went to.
+func invalidateCache(tx *gorm.DB) {
+	if tx.Error == nil && !tx.DryRun && tx.Statement.Table != "" {
+		cache.Default().FlushTags(TableTag(tx.Statement.Table))
+	}
+}
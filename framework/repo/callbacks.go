@@ -0,0 +1,41 @@
+package repo
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+var registered sync.Map // callbacks of a connection -> struct{}
+
+// registerCallbacks hooks the read-only check, model events and cache
+// invalidation into every write gorm performs on the connection, once per
+// connection.
+func registerCallbacks(db *gorm.DB) {
+	// Sessions may copy the config, the callbacks it points to are shared
+	callbacks := db.Callback()
+	if _, loaded := registered.LoadOrStore(callbacks, struct{}{}); loaded {
+		return
+	}
+
+	for _, err := range []error{
+		callbacks.Create().Before("*").Register("repo:read_only", refuseWrites),
+		callbacks.Update().Before("*").Register("repo:read_only", refuseWrites),
+		callbacks.Delete().Before("*").Register("repo:read_only", refuseWrites),
+		callbacks.Create().Before("gorm:create").Register("repo:creating", dispatchModelEvent(Creating)),
+		callbacks.Create().After("gorm:create").Register("repo:invalidate_cache", invalidateCache),
+		callbacks.Create().After("repo:invalidate_cache").Register("repo:created", dispatchModelEvent(Created)),
+		callbacks.Update().Before("gorm:update").Register("repo:updating", dispatchModelEvent(Updating)),
+		callbacks.Update().After("gorm:update").Register("repo:invalidate_cache", invalidateCache),
+		callbacks.Update().After("repo:invalidate_cache").Register("repo:updated", dispatchModelEvent(Updated)),
+		callbacks.Delete().Before("gorm:delete").Register("repo:deleting", dispatchModelEvent(Deleting)),
+		callbacks.Delete().After("gorm:delete").Register("repo:invalidate_cache", invalidateCache),
+		callbacks.Delete().After("repo:invalidate_cache").Register("repo:deleted", dispatchModelEvent(Deleted)),
+	} {
+		if err != nil {
+			slog.Error(fmt.Sprintf("repo: register callbacks: %s", err))
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+
+	"github.com/lemmego/lemmego/framework/crypt"
+)
+
+// Attribute casts convert between a field and its column through gorm
+// serializers:
+//
+//	type Customer struct {
+//		db.Model
+//		Address   Address    `gorm:"serializer:json"`      // struct stored as JSON
+//		Birthday  time.Time  `gorm:"serializer:unixtime"`  // stored as a unix timestamp
+//		Tier      Tier       `gorm:"serializer:enum"`      // rejected unless Tier.Valid()
+//		Phone     string     `gorm:"serializer:encrypted"` // encrypted with APP_KEY at rest
+//		TaxInfo   TaxInfo    `gorm:"serializer:encrypted"` // JSON, then encrypted
+//	}
+//
+// Encrypted columns hold base64 text and cannot be searched or indexed.
+func init() {
+	schema.RegisterSerializer("encrypted", EncryptedSerializer{})
+	schema.RegisterSerializer("enum", EnumSerializer{})
+}
+
+// EncryptedSerializer encrypts a field with the application key. Strings are
+// encrypted as they are, any other type as its JSON.
+type EncryptedSerializer struct{}
+
+func (EncryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	if fieldValue == nil {
+		return nil, nil
+	}
+	if rv := reflect.ValueOf(fieldValue); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil, nil
+	}
+
+	enc, err := crypt.Default()
+	if err != nil {
+		return nil, err
+	}
+
+	if s, ok := fieldValue.(string); ok {
+		return enc.EncryptString(s)
+	}
+	plaintext, err := json.Marshal(fieldValue)
+	if err != nil {
+		return nil, err
+	}
+	return enc.EncryptString(string(plaintext))
+}
+
+func (EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	value := reflect.New(field.FieldType)
+	if dbValue != nil {
+		var sealed string
+		switch v := dbValue.(type) {
+		case string:
+			sealed = v
+		case []byte:
+			sealed = string(v)
+		default:
+			return fmt.Errorf("repo: cannot decrypt %T into %s", dbValue, field.Name)
+		}
+
+		enc, err := crypt.Default()
+		if err != nil {
+			return err
+		}
+		plaintext, err := enc.DecryptString(sealed)
+		if err != nil {
+			return fmt.Errorf("repo: %s: %w", field.Name, err)
+		}
+
+		if field.FieldType.Kind() == reflect.String {
+			value.Elem().SetString(plaintext)
+		} else if err := json.Unmarshal([]byte(plaintext), value.Interface()); err != nil {
+			return err
+		}
+	}
+
+	field.ReflectValueOf(ctx, dst).Set(value.Elem())
+	return nil
+}
+
+// Enum is implemented by enum types stored with serializer:enum.
+type Enum interface {
+	Valid() bool
+}
+
+// EnumSerializer stores string or integer based enums, refusing to write or
+// read values their type does not consider valid.
+type EnumSerializer struct{}
+
+func (EnumSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	if e, ok := fieldValue.(Enum); ok && !e.Valid() {
+		return nil, fmt.Errorf("repo: %v is not a valid %s", fieldValue, field.FieldType)
+	}
+
+	rv := reflect.ValueOf(fieldValue)
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), nil
+	}
+	return nil, fmt.Errorf("repo: enum %s must be based on a string or integer", field.FieldType)
+}
+
+func (EnumSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	value := reflect.New(field.FieldType).Elem()
+	if dbValue != nil {
+		if b, ok := dbValue.([]byte); ok {
+			dbValue = string(b)
+		}
+		raw := reflect.ValueOf(dbValue)
+		if field.FieldType.Kind() == reflect.String && raw.Kind() != reflect.String {
+			// Converting an integer to a string type would yield a rune
+			raw = reflect.ValueOf(fmt.Sprint(dbValue))
+		}
+		if !raw.CanConvert(field.FieldType) {
+			return fmt.Errorf("repo: cannot scan %T into enum %s", dbValue, field.FieldType)
+		}
+		value = raw.Convert(field.FieldType)
+
+		if e, ok := value.Interface().(Enum); ok && !e.Valid() {
+			return fmt.Errorf("repo: %v is not a valid %s", dbValue, field.FieldType)
+		}
+	}
+
+	field.ReflectValueOf(ctx, dst).Set(value)
+	return nil
+}
@@ -0,0 +1,110 @@
+package repo
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// EnumSet lists the values of a string enum and implements its scanning and
+// JSON encoding, for the type's methods to delegate to:
+//
+//	type Status string
+//
+//	var Statuses = repo.NewEnumSet[Status]("pending", "paid", "shipped")
+//
+//	func (s Status) Valid() bool                   { return Statuses.Valid(s) }
+//	func (s Status) Value() (driver.Value, error)  { return Statuses.Value(s) }
+//	func (s *Status) Scan(src any) error           { return Statuses.Scan(s, src) }
+//	func (s *Status) UnmarshalJSON(b []byte) error { return Statuses.DecodeJSON(s, b) }
+//
+// The zero value, an empty string, is valid wherever the column is nullable:
+// it is stored as NULL and NULL scans into it.
+type EnumSet[T ~string] struct {
+	values []T
+}
+
+// NewEnumSet returns the set of values.
+func NewEnumSet[T ~string](values ...T) *EnumSet[T] {
+	return &EnumSet[T]{values: values}
+}
+
+// Values returns the values, in the order given.
+func (e *EnumSet[T]) Values() []T {
+	return slices.Clone(e.values)
+}
+
+// Strings returns the values as strings, e.g. for schema.Table.Enum.
+func (e *EnumSet[T]) Strings() []string {
+	s := make([]string, len(e.values))
+	for i, v := range e.values {
+		s[i] = string(v)
+	}
+	return s
+}
+
+// Valid reports whether v is one of the values.
+func (e *EnumSet[T]) Valid(v T) bool {
+	return slices.Contains(e.values, v)
+}
+
+// Parse returns s as a value, or an error when it is not one.
+func (e *EnumSet[T]) Parse(s string) (T, error) {
+	if !e.Valid(T(s)) {
+		var zero T
+		return zero, fmt.Errorf("repo: %q is not a valid %T", s, zero)
+	}
+	return T(s), nil
+}
+
+// Value returns the column value of v.
+func (e *EnumSet[T]) Value(v T) (driver.Value, error) {
+	if v == "" {
+		return nil, nil
+	}
+	if _, err := e.Parse(string(v)); err != nil {
+		return nil, err
+	}
+	return string(v), nil
+}
+
+// Scan reads a column value into dst.
+func (e *EnumSet[T]) Scan(dst *T, src any) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*dst = ""
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("repo: cannot scan %T into enum %T", src, *dst)
+	}
+	v, err := e.Parse(s)
+	if err != nil {
+		return err
+	}
+	*dst = v
+	return nil
+}
+
+// DecodeJSON decodes a JSON string into dst, refusing unknown values.
+func (e *EnumSet[T]) DecodeJSON(dst *T, data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		*dst = ""
+		return nil
+	}
+	v, err := e.Parse(s)
+	if err != nil {
+		return err
+	}
+	*dst = v
+	return nil
+}
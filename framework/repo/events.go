@@ -0,0 +1,145 @@
+package repo
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/lemmego/lemmego/framework/events"
+)
+
+// Names of the events dispatched around every write gorm performs. Returning
+// an error from a Creating, Updating or Deleting listener aborts the write;
+// errors from the others are returned to the caller, rolling back the
+// surrounding transaction if there is one.
+const (
+	Creating = "model.creating"
+	Created  = "model.created"
+	Updating = "model.updating"
+	Updated  = "model.updated"
+	Deleting = "model.deleting"
+	Deleted  = "model.deleted"
+)
+
+// ModelEvent describes a write. Model is what the statement was given: a
+// model, a slice of models, or for conditional updates and deletes the
+// repository's empty model.
+type ModelEvent struct {
+	Kind  string
+	Table string
+	Model any
+	// DB runs further queries on the same connection or transaction.
+	DB *gorm.DB
+}
+
+func (e *ModelEvent) Name() string {
+	return e.Kind
+}
+
+// Observers implement any of these for their model type.
+type (
+	CreatingObserver[T any] interface {
+		Creating(ctx context.Context, model *T) error
+	}
+	CreatedObserver[T any] interface {
+		Created(ctx context.Context, model *T) error
+	}
+	UpdatingObserver[T any] interface {
+		Updating(ctx context.Context, model *T) error
+	}
+	UpdatedObserver[T any] interface {
+		Updated(ctx context.Context, model *T) error
+	}
+	DeletingObserver[T any] interface {
+		Deleting(ctx context.Context, model *T) error
+	}
+	DeletedObserver[T any] interface {
+		Deleted(ctx context.Context, model *T) error
+	}
+)
+
+// Observe registers the methods observer implements for writes of T, called
+// once per affected model.
+//
+//	type UserObserver struct{}
+//
+//	func (UserObserver) Created(ctx context.Context, u *User) error {
+//		return mail.SendWelcome(ctx, u)
+//	}
+//
+//	repo.Observe[User](UserObserver{})
+func Observe[T any](observer any) {
+	on := func(kind string, fn func(ctx context.Context, model *T) error) {
+		events.Listen(kind, func(ctx context.Context, e events.Event) error {
+			for _, model := range modelsOf[T](e.(*ModelEvent).Model) {
+				if err := fn(ctx, model); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	if o, ok := observer.(CreatingObserver[T]); ok {
+		on(Creating, o.Creating)
+	}
+	if o, ok := observer.(CreatedObserver[T]); ok {
+		on(Created, o.Created)
+	}
+	if o, ok := observer.(UpdatingObserver[T]); ok {
+		on(Updating, o.Updating)
+	}
+	if o, ok := observer.(UpdatedObserver[T]); ok {
+		on(Updated, o.Updated)
+	}
+	if o, ok := observer.(DeletingObserver[T]); ok {
+		on(Deleting, o.Deleting)
+	}
+	if o, ok := observer.(DeletedObserver[T]); ok {
+		on(Deleted, o.Deleted)
+	}
+}
+
+func modelsOf[T any](v any) []*T {
+	switch m := v.(type) {
+	case *T:
+		return []*T{m}
+	case []*T:
+		return m
+	case *[]*T:
+		return *m
+	case []T:
+		models := make([]*T, len(m))
+		for i := range m {
+			models[i] = &m[i]
+		}
+		return models
+	case *[]T:
+		return modelsOf[T](*m)
+	}
+	return nil
+}
+
+// dispatchModelEvent returns a gorm callback dispatching kind for the statement.
+func dispatchModelEvent(kind string) func(tx *gorm.DB) {
+	return func(tx *gorm.DB) {
+		if tx.Error != nil || tx.DryRun || !events.Default().HasListeners(kind) {
+			return
+		}
+
+		model := tx.Statement.Dest
+		if _, isMap := model.(map[string]any); isMap || model == nil {
+			model = tx.Statement.Model
+		}
+
+		err := events.Dispatch(tx.Statement.Context, &ModelEvent{
+			Kind:  kind,
+			Table: tx.Statement.Table,
+			Model: model,
+			DB:    tx.Session(&gorm.Session{NewDB: true}),
+		})
+		if err != nil {
+			_ = tx.AddError(err)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// JSON columns, created with schema.Table.Json or Jsonb, are queried with
+// the same helpers on every driver:
+//
+//	repo.New[User](ctx).WhereJSONContains("meta", map[string]any{"tags": []string{"beta"}}).Find()
+//	repo.New[User](ctx).WhereJSONPath("meta", "settings.theme", "dark").Find()
+//	repo.New[User](ctx).Order(clause.OrderBy{Expression: repo.JSONPath("meta", "rank")}).Find()
+//
+// Paths are keys separated by dots; numeric keys index arrays.
+
+// JSONPath is the value at path inside a JSON column, as text on PostgreSQL
+// and MySQL and as its SQL value on SQLite, e.g. for Select or Order.
+func JSONPath(column, path string) clause.Expression {
+	keys := strings.Split(path, ".")
+	return jsonExpr(func(d string) clause.Expr {
+		col := clause.Column{Name: column}
+		switch d {
+		case "postgres":
+			return clause.Expr{SQL: "(? #>> ?::text[])", Vars: []any{col, pgPath(keys)}}
+		case "mysql":
+			return clause.Expr{SQL: "JSON_UNQUOTE(JSON_EXTRACT(?, ?))", Vars: []any{col, sqlPath(keys)}}
+		}
+		return clause.Expr{SQL: "json_extract(?, ?)", Vars: []any{col, sqlPath(keys)}}
+	})
+}
+
+// JSONContains matches rows whose JSON column contains value, encoded as
+// JSON: objects contain the keys given with matching values, arrays the
+// elements given, in any order. On SQLite, objects nested in arrays are
+// compared as a whole.
+func JSONContains(column string, value any) clause.Expression {
+	doc, err := json.Marshal(value)
+	return jsonExpr(func(d string) clause.Expr {
+		if err != nil {
+			return clause.Expr{SQL: "1 = 0"}
+		}
+		col := clause.Column{Name: column}
+		switch d {
+		case "postgres":
+			return clause.Expr{SQL: "?::jsonb @> ?::jsonb", Vars: []any{col, string(doc)}}
+		case "mysql":
+			return clause.Expr{SQL: "JSON_CONTAINS(?, ?)", Vars: []any{col, string(doc)}}
+		}
+
+		var v any
+		_ = json.Unmarshal(doc, &v)
+		if isScalar(v) {
+			// As on PostgreSQL, a scalar is contained in an equal scalar or
+			// in an array holding it
+			return clause.Expr{SQL: "EXISTS (SELECT 1 FROM json_each(?) WHERE value = ?)", Vars: []any{col, sqliteValue(v)}}
+		}
+		var depth int
+		return sqliteContains(col, "$", v, &depth)
+	})
+}
+
+// WhereJSONContains keeps the rows whose JSON column contains value, see
+// JSONContains.
+func (r *Repo[T]) WhereJSONContains(column string, value any) *Repo[T] {
+	return r.Where(JSONContains(column, value))
+}
+
+// WhereJSONPath keeps the rows whose JSON column holds value at path.
+func (r *Repo[T]) WhereJSONPath(column, path string, value any) *Repo[T] {
+	if value == nil {
+		return r.Where("? IS NULL", JSONPath(column, path))
+	}
+	if b, ok := value.(bool); ok {
+		// PostgreSQL and MySQL compare the text of the value
+		return r.Where(jsonExpr(func(d string) clause.Expr {
+			if d == "sqlite" {
+				return clause.Expr{SQL: "? = ?", Vars: []any{JSONPath(column, path), b}}
+			}
+			return clause.Expr{SQL: "? = ?", Vars: []any{JSONPath(column, path), strconv.FormatBool(b)}}
+		}))
+	}
+	return r.Where("? = ?", JSONPath(column, path), value)
+}
+
+// jsonExpr defers building to when the statement, and so the dialect, is
+// known.
+type jsonExpr func(dialect string) clause.Expr
+
+func (e jsonExpr) Build(builder clause.Builder) {
+	stmt := builder.(*gorm.Statement)
+	e(stmt.DB.Dialector.Name()).Build(stmt)
+}
+
+func pgPath(keys []string) string {
+	quoted := make([]string, len(keys))
+	for i, k := range keys {
+		quoted[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(k) + `"`
+	}
+	return "{" + strings.Join(quoted, ",") + "}"
+}
+
+func sqlPath(keys []string) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, k := range keys {
+		if _, err := strconv.Atoi(k); err == nil {
+			b.WriteString("[" + k + "]")
+		} else {
+			b.WriteString(`."` + strings.ReplaceAll(k, `"`, `\"`) + `"`)
+		}
+	}
+	return b.String()
+}
+
+func isScalar(v any) bool {
+	switch v.(type) {
+	case map[string]any, []any:
+		return false
+	}
+	return true
+}
+
+// sqliteValue is the SQL value json_extract and json_each give for a JSON
+// scalar.
+func sqliteValue(v any) any {
+	switch v := v.(type) {
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	}
+	return v
+}
+
+// sqliteContains checks that the JSON document doc holds v at path. Arrays
+// are walked with json_each, each level under its own alias.
+func sqliteContains(doc any, path string, v any, depth *int) clause.Expr {
+	var parts []clause.Expr
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			parts = append(parts, sqliteContains(doc, path+`."`+strings.ReplaceAll(k, `"`, `\"`)+`"`, v[k], depth))
+		}
+	case []any:
+		for _, elem := range v {
+			*depth++
+			alias := fmt.Sprintf("j%d", *depth)
+			var match clause.Expr
+			if isScalar(elem) {
+				match = clause.Expr{SQL: alias + ".value = ?", Vars: []any{sqliteValue(elem)}}
+				if elem == nil {
+					match = clause.Expr{SQL: alias + ".type = 'null'"}
+				}
+			} else {
+				match = clause.Expr{SQL: alias + ".value = json(?)", Vars: []any{mustJSON(elem)}}
+			}
+			parts = append(parts, clause.Expr{
+				SQL:  "EXISTS (SELECT 1 FROM json_each(?, ?) AS " + alias + " WHERE ?)",
+				Vars: []any{doc, path, match},
+			})
+		}
+	case nil:
+		parts = append(parts, clause.Expr{SQL: "json_type(?, ?) = 'null'", Vars: []any{doc, path}})
+	default:
+		parts = append(parts, clause.Expr{SQL: "json_extract(?, ?) = ?", Vars: []any{doc, path, sqliteValue(v)}})
+	}
+
+	if len(parts) == 0 {
+		// Empty objects and arrays are contained in any document holding
+		// one at path
+		return clause.Expr{SQL: "json_type(?, ?) IN ('object', 'array')", Vars: []any{doc, path}}
+	}
+	sql := make([]string, len(parts))
+	vars := make([]any, len(parts))
+	for i, p := range parts {
+		sql[i], vars[i] = "?", p
+	}
+	return clause.Expr{SQL: "(" + strings.Join(sql, " AND ") + ")", Vars: vars}
+}
+
+func mustJSON(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
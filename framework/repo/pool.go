@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lemmego/api/db"
+
+	"github.com/lemmego/lemmego/framework/metrics"
+)
+
+// PoolOptions sizes the connection pool of a named connection. Zero values
+// leave the current setting untouched.
+type PoolOptions struct {
+	MaxOpen     int
+	MaxIdle     int
+	MaxLifetime time.Duration
+	MaxIdleTime time.Duration
+}
+
+// TunePool applies o to the open connection named connName. It takes effect
+// immediately and can be called again at any time.
+func TunePool(connName string, o PoolOptions) error {
+	conn, err := db.DM().Get(connName)
+	if err != nil {
+		return err
+	}
+	sqlDB := conn.SqlDB()
+	if sqlDB == nil {
+		return fmt.Errorf("repo: connection %s is not open", connName)
+	}
+
+	if o.MaxOpen > 0 {
+		sqlDB.SetMaxOpenConns(o.MaxOpen)
+	}
+	if o.MaxIdle > 0 {
+		sqlDB.SetMaxIdleConns(o.MaxIdle)
+	}
+	if o.MaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(o.MaxLifetime)
+	}
+	if o.MaxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(o.MaxIdleTime)
+	}
+	return nil
+}
+
+// RegisterPoolMetrics reports the pool statistics of every open connection,
+// labelled by connection name, to the metrics registry.
+func RegisterPoolMetrics() {
+	collect := func(typ string) func(name, help string, value func(s sql.DBStats) float64) {
+		return func(name, help string, value func(s sql.DBStats) float64) {
+			metrics.Collect(name, help, typ, func() []metrics.Sample {
+				return poolSamples(value)
+			})
+		}
+	}
+	gauge, counter := collect(metrics.GaugeType), collect(metrics.CounterType)
+
+	gauge("db_pool_max_open_connections", "Maximum number of open connections to the database.",
+		func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) })
+	gauge("db_pool_open_connections", "Established connections, both in use and idle.",
+		func(s sql.DBStats) float64 { return float64(s.OpenConnections) })
+	gauge("db_pool_in_use_connections", "Connections currently in use.",
+		func(s sql.DBStats) float64 { return float64(s.InUse) })
+	gauge("db_pool_idle_connections", "Idle connections.",
+		func(s sql.DBStats) float64 { return float64(s.Idle) })
+	counter("db_pool_wait_count_total", "Connections waited for because the pool was exhausted.",
+		func(s sql.DBStats) float64 { return float64(s.WaitCount) })
+	counter("db_pool_wait_duration_seconds_total", "Time spent waiting for a connection.",
+		func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() })
+	counter("db_pool_max_idle_closed_total", "Connections closed due to the idle limit.",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) })
+	counter("db_pool_max_lifetime_closed_total", "Connections closed due to the lifetime limit.",
+		func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) })
+}
+
+func poolSamples(value func(s sql.DBStats) float64) []metrics.Sample {
+	var samples []metrics.Sample
+	for name, conn := range db.DM().All() {
+		sqlDB := conn.SqlDB()
+		if sqlDB == nil {
+			continue
+		}
+		samples = append(samples, metrics.Sample{
+			Labels: metrics.Labels{"connection": name},
+			Value:  value(sqlDB.Stats()),
+		})
+	}
+	return samples
+}
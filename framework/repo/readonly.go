@@ -0,0 +1,91 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/lemmego/api/config"
+	"github.com/lemmego/api/db"
+	"gorm.io/gorm"
+)
+
+// ErrReadOnly is returned by creates, updates and deletes while the database
+// is read-only.
+var ErrReadOnly = errors.New("repo: the database is read-only")
+
+var (
+	readOnly    atomic.Bool
+	primaryDown atomic.Bool
+)
+
+// ReadOnly reports whether writes are refused: database.read_only is set,
+// SetReadOnly turned it on, or WatchPrimary found the default connection
+// down.
+func ReadOnly() bool {
+	if on, _ := config.Get("database.read_only", false).(bool); on {
+		return true
+	}
+	return readOnly.Load() || primaryDown.Load()
+}
+
+// SetReadOnly turns read-only mode on or off at runtime.
+func SetReadOnly(on bool) {
+	readOnly.Store(on)
+}
+
+// refuseWrites fails writes before gorm runs them while read-only.
+func refuseWrites(tx *gorm.DB) {
+	if ReadOnly() {
+		_ = tx.AddError(ErrReadOnly)
+	}
+}
+
+// replica is the connection serving reads while read-only, if one is
+// configured and open.
+func replica() (string, bool) {
+	name, _ := config.Get("database.replica", "").(string)
+	if name == "" || !ReadOnly() || !db.DM().HasConnection(name) {
+		return "", false
+	}
+	return name, true
+}
+
+// WatchPrimary pings the default connection every interval until ctx is
+// done, keeping the database read-only while the ping fails.
+func WatchPrimary(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := ping(ctx, interval)
+		switch {
+		case err != nil && !primaryDown.Swap(true):
+			slog.Error(fmt.Sprintf("repo: primary database down, read-only until it is back: %s", err))
+		case err == nil && primaryDown.Swap(false):
+			slog.Info("repo: primary database back, writes allowed again")
+		}
+	}
+}
+
+func ping(ctx context.Context, timeout time.Duration) error {
+	conn, err := db.DM().Get()
+	if err != nil {
+		return err
+	}
+	sqlDB := conn.SqlDB()
+	if sqlDB == nil {
+		return errors.New("connection is not open")
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return sqlDB.PingContext(ctx)
+}
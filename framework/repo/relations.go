@@ -0,0 +1,90 @@
+package repo
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Relationships are declared as fields of the model, following gorm's
+// conventions:
+//
+//	type User struct {
+//		db.Model
+//		OrgID uint
+//		Org   *Org    // belongs to, through OrgID
+//		Posts []Post  // has many, through Post.UserID
+//		Roles []*Role `gorm:"many2many:user_roles"` // many to many, through user_roles
+//	}
+//
+// With then eager loads them, one query per relation for the whole result set
+// rather than one per row:
+//
+//	users, err := repo.New[User](ctx).With("org", "roles", "posts.comments").Find()
+
+// With eager loads the named relations. Names are the snake_case field names;
+// nested relations are separated by dots.
+func (r *Repo[T]) With(relations ...string) *Repo[T] {
+	tx := r.db
+	for _, name := range relations {
+		field, err := r.relationField(name)
+		if err != nil {
+			tx = tx.Session(&gorm.Session{})
+			_ = tx.AddError(err)
+			continue
+		}
+		tx = tx.Preload(field)
+	}
+	return r.clone(tx)
+}
+
+// WithWhere eager loads a relation, keeping only related rows matching the condition.
+//
+//	repo.New[User](ctx).WithWhere("posts", "published = ?", true).Find()
+func (r *Repo[T]) WithWhere(relation string, query any, args ...any) *Repo[T] {
+	field, err := r.relationField(relation)
+	if err != nil {
+		tx := r.db.Session(&gorm.Session{})
+		_ = tx.AddError(err)
+		return r.clone(tx)
+	}
+	return r.clone(r.db.Preload(field, append([]any{query}, args...)...))
+}
+
+// relationField maps "posts.comments" to "Posts.Comments" and checks each
+// step names a relation of its model.
+func (r *Repo[T]) relationField(name string) (string, error) {
+	stmt := &gorm.Statement{DB: r.db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return "", err
+	}
+
+	schema := stmt.Schema
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		field := camel(part)
+		rel, ok := schema.Relationships.Relations[field]
+		if !ok {
+			return "", fmt.Errorf("repo: %s has no relation %q", schema.Name, part)
+		}
+		parts[i] = field
+		schema = rel.FieldSchema
+	}
+	return strings.Join(parts, "."), nil
+}
+
+func camel(s string) string {
+	var b strings.Builder
+	for _, word := range strings.Split(s, "_") {
+		if word == "" {
+			continue
+		}
+		if strings.EqualFold(word, "id") {
+			b.WriteString("ID")
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,148 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lemmego/api/db"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when a query expecting a single record matches none.
+var ErrNotFound = gorm.ErrRecordNotFound
+
+// DB returns the named connection (the default one when omitted) bound to ctx,
+// so a query is cancelled as soon as the request that issued it goes away.
+// While the database is read-only, the default is the replica, if any.
+func DB(ctx context.Context, connName ...string) *gorm.DB {
+	if len(connName) == 0 {
+		if name, ok := replica(); ok {
+			connName = []string{name}
+		}
+	}
+	return db.Get(connName...).DB().WithContext(ctx)
+}
+
+// Repo is a typed repository for the model T.
+//
+//	users, err := repo.New[User](c.RequestContext()).Where("active = ?", true).Find()
+type Repo[T any] struct {
+	db      *gorm.DB
+	cache   *cacheOptions
+	without map[string]struct{}
+}
+
+// New returns a repository for T using the named connection bound to ctx.
+func New[T any](ctx context.Context, connName ...string) *Repo[T] {
+	return From[T](DB(ctx, connName...))
+}
+
+// From returns a repository for T on an existing session, e.g. inside a transaction.
+func From[T any](tx *gorm.DB) *Repo[T] {
+	registerCallbacks(tx)
+	// A new session lets every chained call clone the statement instead of sharing it
+	return &Repo[T]{db: tx.Model(new(T)).Session(&gorm.Session{})}
+}
+
+// Query exposes the underlying query builder for anything the repository does not cover.
+// Global scopes are already applied.
+func (r *Repo[T]) Query() *gorm.DB {
+	return r.scoped()
+}
+
+func (r *Repo[T]) clone(tx *gorm.DB) *Repo[T] {
+	return &Repo[T]{db: tx, cache: r.cache, without: r.without}
+}
+
+func (r *Repo[T]) Where(query any, args ...any) *Repo[T] {
+	return r.clone(r.db.Where(query, args...))
+}
+
+func (r *Repo[T]) Order(value any) *Repo[T] {
+	return r.clone(r.db.Order(value))
+}
+
+func (r *Repo[T]) Limit(limit int) *Repo[T] {
+	return r.clone(r.db.Limit(limit))
+}
+
+func (r *Repo[T]) Offset(offset int) *Repo[T] {
+	return r.clone(r.db.Offset(offset))
+}
+
+func (r *Repo[T]) Find(conds ...any) ([]T, error) {
+	if r.cache != nil {
+		return r.cachedFind(conds...)
+	}
+
+	var rows []T
+	if err := r.scoped().Find(&rows, conds...).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (r *Repo[T]) First(conds ...any) (*T, error) {
+	if r.cache != nil {
+		return r.cachedFirst(conds...)
+	}
+
+	var row T
+	if err := r.scoped().First(&row, conds...).Error; err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+func (r *Repo[T]) Count() (int64, error) {
+	if r.cache != nil {
+		return r.cachedCount()
+	}
+
+	var count int64
+	err := r.scoped().Count(&count).Error
+	return count, err
+}
+
+func (r *Repo[T]) Exists(conds ...any) (bool, error) {
+	_, err := r.Limit(1).First(conds...)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *Repo[T]) Create(row *T) error {
+	return r.scoped().Create(row).Error
+}
+
+// Save creates row or writes all its columns. Versioned models are only
+// written if unchanged since they were loaded, see Versioned.
+func (r *Repo[T]) Save(row *T) error {
+	if _, _, _, ok := r.versionOf(row); ok {
+		return r.saveVersioned(row)
+	}
+	// The statement's model is the empty T, which would leave the update
+	// without its primary key condition
+	return r.scoped().Model(row).Save(row).Error
+}
+
+// Update sets the given columns on every record matching the current conditions.
+func (r *Repo[T]) Update(values map[string]any) (int64, error) {
+	result := r.scoped().Updates(values)
+	return result.RowsAffected, result.Error
+}
+
+func (r *Repo[T]) Delete(conds ...any) (int64, error) {
+	result := r.scoped().Delete(new(T), conds...)
+	return result.RowsAffected, result.Error
+}
+
+// Transaction runs fn inside a transaction bound to the same context.
+func (r *Repo[T]) Transaction(fn func(tx *Repo[T]) error) error {
+	return r.db.Session(&gorm.Session{NewDB: true}).Transaction(func(tx *gorm.DB) error {
+		repo := From[T](tx)
+		repo.without = r.without
+		return fn(repo)
+	})
+}
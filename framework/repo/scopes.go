@@ -0,0 +1,115 @@
+package repo
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Scope constrains a query. ctx is the context the repository was created with.
+type Scope func(ctx context.Context, tx *gorm.DB) *gorm.DB
+
+type namedScope struct {
+	name  string
+	scope Scope
+}
+
+var (
+	scopesMu sync.RWMutex
+	scopes   = make(map[reflect.Type][]namedScope)
+)
+
+// AddGlobalScope applies scope to every query, update and delete a repository
+// of T runs, including Query(), until opted out with WithoutGlobalScope.
+// Registering a name again replaces the scope. Eager loaded relations are
+// constrained by the scopes of their own model only when loaded through a
+// repository of that model.
+//
+//	repo.AddGlobalScope[Post]("published", func(ctx context.Context, tx *gorm.DB) *gorm.DB {
+//		return tx.Where("published_at IS NOT NULL")
+//	})
+func AddGlobalScope[T any](name string, scope Scope) {
+	scopesMu.Lock()
+	defer scopesMu.Unlock()
+
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for i, s := range scopes[t] {
+		if s.name == name {
+			scopes[t][i].scope = scope
+			return
+		}
+	}
+	scopes[t] = append(scopes[t], namedScope{name: name, scope: scope})
+}
+
+// WithoutGlobalScope runs the repository's queries without the named scopes,
+// or without any global scope when no name is given.
+func (r *Repo[T]) WithoutGlobalScope(names ...string) *Repo[T] {
+	c := r.clone(r.db)
+	c.without = make(map[string]struct{}, len(r.without)+len(names))
+	for name := range r.without {
+		c.without[name] = struct{}{}
+	}
+	if len(names) == 0 {
+		c.without["*"] = struct{}{}
+	}
+	for _, name := range names {
+		c.without[name] = struct{}{}
+	}
+	return c
+}
+
+// scoped is the statement with the active global scopes applied, used to run
+// every operation.
+func (r *Repo[T]) scoped() *gorm.DB {
+	if _, all := r.without["*"]; all {
+		return r.db
+	}
+
+	scopesMu.RLock()
+	registered := scopes[reflect.TypeOf((*T)(nil)).Elem()]
+	scopesMu.RUnlock()
+
+	tx := r.db
+	for _, s := range registered {
+		if _, skip := r.without[s.name]; skip {
+			continue
+		}
+		scope := s.scope
+		tx = tx.Scopes(func(tx *gorm.DB) *gorm.DB {
+			return scope(tx.Statement.Context, tx)
+		})
+	}
+	return tx
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a context carrying the tenant id used by TenantScope.
+func WithTenant(ctx context.Context, tenant any) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFrom returns the tenant id set by WithTenant.
+func TenantFrom(ctx context.Context) (any, bool) {
+	tenant := ctx.Value(tenantKey{})
+	return tenant, tenant != nil
+}
+
+// TenantScope limits queries to the rows of the tenant in the context. Without
+// a tenant nothing matches, so a missing tenant never exposes other tenants'
+// rows; system code opts out with WithoutGlobalScope("tenant").
+//
+//	repo.AddGlobalScope[Invoice]("tenant", repo.TenantScope("org_id"))
+//	invoices, err := repo.New[Invoice](repo.WithTenant(ctx, org.ID)).Find()
+func TenantScope(column string) Scope {
+	return func(ctx context.Context, tx *gorm.DB) *gorm.DB {
+		tenant, ok := TenantFrom(ctx)
+		if !ok {
+			return tx.Where("1 = 0")
+		}
+		return tx.Where(tx.Statement.Quote(column)+" = ?", tenant)
+	}
+}
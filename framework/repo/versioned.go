@@ -0,0 +1,121 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ErrStaleModel is matched by every StaleModelError.
+var ErrStaleModel = errors.New("repo: model was changed since it was loaded")
+
+// StaleModelError is returned when a versioned model is saved after someone
+// else saved a newer version of it.
+type StaleModelError struct {
+	Model   string
+	Version int64
+}
+
+func (e *StaleModelError) Error() string {
+	return fmt.Sprintf("repo: %s version %d was changed since it was loaded", e.Model, e.Version)
+}
+
+func (e *StaleModelError) Unwrap() error {
+	return ErrStaleModel
+}
+
+// Versioned opts a model into optimistic locking. Save and UpdateVersioned then
+// only write when the row still has the version the model was loaded with, and
+// bump it, so two people editing the same record cannot overwrite each other.
+//
+//	type Post struct {
+//		db.Model
+//		repo.Versioned
+//		Title string
+//	}
+//
+// The column is created with t.Int("version").Default(1) in a migration.
+type Versioned struct {
+	Version int64 `gorm:"not null;default:1"`
+}
+
+const versionField = "Version"
+
+func (r *Repo[T]) versionOf(row *T) (*schema.Schema, *schema.Field, reflect.Value, bool) {
+	stmt := &gorm.Statement{DB: r.db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, nil, reflect.Value{}, false
+	}
+	field := stmt.Schema.LookUpField(versionField)
+	if field == nil {
+		return nil, nil, reflect.Value{}, false
+	}
+	return stmt.Schema, field, reflect.ValueOf(row).Elem(), true
+}
+
+// isNew reports whether row has no primary key yet.
+func isNew(r *gorm.DB, s *schema.Schema, rv reflect.Value) bool {
+	pk := s.PrioritizedPrimaryField
+	if pk == nil {
+		return true
+	}
+	_, zero := pk.ValueOf(r.Statement.Context, rv)
+	return zero
+}
+
+// saveVersioned writes every column of row if the stored version still matches.
+func (r *Repo[T]) saveVersioned(row *T) error {
+	s, field, rv, _ := r.versionOf(row)
+	ctx := r.db.Statement.Context
+	if isNew(r.db, s, rv) {
+		return r.scoped().Create(row).Error
+	}
+
+	current, _ := field.ValueOf(ctx, rv)
+	version := reflect.ValueOf(current).Int()
+	if err := field.Set(ctx, rv, version+1); err != nil {
+		return err
+	}
+
+	result := r.scoped().Model(row).Where(field.DBName+" = ?", version).Select("*").Updates(row)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = &StaleModelError{Model: s.Name, Version: version}
+	}
+	if result.Error != nil {
+		_ = field.Set(ctx, rv, version)
+	}
+	return result.Error
+}
+
+// UpdateVersioned writes the given columns of a versioned model loaded
+// earlier, failing with a StaleModelError if it changed in the meantime.
+// On success row carries the new version.
+func (r *Repo[T]) UpdateVersioned(row *T, values map[string]any) error {
+	s, field, rv, ok := r.versionOf(row)
+	if !ok {
+		return fmt.Errorf("repo: %T has no %s field", row, versionField)
+	}
+	ctx := r.db.Statement.Context
+
+	current, _ := field.ValueOf(ctx, rv)
+	version := reflect.ValueOf(current).Int()
+
+	updates := make(map[string]any, len(values)+1)
+	for k, v := range values {
+		updates[k] = v
+	}
+	updates[field.DBName] = version + 1
+
+	result := r.scoped().Model(row).Where(field.DBName+" = ?", version).Updates(updates)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = &StaleModelError{Model: s.Name, Version: version}
+	}
+	if result.Error != nil {
+		// Updates copies values into row as it goes, the version must not stay bumped
+		_ = field.Set(ctx, rv, version)
+	}
+	return result.Error
+}
@@ -0,0 +1,271 @@
+// Package report mails spreadsheets on a schedule. A report is an export
+// defined by name, run by a worker when its schedule is due, stored on a
+// disk and sent to its recipients:
+//
+//	func init() {
+//		report.Define("weekly-orders", &report.Report{
+//			Title:  "Orders of the week",
+//			Format: export.XLSX,
+//			Export: func(ctx context.Context, format export.Format) (export.Runner, error) {
+//				return &export.Export[Order]{
+//					Name:   "orders",
+//					Format: format,
+//					Query:  repo.New[Order](ctx).Where("created_at >= ?", time.Now().AddDate(0, 0, -7)),
+//					Columns: orderColumns,
+//				}, nil
+//			},
+//		})
+//		report.Schedule("weekly-orders", "0 8 * * 1", "sales@example.com")
+//	}
+//
+// Files up to reports.max_attachment_mb are attached, larger ones linked
+// for reports.link_days. report:run sends one at once.
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lemmego/api/config"
+
+	"github.com/lemmego/lemmego/framework/export"
+	"github.com/lemmego/lemmego/framework/mail"
+	"github.com/lemmego/lemmego/framework/metrics"
+	"github.com/lemmego/lemmego/framework/queue"
+	"github.com/lemmego/lemmego/framework/schedule"
+	"github.com/lemmego/lemmego/framework/signed"
+	"github.com/lemmego/lemmego/framework/storage"
+)
+
+func init() {
+	queue.Register[Job]("report")
+}
+
+var (
+	ErrUnknownReport = errors.New("report: unknown report")
+	ErrNoRecipients  = errors.New("report: no recipients")
+)
+
+var sent = metrics.Counter("reports_sent_total", "Reports generated and mailed, by report and status.")
+
+// Report defines a report.
+type Report struct {
+	// Title is the subject of the mail, the name of the report when empty.
+	Title string
+	// Format defaults to CSV.
+	Format export.Format
+	// Export builds the export of the report, its query and columns, inside
+	// the worker.
+	Export func(ctx context.Context, format export.Format) (export.Runner, error)
+	// Template renders the body of the mail when set, see mail.Template,
+	// with the Data of the run.
+	Template string
+	// Recipients always receive the report, next to those of its schedules.
+	Recipients []string
+}
+
+var (
+	reportsMu sync.RWMutex
+	reports   = map[string]*Report{}
+)
+
+// Define registers a report under name.
+func Define(name string, r *Report) {
+	reportsMu.Lock()
+	defer reportsMu.Unlock()
+	reports[name] = r
+}
+
+// Get returns the report defined under name.
+func Get(name string) (*Report, bool) {
+	reportsMu.RLock()
+	defer reportsMu.RUnlock()
+	r, ok := reports[name]
+	return r, ok
+}
+
+// Names returns the names of the defined reports, sorted.
+func Names() []string {
+	reportsMu.RLock()
+	defer reportsMu.RUnlock()
+	names := make([]string, 0, len(reports))
+	for name := range reports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Schedule queues the report whenever spec is due, see schedule.Register,
+// for recipients along with those of the report. Reports may be scheduled
+// more than once, e.g. daily for some and weekly for others.
+func Schedule(name, spec string, recipients ...string) {
+	schedule.Register("report:"+name+":"+spec, spec, func(ctx context.Context) error {
+		return Dispatch(ctx, name, recipients...)
+	})
+}
+
+// Dispatch queues the report for recipients, along with those of the
+// report.
+func Dispatch(ctx context.Context, name string, recipients ...string) error {
+	if _, ok := Get(name); !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownReport, name)
+	}
+	_, err := queue.Dispatch(ctx, &Job{Name: name, Recipients: recipients})
+	return err
+}
+
+// Data is given to the template of a report.
+type Data struct {
+	Name  string
+	Title string
+	// At is when the report was generated.
+	At time.Time
+	// Filename is that of the file, attached unless URL is set.
+	Filename string
+	// URL links to the file when it is too large to attach.
+	URL string
+	// Expires is when URL stops working.
+	Expires time.Time
+}
+
+// Job generates a report and mails it.
+type Job struct {
+	Name       string   `json:"name"`
+	Recipients []string `json:"recipients"`
+}
+
+func (j *Job) Handle(ctx context.Context) error {
+	err := Run(ctx, j.Name, j.Recipients...)
+	status := "sent"
+	if err != nil {
+		status = "failed"
+	}
+	sent.With(metrics.Labels{"report": j.Name, "status": status}).Inc()
+	return err
+}
+
+// Run generates the report, stores it on reports.disk and mails it to
+// recipients along with those of the report, returning once it is sent.
+func Run(ctx context.Context, name string, recipients ...string) error {
+	r, ok := Get(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownReport, name)
+	}
+	to := unique(append(append([]string(nil), r.Recipients...), recipients...))
+	if len(to) == 0 {
+		return fmt.Errorf("%w: %s", ErrNoRecipients, name)
+	}
+	format := r.Format
+	if format == "" {
+		format = export.CSV
+	}
+	runner, err := r.Export(ctx, format)
+	if err != nil {
+		return err
+	}
+
+	disk, err := Disk(ctx)
+	if err != nil {
+		return err
+	}
+	d := &Data{Name: name, Title: r.Title, At: time.Now(), Filename: runner.Filename()}
+	if d.Title == "" {
+		d.Title = name
+	}
+	p := path.Join(Dir, name, d.Filename)
+	if err := export.Store(ctx, runner, disk, p, nil); err != nil {
+		return err
+	}
+
+	m := &mail.Message{To: to, Subject: d.Title}
+	limit := int64(config.Get("reports.max_attachment_mb", 10).(int)) << 20
+	data, err := read(disk, p, limit)
+	switch {
+	case errors.Is(err, errTooLarge):
+		ttl := time.Duration(config.Get("reports.link_days", 7).(int)) * 24 * time.Hour
+		d.Expires = time.Now().Add(ttl)
+		if d.URL, err = signed.Absolute("/reports/"+name+"/"+d.Filename, ttl); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	default:
+		m.Attachments = append(m.Attachments, mail.Attachment{Name: d.Filename, ContentType: format.ContentType(), Data: data})
+	}
+
+	if r.Template != "" {
+		if err := m.Template(ctx, r.Template, d); err != nil {
+			return err
+		}
+	} else {
+		m.Text = body(d)
+	}
+	if err := mail.Send(ctx, m); err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("report: %s sent to %d recipient(s)", name, len(to)))
+	return nil
+}
+
+// Dir is the directory of the disk reports are stored in, one directory
+// per report.
+const Dir = "reports"
+
+// Disk returns the disk reports are stored on.
+func Disk(ctx context.Context) (*storage.Disk, error) {
+	var name []string
+	if d := config.Get("reports.disk", "").(string); d != "" {
+		name = append(name, d)
+	}
+	return storage.Get(ctx, name...)
+}
+
+var errTooLarge = errors.New("report: too large to attach")
+
+// read reads the file at p, failing with errTooLarge beyond limit bytes.
+func read(disk *storage.Disk, p string, limit int64) ([]byte, error) {
+	f, err := disk.Read(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errTooLarge
+	}
+	return data, nil
+}
+
+func body(d *Data) string {
+	at := d.At.Format("January 2, 2006 at 15:04")
+	if d.URL != "" {
+		return fmt.Sprintf("%s, generated on %s, is too large to attach. Download it until %s:\n\n%s\n", d.Title, at, d.Expires.Format("January 2, 2006"), d.URL)
+	}
+	return fmt.Sprintf("%s, generated on %s, is attached.\n", d.Title, at)
+}
+
+func unique(addrs []string) []string {
+	seen := map[string]bool{}
+	list := addrs[:0]
+	for _, a := range addrs {
+		key := strings.ToLower(strings.TrimSpace(a))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		list = append(list, strings.TrimSpace(a))
+	}
+	return list
+}
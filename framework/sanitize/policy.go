@@ -0,0 +1,153 @@
+// Package sanitize cleans user submitted HTML down to the elements and
+// attributes a policy allows, so rich text can be stored safe and rendered
+// as is.
+//
+//	clean := sanitize.UGC().Sanitize(`<p onclick="x()">Hi <script>alert(1)</script><a href="javascript:x">me</a></p>`)
+//	// <p>Hi <a rel="nofollow noopener">me</a></p>
+//
+// Policies are built like bluemonday's:
+//
+//	p := sanitize.NewPolicy().
+//		AllowElements("p", "br", "strong", "em").
+//		AllowAttrs("href").OnElements("a").
+//		AllowURLSchemes("https", "mailto").
+//		RequireNoFollowLinks()
+//
+// Disallowed elements are dropped but their text is kept, except for
+// elements whose content is never text to show, such as script and style,
+// which go with everything inside them. Comments are always dropped.
+package sanitize
+
+import (
+	"strings"
+	"sync"
+)
+
+// Policy lists what survives sanitizing. Policies are safe for concurrent
+// use once built.
+type Policy struct {
+	elements    map[string]map[string]bool // element -> allowed attributes
+	global      map[string]bool
+	schemes     map[string]bool
+	nofollow    bool
+	relativeURL bool
+}
+
+// NewPolicy returns a policy allowing nothing but text.
+func NewPolicy() *Policy {
+	return &Policy{
+		elements:    map[string]map[string]bool{},
+		global:      map[string]bool{},
+		schemes:     map[string]bool{"http": true, "https": true, "mailto": true},
+		relativeURL: true,
+	}
+}
+
+// AllowElements keeps elements, without attributes unless allowed apart.
+func (p *Policy) AllowElements(names ...string) *Policy {
+	for _, n := range names {
+		n = strings.ToLower(n)
+		if p.elements[n] == nil {
+			p.elements[n] = map[string]bool{}
+		}
+	}
+	return p
+}
+
+// AttrPolicy allows attributes on the elements given to OnElements, or on
+// every allowed element with Globally.
+type AttrPolicy struct {
+	p     *Policy
+	attrs []string
+}
+
+// AllowAttrs starts allowing attributes. Event handlers and style are never
+// kept, whatever the policy says.
+func (p *Policy) AllowAttrs(attrs ...string) *AttrPolicy {
+	return &AttrPolicy{p: p, attrs: attrs}
+}
+
+// OnElements allows the attributes on elements, allowing the elements too.
+func (a *AttrPolicy) OnElements(names ...string) *Policy {
+	a.p.AllowElements(names...)
+	for _, n := range names {
+		for _, attr := range a.attrs {
+			a.p.elements[strings.ToLower(n)][strings.ToLower(attr)] = true
+		}
+	}
+	return a.p
+}
+
+// Globally allows the attributes on every allowed element.
+func (a *AttrPolicy) Globally() *Policy {
+	for _, attr := range a.attrs {
+		a.p.global[strings.ToLower(attr)] = true
+	}
+	return a.p
+}
+
+// AllowURLSchemes replaces the schemes URL attributes may use, http, https
+// and mailto by default.
+func (p *Policy) AllowURLSchemes(schemes ...string) *Policy {
+	p.schemes = map[string]bool{}
+	for _, s := range schemes {
+		p.schemes[strings.ToLower(s)] = true
+	}
+	return p
+}
+
+// AllowRelativeURLs sets whether URLs without a scheme are kept, which they
+// are by default.
+func (p *Policy) AllowRelativeURLs(allow bool) *Policy {
+	p.relativeURL = allow
+	return p
+}
+
+// RequireNoFollowLinks adds rel="nofollow noopener" to links, replacing
+// the rel they had.
+func (p *Policy) RequireNoFollowLinks() *Policy {
+	p.nofollow = true
+	return p
+}
+
+// Strict allows no HTML at all, leaving the text.
+func Strict() *Policy {
+	return NewPolicy()
+}
+
+// UGC allows the formatting of user generated content: paragraphs,
+// emphasis, lists, quotes, code, headings, tables, links and images, with
+// links marked nofollow.
+func UGC() *Policy {
+	return NewPolicy().
+		AllowElements("p", "br", "hr", "b", "strong", "i", "em", "u", "s", "del", "ins", "mark", "small", "sub", "sup",
+			"ul", "ol", "li", "dl", "dt", "dd", "blockquote", "code", "pre", "kbd",
+			"h1", "h2", "h3", "h4", "h5", "h6", "table", "thead", "tbody", "tfoot", "tr", "th", "td", "caption", "figure", "figcaption").
+		AllowAttrs("href", "title").OnElements("a").
+		AllowAttrs("src", "alt", "title", "width", "height").OnElements("img").
+		AllowAttrs("cite").OnElements("blockquote", "q", "del", "ins").
+		AllowAttrs("colspan", "rowspan").OnElements("td", "th").
+		AllowAttrs("start").OnElements("ol").
+		RequireNoFollowLinks()
+}
+
+var (
+	mu       sync.RWMutex
+	policies = map[string]*Policy{"strict": Strict(), "ugc": UGC()}
+)
+
+// Register names a policy for the sanitize=name validate rule. strict and
+// ugc are registered.
+func Register(name string, p *Policy) {
+	mu.Lock()
+	defer mu.Unlock()
+	policies[name] = p
+}
+
+// Named returns a registered policy.
+func Named(name string) (*Policy, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := policies[name]
+	return p, ok
+}
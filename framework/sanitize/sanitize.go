@@ -0,0 +1,131 @@
+package sanitize
+
+import (
+	"html"
+	"net/url"
+	"strings"
+
+	nethtml "golang.org/x/net/html"
+)
+
+// dropped are the elements removed with their content.
+var dropped = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true, "noscript": true,
+	"template": true, "textarea": true, "select": true, "svg": true, "math": true, "head": true, "title": true,
+}
+
+// void are the elements without an end tag.
+var void = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// urlAttrs hold URLs, which must use an allowed scheme.
+var urlAttrs = map[string]bool{"href": true, "src": true, "cite": true, "action": true, "poster": true}
+
+// Sanitize returns s with everything the policy does not allow removed.
+func (p *Policy) Sanitize(s string) string {
+	var b strings.Builder
+	z := nethtml.NewTokenizer(strings.NewReader(s))
+	var open []string
+	skip := ""
+	depth := 0
+
+	for {
+		tt := z.Next()
+		if tt == nethtml.ErrorToken {
+			break
+		}
+		tok := z.Token()
+		name := strings.ToLower(tok.Data)
+
+		if skip != "" {
+			switch {
+			case tt == nethtml.StartTagToken && name == skip:
+				depth++
+			case tt == nethtml.EndTagToken && name == skip:
+				depth--
+				if depth == 0 {
+					skip = ""
+				}
+			}
+			continue
+		}
+
+		switch tt {
+		case nethtml.TextToken:
+			b.WriteString(html.EscapeString(tok.Data))
+		case nethtml.StartTagToken, nethtml.SelfClosingTagToken:
+			if dropped[name] {
+				if tt == nethtml.StartTagToken && !void[name] {
+					skip, depth = name, 1
+				}
+				continue
+			}
+			allowed, ok := p.elements[name]
+			if !ok {
+				continue
+			}
+			b.WriteString("<" + name)
+			for _, a := range tok.Attr {
+				key := strings.ToLower(a.Key)
+				if a.Namespace != "" || !(allowed[key] || p.global[key]) || strings.HasPrefix(key, "on") || key == "style" {
+					continue
+				}
+				if urlAttrs[key] && !p.allowedURL(a.Val) {
+					continue
+				}
+				if key == "rel" && name == "a" && p.nofollow {
+					continue
+				}
+				b.WriteString(" " + key + `="` + html.EscapeString(a.Val) + `"`)
+			}
+			if name == "a" && p.nofollow {
+				b.WriteString(` rel="nofollow noopener"`)
+			}
+			b.WriteString(">")
+			if !void[name] && tt == nethtml.StartTagToken {
+				open = append(open, name)
+			}
+		case nethtml.EndTagToken:
+			if _, ok := p.elements[name]; !ok || void[name] {
+				continue
+			}
+			// Close what was left open inside, and ignore strays
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] != name {
+					continue
+				}
+				for j := len(open) - 1; j >= i; j-- {
+					b.WriteString("</" + open[j] + ">")
+				}
+				open = open[:i]
+				break
+			}
+		}
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+	return b.String()
+}
+
+// allowedURL reports whether a URL is relative, when allowed, or uses an
+// allowed scheme. Whitespace and control characters browsers ignore are
+// ignored here too, so "java\tscript:" is seen for what it is.
+func (p *Policy) allowedURL(raw string) bool {
+	cleaned := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, raw)
+	u, err := url.Parse(cleaned)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" {
+		return p.relativeURL && !strings.HasPrefix(cleaned, "//") || p.schemes["https"] && strings.HasPrefix(cleaned, "//")
+	}
+	return p.schemes[strings.ToLower(u.Scheme)]
+}
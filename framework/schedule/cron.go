@@ -0,0 +1,171 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed cron expression of five fields: minute, hour, day of the
+// month, month and day of the week. Fields take *, numbers, ranges, lists
+// and steps, months and days of the week their English abbreviations too.
+// As in cron, a day matches either day field when both are restricted.
+type Cron struct {
+	minute, hour, dom, month, dow bits
+	// domAny and dowAny tell a * day field apart from one listing every day.
+	domAny, dowAny bool
+}
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	dayNames   = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// ParseCron parses a cron expression or one of the @hourly, @daily,
+// @weekly, @monthly and @yearly macros.
+func ParseCron(spec string) (*Cron, error) {
+	expr := strings.TrimSpace(spec)
+	if m, ok := macros[strings.ToLower(expr)]; ok {
+		expr = m
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule: %q must have 5 fields", spec)
+	}
+
+	c := &Cron{domAny: fields[2] == "*", dowAny: fields[4] == "*"}
+	var err error
+	if c.minute, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("schedule: minute of %q: %w", spec, err)
+	}
+	if c.hour, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("schedule: hour of %q: %w", spec, err)
+	}
+	if c.dom, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("schedule: day of month of %q: %w", spec, err)
+	}
+	if c.month, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("schedule: month of %q: %w", spec, err)
+	}
+	// 7 is Sunday as well
+	if c.dow, err = parseField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, fmt.Errorf("schedule: day of week of %q: %w", spec, err)
+	}
+	if c.dow.has(7) {
+		c.dow |= 1
+	}
+	return c, nil
+}
+
+// Matches reports whether the minute of t is due.
+func (c *Cron) Matches(t time.Time) bool {
+	return c.minute.has(t.Minute()) && c.hour.has(t.Hour()) && c.month.has(int(t.Month())) && c.day(t)
+}
+
+// Next returns the first due minute after t, or the zero time when there is
+// none within five years, such as for February 30.
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	for limit := t.AddDate(5, 0, 0); t.Before(limit); {
+		y, m, d := t.Date()
+		switch {
+		case !c.month.has(int(m)):
+			t = time.Date(y, m+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.day(t):
+			t = time.Date(y, m, d+1, 0, 0, 0, 0, t.Location())
+		case !c.hour.has(t.Hour()):
+			t = time.Date(y, m, d, t.Hour()+1, 0, 0, 0, t.Location())
+		case !c.minute.has(t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (c *Cron) day(t time.Time) bool {
+	dom, dow := c.dom.has(t.Day()), c.dow.has(int(t.Weekday()))
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// bits has bit n set when value n matches.
+type bits uint64
+
+func (b bits) has(n int) bool {
+	return b&(1<<n) != 0
+}
+
+// parseField parses a comma separated list of *, n, a-b, each optionally
+// followed by a /step.
+func parseField(field string, lo, hi int, names []string) (bits, error) {
+	var b bits
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepStr)
+			}
+		}
+
+		var from, to int
+		switch a, z, isRange := strings.Cut(rng, "-"); {
+		case rng == "*":
+			from, to = lo, hi
+		case isRange:
+			var err error
+			if from, err = parseValue(a, lo, hi, names); err != nil {
+				return 0, err
+			}
+			if to, err = parseValue(z, lo, hi, names); err != nil {
+				return 0, err
+			}
+			if from > to {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		default:
+			v, err := parseValue(rng, lo, hi, names)
+			if err != nil {
+				return 0, err
+			}
+			// n/step runs from n to the end, as in cron
+			from, to = v, v
+			if hasStep {
+				to = hi
+			}
+		}
+		for v := from; v <= to; v += step {
+			b |= 1 << v
+		}
+	}
+	return b, nil
+}
+
+func parseValue(s string, lo, hi int, names []string) (int, error) {
+	for i, name := range names {
+		if strings.EqualFold(s, name) {
+			return i + lo, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < lo || v > hi {
+		return 0, fmt.Errorf("%q is not within %d-%d", s, lo, hi)
+	}
+	return v, nil
+}
@@ -0,0 +1,112 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/lemmego/lemmego/framework/metrics"
+	"github.com/lemmego/lemmego/framework/str"
+)
+
+var (
+	leaderGauge   = metrics.Gauge("schedule_leader", "Whether this instance leads, by lease.")
+	leaderChanges = metrics.Counter("schedule_leader_changes_total", "Leaderships taken and lost by this instance, by lease.")
+)
+
+// Elector campaigns for a lease among instances, so that a single one of them
+// leads at a time. Should the leader go away, another one takes over once its
+// lease expires.
+type Elector struct {
+	Lease Lease
+	// Name of the lease, instances campaigning for the same name compete.
+	Name string
+	// Holder identifies the instance, its host name and process id with a
+	// random suffix when empty.
+	Holder string
+	// TTL is how long the lease is held without being renewed, 15 seconds
+	// when zero. It is renewed every third of it.
+	TTL time.Duration
+}
+
+// DefaultHolder identifies the current process among instances.
+func DefaultHolder() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d:%s", host, os.Getpid(), str.RandomString(6))
+}
+
+// Run campaigns until ctx is done, calling lead whenever the instance takes
+// the lease. The context of lead is cancelled once the lease is lost, and
+// the lease is only campaigned for again after lead returns.
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context)) {
+	if e.Holder == "" {
+		e.Holder = DefaultHolder()
+	}
+	if e.TTL <= 0 {
+		e.TTL = 15 * time.Second
+	}
+
+	ticker := time.NewTicker(e.TTL / 3)
+	defer ticker.Stop()
+	for {
+		ok, err := e.Lease.Acquire(ctx, e.Name, e.Holder, e.TTL)
+		if err != nil && ctx.Err() == nil {
+			slog.Warn(fmt.Sprintf("schedule: campaign for %s: %s", e.Name, err))
+		}
+		if ok {
+			e.lead(ctx, ticker, lead)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Elector) lead(ctx context.Context, ticker *time.Ticker, lead func(ctx context.Context)) {
+	labels := metrics.Labels{"lease": e.Name}
+	slog.Info(fmt.Sprintf("schedule: %s leads %s", e.Holder, e.Name))
+	leaderGauge.With(labels).Set(1)
+	leaderChanges.With(metrics.Labels{"lease": e.Name, "change": "acquired"}).Inc()
+
+	leadCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lead(leadCtx)
+	}()
+
+	for stop := false; !stop; {
+		select {
+		case <-ctx.Done():
+			stop = true
+		case <-done:
+			stop = true
+		case <-ticker.C:
+			ok, err := e.Lease.Acquire(ctx, e.Name, e.Holder, e.TTL)
+			if err != nil && ctx.Err() != nil {
+				continue
+			}
+			// Unable to tell whether the lease is still held, stepping
+			// down is the safe bet
+			if !ok {
+				slog.Warn(fmt.Sprintf("schedule: %s lost %s: %v", e.Holder, e.Name, err))
+				stop = true
+			}
+		}
+	}
+	cancel()
+	<-done
+
+	// Let another instance take over right away
+	releaseCtx, cancelRelease := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancelRelease()
+	if err := e.Lease.Release(releaseCtx, e.Name, e.Holder); err != nil {
+		slog.Warn(fmt.Sprintf("schedule: release %s: %s", e.Name, err))
+	}
+	leaderGauge.With(labels).Set(0)
+	leaderChanges.With(metrics.Labels{"lease": e.Name, "change": "lost"}).Inc()
+}
@@ -0,0 +1,119 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/lemmego/api/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Lease is held by at most one holder at a time, until it expires unless
+// renewed.
+type Lease interface {
+	// Acquire takes the lease for holder, or renews it when holder has it,
+	// until ttl from now, reporting whether holder has it.
+	Acquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	// Release gives the lease up if holder has it.
+	Release(ctx context.Context, name, holder string) error
+}
+
+// leaseRow is a lease in the leases table.
+type leaseRow struct {
+	Name      string `gorm:"primaryKey"`
+	Holder    string
+	ExpiresAt time.Time
+}
+
+func (leaseRow) TableName() string {
+	return "leases"
+}
+
+// DatabaseLease keeps leases in the leases table. The clocks of the
+// instances must agree to well within the TTL.
+type DatabaseLease struct {
+	connName []string
+}
+
+func NewDatabaseLease(connName ...string) *DatabaseLease {
+	return &DatabaseLease{connName: connName}
+}
+
+func (l *DatabaseLease) db(ctx context.Context) *gorm.DB {
+	return db.Get(l.connName...).DB().WithContext(ctx)
+}
+
+func (l *DatabaseLease) Acquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	err := l.db(ctx).Model(&leaseRow{}).
+		Where("name = ? AND (holder = ? OR expires_at < ?)", name, holder, now).
+		Updates(map[string]any{"holder": holder, "expires_at": now.Add(ttl)}).Error
+	if err != nil {
+		return false, err
+	}
+	// The first time around there is no row to take over
+	err = l.db(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&leaseRow{Name: name, Holder: holder, ExpiresAt: now.Add(ttl)}).Error
+	if err != nil {
+		return false, err
+	}
+
+	// Updates may affect no row even when they match, so the holder is read back
+	var row leaseRow
+	err = l.db(ctx).Where("name = ?", name).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return err == nil && row.Holder == holder, err
+}
+
+func (l *DatabaseLease) Release(ctx context.Context, name, holder string) error {
+	return l.db(ctx).Where("name = ? AND holder = ?", name, holder).Delete(&leaseRow{}).Error
+}
+
+// RedisLease keeps leases in Redis, under the key lease:<name>.
+type RedisLease struct {
+	pool *redis.Pool
+}
+
+func NewRedisLease(pool *redis.Pool) *RedisLease {
+	return &RedisLease{pool: pool}
+}
+
+// acquireScript sets the key to the holder unless another holder has it.
+var acquireScript = redis.NewScript(1, `
+local current = redis.call("GET", KEYS[1])
+if current == false or current == ARGV[1] then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	return 1
+end
+return 0`)
+
+// releaseScript deletes the key if the holder has it.
+var releaseScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+func (l *RedisLease) Acquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	conn, err := l.pool.GetContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	return redis.Bool(acquireScript.Do(conn, "lease:"+name, holder, ttl.Milliseconds()))
+}
+
+func (l *RedisLease) Release(ctx context.Context, name, holder string) error {
+	conn, err := l.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = releaseScript.Do(conn, "lease:"+name, holder)
+	return err
+}
@@ -0,0 +1,170 @@
+// Package schedule runs tasks on cron schedules. Any number of instances may
+// run the scheduler: they elect a leader through a lease, in the database or
+// Redis, and only the leader runs the tasks. When it goes away another
+// instance takes over within the lease TTL.
+//
+//	func init() {
+//		schedule.Register("refresh-stats", "*/15 * * * *", func(ctx context.Context) error {
+//			return schema.Refresh(ctx, "order_stats", true)
+//		})
+//	}
+//
+// Tasks are run by schedule:run, or inside the web process with
+// schedule.in_process set.
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/lemmego/lemmego/framework/metrics"
+)
+
+// LeaseName is the lease the instances running the scheduler compete for.
+const LeaseName = "schedule"
+
+var runs = metrics.Counter("schedule_runs_total", "Scheduled task runs, by task and status.")
+
+// Task is work to run on a schedule.
+type Task func(ctx context.Context) error
+
+// Entry is a registered task.
+type Entry struct {
+	Name string
+	Spec string
+	Cron *Cron
+	Task Task
+}
+
+var (
+	entriesMu sync.RWMutex
+	entries   []*Entry
+)
+
+// Register runs task whenever spec is due, see ParseCron. It panics on an
+// invalid spec, registrations being made from init functions.
+func Register(name, spec string, task Task) {
+	c, err := ParseCron(spec)
+	if err != nil {
+		panic(err)
+	}
+	entriesMu.Lock()
+	defer entriesMu.Unlock()
+	entries = append(entries, &Entry{Name: name, Spec: spec, Cron: c, Task: task})
+}
+
+// Entries returns the registered tasks in the order of registration.
+func Entries() []Entry {
+	entriesMu.RLock()
+	defer entriesMu.RUnlock()
+	list := make([]Entry, len(entries))
+	for i, e := range entries {
+		list[i] = *e
+	}
+	return list
+}
+
+// RunOptions configure Run.
+type RunOptions struct {
+	// Lease elects the instance running the tasks. Without one the tasks run
+	// on this instance, which must then be the only one.
+	Lease Lease
+	// Holder and TTL are those of the Elector.
+	Holder string
+	TTL    time.Duration
+}
+
+var (
+	configuredMu sync.RWMutex
+	configured   = &RunOptions{}
+)
+
+// Configure sets the options of Run when it is given none.
+func Configure(opts *RunOptions) {
+	configuredMu.Lock()
+	defer configuredMu.Unlock()
+	configured = opts
+}
+
+// Run runs the due tasks at the start of every minute until ctx is done,
+// while this instance leads, then waits for the running ones. A task still
+// running when it is due again is skipped.
+func Run(ctx context.Context, opts *RunOptions) {
+	o := opts
+	if o == nil {
+		configuredMu.RLock()
+		o = configured
+		configuredMu.RUnlock()
+	}
+	if o.Lease == nil {
+		tick(ctx)
+		return
+	}
+	e := &Elector{Lease: o.Lease, Name: LeaseName, Holder: o.Holder, TTL: o.TTL}
+	e.Run(ctx, tick)
+}
+
+func tick(ctx context.Context) {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		running = make(map[string]bool)
+	)
+	defer wg.Wait()
+
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(next.Sub(now)):
+		}
+
+		for _, e := range Entries() {
+			if !e.Cron.Matches(next) {
+				continue
+			}
+			mu.Lock()
+			if running[e.Name] {
+				mu.Unlock()
+				slog.Warn(fmt.Sprintf("schedule: %s is still running, skipped", e.Name))
+				runs.With(metrics.Labels{"task": e.Name, "status": "skipped"}).Inc()
+				continue
+			}
+			running[e.Name] = true
+			mu.Unlock()
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				runEntry(ctx, e)
+				mu.Lock()
+				delete(running, e.Name)
+				mu.Unlock()
+			}()
+		}
+	}
+}
+
+func runEntry(ctx context.Context, e Entry) {
+	start := time.Now()
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		return e.Task(ctx)
+	}()
+	if err != nil {
+		slog.Error(fmt.Sprintf("schedule: %s failed after %s: %s", e.Name, time.Since(start).Round(time.Millisecond), err))
+		runs.With(metrics.Labels{"task": e.Name, "status": "failed"}).Inc()
+		return
+	}
+	slog.Info(fmt.Sprintf("schedule: %s done in %s", e.Name, time.Since(start).Round(time.Millisecond)))
+	runs.With(metrics.Labels{"task": e.Name, "status": "done"}).Inc()
+}
@@ -0,0 +1,232 @@
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	gormschema "gorm.io/gorm/schema"
+)
+
+// Models registered here are compared with the live database by Diff, which
+// the make:migration --diff command writes as a migration to review:
+//
+//	func init() { schema.RegisterModel(Project{}, Task{}) }
+//
+// Only missing tables and added or removed columns are detected; changes to
+// the type of a column are left to hand written migrations.
+var (
+	modelsMu sync.Mutex
+	models   []any
+)
+
+// RegisterModel adds models to those Diff compares.
+func RegisterModel(m ...any) {
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
+	models = append(models, m...)
+}
+
+// Models returns the registered models.
+func Models() []any {
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
+	return append([]any(nil), models...)
+}
+
+// Change is a difference between a model and its table, as the Go source of
+// the migration steps making and undoing it.
+type Change struct {
+	Table  string
+	Column string
+	// Up and Down are expressions building a statement, or comments for
+	// changes that cannot be undone automatically.
+	Up   string
+	Down string
+}
+
+// Diff compares models with the tables of db. Columns added to existing
+// tables are nullable unless they have a default.
+func Diff(db *gorm.DB, models ...any) ([]Change, error) {
+	var changes []Change
+	m := db.Migrator()
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		s := stmt.Schema
+
+		if !m.HasTable(s.Table) {
+			var lines []string
+			for _, f := range s.Fields {
+				if f.DBName != "" {
+					lines = append(lines, column(f))
+				}
+			}
+			changes = append(changes, Change{
+				Table: s.Table,
+				Up:    fmt.Sprintf("migration.Create(%q, func(t *migration.Table) {\n%s\n}).Build()", s.Table, strings.Join(lines, "\n")),
+				Down:  fmt.Sprintf("migration.Drop(%q).Build()", s.Table),
+			})
+			continue
+		}
+
+		live, err := m.ColumnTypes(s.Table)
+		if err != nil {
+			return nil, err
+		}
+		existing := map[string]gorm.ColumnType{}
+		for _, c := range live {
+			existing[c.Name()] = c
+		}
+		for _, f := range s.Fields {
+			if f.DBName == "" {
+				continue
+			}
+			if _, ok := existing[f.DBName]; ok {
+				delete(existing, f.DBName)
+				continue
+			}
+			// Rows already there need a value for the new column
+			add := column(f)
+			if !f.HasDefaultValue && !strings.HasSuffix(add, ".Nullable()") {
+				add += ".Nullable()"
+			}
+			changes = append(changes, Change{
+				Table:  s.Table,
+				Column: f.DBName,
+				Up:     alter(s.Table, add),
+				Down:   alter(s.Table, fmt.Sprintf("t.DropColumn(%q)", f.DBName)),
+			})
+		}
+		for _, f := range live {
+			if _, ok := existing[f.Name()]; !ok {
+				continue
+			}
+			changes = append(changes, Change{
+				Table:  s.Table,
+				Column: f.Name(),
+				Up:     alter(s.Table, fmt.Sprintf("t.DropColumn(%q)", f.Name())),
+				Down:   fmt.Sprintf("// TODO: add %s.%s back, it was %s", s.Table, f.Name(), f.DatabaseTypeName()),
+			})
+		}
+	}
+	return changes, nil
+}
+
+// alter alters a single column, as SQLite adds or drops one per statement.
+func alter(table, column string) string {
+	return fmt.Sprintf("migration.Alter(%q, func(t *migration.Table) {\n%s\n}).Build()", table, column)
+}
+
+// column is the schema builder call adding a field's column.
+func column(f *gormschema.Field) string {
+	name := f.DBName
+	var call string
+	switch f.DataType {
+	case gormschema.Bool:
+		call = fmt.Sprintf("t.Boolean(%q)", name)
+	case gormschema.Int, gormschema.Uint:
+		switch {
+		case f.PrimaryKey && f.AutoIncrement:
+			call = fmt.Sprintf("t.BigIncrements(%q)", name)
+		case f.Size == 64 && f.DataType == gormschema.Uint:
+			call = fmt.Sprintf("t.UnsignedBigInt(%q)", name)
+		case f.Size == 64:
+			call = fmt.Sprintf("t.BigInt(%q)", name)
+		case f.DataType == gormschema.Uint:
+			call = fmt.Sprintf("t.UnsignedInt(%q)", name)
+		default:
+			call = fmt.Sprintf("t.Int(%q)", name)
+		}
+	case gormschema.Float:
+		call = fmt.Sprintf("t.Double(%q, 0, 0)", name)
+	case gormschema.String:
+		size := f.Size
+		if size == 0 {
+			size = 255
+		}
+		call = fmt.Sprintf("t.String(%q, %d)", name, size)
+	case gormschema.Time:
+		// No precision, see the privacy tables
+		call = fmt.Sprintf("t.Timestamp(%q, 0)", name)
+	case gormschema.Bytes:
+		call = fmt.Sprintf("t.Binary(%q)", name)
+	default:
+		// Serialized and custom types, such as JSON
+		call = fmt.Sprintf("t.Text(%q)", name)
+	}
+
+	if f.PrimaryKey {
+		call += ".Primary()"
+	}
+	if f.FieldType.Kind() == reflect.Ptr && !f.PrimaryKey {
+		call += ".Nullable()"
+	}
+	if f.HasDefaultValue && f.DefaultValue != "" && !f.AutoIncrement {
+		call += fmt.Sprintf(".Default(%q)", f.DefaultValue)
+	}
+	return call
+}
+
+// MigrationsDir is where WriteMigration puts files, MIGRATIONS_DIR as for
+// the migrate command, or internal/migrations.
+func MigrationsDir() string {
+	if dir := os.Getenv("MIGRATIONS_DIR"); dir != "" {
+		return strings.TrimSuffix(dir, "/")
+	}
+	return "./internal/migrations"
+}
+
+// WriteMigration writes changes as a migration named name to dir and
+// returns its path.
+func WriteMigration(dir, name string, changes []Change) (string, error) {
+	version := time.Now().Format("20060102150405")
+	fn := "mig_" + version + "_" + name
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "package %s\n\n", filepath.Base(dir))
+	b.WriteString("import (\n\t\"database/sql\"\n\t\"github.com/lemmego/migration\"\n)\n\n")
+	fmt.Fprintf(&b, "func init() {\n\tmigration.GetMigrator().AddMigration(&migration.Migration{\n\t\tVersion: %q,\n\t\tUp: %s_up,\n\t\tDown: %s_down,\n\t})\n}\n\n", version, fn, fn)
+	b.WriteString("// Generated from the models by make:migration --diff, review before running.\n\n")
+	steps := func(suffix string, up bool) {
+		fmt.Fprintf(&b, "func %s_%s(tx *sql.Tx) error {\n\tfor _, statement := range []string{\n", fn, suffix)
+		for i := range changes {
+			c := changes[i]
+			if !up {
+				// Undo in reverse order
+				c = changes[len(changes)-1-i]
+			}
+			step := c.Up
+			if !up {
+				step = c.Down
+			}
+			if strings.HasPrefix(step, "//") {
+				b.WriteString(step + "\n")
+			} else {
+				b.WriteString(step + ",\n")
+			}
+		}
+		b.WriteString("\t} {\n\t\tif _, err := tx.Exec(statement); err != nil {\n\t\t\treturn err\n\t\t}\n\t}\n\treturn nil\n}\n\n")
+	}
+	steps("up", true)
+	steps("down", false)
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, version+"_"+name+".go")
+	return path, os.WriteFile(path, src, 0o644)
+}
@@ -0,0 +1,34 @@
+package schema
+
+import (
+	"strings"
+
+	"github.com/lemmego/migration"
+)
+
+// EnumType is the name of the postgres type of an enum column.
+func EnumType(table, column string) string {
+	return table + "_" + column
+}
+
+// Enum adds a column restricted to values. Postgres gets a native enum type
+// named by EnumType, created ahead of the table; MySQL and SQLite a VARCHAR
+// with a CHECK constraint.
+func (t *Table) Enum(name string, values []string) *migration.Column {
+	quoted := make([]string, len(values))
+	length := 1
+	for i, v := range values {
+		quoted[i] = quote(v)
+		length = max(length, len(v))
+	}
+	list := strings.Join(quoted, ", ")
+
+	if t.dialect == migration.DriverPostgres {
+		typ := EnumType(t.name, name)
+		t.before = append(t.before, "CREATE TYPE "+typ+" AS ENUM ("+list+")")
+		return t.native(name, typ)
+	}
+
+	dt := migration.NewDataType(name, migration.ColTypeVarchar, t.dialect).WithLength(uint(length))
+	return t.AddColumn(name, dt.AppendSufix("CHECK ("+name+" IN ("+list+"))"))
+}
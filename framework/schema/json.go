@@ -0,0 +1,25 @@
+package schema
+
+import "github.com/lemmego/migration"
+
+// Json adds a column holding JSON documents: JSON on PostgreSQL and MySQL,
+// TEXT on SQLite, whose JSON functions read text.
+func (t *Table) Json(name string) *migration.Column {
+	return t.json(name, "JSON")
+}
+
+// Jsonb adds a JSON column stored in binary form on PostgreSQL, which the
+// containment operator and GIN indexes need. Other drivers get Json.
+func (t *Table) Jsonb(name string) *migration.Column {
+	if t.dialect == migration.DriverPostgres {
+		return t.native(name, "JSONB")
+	}
+	return t.json(name, "JSON")
+}
+
+func (t *Table) json(name, sqlType string) *migration.Column {
+	if t.dialect == migration.DriverSQLite {
+		return t.Text(name)
+	}
+	return t.native(name, sqlType)
+}
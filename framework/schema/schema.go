@@ -0,0 +1,108 @@
+// Package schema adds the column types the migration builder cannot express
+// on every driver, such as enums, to its tables. Create and Alter take the
+// same callback as their migration counterparts and return the statements
+// to run, in order:
+//
+//	stmts := schema.Create("orders", func(t *schema.Table) {
+//		t.Ulid("id").Primary()
+//		t.Enum("status", []string{"pending", "paid", "shipped"}).Default("'pending'")
+//	})
+//	for _, stmt := range stmts {
+//		if _, err := tx.Exec(stmt); err != nil {
+//			return err
+//		}
+//	}
+package schema
+
+import (
+	"os"
+	"strings"
+
+	"github.com/lemmego/migration"
+
+	"github.com/lemmego/lemmego/framework/ids"
+)
+
+// Table is a migration table with the helpers of this package.
+type Table struct {
+	*migration.Table
+	name    string
+	dialect string
+	// before runs ahead of the table's statement, e.g. CREATE TYPE
+	before []string
+	// natives maps columns built as TEXT to the type they really have
+	natives map[string]string
+}
+
+// Dialect is the driver migrations are built for, as the migration package
+// determines it.
+func Dialect() string {
+	if d := os.Getenv("DB_DRIVER"); d != "" {
+		return d
+	}
+	return migration.DriverSQLite
+}
+
+// Create returns the statements creating a table.
+func Create(table string, fn func(t *Table)) []string {
+	var t *Table
+	sql := migration.Create(table, func(mt *migration.Table) {
+		t = newTable(table, mt)
+		fn(t)
+	}).Build()
+	return t.statements(sql)
+}
+
+// Alter returns the statements altering a table.
+func Alter(table string, fn func(t *Table)) []string {
+	var t *Table
+	sql := migration.Alter(table, func(mt *migration.Table) {
+		t = newTable(table, mt)
+		fn(t)
+	}).Build()
+	return t.statements(sql)
+}
+
+// Drop returns the statements dropping a table, and on postgres the enum
+// types of the columns named.
+func Drop(table string, enumColumns ...string) []string {
+	stmts := []string{migration.Drop(table).Build()}
+	if Dialect() == migration.DriverPostgres {
+		for _, column := range enumColumns {
+			stmts = append(stmts, "DROP TYPE IF EXISTS "+EnumType(table, column))
+		}
+	}
+	return stmts
+}
+
+func newTable(name string, mt *migration.Table) *Table {
+	return &Table{Table: mt, name: name, dialect: Dialect(), natives: map[string]string{}}
+}
+
+func (t *Table) statements(sql string) []string {
+	// Columns start a line with their name, then their type
+	for column, native := range t.natives {
+		sql = strings.Replace(sql, "\n"+column+" TEXT", "\n"+column+" "+native, 1)
+	}
+	return append(t.before, sql)
+}
+
+// native adds a column of a type the builder does not know.
+func (t *Table) native(name, sqlType string) *migration.Column {
+	t.natives[name] = sqlType
+	return t.Text(name)
+}
+
+// Ulid adds a column holding ULIDs.
+func (t *Table) Ulid(name string) *migration.Column {
+	return ids.ULIDColumn(t.Table, name)
+}
+
+// Uuid adds a column holding UUIDs.
+func (t *Table) Uuid(name string) *migration.Column {
+	return ids.UUIDColumn(t.Table, name)
+}
+
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,219 @@
+package schema
+
+import (
+	"strings"
+
+	"github.com/lemmego/migration"
+)
+
+// Triggers run statements on every row a write touches, e.g. to keep an
+// audit table that no code path can forget to fill:
+//
+//	audit := schema.Trigger{
+//		Name:   "orders_audit",
+//		Table:  "orders",
+//		Timing: schema.After,
+//		Events: []string{schema.Update},
+//		When:   "OLD.status <> NEW.status",
+//		Body:   "INSERT INTO order_audits (order_id, status) VALUES (NEW.id, NEW.status)",
+//	}
+//	stmts := schema.CreateTrigger(audit)
+//
+// Bodies see the row through NEW and OLD on every driver, so plain INSERT,
+// UPDATE and DELETE statements run anywhere.
+
+// Trigger timings.
+const (
+	Before = "BEFORE"
+	After  = "AFTER"
+)
+
+// Trigger events.
+const (
+	Insert = "INSERT"
+	Update = "UPDATE"
+	Delete = "DELETE"
+)
+
+// Trigger describes a row level trigger.
+type Trigger struct {
+	Name   string
+	Table  string
+	Timing string
+	Events []string
+	// Columns limits update triggers to changes of these columns.
+	Columns []string
+	// When is an optional condition on NEW and OLD.
+	When string
+	// Body is one or more statements separated by semicolons.
+	Body string
+}
+
+// CreateTrigger returns the statements creating a trigger. PostgreSQL gets
+// a trigger function named after it with a "_fn" suffix; MySQL and SQLite,
+// whose triggers fire on a single event, one trigger per event, suffixed
+// with the event when there are several.
+func CreateTrigger(t Trigger) []string {
+	if Dialect() == migration.DriverPostgres {
+		events := make([]string, len(t.Events))
+		for i, e := range t.Events {
+			events[i] = event(e, t.Columns)
+		}
+		trigger := "CREATE TRIGGER " + t.Name + " " + t.Timing + " " + strings.Join(events, " OR ") +
+			" ON " + t.Table + " FOR EACH ROW"
+		if t.When != "" {
+			trigger += " WHEN (" + t.When + ")"
+		}
+		return []string{
+			"CREATE OR REPLACE FUNCTION " + t.Name + "_fn() RETURNS trigger LANGUAGE plpgsql AS $$\nBEGIN\n" +
+				statements(t.Body) + "\nRETURN COALESCE(NEW, OLD);\nEND\n$$",
+			trigger + " EXECUTE FUNCTION " + t.Name + "_fn()",
+		}
+	}
+
+	mysql := Dialect() == migration.DriverMySQL
+	var stmts []string
+	for _, e := range t.Events {
+		body := statements(t.Body)
+		columns, when := t.Columns, t.When
+		if mysql && e == Update && len(columns) > 0 {
+			// MySQL has no UPDATE OF, compare the columns instead
+			changed := make([]string, len(columns))
+			for i, c := range columns {
+				changed[i] = "NOT (OLD." + c + " <=> NEW." + c + ")"
+			}
+			when = and("("+strings.Join(changed, " OR ")+")", when)
+			columns = nil
+		}
+
+		trigger := "CREATE TRIGGER " + triggerName(t, e) + " " + t.Timing + " " + event(e, columns) +
+			" ON " + t.Table + " FOR EACH ROW"
+		if when != "" {
+			if mysql {
+				body = "IF " + when + " THEN\n" + body + "\nEND IF;"
+			} else {
+				trigger += " WHEN " + when
+			}
+		}
+		stmts = append(stmts, trigger+"\nBEGIN\n"+body+"\nEND")
+	}
+	return stmts
+}
+
+// DropTrigger returns the statements dropping a trigger made by
+// CreateTrigger.
+func DropTrigger(t Trigger) []string {
+	if Dialect() == migration.DriverPostgres {
+		return []string{
+			"DROP TRIGGER IF EXISTS " + t.Name + " ON " + t.Table,
+			"DROP FUNCTION IF EXISTS " + t.Name + "_fn()",
+		}
+	}
+	var stmts []string
+	for _, e := range t.Events {
+		stmts = append(stmts, "DROP TRIGGER IF EXISTS "+triggerName(t, e))
+	}
+	return stmts
+}
+
+func and(a, b string) string {
+	if b == "" {
+		return a
+	}
+	return a + " AND (" + b + ")"
+}
+
+func triggerName(t Trigger, event string) string {
+	if len(t.Events) == 1 {
+		return t.Name
+	}
+	return t.Name + "_" + strings.ToLower(event)
+}
+
+func event(e string, columns []string) string {
+	if e == Update && len(columns) > 0 {
+		return e + " OF " + strings.Join(columns, ", ")
+	}
+	return e
+}
+
+// statements ends every statement of body with a semicolon, as trigger and
+// routine bodies want.
+func statements(body string) string {
+	body = strings.TrimSpace(body)
+	if body != "" && !strings.HasSuffix(body, ";") {
+		body += ";"
+	}
+	return body
+}
+
+// Routine describes a stored function or procedure. Params and Body are
+// written in the database's own dialect; SQLite has neither, and gets no
+// statements.
+type Routine struct {
+	Name string
+	// Params is the parameter list, e.g. "order_id BIGINT".
+	Params string
+	// Returns is the type a function returns.
+	Returns string
+	// Body is the statements between BEGIN and END.
+	Body string
+	// Deterministic marks functions returning the same result for the same
+	// arguments, which MySQL wants declared.
+	Deterministic bool
+}
+
+// CreateFunction returns the statements creating a stored function.
+func CreateFunction(r Routine) []string {
+	switch Dialect() {
+	case migration.DriverPostgres:
+		volatility := "VOLATILE"
+		if r.Deterministic {
+			volatility = "IMMUTABLE"
+		}
+		return []string{"CREATE OR REPLACE FUNCTION " + r.Name + "(" + r.Params + ") RETURNS " + r.Returns +
+			" LANGUAGE plpgsql " + volatility + " AS $$\nBEGIN\n" + statements(r.Body) + "\nEND\n$$"}
+	case migration.DriverMySQL:
+		characteristic := "NOT DETERMINISTIC"
+		if r.Deterministic {
+			characteristic = "DETERMINISTIC"
+		}
+		return []string{"CREATE FUNCTION " + r.Name + "(" + r.Params + ") RETURNS " + r.Returns + " " +
+			characteristic + "\nBEGIN\n" + statements(r.Body) + "\nEND"}
+	}
+	return nil
+}
+
+// DropFunction returns the statements dropping a stored function.
+func DropFunction(r Routine) []string {
+	switch Dialect() {
+	case migration.DriverPostgres:
+		return []string{"DROP FUNCTION IF EXISTS " + r.Name + "(" + r.Params + ")"}
+	case migration.DriverMySQL:
+		return []string{"DROP FUNCTION IF EXISTS " + r.Name}
+	}
+	return nil
+}
+
+// CreateProcedure returns the statements creating a stored procedure.
+func CreateProcedure(r Routine) []string {
+	switch Dialect() {
+	case migration.DriverPostgres:
+		return []string{"CREATE OR REPLACE PROCEDURE " + r.Name + "(" + r.Params + ") LANGUAGE plpgsql AS $$\nBEGIN\n" +
+			statements(r.Body) + "\nEND\n$$"}
+	case migration.DriverMySQL:
+		return []string{"CREATE PROCEDURE " + r.Name + "(" + r.Params + ")\nBEGIN\n" + statements(r.Body) + "\nEND"}
+	}
+	return nil
+}
+
+// DropProcedure returns the statements dropping a stored procedure.
+func DropProcedure(r Routine) []string {
+	switch Dialect() {
+	case migration.DriverPostgres:
+		return []string{"DROP PROCEDURE IF EXISTS " + r.Name + "(" + r.Params + ")"}
+	case migration.DriverMySQL:
+		return []string{"DROP PROCEDURE IF EXISTS " + r.Name}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lemmego/migration"
+	"gorm.io/gorm"
+
+	"github.com/lemmego/lemmego/framework/queue"
+	"github.com/lemmego/lemmego/framework/repo"
+)
+
+// Reporting views are created by migrations, so their query is versioned
+// with the tables it reads:
+//
+//	for _, stmt := range schema.CreateMaterializedView("monthly_revenue", `
+//		SELECT strftime('%Y-%m', paid_at) AS month, SUM(total) AS total
+//		FROM orders GROUP BY 1`) {
+//		if _, err := tx.Exec(stmt); err != nil {
+//			return err
+//		}
+//	}
+//
+// and refreshed on a schedule, from cron with the view:refresh command or by
+// dispatching a RefreshJob:
+//
+//	queue.Dispatch(ctx, &schema.RefreshJob{View: "monthly_revenue"})
+//
+// PostgreSQL has materialized views. MySQL and SQLite get a table filled
+// from a plain view named with SourceSuffix, which refreshing copies again.
+
+func init() {
+	queue.Register[RefreshJob]("schema.refresh_view")
+}
+
+// SourceSuffix names the view holding the query of a materialized view on
+// databases without them.
+const SourceSuffix = "_source"
+
+var ErrViewName = errors.New("schema: view names are letters, digits and underscores")
+
+// CreateView returns the statement creating a view.
+func CreateView(name, query string) string {
+	return "CREATE VIEW " + name + " AS " + query
+}
+
+// DropView returns the statement dropping a view.
+func DropView(name string) string {
+	return "DROP VIEW IF EXISTS " + name
+}
+
+// CreateMaterializedView returns the statements creating a materialized
+// view, filled with the rows of query.
+func CreateMaterializedView(name, query string) []string {
+	if Dialect() == migration.DriverPostgres {
+		return []string{"CREATE MATERIALIZED VIEW " + name + " AS " + query}
+	}
+	return []string{
+		CreateView(name+SourceSuffix, query),
+		"CREATE TABLE " + name + " AS SELECT * FROM " + name + SourceSuffix,
+	}
+}
+
+// DropMaterializedView returns the statements dropping a materialized view.
+func DropMaterializedView(name string) []string {
+	if Dialect() == migration.DriverPostgres {
+		return []string{"DROP MATERIALIZED VIEW IF EXISTS " + name}
+	}
+	return []string{"DROP TABLE IF EXISTS " + name, DropView(name + SourceSuffix)}
+}
+
+// RefreshMaterializedView returns the statements running the query of a
+// materialized view again, e.g. from a migration changing the data it reads.
+func RefreshMaterializedView(name string) []string {
+	return refresh(Dialect(), name, false)
+}
+
+// Refresh runs the query of a materialized view again, in a transaction.
+// Concurrently lets PostgreSQL serve the view while it refreshes, which
+// needs a unique index on it.
+func Refresh(ctx context.Context, name string, concurrently bool) error {
+	if !validName(name) {
+		return fmt.Errorf("%w: %q", ErrViewName, name)
+	}
+	db := repo.DB(ctx)
+	stmts := refresh(db.Dialector.Name(), name, concurrently)
+	if concurrently && db.Dialector.Name() == migration.DriverPostgres {
+		// REFRESH ... CONCURRENTLY refuses to run in a transaction block
+		return db.Exec(stmts[0]).Error
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range stmts {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func refresh(dialect, name string, concurrently bool) []string {
+	if dialect == migration.DriverPostgres {
+		if concurrently {
+			return []string{"REFRESH MATERIALIZED VIEW CONCURRENTLY " + name}
+		}
+		return []string{"REFRESH MATERIALIZED VIEW " + name}
+	}
+	return []string{
+		"DELETE FROM " + name,
+		"INSERT INTO " + name + " SELECT * FROM " + name + SourceSuffix,
+	}
+}
+
+// validName keeps names handed to Refresh at runtime out of the SQL they are
+// spliced into.
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// RefreshJob refreshes a materialized view on a worker.
+type RefreshJob struct {
+	View         string `json:"view"`
+	Concurrently bool   `json:"concurrently"`
+}
+
+func (j *RefreshJob) Handle(ctx context.Context) error {
+	return Refresh(ctx, j.View, j.Concurrently)
+}
@@ -0,0 +1,383 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Filter is a parsed filter query parameter, e.g.
+// userName eq "jane" or emails[type eq "work" and value co "@example.com"].
+// Attribute names and string comparisons are case insensitive.
+type Filter struct {
+	root expr
+}
+
+// ParseFilter parses a filter, returning nil for an empty one.
+func ParseFilter(s string) (*Filter, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	p := &parser{tokens: tokenize(s)}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return &Filter{root: e}, nil
+}
+
+// Match reports whether resource, as encoded to JSON, passes the filter. A
+// nil filter matches everything.
+func (f *Filter) Match(resource any) bool {
+	if f == nil {
+		return true
+	}
+	m, ok := resource.(map[string]any)
+	if !ok {
+		var err error
+		if m, err = toMap(resource); err != nil {
+			return false
+		}
+	}
+	return f.root.match(m)
+}
+
+// Equals returns the value of a filter of the form attr eq "value", the
+// one identity providers send to look a resource up, so stores can query
+// it directly.
+func (f *Filter) Equals(attr string) (string, bool) {
+	if f == nil {
+		return "", false
+	}
+	c, ok := f.root.(*compare)
+	if !ok || c.op != "eq" || !strings.EqualFold(strings.Join(c.path, "."), attr) {
+		return "", false
+	}
+	s, ok := c.value.(string)
+	return s, ok
+}
+
+func toMap(v any) (map[string]any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	return m, json.Unmarshal(b, &m)
+}
+
+type expr interface {
+	match(m map[string]any) bool
+}
+
+type and struct{ l, r expr }
+
+func (e *and) match(m map[string]any) bool { return e.l.match(m) && e.r.match(m) }
+
+type or struct{ l, r expr }
+
+func (e *or) match(m map[string]any) bool { return e.l.match(m) || e.r.match(m) }
+
+type not struct{ e expr }
+
+func (e *not) match(m map[string]any) bool { return !e.e.match(m) }
+
+// valuePath matches when an element of a multi-valued attribute passes the
+// filter in brackets.
+type valuePath struct {
+	attr   string
+	filter expr
+}
+
+func (e *valuePath) match(m map[string]any) bool {
+	for _, v := range elements(lookup(m, e.attr)) {
+		if sub, ok := v.(map[string]any); ok && e.filter.match(sub) {
+			return true
+		}
+	}
+	return false
+}
+
+type compare struct {
+	path  []string
+	op    string
+	value any
+}
+
+func (e *compare) match(m map[string]any) bool {
+	values := resolve(m, e.path)
+	if e.op == "pr" {
+		for _, v := range values {
+			if v != nil && v != "" {
+				return true
+			}
+		}
+		return false
+	}
+	for _, v := range values {
+		if compareValue(v, e.op, e.value) {
+			return true
+		}
+	}
+	// ne holds for missing attributes too
+	return e.op == "ne" && len(values) == 0
+}
+
+func compareValue(have any, op string, want any) bool {
+	switch w := want.(type) {
+	case string:
+		h, ok := have.(string)
+		if !ok {
+			return false
+		}
+		h, w = strings.ToLower(h), strings.ToLower(w)
+		switch op {
+		case "eq":
+			return h == w
+		case "ne":
+			return h != w
+		case "co":
+			return strings.Contains(h, w)
+		case "sw":
+			return strings.HasPrefix(h, w)
+		case "ew":
+			return strings.HasSuffix(h, w)
+		case "gt":
+			return h > w
+		case "ge":
+			return h >= w
+		case "lt":
+			return h < w
+		case "le":
+			return h <= w
+		}
+	case float64:
+		h, ok := have.(float64)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "eq":
+			return h == w
+		case "ne":
+			return h != w
+		case "gt":
+			return h > w
+		case "ge":
+			return h >= w
+		case "lt":
+			return h < w
+		case "le":
+			return h <= w
+		}
+	default:
+		// Booleans and null
+		switch op {
+		case "eq":
+			return have == want
+		case "ne":
+			return have != want
+		}
+	}
+	return false
+}
+
+// lookup returns the attribute name of m, matched case insensitively.
+func lookup(m map[string]any, name string) any {
+	if v, ok := m[name]; ok {
+		return v
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+// resolve returns the values at path, flattening multi-valued attributes.
+func resolve(m map[string]any, path []string) []any {
+	values := elements(lookup(m, path[0]))
+	if len(path) == 1 {
+		return values
+	}
+	var out []any
+	for _, v := range values {
+		if sub, ok := v.(map[string]any); ok {
+			out = append(out, resolve(sub, path[1:])...)
+		}
+	}
+	return out
+}
+
+func elements(v any) []any {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []any:
+		return v
+	}
+	return []any{v}
+}
+
+// attrPath splits an attribute path, dropping the schema URN it may be
+// qualified with.
+func attrPath(s string) []string {
+	if strings.HasPrefix(strings.ToLower(s), "urn:") {
+		s = s[strings.LastIndex(s, ":")+1:]
+	}
+	return strings.Split(s, ".")
+}
+
+func tokenize(s string) []string {
+	var tokens []string
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(' || c == ')' || c == '[' || c == ']':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			j := i + 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			tokens = append(tokens, s[i:min(j+1, len(s))])
+			i = j + 1
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\n()[]\"", rune(s[j])) {
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		}
+	}
+	return tokens
+}
+
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *parser) expect(t string) error {
+	if got := p.next(); got != t {
+		return fmt.Errorf("expected %q, got %q", t, got)
+	}
+	return nil
+}
+
+func (p *parser) or() (expr, error) {
+	l, err := p.and()
+	for err == nil && strings.EqualFold(p.peek(), "or") {
+		p.pos++
+		var r expr
+		if r, err = p.and(); err == nil {
+			l = &or{l, r}
+		}
+	}
+	return l, err
+}
+
+func (p *parser) and() (expr, error) {
+	l, err := p.unary()
+	for err == nil && strings.EqualFold(p.peek(), "and") {
+		p.pos++
+		var r expr
+		if r, err = p.unary(); err == nil {
+			l = &and{l, r}
+		}
+	}
+	return l, err
+}
+
+func (p *parser) unary() (expr, error) {
+	switch t := p.peek(); {
+	case strings.EqualFold(t, "not"):
+		p.pos++
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return &not{e}, p.expect(")")
+	case t == "(":
+		p.pos++
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return e, p.expect(")")
+	case t == "":
+		return nil, fmt.Errorf("unexpected end of filter")
+	}
+
+	attr := p.next()
+	if p.peek() == "[" {
+		p.pos++
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return &valuePath{attr: attrPath(attr)[0], filter: e}, p.expect("]")
+	}
+
+	op := strings.ToLower(p.next())
+	switch op {
+	case "pr":
+		return &compare{path: attrPath(attr), op: op}, nil
+	case "eq", "ne", "co", "sw", "ew", "gt", "ge", "lt", "le":
+	default:
+		return nil, fmt.Errorf("unknown operator %q", op)
+	}
+	value, err := literal(p.next())
+	if err != nil {
+		return nil, err
+	}
+	return &compare{path: attrPath(attr), op: op, value: value}, nil
+}
+
+func literal(t string) (any, error) {
+	switch strings.ToLower(t) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	case "":
+		return nil, fmt.Errorf("missing value")
+	}
+	if strings.HasPrefix(t, `"`) {
+		return strconv.Unquote(t)
+	}
+	f, err := strconv.ParseFloat(t, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q", t)
+	}
+	return f, nil
+}
@@ -0,0 +1,266 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lemmego/lemmego/framework/org"
+	"github.com/lemmego/lemmego/framework/repo"
+)
+
+func (s *Server) listGroups(w http.ResponseWriter, r *http.Request) {
+	filter, start, count, err := page(r)
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	q := repo.New[org.Organization](r.Context()).Order("id")
+	if name, ok := filter.Equals("displayName"); ok {
+		q = q.Where("LOWER(name) = ?", strings.ToLower(name))
+	}
+	orgs, err := q.Find()
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+
+	var matched []*Group
+	for i := range orgs {
+		g, err := s.group(r, &orgs[i])
+		if err != nil {
+			fail(w, r, err)
+			return
+		}
+		if filter.Match(g) {
+			matched = append(matched, g)
+		}
+	}
+	total := len(matched)
+	matched = matched[min(start-1, total):min(start-1+count, total)]
+	if strings.Contains(strings.ToLower(r.URL.Query().Get("excludedAttributes")), "members") {
+		for _, g := range matched {
+			g.Members = nil
+		}
+	}
+	writeJSON(w, http.StatusOK, list(matched, total, start))
+}
+
+func (s *Server) getGroup(w http.ResponseWriter, r *http.Request) {
+	o, err := findOrg(r.Context(), r.PathValue("id"))
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	g, err := s.group(r, o)
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, g)
+}
+
+func (s *Server) createGroup(w http.ResponseWriter, r *http.Request) {
+	g := &Group{}
+	if err := decode(r, g); err != nil {
+		fail(w, r, err)
+		return
+	}
+	if g.DisplayName == "" {
+		writeError(w, http.StatusBadRequest, "invalidValue", "displayName is required")
+		return
+	}
+	if err := s.checkMembers(r.Context(), g.Members); err != nil {
+		fail(w, r, err)
+		return
+	}
+	o, err := org.Create(r.Context(), g.DisplayName, "")
+	if err == nil {
+		err = s.syncMembers(r.Context(), o.ID, g.Members)
+	}
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	created, err := s.group(r, o)
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	w.Header().Set("Location", created.Meta.Location)
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (s *Server) replaceGroup(w http.ResponseWriter, r *http.Request) {
+	o, err := findOrg(r.Context(), r.PathValue("id"))
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	g := &Group{}
+	if err := decode(r, g); err != nil {
+		fail(w, r, err)
+		return
+	}
+	s.update(w, r, o, g)
+}
+
+func (s *Server) patchGroup(w http.ResponseWriter, r *http.Request) {
+	o, err := findOrg(r.Context(), r.PathValue("id"))
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	body, err := readBody(r)
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	current, err := s.group(r, o)
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	g := &Group{}
+	if err := patch(current, body, g); err != nil {
+		fail(w, r, err)
+		return
+	}
+	s.update(w, r, o, g)
+}
+
+// update renames the organization of a group and brings its members in line
+// with those of g.
+func (s *Server) update(w http.ResponseWriter, r *http.Request, o *org.Organization, g *Group) {
+	if g.DisplayName == "" {
+		writeError(w, http.StatusBadRequest, "invalidValue", "displayName is required")
+		return
+	}
+	if err := s.checkMembers(r.Context(), g.Members); err != nil {
+		fail(w, r, err)
+		return
+	}
+	if g.DisplayName != o.Name {
+		if err := org.Rename(r.Context(), o.ID, g.DisplayName); err != nil {
+			fail(w, r, err)
+			return
+		}
+		o.Name = g.DisplayName
+	}
+	if err := s.syncMembers(r.Context(), o.ID, g.Members); err != nil {
+		fail(w, r, err)
+		return
+	}
+	updated, err := s.group(r, o)
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (s *Server) deleteGroup(w http.ResponseWriter, r *http.Request) {
+	o, err := findOrg(r.Context(), r.PathValue("id"))
+	if err == nil {
+		err = org.Delete(r.Context(), o.ID)
+	}
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkMembers refuses members that are not users.
+func (s *Server) checkMembers(ctx context.Context, members []Ref) error {
+	for _, m := range members {
+		if _, err := s.users.Get(ctx, m.Value); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return &patchError{scimType: "invalidValue", detail: "unknown member " + m.Value}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// syncMembers adds the members missing from the organization, as members,
+// and removes those not listed. Owners cannot all be removed this way.
+func (s *Server) syncMembers(ctx context.Context, orgID uint, members []Ref) error {
+	want := map[string]bool{}
+	for _, m := range members {
+		want[m.Value] = true
+	}
+	current, err := org.Members(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	for _, m := range current {
+		if want[m.UserID] {
+			delete(want, m.UserID)
+			continue
+		}
+		if err := org.Remove(ctx, orgID, m.UserID); err != nil {
+			if errors.Is(err, org.ErrLastOwner) {
+				return &patchError{scimType: "mutability", detail: err.Error()}
+			}
+			return err
+		}
+	}
+	for userID := range want {
+		if _, err := org.Add(ctx, orgID, userID, org.Member); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// group returns the organization as a group.
+func (s *Server) group(r *http.Request, o *org.Organization) (*Group, error) {
+	members, err := org.Members(r.Context(), o.ID)
+	if err != nil {
+		return nil, err
+	}
+	id := strconv.FormatUint(uint64(o.ID), 10)
+	created, modified := o.CreatedAt, o.UpdatedAt
+	g := &Group{
+		Schemas:     []string{GroupSchema},
+		ID:          id,
+		DisplayName: o.Name,
+		Meta: &Meta{
+			ResourceType: "Group",
+			Created:      timeOrNil(created),
+			LastModified: timeOrNil(modified),
+			Location:     s.location(r, "Groups", id),
+		},
+	}
+	for _, m := range members {
+		g.Members = append(g.Members, Ref{Value: m.UserID, Ref: s.location(r, "Users", m.UserID)})
+	}
+	return g, nil
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func findOrg(ctx context.Context, id string) (*org.Organization, error) {
+	n, err := strconv.ParseUint(id, 10, 0)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	orgs, err := repo.New[org.Organization](ctx).Where("id = ?", n).Limit(1).Find()
+	if err != nil {
+		return nil, err
+	}
+	if len(orgs) == 0 {
+		return nil, ErrNotFound
+	}
+	return &orgs[0], nil
+}
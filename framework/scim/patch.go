@@ -0,0 +1,224 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PatchSchema is the schema of PATCH requests.
+const PatchSchema = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+
+// PatchRequest is the body of a PATCH request.
+type PatchRequest struct {
+	Schemas    []string    `json:"schemas"`
+	Operations []Operation `json:"Operations"`
+}
+
+// Operation is an add, replace or remove of a PATCH request. Path is an
+// attribute, such as name.givenName, possibly with a filter selecting
+// elements of a multi-valued one, such as members[value eq "42"] or
+// emails[type eq "work"].value. Without a path, the value is a map of the
+// attributes to add or replace.
+type Operation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+// Apply applies the operations in order to resource, its JSON form.
+func (p *PatchRequest) Apply(resource map[string]any) error {
+	for _, op := range p.Operations {
+		if err := apply(resource, strings.ToLower(op.Op), op.Path, op.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patchError is a PATCH refused with a scimType.
+type patchError struct {
+	scimType string
+	detail   string
+}
+
+func (e *patchError) Error() string {
+	return e.detail
+}
+
+func invalidPath(format string, args ...any) error {
+	return &patchError{scimType: "invalidPath", detail: fmt.Sprintf(format, args...)}
+}
+
+func apply(m map[string]any, op, path string, value any) error {
+	switch op {
+	case "add", "replace", "remove":
+	default:
+		return &patchError{scimType: "invalidSyntax", detail: fmt.Sprintf("unknown operation %q", op)}
+	}
+
+	if path == "" {
+		if op == "remove" {
+			return &patchError{scimType: "noTarget", detail: "remove needs a path"}
+		}
+		values, ok := value.(map[string]any)
+		if !ok {
+			return &patchError{scimType: "invalidValue", detail: "a value without path must be an object"}
+		}
+		for k, v := range values {
+			if err := apply(m, op, k, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	attr, filter, sub, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	if filter != nil {
+		return applyFiltered(m, op, attr, filter, sub, value)
+	}
+
+	names := attrPath(attr)
+	for _, name := range names[:len(names)-1] {
+		next, ok := lookup(m, name).(map[string]any)
+		if !ok {
+			if op == "remove" {
+				return nil
+			}
+			next = map[string]any{}
+			m[key(m, name)] = next
+		}
+		m = next
+	}
+	name := key(m, names[len(names)-1])
+	value = coerce(name, value)
+
+	switch op {
+	case "remove":
+		delete(m, name)
+	case "add":
+		// Values are appended to multi-valued attributes
+		if existing, ok := m[name].([]any); ok {
+			m[name] = append(existing, elements(value)...)
+			return nil
+		}
+		m[name] = value
+	case "replace":
+		m[name] = value
+	}
+	return nil
+}
+
+// applyFiltered applies an operation to the elements of a multi-valued
+// attribute matching filter, or to their sub attribute.
+func applyFiltered(m map[string]any, op, attr string, filter expr, sub string, value any) error {
+	name := key(m, attr)
+	items, _ := m[name].([]any)
+	var kept []any
+	matched := false
+	for _, item := range items {
+		elem, ok := item.(map[string]any)
+		if !ok || !filter.match(elem) {
+			kept = append(kept, item)
+			continue
+		}
+		matched = true
+		switch {
+		case op == "remove" && sub == "":
+			continue
+		case op == "remove":
+			delete(elem, key(elem, sub))
+		case sub != "":
+			elem[key(elem, sub)] = value
+		default:
+			values, ok := value.(map[string]any)
+			if !ok {
+				return &patchError{scimType: "invalidValue", detail: "the value must be an object"}
+			}
+			for k, v := range values {
+				elem[key(elem, k)] = v
+			}
+		}
+		kept = append(kept, elem)
+	}
+	if !matched && op == "replace" {
+		return &patchError{scimType: "noTarget", detail: "no value matches the path filter"}
+	}
+	if kept == nil {
+		delete(m, name)
+		return nil
+	}
+	m[name] = kept
+	return nil
+}
+
+// parsePath splits attr[filter].sub.
+func parsePath(path string) (attr string, filter expr, sub string, err error) {
+	open := strings.Index(path, "[")
+	if open < 0 {
+		return path, nil, "", nil
+	}
+	end := strings.LastIndex(path, "]")
+	if end < open {
+		return "", nil, "", invalidPath("unclosed filter in %q", path)
+	}
+	p := &parser{tokens: tokenize(path[open+1 : end])}
+	if filter, err = p.or(); err != nil || p.pos < len(p.tokens) {
+		return "", nil, "", invalidPath("invalid filter in %q", path)
+	}
+	attr = attrPath(path[:open])[0]
+	sub = strings.TrimPrefix(path[end+1:], ".")
+	return attr, filter, sub, nil
+}
+
+// key returns the key of m matching name case insensitively, or name.
+func key(m map[string]any, name string) string {
+	if _, ok := m[name]; ok {
+		return name
+	}
+	for k := range m {
+		if strings.EqualFold(k, name) {
+			return k
+		}
+	}
+	return name
+}
+
+// coerce fixes values some identity providers send as strings, such as
+// "active": "False".
+func coerce(name string, value any) any {
+	if s, ok := value.(string); ok && strings.EqualFold(name, "active") {
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// patch applies the PATCH request of body to resource, decoding the result
+// into out.
+func patch(resource any, body []byte, out any) error {
+	var req PatchRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return &patchError{scimType: "invalidSyntax", detail: err.Error()}
+	}
+	m, err := toMap(resource)
+	if err != nil {
+		return err
+	}
+	if err := req.Apply(m); err != nil {
+		return err
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return &patchError{scimType: "invalidValue", detail: err.Error()}
+	}
+	return nil
+}
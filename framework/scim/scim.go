@@ -0,0 +1,119 @@
+// Package scim is a SCIM 2.0 server (RFC 7643 and 7644), so identity
+// providers such as Okta or Entra ID provision and deprovision accounts.
+// Users are kept by the application, behind the Users interface, and groups
+// are organizations, their members the memberships of package org:
+//
+//	scim.New(&UserStore{}).Mount(r, "/scim/v2")
+//
+// Requests authenticate with services.scim.token as a bearer token, and the
+// endpoints answer 404 while it is empty.
+package scim
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Schemas.
+const (
+	UserSchema          = "urn:ietf:params:scim:schemas:core:2.0:User"
+	GroupSchema         = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	ListResponseSchema  = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	ErrorSchema         = "urn:ietf:params:scim:api:messages:2.0:Error"
+	ServiceConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	ResourceTypeSchema  = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+)
+
+// MaxResults caps the resources of a page.
+const MaxResults = 200
+
+var (
+	// ErrNotFound is returned by Users for unknown ids.
+	ErrNotFound = errors.New("scim: resource not found")
+	// ErrConflict is returned by Users when the userName is taken.
+	ErrConflict = errors.New("scim: resource already exists")
+)
+
+// User is the core user resource.
+type User struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	UserName    string       `json:"userName"`
+	Name        *Name        `json:"name,omitempty"`
+	DisplayName string       `json:"displayName,omitempty"`
+	Emails      []MultiValue `json:"emails,omitempty"`
+	Active      bool         `json:"active"`
+	// Groups are read only, filled from the memberships of the user.
+	Groups []Ref `json:"groups,omitempty"`
+	Meta   *Meta `json:"meta,omitempty"`
+}
+
+// Name is the name of a user.
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// MultiValue is an element of a multi-valued attribute, such as an email.
+type MultiValue struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// PrimaryEmail returns the primary email of u, or its first one.
+func (u *User) PrimaryEmail() string {
+	for _, e := range u.Emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	return ""
+}
+
+// Group is the core group resource.
+type Group struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	DisplayName string   `json:"displayName"`
+	Members     []Ref    `json:"members,omitempty"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+// Ref references a resource, the value being its id.
+type Ref struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// Meta describes a resource.
+type Meta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location,omitempty"`
+}
+
+// Users keeps the users of the application. Meta.Created and
+// Meta.LastModified are the store's to fill, the rest of Meta the server's.
+type Users interface {
+	// List returns a page of the users matching filter, nil matching all,
+	// starting at the 1-based startIndex, with the total number matching.
+	// Filter.Match evaluates the filter on a user, and Filter.Equals gives
+	// the value of the userName or externalId lookups providers send.
+	List(ctx context.Context, filter *Filter, startIndex, count int) ([]*User, int, error)
+	Get(ctx context.Context, id string) (*User, error)
+	// Create stores a new user and returns it with its id.
+	Create(ctx context.Context, u *User) (*User, error)
+	// Replace stores every attribute of u, deactivating the user when
+	// Active is false.
+	Replace(ctx context.Context, u *User) (*User, error)
+	Delete(ctx context.Context, id string) error
+}
@@ -0,0 +1,197 @@
+package scim
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/config"
+
+	"github.com/lemmego/lemmego/framework/logs"
+)
+
+// maxBody is the largest request body accepted.
+const maxBody = 1 << 20
+
+// Server serves the SCIM endpoints.
+type Server struct {
+	users  Users
+	prefix string
+}
+
+// New returns a server provisioning users into users.
+func New(users Users) *Server {
+	return &Server{users: users}
+}
+
+// Mount serves the endpoints under prefix, e.g. /scim/v2.
+func (s *Server) Mount(r app.Router, prefix string) {
+	s.prefix = "/" + strings.Trim(prefix, "/")
+	handle := func(pattern string, h http.HandlerFunc) {
+		method, path, _ := strings.Cut(pattern, " ")
+		r.Handle(method+" "+s.prefix+path, s.authenticate(h))
+	}
+	handle("GET /ServiceProviderConfig", s.serviceProviderConfig)
+	handle("GET /ResourceTypes", s.resourceTypes)
+
+	handle("GET /Users", s.listUsers)
+	handle("POST /Users", s.createUser)
+	handle("GET /Users/{id}", s.getUser)
+	handle("PUT /Users/{id}", s.replaceUser)
+	handle("PATCH /Users/{id}", s.patchUser)
+	handle("DELETE /Users/{id}", s.deleteUser)
+
+	handle("GET /Groups", s.listGroups)
+	handle("POST /Groups", s.createGroup)
+	handle("GET /Groups/{id}", s.getGroup)
+	handle("PUT /Groups/{id}", s.replaceGroup)
+	handle("PATCH /Groups/{id}", s.patchGroup)
+	handle("DELETE /Groups/{id}", s.deleteGroup)
+}
+
+func (s *Server) authenticate(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _ := config.Get("services.scim.token", "").(string)
+		if token == "" {
+			http.NotFound(w, r)
+			return
+		}
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "", "invalid bearer token")
+			return
+		}
+		next(w, r)
+	})
+}
+
+// location is the URL of a resource.
+func (s *Server) location(r *http.Request, kind, id string) string {
+	base, _ := config.Get("app.url", "").(string)
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	return strings.TrimSuffix(base, "/") + s.prefix + "/" + kind + "/" + id
+}
+
+func (s *Server) serviceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	supported := func(ok bool) map[string]any { return map[string]any{"supported": ok} }
+	writeJSON(w, http.StatusOK, map[string]any{
+		"schemas":        []string{ServiceConfigSchema},
+		"patch":          supported(true),
+		"bulk":           map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]any{"supported": true, "maxResults": MaxResults},
+		"changePassword": supported(false),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []map[string]any{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer token",
+			"description": "The token of services.scim.token, in the Authorization header",
+		}},
+	})
+}
+
+func (s *Server) resourceTypes(w http.ResponseWriter, r *http.Request) {
+	types := []map[string]any{
+		{"schemas": []string{ResourceTypeSchema}, "id": "User", "name": "User", "endpoint": "/Users", "schema": UserSchema},
+		{"schemas": []string{ResourceTypeSchema}, "id": "Group", "name": "Group", "endpoint": "/Groups", "schema": GroupSchema},
+	}
+	writeJSON(w, http.StatusOK, list(types, len(types), 1))
+}
+
+// page reads the filter, startIndex and count query parameters.
+func page(r *http.Request) (*Filter, int, int, error) {
+	q := r.URL.Query()
+	filter, err := ParseFilter(q.Get("filter"))
+	if err != nil {
+		return nil, 0, 0, &patchError{scimType: "invalidFilter", detail: err.Error()}
+	}
+	start, count := 1, 100
+	if v, err := strconv.Atoi(q.Get("startIndex")); err == nil && v > 1 {
+		start = v
+	}
+	if v, err := strconv.Atoi(q.Get("count")); err == nil && v >= 0 {
+		count = min(v, MaxResults)
+	}
+	return filter, start, count, nil
+}
+
+func list[T any](resources []T, total, start int) map[string]any {
+	if resources == nil {
+		resources = []T{}
+	}
+	return map[string]any{
+		"schemas":      []string{ListResponseSchema},
+		"totalResults": total,
+		"startIndex":   start,
+		"itemsPerPage": len(resources),
+		"Resources":    resources,
+	}
+}
+
+// decode reads a JSON request body into v.
+func decode(r *http.Request, v any) error {
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return &patchError{scimType: "invalidSyntax", detail: err.Error()}
+	}
+	return nil
+}
+
+func readBody(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBody+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxBody {
+		return nil, &patchError{scimType: "tooMany", detail: "the request body is too large"}
+	}
+	return body, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, scimType, detail string) {
+	body := map[string]any{
+		"schemas": []string{ErrorSchema},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	}
+	if scimType != "" {
+		body["scimType"] = scimType
+	}
+	writeJSON(w, status, body)
+}
+
+// fail answers err as a SCIM error.
+func fail(w http.ResponseWriter, r *http.Request, err error) {
+	var pe *patchError
+	switch {
+	case errors.As(err, &pe):
+		writeError(w, http.StatusBadRequest, pe.scimType, pe.detail)
+	case errors.Is(err, ErrNotFound):
+		writeError(w, http.StatusNotFound, "", "resource not found")
+	case errors.Is(err, ErrConflict):
+		writeError(w, http.StatusConflict, "uniqueness", err.Error())
+	default:
+		logs.Ctx(r.Context()).Error("scim: " + err.Error())
+		writeError(w, http.StatusInternalServerError, "", "internal error")
+	}
+}
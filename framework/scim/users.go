@@ -0,0 +1,151 @@
+package scim
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/lemmego/lemmego/framework/org"
+	"github.com/lemmego/lemmego/framework/repo"
+)
+
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request) {
+	filter, start, count, err := page(r)
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	users, total, err := s.users.List(r.Context(), filter, start, count)
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	for _, u := range users {
+		if err := s.present(r, u); err != nil {
+			fail(w, r, err)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, list(users, total, start))
+}
+
+func (s *Server) getUser(w http.ResponseWriter, r *http.Request) {
+	u, err := s.users.Get(r.Context(), r.PathValue("id"))
+	if err == nil {
+		err = s.present(r, u)
+	}
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, u)
+}
+
+func (s *Server) createUser(w http.ResponseWriter, r *http.Request) {
+	// Users are active unless told otherwise
+	u := &User{Active: true}
+	if err := decode(r, u); err != nil {
+		fail(w, r, err)
+		return
+	}
+	if u.UserName == "" {
+		writeError(w, http.StatusBadRequest, "invalidValue", "userName is required")
+		return
+	}
+	u.ID, u.Groups, u.Meta = "", nil, nil
+	created, err := s.users.Create(r.Context(), u)
+	if err == nil {
+		err = s.present(r, created)
+	}
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	w.Header().Set("Location", created.Meta.Location)
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (s *Server) replaceUser(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, err := s.users.Get(r.Context(), id); err != nil {
+		fail(w, r, err)
+		return
+	}
+	u := &User{Active: true}
+	if err := decode(r, u); err != nil {
+		fail(w, r, err)
+		return
+	}
+	s.replace(w, r, id, u)
+}
+
+func (s *Server) patchUser(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	current, err := s.users.Get(r.Context(), id)
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	body, err := readBody(r)
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	u := &User{}
+	if err := patch(current, body, u); err != nil {
+		fail(w, r, err)
+		return
+	}
+	s.replace(w, r, id, u)
+}
+
+func (s *Server) replace(w http.ResponseWriter, r *http.Request, id string, u *User) {
+	if u.UserName == "" {
+		writeError(w, http.StatusBadRequest, "invalidValue", "userName is required")
+		return
+	}
+	u.ID, u.Groups, u.Meta = id, nil, nil
+	replaced, err := s.users.Replace(r.Context(), u)
+	if err == nil {
+		err = s.present(r, replaced)
+	}
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, replaced)
+}
+
+// deleteUser deletes the user and their memberships, owners included, as
+// they cannot sign in any more.
+func (s *Server) deleteUser(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := s.users.Delete(r.Context(), id); err != nil {
+		fail(w, r, err)
+		return
+	}
+	if _, err := repo.New[org.Membership](r.Context()).Delete("user_id = ?", id); err != nil {
+		fail(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// present fills the schemas, groups and meta of a user.
+func (s *Server) present(r *http.Request, u *User) error {
+	orgs, err := org.Of(r.Context(), u.ID)
+	if err != nil {
+		return err
+	}
+	u.Schemas = []string{UserSchema}
+	u.Groups = nil
+	for _, o := range orgs {
+		id := strconv.FormatUint(uint64(o.ID), 10)
+		u.Groups = append(u.Groups, Ref{Value: id, Display: o.Name, Ref: s.location(r, "Groups", id)})
+	}
+	if u.Meta == nil {
+		u.Meta = &Meta{}
+	}
+	u.Meta.ResourceType = "User"
+	u.Meta.Location = s.location(r, "Users", u.ID)
+	return nil
+}
@@ -0,0 +1,216 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Elasticsearch talks to an Elasticsearch or OpenSearch cluster. Indexes use
+// dynamic mapping, so string filters match the keyword sub-field and sorts on
+// text fields need it named, e.g. OrderBy("title.keyword").
+type Elasticsearch struct {
+	Host string
+	// APIKey, or Username and Password, authenticate requests when set.
+	APIKey   string
+	Username string
+	Password string
+	Timeout  time.Duration
+}
+
+func (e *Elasticsearch) Configure(ctx context.Context, index string, s Settings) error {
+	err := e.do(ctx, http.MethodHead, "/"+url.PathEscape(index), nil, nil)
+	if err == nil {
+		return nil
+	}
+	// Missing indexes are created up front, so the first bulk request does
+	// not race a concurrent one to create it
+	return e.do(ctx, http.MethodPut, "/"+url.PathEscape(index), map[string]any{}, nil)
+}
+
+func (e *Elasticsearch) Index(ctx context.Context, index string, docs []Document) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, doc := range docs {
+		if err := enc.Encode(map[string]any{"index": map[string]string{"_index": index, "_id": doc.ID}}); err != nil {
+			return err
+		}
+		if err := enc.Encode(doc.Fields); err != nil {
+			return err
+		}
+	}
+	return e.bulk(ctx, body.Bytes())
+}
+
+func (e *Elasticsearch) Delete(ctx context.Context, index string, ids []string) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, id := range ids {
+		if err := enc.Encode(map[string]any{"delete": map[string]string{"_index": index, "_id": id}}); err != nil {
+			return err
+		}
+	}
+	return e.bulk(ctx, body.Bytes())
+}
+
+// bulk sends a _bulk request, which succeeds even when some of its actions
+// failed, so the response is checked item by item.
+func (e *Elasticsearch) bulk(ctx context.Context, body []byte) error {
+	var out struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := e.do(ctx, http.MethodPost, "/_bulk", body, &out); err != nil {
+		return err
+	}
+	if !out.Errors {
+		return nil
+	}
+	for _, item := range out.Items {
+		for action, result := range item {
+			// Deleting a document that is already gone is fine
+			if result.Error != nil && !(action == "delete" && result.Status == http.StatusNotFound) {
+				return fmt.Errorf("search: bulk %s failed: %s", action, result.Error)
+			}
+		}
+	}
+	return nil
+}
+
+func (e *Elasticsearch) Search(ctx context.Context, index string, q *Query) (*Result, error) {
+	query := map[string]any{"match_all": map[string]any{}}
+	if q.Text != "" {
+		fields := settingsOf(index).Searchable
+		if len(fields) == 0 {
+			fields = []string{"*"}
+		}
+		query = map[string]any{"simple_query_string": map[string]any{
+			"query":            q.Text,
+			"fields":           fields,
+			"default_operator": "and",
+		}}
+	}
+
+	var filter, mustNot []any
+	for _, f := range q.Filters {
+		clause, err := esFilter(f)
+		if err != nil {
+			return nil, err
+		}
+		if f.Op == Ne {
+			mustNot = append(mustNot, clause)
+		} else {
+			filter = append(filter, clause)
+		}
+	}
+	body := map[string]any{
+		"query": map[string]any{"bool": map[string]any{
+			"must":     query,
+			"filter":   nonNilAny(filter),
+			"must_not": nonNilAny(mustNot),
+		}},
+		"from":             q.offset(),
+		"size":             q.PerPage,
+		"track_total_hits": true,
+		"_source":          false,
+	}
+	if len(q.Sort) > 0 {
+		var sort []any
+		for _, s := range q.Sort {
+			if !fieldName.MatchString(s.Field) {
+				return nil, fmt.Errorf("search: invalid field %q", s.Field)
+			}
+			order := "asc"
+			if s.Desc {
+				order = "desc"
+			}
+			sort = append(sort, map[string]any{s.Field: map[string]any{"order": order, "unmapped_type": "keyword"}})
+		}
+		body["sort"] = sort
+	}
+
+	var out struct {
+		Hits struct {
+			Total struct {
+				Value int64 `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				ID    string   `json:"_id"`
+				Score *float64 `json:"_score"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := e.do(ctx, http.MethodPost, "/"+url.PathEscape(index)+"/_search", body, &out); err != nil {
+		return nil, err
+	}
+
+	res := &Result{Total: out.Hits.Total.Value, Page: q.Page, PerPage: q.PerPage}
+	for _, h := range out.Hits.Hits {
+		hit := Hit{ID: h.ID}
+		if h.Score != nil {
+			hit.Score = *h.Score
+		}
+		res.Hits = append(res.Hits, hit)
+	}
+	return res, nil
+}
+
+func nonNilAny(s []any) []any {
+	if s == nil {
+		return []any{}
+	}
+	return s
+}
+
+// esFilter builds a term, terms or range clause. Strings are matched against
+// the keyword sub-field dynamic mapping adds.
+func esFilter(f Filter) (map[string]any, error) {
+	if !fieldName.MatchString(f.Field) || !operators[f.Op] {
+		return nil, fmt.Errorf("search: invalid filter %s %s", f.Field, f.Op)
+	}
+	field := func(v any) string {
+		if _, ok := v.(string); ok {
+			return f.Field + ".keyword"
+		}
+		return f.Field
+	}
+
+	switch f.Op {
+	case Eq, Ne:
+		return map[string]any{"term": map[string]any{field(f.Value): f.Value}}, nil
+	case In:
+		values := list(f.Value)
+		if len(values) == 0 {
+			return nil, fmt.Errorf("search: %s in needs a list of values", f.Field)
+		}
+		return map[string]any{"terms": map[string]any{field(values[0]): values}}, nil
+	}
+	op := map[string]string{Gt: "gt", Gte: "gte", Lt: "lt", Lte: "lte"}[f.Op]
+	return map[string]any{"range": map[string]any{field(f.Value): map[string]any{op: f.Value}}}, nil
+}
+
+func (e *Elasticsearch) do(ctx context.Context, method, path string, body, out any) error {
+	header := http.Header{}
+	switch {
+	case e.APIKey != "":
+		header.Set("Authorization", "ApiKey "+e.APIKey)
+	case e.Username != "":
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(e.Username+":"+e.Password)))
+	}
+	if _, ok := body.([]byte); ok {
+		header.Set("Content-Type", "application/x-ndjson")
+	}
+	if body == nil {
+		body = []byte{}
+	}
+	return request(ctx, e.Timeout, method, strings.TrimRight(e.Host, "/")+path, header, body, out)
+}
@@ -0,0 +1,190 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lemmego/lemmego/framework/httpclient"
+)
+
+// Meilisearch talks to a Meilisearch server. Filters and sorts need the
+// fields declared Filterable and Sortable, applied by Configure.
+type Meilisearch struct {
+	Host string
+	// Key is an API key allowed to manage documents and settings.
+	Key     string
+	Timeout time.Duration
+}
+
+func (m *Meilisearch) Configure(ctx context.Context, index string, s Settings) error {
+	settings := map[string]any{
+		"filterableAttributes": nonNil(s.Filterable),
+		"sortableAttributes":   nonNil(s.Sortable),
+	}
+	if len(s.Searchable) > 0 {
+		settings["searchableAttributes"] = s.Searchable
+	}
+	return m.do(ctx, http.MethodPatch, "/indexes/"+url.PathEscape(index)+"/settings", settings, nil)
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+func (m *Meilisearch) Index(ctx context.Context, index string, docs []Document) error {
+	body := make([]map[string]any, len(docs))
+	for i, doc := range docs {
+		fields := make(map[string]any, len(doc.Fields)+1)
+		for k, v := range doc.Fields {
+			fields[k] = v
+		}
+		fields["id"] = doc.ID
+		body[i] = fields
+	}
+	return m.do(ctx, http.MethodPost, "/indexes/"+url.PathEscape(index)+"/documents?primaryKey=id", body, nil)
+}
+
+func (m *Meilisearch) Delete(ctx context.Context, index string, ids []string) error {
+	return m.do(ctx, http.MethodPost, "/indexes/"+url.PathEscape(index)+"/documents/delete-batch", ids, nil)
+}
+
+func (m *Meilisearch) Search(ctx context.Context, index string, q *Query) (*Result, error) {
+	body := map[string]any{
+		"q":                    q.Text,
+		"page":                 q.Page,
+		"hitsPerPage":          q.PerPage,
+		"attributesToRetrieve": []string{"id"},
+		"showRankingScore":     true,
+	}
+	if len(q.Filters) > 0 {
+		var clauses []string
+		for _, f := range q.Filters {
+			clause, err := meiliFilter(f)
+			if err != nil {
+				return nil, err
+			}
+			clauses = append(clauses, clause)
+		}
+		body["filter"] = strings.Join(clauses, " AND ")
+	}
+	if len(q.Sort) > 0 {
+		var sort []string
+		for _, s := range q.Sort {
+			if !fieldName.MatchString(s.Field) {
+				return nil, fmt.Errorf("search: invalid field %q", s.Field)
+			}
+			dir := ":asc"
+			if s.Desc {
+				dir = ":desc"
+			}
+			sort = append(sort, s.Field+dir)
+		}
+		body["sort"] = sort
+	}
+
+	var out struct {
+		Hits []struct {
+			ID           any     `json:"id"`
+			RankingScore float64 `json:"_rankingScore"`
+		} `json:"hits"`
+		TotalHits int64 `json:"totalHits"`
+	}
+	if err := m.do(ctx, http.MethodPost, "/indexes/"+url.PathEscape(index)+"/search", body, &out); err != nil {
+		return nil, err
+	}
+
+	res := &Result{Total: out.TotalHits, Page: q.Page, PerPage: q.PerPage}
+	for _, h := range out.Hits {
+		res.Hits = append(res.Hits, Hit{ID: fmt.Sprint(h.ID), Score: h.RankingScore})
+	}
+	return res, nil
+}
+
+// meiliFilter renders a filter in Meilisearch's filter syntax.
+func meiliFilter(f Filter) (string, error) {
+	if !fieldName.MatchString(f.Field) || !operators[f.Op] {
+		return "", fmt.Errorf("search: invalid filter %s %s", f.Field, f.Op)
+	}
+	if f.Op == In {
+		values := list(f.Value)
+		if len(values) == 0 {
+			return "", fmt.Errorf("search: %s in needs a list of values", f.Field)
+		}
+		literals := make([]string, len(values))
+		for i, v := range values {
+			literals[i] = meiliLiteral(v)
+		}
+		return f.Field + " IN [" + strings.Join(literals, ", ") + "]", nil
+	}
+	return f.Field + " " + f.Op + " " + meiliLiteral(f.Value), nil
+}
+
+func meiliLiteral(v any) string {
+	switch v := v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(fmt.Sprint(v)) + `"`
+}
+
+func (m *Meilisearch) do(ctx context.Context, method, path string, body, out any) error {
+	header := http.Header{}
+	if m.Key != "" {
+		header.Set("Authorization", "Bearer "+m.Key)
+	}
+	return request(ctx, m.Timeout, method, strings.TrimRight(m.Host, "/")+path, header, body, out)
+}
+
+// request sends body as JSON, or as is when it is already bytes, and decodes
+// a 2xx response into out.
+func request(ctx context.Context, timeout time.Duration, method, url string, header http.Header, body, out any) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	data, ok := body.([]byte)
+	if !ok {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := httpclient.Do(ctx, req)
+	if err != nil {
+		return fmt.Errorf("search: %s %s: %w", method, url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("search: %s %s returned %s: %s", method, url, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,227 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/lemmego/lemmego/framework/repo"
+	"gorm.io/gorm"
+)
+
+// Postgres indexes documents in a search_documents table with a tsvector
+// column, created on first use, and queries it with websearch_to_tsquery.
+// The Searchable fields of a document are searched, or all of its string
+// fields.
+type Postgres struct {
+	// Connection is the database connection name, the default one when empty.
+	Connection string
+	// Language is the text search configuration, e.g. "english" or "simple".
+	Language string
+
+	once       sync.Once
+	migrateErr error
+}
+
+func (p *Postgres) db(ctx context.Context) (*gorm.DB, error) {
+	var names []string
+	if p.Connection != "" {
+		names = append(names, p.Connection)
+	}
+	tx := repo.DB(ctx, names...)
+
+	p.once.Do(func() {
+		for _, stmt := range []string{
+			`CREATE TABLE IF NOT EXISTS search_documents (
+				index_name varchar(100) NOT NULL,
+				doc_key varchar(255) NOT NULL,
+				fields jsonb NOT NULL,
+				content tsvector NOT NULL,
+				PRIMARY KEY (index_name, doc_key)
+			)`,
+			`CREATE INDEX IF NOT EXISTS search_documents_content ON search_documents USING GIN (content)`,
+		} {
+			if p.migrateErr = tx.Exec(stmt).Error; p.migrateErr != nil {
+				return
+			}
+		}
+	})
+	return tx, p.migrateErr
+}
+
+func (p *Postgres) language() string {
+	if p.Language == "" {
+		return "english"
+	}
+	return p.Language
+}
+
+func (p *Postgres) Configure(ctx context.Context, index string, s Settings) error {
+	_, err := p.db(ctx)
+	return err
+}
+
+func (p *Postgres) Index(ctx context.Context, index string, docs []Document) error {
+	tx, err := p.db(ctx)
+	if err != nil {
+		return err
+	}
+
+	fields := settingsOf(index).Searchable
+	for _, doc := range docs {
+		data, err := json.Marshal(doc.Fields)
+		if err != nil {
+			return err
+		}
+		err = tx.Exec(`INSERT INTO search_documents (index_name, doc_key, fields, content)
+			VALUES (?, ?, ?::jsonb, to_tsvector(?::regconfig, ?))
+			ON CONFLICT (index_name, doc_key) DO UPDATE SET fields = excluded.fields, content = excluded.content`,
+			index, doc.ID, string(data), p.language(), text(doc.Fields, fields)).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// text joins the searchable string fields of a document.
+func text(doc map[string]any, fields []string) string {
+	var parts []string
+	if len(fields) > 0 {
+		for _, name := range fields {
+			if s, ok := doc[name].(string); ok {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, "\n")
+	}
+	for _, v := range doc {
+		if s, ok := v.(string); ok {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func (p *Postgres) Delete(ctx context.Context, index string, ids []string) error {
+	tx, err := p.db(ctx)
+	if err != nil {
+		return err
+	}
+	return tx.Exec("DELETE FROM search_documents WHERE index_name = ? AND doc_key IN ?", index, ids).Error
+}
+
+func (p *Postgres) Search(ctx context.Context, index string, q *Query) (*Result, error) {
+	tx, err := p.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	where := []string{"index_name = ?"}
+	args := []any{index}
+	score := "0"
+	if q.Text != "" {
+		where = append(where, "content @@ websearch_to_tsquery(?::regconfig, ?)")
+		args = append(args, p.language(), q.Text)
+		score = "ts_rank(content, websearch_to_tsquery(?::regconfig, ?))"
+	}
+	for _, f := range q.Filters {
+		clause, values, err := pgFilter(f)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, clause)
+		args = append(args, values...)
+	}
+
+	order := []string{"score DESC", "doc_key"}
+	if len(q.Sort) > 0 {
+		order = order[:0]
+		for _, s := range q.Sort {
+			// Field names are interpolated, they must be plain identifiers
+			if !fieldName.MatchString(s.Field) {
+				return nil, fmt.Errorf("search: invalid field %q", s.Field)
+			}
+			dir := "ASC"
+			if s.Desc {
+				dir = "DESC"
+			}
+			order = append(order, jsonPath(s.Field, false)+" "+dir)
+		}
+	}
+
+	sql := fmt.Sprintf("SELECT doc_key, %s AS score, count(*) OVER () AS total FROM search_documents WHERE %s ORDER BY %s LIMIT ? OFFSET ?",
+		score, strings.Join(where, " AND "), strings.Join(order, ", "))
+	if q.Text != "" {
+		args = append([]any{p.language(), q.Text}, args...)
+	}
+	args = append(args, q.PerPage, q.offset())
+
+	var rows []struct {
+		DocKey string
+		Score  float64
+		Total  int64
+	}
+	if err := tx.Raw(sql, args...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	res := &Result{Page: q.Page, PerPage: q.PerPage}
+	for _, row := range rows {
+		res.Hits = append(res.Hits, Hit{ID: row.DocKey, Score: row.Score})
+		res.Total = row.Total
+	}
+	if len(rows) == 0 && q.Page > 1 {
+		// Past the last page the window function has nothing to count
+		q := *q
+		q.Page, q.PerPage = 1, 1
+		first, err := p.Search(ctx, index, &q)
+		if err != nil {
+			return nil, err
+		}
+		res.Total = first.Total
+	}
+	return res, nil
+}
+
+// jsonPath selects a possibly nested field of the document, as text or jsonb.
+func jsonPath(field string, asText bool) string {
+	op := "#>"
+	if asText {
+		op = "#>>"
+	}
+	return fmt.Sprintf("fields %s '{%s}'", op, strings.ReplaceAll(field, ".", ","))
+}
+
+// pgFilter compares a jsonb field. Numbers and booleans compare as jsonb,
+// so 10 sorts after 9, anything else as text.
+func pgFilter(f Filter) (string, []any, error) {
+	if !fieldName.MatchString(f.Field) || !operators[f.Op] {
+		return "", nil, fmt.Errorf("search: invalid filter %s %s", f.Field, f.Op)
+	}
+	field := jsonPath(f.Field, true)
+	if f.Op == In {
+		values := list(f.Value)
+		if len(values) == 0 {
+			return "", nil, fmt.Errorf("search: %s in needs a list of values", f.Field)
+		}
+		texts := make([]any, len(values))
+		for i, v := range values {
+			texts[i] = fmt.Sprint(v)
+		}
+		return field + " IN ?", []any{texts}, nil
+	}
+
+	op := f.Op
+	if op == Ne {
+		op = "<>"
+	}
+	switch f.Value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, bool:
+		data, _ := json.Marshal(f.Value)
+		return jsonPath(f.Field, false) + " " + op + " ?::jsonb", []any{string(data)}, nil
+	}
+	return field + " " + op + " ?", []any{fmt.Sprint(f.Value)}, nil
+}
@@ -0,0 +1,240 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"github.com/lemmego/lemmego/framework/repo"
+	"gorm.io/gorm"
+)
+
+// Filter operators.
+const (
+	Eq  = "="
+	Ne  = "!="
+	Gt  = ">"
+	Gte = ">="
+	Lt  = "<"
+	Lte = "<="
+	In  = "in"
+)
+
+var (
+	operators = map[string]bool{Eq: true, Ne: true, Gt: true, Gte: true, Lt: true, Lte: true, In: true}
+	// Fields may name nested values with dots, e.g. "author.name"
+	fieldName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z0-9_]+)*$`)
+)
+
+// Filter restricts results on a document field. Value is a slice for In.
+type Filter struct {
+	Field string
+	Op    string
+	Value any
+}
+
+// list returns the elements of a slice value, or nil.
+func list(v any) []any {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil
+	}
+	values := make([]any, rv.Len())
+	for i := range values {
+		values[i] = rv.Index(i).Interface()
+	}
+	return values
+}
+
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// Query is what engines search for. An empty Text matches every document.
+type Query struct {
+	Text    string
+	Filters []Filter
+	// Sort orders results, by relevance when empty.
+	Sort    []Sort
+	Page    int
+	PerPage int
+}
+
+func (q *Query) offset() int {
+	return (q.Page - 1) * q.PerPage
+}
+
+type Hit struct {
+	ID    string
+	Score float64
+}
+
+// Result is a page of matches, best first.
+type Result struct {
+	Hits    []Hit
+	Total   int64
+	Page    int
+	PerPage int
+}
+
+func (r *Result) IDs() []string {
+	ids := make([]string, len(r.Hits))
+	for i, h := range r.Hits {
+		ids[i] = h.ID
+	}
+	return ids
+}
+
+// Paginated is a page of models.
+type Paginated[T any] struct {
+	Items    []T   `json:"items"`
+	Total    int64 `json:"total"`
+	Page     int   `json:"page"`
+	PerPage  int   `json:"per_page"`
+	LastPage int   `json:"last_page"`
+}
+
+// Builder queries the index of T.
+type Builder[T any, PT interface {
+	*T
+	Indexable
+}] struct {
+	ctx   context.Context
+	index string
+	query Query
+	repo  *repo.Repo[T]
+	err   error
+}
+
+// For starts a search of T's index.
+func For[T any, PT interface {
+	*T
+	Indexable
+}](ctx context.Context, text string) *Builder[T, PT] {
+	return &Builder[T, PT]{ctx: ctx, index: PT(new(T)).SearchIndex(), query: Query{Text: text}}
+}
+
+// Using loads the matching models through r, e.g. to preload relations with
+// repo.New[Post](ctx).With("Author").
+func (b *Builder[T, PT]) Using(r *repo.Repo[T]) *Builder[T, PT] {
+	b.repo = r
+	return b
+}
+
+// Where filters on a field, e.g. Where("price", search.Lte, 100).
+func (b *Builder[T, PT]) Where(field, op string, value any) *Builder[T, PT] {
+	if !fieldName.MatchString(field) {
+		b.err = fmt.Errorf("search: invalid field %q", field)
+	} else if !operators[op] {
+		b.err = fmt.Errorf("search: invalid operator %q", op)
+	}
+	b.query.Filters = append(b.query.Filters, Filter{Field: field, Op: op, Value: value})
+	return b
+}
+
+func (b *Builder[T, PT]) WhereIn(field string, values ...any) *Builder[T, PT] {
+	return b.Where(field, In, values)
+}
+
+func (b *Builder[T, PT]) OrderBy(field string) *Builder[T, PT] {
+	return b.order(field, false)
+}
+
+func (b *Builder[T, PT]) OrderByDesc(field string) *Builder[T, PT] {
+	return b.order(field, true)
+}
+
+func (b *Builder[T, PT]) order(field string, desc bool) *Builder[T, PT] {
+	if !fieldName.MatchString(field) {
+		b.err = fmt.Errorf("search: invalid field %q", field)
+	}
+	b.query.Sort = append(b.query.Sort, Sort{Field: field, Desc: desc})
+	return b
+}
+
+// Raw runs the query and returns the matching keys without loading models.
+func (b *Builder[T, PT]) Raw(page, perPage int) (*Result, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	e, err := Default()
+	if err != nil {
+		return nil, err
+	}
+
+	q := b.query
+	q.Page, q.PerPage = max(page, 1), perPage
+	if q.PerPage <= 0 {
+		q.PerPage = 15
+	}
+	return e.Search(b.ctx, Name(b.index), &q)
+}
+
+// Paginate runs the query and loads the matching models in order of
+// relevance. Matches whose rows are gone, or hidden by global scopes, are
+// left out of Items but still counted in Total.
+func (b *Builder[T, PT]) Paginate(page, perPage int) (*Paginated[T], error) {
+	res, err := b.Raw(page, perPage)
+	if err != nil {
+		return nil, err
+	}
+
+	r := b.repo
+	if r == nil {
+		r = repo.New[T](b.ctx)
+	}
+	items, err := load[T, PT](r, res.IDs())
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Paginated[T]{Items: items, Total: res.Total, Page: res.Page, PerPage: res.PerPage}
+	if p.PerPage > 0 {
+		p.LastPage = max(int((res.Total+int64(p.PerPage)-1)/int64(p.PerPage)), 1)
+	}
+	return p, nil
+}
+
+// load fetches the models with the given keys, in the same order.
+func load[T any, PT interface {
+	*T
+	Indexable
+}](r *repo.Repo[T], keys []string) ([]T, error) {
+	if len(keys) == 0 {
+		return []T{}, nil
+	}
+
+	pk, err := primaryKey[T](r.Query())
+	if err != nil {
+		return nil, err
+	}
+	var rows []T
+	if err := r.Query().Where(pk+" IN ?", keys).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]int, len(rows))
+	for i := range rows {
+		byKey[PT(&rows[i]).SearchKey()] = i
+	}
+	items := make([]T, 0, len(rows))
+	for _, key := range keys {
+		if i, ok := byKey[key]; ok {
+			items = append(items, rows[i])
+		}
+	}
+	return items, nil
+}
+
+func primaryKey[T any](tx *gorm.DB) (string, error) {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(new(T)); err != nil {
+		return "", err
+	}
+	if stmt.Schema.PrioritizedPrimaryField == nil {
+		return "", fmt.Errorf("search: %s has no primary key", stmt.Schema.Name)
+	}
+	return stmt.Quote(stmt.Schema.PrioritizedPrimaryField.DBName), nil
+}
@@ -0,0 +1,126 @@
+// Package search keeps models in a full-text index and queries it. Postgres
+// full-text search, Meilisearch and Elasticsearch are supported.
+//
+// A model describes its document, and Sync keeps the index up to date through
+// the queue whenever a row is written:
+//
+//	func (p *Post) SearchIndex() string            { return "posts" }
+//	func (p *Post) SearchKey() string              { return strconv.Itoa(int(p.ID)) }
+//	func (p *Post) SearchDocument() map[string]any { return map[string]any{"title": p.Title, "body": p.Body, "author_id": p.AuthorID} }
+//
+//	search.Sync[Post](search.Settings{Filterable: []string{"author_id"}})
+//
+//	page, err := search.For[Post](ctx, "golang generics").Where("author_id", "=", 7).Paginate(1, 20)
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lemmego/api/config"
+)
+
+var (
+	ErrDisabled     = errors.New("search: no driver configured")
+	ErrUnknownIndex = errors.New("search: unknown index")
+)
+
+// Indexable is a model kept in a search index.
+type Indexable interface {
+	// SearchIndex names the index, usually after the table.
+	SearchIndex() string
+	// SearchKey is the primary key the model is loaded back with.
+	SearchKey() string
+	// SearchDocument holds the fields to index. String fields are searched,
+	// any field can be filtered and sorted on.
+	SearchDocument() map[string]any
+}
+
+// Document is an indexed model.
+type Document struct {
+	ID     string         `json:"id"`
+	Fields map[string]any `json:"fields"`
+}
+
+// Settings declare the fields an index is queried on. Meilisearch rejects
+// filters and sorts on fields not listed here.
+type Settings struct {
+	// Searchable restricts and ranks the searched fields, all by default.
+	Searchable []string
+	Filterable []string
+	Sortable   []string
+}
+
+// Engine is a search backend.
+type Engine interface {
+	// Configure applies settings to an index, creating it if needed.
+	Configure(ctx context.Context, index string, s Settings) error
+	// Index adds documents, replacing those with the same ID.
+	Index(ctx context.Context, index string, docs []Document) error
+	Delete(ctx context.Context, index string, ids []string) error
+	Search(ctx context.Context, index string, q *Query) (*Result, error)
+}
+
+var (
+	mu            sync.Mutex
+	defaultEngine Engine
+)
+
+// Default returns the engine configured by search.driver.
+func Default() (Engine, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if defaultEngine != nil {
+		return defaultEngine, nil
+	}
+
+	timeout := time.Duration(config.Get("search.timeout", 10).(int)) * time.Second
+	switch name := config.Get("search.driver", "none").(string); name {
+	case "none", "":
+		return nil, ErrDisabled
+	case "pgsql":
+		defaultEngine = &Postgres{
+			Connection: config.Get("search.pgsql.connection", "").(string),
+			Language:   config.Get("search.pgsql.language", "english").(string),
+		}
+	case "meilisearch":
+		defaultEngine = &Meilisearch{
+			Host:    config.Get("search.meilisearch.host", "http://localhost:7700").(string),
+			Key:     config.Get("search.meilisearch.key", "").(string),
+			Timeout: timeout,
+		}
+	case "elasticsearch":
+		defaultEngine = &Elasticsearch{
+			Host:     config.Get("search.elasticsearch.host", "http://localhost:9200").(string),
+			APIKey:   config.Get("search.elasticsearch.api_key", "").(string),
+			Username: config.Get("search.elasticsearch.username", "").(string),
+			Password: config.Get("search.elasticsearch.password", "").(string),
+			Timeout:  timeout,
+		}
+	default:
+		return nil, fmt.Errorf("search: unknown driver %q", name)
+	}
+	return defaultEngine, nil
+}
+
+// SetDefault replaces the configured engine.
+func SetDefault(e Engine) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultEngine = e
+}
+
+// Enabled reports whether a driver is configured.
+func Enabled() bool {
+	_, err := Default()
+	return err == nil
+}
+
+// Name is the engine side name of index, with search.prefix applied so
+// environments can share a cluster.
+func Name(index string) string {
+	return config.Get("search.prefix", "").(string) + index
+}
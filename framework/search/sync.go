@@ -0,0 +1,186 @@
+package search
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lemmego/api/config"
+	"github.com/lemmego/lemmego/framework/queue"
+	"github.com/lemmego/lemmego/framework/repo"
+	"gorm.io/gorm"
+)
+
+func init() {
+	queue.Register[SyncJob]("search.sync")
+}
+
+// Conditional models are only indexed while ShouldIndex returns true, e.g.
+// once a post is published. They are removed from the index otherwise.
+type Conditional interface {
+	ShouldIndex() bool
+}
+
+type definition struct {
+	settings Settings
+	// importAll indexes every row in chunks, returning how many were indexed.
+	importAll func(ctx context.Context, e Engine, chunk int) (int, error)
+}
+
+var (
+	definitionsMu sync.RWMutex
+	definitions   = make(map[string]definition)
+)
+
+// Sync registers T's index and keeps it up to date: every create, update and
+// delete of a T queues a SyncJob carrying the changed documents. Updates and
+// deletes by condition, which never load the models, are not seen; run
+// search:import after those.
+func Sync[T any, PT interface {
+	*T
+	Indexable
+}](settings ...Settings) {
+	index := PT(new(T)).SearchIndex()
+	var s Settings
+	if len(settings) > 0 {
+		s = settings[0]
+	}
+
+	definitionsMu.Lock()
+	definitions[index] = definition{settings: s, importAll: importAll[T, PT]}
+	definitionsMu.Unlock()
+
+	repo.Observe[T](observer[T, PT]{})
+}
+
+type observer[T any, PT interface {
+	*T
+	Indexable
+}] struct{}
+
+func (observer[T, PT]) Created(ctx context.Context, model *T) error {
+	return dispatch(ctx, PT(model), false)
+}
+
+func (observer[T, PT]) Updated(ctx context.Context, model *T) error {
+	return dispatch(ctx, PT(model), false)
+}
+
+func (observer[T, PT]) Deleted(ctx context.Context, model *T) error {
+	return dispatch(ctx, PT(model), true)
+}
+
+func dispatch(ctx context.Context, model Indexable, deleted bool) error {
+	key := model.SearchKey()
+	if !Enabled() || key == "" || key == "0" {
+		// Nothing to sync, or a write by condition without a loaded model
+		return nil
+	}
+
+	job := &SyncJob{Index: model.SearchIndex()}
+	if c, ok := model.(Conditional); deleted || ok && !c.ShouldIndex() {
+		job.Delete = []string{key}
+	} else {
+		job.Documents = []Document{{ID: key, Fields: model.SearchDocument()}}
+	}
+	_, err := queue.Dispatch(ctx, job, &queue.Options{Queue: config.Get("search.queue", "").(string)})
+	return err
+}
+
+// SyncJob applies index changes. It carries the documents as they were when
+// written, so it does not read rows a transaction has yet to commit.
+type SyncJob struct {
+	Index     string     `json:"index"`
+	Documents []Document `json:"documents,omitempty"`
+	Delete    []string   `json:"delete,omitempty"`
+}
+
+func (j *SyncJob) Handle(ctx context.Context) error {
+	e, err := Default()
+	if err != nil {
+		return err
+	}
+	if len(j.Documents) > 0 {
+		if err := e.Index(ctx, Name(j.Index), j.Documents); err != nil {
+			return err
+		}
+	}
+	if len(j.Delete) > 0 {
+		return e.Delete(ctx, Name(j.Index), j.Delete)
+	}
+	return nil
+}
+
+// settingsOf returns the settings of an engine side index name.
+func settingsOf(name string) Settings {
+	definitionsMu.RLock()
+	defer definitionsMu.RUnlock()
+	return definitions[strings.TrimPrefix(name, config.Get("search.prefix", "").(string))].settings
+}
+
+// Indexes lists the names of the indexes registered by Sync.
+func Indexes() []string {
+	definitionsMu.RLock()
+	defer definitionsMu.RUnlock()
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Import configures an index and indexes all of its rows, for the initial
+// fill or after writes Sync could not see.
+func Import(ctx context.Context, index string, chunk int) (int, error) {
+	definitionsMu.RLock()
+	def, ok := definitions[index]
+	definitionsMu.RUnlock()
+	if !ok {
+		return 0, ErrUnknownIndex
+	}
+
+	e, err := Default()
+	if err != nil {
+		return 0, err
+	}
+	if err := e.Configure(ctx, Name(index), def.settings); err != nil {
+		return 0, err
+	}
+	return def.importAll(ctx, e, chunk)
+}
+
+func importAll[T any, PT interface {
+	*T
+	Indexable
+}](ctx context.Context, e Engine, chunk int) (int, error) {
+	var rows []T
+	count := 0
+	index := Name(PT(new(T)).SearchIndex())
+	err := repo.New[T](ctx).Query().FindInBatches(&rows, chunk, func(tx *gorm.DB, batch int) error {
+		docs := make([]Document, 0, len(rows))
+		var hidden []string
+		for i := range rows {
+			model := PT(&rows[i])
+			if c, ok := any(model).(Conditional); ok && !c.ShouldIndex() {
+				hidden = append(hidden, model.SearchKey())
+				continue
+			}
+			docs = append(docs, Document{ID: model.SearchKey(), Fields: model.SearchDocument()})
+		}
+		if len(docs) > 0 {
+			if err := e.Index(ctx, index, docs); err != nil {
+				return err
+			}
+		}
+		if len(hidden) > 0 {
+			if err := e.Delete(ctx, index, hidden); err != nil {
+				return err
+			}
+		}
+		count += len(docs)
+		return nil
+	}).Error
+	return count, err
+}
@@ -0,0 +1,41 @@
+//go:build http3
+
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+type http3Server struct {
+	*http3.Server
+}
+
+func serveHTTP3(addr string, tlsConfig *tls.Config, handler http.Handler) (*http3Server, error) {
+	srv := &http3.Server{
+		Addr:      addr,
+		Handler:   handler,
+		TLSConfig: http3.ConfigureTLSConfig(tlsConfig),
+	}
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error(fmt.Sprintf("server: http3 listener on %s stopped: %s", addr, err))
+		}
+	}()
+	slog.Info(fmt.Sprintf("Serving HTTP/3 on %s", addr))
+	return &http3Server{srv}, nil
+}
+
+// advertise adds the Alt-Svc header so clients upgrade to HTTP/3.
+func (s *http3Server) advertise(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = s.SetQUICHeaders(w.Header())
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,25 @@
+//go:build !http3
+
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+)
+
+// HTTP/3 pulls in quic-go, so it is only compiled with `go build -tags http3`.
+type http3Server struct{}
+
+func serveHTTP3(addr string, tlsConfig *tls.Config, handler http.Handler) (*http3Server, error) {
+	return nil, errors.New("server: http3 is not available, rebuild with -tags http3")
+}
+
+func (s *http3Server) advertise(next http.Handler) http.Handler {
+	return next
+}
+
+func (s *http3Server) Shutdown(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lemmego/api/app"
+)
+
+type listenerKey struct{}
+
+// Listener describes an additional address the application is served on.
+type Listener struct {
+	// Name identifies the listener in logs and through ListenerFrom.
+	Name string
+	// Network is "tcp" (default) or "unix".
+	Network string
+	// Address is a host:port for tcp or a socket path for unix.
+	Address string
+	// TLS serves HTTPS on the listener when set.
+	TLS *tls.Config
+	// Internal marks the listener as private, see HideInternal.
+	Internal bool
+	// Paths restricts the listener to these path prefixes when not empty.
+	Paths []string
+	// Middleware wraps the handler of this listener only.
+	Middleware []app.HTTPMiddleware
+	// Handler replaces the application handler for this listener.
+	Handler http.Handler
+}
+
+// Listen binds l, served in the background once the routes are mounted, see
+// ServeWhenMounted.
+func (s *Server) Listen(l *Listener) error {
+	handler := l.Handler
+	if handler == nil {
+		h, err := s.Handler()
+		if err != nil {
+			return err
+		}
+		handler = h
+	}
+
+	if len(l.Paths) > 0 {
+		handler = onlyPaths(l.Paths, handler)
+	}
+	for i := len(l.Middleware) - 1; i >= 0; i-- {
+		handler = l.Middleware[i](handler)
+	}
+	handler = tagListener(l, handler)
+
+	ln, err := listen(l)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.listeners[l.key()] = ln
+	s.mu.Unlock()
+
+	srv := &http.Server{
+		Handler:           handler,
+		TLSConfig:         l.TLS,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	s.track(srv)
+
+	s.serve(func() {
+		var err error
+		if l.TLS != nil {
+			err = srv.ServeTLS(ln, "", "")
+		} else {
+			err = srv.Serve(ln)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error(fmt.Sprintf("server: listener %s on %s stopped: %s", l.Name, l.Address, err))
+		}
+	})
+
+	scheme := "http"
+	if l.TLS != nil {
+		scheme = "https"
+	}
+	slog.Info(fmt.Sprintf("Serving %s listener %s on %s:%s", scheme, l.Name, l.network(), l.Address))
+	return nil
+}
+
+func (l *Listener) network() string {
+	if l.Network == "" {
+		return "tcp"
+	}
+	return l.Network
+}
+
+func (l *Listener) key() string {
+	return l.network() + ":" + l.Address
+}
+
+// listen reuses a listener handed down by a restarting parent, or binds a new one.
+func listen(l *Listener) (net.Listener, error) {
+	if ln, ok := inheritedListener(l.key()); ok {
+		if ul, ok := ln.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+		return ln, nil
+	}
+
+	switch l.network() {
+	case "tcp", "tcp4", "tcp6":
+		lc := net.ListenConfig{Control: reusePort}
+		return lc.Listen(context.Background(), l.network(), l.Address)
+	case "unix":
+		// A socket left behind by a crashed process would make the bind fail
+		if _, err := os.Stat(l.Address); err == nil {
+			if err := os.Remove(l.Address); err != nil {
+				return nil, err
+			}
+		}
+		ln, err := net.Listen("unix", l.Address)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Chmod(l.Address, 0660); err != nil {
+			ln.Close()
+			return nil, err
+		}
+		// The socket file must outlive this process when it is handed to a new one
+		ln.(*net.UnixListener).SetUnlinkOnClose(false)
+		return ln, nil
+	}
+	return nil, fmt.Errorf("server: unsupported network %q", l.Network)
+}
+
+func tagListener(l *Listener, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), listenerKey{}, l)))
+	})
+}
+
+func onlyPaths(prefixes []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hasPathPrefix(r.URL.Path, prefixes) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		http.NotFound(w, r)
+	})
+}
+
+func hasPathPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ListenerFrom returns the listener a request arrived on. Requests served by
+// the framework's own HTTP listener return nil.
+func ListenerFrom(ctx context.Context) *Listener {
+	l, _ := ctx.Value(listenerKey{}).(*Listener)
+	return l
+}
+
+// HideInternal answers 404 for the given path prefixes unless the request came
+// in through an internal listener, so endpoints such as metrics or health
+// checks are never exposed on the public port.
+func HideInternal(prefixes ...string) app.HTTPMiddleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if hasPathPrefix(r.URL.Path, prefixes) {
+				if l := ListenerFrom(r.Context()); l == nil || !l.Internal {
+					http.NotFound(w, r)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// envInheritedFDs maps listener keys to file descriptors, e.g. "tcp::8080=3;unix:./app.sock=4".
+	envInheritedFDs = "LEMMEGO_INHERITED_FDS"
+	// envReadyFD is the descriptor a child writes to once it is serving.
+	envReadyFD = "LEMMEGO_READY_FD"
+)
+
+var (
+	inheritedOnce sync.Once
+	inherited     map[string]net.Listener
+)
+
+// inheritedListener returns a listener passed down by the parent process.
+func inheritedListener(key string) (net.Listener, bool) {
+	inheritedOnce.Do(func() {
+		inherited = make(map[string]net.Listener)
+		for _, entry := range strings.Split(os.Getenv(envInheritedFDs), ";") {
+			k, v, found := strings.Cut(entry, "=")
+			if !found {
+				continue
+			}
+			fd, err := strconv.Atoi(v)
+			if err != nil {
+				continue
+			}
+			f := os.NewFile(uintptr(fd), k)
+			ln, err := net.FileListener(f)
+			f.Close()
+			if err != nil {
+				slog.Error(fmt.Sprintf("server: cannot inherit listener %s: %s", k, err))
+				continue
+			}
+			inherited[k] = ln
+		}
+	})
+
+	ln, ok := inherited[key]
+	if ok {
+		delete(inherited, key)
+	}
+	return ln, ok
+}
+
+// IsChild reports whether the process was started by Restart.
+func IsChild() bool {
+	return os.Getenv(envReadyFD) != ""
+}
+
+// Ready tells the parent process that this process serves requests, after
+// which the parent drains its connections and exits. It is a no-op for
+// processes not started by Restart.
+func Ready() error {
+	v := os.Getenv(envReadyFD)
+	if v == "" {
+		return nil
+	}
+	fd, err := strconv.Atoi(v)
+	if err != nil {
+		return err
+	}
+
+	f := os.NewFile(uintptr(fd), "ready")
+	defer f.Close()
+	_, err = f.Write([]byte("ready"))
+	os.Unsetenv(envReadyFD)
+	return err
+}
+
+// Restart starts a new copy of the executable, hands it every listener and
+// waits until it is serving. The current process then drains its connections
+// and delivers SIGTERM to itself so the framework's HandleSignals closes the
+// remaining resources and exits. Deploy by replacing the binary on disk and
+// sending SIGHUP.
+func (s *Server) Restart() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	listeners := s.listeners
+	s.mu.Unlock()
+
+	var files []*os.File
+	var mapping []string
+	for key, ln := range listeners {
+		f, err := listenerFile(ln)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		// ExtraFiles start at descriptor 3 in the child
+		mapping = append(mapping, fmt.Sprintf("%s=%d", key, 3+len(files)))
+		files = append(files, f)
+	}
+
+	readR, readyW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer readR.Close()
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = append(files, readyW)
+	cmd.Env = append(os.Environ(),
+		envInheritedFDs+"="+strings.Join(mapping, ";"),
+		envReadyFD+"="+strconv.Itoa(3+len(files)),
+	)
+
+	if err := cmd.Start(); err != nil {
+		readyW.Close()
+		return err
+	}
+	readyW.Close()
+
+	ready := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 5)
+		_, err := readR.Read(buf)
+		ready <- err
+	}()
+
+	select {
+	case err := <-ready:
+		if err != nil {
+			_ = cmd.Process.Kill()
+			return fmt.Errorf("server: new process exited before becoming ready: %w", err)
+		}
+	case <-time.After(time.Minute):
+		_ = cmd.Process.Kill()
+		return errors.New("server: new process did not become ready in time")
+	}
+
+	slog.Info(fmt.Sprintf("New process %d is serving, draining connections...", cmd.Process.Pid))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		slog.Error(fmt.Sprintf("server: shutdown: %s", err))
+	}
+
+	self, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		return err
+	}
+	return self.Signal(syscall.SIGTERM)
+}
+
+// RestartOnSignal calls Restart whenever the process receives SIGHUP.
+func (s *Server) RestartOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		slog.Info("Received SIGHUP, restarting...")
+		if err := s.Restart(); err != nil {
+			slog.Error(fmt.Sprintf("server: restart failed, keeping the current process: %s", err))
+		}
+	}
+}
+
+func listenerFile(ln net.Listener) (*os.File, error) {
+	switch l := ln.(type) {
+	case *net.TCPListener:
+		return l.File()
+	case *net.UnixListener:
+		return l.File()
+	}
+	return nil, fmt.Errorf("server: cannot pass %T to a new process", ln)
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package server
+
+import (
+	"syscall"
+)
+
+// reusePort is a no-op where SO_REUSEPORT is unavailable, restarts still work
+// through descriptor passing.
+func reusePort(network, address string, c syscall.RawConn) error {
+	return nil
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/session"
+)
+
+// Server runs the listeners that the framework does not manage itself, such
+// as HTTPS. Every listener serves the application router wrapped in the same
+// session middleware the framework uses for its own HTTP listener.
+type Server struct {
+	app app.App
+
+	mu        sync.Mutex
+	servers   []*http.Server
+	listeners map[string]net.Listener
+	shutdown  []func(ctx context.Context) error
+	// pending listeners wait for the routes, see ServeWhenMounted
+	pending []func()
+	serving bool
+}
+
+func New(a app.App) *Server {
+	return &Server{app: a, listeners: make(map[string]net.Listener)}
+}
+
+// Handler returns the application handler served by every listener.
+func (s *Server) Handler() (http.Handler, error) {
+	router, ok := s.app.Router().(http.Handler)
+	if !ok {
+		return nil, errors.New("server: router is not an http.Handler")
+	}
+
+	var sess *session.Session
+	if err := s.app.Service(&sess); err != nil {
+		return nil, err
+	}
+
+	return sess.LoadAndSave(router), nil
+}
+
+// ServeTLS starts serving HTTPS on addr in the background. HTTP/2 is negotiated
+// through ALPN, and when http3 is true HTTP/3 is served on the same UDP port.
+func (s *Server) ServeTLS(addr string, tlsConfig *tls.Config, http3 bool) error {
+	handler, err := s.Handler()
+	if err != nil {
+		return err
+	}
+
+	if http3 {
+		h3, err := serveHTTP3(addr, tlsConfig, handler)
+		if err != nil {
+			return err
+		}
+		handler = h3.advertise(handler)
+		s.onShutdown(h3.Shutdown)
+	}
+
+	return s.Listen(&Listener{Name: "https", Address: addr, TLS: tlsConfig, Handler: handler})
+}
+
+// serve runs start in the background once the routes are mounted.
+func (s *Server) serve(start func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.serving {
+		go start()
+		return
+	}
+	s.pending = append(s.pending, start)
+}
+
+// ServeWhenMounted serves the listeners bound by Listen once the framework
+// accepts connections on port, its own listener, which it only opens after
+// mounting every route: served before, the listeners would answer 404.
+// A process started by Restart then tells its parent it is ready.
+func (s *Server) ServeWhenMounted(port int) {
+	go func() {
+		addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+		for {
+			conn, err := net.DialTimeout("tcp", addr, time.Second)
+			if err == nil {
+				conn.Close()
+				break
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+
+		s.mu.Lock()
+		s.serving = true
+		pending := s.pending
+		s.pending = nil
+		s.mu.Unlock()
+		for _, start := range pending {
+			go start()
+		}
+
+		if err := Ready(); err != nil {
+			slog.Error(fmt.Sprintf("server: notify parent: %s", err))
+		}
+	}()
+}
+
+// Shutdown gracefully stops every listener started by the server.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	servers := s.servers
+	callbacks := s.shutdown
+	s.mu.Unlock()
+
+	var errs []error
+	for _, srv := range servers {
+		errs = append(errs, srv.Shutdown(ctx))
+	}
+	for _, cb := range callbacks {
+		errs = append(errs, cb(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// ShutdownOnSignal stops the listeners on SIGINT/SIGTERM, alongside the
+// framework's own HandleSignals which closes the main listener.
+func (s *Server) ShutdownOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	<-signals
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		slog.Error(fmt.Sprintf("server: shutdown: %s", err))
+	}
+}
+
+func (s *Server) track(srv *http.Server) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.servers = append(s.servers, srv)
+}
+
+func (s *Server) onShutdown(cb func(ctx context.Context) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shutdown = append(s.shutdown, cb)
+}
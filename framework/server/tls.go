@@ -0,0 +1,130 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/lemmego/api/app"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+type TLSOptions struct {
+	// CertFile and KeyFile point to a PEM certificate and key.
+	CertFile string
+	KeyFile  string
+
+	// Domains enables automatic Let's Encrypt certificates when no CertFile is given.
+	Domains []string
+	// Email is the ACME account contact address.
+	Email string
+	// CacheDir stores issued certificates between restarts.
+	CacheDir string
+
+	// ClientCAFile asks clients for certificates issued by the authorities
+	// of this PEM bundle. Clients without one are still served, routes
+	// requiring one check it themselves.
+	ClientCAFile string
+}
+
+// NewTLSConfig builds a TLS configuration from a certificate pair or, when only
+// domains are given, from an autocert manager. The manager is returned so the
+// HTTP listener can answer ACME http-01 challenges.
+func NewTLSConfig(o *TLSOptions) (*tls.Config, *autocert.Manager, error) {
+	tlsConfig, manager, err := newTLSConfig(o)
+	if err != nil || o.ClientCAFile == "" {
+		return tlsConfig, manager, err
+	}
+
+	pem, err := os.ReadFile(o.ClientCAFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	tlsConfig.ClientCAs = x509.NewCertPool()
+	if !tlsConfig.ClientCAs.AppendCertsFromPEM(pem) {
+		return nil, nil, fmt.Errorf("server: no certificates in %s", o.ClientCAFile)
+	}
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	return tlsConfig, manager, nil
+}
+
+func newTLSConfig(o *TLSOptions) (*tls.Config, *autocert.Manager, error) {
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{cert},
+			NextProtos:   []string{"h2", "http/1.1"},
+		}, nil, nil
+	}
+
+	if len(o.Domains) == 0 {
+		return nil, nil, errors.New("server: tls requires a certificate pair or autocert domains")
+	}
+
+	cacheDir := o.CacheDir
+	if cacheDir == "" {
+		cacheDir = "./storage/certs"
+	}
+
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(o.Domains...),
+		Cache:      autocert.DirCache(cacheDir),
+		Email:      o.Email,
+	}
+
+	tlsConfig := manager.TLSConfig()
+	tlsConfig.MinVersion = tls.VersionTLS12
+	return tlsConfig, manager, nil
+}
+
+// RedirectHTTPS redirects requests on the framework's plain HTTP listener to the
+// HTTPS port. Requests on additional listeners, such as an internal port or a
+// unix socket, are left alone. ACME http-01 challenges are answered instead of
+// redirected when a manager is given. X-Forwarded-Proto is only believed on
+// requests fromProxy reports as sent by a trusted proxy, such as
+// middleware.FromTrustedProxy, registered after it; a nil fromProxy trusts none.
+func RedirectHTTPS(httpsPort string, manager *autocert.Manager, fromProxy func(r *http.Request) bool) app.HTTPMiddleware {
+	return func(next http.Handler) http.Handler {
+		var challenge http.Handler
+		if manager != nil {
+			challenge = manager.HTTPHandler(nil)
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			forwarded := fromProxy != nil && fromProxy(r) && r.Header.Get("X-Forwarded-Proto") == "https"
+			if r.TLS != nil || forwarded || ListenerFrom(r.Context()) != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if challenge != nil && strings.HasPrefix(r.URL.Path, "/.well-known/acme-challenge/") {
+				challenge.ServeHTTP(w, r)
+				return
+			}
+
+			host := r.Host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			if httpsPort != "" && httpsPort != "443" {
+				host = net.JoinHostPort(host, httpsPort)
+			}
+
+			status := http.StatusMovedPermanently
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				status = http.StatusPermanentRedirect
+			}
+			http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), status)
+		})
+	}
+}
@@ -0,0 +1,271 @@
+// Package sitemap builds sitemap.xml from iterators provided by the app. Past
+// 50,000 URLs or 50 MB the sitemap is split into pages listed by a sitemap
+// index, as the protocol requires.
+//
+//	sitemap.Mount(r, &sitemap.Sitemap{
+//		BaseURL: "https://example.com",
+//		Sources: []sitemap.Source{
+//			sitemap.Static(sitemap.URL{Loc: "/", ChangeFreq: sitemap.Daily}),
+//			sitemap.FromRepo(func(ctx context.Context) *repo.Repo[Post] {
+//				return repo.New[Post](ctx).Where("published = ?", true)
+//			}, func(p *Post) sitemap.URL {
+//				return sitemap.URL{Loc: "/posts/" + p.Slug, LastMod: p.UpdatedAt}
+//			}),
+//		},
+//	}, time.Hour)
+package sitemap
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/config"
+	"github.com/lemmego/lemmego/framework/cache"
+	"github.com/lemmego/lemmego/framework/repo"
+	"gorm.io/gorm"
+)
+
+// Protocol limits for a single sitemap file.
+const (
+	MaxURLs  = 50_000
+	MaxBytes = 50 << 20
+)
+
+// cacheTag marks every cached sitemap, see Flush.
+const cacheTag = "sitemap"
+
+type ChangeFreq string
+
+const (
+	Always  ChangeFreq = "always"
+	Hourly  ChangeFreq = "hourly"
+	Daily   ChangeFreq = "daily"
+	Weekly  ChangeFreq = "weekly"
+	Monthly ChangeFreq = "monthly"
+	Yearly  ChangeFreq = "yearly"
+	Never   ChangeFreq = "never"
+)
+
+// URL is a sitemap entry. Optional fields are left out when zero.
+type URL struct {
+	// Loc is absolute, or a path resolved against Sitemap.BaseURL.
+	Loc        string
+	LastMod    time.Time
+	ChangeFreq ChangeFreq
+	// Priority ranges from 0 to 1.
+	Priority float64
+}
+
+// Source yields the URLs of one kind of page. It is called on every build.
+type Source func(ctx context.Context) iter.Seq2[URL, error]
+
+// Static is a Source of fixed URLs.
+func Static(urls ...URL) Source {
+	return func(context.Context) iter.Seq2[URL, error] {
+		return func(yield func(URL, error) bool) {
+			for _, u := range urls {
+				if !yield(u, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+var errStop = errors.New("sitemap: stop")
+
+// FromRepo is a Source reading rows in batches, so large tables are never
+// loaded at once.
+func FromRepo[T any](query func(ctx context.Context) *repo.Repo[T], url func(*T) URL) Source {
+	return func(ctx context.Context) iter.Seq2[URL, error] {
+		return func(yield func(URL, error) bool) {
+			var rows []T
+			err := query(ctx).Query().FindInBatches(&rows, 1000, func(tx *gorm.DB, batch int) error {
+				for i := range rows {
+					if !yield(url(&rows[i]), nil) {
+						return errStop
+					}
+				}
+				return nil
+			}).Error
+			if err != nil && !errors.Is(err, errStop) {
+				yield(URL{}, err)
+			}
+		}
+	}
+}
+
+// Sitemap lists the URLs of every Source.
+type Sitemap struct {
+	// BaseURL resolves relative locations and links the pages of an index,
+	// app.url by default.
+	BaseURL string
+	Sources []Source
+	// PagePath is where the pages of an index are served, "/sitemaps".
+	PagePath string
+}
+
+// Files holds a built sitemap by path: "sitemap.xml", and when it was split
+// "sitemaps/1.xml" onwards.
+type Files map[string][]byte
+
+func (s *Sitemap) baseURL() string {
+	if s.BaseURL != "" {
+		return strings.TrimRight(s.BaseURL, "/")
+	}
+	return strings.TrimRight(config.Get("app.url", "").(string), "/")
+}
+
+func (s *Sitemap) pagePath() string {
+	if s.PagePath != "" {
+		return strings.Trim(s.PagePath, "/")
+	}
+	return "sitemaps"
+}
+
+const (
+	urlsetOpen  = xml.Header + `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` + "\n"
+	urlsetClose = "</urlset>\n"
+)
+
+// Build renders the sitemap, splitting it once a page reaches the limits.
+func (s *Sitemap) Build(ctx context.Context) (Files, error) {
+	base := s.baseURL()
+	var pages [][]byte
+	var page bytes.Buffer
+	count := 0
+	var entry bytes.Buffer
+
+	flush := func() {
+		page.WriteString(urlsetClose)
+		pages = append(pages, bytes.Clone(page.Bytes()))
+		page.Reset()
+		count = 0
+	}
+
+	for _, source := range s.Sources {
+		for u, err := range source(ctx) {
+			if err != nil {
+				return nil, err
+			}
+			entry.Reset()
+			writeURL(&entry, base, u)
+			if count > 0 && (count == MaxURLs || page.Len()+entry.Len()+len(urlsetClose) > MaxBytes) {
+				flush()
+			}
+			if count == 0 {
+				page.WriteString(urlsetOpen)
+			}
+			page.Write(entry.Bytes())
+			count++
+		}
+	}
+	if count > 0 || len(pages) == 0 {
+		if count == 0 {
+			page.WriteString(urlsetOpen)
+		}
+		flush()
+	}
+
+	if len(pages) == 1 {
+		return Files{"sitemap.xml": pages[0]}, nil
+	}
+
+	files := Files{}
+	var index bytes.Buffer
+	index.WriteString(xml.Header + `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` + "\n")
+	now := time.Now().UTC().Format(time.RFC3339)
+	for i, p := range pages {
+		name := fmt.Sprintf("%s/%d.xml", s.pagePath(), i+1)
+		files[name] = p
+		index.WriteString("<sitemap><loc>")
+		xml.EscapeText(&index, []byte(base+"/"+name))
+		index.WriteString("</loc><lastmod>" + now + "</lastmod></sitemap>\n")
+	}
+	index.WriteString("</sitemapindex>\n")
+	files["sitemap.xml"] = index.Bytes()
+	return files, nil
+}
+
+func writeURL(b *bytes.Buffer, base string, u URL) {
+	loc := u.Loc
+	if !strings.Contains(loc, "://") {
+		loc = base + "/" + strings.TrimLeft(loc, "/")
+	}
+	b.WriteString("<url><loc>")
+	xml.EscapeText(b, []byte(loc))
+	b.WriteString("</loc>")
+	if !u.LastMod.IsZero() {
+		b.WriteString("<lastmod>" + u.LastMod.UTC().Format(time.RFC3339) + "</lastmod>")
+	}
+	if u.ChangeFreq != "" {
+		b.WriteString("<changefreq>" + string(u.ChangeFreq) + "</changefreq>")
+	}
+	if u.Priority > 0 {
+		b.WriteString("<priority>" + strconv.FormatFloat(min(u.Priority, 1), 'f', 1, 64) + "</priority>")
+	}
+	b.WriteString("</url>\n")
+}
+
+// Mount serves the sitemap at /sitemap.xml and its pages under PagePath,
+// rebuilding it at most once per ttl.
+func Mount(r app.Router, s *Sitemap, ttl time.Duration) {
+	key := "sitemap:" + s.pagePath()
+	serve := func(w http.ResponseWriter, req *http.Request, name string) {
+		files, err := cache.Remember(cache.Default(), key, ttl, func() (Files, error) {
+			if s.baseURL() == "" {
+				// Fall back to the host of the request that builds it
+				withBase := *s
+				withBase.BaseURL = requestBase(req)
+				return withBase.Build(req.Context())
+			}
+			return s.Build(req.Context())
+		}, cacheTag)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		body, ok := files[name]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		sum := sha256.Sum256(body)
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(ttl.Seconds())))
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:8])+`"`)
+		http.ServeContent(w, req, "", time.Time{}, bytes.NewReader(body))
+	}
+
+	r.Handle("GET /sitemap.xml", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		serve(w, req, "sitemap.xml")
+	}))
+	r.Handle("GET /"+s.pagePath()+"/{page}", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		serve(w, req, s.pagePath()+"/"+req.PathValue("page"))
+	}))
+}
+
+func requestBase(req *http.Request) string {
+	scheme := "http"
+	if req.TLS != nil || req.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + req.Host
+}
+
+// Flush drops the cached sitemaps, so the next request rebuilds them after
+// content changed.
+func Flush() {
+	cache.Default().FlushTags(cacheTag)
+}
@@ -0,0 +1,202 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sns"
+
+	"github.com/lemmego/lemmego/framework/httpclient"
+)
+
+// Twilio sends through the Programmable Messaging API. From is a Twilio
+// number, or the sid of a messaging service, starting with MG.
+type Twilio struct {
+	AccountSID string
+	AuthToken  string
+	// BaseURL defaults to https://api.twilio.com.
+	BaseURL string
+}
+
+func (t *Twilio) Send(ctx context.Context, m *Message) error {
+	base := t.BaseURL
+	if base == "" {
+		base = "https://api.twilio.com"
+	}
+	form := url.Values{"To": {m.To}, "Body": {m.Text}}
+	if strings.HasPrefix(m.From, "MG") {
+		form.Set("MessagingServiceSid", m.From)
+	} else {
+		form.Set("From", m.From)
+	}
+	req, err := http.NewRequest(http.MethodPost, base+"/2010-04-01/Accounts/"+url.PathEscape(t.AccountSID)+"/Messages.json", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(t.AccountSID, t.AuthToken)
+	resp, err := httpclient.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var e struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		return fmt.Errorf("sms: twilio answered %s: %d %s", resp.Status, e.Code, e.Message)
+	}
+	return nil
+}
+
+// Vonage sends through the SMS API of Vonage, formerly Nexmo.
+type Vonage struct {
+	APIKey    string
+	APISecret string
+	// BaseURL defaults to https://rest.nexmo.com.
+	BaseURL string
+}
+
+func (v *Vonage) Send(ctx context.Context, m *Message) error {
+	base := v.BaseURL
+	if base == "" {
+		base = "https://rest.nexmo.com"
+	}
+	form := url.Values{
+		"api_key":    {v.APIKey},
+		"api_secret": {v.APISecret},
+		"from":       {strings.TrimPrefix(m.From, "+")},
+		"to":         {strings.TrimPrefix(m.To, "+")},
+		"text":       {m.Text},
+	}
+	if !isGSM(m.Text) {
+		form.Set("type", "unicode")
+	}
+	resp, err := httpclient.Post(ctx, base+"/sms/json", "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("sms: vonage answered %s", resp.Status)
+	}
+	// Every part of a long message has a status, "0" when accepted
+	var out struct {
+		Messages []struct {
+			Status    string `json:"status"`
+			ErrorText string `json:"error-text"`
+		} `json:"messages"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return err
+	}
+	for _, part := range out.Messages {
+		if part.Status != "0" {
+			return fmt.Errorf("sms: vonage rejected the message: %s %s", part.Status, part.ErrorText)
+		}
+	}
+	return nil
+}
+
+// gsmChars is the GSM 03.38 basic character set, which messages are sent
+// in unless they need unicode.
+const gsmChars = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà^{}\\[~]|€"
+
+func isGSM(s string) bool {
+	for _, r := range s {
+		if !strings.ContainsRune(gsmChars, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// SNS sends through Amazon SNS, whose sender id is From when set.
+type SNS struct {
+	client *sns.SNS
+}
+
+// NewSNS returns an SNS driver for region, with the default AWS credentials.
+func NewSNS(region string) (*SNS, error) {
+	cfg := aws.NewConfig()
+	if region != "" {
+		cfg = cfg.WithRegion(region)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{Config: *cfg, SharedConfigState: session.SharedConfigEnable})
+	if err != nil {
+		return nil, err
+	}
+	return &SNS{client: sns.New(sess)}, nil
+}
+
+func (s *SNS) Send(ctx context.Context, m *Message) error {
+	attrs := map[string]*sns.MessageAttributeValue{
+		// Transactional messages are delivered ahead of promotional ones
+		"AWS.SNS.SMS.SMSType": {DataType: aws.String("String"), StringValue: aws.String("Transactional")},
+	}
+	if m.From != "" && !strings.HasPrefix(m.From, "+") {
+		attrs["AWS.SNS.SMS.SenderID"] = &sns.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(m.From)}
+	}
+	_, err := s.client.PublishWithContext(ctx, &sns.PublishInput{
+		PhoneNumber:       aws.String(m.To),
+		Message:           aws.String(m.Text),
+		MessageAttributes: attrs,
+	})
+	return err
+}
+
+// Log writes messages to the log instead of sending them, for development.
+type Log struct{}
+
+func (Log) Send(ctx context.Context, m *Message) error {
+	slog.Info(fmt.Sprintf("sms: to %s: %s", m.To, m.Text))
+	return nil
+}
+
+// Fake keeps the messages instead of sending them, for tests:
+//
+//	fake := &sms.Fake{}
+//	sms.SetDefault(fake)
+//	...
+//	sent := fake.Sent()
+type Fake struct {
+	// Err fails every message when set.
+	Err error
+
+	mu   sync.Mutex
+	sent []Message
+}
+
+func (f *Fake) Send(ctx context.Context, m *Message) error {
+	if f.Err != nil {
+		return f.Err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, *m)
+	return nil
+}
+
+// Sent returns the messages sent so far.
+func (f *Fake) Sent() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Message(nil), f.sent...)
+}
+
+// Reset forgets the messages sent.
+func (f *Fake) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = nil
+}
@@ -0,0 +1,155 @@
+// Package sms sends text messages through the driver configured by
+// sms.driver: Twilio, Vonage, Amazon SNS, or the log for development.
+// Messages are sent right away, or queued to survive an outage of the
+// provider, and translated into the locale of the context:
+//
+//	err := sms.Queue(ctx, user.Phone, "Your order :id has shipped", i18n.Args{"id": order.ID})
+//
+// Numbers are international, or national ones of sms.region.
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/lemmego/api/config"
+
+	"github.com/lemmego/lemmego/framework/i18n"
+	"github.com/lemmego/lemmego/framework/phone"
+	"github.com/lemmego/lemmego/framework/queue"
+)
+
+func init() {
+	queue.Register[SendJob]("sms.send")
+}
+
+var ErrUnknownDriver = errors.New("sms: unknown driver")
+
+// Message is a text message.
+type Message struct {
+	// To is the number of the recipient in E.164 form, as in +14155550100.
+	To string
+	// From is a number or an alphanumeric sender id, sms.from when empty.
+	From string
+	Text string
+}
+
+// Driver delivers messages.
+type Driver interface {
+	Send(ctx context.Context, m *Message) error
+}
+
+var (
+	mu            sync.Mutex
+	defaultDriver Driver
+)
+
+// Default returns the driver configured by sms.driver.
+func Default() (Driver, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if defaultDriver != nil {
+		return defaultDriver, nil
+	}
+
+	switch name := config.Get("sms.driver", "log").(string); name {
+	case "log":
+		defaultDriver = &Log{}
+	case "fake":
+		defaultDriver = &Fake{}
+	case "twilio":
+		defaultDriver = &Twilio{
+			AccountSID: config.Get("sms.twilio.account_sid", "").(string),
+			AuthToken:  config.Get("sms.twilio.auth_token", "").(string),
+		}
+	case "vonage":
+		defaultDriver = &Vonage{
+			APIKey:    config.Get("sms.vonage.key", "").(string),
+			APISecret: config.Get("sms.vonage.secret", "").(string),
+		}
+	case "sns":
+		d, err := NewSNS(config.Get("sms.sns.region", "").(string))
+		if err != nil {
+			return nil, err
+		}
+		defaultDriver = d
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownDriver, name)
+	}
+	return defaultDriver, nil
+}
+
+// SetDefault replaces the configured driver, e.g. with a Fake in tests.
+func SetDefault(d Driver) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultDriver = d
+}
+
+// Normalize returns number in E.164 form, reading national numbers as
+// numbers of sms.region.
+func Normalize(number string) (string, error) {
+	n, err := phone.Parse(number)
+	if err != nil {
+		if region := config.Get("sms.region", "").(string); region != "" {
+			n, err = phone.Parse(number, region)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return n.E164(), nil
+}
+
+// SendMessage delivers m through the default driver.
+func SendMessage(ctx context.Context, m *Message) error {
+	d, err := Default()
+	if err != nil {
+		return err
+	}
+	if m.To, err = Normalize(m.To); err != nil {
+		return err
+	}
+	if m.From == "" {
+		m.From = config.Get("sms.from", "").(string)
+	}
+	return d.Send(ctx, m)
+}
+
+// Send texts to the message key translated into the locale of ctx.
+func Send(ctx context.Context, to, key string, args i18n.Args) error {
+	return SendMessage(ctx, &Message{To: to, Text: i18n.T(i18n.LocaleFrom(ctx), key, args)})
+}
+
+// Queue texts to the message key, translated into the locale of ctx now,
+// from a worker.
+func Queue(ctx context.Context, to, key string, args i18n.Args, opts ...*queue.Options) error {
+	to, err := Normalize(to)
+	if err != nil {
+		return err
+	}
+	_, err = queue.Dispatch(ctx, &SendJob{To: to, Text: i18n.T(i18n.LocaleFrom(ctx), key, args)}, opts...)
+	return err
+}
+
+// SendCode texts a one time code, e.g. for a second factor. It is sent
+// right away, as the user waits for it.
+func SendCode(ctx context.Context, to, code string) error {
+	return Send(ctx, to, ":app: your verification code is :code", i18n.Args{
+		"app":  config.Get("app.name", "").(string),
+		"code": code,
+	})
+}
+
+// SendJob sends a queued message.
+type SendJob struct {
+	To   string `json:"to"`
+	From string `json:"from,omitempty"`
+	Text string `json:"text"`
+}
+
+func (j *SendJob) Handle(ctx context.Context) error {
+	return SendMessage(ctx, &Message{To: j.To, From: j.From, Text: j.Text})
+}
@@ -0,0 +1,194 @@
+package static
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lemmego/api/app"
+)
+
+type Options struct {
+	// MaxAge sets Cache-Control max-age on served files. Zero sends no-cache.
+	MaxAge time.Duration
+	// Immutable marks files as never changing, suitable for fingerprinted assets.
+	Immutable bool
+	// Precompressed serves file.br or file.gz when present and accepted by the client.
+	Precompressed bool
+	// SPA serves Index for GET requests that match no file, so client side
+	// routers can handle deep links.
+	SPA bool
+	// Index is the file served for directories and SPA fallbacks. Defaults to "index.html".
+	Index string
+}
+
+// encodings are tried in order of preference.
+var encodings = []struct {
+	name string
+	ext  string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// Mount serves fsys under prefix, e.g. Mount(r, "/assets", os.DirFS("public/assets")).
+// Any fs.FS works, including an embed.FS for single binary deployments.
+func Mount(r app.Router, prefix string, fsys fs.FS, opts ...*Options) {
+	prefix = "/" + strings.Trim(prefix, "/")
+	if prefix == "/" {
+		r.Handle("GET /", Handler(fsys, opts...))
+		return
+	}
+	r.Handle("GET "+prefix+"/", http.StripPrefix(prefix, Handler(fsys, opts...)))
+}
+
+// Handler returns an http.Handler serving files from fsys. Directory listings are never served.
+func Handler(fsys fs.FS, opts ...*Options) http.Handler {
+	o := &Options{}
+	if len(opts) > 0 && opts[0] != nil {
+		o = opts[0]
+	}
+	if o.Index == "" {
+		o.Index = "index.html"
+	}
+
+	cacheControl := "no-cache"
+	if o.MaxAge > 0 {
+		cacheControl = fmt.Sprintf("public, max-age=%d", int(o.MaxAge.Seconds()))
+		if o.Immutable {
+			cacheControl += ", immutable"
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		if name == "" {
+			name = "."
+		}
+
+		if stat, err := fs.Stat(fsys, name); err == nil && stat.IsDir() {
+			name = path.Join(name, o.Index)
+		}
+
+		if _, err := fs.Stat(fsys, name); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			// Missing files that look like assets stay 404 so broken links surface
+			if !o.SPA || path.Ext(name) != "" {
+				http.NotFound(w, r)
+				return
+			}
+			name = o.Index
+			// The fallback document changes with every deploy
+			w.Header().Set("Cache-Control", "no-cache")
+		} else {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+
+		serveFile(w, r, fsys, name, o.Precompressed)
+	})
+}
+
+func serveFile(w http.ResponseWriter, r *http.Request, fsys fs.FS, name string, precompressed bool) {
+	servedName := name
+	if precompressed {
+		w.Header().Add("Vary", "Accept-Encoding")
+		accepted := qualities(r.Header.Get("Accept-Encoding"))
+		best, encoding := 0.0, ""
+		for _, enc := range encodings {
+			// Ties go to the preferred encoding, listed first
+			q := quality(accepted, enc.name)
+			if q <= best {
+				continue
+			}
+			if _, err := fs.Stat(fsys, name+enc.ext); err == nil {
+				best, encoding, servedName = q, enc.name, name+enc.ext
+			}
+		}
+		if encoding != "" {
+			w.Header().Set("Content-Encoding", encoding)
+		}
+	}
+
+	f, err := fsys.Open(servedName)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	// Content-Type comes from the original name, not the .br/.gz variant
+	if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+		w.Header().Set("Content-Type", ctype)
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		content = bytes.NewReader(b)
+	}
+
+	http.ServeContent(w, r, name, stat.ModTime(), content)
+}
+
+// qualities parses an Accept-Encoding header into the quality of each
+// coding it lists, "*" standing for the others.
+func qualities(header string) map[string]float64 {
+	q := map[string]float64{}
+	for _, item := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(item, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		value := 1.0
+		for _, param := range strings.Split(params, ";") {
+			k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(k, "q") {
+				// A malformed quality refuses the coding rather than forcing it
+				f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+				if err != nil || !(f >= 0 && f <= 1) {
+					f = 0
+				}
+				value = f
+			}
+		}
+		q[coding] = value
+	}
+	return q
+}
+
+// quality returns the quality a client gives coding, zero when refused or
+// not accepted.
+func quality(q map[string]float64, coding string) float64 {
+	if v, ok := q[coding]; ok {
+		return v
+	}
+	return q["*"]
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// ClamAV scans files with a clamd daemon, streaming them over its INSTREAM
+// command.
+type ClamAV struct {
+	Network string
+	Address string
+	// Timeout bounds a scan, one minute when zero.
+	Timeout time.Duration
+}
+
+// NewClamAV returns a scanner for the daemon at address, such as
+// "tcp://127.0.0.1:3310", "unix:///run/clamav/clamd.ctl" or "host:3310".
+func NewClamAV(address string) *ClamAV {
+	if network, addr, ok := strings.Cut(address, "://"); ok {
+		return &ClamAV{Network: network, Address: addr}
+	}
+	return &ClamAV{Network: "tcp", Address: address}
+}
+
+// clamdChunk stays well below clamd's default StreamMaxLength.
+const clamdChunk = 64 << 10
+
+func (c *ClamAV) Scan(ctx context.Context, r io.Reader) (ScanResult, error) {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, c.Network, c.Address)
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("clamav: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return ScanResult{}, fmt.Errorf("clamav: %w", err)
+	}
+	buf := make([]byte, 4+clamdChunk)
+	for {
+		n, err := r.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				return ScanResult{}, fmt.Errorf("clamav: %w", err)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return ScanResult{}, err
+		}
+	}
+	// A zero length chunk ends the stream
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return ScanResult{}, fmt.Errorf("clamav: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return ScanResult{}, fmt.Errorf("clamav: %w", err)
+	}
+	return parseClamdReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// parseClamdReply reads replies such as "stream: OK" and
+// "stream: Eicar-Signature FOUND".
+func parseClamdReply(reply string) (ScanResult, error) {
+	result := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+	switch {
+	case result == "OK":
+		return ScanResult{}, nil
+	case strings.HasSuffix(result, " FOUND"):
+		return ScanResult{Infected: true, Signature: strings.TrimSuffix(result, " FOUND")}, nil
+	}
+	return ScanResult{}, fmt.Errorf("clamav: %s", reply)
+}
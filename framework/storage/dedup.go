@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/lemmego/lemmego/framework/queue"
+	"github.com/lemmego/lemmego/framework/repo"
+)
+
+// Files stored with PutDedup are named after the SHA-256 of their contents,
+// so uploading the same file twice, by any user, stores it once:
+//
+//	p, err := disk.PutDedup(contents) // blobs/9f/86/9f86d0...
+//	...
+//	err = disk.Release(p) // once nothing refers to it anymore
+//
+// The storage_blobs table counts the references to every blob. Blobs no
+// longer referenced are deleted by CollectGarbage, from cron with the
+// storage:gc command or by dispatching a GCJob, once they have been
+// unreferenced for a grace period.
+
+func init() {
+	queue.Register[GCJob]("storage.gc")
+}
+
+// BlobDir is the directory of deduplicated files on a disk.
+const BlobDir = "blobs"
+
+var (
+	ErrNotBlob     = errors.New("storage: not the path of a deduplicated file")
+	ErrUnnamedDisk = errors.New("storage: deduplication needs a disk resolved by Get")
+)
+
+// Blob counts the references to a deduplicated file, in the storage_blobs
+// table.
+type Blob struct {
+	ID        uint64    `gorm:"primaryKey" json:"id"`
+	Disk      string    `json:"disk"`
+	Hash      string    `json:"hash"`
+	Size      int64     `json:"size"`
+	Refs      int64     `json:"refs"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Blob) TableName() string {
+	return "storage_blobs"
+}
+
+// BlobPath is the path of the file with the given hash.
+func BlobPath(hash string) string {
+	return path.Join(BlobDir, hash[:2], hash[2:4], hash)
+}
+
+// PutDedup stores contents unless the disk already has them, adds a
+// reference to them and returns their path.
+func (d *Disk) PutDedup(contents []byte) (string, error) {
+	if d.name == "" {
+		return "", ErrUnnamedDisk
+	}
+	sum := sha256.Sum256(contents)
+	hash := hex.EncodeToString(sum[:])
+	p := BlobPath(hash)
+
+	// The row stays locked until the file is written, see CollectGarbage
+	err := repo.DB(d.ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "disk"}, {Name: "hash"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"refs":       gorm.Expr("refs + 1"),
+				"updated_at": now,
+			}),
+		}).Create(&Blob{Disk: d.blobKey(), Hash: hash, Size: int64(len(contents)), Refs: 1, CreatedAt: now, UpdatedAt: now}).Error
+		if err != nil {
+			return err
+		}
+
+		exists, err := d.Exists(p)
+		if err != nil || exists {
+			return err
+		}
+		return d.Write(p, contents)
+	})
+	if err != nil {
+		return "", err
+	}
+	return p, nil
+}
+
+// Release removes a reference to the deduplicated file at p.
+func (d *Disk) Release(p string) error {
+	hash, ok := blobHash(p)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotBlob, p)
+	}
+	return repo.DB(d.ctx).Model(&Blob{}).
+		Where("disk = ? AND hash = ? AND refs > 0", d.blobKey(), hash).
+		Updates(map[string]any{"refs": gorm.Expr("refs - 1"), "updated_at": time.Now()}).Error
+}
+
+// blobKey tells the blobs of scoped disks apart from those of their disk.
+func (d *Disk) blobKey() string {
+	if p := d.Prefix(); p != "" {
+		return d.name + "/" + p
+	}
+	return d.name
+}
+
+func blobHash(p string) (string, bool) {
+	hash := path.Base(p)
+	if len(hash) != sha256.Size*2 || path.Clean(p) != BlobPath(hash) {
+		return "", false
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", false
+	}
+	return hash, true
+}
+
+// CollectGarbage deletes the blobs unreferenced for longer than grace and
+// returns how many it collected.
+func CollectGarbage(ctx context.Context, grace time.Duration) (int, error) {
+	blobs, err := repo.New[Blob](ctx).Where("refs <= 0 AND updated_at < ?", time.Now().Add(-grace)).Find()
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, b := range blobs {
+		disk, err := blobDisk(ctx, b.Disk)
+		if err != nil {
+			return n, err
+		}
+		// A PutDedup of the same contents waits for the file to be gone,
+		// and writes it again
+		collected := false
+		err = repo.DB(ctx).Transaction(func(tx *gorm.DB) error {
+			res := tx.Where("id = ? AND refs <= 0", b.ID).Delete(&Blob{})
+			if res.Error != nil || res.RowsAffected == 0 {
+				return res.Error
+			}
+			collected = true
+			p := BlobPath(b.Hash)
+			exists, err := disk.Exists(p)
+			if err != nil || !exists {
+				return err
+			}
+			return disk.Delete(p)
+		})
+		if err != nil {
+			return n, fmt.Errorf("storage: collect blob %s of %s: %w", b.Hash, b.Disk, err)
+		}
+		if collected {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// blobDisk resolves the disk a blob key names.
+func blobDisk(ctx context.Context, key string) (*Disk, error) {
+	name, prefix, _ := strings.Cut(key, "/")
+	return diskAt(ctx, name, prefix)
+}
+
+// GCJob collects unreferenced blobs on a worker.
+type GCJob struct {
+	Grace time.Duration `json:"grace"`
+}
+
+func (j *GCJob) Handle(ctx context.Context) error {
+	_, err := CollectGarbage(ctx, j.Grace)
+	return err
+}
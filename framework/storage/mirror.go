@@ -0,0 +1,266 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"os"
+	"path"
+
+	"github.com/lemmego/api/config"
+
+	"github.com/lemmego/lemmego/framework/queue"
+)
+
+// A mirrored disk composes other disks, e.g. to move files from the local
+// disk to S3 without downtime:
+//
+//	"mirrored": config.M{
+//		"driver": "mirror",
+//		"disks":  []string{"s3", "local"},
+//		"async":  true,
+//	},
+//
+// Writes go to the first disk and are replicated to the others, by a queued
+// ReplicateJob when async is set. Reads fall back to the next disk when one
+// fails, so files not copied yet are still served from the disk they were
+// written to. Once every file has been replicated, the mirror can be swapped
+// for the new disk.
+
+func init() {
+	queue.Register[ReplicateJob]("storage.replicate")
+}
+
+// DriverMirror is the driver of mirrored disks.
+const DriverMirror = "mirror"
+
+// Mirror writes to every disk it composes and reads from the first one
+// that succeeds.
+type Mirror struct {
+	name  string
+	disks []*Disk
+	async bool
+}
+
+// NewMirror composes disks, the first one being the primary. Name is the
+// configured disk the mirror is resolved from again by ReplicateJob.
+func NewMirror(name string, async bool, disks ...*Disk) *Mirror {
+	return &Mirror{name: name, disks: disks, async: async}
+}
+
+// mirror resolves the disk named name when it is configured as a mirror.
+func mirror(ctx context.Context, name string) (*Mirror, bool, error) {
+	conf, ok := config.Get("filesystems.disks."+name, nil).(config.M)
+	if !ok || conf["driver"] != DriverMirror {
+		return nil, false, nil
+	}
+	names, _ := conf["disks"].([]string)
+	if len(names) == 0 {
+		return nil, true, fmt.Errorf("storage: mirror %s has no disks", name)
+	}
+	async, _ := conf["async"].(bool)
+
+	disks := make([]*Disk, len(names))
+	for i, n := range names {
+		if n == name {
+			return nil, true, fmt.Errorf("storage: mirror %s includes itself", name)
+		}
+		d, err := Get(ctx, n)
+		if err != nil {
+			return nil, true, err
+		}
+		disks[i] = d
+	}
+	return NewMirror(name, async, disks...), true, nil
+}
+
+func (m *Mirror) Driver() string {
+	return DriverMirror
+}
+
+// Disks returns the composed disks, the primary first.
+func (m *Mirror) Disks() []*Disk {
+	return m.disks
+}
+
+func (m *Mirror) primary() *Disk {
+	return m.disks[0]
+}
+
+// Read reads path from the first disk having it.
+func (m *Mirror) Read(path string) (io.ReadCloser, error) {
+	return fallback(m, func(d *Disk) (io.ReadCloser, error) { return d.Read(path) })
+}
+
+func (m *Mirror) Open(path string) (*os.File, error) {
+	return fallback(m, func(d *Disk) (*os.File, error) { return d.Open(path) })
+}
+
+// Exists reports whether any disk has path.
+func (m *Mirror) Exists(path string) (bool, error) {
+	var errs []error
+	for _, d := range m.disks {
+		ok, err := d.Exists(path)
+		if ok {
+			return true, nil
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == len(m.disks) {
+		return false, errors.Join(errs...)
+	}
+	return false, nil
+}
+
+func (m *Mirror) GetUrl(path string) (string, error) {
+	return m.primary().GetUrl(path)
+}
+
+func (m *Mirror) Write(path string, contents []byte) error {
+	if err := m.primary().Write(path, contents); err != nil {
+		return err
+	}
+	return m.replicate(path)
+}
+
+func (m *Mirror) Delete(path string) error {
+	if err := m.primary().Delete(path); err != nil {
+		return err
+	}
+	return m.replicate(path)
+}
+
+func (m *Mirror) Rename(oldPath, newPath string) error {
+	if err := m.primary().Rename(oldPath, newPath); err != nil {
+		return err
+	}
+	return m.replicate(oldPath, newPath)
+}
+
+func (m *Mirror) Copy(sourcePath, destinationPath string) error {
+	if err := m.primary().Copy(sourcePath, destinationPath); err != nil {
+		return err
+	}
+	return m.replicate(destinationPath)
+}
+
+// CreateDirectory creates path on every disk right away, as there is no file
+// to replicate.
+func (m *Mirror) CreateDirectory(path string) error {
+	var errs []error
+	for _, d := range m.disks {
+		errs = append(errs, d.CreateDirectory(path))
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Mirror) Upload(file multipart.File, header *multipart.FileHeader, dir string) (*os.File, error) {
+	f, err := m.primary().upload(file, header, dir)
+	if err != nil {
+		return nil, err
+	}
+	return f, m.replicate(path.Join(dir, header.Filename))
+}
+
+// replicate brings paths on the other disks in line with the primary, now or
+// on a worker. Failed synchronous copies are queued to be retried.
+func (m *Mirror) replicate(paths ...string) error {
+	ctx := m.primary().Context()
+	for _, p := range paths {
+		if !m.async {
+			err := Replicate(ctx, m, p)
+			if err == nil {
+				continue
+			}
+			slog.Warn(fmt.Sprintf("storage: mirror %s: replicate %s, queued: %s", m.name, p, err))
+		}
+		if _, err := queue.Dispatch(ctx, &ReplicateJob{Disk: m.name, Path: p}); err != nil {
+			return fmt.Errorf("storage: mirror %s: queue replication of %s: %w", m.name, p, err)
+		}
+	}
+	return nil
+}
+
+// Replicate copies path from the primary disk of m to the others, or deletes
+// it from them when the primary no longer has it.
+func Replicate(ctx context.Context, m *Mirror, path string) error {
+	primary := WithContext(ctx, m.primary())
+	ok, err := primary.Exists(path)
+	if err != nil {
+		return err
+	}
+
+	var contents []byte
+	if ok {
+		rc, err := primary.Read(path)
+		if err != nil {
+			return err
+		}
+		contents, err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	var errs []error
+	for _, d := range m.disks[1:] {
+		d = WithContext(ctx, d)
+		if ok {
+			errs = append(errs, d.Write(path, contents))
+			continue
+		}
+		exists, err := d.Exists(path)
+		if err != nil {
+			errs = append(errs, err)
+		} else if exists {
+			errs = append(errs, d.Delete(path))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fallback returns the result of the first disk op succeeds on, or the
+// error of the primary.
+func fallback[T any](m *Mirror, op func(d *Disk) (T, error)) (T, error) {
+	var first error
+	for i, d := range m.disks {
+		v, err := op(d)
+		if err == nil {
+			if i > 0 {
+				slog.Debug(fmt.Sprintf("storage: mirror %s: served from its disk %d", m.name, i))
+			}
+			return v, nil
+		}
+		if first == nil {
+			first = err
+		}
+		if d.Context().Err() != nil {
+			break
+		}
+	}
+	var zero T
+	return zero, first
+}
+
+// ReplicateJob replicates a file of a mirrored disk on a worker.
+type ReplicateJob struct {
+	Disk string `json:"disk"`
+	Path string `json:"path"`
+}
+
+func (j *ReplicateJob) Handle(ctx context.Context) error {
+	m, ok, err := mirror(ctx, j.Disk)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("storage: %s is not a mirrored disk", j.Disk)
+	}
+	return Replicate(ctx, m, j.Path)
+}
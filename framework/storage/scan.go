@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/lemmego/api/config"
+
+	"github.com/lemmego/lemmego/framework/events"
+	"github.com/lemmego/lemmego/framework/queue"
+)
+
+// Uploads are scanned for malware when filesystems.scanner names a driver,
+// "clamav" being built in, or SetScanner installs one. Disk.Upload scans the
+// file before storing it, refusing it with ErrInfected, or, with
+// filesystems.scan_queued, stores it right away and queues a ScanJob. Either
+// way infected files end up under QuarantineDir and FileInfected is
+// dispatched:
+//
+//	events.Listen(storage.FileInfected, func(ctx context.Context, e events.Event) error {
+//		slog.Warn("infected upload", "path", e.(*storage.InfectedEvent).Quarantine)
+//		return nil
+//	})
+
+func init() {
+	queue.Register[ScanJob]("storage.scan")
+}
+
+// FileInfected is dispatched with an InfectedEvent when a scan finds malware.
+const FileInfected = "storage.file_infected"
+
+// QuarantineDir is where infected files are moved to on their disk.
+const QuarantineDir = "quarantine"
+
+var ErrInfected = errors.New("storage: the file is infected")
+
+// Scanner checks the contents of a file for malware.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (ScanResult, error)
+}
+
+// ScanResult is the verdict of a Scanner.
+type ScanResult struct {
+	Infected bool
+	// Signature names what was found.
+	Signature string
+}
+
+// InfectedEvent carries an infected file, moved to quarantine.
+type InfectedEvent struct {
+	Disk       string
+	Path       string
+	Quarantine string
+	Signature  string
+}
+
+func (e *InfectedEvent) Name() string {
+	return FileInfected
+}
+
+var (
+	scannerMu sync.RWMutex
+	custom    Scanner
+)
+
+// SetScanner installs s for every upload, instead of filesystems.scanner.
+// A nil s goes back to the configured one.
+func SetScanner(s Scanner) {
+	scannerMu.Lock()
+	defer scannerMu.Unlock()
+	custom = s
+}
+
+// scanner returns the scanner of uploads, nil when they are not scanned.
+func scanner() Scanner {
+	scannerMu.RLock()
+	s := custom
+	scannerMu.RUnlock()
+	if s != nil {
+		return s
+	}
+	switch config.Get("filesystems.scanner", "") {
+	case "clamav":
+		address, _ := config.Get("filesystems.clamav", "").(string)
+		return NewClamAV(address)
+	}
+	return nil
+}
+
+// scanUpload scans an upload before it is stored.
+func (d *Disk) scanUpload(s Scanner, file multipart.File, header *multipart.FileHeader, dir string) error {
+	result, err := s.Scan(d.ctx, file)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if !result.Infected {
+		return nil
+	}
+
+	quarantined := *header
+	quarantined.Filename = time.Now().Format("20060102150405") + "-" + header.Filename
+	if _, err := d.upload(file, &quarantined, QuarantineDir); err != nil {
+		return err
+	}
+	e := &InfectedEvent{
+		Disk:       d.name,
+		Path:       path.Join(dir, header.Filename),
+		Quarantine: path.Join(QuarantineDir, quarantined.Filename),
+		Signature:  result.Signature,
+	}
+	if err := events.Dispatch(d.ctx, e); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s", ErrInfected, result.Signature)
+}
+
+// Scan scans the file at p, moving it to quarantine when it is infected.
+func (d *Disk) Scan(s Scanner, p string) (ScanResult, error) {
+	rc, err := d.Read(p)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	result, err := s.Scan(d.ctx, rc)
+	rc.Close()
+	if err != nil || !result.Infected {
+		return result, err
+	}
+
+	quarantine := path.Join(QuarantineDir, p)
+	if err := d.Rename(p, quarantine); err != nil {
+		return result, err
+	}
+	slog.Warn(fmt.Sprintf("storage: quarantined %s of %s: %s", p, d.name, result.Signature))
+	return result, events.Dispatch(d.ctx, &InfectedEvent{Disk: d.name, Path: p, Quarantine: quarantine, Signature: result.Signature})
+}
+
+// ScanJob scans an uploaded file on a worker.
+type ScanJob struct {
+	Disk   string `json:"disk"`
+	Prefix string `json:"prefix"`
+	Path   string `json:"path"`
+}
+
+func (j *ScanJob) Handle(ctx context.Context) error {
+	s := scanner()
+	if s == nil {
+		return nil
+	}
+	disk, err := diskAt(ctx, j.Disk, j.Prefix)
+	if err != nil {
+		return err
+	}
+	_, err = disk.Scan(s, j.Path)
+	return err
+}
+
+// diskAt resolves the named disk, scoped to prefix when not empty.
+func diskAt(ctx context.Context, name, prefix string) (*Disk, error) {
+	disk, err := Get(ctx, name)
+	if err != nil || prefix == "" {
+		return disk, err
+	}
+	return disk.Scoped(prefix), nil
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"path"
+	"strings"
+)
+
+var ErrOutsideScope = errors.New("storage: path is outside of the disk's scope")
+
+// Scoped returns a disk confined to prefix, e.g. to hand tenant code a disk
+// that cannot reach the files of other tenants:
+//
+//	disk, err := storage.Get(ctx)
+//	...
+//	tenant := disk.Scoped("tenants/42")
+//	tenant.Write("avatar.png", contents) // tenants/42/avatar.png
+//
+// Paths are resolved below prefix; those climbing out of it with ".." fail
+// with ErrOutsideScope. Scoping a scoped disk nests the prefixes.
+func (d *Disk) Scoped(prefix string) *Disk {
+	p := path.Clean("/" + prefix)[1:]
+	return &Disk{FS: &scope{disk: d, prefix: p}, ctx: d.ctx, name: d.name}
+}
+
+// Prefix is the prefix a scoped disk is confined to, empty for other disks.
+func (d *Disk) Prefix() string {
+	if s, ok := d.FS.(*scope); ok {
+		if parent := s.disk.Prefix(); parent != "" {
+			return parent + "/" + s.prefix
+		}
+		return s.prefix
+	}
+	return ""
+}
+
+type scope struct {
+	disk   *Disk
+	prefix string
+}
+
+// path resolves p below the prefix.
+func (s *scope) path(p string) (string, error) {
+	clean := path.Clean(p)
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("%w: %s", ErrOutsideScope, p)
+	}
+	return path.Join(s.prefix, clean), nil
+}
+
+func (s *scope) Driver() string {
+	return s.disk.Driver()
+}
+
+func (s *scope) Read(p string) (io.ReadCloser, error) {
+	full, err := s.path(p)
+	if err != nil {
+		return nil, err
+	}
+	return s.disk.Read(full)
+}
+
+func (s *scope) Write(p string, contents []byte) error {
+	full, err := s.path(p)
+	if err != nil {
+		return err
+	}
+	return s.disk.Write(full, contents)
+}
+
+func (s *scope) Delete(p string) error {
+	full, err := s.path(p)
+	if err != nil {
+		return err
+	}
+	return s.disk.Delete(full)
+}
+
+func (s *scope) Exists(p string) (bool, error) {
+	full, err := s.path(p)
+	if err != nil {
+		return false, err
+	}
+	return s.disk.Exists(full)
+}
+
+func (s *scope) Rename(oldPath, newPath string) error {
+	from, err := s.path(oldPath)
+	if err != nil {
+		return err
+	}
+	to, err := s.path(newPath)
+	if err != nil {
+		return err
+	}
+	return s.disk.Rename(from, to)
+}
+
+func (s *scope) Copy(sourcePath, destinationPath string) error {
+	from, err := s.path(sourcePath)
+	if err != nil {
+		return err
+	}
+	to, err := s.path(destinationPath)
+	if err != nil {
+		return err
+	}
+	return s.disk.Copy(from, to)
+}
+
+func (s *scope) CreateDirectory(p string) error {
+	full, err := s.path(p)
+	if err != nil {
+		return err
+	}
+	return s.disk.CreateDirectory(full)
+}
+
+func (s *scope) GetUrl(p string) (string, error) {
+	full, err := s.path(p)
+	if err != nil {
+		return "", err
+	}
+	return s.disk.GetUrl(full)
+}
+
+func (s *scope) Open(p string) (*os.File, error) {
+	full, err := s.path(p)
+	if err != nil {
+		return nil, err
+	}
+	return s.disk.Open(full)
+}
+
+// Upload stores the file in dir below the prefix. The multipart reader
+// already strips directories from the file name.
+func (s *scope) Upload(file multipart.File, header *multipart.FileHeader, dir string) (*os.File, error) {
+	full, err := s.path(dir)
+	if err != nil {
+		return nil, err
+	}
+	return s.disk.upload(file, header, full)
+}
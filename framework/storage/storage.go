@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/config"
+	"github.com/lemmego/api/fs"
+	"github.com/lemmego/fsys"
+
+	"github.com/lemmego/lemmego/framework/queue"
+)
+
+// Disk binds a storage driver to a context. The fsys drivers do not accept a
+// context themselves, so every operation checks for cancellation before it
+// starts and reads stop as soon as the context is done.
+type Disk struct {
+	fsys.FS
+	ctx  context.Context
+	name string
+}
+
+// Get resolves the named disk (the default one when omitted) bound to ctx.
+// Disks with the mirror driver are composed here, see Mirror.
+func Get(ctx context.Context, diskName ...string) (*Disk, error) {
+	name := os.Getenv("FILESYSTEM_DISK")
+	if len(diskName) > 0 {
+		name = diskName[0]
+	}
+	if m, ok, err := mirror(ctx, name); ok {
+		if err != nil {
+			return nil, err
+		}
+		return &Disk{FS: m, ctx: ctx, name: name}, nil
+	}
+
+	var fm *fs.FilesystemManager
+	if err := app.Get().Service(&fm); err != nil {
+		return nil, err
+	}
+
+	disk, err := fm.Get(diskName...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Disk{FS: disk, ctx: ctx, name: name}, nil
+}
+
+// WithContext binds any storage driver to ctx.
+func WithContext(ctx context.Context, disk fsys.FS) *Disk {
+	var name string
+	if d, ok := disk.(*Disk); ok {
+		disk, name = d.FS, d.name
+	}
+	if s, ok := disk.(*scope); ok {
+		return WithContext(ctx, s.disk).Scoped(s.prefix)
+	}
+	return &Disk{FS: disk, ctx: ctx, name: name}
+}
+
+func (d *Disk) Context() context.Context {
+	return d.ctx
+}
+
+// Name is the configured name of the disk, empty for drivers bound with
+// WithContext.
+func (d *Disk) Name() string {
+	return d.name
+}
+
+func (d *Disk) Read(path string) (io.ReadCloser, error) {
+	if err := d.ctx.Err(); err != nil {
+		return nil, err
+	}
+	rc, err := d.FS.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	return &contextReader{ReadCloser: rc, ctx: d.ctx}, nil
+}
+
+// Write stores contents at path. On the local driver missing parent
+// directories are created first, as object stores do implicitly.
+func (d *Disk) Write(path string, contents []byte) error {
+	if err := d.ctx.Err(); err != nil {
+		return err
+	}
+	if err := d.createParent(path); err != nil {
+		return err
+	}
+	return d.FS.Write(path, contents)
+}
+
+// createParent creates the missing parent directories of path on the local
+// driver, as object stores do implicitly.
+func (d *Disk) createParent(path string) error {
+	if dir := filepath.Dir(path); d.FS.Driver() == fsys.DRIVER_LOCAL && dir != "." {
+		return d.FS.CreateDirectory(dir)
+	}
+	return nil
+}
+
+func (d *Disk) Delete(path string) error {
+	if err := d.ctx.Err(); err != nil {
+		return err
+	}
+	return d.FS.Delete(path)
+}
+
+func (d *Disk) Exists(path string) (bool, error) {
+	if err := d.ctx.Err(); err != nil {
+		return false, err
+	}
+	return d.FS.Exists(path)
+}
+
+func (d *Disk) Rename(oldPath, newPath string) error {
+	if err := d.ctx.Err(); err != nil {
+		return err
+	}
+	if err := d.createParent(newPath); err != nil {
+		return err
+	}
+	return d.FS.Rename(oldPath, newPath)
+}
+
+func (d *Disk) Copy(sourcePath, destinationPath string) error {
+	if err := d.ctx.Err(); err != nil {
+		return err
+	}
+	if err := d.createParent(destinationPath); err != nil {
+		return err
+	}
+	return d.FS.Copy(sourcePath, destinationPath)
+}
+
+func (d *Disk) CreateDirectory(path string) error {
+	if err := d.ctx.Err(); err != nil {
+		return err
+	}
+	return d.FS.CreateDirectory(path)
+}
+
+func (d *Disk) Open(path string) (*os.File, error) {
+	if err := d.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return d.FS.Open(path)
+}
+
+// Upload stores an uploaded file in dir, scanning it first when uploads are
+// scanned, see Scanner.
+func (d *Disk) Upload(file multipart.File, header *multipart.FileHeader, dir string) (*os.File, error) {
+	s := scanner()
+	queued, _ := config.Get("filesystems.scan_queued", false).(bool)
+	if s != nil && !queued {
+		if err := d.scanUpload(s, file, header, dir); err != nil {
+			return nil, err
+		}
+	}
+	f, err := d.upload(file, header, dir)
+	if err != nil || s == nil || !queued {
+		return f, err
+	}
+	job := &ScanJob{Disk: d.name, Prefix: d.Prefix(), Path: path.Join(dir, header.Filename)}
+	if _, err := queue.Dispatch(d.ctx, job); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+// upload stores a file without scanning it, for disks wrapping others.
+func (d *Disk) upload(file multipart.File, header *multipart.FileHeader, dir string) (*os.File, error) {
+	if err := d.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return d.FS.Upload(file, header, dir)
+}
+
+// contextReader fails reads once its context is cancelled.
+type contextReader struct {
+	io.ReadCloser
+	ctx context.Context
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.ReadCloser.Read(p)
+}
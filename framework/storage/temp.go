@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/lemmego/lemmego/framework/queue"
+)
+
+// Temp gives code needing files on the local filesystem, such as imports
+// reading spreadsheets or exports building archives, a scratch directory
+// removed once the request or job it belongs to is done:
+//
+//	scratch, err := storage.Temp(ctx)
+//	...
+//	f, err := scratch.Create("export-*.zip")
+//	...
+//	err = scratch.MoveToDisk(f.Name(), disk, "exports/report.zip")
+//
+// Jobs get their scope from the queue, requests from middleware.Scratch.
+// Outside of both, e.g. in commands, call Cleanup when done.
+
+func init() {
+	queue.Wrap(func(ctx context.Context, handle func(ctx context.Context) error) error {
+		ctx, done := WithScratch(ctx)
+		defer done()
+		return handle(ctx)
+	})
+}
+
+// Scratch is a temporary directory.
+type Scratch struct {
+	dir string
+}
+
+type scratchKey struct{}
+
+// scratches are the directories created within a scope.
+type scratches struct {
+	mu   sync.Mutex
+	dirs []*Scratch
+}
+
+// WithScratch starts a scope for the scratch directories of ctx. Done
+// removes those created within it.
+func WithScratch(ctx context.Context) (context.Context, func()) {
+	sc := &scratches{}
+	return context.WithValue(ctx, scratchKey{}, sc), func() {
+		sc.mu.Lock()
+		defer sc.mu.Unlock()
+		for _, s := range sc.dirs {
+			_ = s.Cleanup()
+		}
+		sc.dirs = nil
+	}
+}
+
+// Temp creates a scratch directory, removed when the scope of ctx ends.
+func Temp(ctx context.Context) (*Scratch, error) {
+	dir, err := os.MkdirTemp("", "scratch-*")
+	if err != nil {
+		return nil, err
+	}
+	s := &Scratch{dir: dir}
+	if sc, ok := ctx.Value(scratchKey{}).(*scratches); ok {
+		sc.mu.Lock()
+		sc.dirs = append(sc.dirs, s)
+		sc.mu.Unlock()
+	}
+	return s, nil
+}
+
+// Dir is the path of the directory.
+func (s *Scratch) Dir() string {
+	return s.dir
+}
+
+// Path returns the path of name within the directory.
+func (s *Scratch) Path(name string) string {
+	return filepath.Join(s.dir, filepath.Clean("/"+name))
+}
+
+// Create creates a new file in the directory, named after pattern as for
+// os.CreateTemp.
+func (s *Scratch) Create(pattern string) (*os.File, error) {
+	return os.CreateTemp(s.dir, pattern)
+}
+
+// MoveToDisk stores the file at src, a name within the directory or a path
+// it returned, at dst on disk and removes it.
+func (s *Scratch) MoveToDisk(src string, disk *Disk, dst string) error {
+	if !filepath.IsAbs(src) {
+		src = s.Path(src)
+	}
+	if !strings.HasPrefix(src, s.dir+string(filepath.Separator)) {
+		return errors.New("storage: not a file of the scratch directory")
+	}
+	contents, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := disk.Write(dst, contents); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// Cleanup removes the directory and everything in it.
+func (s *Scratch) Cleanup() error {
+	return os.RemoveAll(s.dir)
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/lemmego/api/config"
+	"github.com/lemmego/fsys"
+)
+
+// A disk with a url setting gets URLs below it from GetUrl instead of those
+// of its driver, e.g. a CDN in front of a bucket. A url that is only a path
+// is relative to app.url and, on local disks, served by the app itself:
+//
+//	"public": config.M{
+//		"driver": "local",
+//		"path":   "./storage/public",
+//		"url":    "/storage",
+//	},
+//
+// makes GetUrl("avatars/a.png") return https://example.com/storage/avatars/a.png.
+
+// GetUrl returns the URL of the file at p.
+func (d *Disk) GetUrl(p string) (string, error) {
+	if err := d.ctx.Err(); err != nil {
+		return "", err
+	}
+	// Scoped disks pass the full path to the disk they wrap
+	if _, scoped := d.FS.(*scope); !scoped {
+		if base := baseURL(d.name); base != "" {
+			return base + "/" + escapePath(strings.TrimPrefix(p, "/")), nil
+		}
+	}
+	return d.FS.GetUrl(p)
+}
+
+// baseURL is the url setting of the named disk, made absolute.
+func baseURL(name string) string {
+	base, _ := config.Get("filesystems.disks."+name+".url", "").(string)
+	if strings.HasPrefix(base, "/") {
+		appURL, _ := config.Get("app.url", "").(string)
+		base = strings.TrimSuffix(appURL, "/") + base
+	}
+	return strings.TrimSuffix(base, "/")
+}
+
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// Served returns the directories of the local disks whose url is a path,
+// by that path, for the routes to serve them.
+func Served() map[string]string {
+	disks, _ := config.Get("filesystems.disks", nil).(config.M)
+	served := map[string]string{}
+	for _, c := range disks {
+		conf, ok := c.(config.M)
+		if !ok || conf["driver"] != fsys.DRIVER_LOCAL {
+			continue
+		}
+		base, _ := conf["url"].(string)
+		dir, _ := conf["path"].(string)
+		if strings.HasPrefix(base, "/") && dir != "" {
+			served[strings.TrimSuffix(base, "/")] = dir
+		}
+	}
+	return served
+}
@@ -0,0 +1,81 @@
+package str
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Words splits s into words at spaces, punctuation, and changes of case:
+// "HTTPServerError", "http_server_error" and "http-server error" are all
+// http, server and error, as written.
+func Words(s string) []string {
+	var words []string
+	var cur []rune
+	runes := []rune(s)
+	flush := func() {
+		if len(cur) > 0 {
+			words = append(words, string(cur))
+			cur = nil
+		}
+	}
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if len(cur) > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && nextLower {
+				flush()
+			}
+		}
+		cur = append(cur, r)
+	}
+	flush()
+	return words
+}
+
+func join(s, sep string, word func(i int, w string) string) string {
+	words := Words(s)
+	for i, w := range words {
+		words[i] = word(i, w)
+	}
+	return strings.Join(words, sep)
+}
+
+func upperFirst(w string) string {
+	r := []rune(strings.ToLower(w))
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// Snake converts s to snake_case.
+func Snake(s string) string {
+	return join(s, "_", func(_ int, w string) string { return strings.ToLower(w) })
+}
+
+// Kebab converts s to kebab-case.
+func Kebab(s string) string {
+	return join(s, "-", func(_ int, w string) string { return strings.ToLower(w) })
+}
+
+// Camel converts s to camelCase.
+func Camel(s string) string {
+	return join(s, "", func(i int, w string) string {
+		if i == 0 {
+			return strings.ToLower(w)
+		}
+		return upperFirst(w)
+	})
+}
+
+// Pascal converts s to PascalCase, the case of exported Go names.
+func Pascal(s string) string {
+	return join(s, "", func(_ int, w string) string { return upperFirst(w) })
+}
+
+// Title converts s to space separated Title Case.
+func Title(s string) string {
+	return join(s, " ", func(_ int, w string) string { return upperFirst(w) })
+}
@@ -0,0 +1,156 @@
+// Package str holds the string helpers routes, models and commands share:
+// slugs, random tokens, masking, truncation and case conversion.
+//
+//	str.Slug("Crème Brûlée Café")  // creme-brulee-cafe
+//	str.Mask("4111111111111111", '*', 0, -4) // ************1111
+//	str.Snake("HTTPServerError")   // http_server_error
+package str
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+// folds are the letters decomposition does not reduce to ASCII.
+var folds = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'ł': "l", 'þ': "th", 'ı': "i",
+}
+
+// Slug lowercases s, strips the accents off Latin letters and joins the
+// words with dashes. Letters and digits of other scripts are kept as they
+// are, so "Привет мир" is "привет-мир".
+func Slug(s string) string {
+	return slug(s, false)
+}
+
+// ASCIISlug is Slug keeping only ASCII letters and digits, for slugs used
+// as host names, such as organization subdomains.
+func ASCIISlug(s string) string {
+	return slug(s, true)
+}
+
+func slug(s string, ascii bool) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range norm.NFKD.String(strings.ToLower(s)) {
+		f, folded := folds[r]
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case folded || (unicode.IsLetter(r) || unicode.IsDigit(r)) && (!ascii || r < unicode.MaxASCII):
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			if folded {
+				b.WriteString(f)
+			} else {
+				b.WriteRune(r)
+			}
+		default:
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// UniqueSlug suffixes slug with -2, -3 and so on until no row of table has
+// it in column. Run it in the transaction inserting the row, behind a
+// unique index, as two requests may pick the same slug.
+//
+//	slug, err := str.UniqueSlug(tx, "posts", "slug", str.Slug(title))
+func UniqueSlug(db *gorm.DB, table, column, slug string) (string, error) {
+	candidate := slug
+	for i := 2; ; i++ {
+		var taken int64
+		if err := db.Table(table).Where(column+" = ?", candidate).Count(&taken).Error; err != nil {
+			return "", err
+		}
+		if taken == 0 {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%d", slug, i)
+	}
+}
+
+const alphanumeric = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// RandomString returns n letters and digits from crypto/rand, for tokens
+// and codes.
+func RandomString(n int) string {
+	out := make([]byte, n)
+	buf := make([]byte, n+n/2)
+	for i := 0; i < n; {
+		if _, err := rand.Read(buf); err != nil {
+			panic(err)
+		}
+		for _, c := range buf {
+			// 248 is the largest multiple of 62 under 256, which keeps the
+			// characters equally likely
+			if c >= 248 || i == n {
+				continue
+			}
+			out[i] = alphanumeric[c%62]
+			i++
+		}
+	}
+	return string(out)
+}
+
+// Mask replaces the characters of s from start, counted from the end when
+// negative, with mask. A positive length masks that many characters, a
+// negative one all but that many at the end, and zero all the rest.
+//
+//	str.Mask("jane@example.com", '*', 1, 3) // j***@example.com
+func Mask(s string, mask rune, start, length int) string {
+	runes := []rune(s)
+	n := len(runes)
+	if start < 0 {
+		start = max(n+start, 0)
+	}
+	if start >= n {
+		return s
+	}
+	end := n
+	switch {
+	case length > 0:
+		end = min(start+length, n)
+	case length < 0:
+		end = max(n+length, start)
+	}
+	for i := start; i < end; i++ {
+		runes[i] = mask
+	}
+	return string(runes)
+}
+
+// Truncate shortens s to at most limit characters, suffix included, cutting
+// at the last word boundary that fits. Words longer than limit are cut
+// where they must be.
+//
+//	str.Truncate("The quick brown fox", 14, "…") // The quick…
+func Truncate(s string, limit int, suffix string) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	room := limit - len([]rune(suffix))
+	if room <= 0 {
+		return string([]rune(suffix)[:limit])
+	}
+	cut := room
+	for i := room; i > 0; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+	return strings.TrimRightFunc(string(runes[:cut]), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + suffix
+}
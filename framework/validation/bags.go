@@ -0,0 +1,149 @@
+package validation
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/shared"
+)
+
+// HeaderBag is the bag of errors about request headers and cookies, kept
+// apart from the errors of the submitted fields: they are the client's
+// fault rather than the user's, and pages show them as a banner instead of
+// next to an input.
+//
+//	type ListInput struct {
+//		*app.BaseInput
+//		Version string `in:"header=API-Version" validate:"required,in=1|2"`
+//		TraceID string `in:"header=X-Correlation-Id" validate:"uuid"`
+//	}
+//
+// The version error above is reported under "headers:API-Version", and
+// Bag(errs, HeaderBag) returns it under "API-Version".
+const HeaderBag = "headers"
+
+// bagSeparator separates the bag from the field in error keys. Errors stay
+// one flat shared.ValidationErrors, which the framework flashes and renders
+// as is.
+const bagSeparator = ":"
+
+// BagKey is the key errors about field are reported under in bag. The
+// default bag, "", uses the field name alone.
+func BagKey(bag, field string) string {
+	if bag == "" {
+		return field
+	}
+	return bag + bagSeparator + field
+}
+
+// Bag returns the errors of one bag keyed by field name, "" being the bag
+// of errors reported without one.
+func Bag(errs shared.ValidationErrors, bag string) shared.ValidationErrors {
+	out := shared.ValidationErrors{}
+	for key, messages := range errs {
+		b, field, ok := strings.Cut(key, bagSeparator)
+		if !ok {
+			b, field = "", key
+		}
+		if b == bag {
+			out[field] = messages
+		}
+	}
+	return out
+}
+
+// ErrorKey is the key errors about a struct field are reported under:
+// FieldName in HeaderBag for fields bound from a header or a cookie, and
+// FieldName alone otherwise.
+func ErrorKey(sf reflect.StructField) string {
+	return BagKey(bagOf(sf), FieldName(sf))
+}
+
+// bagOf reads the source of the first `in` directive, the one FieldName
+// takes the name from.
+func bagOf(sf reflect.StructField) string {
+	for _, directive := range strings.Split(sf.Tag.Get("in"), ";") {
+		source, keys, found := strings.Cut(strings.TrimSpace(directive), "=")
+		if !found || keys == "" {
+			continue
+		}
+		if source == "header" || source == "cookie" {
+			return HeaderBag
+		}
+		return ""
+	}
+	return ""
+}
+
+// Header starts rules on a request header, reported in HeaderBag, for
+// inputs validated by hand.
+//
+//	validation.Header(i.Validator, i.Ctx.Request(), "API-Version").Required().In([]string{"1", "2"})
+func Header(v *app.Validator, r *http.Request, name string) *app.VField {
+	return v.Field(BagKey(HeaderBag, name), r.Header.Get(name))
+}
+
+// Cookie starts rules on a request cookie, reported in HeaderBag. Missing
+// cookies are empty.
+func Cookie(v *app.Validator, r *http.Request, name string) *app.VField {
+	var value string
+	if c, err := r.Cookie(name); err == nil {
+		value = c.Value
+	}
+	return v.Field(BagKey(HeaderBag, name), value)
+}
+
+// Bagged is implemented by inputs reporting their errors in a named bag, for
+// pages showing several forms, such as sign in and sign up side by side.
+//
+//	func (*LoginInput) ErrorBag() string { return "login" }
+type Bagged interface {
+	ErrorBag() string
+}
+
+// InBag moves validation errors into bag, leaving other errors as they
+// are. Errors already in a bag, such as HeaderBag, stay in it.
+//
+//	if err := c.Validate(in); err != nil {
+//		return validation.InBag("register", err)
+//	}
+func InBag(bag string, err error) error {
+	var errs shared.ValidationErrors
+	if bag == "" || !errors.As(err, &errs) {
+		return err
+	}
+	out := make(shared.ValidationErrors, len(errs))
+	for key, messages := range errs {
+		if !strings.Contains(key, bagSeparator) {
+			key = BagKey(bag, key)
+		}
+		out[key] = messages
+	}
+	return out
+}
+
+// Merge combines the results of several validations in one request, so
+// all their errors are reported together. Errors other than validation
+// errors win.
+func Merge(errs ...error) error {
+	out := shared.ValidationErrors{}
+	for _, err := range errs {
+		var verrs shared.ValidationErrors
+		switch {
+		case err == nil:
+		case errors.As(err, &verrs):
+			for key, messages := range verrs {
+				out[key] = append(out[key], messages...)
+			}
+		default:
+			return err
+		}
+	}
+	if len(out) > 0 {
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package validation
+
+import (
+	"reflect"
+	"strconv"
+
+	"github.com/lemmego/api/app"
+
+	"github.com/lemmego/lemmego/framework/i18n"
+)
+
+// items returns the elements of a slice or array value, and false for
+// anything else. Nil slices have no elements.
+func items(value any) (reflect.Value, bool) {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return rv, false
+	}
+	return rv, true
+}
+
+// MinItems requires a slice to hold at least n elements, as the
+// min_items=n tag does.
+func MinItems(n int) Rule {
+	return func(v *app.Validator, f *app.VField) {
+		if rv, ok := items(f.Value()); ok && rv.Len() < n {
+			v.AddError(f.Name(), message("This field must have at least :count item|items", i18n.Args{"count": n}))
+		}
+	}
+}
+
+// MaxItems allows a slice at most n elements, as the max_items=n tag does.
+func MaxItems(n int) Rule {
+	return func(v *app.Validator, f *app.VField) {
+		if rv, ok := items(f.Value()); ok && rv.Len() > n {
+			v.AddError(f.Name(), message("This field must not have more than :count item|items", i18n.Args{"count": n}))
+		}
+	}
+}
+
+// DistinctBy rejects slices holding two elements with the same key: the
+// field of struct elements whose json or Go name is key, or the entry of
+// map elements. An empty key compares the elements themselves. Tags write
+// it distinct=key, or distinct alone.
+//
+// Duplicates are reported under their path, e.g. "items.2.sku", so each
+// row of a form shows its own error.
+func DistinctBy(key string) Rule {
+	return func(v *app.Validator, f *app.VField) {
+		rv, ok := items(f.Value())
+		if !ok {
+			return
+		}
+		seen := map[any]bool{}
+		for i := range rv.Len() {
+			value, ok := keyOf(rv.Index(i), key)
+			if !ok {
+				continue
+			}
+			if seen[value] {
+				path := f.Name() + "." + strconv.Itoa(i)
+				if key != "" {
+					path += "." + key
+				}
+				v.AddError(path, "This value appears more than once")
+			}
+			seen[value] = true
+		}
+	}
+}
+
+// keyOf returns the comparable value DistinctBy compares an element by.
+func keyOf(elem reflect.Value, key string) (any, bool) {
+	for elem.Kind() == reflect.Pointer || elem.Kind() == reflect.Interface {
+		if elem.IsNil() {
+			return nil, false
+		}
+		elem = elem.Elem()
+	}
+	if key != "" {
+		switch elem.Kind() {
+		case reflect.Struct:
+			field, ok := fieldNamed(elem.Type(), key)
+			if !ok {
+				return nil, false
+			}
+			return keyOf(elem.FieldByIndex(field.Index), "")
+		case reflect.Map:
+			if elem.Type().Key().Kind() != reflect.String {
+				return nil, false
+			}
+			entry := elem.MapIndex(reflect.ValueOf(key).Convert(elem.Type().Key()))
+			if !entry.IsValid() {
+				return nil, false
+			}
+			return keyOf(entry, "")
+		default:
+			return nil, false
+		}
+	}
+	if !elem.Type().Comparable() {
+		return nil, false
+	}
+	return elem.Interface(), true
+}
+
+func fieldNamed(t reflect.Type, name string) (reflect.StructField, bool) {
+	for _, sf := range reflect.VisibleFields(t) {
+		if sf.IsExported() && (FieldName(sf) == name || sf.Name == name) {
+			return sf, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// Each applies rules to every element of a slice, reporting errors under
+// the element's path, e.g. "emails.1". Tags list the element rules after
+// each:
+//
+//	Emails []string `in:"form=emails" validate:"min_items=1,each,required,email"`
+func Each(rules ...Rule) Rule {
+	return func(v *app.Validator, f *app.VField) {
+		rv, ok := items(f.Value())
+		if !ok {
+			return
+		}
+		for i := range rv.Len() {
+			elem := v.Field(f.Name()+"."+strconv.Itoa(i), rv.Index(i).Interface())
+			for _, apply := range rules {
+				apply(v, elem)
+			}
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package validation
+
+import (
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ibanLengths holds the IBAN length of each country using IBANs.
+var ibanLengths = map[string]int{
+	"AD": 24, "AE": 23, "AL": 28, "AT": 20, "AZ": 28, "BA": 20, "BE": 16,
+	"BG": 22, "BH": 22, "BR": 29, "BY": 28, "CH": 21, "CR": 22, "CY": 28,
+	"CZ": 24, "DE": 22, "DK": 18, "DO": 28, "EE": 20, "EG": 29, "ES": 24,
+	"FI": 18, "FO": 18, "FR": 27, "GB": 22, "GE": 22, "GI": 23, "GL": 18,
+	"GR": 27, "GT": 28, "HR": 21, "HU": 28, "IE": 22, "IL": 23, "IQ": 23,
+	"IS": 26, "IT": 27, "JO": 30, "KW": 30, "KZ": 20, "LB": 28, "LC": 32,
+	"LI": 21, "LT": 20, "LU": 20, "LV": 21, "MC": 27, "MD": 24, "ME": 22,
+	"MK": 19, "MR": 27, "MT": 31, "MU": 30, "NL": 18, "NO": 15, "PK": 24,
+	"PL": 28, "PS": 29, "PT": 25, "QA": 29, "RO": 24, "RS": 22, "SA": 24,
+	"SC": 31, "SE": 24, "SI": 19, "SK": 24, "SM": 27, "ST": 25, "SV": 28,
+	"TL": 23, "TN": 24, "TR": 26, "UA": 29, "VA": 22, "VG": 24, "XK": 20,
+}
+
+// compact drops the spaces and dashes people group identifiers with, and
+// upper cases the rest.
+func compact(s string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "", ".", "").Replace(s))
+}
+
+// IBAN reports whether s, grouped or not, is an IBAN of the right length for
+// its country with valid check digits.
+func IBAN(s string) bool {
+	s = compact(s)
+	if len(s) < 5 || ibanLengths[s[:2]] != len(s) {
+		return false
+	}
+	var digits strings.Builder
+	for _, c := range s[4:] + s[:4] {
+		switch {
+		case c >= '0' && c <= '9':
+			digits.WriteRune(c)
+		case c >= 'A' && c <= 'Z':
+			digits.WriteString(strconv.Itoa(int(c-'A') + 10))
+		default:
+			return false
+		}
+	}
+	n, ok := new(big.Int).SetString(digits.String(), 10)
+	return ok && n.Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+var bicPattern = regexp.MustCompile(`^[A-Z]{4}[A-Z]{2}[A-Z0-9]{2}(?:[A-Z0-9]{3})?$`)
+
+// BIC reports whether s is a SWIFT BIC: bank, country and location codes,
+// and an optional branch code.
+func BIC(s string) bool {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	return bicPattern.MatchString(s) && (Country(s[4:6]) || s[4:6] == "XK")
+}
+
+// Card brands.
+const (
+	Visa       = "visa"
+	Mastercard = "mastercard"
+	Amex       = "amex"
+	Discover   = "discover"
+	Diners     = "diners"
+	JCB        = "jcb"
+	UnionPay   = "unionpay"
+	Maestro    = "maestro"
+)
+
+type cardBrand struct {
+	name    string
+	ranges  [][2]int // prefixes, compared on the digits of the bounds
+	lengths []int
+}
+
+// cardBrands are checked in order, so the narrower ranges come first.
+var cardBrands = []cardBrand{
+	{Amex, [][2]int{{34, 34}, {37, 37}}, []int{15}},
+	{Diners, [][2]int{{300, 305}, {36, 36}, {38, 39}}, []int{14, 15, 16, 17, 18, 19}},
+	{JCB, [][2]int{{3528, 3589}}, []int{16, 17, 18, 19}},
+	{Visa, [][2]int{{4, 4}}, []int{13, 16, 19}},
+	{Mastercard, [][2]int{{51, 55}, {2221, 2720}}, []int{16}},
+	{Discover, [][2]int{{6011, 6011}, {644, 649}, {65, 65}}, []int{16, 17, 18, 19}},
+	{UnionPay, [][2]int{{62, 62}}, []int{16, 17, 18, 19}},
+	{Maestro, [][2]int{{50, 50}, {56, 69}}, []int{12, 13, 14, 15, 16, 17, 18, 19}},
+}
+
+// CardBrand returns the brand of a card number, grouped or not, when it
+// passes the Luhn check and has a length its brand issues.
+func CardBrand(s string) (string, bool) {
+	s = compact(s)
+	if len(s) < 12 || len(s) > 19 || !luhn(s) {
+		return "", false
+	}
+	for _, b := range cardBrands {
+		for _, r := range b.ranges {
+			size := len(strconv.Itoa(r[0]))
+			prefix, _ := strconv.Atoi(s[:size])
+			if prefix < r[0] || prefix > r[1] {
+				continue
+			}
+			for _, l := range b.lengths {
+				if l == len(s) {
+					return b.name, true
+				}
+			}
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// luhn runs the Luhn checksum on a string of digits.
+func luhn(s string) bool {
+	sum := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		d := int(s[i] - '0')
+		if d < 0 || d > 9 {
+			return false
+		}
+		if (len(s)-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// vatPatterns are the formats of EU VAT numbers, as VIES lists them, and of
+// the United Kingdom, Switzerland and Norway, after the country prefix.
+var vatPatterns = map[string]*regexp.Regexp{
+	"AT":  regexp.MustCompile(`^U\d{8}$`),
+	"BE":  regexp.MustCompile(`^[01]\d{9}$`),
+	"BG":  regexp.MustCompile(`^\d{9,10}$`),
+	"CY":  regexp.MustCompile(`^\d{8}[A-Z]$`),
+	"CZ":  regexp.MustCompile(`^\d{8,10}$`),
+	"DE":  regexp.MustCompile(`^\d{9}$`),
+	"DK":  regexp.MustCompile(`^\d{8}$`),
+	"EE":  regexp.MustCompile(`^\d{9}$`),
+	"EL":  regexp.MustCompile(`^\d{9}$`),
+	"ES":  regexp.MustCompile(`^[A-Z0-9]\d{7}[A-Z0-9]$`),
+	"FI":  regexp.MustCompile(`^\d{8}$`),
+	"FR":  regexp.MustCompile(`^[A-HJ-NP-Z0-9]{2}\d{9}$`),
+	"HR":  regexp.MustCompile(`^\d{11}$`),
+	"HU":  regexp.MustCompile(`^\d{8}$`),
+	"IE":  regexp.MustCompile(`^\d{7}[A-W][A-I]?$|^\d[A-Z+*]\d{5}[A-W]$`),
+	"IT":  regexp.MustCompile(`^\d{11}$`),
+	"LT":  regexp.MustCompile(`^(?:\d{9}|\d{12})$`),
+	"LU":  regexp.MustCompile(`^\d{8}$`),
+	"LV":  regexp.MustCompile(`^\d{11}$`),
+	"MT":  regexp.MustCompile(`^\d{8}$`),
+	"NL":  regexp.MustCompile(`^\d{9}B\d{2}$`),
+	"PL":  regexp.MustCompile(`^\d{10}$`),
+	"PT":  regexp.MustCompile(`^\d{9}$`),
+	"RO":  regexp.MustCompile(`^\d{2,10}$`),
+	"SE":  regexp.MustCompile(`^\d{10}01$`),
+	"SI":  regexp.MustCompile(`^\d{8}$`),
+	"SK":  regexp.MustCompile(`^\d{10}$`),
+	"XI":  regexp.MustCompile(`^(?:\d{9}|\d{12}|GD[0-4]\d{2}|HA[5-9]\d{2})$`),
+	"GB":  regexp.MustCompile(`^(?:\d{9}|\d{12}|GD[0-4]\d{2}|HA[5-9]\d{2})$`),
+	"CHE": regexp.MustCompile(`^\d{9}(?:MWST|TVA|IVA)?$`),
+	"NO":  regexp.MustCompile(`^\d{9}(?:MVA)?$`),
+}
+
+// VAT reports whether s, grouped or not, is a VAT number in the format of
+// its country prefix, such as DE123456789. The format is all that is
+// checked; whether the number is registered takes a VIES lookup.
+func VAT(s string) bool {
+	s = compact(s)
+	if len(s) < 4 {
+		return false
+	}
+	if p, ok := vatPatterns[s[:3]]; ok && s[:3] == "CHE" {
+		return p.MatchString(s[3:])
+	}
+	p, ok := vatPatterns[s[:2]]
+	return ok && p.MatchString(s[2:])
+}
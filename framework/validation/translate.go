@@ -0,0 +1,159 @@
+package validation
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/shared"
+
+	"github.com/lemmego/lemmego/framework/i18n"
+)
+
+// templates are the messages rules and package jsonschema build with
+// values, the keys their translations are found under, the most specific
+// first. Messages without values are keys as they are.
+var templates = compileTemplates(
+	"This field must be at least :count character|characters",
+	"This field must not be longer than :count character|characters",
+	"This field must have at least :count property|properties",
+	"This field must not have more than :count property|properties",
+	"This field must be of type :type",
+	"This field must be greater than :value",
+	"This field must be less than :value",
+	"This field must be a multiple of :value",
+	"This field is required when :other is present",
+	"This field must be at least :min",
+	"This field must not exceed :max",
+	"This field must be between :min and :max",
+	"This field must be a valid date in the format :format",
+	"This field must be one of the following: :values",
+	"This field must match the pattern: :pattern",
+	"This field must be a date after :date",
+	"This field must be a date before :date",
+	"This field must start with :value",
+	"This field must end with :value",
+	"This field must contain :value",
+	"This field must have at most :count decimal place|places",
+	"This field must have at least :count item|items",
+	"This field must not have more than :count item|items",
+	"This field must be a valid :format",
+	"This field must be :value",
+)
+
+type template struct {
+	key    string
+	re     *regexp.Regexp
+	params []string
+}
+
+var placeholder = regexp.MustCompile(`:[a-z_]+`)
+
+func compileTemplates(keys ...string) []template {
+	ts := make([]template, len(keys))
+	for i, key := range keys {
+		t := template{key: key}
+		var b strings.Builder
+		for _, word := range strings.Split(key, " ") {
+			if b.Len() > 0 {
+				b.WriteString(" ")
+			}
+			if strings.Contains(word, "|") {
+				b.WriteString("(?:" + strings.Join(quoteAll(strings.Split(word, "|")), "|") + ")")
+				continue
+			}
+			last := 0
+			for _, loc := range placeholder.FindAllStringIndex(word, -1) {
+				b.WriteString(regexp.QuoteMeta(word[last:loc[0]]) + "(.+?)")
+				t.params = append(t.params, word[loc[0]+1:loc[1]])
+				last = loc[1]
+			}
+			b.WriteString(regexp.QuoteMeta(word[last:]))
+		}
+		t.re = regexp.MustCompile("^" + b.String() + "$")
+		ts[i] = t
+	}
+	return ts
+}
+
+func quoteAll(ss []string) []string {
+	for i, s := range ss {
+		ss[i] = regexp.QuoteMeta(s)
+	}
+	return ss
+}
+
+// message renders a template in English.
+func message(key string, args i18n.Args) string {
+	return i18n.Format(key, args)
+}
+
+// Translate translates the messages of validation errors into locale with
+// the i18n Default catalog, leaving other errors as they are. Translations
+// may name the field with :attribute: the catalog's attributes.<field>, or
+// the field name.
+func Translate(err error, locale string) error {
+	var errs shared.ValidationErrors
+	if !errors.As(err, &errs) {
+		return err
+	}
+	catalog := i18n.Default()
+	out := make(shared.ValidationErrors, len(errs))
+	for key, messages := range errs {
+		attr := attribute(key)
+		if t, ok := catalog.Lookup(locale, "attributes."+attr); ok {
+			attr = t
+		}
+		translated := make([]string, len(messages))
+		for i, msg := range messages {
+			translated[i] = msg
+			if t, ok := catalog.Lookup(locale, msg); ok {
+				translated[i] = i18n.Format(t, i18n.Args{"attribute": attr})
+				continue
+			}
+			key, args := parse(msg)
+			args["attribute"] = attr
+			if t, ok := catalog.Lookup(locale, key); ok {
+				translated[i] = i18n.Format(t, args)
+			}
+		}
+		out[key] = translated
+	}
+	return out
+}
+
+// parse finds the template of a message and the values it was built with.
+func parse(msg string) (string, i18n.Args) {
+	for _, t := range templates {
+		if m := t.re.FindStringSubmatch(msg); m != nil {
+			args := i18n.Args{}
+			for i, p := range t.params {
+				args[p] = m[i+1]
+			}
+			return t.key, args
+		}
+	}
+	return msg, i18n.Args{}
+}
+
+// attribute is the field an error key is about: "items.2.sku" is about
+// sku, "headers:API-Version" about API-Version.
+func attribute(key string) string {
+	if i := strings.LastIndex(key, bagSeparator); i >= 0 {
+		key = key[i+1:]
+	}
+	parts := strings.Split(key, ".")
+	for i := len(parts) - 1; i > 0; i-- {
+		if strings.Trim(parts[i], "0123456789") != "" {
+			return parts[i]
+		}
+	}
+	return parts[0]
+}
+
+// Localize translates the validation errors handlers return into the
+// request's locale, set by i18n.Middleware.
+func Localize(c *app.Context) error {
+	return Translate(c.Next(), i18n.LocaleFrom(c.RequestContext()))
+}
@@ -0,0 +1,506 @@
+// Package validation validates input structs from `validate` struct tags.
+//
+// The rules of an input type are parsed once, on first use, into a plan of
+// field indices and rule closures. Validating a request afterwards only reads
+// the planned fields and runs the closures, without walking tags again.
+//
+//	type PostInput struct {
+//		*app.BaseInput
+//		Title string `in:"form=title" validate:"required,max=120"`
+//		Email string `in:"form=email" validate:"required,email"`
+//	}
+//
+//	func (i *PostInput) Validate() error {
+//		return validation.Struct(i.Validator, i)
+//	}
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/decimal"
+	"github.com/lemmego/lemmego/framework/i18n"
+	"github.com/lemmego/lemmego/framework/ids"
+	"github.com/lemmego/lemmego/framework/money"
+	"github.com/lemmego/lemmego/framework/phone"
+	"github.com/lemmego/lemmego/framework/sanitize"
+)
+
+// Rule checks a field, adding its errors to v. Rules may replace the value
+// of the field with a normalized one.
+type Rule func(v *app.Validator, f *app.VField)
+
+func method(m func(*app.VField) *app.VField) Rule {
+	return func(_ *app.Validator, f *app.VField) { m(f) }
+}
+
+type fieldPlan struct {
+	index []int
+	name  string
+	rules []Rule
+}
+
+type plan struct {
+	fields []fieldPlan
+	err    error
+}
+
+var plans sync.Map // reflect.Type -> *plan
+
+// Struct runs the rules declared on input and returns v.Validate(). Valid
+// string fields take the value normalizing rules leave, such as the E.164
+// form of phone numbers, so input must be a pointer for them to apply.
+func Struct(v *app.Validator, input any) error {
+	rv := reflect.ValueOf(input)
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("validation: %T is not a struct", input)
+	}
+
+	p := planFor(rv.Type())
+	if p.err != nil {
+		return p.err
+	}
+
+	for _, fp := range p.fields {
+		fv := rv.FieldByIndex(fp.index)
+		field := v.Field(fp.name, fv.Interface())
+		errs := len(v.Errors[fp.name])
+		for _, apply := range fp.rules {
+			apply(v, field)
+		}
+		// Rules such as phone normalize valid values
+		if s, ok := field.Value().(string); ok && fv.Kind() == reflect.String && fv.CanSet() && len(v.Errors[fp.name]) == errs {
+			fv.SetString(s)
+		}
+	}
+	return v.Validate()
+}
+
+// Compile builds the plan of an input type ahead of time, e.g. from an init
+// function, so malformed tags fail at startup rather than on the first request.
+func Compile(input any) error {
+	t := reflect.TypeOf(input)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return planFor(t).err
+}
+
+func planFor(t reflect.Type) *plan {
+	if p, ok := plans.Load(t); ok {
+		return p.(*plan)
+	}
+	p, _ := plans.LoadOrStore(t, compile(t))
+	return p.(*plan)
+}
+
+func compile(t reflect.Type) *plan {
+	p := &plan{}
+	for _, sf := range reflect.VisibleFields(t) {
+		tag, ok := sf.Tag.Lookup("validate")
+		if !ok || !sf.IsExported() || tag == "-" {
+			continue
+		}
+
+		fp := fieldPlan{index: sf.Index, name: ErrorKey(sf)}
+		// Rules after "each" apply to the elements of a slice
+		var each []Rule
+		inEach := false
+		for _, spec := range splitTag(tag) {
+			if strings.TrimSpace(spec) == "each" {
+				inEach = true
+				continue
+			}
+			r, err := parseRule(strings.TrimSpace(spec))
+			if err != nil {
+				p.err = fmt.Errorf("validation: %s.%s: %w", t.Name(), sf.Name, err)
+				return p
+			}
+			switch {
+			case r == nil:
+			case inEach:
+				each = append(each, r)
+			default:
+				fp.rules = append(fp.rules, r)
+			}
+		}
+		if inEach {
+			fp.rules = append(fp.rules, Each(each...))
+		}
+		p.fields = append(p.fields, fp)
+	}
+	return p
+}
+
+// splitTag splits a validate tag into its rules. A regex rule takes the rest
+// of the tag, commas included, so it comes last: validate:"max=10,regex=^\d{1,3}$".
+func splitTag(tag string) []string {
+	specs := strings.Split(tag, ",")
+	for i, spec := range specs {
+		if strings.HasPrefix(strings.TrimSpace(spec), "regex=") {
+			return append(specs[:i], strings.Join(specs[i:], ","))
+		}
+	}
+	return specs
+}
+
+// FieldName is the name errors are reported under: the httpin form or query
+// key, the json key, or the Go field name.
+func FieldName(sf reflect.StructField) string {
+	if in := sf.Tag.Get("in"); in != "" {
+		for _, directive := range strings.Split(in, ";") {
+			_, keys, found := strings.Cut(strings.TrimSpace(directive), "=")
+			if found && keys != "" {
+				name, _, _ := strings.Cut(keys, ",")
+				return name
+			}
+		}
+	}
+	if name, _, _ := strings.Cut(sf.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	return sf.Name
+}
+
+// Parse reads a rule as written in validate tags, such as "max=120".
+func Parse(spec string) (Rule, error) {
+	return parseRule(strings.TrimSpace(spec))
+}
+
+func parseRule(spec string) (Rule, error) {
+	name, arg, _ := strings.Cut(spec, "=")
+	switch name {
+	case "":
+		return nil, nil
+	case "required":
+		return method((*app.VField).Required), nil
+	case "email":
+		return method((*app.VField).Email), nil
+	case "alpha":
+		return method((*app.VField).Alpha), nil
+	case "alpha_num":
+		return method((*app.VField).AlphaNumeric), nil
+	case "alpha_dash":
+		return method((*app.VField).AlphaDash), nil
+	case "numeric":
+		return method((*app.VField).Numeric), nil
+	case "boolean":
+		return method((*app.VField).Boolean), nil
+	case "url":
+		return method((*app.VField).URL), nil
+	case "ip":
+		return method((*app.VField).IP), nil
+	case "uuid":
+		return method((*app.VField).UUID), nil
+	case "ulid":
+		return normalized(ids.ULID.Valid, strings.ToUpper, "This field must be a valid ULID"), nil
+	case "json":
+		return method((*app.VField).JSON), nil
+	case "token":
+		// The characters header values such as request ids are safe in
+		return func(v *app.Validator, f *app.VField) {
+			if s, ok := f.Value().(string); ok && s != "" && !isToken(s) {
+				v.AddError(f.Name(), "This field may only contain letters, digits and !#$%&'*+-.^_`|~")
+			}
+		}, nil
+	case "timezone":
+		return method((*app.VField).Timezone), nil
+	case "min", "max":
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%s needs an integer, got %q", name, arg)
+		}
+		if name == "min" {
+			return func(_ *app.Validator, f *app.VField) { f.Min(n) }, nil
+		}
+		return func(_ *app.Validator, f *app.VField) { f.Max(n) }, nil
+	case "between":
+		lo, hi, _ := strings.Cut(arg, ":")
+		min, err1 := strconv.Atoi(lo)
+		max, err2 := strconv.Atoi(hi)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("between needs min:max, got %q", arg)
+		}
+		return func(_ *app.Validator, f *app.VField) { f.Between(min, max) }, nil
+	case "in":
+		values := strings.Split(arg, "|")
+		return func(_ *app.Validator, f *app.VField) { f.In(values) }, nil
+	case "enum":
+		// Fields of enum types validate with their own Valid method
+		return func(v *app.Validator, f *app.VField) {
+			if e, ok := f.Value().(interface{ Valid() bool }); ok && !reflect.ValueOf(e).IsZero() && !e.Valid() {
+				v.AddError(f.Name(), "The selected value is invalid")
+			}
+		}, nil
+	case "date":
+		if arg == "" {
+			arg = "2006-01-02"
+		}
+		return func(_ *app.Validator, f *app.VField) { f.Date(arg) }, nil
+	case "starts_with":
+		return func(_ *app.Validator, f *app.VField) { f.StartsWith(arg) }, nil
+	case "ends_with":
+		return func(_ *app.Validator, f *app.VField) { f.EndsWith(arg) }, nil
+	case "contains":
+		return func(_ *app.Validator, f *app.VField) { f.Contains(arg) }, nil
+	case "decimal":
+		places := -1
+		if arg != "" {
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("decimal needs a number of places, got %q", arg)
+			}
+			places = n
+		}
+		return func(v *app.Validator, f *app.VField) {
+			d, present, ok := decimalValue(f.Value())
+			switch {
+			case !present:
+			case !ok:
+				v.AddError(f.Name(), "This field must be a decimal number")
+			case places >= 0 && d.Scale() > int32(places) && !d.Equal(d.Truncate(int32(places))):
+				v.AddError(f.Name(), message("This field must have at most :count decimal place|places", i18n.Args{"count": places}))
+			}
+		}, nil
+	case "decimal_min", "decimal_max":
+		bound, err := decimal.Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%s needs a decimal, got %q", name, arg)
+		}
+		return func(v *app.Validator, f *app.VField) {
+			d, present, ok := decimalValue(f.Value())
+			if !present || !ok {
+				return
+			}
+			if name == "decimal_min" && d.LessThan(bound) {
+				v.AddError(f.Name(), "This field must be at least "+arg)
+			} else if name == "decimal_max" && d.GreaterThan(bound) {
+				v.AddError(f.Name(), "This field must not exceed "+arg)
+			}
+		}, nil
+	case "country":
+		valid, message := Country, "This field must be a valid country code"
+		switch arg {
+		case "", "alpha2":
+		case "alpha3":
+			valid = Country3
+		default:
+			return nil, fmt.Errorf("country takes alpha2 or alpha3, got %q", arg)
+		}
+		return codeRule(valid, message), nil
+	case "language":
+		return codeRule(Language, "This field must be a valid language code"), nil
+	case "locale":
+		return codeRule(Locale, "This field must be a valid locale"), nil
+	case "currency":
+		return func(v *app.Validator, f *app.VField) {
+			var code string
+			switch c := f.Value().(type) {
+			case string:
+				code = c
+			case money.Money:
+				code = c.Currency
+			case *money.Money:
+				if c == nil {
+					return
+				}
+				code = c.Currency
+			default:
+				return
+			}
+			if code != "" && !money.Valid(code) {
+				v.AddError(f.Name(), "This field must be a valid currency code")
+			}
+		}, nil
+	case "phone":
+		var regions []string
+		if arg != "" {
+			regions = strings.Split(arg, "|")
+		}
+		for _, code := range regions {
+			if !phone.Known(code) {
+				return nil, fmt.Errorf("phone: unknown region %q", code)
+			}
+		}
+		return func(v *app.Validator, f *app.VField) {
+			s, ok := f.Value().(string)
+			if !ok || s == "" {
+				return
+			}
+			n, err := phone.Parse(s, regions...)
+			if err != nil {
+				v.AddError(f.Name(), "This field must be a valid phone number")
+				return
+			}
+			f.SetValue(n.E164())
+		}, nil
+	case "iban":
+		return normalized(IBAN, compact, "This field must be a valid IBAN"), nil
+	case "bic":
+		return normalized(BIC, compact, "This field must be a valid BIC"), nil
+	case "vat":
+		return normalized(VAT, compact, "This field must be a valid VAT number"), nil
+	case "card":
+		var brands []string
+		if arg != "" {
+			brands = strings.Split(arg, "|")
+		}
+		for _, b := range brands {
+			if !slices.ContainsFunc(cardBrands, func(cb cardBrand) bool { return cb.name == b }) {
+				return nil, fmt.Errorf("card: unknown brand %q", b)
+			}
+		}
+		return func(v *app.Validator, f *app.VField) {
+			s, ok := f.Value().(string)
+			if !ok || s == "" {
+				return
+			}
+			brand, ok := CardBrand(s)
+			switch {
+			case !ok:
+				v.AddError(f.Name(), "This field must be a valid card number")
+			case len(brands) > 0 && !slices.Contains(brands, brand):
+				v.AddError(f.Name(), "This card is not accepted")
+			default:
+				f.SetValue(compact(s))
+			}
+		}, nil
+	case "min_items", "max_items":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%s needs a count, got %q", name, arg)
+		}
+		if name == "min_items" {
+			return MinItems(n), nil
+		}
+		return MaxItems(n), nil
+	case "distinct":
+		return DistinctBy(arg), nil
+	case "sanitize":
+		if arg == "" {
+			arg = "ugc"
+		}
+		policy, ok := sanitize.Named(arg)
+		if !ok {
+			return nil, fmt.Errorf("sanitize: unknown policy %q", arg)
+		}
+		return HTMLSanitize(policy), nil
+	case "regex":
+		// VField.Regex compiles the pattern on every call, the plan does it
+		// once. The pattern is the rest of the tag, see splitTag
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("regex: %w", err)
+		}
+		return func(v *app.Validator, f *app.VField) {
+			if s, ok := f.Value().(string); ok && s != "" && !re.MatchString(s) {
+				v.AddError(f.Name(), "This field must match the pattern: "+arg)
+			}
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown rule %q", name)
+}
+
+// HTMLSanitize cleans string fields with a policy, as the sanitize=name tag
+// does with a registered one, ugc by default. Cleaning happens before the
+// rules after it, so put it first:
+//
+//	Body string `in:"form=body" validate:"sanitize,required,max=10000"`
+func HTMLSanitize(p *sanitize.Policy) Rule {
+	return func(_ *app.Validator, f *app.VField) {
+		if s, ok := f.Value().(string); ok && s != "" {
+			f.SetValue(p.Sanitize(s))
+		}
+	}
+}
+
+// InEnum checks that string values, of any string based type, are one of
+// values. Empty values pass, as with the in rule.
+//
+//	validation.InEnum(Statuses.Values()...)
+func InEnum[T ~string](values ...T) Rule {
+	allowed := make([]string, len(values))
+	for i, v := range values {
+		allowed[i] = string(v)
+	}
+	return func(v *app.Validator, f *app.VField) {
+		rv := reflect.ValueOf(f.Value())
+		if rv.Kind() != reflect.String || rv.Len() == 0 || slices.Contains(allowed, rv.String()) {
+			return
+		}
+		v.AddError(f.Name(), "This field must be one of the following: "+strings.Join(allowed, ", "))
+	}
+}
+
+// codeRule checks non-empty strings against a dataset of codes.
+func codeRule(valid func(string) bool, message string) Rule {
+	return func(v *app.Validator, f *app.VField) {
+		if s, ok := f.Value().(string); ok && s != "" && !valid(s) {
+			v.AddError(f.Name(), message)
+		}
+	}
+}
+
+// normalized is a rule checking non-empty strings with valid, and storing
+// them as normalize returns them when they pass.
+func normalized(valid func(string) bool, normalize func(string) string, message string) Rule {
+	return func(v *app.Validator, f *app.VField) {
+		s, ok := f.Value().(string)
+		if !ok || s == "" {
+			return
+		}
+		if !valid(s) {
+			v.AddError(f.Name(), message)
+			return
+		}
+		f.SetValue(normalize(s))
+	}
+}
+
+// isToken reports whether s is an RFC 9110 token.
+func isToken(s string) bool {
+	for _, r := range s {
+		if r > 0x7e || r <= ' ' || strings.ContainsRune("\"(),/:;<=>?@[\\]{}", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// decimalValue reads a decimal from a string, Decimal or Money field. Empty
+// values are not present, so only required rejects them.
+func decimalValue(value any) (d decimal.Decimal, present, ok bool) {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return d, false, false
+		}
+		d, err := decimal.Parse(v)
+		return d, true, err == nil
+	case decimal.Decimal:
+		return v, true, true
+	case *decimal.Decimal:
+		if v == nil {
+			return d, false, false
+		}
+		return *v, true, true
+	case money.Money:
+		return v.Amount, true, true
+	case *money.Money:
+		if v == nil {
+			return d, false, false
+		}
+		return v.Amount, true, true
+	}
+	return d, false, false
+}
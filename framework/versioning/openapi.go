@@ -0,0 +1,199 @@
+package versioning
+
+import (
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lemmego/api/config"
+)
+
+// OpenAPI returns the OpenAPI 3.1 document of a version. Schemas are read
+// from the values given to Accepts and Returns through their json tags.
+func (a *API) OpenAPI(v *Version) map[string]any {
+	title := a.Title
+	if title == "" {
+		title, _ = config.Get("app.name", "API").(string)
+	}
+	info := map[string]any{"title": title, "version": v.Name}
+	if !v.Sunset.IsZero() {
+		info["description"] = "Deprecated, served until " + v.Sunset.UTC().Format(time.DateOnly) + "."
+	}
+
+	s := &schemas{defs: map[string]any{}}
+	paths := map[string]map[string]any{}
+	for _, r := range v.routes {
+		p, params := openAPIPath(r.Pattern)
+		if paths[p] == nil {
+			paths[p] = map[string]any{}
+		}
+		paths[p][strings.ToLower(r.Method)] = r.operation(s, params)
+	}
+
+	doc := map[string]any{
+		"openapi": "3.1.0",
+		"info":    info,
+		"servers": []map[string]any{{"url": strings.TrimRight(config.Get("app.url", "").(string), "/") + a.prefix + "/" + v.Name}},
+		"paths":   paths,
+	}
+	if len(s.defs) > 0 {
+		doc["components"] = map[string]any{"schemas": s.defs}
+	}
+	return doc
+}
+
+var wildcard = regexp.MustCompile(`\{([^}.$]+)(\.\.\.)?\}`)
+
+// openAPIPath turns a mux pattern into an OpenAPI path and its parameters.
+func openAPIPath(pattern string) (string, []string) {
+	p := strings.TrimSuffix(pattern, "{$}")
+	var params []string
+	p = wildcard.ReplaceAllStringFunc(p, func(m string) string {
+		name := wildcard.FindStringSubmatch(m)[1]
+		params = append(params, name)
+		return "{" + name + "}"
+	})
+	if p != "/" {
+		p = strings.TrimSuffix(p, "/")
+	}
+	return p, params
+}
+
+func (r *Route) operation(s *schemas, params []string) map[string]any {
+	op := map[string]any{}
+	if r.summary != "" {
+		op["summary"] = r.summary
+	}
+	if r.description != "" {
+		op["description"] = r.description
+	}
+	if len(r.tags) > 0 {
+		op["tags"] = r.tags
+	}
+	if since, _, _ := r.deprecation(); !since.IsZero() {
+		op["deprecated"] = true
+	}
+	if len(params) > 0 {
+		var ps []map[string]any
+		for _, name := range params {
+			ps = append(ps, map[string]any{
+				"name":     name,
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "string"},
+			})
+		}
+		op["parameters"] = ps
+	}
+	if r.request != nil {
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": s.of(reflect.TypeOf(r.request))}},
+		}
+	}
+
+	responses := map[string]any{}
+	for status, body := range r.responses {
+		resp := map[string]any{"description": http.StatusText(status)}
+		if body != nil {
+			resp["content"] = map[string]any{"application/json": map[string]any{"schema": s.of(reflect.TypeOf(body))}}
+		}
+		responses[strconv.Itoa(status)] = resp
+	}
+	if len(responses) == 0 {
+		responses["200"] = map[string]any{"description": http.StatusText(http.StatusOK)}
+	}
+	op["responses"] = responses
+	return op
+}
+
+// schemas builds JSON schemas, sharing named structs as components.
+type schemas struct {
+	defs map[string]any
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	rawMessageType    = reflect.TypeFor[json.RawMessage]()
+	bytesType         = reflect.TypeFor[[]byte]()
+)
+
+func (s *schemas) of(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return map[string]any{}
+	case t == bytesType:
+		return map[string]any{"type": "string", "format": "byte"}
+	case t.Kind() != reflect.Struct && (t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType)):
+		return map[string]any{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return map[string]any{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": s.of(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.of(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		ref := map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+		if _, ok := s.defs[t.Name()]; !ok {
+			s.defs[t.Name()] = map[string]any{} // placeholder against recursion
+			s.defs[t.Name()] = s.object(t)
+		}
+		return ref
+	}
+	return map[string]any{}
+}
+
+// object reads the fields of a struct as encoding/json would.
+func (s *schemas) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous && f.Type.Kind() == reflect.Struct {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = s.of(f.Type)
+		if !slices.Contains(strings.Split(opts, ","), "omitempty") && f.Type.Kind() != reflect.Pointer {
+			required = append(required, name)
+		}
+	}
+	obj := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		obj["required"] = required
+	}
+	return obj
+}
@@ -0,0 +1,73 @@
+package versioning
+
+import (
+	"time"
+
+	"github.com/lemmego/api/app"
+)
+
+// Route is a route of a version, with what its OpenAPI operation says.
+type Route struct {
+	*app.Route
+	// Pattern is the path within the version.
+	Pattern string
+
+	summary     string
+	description string
+	tags        []string
+	request     any
+	responses   map[int]any
+
+	// Deprecated, Sunset and Link override those of the version.
+	Deprecated time.Time
+	Sunset     time.Time
+	Link       string
+
+	version *Version
+}
+
+// UseBefore adds middleware to the route.
+func (r *Route) UseBefore(handlers ...app.Handler) *Route {
+	r.Route.UseBefore(handlers...)
+	return r
+}
+
+// Summary sets the one line description of the operation.
+func (r *Route) Summary(summary string) *Route {
+	r.summary = summary
+	return r
+}
+
+// Describe sets the long description of the operation.
+func (r *Route) Describe(description string) *Route {
+	r.description = description
+	return r
+}
+
+// Tag groups the operation in the documentation.
+func (r *Route) Tag(tags ...string) *Route {
+	r.tags = append(r.tags, tags...)
+	return r
+}
+
+// Accepts documents the JSON body, given as a value of its type.
+func (r *Route) Accepts(body any) *Route {
+	r.request = body
+	return r
+}
+
+// Returns documents a response status and its JSON body, nil for none.
+func (r *Route) Returns(status int, body any) *Route {
+	if r.responses == nil {
+		r.responses = map[int]any{}
+	}
+	r.responses[status] = body
+	return r
+}
+
+// Deprecate marks the route deprecated in a version that is not, e.g. when
+// a newer version replaces it.
+func (r *Route) Deprecate(since, sunset time.Time, link string) *Route {
+	r.Deprecated, r.Sunset, r.Link = since, sunset, link
+	return r
+}
@@ -0,0 +1,243 @@
+// Package versioning serves several versions of an API side by side. Each
+// version is a route group under the API prefix, and clients pick one by
+// path or by Accept header:
+//
+//	api := versioning.New(r, "/api")
+//	api.Default = "v1"
+//	api.Version("v1", func(v *versioning.Version) {
+//		v.Get("/users/{id}", showUser).Summary("Show a user").Returns(http.StatusOK, User{})
+//	}).Deprecate(since, sunset, "https://example.com/docs/v2-migration")
+//	api.Version("v2", func(v *versioning.Version) { ... })
+//
+//	GET /api/v2/users/1
+//	GET /api/users/1  Accept: application/vnd.example.v2+json
+//	GET /api/users/1  Accept: application/json; version=2
+//
+// Responses carry the version served in API-Version and, for deprecated
+// versions or routes, the Deprecation, Sunset and Link headers. Every
+// version documents itself at <prefix>/<version>/openapi.json.
+package versioning
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lemmego/api/app"
+
+	"github.com/lemmego/lemmego/framework/middleware"
+)
+
+// API is the set of versions mounted under a prefix.
+type API struct {
+	// Default is the version served when a request names none. Unversioned
+	// requests are left to the router when empty.
+	Default string
+	// Title names the API in its OpenAPI documents, app.name by default.
+	Title string
+
+	router   app.Router
+	prefix   string
+	versions []*Version
+
+	mu  sync.RWMutex
+	mux *http.ServeMux // the versioned routes, to tell which paths exist
+}
+
+// New mounts versions under prefix and negotiates the version of
+// unversioned requests.
+func New(r app.Router, prefix string) *API {
+	a := &API{router: r, prefix: path.Join("/", prefix), mux: http.NewServeMux()}
+	// In a Chain, which hands the same handler back for the same next, so that
+	// the Chain registered before it still reuses its stack
+	r.Use(middleware.Chain(a.negotiate))
+	return a
+}
+
+// Version registers the routes of a version, added by fn.
+func (a *API) Version(name string, fn func(v *Version)) *Version {
+	v := &Version{Name: name, api: a, group: a.router.Group(path.Join(a.prefix, name))}
+	a.versions = append(a.versions, v)
+	fn(v)
+	v.group.Get("/openapi.json", func(c *app.Context) error {
+		return c.JSON(app.M(a.OpenAPI(v)))
+	})
+	return v
+}
+
+// Lookup returns a registered version.
+func (a *API) Lookup(name string) (*Version, bool) {
+	for _, v := range a.versions {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// Version is a version of the API and its routes.
+type Version struct {
+	Name string
+	// Deprecated is when the version was deprecated, and Sunset when it
+	// stops being served. Link documents the migration.
+	Deprecated time.Time
+	Sunset     time.Time
+	Link       string
+
+	api    *API
+	group  *app.Group
+	routes []*Route
+}
+
+// Deprecate marks the version deprecated since a date, to be removed at
+// sunset, which may be zero when not planned yet.
+func (v *Version) Deprecate(since, sunset time.Time, link string) *Version {
+	v.Deprecated, v.Sunset, v.Link = since, sunset, link
+	return v
+}
+
+// UseBefore adds middleware to every route of the version.
+func (v *Version) UseBefore(handlers ...app.Handler) {
+	v.group.UseBefore(handlers...)
+}
+
+func (v *Version) Get(pattern string, handlers ...app.Handler) *Route {
+	return v.add(http.MethodGet, pattern, v.group.Get(pattern, handlers...))
+}
+
+func (v *Version) Post(pattern string, handlers ...app.Handler) *Route {
+	return v.add(http.MethodPost, pattern, v.group.Post(pattern, handlers...))
+}
+
+func (v *Version) Put(pattern string, handlers ...app.Handler) *Route {
+	return v.add(http.MethodPut, pattern, v.group.Put(pattern, handlers...))
+}
+
+func (v *Version) Patch(pattern string, handlers ...app.Handler) *Route {
+	return v.add(http.MethodPatch, pattern, v.group.Patch(pattern, handlers...))
+}
+
+func (v *Version) Delete(pattern string, handlers ...app.Handler) *Route {
+	return v.add(http.MethodDelete, pattern, v.group.Delete(pattern, handlers...))
+}
+
+func (v *Version) add(method, pattern string, r *app.Route) *Route {
+	route := &Route{Route: r, Pattern: path.Join("/", pattern), version: v}
+	v.routes = append(v.routes, route)
+	r.BeforeMiddleware = append([]app.Handler{route.headers}, r.BeforeMiddleware...)
+
+	v.api.mu.Lock()
+	v.api.mux.Handle(method+" "+r.Path, http.NotFoundHandler())
+	v.api.mu.Unlock()
+	return route
+}
+
+// deprecation returns the deprecation of a route, falling back to its
+// version's.
+func (r *Route) deprecation() (since, sunset time.Time, link string) {
+	if !r.Deprecated.IsZero() {
+		return r.Deprecated, r.Sunset, r.Link
+	}
+	return r.version.Deprecated, r.version.Sunset, r.version.Link
+}
+
+// headers announces the version served and its deprecation.
+func (r *Route) headers(c *app.Context) error {
+	h := c.ResponseWriter().Header()
+	h.Set("API-Version", r.version.Name)
+	since, sunset, link := r.deprecation()
+	if !since.IsZero() {
+		// RFC 9745
+		h.Set("Deprecation", "@"+strconv.FormatInt(since.Unix(), 10))
+		if link != "" {
+			h.Add("Link", "<"+link+`>; rel="deprecation"`)
+		}
+	}
+	if !sunset.IsZero() {
+		// RFC 8594
+		h.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+	}
+	return c.Next()
+}
+
+// negotiate rewrites unversioned requests under the prefix to the version
+// asked for in the Accept header, or the default one, when that version has
+// a matching route.
+func (a *API) negotiate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := strings.CutPrefix(r.URL.Path, a.prefix+"/")
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if first, _, _ := strings.Cut(rest, "/"); a.known(first) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		name := requested(r.Header.Get("Accept"))
+		if name == "" {
+			name = a.Default
+		}
+		if name == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept")
+		if !a.known(name) {
+			http.Error(w, "Unsupported API version "+name, http.StatusNotAcceptable)
+			return
+		}
+
+		versioned := r.Clone(r.Context())
+		versioned.URL.Path = a.prefix + "/" + name + "/" + rest
+		versioned.URL.RawPath = ""
+		a.mu.RLock()
+		_, pattern := a.mux.Handler(versioned)
+		a.mu.RUnlock()
+		if pattern == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		versioned.RequestURI = versioned.URL.RequestURI()
+		next.ServeHTTP(w, versioned)
+	})
+}
+
+func (a *API) known(name string) bool {
+	_, ok := a.Lookup(name)
+	return ok
+}
+
+// requested reads the version of an Accept header, either a vendor media
+// type such as application/vnd.example.v2+json or a version parameter.
+func requested(accept string) string {
+	for _, media := range strings.Split(accept, ",") {
+		typ, params, _ := strings.Cut(media, ";")
+		for _, param := range strings.Split(params, ";") {
+			k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(k, "version") && v != "" {
+				return normalize(strings.Trim(v, `"`))
+			}
+		}
+		typ = strings.TrimSpace(typ)
+		if sub, ok := strings.CutPrefix(typ, "application/vnd."); ok {
+			sub, _, _ = strings.Cut(sub, "+")
+			if i := strings.LastIndexByte(sub, '.'); i >= 0 {
+				return normalize(sub[i+1:])
+			}
+		}
+	}
+	return ""
+}
+
+// normalize names versions given as "2" like those registered, "v2".
+func normalize(v string) string {
+	if v != "" && v[0] >= '0' && v[0] <= '9' {
+		return "v" + v
+	}
+	return v
+}
@@ -0,0 +1,55 @@
+// Package webhook receives the events third party services push to the
+// application. A Verifier checks that the request comes from the service,
+// then the handler gets the raw body:
+//
+//	r.Post("/webhooks/stripe", webhook.Receive(billing.Signature(secret), billing.HandleWebhook))
+//
+// Webhook routes live under /webhooks/, which skips the CSRF check: the
+// signature is their protection.
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/lemmego/api/app"
+
+	"github.com/lemmego/lemmego/framework/jsonx"
+)
+
+// Prefix is the path under which webhook routes are mounted.
+const Prefix = "/webhooks/"
+
+// MaxBody is the largest payload accepted.
+const MaxBody = 1 << 20
+
+var ErrSignature = errors.New("webhook: invalid signature")
+
+// Verifier checks the signature of a webhook request and its body.
+type Verifier func(r *http.Request, body []byte) error
+
+// Handler processes a verified payload. Returning an error answers 500, so
+// the service retries the delivery later.
+type Handler func(ctx context.Context, body []byte) error
+
+// Receive reads, verifies and handles webhook deliveries.
+func Receive(verify Verifier, handle Handler) app.Handler {
+	return func(c *app.Context) error {
+		body, err := io.ReadAll(io.LimitReader(c.Request().Body, MaxBody+1))
+		if err != nil {
+			return c.BadRequest(err)
+		}
+		if len(body) > MaxBody {
+			return jsonx.Write(c, http.StatusRequestEntityTooLarge, app.M{"message": "Payload too large"})
+		}
+		if err := verify(c.Request(), body); err != nil {
+			return c.BadRequest(err)
+		}
+		if err := handle(c.RequestContext(), body); err != nil {
+			return err
+		}
+		return jsonx.Write(c, http.StatusOK, app.M{"received": true})
+	}
+}
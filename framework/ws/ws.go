@@ -0,0 +1,247 @@
+// Package ws keeps the WebSocket connections of signed in users, so the
+// server can push messages to their open pages as things happen:
+//
+//	err := ws.Send(ctx, user.ID, "order.shipped", order)
+//
+// Pages connect to the /ws route and receive messages as JSON, along with
+// a message of type ping every 30 seconds:
+//
+//	const socket = new WebSocket(`${location.origin.replace("http", "ws")}/ws`)
+//	socket.onmessage = e => {
+//		const { type, data } = JSON.parse(e.data)
+//	}
+//
+// Connections live in the process serving them. With an events broker,
+// messages sent by any process, queue workers included, reach the
+// connections of every web instance, see Publish and Receive.
+package ws
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/lemmego/lemmego/framework/events"
+	"github.com/lemmego/lemmego/framework/metrics"
+	"github.com/lemmego/lemmego/framework/str"
+)
+
+// pingInterval is how often idle connections get a ping message, before
+// proxies drop them as silent.
+const pingInterval = 30 * time.Second
+
+// sendBuffer is how many messages may wait for a slow connection before it
+// is closed.
+const sendBuffer = 32
+
+var connections = metrics.Gauge("ws_connections", "Open WebSocket connections.")
+
+// Message is what connections receive.
+type Message struct {
+	Type string `json:"type"`
+	Data any    `json:"data,omitempty"`
+}
+
+// envelope is a message on the broker, for the connections of a user.
+type envelope struct {
+	UserID string          `json:"user_id"`
+	Data   json.RawMessage `json:"data"`
+}
+
+type conn struct {
+	send chan []byte
+	done chan struct{}
+	once sync.Once
+}
+
+func (c *conn) close() {
+	c.once.Do(func() { close(c.done) })
+}
+
+var (
+	mu    sync.RWMutex
+	users = map[string]map[*conn]struct{}{}
+
+	brokerMu sync.RWMutex
+	broker   events.Broker
+	topic    string
+)
+
+// Serve upgrades the request to a WebSocket connection of userID, and
+// returns once it is closed. Only pages of the request's own host, or of
+// allowedOrigins, may connect, cookies authenticating them.
+func Serve(w http.ResponseWriter, r *http.Request, userID string, allowedOrigins ...string) {
+	server := websocket.Server{
+		Handshake: func(cfg *websocket.Config, r *http.Request) error {
+			return checkOrigin(r, allowedOrigins)
+		},
+		Handler: func(ws *websocket.Conn) {
+			handle(ws, userID)
+		},
+	}
+	// Middleware wrap the writer, hijacking goes through their Unwrap
+	server.ServeHTTP(hijacker{w}, r)
+}
+
+func checkOrigin(r *http.Request, allowed []string) error {
+	origin := r.Header.Get("Origin")
+	u, err := url.Parse(origin)
+	if err != nil || origin == "" {
+		return errors.New("ws: missing or malformed origin")
+	}
+	if u.Host == r.Host {
+		return nil
+	}
+	for _, a := range allowed {
+		if a == origin {
+			return nil
+		}
+	}
+	return fmt.Errorf("ws: origin %s is not allowed", origin)
+}
+
+type hijacker struct {
+	http.ResponseWriter
+}
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(h.ResponseWriter).Hijack()
+}
+
+func handle(ws *websocket.Conn, userID string) {
+	// The timeouts of the server are for requests, not connections
+	_ = ws.SetDeadline(time.Time{})
+	c := &conn{send: make(chan []byte, sendBuffer), done: make(chan struct{})}
+	add(userID, c)
+	connections.Inc()
+	defer func() {
+		remove(userID, c)
+		connections.Add(-1)
+		ws.Close()
+	}()
+
+	// Pages send nothing but may close the connection, which ends reads
+	go func() {
+		defer c.close()
+		var discard []byte
+		for {
+			if err := websocket.Message.Receive(ws, &discard); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping, _ := json.Marshal(Message{Type: "ping"})
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		var data []byte
+		select {
+		case <-c.done:
+			return
+		case data = <-c.send:
+		case <-ticker.C:
+			data = ping
+		}
+		_ = ws.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		if err := websocket.Message.Send(ws, string(data)); err != nil {
+			return
+		}
+	}
+}
+
+func add(userID string, c *conn) {
+	mu.Lock()
+	defer mu.Unlock()
+	if users[userID] == nil {
+		users[userID] = map[*conn]struct{}{}
+	}
+	users[userID][c] = struct{}{}
+}
+
+func remove(userID string, c *conn) {
+	c.close()
+	mu.Lock()
+	defer mu.Unlock()
+	delete(users[userID], c)
+	if len(users[userID]) == 0 {
+		delete(users, userID)
+	}
+}
+
+// Connected reports whether the user has connections in this process.
+func Connected(userID string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return len(users[userID]) > 0
+}
+
+// Send sends a message of type to the connections of a user, through the
+// broker when there is one.
+func Send(ctx context.Context, userID, typ string, data any) error {
+	msg, err := json.Marshal(Message{Type: typ, Data: data})
+	if err != nil {
+		return err
+	}
+	brokerMu.RLock()
+	b, t := broker, topic
+	brokerMu.RUnlock()
+	if b == nil {
+		deliver(userID, msg)
+		return nil
+	}
+	env, err := json.Marshal(envelope{UserID: userID, Data: msg})
+	if err != nil {
+		return err
+	}
+	return b.Publish(ctx, t, env)
+}
+
+// deliver queues msg on the connections of a user in this process,
+// closing those too slow to keep up.
+func deliver(userID string, msg []byte) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for c := range users[userID] {
+		select {
+		case c.send <- msg:
+		default:
+			c.close()
+		}
+	}
+}
+
+// Publish makes Send go through the broker on topic, for the processes
+// receiving it to deliver.
+func Publish(b events.Broker, t string) {
+	brokerMu.Lock()
+	defer brokerMu.Unlock()
+	broker, topic = b, t
+}
+
+// Receive delivers the messages of topic to the connections of this
+// process until ctx is done. Every process receives every message, each
+// subscribing in a group of its own.
+func Receive(ctx context.Context, b events.Broker, t string) error {
+	host, _ := os.Hostname()
+	group := fmt.Sprintf("ws:%s:%d:%s", host, os.Getpid(), str.RandomString(6))
+	return b.Subscribe(ctx, t, group, func(data []byte) {
+		var env envelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			slog.Warn(fmt.Sprintf("ws: malformed message on %s: %s", t, err))
+			return
+		}
+		deliver(env.UserID, env.Data)
+	})
+}
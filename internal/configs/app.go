@@ -7,4 +7,15 @@ var app = config.M{
 	"port":  config.MustEnv("APP_PORT", 8080),
 	"env":   config.MustEnv("APP_ENV", "development"),
 	"debug": config.MustEnv("APP_DEBUG", false),
+	// Locale used when a request asks for none the translations support
+	"locale": config.MustEnv("APP_LOCALE", "en"),
+	// Directory of the <locale>.json translation files
+	"lang_path": "resources/lang",
+	// Public URL, used for absolute links in sitemaps and feeds
+	"url": config.MustEnv("APP_URL", ""),
+
+	// Used to sign and encrypt values, generate one with the key:generate command
+	"key": config.MustEnv("APP_KEY", ""),
+	// Comma separated keys replaced by APP_KEY, still accepted for decryption
+	"previous_keys": config.MustEnv("APP_PREVIOUS_KEYS", ""),
 }
@@ -0,0 +1,72 @@
+package configs
+
+import "github.com/lemmego/api/config"
+
+var auth = config.M{
+	// The provider checking the credentials of each guard. "users" is the
+	// application's own, registered with auth.RegisterProvider, and other
+	// names are configured under providers.
+	"guards": config.M{
+		"web": config.M{
+			"provider": config.MustEnv("AUTH_WEB_PROVIDER", "users"),
+		},
+		"staff": config.M{
+			"provider": config.MustEnv("AUTH_STAFF_PROVIDER", "ldap"),
+		},
+	},
+
+	// Lookups of API tokens are cached, sparing the database a query per
+	// request. Unknown tokens are cached briefly too, so floods of invalid
+	// ones are cheap. Revoking a token forgets its lookup in the cache of the
+	// process handling the revocation only: with several instances and the
+	// in-process cache, the others accept a revoked token for up to
+	// cache_seconds. Keep it short, or 0 to query every request, unless the
+	// default cache store is shared between instances
+	"tokens": config.M{
+		"cache_seconds":          config.MustEnv("AUTH_TOKEN_CACHE_SECONDS", 5),
+		"negative_cache_seconds": config.MustEnv("AUTH_TOKEN_NEGATIVE_CACHE_SECONDS", 5),
+	},
+
+	// Client certificates of machine to machine routes, issued by ca_file and
+	// mapped to users by identity, such as
+	// "uri:spiffe://example.com/billing": "billing" or "cn:backup": "backup".
+	// header is the certificate forwarded by a proxy terminating TLS, read on
+	// internal listeners only.
+	"client_certs": config.M{
+		"ca_file": config.MustEnv("MTLS_CA_FILE", ""),
+		"header":  config.MustEnv("MTLS_HEADER", ""),
+		"users":   config.M{},
+	},
+
+	"providers": config.M{
+		// Users are searched under base_dn with user_filter, as bind_dn, then
+		// bound as with their password. On Active Directory, the filter is
+		// usually (&(objectClass=user)(sAMAccountName={username})) and the
+		// id attribute sAMAccountName.
+		"ldap": config.M{
+			"driver":        "ldap",
+			"url":           config.MustEnv("LDAP_URL", "ldap://localhost:389"),
+			"bind_dn":       config.MustEnv("LDAP_BIND_DN", ""),
+			"bind_password": config.MustEnv("LDAP_BIND_PASSWORD", ""),
+			"base_dn":       config.MustEnv("LDAP_BASE_DN", ""),
+			"user_filter":   config.MustEnv("LDAP_USER_FILTER", "(&(objectClass=person)(uid={username}))"),
+
+			"id_attribute":    config.MustEnv("LDAP_ID_ATTRIBUTE", "uid"),
+			"name_attribute":  "cn",
+			"email_attribute": "mail",
+			"group_attribute": "memberOf",
+			// Roles of the members of groups, by DN or common name
+			"group_roles": config.M{},
+
+			// ldaps:// URLs use TLS from the start, start_tls upgrades
+			// ldap:// ones. ca_file trusts a private certificate authority.
+			"start_tls":            config.MustEnv("LDAP_START_TLS", false),
+			"ca_file":              config.MustEnv("LDAP_CA_FILE", ""),
+			"insecure_skip_verify": config.MustEnv("LDAP_INSECURE_SKIP_VERIFY", false),
+
+			// Idle connections kept, and seconds before an operation fails
+			"pool_size": config.MustEnv("LDAP_POOL_SIZE", 4),
+			"timeout":   config.MustEnv("LDAP_TIMEOUT", 10),
+		},
+	},
+}
@@ -0,0 +1,22 @@
+package configs
+
+import "github.com/lemmego/api/config"
+
+var backup = config.M{
+	// Disk and path the archives are written to, empty for the default disk
+	"disk": config.MustEnv("BACKUP_DISK", ""),
+	"path": config.MustEnv("BACKUP_PATH", "backups"),
+
+	// Connection to dump, empty for the default one
+	"connection": config.MustEnv("BACKUP_CONNECTION", ""),
+
+	// Comma separated directories archived next to the database
+	"directories": config.MustEnv("BACKUP_DIRECTORIES", "storage/app"),
+
+	// Encrypt archives with APP_KEY, restoring then needs the same key
+	"encrypt": config.MustEnv("BACKUP_ENCRYPT", true),
+
+	// Newest backups always kept, and the age in days after which older ones are removed
+	"keep":     config.MustEnv("BACKUP_KEEP", 7),
+	"max_days": config.MustEnv("BACKUP_MAX_DAYS", 30),
+}
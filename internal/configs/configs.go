@@ -6,10 +6,26 @@ import (
 
 func Load() config.M {
 	return config.M{
-		"app":         app,
-		"session":     session,
-		"database":    database["database"],
-		"redis":       database["redis"],
-		"filesystems": filesystems,
+		"app":           app,
+		"session":       session,
+		"database":      database["database"],
+		"redis":         database["redis"],
+		"filesystems":   filesystems,
+		"server":        server,
+		"services":      services,
+		"queue":         queue,
+		"schedule":      schedule,
+		"events":        events,
+		"outbox":        outbox,
+		"backup":        backup,
+		"reports":       reports,
+		"pdf":           pdf,
+		"search":        search,
+		"logging":       logging,
+		"mail":          mail,
+		"sms":           sms,
+		"push":          push,
+		"notifications": notifications,
+		"auth":          auth,
 	}
 }
@@ -5,52 +5,64 @@ import (
 	"time"
 )
 
-var database = config.M{
-	"database": config.M{
-		"default": config.MustEnv("DB_CONNECTION", "sqlite"),
-		"connections": config.M{
-			"sqlite": config.M{
-				"driver":                  "sqlite",
-				"url":                     config.MustEnv("DATABASE_URL", "file:./storage/database.sqlite?cache=shared&mode=memory"),
-				"database":                config.MustEnv("DB_DATABASE", "./storage/database.sqlite"),
-				"prefix":                  "",
-				"foreign_key_constraints": config.MustEnv("DB_FOREIGN_KEYS", true),
-			},
-			"mysql": config.M{
-				"driver":            "mysql",
-				"host":              config.MustEnv("DB_HOST", "localhost"),
-				"port":              config.MustEnv("DB_PORT", 3306),
-				"database":          config.MustEnv("DB_DATABASE", "lemmego"),
-				"user":              config.MustEnv("DB_USERNAME", "root"),
-				"password":          config.MustEnv("DB_PASSWORD", ""),
-				"params":            config.MustEnv("DB_PARAMS", ""),
-				"auto_create":       config.MustEnv("DB_AUTOCREATE", false),
-				"max_open_conns":    config.MustEnv("DB_MAX_OPEN_CONNS", 100),
-				"max_idle_conns":    config.MustEnv("DB_MAX_IDLE_CONNS", 10),
-				"conn_max_lifetime": config.MustEnv("DB_CONN_MAX_LIFETIME", time.Hour),
-			},
-			"pgsql": config.M{
-				"driver":            "pgsql",
-				"host":              config.MustEnv("DB_HOST", "localhost"),
-				"port":              config.MustEnv("DB_PORT", 5432),
-				"database":          config.MustEnv("DB_DATABASE", "lemmego"),
-				"user":              config.MustEnv("DB_USERNAME", ""),
-				"password":          config.MustEnv("DB_PASSWORD", ""),
-				"params":            config.MustEnv("DB_PARAMS", ""),
-				"auto_create":       config.MustEnv("DB_AUTOCREATE", false),
-				"max_open_conns":    config.MustEnv("DB_MAX_OPEN_CONNS", 100),
-				"max_idle_conns":    config.MustEnv("DB_MAX_IDLE_CONNS", 10),
-				"conn_max_lifetime": config.MustEnv("DB_CONN_MAX_LIFETIME", time.Hour),
+var database = loadDatabase()
+
+// loadDatabase is evaluated again by Reload so pool settings can be tuned at runtime.
+func loadDatabase() config.M {
+	return config.M{
+		"database": config.M{
+			"default": config.MustEnv("DB_CONNECTION", "sqlite"),
+			// Writes fail with repo.ErrReadOnly, e.g. during maintenance
+			"read_only": config.MustEnv("DB_READ_ONLY", false),
+			// A connection below serving reads while the database is read-only
+			"replica": config.MustEnv("DB_REPLICA_CONNECTION", ""),
+			// How often the default connection is pinged, turning read-only
+			// mode on while it is down. Zero disables the check.
+			"failover_check": config.MustEnv("DB_FAILOVER_CHECK", 5*time.Second),
+			"connections": config.M{
+				"sqlite": config.M{
+					"driver":                  "sqlite",
+					"url":                     config.MustEnv("DATABASE_URL", "file:./storage/database.sqlite?cache=shared&mode=memory"),
+					"database":                config.MustEnv("DB_DATABASE", "./storage/database.sqlite"),
+					"prefix":                  "",
+					"foreign_key_constraints": config.MustEnv("DB_FOREIGN_KEYS", true),
+				},
+				"mysql": config.M{
+					"driver":            "mysql",
+					"host":              config.MustEnv("DB_HOST", "localhost"),
+					"port":              config.MustEnv("DB_PORT", 3306),
+					"database":          config.MustEnv("DB_DATABASE", "lemmego"),
+					"user":              config.MustEnv("DB_USERNAME", "root"),
+					"password":          config.MustEnv("DB_PASSWORD", ""),
+					"params":            config.MustEnv("DB_PARAMS", ""),
+					"auto_create":       config.MustEnv("DB_AUTOCREATE", false),
+					"max_open_conns":    config.MustEnv("DB_MAX_OPEN_CONNS", 100),
+					"max_idle_conns":    config.MustEnv("DB_MAX_IDLE_CONNS", 10),
+					"conn_max_lifetime": config.MustEnv("DB_CONN_MAX_LIFETIME", time.Hour),
+				},
+				"pgsql": config.M{
+					"driver":            "pgsql",
+					"host":              config.MustEnv("DB_HOST", "localhost"),
+					"port":              config.MustEnv("DB_PORT", 5432),
+					"database":          config.MustEnv("DB_DATABASE", "lemmego"),
+					"user":              config.MustEnv("DB_USERNAME", ""),
+					"password":          config.MustEnv("DB_PASSWORD", ""),
+					"params":            config.MustEnv("DB_PARAMS", ""),
+					"auto_create":       config.MustEnv("DB_AUTOCREATE", false),
+					"max_open_conns":    config.MustEnv("DB_MAX_OPEN_CONNS", 100),
+					"max_idle_conns":    config.MustEnv("DB_MAX_IDLE_CONNS", 10),
+					"conn_max_lifetime": config.MustEnv("DB_CONN_MAX_LIFETIME", time.Hour),
+				},
 			},
 		},
-	},
-	"redis": config.M{
-		"connections": config.M{
-			"default": config.M{
-				"host":     config.MustEnv("REDIS_HOST", "localhost"),
-				"port":     config.MustEnv("REDIS_PORT", 6379),
-				"password": config.MustEnv("REDIS_PASSWORD", ""),
+		"redis": config.M{
+			"connections": config.M{
+				"default": config.M{
+					"host":     config.MustEnv("REDIS_HOST", "localhost"),
+					"port":     config.MustEnv("REDIS_PORT", 6379),
+					"password": config.MustEnv("REDIS_PASSWORD", ""),
+				},
 			},
 		},
-	},
+	}
 }
@@ -0,0 +1,21 @@
+package configs
+
+import "github.com/lemmego/api/config"
+
+var events = config.M{
+	// Broker carrying events to other instances and services: "nats",
+	// "kafka" (through a REST Proxy) or "none"
+	"broker": config.MustEnv("EVENTS_BROKER", "none"),
+
+	// Address of the NATS server or the Kafka REST Proxy
+	"url": config.MustEnv("EVENTS_BROKER_URL", ""),
+
+	// Subject or topic the events are published on and received from
+	"topic": config.MustEnv("EVENTS_TOPIC", "app.events"),
+
+	// Group the web processes receive as, sharing the events among them
+	"group": config.MustEnv("EVENTS_GROUP", "web"),
+
+	// Comma separated names of the events published as they are dispatched
+	"forward": config.MustEnv("EVENTS_FORWARD", ""),
+}
@@ -6,12 +6,23 @@ import (
 
 var filesystems = config.M{
 	"default": config.MustEnv("FILESYSTEM_DISK", "local"),
+	// Uploads are scanned for malware by this driver, "clamav" or none
+	"scanner": config.MustEnv("UPLOAD_SCANNER", ""),
+	// Store uploads right away and scan them on a worker
+	"scan_queued": config.MustEnv("UPLOAD_SCAN_QUEUED", false),
+	"clamav":      config.MustEnv("CLAMAV_ADDRESS", "tcp://127.0.0.1:3310"),
 	"disks": config.M{
 		"local": config.M{
 			"driver": "local",
 			"root":   "storage",
 			"path":   "./storage",
 		},
+		// Served by the app below url, see storage.Served
+		"public": config.M{
+			"driver": "local",
+			"path":   "./storage/public",
+			"url":    "/storage",
+		},
 		"s3": config.M{
 			"driver":   "s3",
 			"key":      config.MustEnv("AWS_ACCESS_KEY_ID", ""),
@@ -19,6 +30,8 @@ var filesystems = config.M{
 			"region":   config.MustEnv("AWS_DEFAULT_REGION", "us-east-1"),
 			"bucket":   config.MustEnv("AWS_BUCKET", ""),
 			"endpoint": config.MustEnv("AWS_ENDPOINT", ""),
+			// A CDN in front of the bucket, if any
+			"url": config.MustEnv("AWS_URL", ""),
 		},
 		"r2": config.M{
 			"driver":   "s3",
@@ -28,5 +41,12 @@ var filesystems = config.M{
 			"bucket":   config.MustEnv("R2_BUCKET", ""),
 			"endpoint": config.MustEnv("R2_ENDPOINT", ""),
 		},
+		// Writes go to the first disk and are copied to the others, reads fall
+		// back to them, e.g. while moving files from local to s3
+		"mirrored": config.M{
+			"driver": "mirror",
+			"disks":  []string{"s3", "local"},
+			"async":  config.MustEnv("FILESYSTEM_MIRROR_ASYNC", true),
+		},
 	},
 }
@@ -0,0 +1,55 @@
+package configs
+
+import "github.com/lemmego/api/config"
+
+var logging = config.M{
+	// Channel slog's default logger writes to
+	"default": config.MustEnv("LOG_CHANNEL", "app"),
+
+	// Every channel writes to its comma separated sinks: stderr, file, syslog,
+	// loki and alerts, which sends records to chat, see notifications.alerts. Records below level are dropped, and only the sample share
+	// of those below warn is kept, 1 keeping all of them.
+	"channels": config.M{
+		"app": config.M{
+			"sinks":  config.MustEnv("LOG_SINKS", "stderr,alerts"),
+			"level":  config.MustEnv("LOG_LEVEL", "info"),
+			"sample": config.MustEnv("LOG_SAMPLE", 1.0),
+			"path":   "./storage/logs/app.log",
+		},
+		"audit": config.M{
+			"sinks":  config.MustEnv("LOG_AUDIT_SINKS", "file"),
+			"level":  "info",
+			"sample": 1.0,
+			"path":   "./storage/logs/audit.log",
+		},
+		"security": config.M{
+			"sinks":  config.MustEnv("LOG_SECURITY_SINKS", "stderr,file,alerts"),
+			"level":  "info",
+			"sample": 1.0,
+			"path":   "./storage/logs/security.log",
+		},
+	},
+
+	// Log files are rotated once they reach max_size megabytes and every
+	// rotate_hours, at midnight UTC for 24. Rotated files are gzipped when
+	// compress is set, and kept up to max_files of them for max_age_days,
+	// zero lifting either limit.
+	"max_size":     config.MustEnv("LOG_MAX_SIZE", 100),
+	"rotate_hours": config.MustEnv("LOG_ROTATE_HOURS", 24),
+	"compress":     config.MustEnv("LOG_COMPRESS", true),
+	"max_files":    config.MustEnv("LOG_MAX_FILES", 14),
+	"max_age_days": config.MustEnv("LOG_MAX_AGE_DAYS", 30),
+	// Network and address of the syslog daemon, empty for the local one
+	"syslog_network": config.MustEnv("LOG_SYSLOG_NETWORK", ""),
+	"syslog_address": config.MustEnv("LOG_SYSLOG_ADDRESS", ""),
+	// Base URL of Loki, records are pushed to its /loki/api/v1/push
+	"loki_url": config.MustEnv("LOG_LOKI_URL", ""),
+
+	// Secrets are redacted from every record when scrub is set: values
+	// logged under names such as password, token or authorization, card
+	// numbers, bearer tokens and JWTs. scrub_fields adds comma separated
+	// names, and values matching scrub_patterns are redacted too.
+	"scrub":          config.MustEnv("LOG_SCRUB", true),
+	"scrub_fields":   config.MustEnv("LOG_SCRUB_FIELDS", ""),
+	"scrub_patterns": []string{},
+}
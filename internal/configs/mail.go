@@ -0,0 +1,25 @@
+package configs
+
+import "github.com/lemmego/api/config"
+
+var mail = config.M{
+	// log writes mails to the log, smtp sends them
+	"driver":   config.MustEnv("MAIL_DRIVER", "log"),
+	"host":     config.MustEnv("MAIL_HOST", "localhost"),
+	"port":     config.MustEnv("MAIL_PORT", 587),
+	"username": config.MustEnv("MAIL_USERNAME", ""),
+	"password": config.MustEnv("MAIL_PASSWORD", ""),
+	// starttls, tls for implicit TLS as on port 465, or none
+	"encryption": config.MustEnv("MAIL_ENCRYPTION", "starttls"),
+	"from": config.M{
+		"address": config.MustEnv("MAIL_FROM_ADDRESS", "hello@example.com"),
+		"name":    config.MustEnv("MAIL_FROM_NAME", "Lemmego"),
+	},
+
+	// Directory of the <name>.md, <name>.mjml and <name>.txt templates
+	"templates": "resources/views/mail",
+	// Theme of markdown mails, registered with mail.RegisterTheme
+	"theme": config.MustEnv("MAIL_THEME", "default"),
+	// Path to the MJML compiler, for .mjml templates
+	"mjml": config.MustEnv("MAIL_MJML_BINARY", "mjml"),
+}
@@ -0,0 +1,53 @@
+package configs
+
+import "github.com/lemmego/api/config"
+
+var notifications = config.M{
+	// Channels notifications and alerts are sent through, by name. Drivers:
+	// slack and discord post to a webhook url, telegram sends through the
+	// bot of token to chat_id, sms texts the comma separated numbers of to,
+	// push sends to the devices subscribed to topic.
+	"channels": config.M{
+		"slack": config.M{
+			"driver": "slack",
+			"url":    config.MustEnv("SLACK_WEBHOOK_URL", ""),
+		},
+		"discord": config.M{
+			"driver": "discord",
+			"url":    config.MustEnv("DISCORD_WEBHOOK_URL", ""),
+		},
+		"telegram": config.M{
+			"driver":  "telegram",
+			"token":   config.MustEnv("TELEGRAM_BOT_TOKEN", ""),
+			"chat_id": config.MustEnv("TELEGRAM_CHAT_ID", ""),
+		},
+		"sms": config.M{
+			"driver": "sms",
+			"to":     config.MustEnv("SMS_ALERT_NUMBERS", ""),
+		},
+		"push": config.M{
+			"driver": "push",
+			"topic":  config.MustEnv("PUSH_ALERT_TOPIC", "alerts"),
+		},
+	},
+
+	// Records logged at or above the level of the environment, by log
+	// channels with the alerts sink, are sent to the comma separated
+	// channels. "off" sends none, and ALERT_LEVEL overrides the level of
+	// every environment.
+	"alerts": config.M{
+		"channels": config.MustEnv("ALERT_CHANNELS", ""),
+		"level":    config.MustEnv("ALERT_LEVEL", ""),
+		"levels": config.M{
+			"production":  "error",
+			"staging":     "error",
+			"development": "off",
+		},
+		// Alerts are sent in batches gathered over batch_seconds, those
+		// repeating a message sent in the last dedup_minutes are folded into
+		// a count, and at most per_hour batches are sent each hour.
+		"batch_seconds": config.MustEnv("ALERT_BATCH_SECONDS", 30),
+		"dedup_minutes": config.MustEnv("ALERT_DEDUP_MINUTES", 10),
+		"per_hour":      config.MustEnv("ALERT_PER_HOUR", 20),
+	},
+}
@@ -0,0 +1,15 @@
+package configs
+
+import "github.com/lemmego/api/config"
+
+var outbox = config.M{
+	// Relay the outbox inside the web process, instead of outbox:relay
+	"relay": config.MustEnv("OUTBOX_RELAY", true),
+
+	// Connection holding the outbox table, empty for the default one
+	"connection": config.MustEnv("OUTBOX_CONNECTION", ""),
+
+	// Seconds between polls of the outbox table, transactions committed
+	// through outbox.Transaction waking the relay of their process sooner
+	"interval": config.MustEnv("OUTBOX_INTERVAL", 1),
+}
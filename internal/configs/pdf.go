@@ -0,0 +1,14 @@
+package configs
+
+import "github.com/lemmego/api/config"
+
+var pdf = config.M{
+	// wkhtmltopdf or chrome
+	"driver": config.MustEnv("PDF_DRIVER", "wkhtmltopdf"),
+	// Path to the renderer, empty to look it up on the PATH
+	"binary": config.MustEnv("PDF_BINARY", ""),
+	// Seconds a single document may take to render
+	"timeout": config.MustEnv("PDF_TIMEOUT", 60),
+	// Chrome refuses to start as root without this, e.g. in containers
+	"no_sandbox": config.MustEnv("PDF_NO_SANDBOX", false),
+}
@@ -0,0 +1,30 @@
+package configs
+
+import "github.com/lemmego/api/config"
+
+var push = config.M{
+	// log, fake, or providers to send through the provider of each platform
+	"driver": config.MustEnv("PUSH_DRIVER", "log"),
+	// Devices not registered again for this many days are deleted by
+	// push:prune, apps registering as they start
+	"stale_days": config.MustEnv("PUSH_STALE_DAYS", 60),
+
+	// Key of a service account of the Firebase project, as JSON
+	"fcm": config.M{
+		"credentials": config.MustEnv("FCM_CREDENTIALS", ""),
+	},
+	// The .p8 key of the team, and the bundle id of the app as topic
+	"apns": config.M{
+		"key_file":   config.MustEnv("APNS_KEY_FILE", ""),
+		"key_id":     config.MustEnv("APNS_KEY_ID", ""),
+		"team_id":    config.MustEnv("APNS_TEAM_ID", ""),
+		"topic":      config.MustEnv("APNS_TOPIC", ""),
+		"production": config.MustEnv("APNS_PRODUCTION", false),
+	},
+	// VAPID key pair made by push:vapid, and a mailto: or https: subject
+	"webpush": config.M{
+		"public_key":  config.MustEnv("VAPID_PUBLIC_KEY", ""),
+		"private_key": config.MustEnv("VAPID_PRIVATE_KEY", ""),
+		"subject":     config.MustEnv("VAPID_SUBJECT", ""),
+	},
+}
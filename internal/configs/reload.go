@@ -0,0 +1,45 @@
+package configs
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/joho/godotenv"
+	apiapp "github.com/lemmego/api/app"
+	"github.com/lemmego/api/config"
+	"github.com/lemmego/lemmego/framework/reload"
+)
+
+func init() {
+	apiapp.BootService(func(a apiapp.App) error {
+		if !a.RunningInConsole() {
+			go ReloadOnSignal()
+		}
+		return nil
+	})
+}
+
+// Reload reads .env again and rebuilds the sections that support runtime
+// changes, currently the database pool settings, then tells the services
+// registered with reload.On.
+func Reload() (err error) {
+	// MustEnv panics on malformed values, a bad edit must not bring the process down
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	if _, err := os.Stat(".env"); err == nil {
+		if err := godotenv.Overload(".env"); err != nil {
+			return err
+		}
+	}
+
+	database = loadDatabase()
+	config.Set("database", database["database"])
+	config.Set("redis", database["redis"])
+
+	reload.Notify()
+	return nil
+}
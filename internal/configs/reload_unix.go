@@ -0,0 +1,23 @@
+//go:build !windows
+
+package configs
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ReloadOnSignal calls Reload whenever the process receives SIGUSR1.
+func ReloadOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	for range signals {
+		slog.Info("Received SIGUSR1, reloading configuration...")
+		if err := Reload(); err != nil {
+			slog.Error(fmt.Sprintf("configs: reload: %s", err))
+		}
+	}
+}
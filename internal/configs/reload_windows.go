@@ -0,0 +1,4 @@
+package configs
+
+// ReloadOnSignal does nothing, Windows has no SIGUSR1.
+func ReloadOnSignal() {}
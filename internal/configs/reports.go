@@ -0,0 +1,13 @@
+package configs
+
+import "github.com/lemmego/api/config"
+
+var reports = config.M{
+	// Disk the generated reports are stored on, empty for the default disk
+	"disk": config.MustEnv("REPORTS_DISK", ""),
+
+	// Reports larger than this are linked in the mail instead of attached,
+	// the signed link working for link_days
+	"max_attachment_mb": config.MustEnv("REPORTS_MAX_ATTACHMENT_MB", 10),
+	"link_days":         config.MustEnv("REPORTS_LINK_DAYS", 7),
+}
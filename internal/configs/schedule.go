@@ -0,0 +1,18 @@
+package configs
+
+import "github.com/lemmego/api/config"
+
+var schedule = config.M{
+	// Lease electing the instance that runs the scheduled tasks: "database"
+	// (the leases table), "redis" or "none" for a single instance
+	"lease": config.MustEnv("SCHEDULE_LEASE", "database"),
+
+	// Connection used by the database lease, empty for the default one
+	"connection": config.MustEnv("SCHEDULE_CONNECTION", ""),
+
+	// Seconds another instance waits to take over from a leader gone away
+	"ttl": config.MustEnv("SCHEDULE_LEASE_TTL", 15),
+
+	// Run the scheduler inside the web process, instead of schedule:run
+	"in_process": config.MustEnv("SCHEDULE_IN_PROCESS", false),
+}
@@ -0,0 +1,31 @@
+package configs
+
+import "github.com/lemmego/api/config"
+
+var search = config.M{
+	// none, pgsql, meilisearch or elasticsearch
+	"driver": config.MustEnv("SEARCH_DRIVER", "none"),
+	// Prepended to index names, so environments can share a server
+	"prefix": config.MustEnv("SEARCH_PREFIX", ""),
+	// Queue the index sync jobs run on, the default one when empty
+	"queue": config.MustEnv("SEARCH_QUEUE", ""),
+	// Seconds a request to the search server may take
+	"timeout": config.MustEnv("SEARCH_TIMEOUT", 10),
+
+	"pgsql": config.M{
+		// Database connection holding the search_documents table, empty for the default one
+		"connection": config.MustEnv("SEARCH_PGSQL_CONNECTION", ""),
+		// Text search configuration used for stemming, e.g. english or simple
+		"language": config.MustEnv("SEARCH_PGSQL_LANGUAGE", "english"),
+	},
+	"meilisearch": config.M{
+		"host": config.MustEnv("MEILISEARCH_HOST", "http://localhost:7700"),
+		"key":  config.MustEnv("MEILISEARCH_KEY", ""),
+	},
+	"elasticsearch": config.M{
+		"host":     config.MustEnv("ELASTICSEARCH_HOST", "http://localhost:9200"),
+		"api_key":  config.MustEnv("ELASTICSEARCH_API_KEY", ""),
+		"username": config.MustEnv("ELASTICSEARCH_USERNAME", ""),
+		"password": config.MustEnv("ELASTICSEARCH_PASSWORD", ""),
+	},
+}
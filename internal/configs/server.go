@@ -0,0 +1,134 @@
+package configs
+
+import (
+	"github.com/lemmego/api/config"
+)
+
+var server = config.M{
+	// Additional listeners served next to APP_PORT, e.g.:
+	// "internal": config.M{"network": "tcp", "address": "127.0.0.1:9090", "internal": true, "paths": "/metrics,/health"},
+	// "socket":   config.M{"network": "unix", "address": "./storage/app.sock"},
+	"listeners": config.M{},
+
+	// Comma separated path prefixes only reachable through listeners marked internal
+	"internal_paths": config.MustEnv("INTERNAL_PATHS", ""),
+
+	// Swap binaries without dropping connections: replace the executable and send SIGHUP.
+	// APP_PORT is then served by an inheritable listener instead of the framework's own.
+	"graceful_restart": config.MustEnv("GRACEFUL_RESTART", false),
+
+	"tls": config.M{
+		"enabled": config.MustEnv("TLS_ENABLED", false),
+		"port":    config.MustEnv("TLS_PORT", 8443),
+
+		// Certificate pair, leave empty to obtain certificates from Let's Encrypt
+		"cert_file": config.MustEnv("TLS_CERT_FILE", ""),
+		"key_file":  config.MustEnv("TLS_KEY_FILE", ""),
+
+		// Comma separated domains to request Let's Encrypt certificates for
+		"autocert_domains": config.MustEnv("TLS_AUTOCERT_DOMAINS", ""),
+		"autocert_email":   config.MustEnv("TLS_AUTOCERT_EMAIL", ""),
+		"autocert_cache":   "./storage/certs",
+
+		// Ask clients for certificates of this CA bundle, for routes guarded by
+		// auth.RequireClientCert
+		"client_ca_file": config.MustEnv("MTLS_CA_FILE", ""),
+
+		// Redirect requests on the plain HTTP port (APP_PORT) to HTTPS
+		"redirect_http": config.MustEnv("TLS_REDIRECT_HTTP", true),
+
+		// Serve HTTP/3 over QUIC as well, requires building with -tags http3
+		"http3": config.MustEnv("TLS_HTTP3", false),
+	},
+
+	// Prometheus metrics, e.g. database pool saturation
+	"metrics": config.M{
+		"enabled": config.MustEnv("METRICS_ENABLED", false),
+		"path":    config.MustEnv("METRICS_PATH", "/metrics"),
+		// Seconds between samples of goroutines, heap and GC pauses, 0 disables them
+		"runtime_interval": config.MustEnv("METRICS_RUNTIME_INTERVAL", 15),
+	},
+
+	// pprof under /debug/pprof/ and expvar under /debug/vars, reachable through
+	// internal listeners, or anywhere with the token sent as a bearer token
+	"debug": config.M{
+		"enabled": config.MustEnv("DEBUG_ENDPOINTS", false),
+		"token":   config.MustEnv("DEBUG_TOKEN", ""),
+	},
+
+	// Keep the last requests with their responses, to export them as HAR or
+	// replay them under /debug/requests, guarded like the debug endpoints.
+	// For development and staging only: requests are kept as sent.
+	"capture": config.M{
+		"enabled": config.MustEnv("CAPTURE_REQUESTS", false),
+		"size":    config.MustEnv("CAPTURE_SIZE", 100),
+		// Kilobytes kept of each body, bodies of binary content types are never kept
+		"max_body_kb": config.MustEnv("CAPTURE_MAX_BODY_KB", 64),
+		// Comma separated path prefixes not captured
+		"except": "/debug/,/public/,/storage/",
+		// Base URL requests are replayed against, APP_URL when empty
+		"replay_url": config.MustEnv("CAPTURE_REPLAY_URL", ""),
+	},
+
+	// Inject faults into a share of the requests, to check how clients cope
+	// with slow or failing responses. Never applied in production.
+	"chaos": config.M{
+		"enabled": config.MustEnv("CHAOS_ENABLED", false),
+		// Share of requests affected, from 0 to 1
+		"rate": config.MustEnv("CHAOS_RATE", 0.05),
+		// Comma separated faults picked from: latency, error, drop
+		"faults": config.MustEnv("CHAOS_FAULTS", "latency,error,drop"),
+		// Longest delay added by latency faults, in milliseconds
+		"latency_ms": config.MustEnv("CHAOS_LATENCY_MS", 2000),
+		// Status of error faults
+		"status": config.MustEnv("CHAOS_STATUS", 503),
+		// Comma separated path prefixes affected, every path when empty
+		"paths": config.MustEnv("CHAOS_PATHS", "/api/"),
+	},
+
+	// Seconds before the request context is cancelled, stopping queries, storage
+	// and outbound calls made with it (0 disables)
+	"request_timeout": config.MustEnv("REQUEST_TIMEOUT", 30),
+
+	// Comma separated addresses or CIDRs of proxies allowed to set X-Forwarded-For/X-Real-IP,
+	// use "*" to trust every peer
+	"trusted_proxies": config.MustEnv("TRUSTED_PROXIES", "127.0.0.1,::1"),
+
+	"ip_filter": config.M{
+		// Comma separated addresses or CIDRs, an empty allow list admits everyone
+		"allow": config.MustEnv("IP_ALLOW", ""),
+		"deny":  config.MustEnv("IP_DENY", ""),
+
+		// Comma separated ISO country codes, requires a MaxMind country database
+		"allow_countries": config.MustEnv("IP_ALLOW_COUNTRIES", ""),
+		"deny_countries":  config.MustEnv("IP_DENY_COUNTRIES", ""),
+		"geoip_database":  config.MustEnv("GEOIP_DATABASE", ""),
+	},
+
+	"body_limit": config.M{
+		// Maximum size of a regular request body in kilobytes, 0 disables the limit
+		"max_body_size": config.MustEnv("MAX_BODY_SIZE", 2048),
+
+		// Maximum size of a multipart/form-data request body in kilobytes, 0 disables the limit
+		"max_multipart_size": config.MustEnv("MAX_MULTIPART_SIZE", 20480),
+	},
+
+	"security_headers": config.M{
+		// Strict-Transport-Security max-age in seconds, 0 disables HSTS
+		"hsts_max_age": config.MustEnv("HSTS_MAX_AGE", 0),
+
+		"frame_options":   config.MustEnv("FRAME_OPTIONS", "SAMEORIGIN"),
+		"referrer_policy": config.MustEnv("REFERRER_POLICY", "strict-origin-when-cross-origin"),
+
+		// Send the Content-Security-Policy in report-only mode
+		"csp_report_only": config.MustEnv("CSP_REPORT_ONLY", false),
+	},
+
+	"idempotency": config.M{
+		// Supported: "memory", "database"
+		"store": config.MustEnv("IDEMPOTENCY_STORE", "memory"),
+
+		// Number of hours a stored response is replayed for retries
+		"ttl": config.MustEnv("IDEMPOTENCY_TTL", 24),
+	},
+}
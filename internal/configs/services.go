@@ -0,0 +1,37 @@
+package configs
+
+import (
+	"github.com/lemmego/api/config"
+)
+
+// Credentials for third party services
+var services = config.M{
+	"captcha": config.M{
+		// Supported: "", "hcaptcha", "turnstile"
+		"provider": config.MustEnv("CAPTCHA_PROVIDER", ""),
+		"site_key": config.MustEnv("CAPTCHA_SITE_KEY", ""),
+		"secret":   config.MustEnv("CAPTCHA_SECRET", ""),
+	},
+	// Bearer token identity providers provision users with, SCIM is off when empty
+	"scim": config.M{
+		"token": config.MustEnv("SCIM_TOKEN", ""),
+	},
+	"stripe": config.M{
+		"secret":         config.MustEnv("STRIPE_SECRET", ""),
+		"webhook_secret": config.MustEnv("STRIPE_WEBHOOK_SECRET", ""),
+		// Empty for the live API, or the address of stripe-mock
+		"base_url": config.MustEnv("STRIPE_BASE_URL", ""),
+	},
+	// Inbound mail, received at /webhooks/inbound/<service> by the services
+	// configured. Messages are also bound by the body limits of the server.
+	"inbound": config.M{
+		"mailgun_signing_key": config.MustEnv("MAILGUN_WEBHOOK_SIGNING_KEY", ""),
+		// Credentials in the webhook URL of the inbound stream
+		"postmark_username": config.MustEnv("POSTMARK_INBOUND_USERNAME", ""),
+		"postmark_password": config.MustEnv("POSTMARK_INBOUND_PASSWORD", ""),
+		// SNS topic the SES receipt rule notifies
+		"ses_topic_arn": config.MustEnv("SES_INBOUND_TOPIC_ARN", ""),
+		// Largest message accepted in megabytes, attachments included
+		"max_size": config.MustEnv("INBOUND_MAX_SIZE", 25),
+	},
+}
@@ -0,0 +1,25 @@
+package configs
+
+import "github.com/lemmego/api/config"
+
+var sms = config.M{
+	// log, fake, twilio, vonage or sns
+	"driver": config.MustEnv("SMS_DRIVER", "log"),
+	// Number or alphanumeric sender id messages are sent from
+	"from": config.MustEnv("SMS_FROM", ""),
+	// Region of national numbers, e.g. US, international ones only when empty
+	"region": config.MustEnv("SMS_REGION", ""),
+
+	"twilio": config.M{
+		"account_sid": config.MustEnv("TWILIO_ACCOUNT_SID", ""),
+		"auth_token":  config.MustEnv("TWILIO_AUTH_TOKEN", ""),
+	},
+	"vonage": config.M{
+		"key":    config.MustEnv("VONAGE_API_KEY", ""),
+		"secret": config.MustEnv("VONAGE_API_SECRET", ""),
+	},
+	// Credentials are the default ones of the AWS SDK
+	"sns": config.M{
+		"region": config.MustEnv("AWS_DEFAULT_REGION", ""),
+	},
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"database/sql"
+	"github.com/lemmego/migration"
+)
+
+func init() {
+	migration.GetMigrator().AddMigration(&migration.Migration{
+		Version: "20261018090000",
+		Up:      mig_20261018090000_create_idempotency_keys_table_up,
+		Down:    mig_20261018090000_create_idempotency_keys_table_down,
+	})
+}
+
+func mig_20261018090000_create_idempotency_keys_table_up(tx *sql.Tx) error {
+	schema := migration.Create("idempotency_keys", func(t *migration.Table) {
+		t.String("idempotency_key", 255).Primary()
+		t.String("fingerprint", 64)
+		t.Int("status").Default(0)
+		t.Text("headers").Nullable()
+		t.Binary("body").Nullable()
+		t.Timestamp("expires_at", 6)
+	}).Build()
+
+	if _, err := tx.Exec(schema); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func mig_20261018090000_create_idempotency_keys_table_down(tx *sql.Tx) error {
+	schema := migration.Drop("idempotency_keys").Build()
+	if _, err := tx.Exec(schema); err != nil {
+		return err
+	}
+	return nil
+}
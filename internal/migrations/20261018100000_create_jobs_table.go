@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"database/sql"
+	"github.com/lemmego/migration"
+)
+
+func init() {
+	migration.GetMigrator().AddMigration(&migration.Migration{
+		Version: "20261018100000",
+		Up:      mig_20261018100000_create_jobs_table_up,
+		Down:    mig_20261018100000_create_jobs_table_down,
+	})
+}
+
+func mig_20261018100000_create_jobs_table_up(tx *sql.Tx) error {
+	jobs := migration.Create("jobs", func(t *migration.Table) {
+		t.String("id", 32).Primary()
+		t.String("queue", 100)
+		t.String("job", 255)
+		t.Text("payload")
+		t.Int("attempts").Default(0)
+		t.Int("max_attempts").Default(1)
+		t.Timestamp("available_at", 6)
+		t.Timestamp("reserved_at", 6).Nullable()
+		t.Timestamp("created_at", 6)
+	}).Build()
+
+	failedJobs := migration.Create("failed_jobs", func(t *migration.Table) {
+		t.String("id", 32).Primary()
+		t.String("queue", 100)
+		t.String("job", 255)
+		t.Text("payload")
+		t.Int("attempts").Default(0)
+		t.Text("exception")
+		t.Timestamp("failed_at", 6)
+	}).Build()
+
+	for _, schema := range []string{jobs, failedJobs} {
+		if _, err := tx.Exec(schema); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func mig_20261018100000_create_jobs_table_down(tx *sql.Tx) error {
+	for _, table := range []string{"failed_jobs", "jobs"} {
+		if _, err := tx.Exec(migration.Drop(table).Build()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
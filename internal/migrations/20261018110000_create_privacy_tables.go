@@ -0,0 +1,59 @@
+package migrations
+
+import (
+	"database/sql"
+	"github.com/lemmego/migration"
+)
+
+func init() {
+	migration.GetMigrator().AddMigration(&migration.Migration{
+		Version: "20261018110000",
+		Up:      mig_20261018110000_create_privacy_tables_up,
+		Down:    mig_20261018110000_create_privacy_tables_down,
+	})
+}
+
+func mig_20261018110000_create_privacy_tables_up(tx *sql.Tx) error {
+	// Timestamps have no precision: the SQLite driver only scans columns
+	// declared exactly TIMESTAMP into time.Time
+	audits := migration.Create("privacy_audits", func(t *migration.Table) {
+		t.BigIncrements("id").Primary()
+		t.String("subject", 255)
+		t.String("action", 50)
+		t.String("actor", 255)
+		t.Text("details")
+		t.Timestamp("created_at", 0)
+	}).Build()
+
+	consents := migration.Create("privacy_consents", func(t *migration.Table) {
+		t.BigIncrements("id").Primary()
+		t.String("subject", 255)
+		t.String("purpose", 100)
+		t.Boolean("granted").Default(false)
+		t.Timestamp("granted_at", 0).Nullable()
+		t.Timestamp("revoked_at", 0).Nullable()
+		t.Timestamp("updated_at", 0)
+	}).Build()
+
+	for _, schema := range []string{
+		audits,
+		consents,
+		"CREATE INDEX privacy_audits_subject ON privacy_audits (subject)",
+		"CREATE UNIQUE INDEX privacy_consents_subject_purpose ON privacy_consents (subject, purpose)",
+	} {
+		if _, err := tx.Exec(schema); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func mig_20261018110000_create_privacy_tables_down(tx *sql.Tx) error {
+	for _, table := range []string{"privacy_consents", "privacy_audits"} {
+		if _, err := tx.Exec(migration.Drop(table).Build()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
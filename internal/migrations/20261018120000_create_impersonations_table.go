@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"database/sql"
+	"github.com/lemmego/migration"
+)
+
+func init() {
+	migration.GetMigrator().AddMigration(&migration.Migration{
+		Version: "20261018120000",
+		Up:      mig_20261018120000_create_impersonations_table_up,
+		Down:    mig_20261018120000_create_impersonations_table_down,
+	})
+}
+
+func mig_20261018120000_create_impersonations_table_up(tx *sql.Tx) error {
+	schema := migration.Create("impersonations", func(t *migration.Table) {
+		t.BigIncrements("id").Primary()
+		t.String("impersonator_id", 255)
+		t.String("user_id", 255)
+		t.String("action", 20)
+		t.String("ip", 45)
+		t.Text("user_agent")
+		// No precision, see the privacy tables
+		t.Timestamp("created_at", 0)
+	}).Build()
+
+	for _, statement := range []string{
+		schema,
+		"CREATE INDEX impersonations_impersonator_id ON impersonations (impersonator_id)",
+		"CREATE INDEX impersonations_user_id ON impersonations (user_id)",
+	} {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mig_20261018120000_create_impersonations_table_down(tx *sql.Tx) error {
+	_, err := tx.Exec(migration.Drop("impersonations").Build())
+	return err
+}
@@ -0,0 +1,70 @@
+package migrations
+
+import (
+	"database/sql"
+	"github.com/lemmego/migration"
+)
+
+func init() {
+	migration.GetMigrator().AddMigration(&migration.Migration{
+		Version: "20261018130000",
+		Up:      mig_20261018130000_create_organizations_tables_up,
+		Down:    mig_20261018130000_create_organizations_tables_down,
+	})
+}
+
+func mig_20261018130000_create_organizations_tables_up(tx *sql.Tx) error {
+	// No precision, see the privacy tables
+	organizations := migration.Create("organizations", func(t *migration.Table) {
+		t.BigIncrements("id").Primary()
+		t.String("name", 255)
+		t.String("slug", 255)
+		t.Timestamp("created_at", 0)
+		t.Timestamp("updated_at", 0)
+	}).Build()
+
+	members := migration.Create("organization_members", func(t *migration.Table) {
+		t.BigIncrements("id").Primary()
+		t.BigInt("organization_id")
+		t.String("user_id", 255)
+		t.String("role", 20)
+		t.Timestamp("created_at", 0)
+		t.Timestamp("updated_at", 0)
+	}).Build()
+
+	invitations := migration.Create("organization_invitations", func(t *migration.Table) {
+		t.BigIncrements("id").Primary()
+		t.BigInt("organization_id")
+		t.String("email", 255)
+		t.String("role", 20)
+		t.String("invited_by", 255)
+		t.Timestamp("expires_at", 0)
+		t.Timestamp("accepted_at", 0).Nullable()
+		t.Timestamp("created_at", 0)
+	}).Build()
+
+	for _, schema := range []string{
+		organizations,
+		members,
+		invitations,
+		"CREATE UNIQUE INDEX organizations_slug ON organizations (slug)",
+		"CREATE UNIQUE INDEX organization_members_organization_id_user_id ON organization_members (organization_id, user_id)",
+		"CREATE INDEX organization_members_user_id ON organization_members (user_id)",
+		"CREATE INDEX organization_invitations_organization_id ON organization_invitations (organization_id)",
+	} {
+		if _, err := tx.Exec(schema); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func mig_20261018130000_create_organizations_tables_down(tx *sql.Tx) error {
+	for _, table := range []string{"organization_invitations", "organization_members", "organizations"} {
+		if _, err := tx.Exec(migration.Drop(table).Build()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package migrations
+
+import (
+	"database/sql"
+	"github.com/lemmego/migration"
+)
+
+func init() {
+	migration.GetMigrator().AddMigration(&migration.Migration{
+		Version: "20261018140000",
+		Up:      mig_20261018140000_create_billing_tables_up,
+		Down:    mig_20261018140000_create_billing_tables_down,
+	})
+}
+
+func mig_20261018140000_create_billing_tables_up(tx *sql.Tx) error {
+	// No precision, see the privacy tables
+	customers := migration.Create("billing_customers", func(t *migration.Table) {
+		t.BigIncrements("id").Primary()
+		t.String("billable_id", 255)
+		t.String("stripe_id", 255)
+		t.Timestamp("created_at", 0)
+		t.Timestamp("updated_at", 0)
+	}).Build()
+
+	subscriptions := migration.Create("billing_subscriptions", func(t *migration.Table) {
+		t.BigIncrements("id").Primary()
+		t.String("billable_id", 255)
+		t.String("stripe_id", 255)
+		t.String("status", 50)
+		t.String("price", 255)
+		t.Int("quantity").Default(0)
+		t.String("metered_item", 255)
+		t.Timestamp("trial_ends_at", 0).Nullable()
+		t.Timestamp("ends_at", 0).Nullable()
+		t.Timestamp("created_at", 0)
+		t.Timestamp("updated_at", 0)
+	}).Build()
+
+	for _, schema := range []string{
+		customers,
+		subscriptions,
+		"CREATE UNIQUE INDEX billing_customers_billable_id ON billing_customers (billable_id)",
+		"CREATE UNIQUE INDEX billing_customers_stripe_id ON billing_customers (stripe_id)",
+		"CREATE UNIQUE INDEX billing_subscriptions_stripe_id ON billing_subscriptions (stripe_id)",
+		"CREATE INDEX billing_subscriptions_billable_id ON billing_subscriptions (billable_id)",
+	} {
+		if _, err := tx.Exec(schema); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func mig_20261018140000_create_billing_tables_down(tx *sql.Tx) error {
+	for _, table := range []string{"billing_subscriptions", "billing_customers"} {
+		if _, err := tx.Exec(migration.Drop(table).Build()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"database/sql"
+	"github.com/lemmego/migration"
+)
+
+func init() {
+	migration.GetMigrator().AddMigration(&migration.Migration{
+		Version: "20261018150000",
+		Up:      mig_20261018150000_create_data_migrations_table_up,
+		Down:    mig_20261018150000_create_data_migrations_table_down,
+	})
+}
+
+func mig_20261018150000_create_data_migrations_table_up(tx *sql.Tx) error {
+	schema := migration.Create("data_migrations", func(t *migration.Table) {
+		t.String("version", 32).Primary()
+		t.String("name", 255)
+		t.String("status", 20)
+		t.BigInt("total").Default(0)
+		t.BigInt("done").Default(0)
+		t.Text("error").Nullable()
+		// No precision, see the privacy tables
+		t.Timestamp("started_at", 0).Nullable()
+		t.Timestamp("finished_at", 0).Nullable()
+		t.Timestamp("created_at", 0)
+		t.Timestamp("updated_at", 0)
+	}).Build()
+
+	_, err := tx.Exec(schema)
+	return err
+}
+
+func mig_20261018150000_create_data_migrations_table_down(tx *sql.Tx) error {
+	_, err := tx.Exec(migration.Drop("data_migrations").Build())
+	return err
+}
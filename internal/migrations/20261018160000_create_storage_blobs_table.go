@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"database/sql"
+	"github.com/lemmego/migration"
+)
+
+func init() {
+	migration.GetMigrator().AddMigration(&migration.Migration{
+		Version: "20261018160000",
+		Up:      mig_20261018160000_create_storage_blobs_table_up,
+		Down:    mig_20261018160000_create_storage_blobs_table_down,
+	})
+}
+
+func mig_20261018160000_create_storage_blobs_table_up(tx *sql.Tx) error {
+	blobs := migration.Create("storage_blobs", func(t *migration.Table) {
+		t.BigIncrements("id").Primary()
+		t.String("disk", 255)
+		t.Char("hash", 64)
+		t.BigInt("size")
+		t.BigInt("refs").Default(0)
+		// No precision, see the privacy tables
+		t.Timestamp("created_at", 0)
+		t.Timestamp("updated_at", 0)
+	}).Build()
+
+	for _, schema := range []string{
+		blobs,
+		"CREATE UNIQUE INDEX storage_blobs_disk_hash ON storage_blobs (disk, hash)",
+		"CREATE INDEX storage_blobs_refs_updated_at ON storage_blobs (refs, updated_at)",
+	} {
+		if _, err := tx.Exec(schema); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func mig_20261018160000_create_storage_blobs_table_down(tx *sql.Tx) error {
+	_, err := tx.Exec(migration.Drop("storage_blobs").Build())
+	return err
+}
@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"database/sql"
+	"github.com/lemmego/lemmego/framework/ids"
+	"github.com/lemmego/migration"
+)
+
+func init() {
+	migration.GetMigrator().AddMigration(&migration.Migration{
+		Version: "20261018170000",
+		Up:      mig_20261018170000_create_user_sessions_table_up,
+		Down:    mig_20261018170000_create_user_sessions_table_down,
+	})
+}
+
+func mig_20261018170000_create_user_sessions_table_up(tx *sql.Tx) error {
+	schema := migration.Create("user_sessions", func(t *migration.Table) {
+		ids.ULIDColumn(t, "id").Primary()
+		t.String("user_id", 255)
+		t.String("name", 100)
+		t.Text("user_agent")
+		t.String("ip", 45)
+		// No precision, see the privacy tables
+		t.Timestamp("last_active_at", 0)
+		t.Timestamp("created_at", 0)
+	}).Build()
+
+	for _, statement := range []string{
+		schema,
+		"CREATE INDEX user_sessions_user_id ON user_sessions (user_id)",
+		"CREATE INDEX user_sessions_last_active_at ON user_sessions (last_active_at)",
+	} {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mig_20261018170000_create_user_sessions_table_down(tx *sql.Tx) error {
+	_, err := tx.Exec(migration.Drop("user_sessions").Build())
+	return err
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"database/sql"
+	"github.com/lemmego/migration"
+)
+
+func init() {
+	migration.GetMigrator().AddMigration(&migration.Migration{
+		Version: "20261018180000",
+		Up:      mig_20261018180000_create_api_tokens_table_up,
+		Down:    mig_20261018180000_create_api_tokens_table_down,
+	})
+}
+
+func mig_20261018180000_create_api_tokens_table_up(tx *sql.Tx) error {
+	schema := migration.Create("api_tokens", func(t *migration.Table) {
+		t.BigIncrements("id").Primary()
+		t.String("user_id", 255)
+		t.String("name", 255)
+		t.Char("hash", 64)
+		// No precision, see the privacy tables
+		t.Timestamp("expires_at", 0).Nullable()
+		t.Timestamp("created_at", 0)
+	}).Build()
+
+	for _, statement := range []string{
+		schema,
+		"CREATE UNIQUE INDEX api_tokens_hash ON api_tokens (hash)",
+		"CREATE INDEX api_tokens_user_id ON api_tokens (user_id)",
+	} {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mig_20261018180000_create_api_tokens_table_down(tx *sql.Tx) error {
+	_, err := tx.Exec(migration.Drop("api_tokens").Build())
+	return err
+}
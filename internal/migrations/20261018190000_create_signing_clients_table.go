@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"database/sql"
+	"github.com/lemmego/migration"
+)
+
+func init() {
+	migration.GetMigrator().AddMigration(&migration.Migration{
+		Version: "20261018190000",
+		Up:      mig_20261018190000_create_signing_clients_table_up,
+		Down:    mig_20261018190000_create_signing_clients_table_down,
+	})
+}
+
+func mig_20261018190000_create_signing_clients_table_up(tx *sql.Tx) error {
+	schema := migration.Create("signing_clients", func(t *migration.Table) {
+		t.BigIncrements("id").Primary()
+		t.String("client_key", 64)
+		t.String("name", 255)
+		// Encrypted with APP_KEY
+		t.Text("secret")
+		t.Text("previous_secret").Nullable()
+		// No precision, see the privacy tables
+		t.Timestamp("created_at", 0)
+		t.Timestamp("updated_at", 0)
+	}).Build()
+
+	for _, statement := range []string{
+		schema,
+		"CREATE UNIQUE INDEX signing_clients_client_key ON signing_clients (client_key)",
+	} {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mig_20261018190000_create_signing_clients_table_down(tx *sql.Tx) error {
+	_, err := tx.Exec(migration.Drop("signing_clients").Build())
+	return err
+}
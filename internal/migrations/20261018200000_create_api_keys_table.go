@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"database/sql"
+	"github.com/lemmego/migration"
+)
+
+func init() {
+	migration.GetMigrator().AddMigration(&migration.Migration{
+		Version: "20261018200000",
+		Up:      mig_20261018200000_create_api_keys_table_up,
+		Down:    mig_20261018200000_create_api_keys_table_down,
+	})
+}
+
+func mig_20261018200000_create_api_keys_table_up(tx *sql.Tx) error {
+	schema := migration.Create("api_keys", func(t *migration.Table) {
+		t.BigIncrements("id").Primary()
+		t.BigInt("organization_id")
+		t.String("name", 255)
+		t.String("hint", 20)
+		t.Char("hash", 64)
+		t.Text("scopes")
+		t.Int("rate_limit").Default(0)
+		// No precision, see the privacy tables
+		t.Timestamp("expires_at", 0).Nullable()
+		t.Timestamp("last_used_at", 0).Nullable()
+		t.String("created_by", 255)
+		t.Timestamp("created_at", 0)
+		t.Timestamp("updated_at", 0)
+	}).Build()
+
+	for _, statement := range []string{
+		schema,
+		"CREATE UNIQUE INDEX api_keys_hash ON api_keys (hash)",
+		"CREATE INDEX api_keys_organization_id ON api_keys (organization_id)",
+	} {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mig_20261018200000_create_api_keys_table_down(tx *sql.Tx) error {
+	_, err := tx.Exec(migration.Drop("api_keys").Build())
+	return err
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"database/sql"
+	"github.com/lemmego/migration"
+)
+
+func init() {
+	migration.GetMigrator().AddMigration(&migration.Migration{
+		Version: "20261018210000",
+		Up:      mig_20261018210000_create_leases_table_up,
+		Down:    mig_20261018210000_create_leases_table_down,
+	})
+}
+
+func mig_20261018210000_create_leases_table_up(tx *sql.Tx) error {
+	schema := migration.Create("leases", func(t *migration.Table) {
+		t.String("name", 100).Primary()
+		t.String("holder", 255)
+		// No precision, see the privacy tables
+		t.Timestamp("expires_at", 0)
+	}).Build()
+
+	_, err := tx.Exec(schema)
+	return err
+}
+
+func mig_20261018210000_create_leases_table_down(tx *sql.Tx) error {
+	_, err := tx.Exec(migration.Drop("leases").Build())
+	return err
+}
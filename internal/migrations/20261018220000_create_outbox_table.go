@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"database/sql"
+	"github.com/lemmego/migration"
+)
+
+func init() {
+	migration.GetMigrator().AddMigration(&migration.Migration{
+		Version: "20261018220000",
+		Up:      mig_20261018220000_create_outbox_table_up,
+		Down:    mig_20261018220000_create_outbox_table_down,
+	})
+}
+
+func mig_20261018220000_create_outbox_table_up(tx *sql.Tx) error {
+	schema := migration.Create("outbox", func(t *migration.Table) {
+		t.BigIncrements("id").Primary()
+		t.String("kind", 20)
+		t.String("name", 255)
+		t.Text("payload")
+		t.Int("attempts").Default(0)
+		t.Text("last_error").Nullable()
+		// No precision, see the privacy tables
+		t.Timestamp("available_at", 0)
+		t.String("claimed_by", 32).Nullable()
+		t.Timestamp("claimed_at", 0).Nullable()
+		t.Timestamp("created_at", 0)
+	}).Build()
+	if _, err := tx.Exec(schema); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(`CREATE INDEX outbox_available_at ON outbox (available_at)`)
+	return err
+}
+
+func mig_20261018220000_create_outbox_table_down(tx *sql.Tx) error {
+	_, err := tx.Exec(migration.Drop("outbox").Build())
+	return err
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"database/sql"
+	"github.com/lemmego/migration"
+)
+
+func init() {
+	migration.GetMigrator().AddMigration(&migration.Migration{
+		Version: "20261018230000",
+		Up:      mig_20261018230000_create_processed_messages_table_up,
+		Down:    mig_20261018230000_create_processed_messages_table_down,
+	})
+}
+
+func mig_20261018230000_create_processed_messages_table_up(tx *sql.Tx) error {
+	schema := migration.Create("processed_messages", func(t *migration.Table) {
+		t.BigIncrements("id").Primary()
+		t.String("consumer", 100)
+		t.String("message_id", 255)
+		// No precision, see the privacy tables
+		t.Timestamp("processed_at", 0)
+	}).Build()
+	if _, err := tx.Exec(schema); err != nil {
+		return err
+	}
+
+	// The unique index is what keeps two workers from processing a message
+	if _, err := tx.Exec(`CREATE UNIQUE INDEX processed_messages_consumer_message_id ON processed_messages (consumer, message_id)`); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`CREATE INDEX processed_messages_processed_at ON processed_messages (processed_at)`)
+	return err
+}
+
+func mig_20261018230000_create_processed_messages_table_down(tx *sql.Tx) error {
+	_, err := tx.Exec(migration.Drop("processed_messages").Build())
+	return err
+}
@@ -0,0 +1,62 @@
+package migrations
+
+import (
+	"database/sql"
+	"github.com/lemmego/migration"
+)
+
+func init() {
+	migration.GetMigrator().AddMigration(&migration.Migration{
+		Version: "20261019000000",
+		Up:      mig_20261019000000_create_push_tables_up,
+		Down:    mig_20261019000000_create_push_tables_down,
+	})
+}
+
+func mig_20261019000000_create_push_tables_up(tx *sql.Tx) error {
+	// No precision, see the privacy tables
+	devices := migration.Create("push_devices", func(t *migration.Table) {
+		t.BigIncrements("id").Primary()
+		t.String("user_id", 255)
+		t.String("platform", 10)
+		// Endpoints of web push subscriptions are URLs
+		t.String("token", 512)
+		t.String("p256dh", 255)
+		t.String("auth", 255)
+		t.String("name", 255)
+		t.Timestamp("invalid_at", 0).Nullable()
+		t.Timestamp("last_seen_at", 0)
+		t.Timestamp("created_at", 0)
+	}).Build()
+
+	subscriptions := migration.Create("push_subscriptions", func(t *migration.Table) {
+		t.BigIncrements("id").Primary()
+		t.BigInt("device_id")
+		t.String("topic", 100)
+		t.Timestamp("created_at", 0)
+	}).Build()
+
+	for _, schema := range []string{
+		devices,
+		subscriptions,
+		"CREATE UNIQUE INDEX push_devices_token ON push_devices (token)",
+		"CREATE INDEX push_devices_user_id ON push_devices (user_id)",
+		"CREATE UNIQUE INDEX push_subscriptions_device_id_topic ON push_subscriptions (device_id, topic)",
+		"CREATE INDEX push_subscriptions_topic ON push_subscriptions (topic)",
+	} {
+		if _, err := tx.Exec(schema); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func mig_20261019000000_create_push_tables_down(tx *sql.Tx) error {
+	for _, table := range []string{"push_subscriptions", "push_devices"} {
+		if _, err := tx.Exec(migration.Drop(table).Build()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
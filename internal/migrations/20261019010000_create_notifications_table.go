@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"database/sql"
+	"github.com/lemmego/lemmego/framework/ids"
+	"github.com/lemmego/migration"
+)
+
+func init() {
+	migration.GetMigrator().AddMigration(&migration.Migration{
+		Version: "20261019010000",
+		Up:      mig_20261019010000_create_notifications_table_up,
+		Down:    mig_20261019010000_create_notifications_table_down,
+	})
+}
+
+func mig_20261019010000_create_notifications_table_up(tx *sql.Tx) error {
+	schema := migration.Create("notifications", func(t *migration.Table) {
+		ids.ULIDColumn(t, "id").Primary()
+		t.String("user_id", 255)
+		t.String("type", 100)
+		t.String("title", 255)
+		t.Text("body")
+		t.String("url", 2048)
+		t.Text("data").Nullable()
+		// No precision, see the privacy tables
+		t.Timestamp("read_at", 0).Nullable()
+		t.Timestamp("created_at", 0)
+	}).Build()
+
+	for _, statement := range []string{
+		schema,
+		// Lists page through the ids of a user, counts filter on read_at
+		"CREATE INDEX notifications_user_id_id ON notifications (user_id, id)",
+		"CREATE INDEX notifications_user_id_read_at ON notifications (user_id, read_at)",
+		"CREATE INDEX notifications_created_at ON notifications (created_at)",
+	} {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mig_20261019010000_create_notifications_table_down(tx *sql.Tx) error {
+	_, err := tx.Exec(migration.Drop("notifications").Build())
+	return err
+}
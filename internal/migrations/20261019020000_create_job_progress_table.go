@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"database/sql"
+	"github.com/lemmego/migration"
+)
+
+func init() {
+	migration.GetMigrator().AddMigration(&migration.Migration{
+		Version: "20261019020000",
+		Up:      mig_20261019020000_create_job_progress_table_up,
+		Down:    mig_20261019020000_create_job_progress_table_down,
+	})
+}
+
+func mig_20261019020000_create_job_progress_table_up(tx *sql.Tx) error {
+	schema := migration.Create("job_progress", func(t *migration.Table) {
+		t.String("id", 32).Primary()
+		t.String("job", 255)
+		t.String("status", 20)
+		t.Int("percent").Default(0)
+		t.Text("message")
+		t.Text("error")
+		t.String("owner", 255)
+		t.Timestamp("updated_at", 6)
+		t.Timestamp("expires_at", 6)
+	}).Build()
+
+	for _, statement := range []string{
+		schema,
+		// Expired progress is pruned as jobs are dispatched
+		"CREATE INDEX job_progress_expires_at ON job_progress (expires_at)",
+	} {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mig_20261019020000_create_job_progress_table_down(tx *sql.Tx) error {
+	_, err := tx.Exec(migration.Drop("job_progress").Build())
+	return err
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"database/sql"
+	"github.com/lemmego/migration"
+)
+
+func init() {
+	migration.GetMigrator().AddMigration(&migration.Migration{
+		Version: "20261019030000",
+		Up:      mig_20261019030000_create_used_nonces_table_up,
+		Down:    mig_20261019030000_create_used_nonces_table_down,
+	})
+}
+
+func mig_20261019030000_create_used_nonces_table_up(tx *sql.Tx) error {
+	schema := migration.Create("used_nonces", func(t *migration.Table) {
+		// The client key and the nonce of signed requests
+		t.String("nonce", 129).Primary()
+		t.Timestamp("expires_at", 0)
+	}).Build()
+
+	for _, statement := range []string{
+		schema,
+		"CREATE INDEX used_nonces_expires_at ON used_nonces (expires_at)",
+	} {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mig_20261019030000_create_used_nonces_table_down(tx *sql.Tx) error {
+	_, err := tx.Exec(migration.Drop("used_nonces").Build())
+	return err
+}
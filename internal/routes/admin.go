@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/lemmego/api/app"
+)
+
+// adminRoutes mounts the CRUD screens of the admin panel, e.g.:
+//
+//	panel := admin.New("/admin", admin.PolicyFunc(func(c *app.Context, action admin.Action, model any) bool {
+//		u, ok := c.AuthUser().(*models.User)
+//		return ok && u.IsAdmin
+//	}))
+//	admin.Register[models.Post](panel, admin.Resource{PerPage: 50})
+//	panel.Mount(r)
+func adminRoutes(r app.Router) {
+}
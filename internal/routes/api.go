@@ -1,14 +1,26 @@
 package routes
 
 import (
+	"net/http"
+
 	"github.com/lemmego/api/app"
+
+	"github.com/lemmego/lemmego/framework/jsonx"
+	"github.com/lemmego/lemmego/framework/versioning"
 )
 
+type pong struct {
+	Message string `json:"message"`
+}
+
 func apiRoutes(r app.Router) {
-	apiGroup := r.Group("/api")
-	{
-		apiGroup.Get("/ping", func(c *app.Context) error {
-			return app.M{"message": "pong"}
-		})
-	}
+	api := versioning.New(r, "/api")
+	// Unversioned requests, like /api/ping, are served by v1
+	api.Default = "v1"
+
+	api.Version("v1", func(v *versioning.Version) {
+		v.Get("/ping", func(c *app.Context) error {
+			return jsonx.OK(c, pong{Message: "pong"})
+		}).Summary("Check that the API is up").Returns(http.StatusOK, pong{})
+	})
 }
@@ -0,0 +1,113 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lemmego/api/app"
+
+	"github.com/lemmego/lemmego/framework/apikey"
+	"github.com/lemmego/lemmego/framework/auth"
+	"github.com/lemmego/lemmego/framework/jsonx"
+	"github.com/lemmego/lemmego/framework/org"
+)
+
+type apiKeyInput struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+	RateLimit     int      `json:"rate_limit"`
+}
+
+type rotateInput struct {
+	// GraceHours the old key goes on working, 24 when missing.
+	GraceHours *int `json:"grace_hours"`
+}
+
+// issuedKey is the answer to issuing a key, the only one showing it.
+type issuedKey struct {
+	Key    string      `json:"key"`
+	APIKey *apikey.Key `json:"api_key"`
+}
+
+// apiKeyRoutes lets the admins of an organization manage its API keys.
+func apiKeyRoutes(r app.Router) {
+	g := r.Group("/orgs/{org}/api-keys")
+	g.UseBefore(org.Require(org.Admin))
+
+	g.Get("", func(c *app.Context) error {
+		keys, err := apikey.List(c.RequestContext(), currentOrg(c))
+		if err != nil {
+			return err
+		}
+		return jsonx.OK(c, app.M{"api_keys": keys})
+	})
+
+	g.Post("", func(c *app.Context) error {
+		in := &apiKeyInput{}
+		if err := jsonx.Decode(c, in); err != nil {
+			return c.BadRequest(err)
+		}
+		if in.Name = strings.TrimSpace(in.Name); in.Name == "" || in.ExpiresInDays < 0 || in.RateLimit < 0 {
+			return jsonx.Write(c, http.StatusUnprocessableEntity, app.M{"message": "a name is required, and days and rate limit cannot be negative"})
+		}
+		plain, k, err := apikey.Issue(c.RequestContext(), currentOrg(c), &apikey.Options{
+			Name:      in.Name,
+			Scopes:    in.Scopes,
+			TTL:       time.Duration(in.ExpiresInDays) * 24 * time.Hour,
+			RateLimit: in.RateLimit,
+			CreatedBy: c.GetSessionString(auth.UserKey),
+		})
+		if err != nil {
+			return err
+		}
+		return jsonx.Write(c, http.StatusCreated, issuedKey{Key: plain, APIKey: k})
+	})
+
+	g.Post("/{id}/rotate", func(c *app.Context) error {
+		in := &rotateInput{}
+		if c.Request().ContentLength != 0 {
+			if err := jsonx.Decode(c, in); err != nil {
+				return c.BadRequest(err)
+			}
+		}
+		grace := 24 * time.Hour
+		if in.GraceHours != nil && *in.GraceHours >= 0 {
+			grace = time.Duration(*in.GraceHours) * time.Hour
+		}
+		plain, k, err := apikey.Rotate(c.RequestContext(), currentOrg(c), keyID(c), grace, c.GetSessionString(auth.UserKey))
+		if errors.Is(err, apikey.ErrNotFound) {
+			return c.NotFound(err)
+		}
+		if err != nil {
+			return err
+		}
+		return jsonx.Write(c, http.StatusCreated, issuedKey{Key: plain, APIKey: k})
+	})
+
+	g.Delete("/{id}", func(c *app.Context) error {
+		err := apikey.Revoke(c.RequestContext(), currentOrg(c), keyID(c))
+		if errors.Is(err, apikey.ErrNotFound) {
+			return c.NotFound(err)
+		}
+		if err != nil {
+			return err
+		}
+		return c.NoContent()
+	})
+}
+
+// currentOrg returns the organization checked by org.Require.
+func currentOrg(c *app.Context) uint {
+	m, _ := org.MembershipFrom(c.RequestContext())
+	return m.OrganizationID
+}
+
+// keyID returns the {id} parameter, 0 matching no key when malformed.
+func keyID(c *app.Context) uint {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 0)
+	return uint(id)
+}
@@ -0,0 +1,59 @@
+package routes
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/config"
+
+	"github.com/lemmego/lemmego/framework/billing"
+	"github.com/lemmego/lemmego/framework/jsonx"
+	"github.com/lemmego/lemmego/framework/webhook"
+)
+
+// billingRoutes receives Stripe webhooks and lists the invoices of the
+// signed in billable. Paid features are guarded with billing.RequirePlan.
+func billingRoutes(r app.Router) {
+	if secret := config.Get("services.stripe.webhook_secret", "").(string); secret != "" {
+		r.Post(webhook.Prefix+"stripe", webhook.Receive(billing.Signature(secret), billing.HandleWebhook))
+	}
+
+	g := r.Group("/billing")
+	g.Get("/invoices", func(c *app.Context) error {
+		id := billing.BillableID(c)
+		if id == "" {
+			return c.Unauthorized(errors.New("sign in to continue"))
+		}
+		limit, _ := strconv.Atoi(c.Query("limit"))
+		if limit <= 0 || limit > 100 {
+			limit = 24
+		}
+		invoices, err := billing.Invoices(c.RequestContext(), id, limit)
+		if errors.Is(err, billing.ErrNoCustomer) {
+			invoices, err = []billing.Invoice{}, nil
+		}
+		if err != nil {
+			return err
+		}
+		return jsonx.OK(c, app.M{"data": invoices})
+	})
+
+	g.Get("/invoices/{id}", func(c *app.Context) error {
+		id := billing.BillableID(c)
+		if id == "" {
+			return c.Unauthorized(errors.New("sign in to continue"))
+		}
+		invoice, err := billing.FindInvoice(c.RequestContext(), id, c.Param("id"))
+		if errors.Is(err, billing.ErrNoCustomer) {
+			return c.NotFound(err)
+		}
+		if err != nil {
+			return err
+		}
+		if c.Query("download") != "" && invoice.PDF != "" {
+			return c.Redirect(invoice.PDF)
+		}
+		return jsonx.OK(c, app.M{"data": invoice})
+	})
+}
@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/config"
+
+	"github.com/lemmego/lemmego/framework/capture"
+	appmiddleware "github.com/lemmego/lemmego/framework/middleware"
+)
+
+// debugRoutes exposes pprof, expvar and the captured requests to internal
+// listeners, or to requests carrying server.debug.token.
+func debugRoutes(r app.Router) {
+	guard := appmiddleware.DebugAccess(config.Get("server.debug.token", "").(string))
+
+	if config.Get("server.debug.enabled", false).(bool) {
+		handle := func(pattern string, h http.HandlerFunc) {
+			r.Handle(pattern, guard(h))
+		}
+		handle("GET /debug/pprof/", pprof.Index)
+		handle("GET /debug/pprof/cmdline", pprof.Cmdline)
+		handle("GET /debug/pprof/profile", pprof.Profile)
+		handle("GET /debug/pprof/symbol", pprof.Symbol)
+		handle("GET /debug/pprof/trace", pprof.Trace)
+		r.Handle("GET /debug/vars", guard(expvar.Handler()))
+	}
+
+	if config.Get("server.capture.enabled", false).(bool) {
+		base := config.Get("server.capture.replay_url", "").(string)
+		if base == "" {
+			base = config.Get("app.url", "").(string)
+		}
+		r.Handle("GET /debug/requests", guard(capture.ListHandler(capture.Default())))
+		r.Handle("GET /debug/requests/har", guard(capture.HARHandler(capture.Default())))
+		r.Handle("POST /debug/requests/{id}/replay", guard(capture.ReplayHandler(capture.Default(), base)))
+	}
+}
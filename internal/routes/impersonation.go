@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"errors"
+
+	"github.com/lemmego/api/app"
+
+	"github.com/lemmego/lemmego/framework/auth"
+)
+
+// impersonationRoutes serves the way back from impersonating a user, posted
+// by the banner of the base layout. Staff start impersonating from a route
+// of their own, e.g.:
+//
+//	r.Post("/users/{id}/impersonate", func(c *app.Context) error {
+//		staff, target := currentUser(c), findUser(c.Param("id"))
+//		if err := auth.Impersonate(c, staff, target); err != nil {
+//			return c.Forbidden(err)
+//		}
+//		return c.Redirect("/")
+//	})
+func impersonationRoutes(r app.Router) {
+	r.Post("/impersonate/stop", func(c *app.Context) error {
+		if err := auth.StopImpersonating(c); err != nil && !errors.Is(err, auth.ErrNotImpersonating) {
+			return err
+		}
+		return c.Redirect("/")
+	})
+}
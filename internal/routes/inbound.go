@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/config"
+
+	"github.com/lemmego/lemmego/framework/inbound"
+	"github.com/lemmego/lemmego/framework/webhook"
+)
+
+// inboundRoutes receives the emails sent to the application from the
+// services configured under services.inbound. Handlers are added with
+// inbound.Route.
+func inboundRoutes(r app.Router) {
+	inbound.MaxSize = int64(config.Get("services.inbound.max_size", 25).(int)) << 20
+
+	if key := config.Get("services.inbound.mailgun_signing_key", "").(string); key != "" {
+		r.Post(webhook.Prefix+"inbound/mailgun", inbound.Mailgun(key))
+	}
+	if user := config.Get("services.inbound.postmark_username", "").(string); user != "" {
+		r.Post(webhook.Prefix+"inbound/postmark", inbound.Postmark(user, config.Get("services.inbound.postmark_password", "").(string)))
+	}
+	if arn := config.Get("services.inbound.ses_topic_arn", "").(string); arn != "" {
+		r.Post(webhook.Prefix+"inbound/ses", inbound.SES(arn))
+	}
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/config"
+
+	"github.com/lemmego/lemmego/framework/metrics"
+)
+
+// metricsRoutes exposes the metrics registry. Keep it off the public port by
+// listing the path in server.internal_paths.
+func metricsRoutes(r app.Router) {
+	if !config.Get("server.metrics.enabled", false).(bool) {
+		return
+	}
+	r.Handle("GET "+config.Get("server.metrics.path", "/metrics").(string), metrics.Handler())
+}
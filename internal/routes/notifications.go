@@ -0,0 +1,100 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/lemmego/api/app"
+
+	"github.com/lemmego/lemmego/framework/api"
+	"github.com/lemmego/lemmego/framework/auth"
+	"github.com/lemmego/lemmego/framework/jsonx"
+	"github.com/lemmego/lemmego/framework/notifications"
+)
+
+type markReadInput struct {
+	IDs []string `json:"ids"`
+}
+
+var notificationResource api.Resource[notifications.Notification] = func(c *app.Context, n *notifications.Notification) api.M {
+	return api.M{
+		"id":         n.ID,
+		"type":       n.Type,
+		"title":      n.Title,
+		"body":       n.Body,
+		"url":        api.When(n.URL != "", n.URL),
+		"data":       api.When(len(n.Data) > 0, n.Data),
+		"read_at":    n.ReadAt,
+		"created_at": n.CreatedAt,
+	}
+}
+
+// notificationRoutes are the notification center of signed in users, the
+// impersonated one while impersonating. New notifications arrive over
+// WebSocket, see the notifications package.
+func notificationRoutes(r app.Router) {
+	g := r.Group("/account/notifications")
+	g.UseBefore(signedIn)
+
+	// Pages of 20, only the unread ones with ?unread=1
+	g.Get("", func(c *app.Context) error {
+		q := notifications.Query(c.RequestContext(), c.GetSessionString(auth.UserKey), c.Query("unread") == "1")
+		page, err := api.Paginate(c, q, 20)
+		if err != nil {
+			return err
+		}
+		return jsonx.OK(c, notificationResource.Page(c, page))
+	})
+
+	g.Get("/unread", func(c *app.Context) error {
+		n, err := notifications.Unread(c.RequestContext(), c.GetSessionString(auth.UserKey))
+		if err != nil {
+			return err
+		}
+		return jsonx.OK(c, app.M{"unread": n})
+	})
+
+	g.Post("/read", func(c *app.Context) error {
+		in := &markReadInput{}
+		if err := jsonx.Decode(c, in); err != nil {
+			return c.BadRequest(err)
+		}
+		if len(in.IDs) == 0 || len(in.IDs) > 100 {
+			return jsonx.Write(c, http.StatusUnprocessableEntity, app.M{"message": "between 1 and 100 ids are required"})
+		}
+		n, err := notifications.MarkRead(c.RequestContext(), c.GetSessionString(auth.UserKey), in.IDs...)
+		if err != nil {
+			return err
+		}
+		return jsonx.OK(c, app.M{"unread": n})
+	})
+
+	g.Post("/read-all", func(c *app.Context) error {
+		if err := notifications.MarkAllRead(c.RequestContext(), c.GetSessionString(auth.UserKey)); err != nil {
+			return err
+		}
+		return jsonx.OK(c, app.M{"unread": 0})
+	})
+
+	g.Post("/{id}/unread", func(c *app.Context) error {
+		n, err := notifications.MarkUnread(c.RequestContext(), c.GetSessionString(auth.UserKey), c.Param("id"))
+		if errors.Is(err, notifications.ErrNotFound) {
+			return c.NotFound(err)
+		}
+		if err != nil {
+			return err
+		}
+		return jsonx.OK(c, app.M{"unread": n})
+	})
+
+	g.Delete("/{id}", func(c *app.Context) error {
+		n, err := notifications.Delete(c.RequestContext(), c.GetSessionString(auth.UserKey), c.Param("id"))
+		if errors.Is(err, notifications.ErrNotFound) {
+			return c.NotFound(err)
+		}
+		if err != nil {
+			return err
+		}
+		return jsonx.OK(c, app.M{"unread": n})
+	})
+}
@@ -0,0 +1,90 @@
+package routes
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/lemmego/api/app"
+	"gorm.io/gorm"
+
+	"github.com/lemmego/lemmego/framework/auth"
+	"github.com/lemmego/lemmego/framework/org"
+	"github.com/lemmego/lemmego/framework/repo"
+	"github.com/lemmego/lemmego/framework/signed"
+	"github.com/lemmego/lemmego/framework/templates"
+)
+
+// orgRoutes accepts invitations, once org.UserEmail reads the email address
+// of users, and switches the current organization. Routes of an organization go in a group guarded by org.Require, e.g.:
+//
+//	g := r.Group("/orgs/{org}")
+//	g.UseBefore(org.Require(org.Member))
+//	g.Get("/projects", listProjects)
+func orgRoutes(r app.Router) {
+	// Accepting changes state, so the signed link opens a confirmation posting
+	// back to it, which the CSRF check covers
+	r.Get("/invitations/{id}/accept", func(c *app.Context) error {
+		if c.GetSessionString(auth.UserKey) == "" {
+			return c.Unauthorized(errors.New("sign in to accept the invitation"))
+		}
+		id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+		if err != nil {
+			return c.NotFound(err)
+		}
+
+		inv, err := org.FindInvitation(c.RequestContext(), uint(id))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.NotFound(err)
+		}
+		if err != nil {
+			return err
+		}
+		orgs, err := repo.New[org.Organization](c.RequestContext()).Where("id = ?", inv.OrganizationID).Limit(1).Find()
+		if err != nil {
+			return err
+		}
+		if len(orgs) == 0 {
+			return c.NotFound(gorm.ErrRecordNotFound)
+		}
+		return c.Templ(templates.BaseLayout(templates.AcceptInvitation(c.Request().URL.RequestURI(), orgs[0].Name, inv.Email, inv.Role)))
+	}).UseBefore(signed.Verify)
+
+	r.Post("/invitations/{id}/accept", func(c *app.Context) error {
+		user := c.GetSessionString(auth.UserKey)
+		if user == "" {
+			return c.Unauthorized(errors.New("sign in to accept the invitation"))
+		}
+		id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+		if err != nil {
+			return c.NotFound(err)
+		}
+
+		m, err := org.Accept(c.RequestContext(), uint(id), user)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.NotFound(err)
+		case errors.Is(err, org.ErrInvitationExpired), errors.Is(err, org.ErrInvitationAccepted), errors.Is(err, org.ErrInvitationEmail):
+			return c.Forbidden(err)
+		case err != nil:
+			return err
+		}
+		if err := org.Switch(c, m.OrganizationID); err != nil {
+			return err
+		}
+		return c.Redirect("/")
+	}).UseBefore(signed.Verify)
+
+	r.Post("/orgs/{id}/switch", func(c *app.Context) error {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+		if err != nil {
+			return c.NotFound(err)
+		}
+		if err := org.Switch(c, uint(id)); err != nil {
+			if errors.Is(err, org.ErrNotMember) {
+				return c.Forbidden(err)
+			}
+			return err
+		}
+		return c.Back()
+	})
+}
@@ -0,0 +1,129 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/config"
+
+	"github.com/lemmego/lemmego/framework/auth"
+	"github.com/lemmego/lemmego/framework/jsonx"
+	"github.com/lemmego/lemmego/framework/push"
+)
+
+// deviceInput registers an app by its token, or a browser by its push
+// subscription as PushSubscription.toJSON gives it.
+type deviceInput struct {
+	Platform string `json:"platform"`
+	Token    string `json:"token"`
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+	Name string `json:"name"`
+}
+
+// pushRoutes lets signed in users register the devices they get push
+// notifications on, and subscribe them to the public topics.
+func pushRoutes(r app.Router) {
+	// Browsers subscribe with the key before anyone signs in
+	r.Get("/push/vapid-key", func(c *app.Context) error {
+		return jsonx.OK(c, app.M{"public_key": config.Get("push.webpush.public_key", "").(string)})
+	})
+
+	g := r.Group("/account/push/devices")
+	g.UseBefore(signedIn)
+
+	g.Get("", func(c *app.Context) error {
+		devices, err := push.Devices(c.RequestContext(), auth.SessionOwner(c))
+		if err != nil {
+			return err
+		}
+		return jsonx.OK(c, app.M{"devices": devices})
+	})
+
+	g.Post("", func(c *app.Context) error {
+		in := &deviceInput{}
+		if err := jsonx.Decode(c, in); err != nil {
+			return c.BadRequest(err)
+		}
+		d := &push.Device{Platform: in.Platform, Token: in.Token, P256dh: in.Keys.P256dh, Auth: in.Keys.Auth, Name: in.Name}
+		if in.Endpoint != "" {
+			d.Platform, d.Token = push.PlatformWebPush, in.Endpoint
+		}
+		d, err := push.Register(c.RequestContext(), auth.SessionOwner(c), d)
+		if err != nil {
+			if errors.Is(err, push.ErrInvalidDevice) {
+				return jsonx.Write(c, http.StatusUnprocessableEntity, app.M{"message": err.Error()})
+			}
+			return err
+		}
+		return jsonx.Write(c, http.StatusCreated, app.M{"device": d})
+	})
+
+	g.Delete("/{id}", func(c *app.Context) error {
+		err := push.Unregister(c.RequestContext(), auth.SessionOwner(c), deviceID(c))
+		if errors.Is(err, push.ErrUnknownDevice) {
+			return c.NotFound(err)
+		}
+		if err != nil {
+			return err
+		}
+		return c.NoContent()
+	})
+
+	g.Get("/{id}/topics", func(c *app.Context) error {
+		d, err := push.DeviceOf(c.RequestContext(), auth.SessionOwner(c), deviceID(c))
+		if errors.Is(err, push.ErrUnknownDevice) {
+			return c.NotFound(err)
+		}
+		if err != nil {
+			return err
+		}
+		topics, err := push.Topics(c.RequestContext(), d.ID)
+		if err != nil {
+			return err
+		}
+		return jsonx.OK(c, app.M{"topics": topics})
+	})
+
+	g.Put("/{id}/topics/{topic}", func(c *app.Context) error {
+		d, err := push.DeviceOf(c.RequestContext(), auth.SessionOwner(c), deviceID(c))
+		if errors.Is(err, push.ErrUnknownDevice) {
+			return c.NotFound(err)
+		}
+		if err != nil {
+			return err
+		}
+		if !push.IsPublic(c.Param("topic")) {
+			return c.Forbidden(errors.New("this topic cannot be subscribed to"))
+		}
+		if err := push.Subscribe(c.RequestContext(), d.ID, c.Param("topic")); err != nil {
+			return err
+		}
+		return c.NoContent()
+	})
+
+	g.Delete("/{id}/topics/{topic}", func(c *app.Context) error {
+		d, err := push.DeviceOf(c.RequestContext(), auth.SessionOwner(c), deviceID(c))
+		if errors.Is(err, push.ErrUnknownDevice) {
+			return c.NotFound(err)
+		}
+		if err != nil {
+			return err
+		}
+		if err := push.Unsubscribe(c.RequestContext(), d.ID, c.Param("topic")); err != nil {
+			return err
+		}
+		return c.NoContent()
+	})
+}
+
+// deviceID returns the {id} parameter, 0 matching no device when malformed.
+func deviceID(c *app.Context) uint {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 0)
+	return uint(id)
+}
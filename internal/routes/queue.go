@@ -0,0 +1,149 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/config"
+
+	appmiddleware "github.com/lemmego/lemmego/framework/middleware"
+	"github.com/lemmego/lemmego/framework/queue"
+)
+
+// queueRoutes let operators browse, retry and prune the failed jobs, guarded
+// like the debug endpoints. Lists take the queue, job, limit and offset
+// query parameters, and so does bulk retry, which retries every match.
+func queueRoutes(r app.Router) {
+	guard := appmiddleware.DebugAccess(config.Get("server.debug.token", "").(string))
+	handle := func(pattern string, h func(w http.ResponseWriter, r *http.Request, store queue.FailedStore) error) {
+		r.Handle(pattern, guard(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			store, err := queue.Default().Failed()
+			if err == nil {
+				err = h(w, r, store)
+			}
+			switch {
+			case errors.Is(err, queue.ErrNotFailed):
+				writeFailedJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			case errors.Is(err, queue.ErrNoFailedStore), errors.Is(err, errBadQuery):
+				writeFailedJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			case err != nil:
+				writeFailedJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			}
+		})))
+	}
+
+	handle("GET /queue/failed", func(w http.ResponseWriter, r *http.Request, store queue.FailedStore) error {
+		q, err := failedQuery(r)
+		if err != nil {
+			return err
+		}
+		jobs, err := store.Failed(r.Context(), q)
+		if err != nil {
+			return err
+		}
+		list := make([]failedJob, len(jobs))
+		for i := range jobs {
+			list[i] = newFailedJob(&jobs[i])
+		}
+		writeFailedJSON(w, http.StatusOK, map[string]any{"jobs": list})
+		return nil
+	})
+
+	handle("GET /queue/failed/{id}", func(w http.ResponseWriter, r *http.Request, store queue.FailedStore) error {
+		job, err := store.FindFailed(r.Context(), r.PathValue("id"))
+		if err != nil {
+			return err
+		}
+		writeFailedJSON(w, http.StatusOK, newFailedJob(job))
+		return nil
+	})
+
+	handle("POST /queue/failed/{id}/retry", func(w http.ResponseWriter, r *http.Request, store queue.FailedStore) error {
+		if err := store.Retry(r.Context(), r.PathValue("id")); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	})
+
+	handle("POST /queue/failed/retry", func(w http.ResponseWriter, r *http.Request, store queue.FailedStore) error {
+		q, err := failedQuery(r)
+		if err != nil {
+			return err
+		}
+		n, err := queue.RetryFailed(r.Context(), store, q)
+		if err != nil {
+			return err
+		}
+		writeFailedJSON(w, http.StatusOK, map[string]int{"retried": n})
+		return nil
+	})
+
+	handle("DELETE /queue/failed/{id}", func(w http.ResponseWriter, r *http.Request, store queue.FailedStore) error {
+		if err := store.Forget(r.Context(), r.PathValue("id")); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	})
+
+	// Prunes the jobs that failed longer ago than the older_than duration, a week by default
+	handle("DELETE /queue/failed", func(w http.ResponseWriter, r *http.Request, store queue.FailedStore) error {
+		olderThan := 7 * 24 * time.Hour
+		if v := r.URL.Query().Get("older_than"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return errBadQuery
+			}
+			olderThan = d
+		}
+		n, err := store.Prune(r.Context(), time.Now().Add(-olderThan))
+		if err != nil {
+			return err
+		}
+		writeFailedJSON(w, http.StatusOK, map[string]int64{"pruned": n})
+		return nil
+	})
+}
+
+var errBadQuery = errors.New("invalid query parameter")
+
+func failedQuery(r *http.Request) (*queue.FailedQuery, error) {
+	values := r.URL.Query()
+	q := &queue.FailedQuery{Queue: values.Get("queue"), Job: values.Get("job")}
+	for name, dst := range map[string]*int{"limit": &q.Limit, "offset": &q.Offset} {
+		if v := values.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return nil, errBadQuery
+			}
+			*dst = n
+		}
+	}
+	q.Limit = min(q.Limit, 500)
+	return q, nil
+}
+
+// failedJob shows the payload of a failed job as JSON rather than a string.
+type failedJob struct {
+	*queue.FailedJob
+	Payload json.RawMessage `json:"payload"`
+}
+
+func newFailedJob(job *queue.FailedJob) failedJob {
+	payload := json.RawMessage(job.Payload)
+	if !json.Valid(payload) {
+		payload, _ = json.Marshal(job.Payload)
+	}
+	return failedJob{FailedJob: job, Payload: payload}
+}
+
+func writeFailedJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,56 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/lemmego/api/app"
+
+	"github.com/lemmego/lemmego/framework/export"
+	"github.com/lemmego/lemmego/framework/report"
+	"github.com/lemmego/lemmego/framework/signed"
+)
+
+var errReportNotFound = errors.New("report not found")
+
+// reportRoutes serves the reports too large to be mailed, through the
+// signed links mailed instead.
+func reportRoutes(r app.Router) {
+	r.Get("/reports/{name}/{file}", func(c *app.Context) error {
+		name, file := c.Param("name"), c.Param("file")
+		if strings.ContainsAny(name+file, `/\`) || strings.HasPrefix(name, ".") || strings.HasPrefix(file, ".") {
+			return c.NotFound(errReportNotFound)
+		}
+		disk, err := report.Disk(c.RequestContext())
+		if err != nil {
+			return err
+		}
+		p := path.Join(report.Dir, name, file)
+		if ok, err := disk.Exists(p); err != nil {
+			return err
+		} else if !ok {
+			return c.NotFound(errReportNotFound)
+		}
+		f, err := disk.Read(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		format := export.CSV
+		if path.Ext(file) == ".xlsx" {
+			format = export.XLSX
+		}
+		w := c.ResponseWriter()
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, file))
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		_, err = io.Copy(w, f)
+		return err
+	}).UseBefore(signed.Verify)
+}
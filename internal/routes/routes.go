@@ -1,18 +1,178 @@
 package routes
 
 import (
+	"net/http"
+	"strings"
+	"time"
+
 	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/config"
 	"github.com/lemmego/api/middleware"
+
+	"github.com/lemmego/lemmego/framework/auth"
+	"github.com/lemmego/lemmego/framework/i18n"
+	appmiddleware "github.com/lemmego/lemmego/framework/middleware"
+	"github.com/lemmego/lemmego/framework/notifications"
+	"github.com/lemmego/lemmego/framework/validation"
+	"github.com/lemmego/lemmego/framework/webhook"
 )
 
 func Load() app.RouteCallback {
 	// Define your routes here
 	return func(r app.Router) {
-		r.Use(middleware.Recoverer(), middleware.RequestLogger(), middleware.MethodOverride)
-		r.UseBefore(middleware.VerifyCSRF)
+		// Compiled once and reused, instead of wrapping the mux on every request
+		r.Use(appmiddleware.Chain(
+			appmiddleware.TrustProxies(&appmiddleware.TrustedProxiesOptions{
+				Proxies: splitList(config.Get("server.trusted_proxies", "").(string)),
+			}),
+			// After TrustProxies, so the client address is resolved
+			appmiddleware.LogContext,
+			capturing(),
+			middleware.Recoverer(),
+			middleware.RequestLogger(),
+			appmiddleware.RequestTimeout(time.Duration(config.Get("server.request_timeout", 0).(int))*time.Second),
+			appmiddleware.Scratch,
+			chaos(),
+			appmiddleware.SecurityHeaders(securityHeadersOptions()),
+			// Body limits and idempotency must see the raw body before MethodOverride parses the form
+			appmiddleware.BodyLimit(bodyLimitOptions()),
+			appmiddleware.Idempotency(idempotencyOptions()),
+			middleware.MethodOverride,
+		))
+		r.UseBefore(
+			appmiddleware.IPFilter(ipFilterOptions()),
+			appmiddleware.ReadOnly,
+			// Webhooks are signed by their sender instead
+			appmiddleware.Except(middleware.VerifyCSRF, webhook.Prefix),
+			auth.TrackDevices,
+			auth.ShareImpersonation,
+			auth.ShareDevices,
+			notifications.ShareUnread,
+			i18n.Middleware,
+			validation.Localize,
+		)
 
+		staticRoutes(r)
+		metricsRoutes(r)
+		debugRoutes(r)
+		queueRoutes(r)
+		sitemapRoutes(r)
+		adminRoutes(r)
+		impersonationRoutes(r)
+		sessionRoutes(r)
+		pushRoutes(r)
+		notificationRoutes(r)
+		wsRoutes(r)
+		taskRoutes(r)
+		reportRoutes(r)
+		orgRoutes(r)
+		apiKeyRoutes(r)
+		scimRoutes(r)
+		billingRoutes(r)
+		inboundRoutes(r)
 		webRoutes(r)
 		apiRoutes(r)
 		//authRoutes(r)
 	}
 }
+
+func idempotencyOptions() *appmiddleware.IdempotencyOptions {
+	opts := &appmiddleware.IdempotencyOptions{
+		TTL:           time.Duration(config.Get("server.idempotency.ttl", 24).(int)) * time.Hour,
+		SessionCookie: config.Get("session.cookie", "").(string),
+	}
+	if config.Get("server.idempotency.store") == "database" {
+		opts.Store = appmiddleware.NewDatabaseIdempotencyStore()
+	}
+	return opts
+}
+
+func bodyLimitOptions() *appmiddleware.BodyLimitOptions {
+	return &appmiddleware.BodyLimitOptions{
+		MaxBytes:          int64(config.Get("server.body_limit.max_body_size", 0).(int)) << 10,
+		MaxMultipartBytes: int64(config.Get("server.body_limit.max_multipart_size", 0).(int)) << 10,
+	}
+}
+
+func securityHeadersOptions() *appmiddleware.SecurityHeadersOptions {
+	csp := appmiddleware.NewCSP().
+		DefaultSrc(appmiddleware.CSPSelf).
+		ScriptSrc(appmiddleware.CSPSelf, appmiddleware.CSPNonce).
+		StyleSrc(appmiddleware.CSPSelf, appmiddleware.CSPUnsafeInline).
+		ImgSrc(appmiddleware.CSPSelf, appmiddleware.CSPData).
+		ObjectSrc(appmiddleware.CSPNone).
+		BaseURI(appmiddleware.CSPSelf).
+		FrameAncestors(appmiddleware.CSPSelf)
+
+	// Allow the vite dev server and its HMR websocket while developing
+	if config.Get("app.env") == "development" {
+		csp.ScriptSrc("http://localhost:5173").
+			StyleSrc("http://localhost:5173").
+			ConnectSrc(appmiddleware.CSPSelf, "http://localhost:5173", "ws://localhost:5173")
+	}
+
+	return &appmiddleware.SecurityHeadersOptions{
+		HSTSMaxAge:     time.Duration(config.Get("server.security_headers.hsts_max_age", 0).(int)) * time.Second,
+		FrameOptions:   config.Get("server.security_headers.frame_options", "").(string),
+		ReferrerPolicy: config.Get("server.security_headers.referrer_policy", "").(string),
+		CSP:            csp,
+		CSPReportOnly:  config.Get("server.security_headers.csp_report_only", false).(bool),
+	}
+}
+
+// capturing records requests for debugging when server.capture.enabled.
+func capturing() app.HTTPMiddleware {
+	if !config.Get("server.capture.enabled", false).(bool) {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return appmiddleware.Capture(&appmiddleware.CaptureOptions{
+		MaxBody: config.Get("server.capture.max_body_kb", 0).(int) << 10,
+		Except:  splitList(config.Get("server.capture.except", "").(string)),
+	})
+}
+
+// chaos injects faults when server.chaos.enabled, outside of production.
+func chaos() app.HTTPMiddleware {
+	if !config.Get("server.chaos.enabled", false).(bool) || config.Get("app.env") == "production" {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return appmiddleware.Chaos(&appmiddleware.ChaosOptions{
+		Rate:    config.Get("server.chaos.rate", 0.0).(float64),
+		Faults:  splitList(config.Get("server.chaos.faults", "").(string)),
+		Latency: time.Duration(config.Get("server.chaos.latency_ms", 0).(int)) * time.Millisecond,
+		Status:  config.Get("server.chaos.status", 0).(int),
+		Paths:   splitList(config.Get("server.chaos.paths", "").(string)),
+	})
+}
+
+// splitList splits a comma separated config value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ipFilterOptions configure IPFilter from server.ip_filter, allowing and
+// denying clients by address or, with a GeoIP database, by country.
+func ipFilterOptions() *appmiddleware.IPFilterOptions {
+	opts := &appmiddleware.IPFilterOptions{
+		Allow:          splitList(config.Get("server.ip_filter.allow", "").(string)),
+		Deny:           splitList(config.Get("server.ip_filter.deny", "").(string)),
+		AllowCountries: splitList(config.Get("server.ip_filter.allow_countries", "").(string)),
+		DenyCountries:  splitList(config.Get("server.ip_filter.deny_countries", "").(string)),
+	}
+
+	if path := config.Get("server.ip_filter.geoip_database", "").(string); path != "" {
+		resolver, err := appmiddleware.NewMaxMindResolver(path)
+		if err != nil {
+			panic(err)
+		}
+		opts.Countries = resolver
+	}
+
+	return opts
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/lemmego/api/app"
+)
+
+// scimRoutes lets identity providers provision users, kept by the
+// application, and groups, which are organizations, e.g.:
+//
+//	scim.New(&UserStore{}).Mount(r, "/scim/v2")
+//
+// where UserStore implements scim.Users over the users table.
+func scimRoutes(r app.Router) {
+}
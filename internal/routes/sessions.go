@@ -0,0 +1,59 @@
+package routes
+
+import (
+	"errors"
+
+	"github.com/lemmego/api/app"
+
+	"github.com/lemmego/lemmego/framework/auth"
+)
+
+// sessionRoutes lets signed in users see where they are signed in and sign
+// out of other devices. Pages get the same list as the sessions prop.
+func sessionRoutes(r app.Router) {
+	g := r.Group("/account/sessions")
+	g.UseBefore(signedIn)
+
+	g.Get("", func(c *app.Context) error {
+		devices, err := auth.Devices(c.RequestContext(), auth.SessionOwner(c), auth.CurrentDevice(c))
+		if err != nil {
+			return err
+		}
+		return c.JSON(app.M{"sessions": devices})
+	})
+
+	g.Delete("/{id}", func(c *app.Context) error {
+		if c.Param("id") == auth.CurrentDevice(c) {
+			return c.BadRequest(errors.New("sign out to end the current session"))
+		}
+		if err := auth.RevokeDevice(c.RequestContext(), auth.SessionOwner(c), c.Param("id")); err != nil {
+			if errors.Is(err, auth.ErrUnknownDevice) {
+				return c.NotFound(err)
+			}
+			return err
+		}
+		return revoked(c)
+	})
+
+	// Log out other devices
+	g.Post("/revoke-others", func(c *app.Context) error {
+		if _, err := auth.RevokeOtherDevices(c); err != nil {
+			return err
+		}
+		return revoked(c)
+	})
+}
+
+func signedIn(c *app.Context) error {
+	if auth.SessionOwner(c) == "" {
+		return c.Unauthorized(errors.New("sign in to continue"))
+	}
+	return c.Next()
+}
+
+func revoked(c *app.Context) error {
+	if c.WantsJSON() {
+		return c.NoContent()
+	}
+	return c.Back()
+}
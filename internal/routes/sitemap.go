@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"time"
+
+	"github.com/lemmego/api/app"
+
+	"github.com/lemmego/lemmego/framework/sitemap"
+)
+
+// sitemapRoutes serves sitemap.xml and the syndication feeds. Call
+// sitemap.Flush or feed.Flush after publishing to refresh them early.
+func sitemapRoutes(r app.Router) {
+	sitemap.Mount(r, &sitemap.Sitemap{
+		Sources: []sitemap.Source{
+			sitemap.Static(sitemap.URL{Loc: "/", ChangeFreq: sitemap.Daily, Priority: 1}),
+		},
+	}, time.Hour)
+
+	// Serve /feed as RSS and /feed/atom as Atom, e.g.:
+	// feed.Mount(r, "/feed", time.Hour, func(ctx context.Context) (*feed.Feed, error) {
+	// 	posts, err := repo.New[Post](ctx).Order("published_at desc").Limit(20).Find()
+	// 	if err != nil {
+	// 		return nil, err
+	// 	}
+	// 	return &feed.Feed{Title: "Blog", Link: config.Get("app.url", "").(string), Items: feed.FromSlice(posts, postItem)}, nil
+	// })
+}
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"os"
+	"time"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/lemmego/framework/assets"
+	"github.com/lemmego/lemmego/framework/static"
+	"github.com/lemmego/lemmego/framework/storage"
+)
+
+func staticRoutes(r app.Router) {
+	// Vite fingerprints built assets, so they can be cached forever
+	static.Mount(r, "/public/build", assets.Dir("public/build"), &static.Options{
+		MaxAge:        365 * 24 * time.Hour,
+		Immutable:     true,
+		Precompressed: true,
+	})
+
+	// Local disks with a public url, such as the public disk
+	for prefix, dir := range storage.Served() {
+		static.Mount(r, prefix, os.DirFS(dir), &static.Options{MaxAge: time.Hour})
+	}
+
+	// Serve a client side rendered app with deep link support, e.g.:
+	// static.Mount(r, "/app", os.DirFS("public/app"), &static.Options{SPA: true})
+}
@@ -0,0 +1,106 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/lemmego/api/app"
+
+	"github.com/lemmego/lemmego/framework/auth"
+	"github.com/lemmego/lemmego/framework/jsonx"
+	"github.com/lemmego/lemmego/framework/queue"
+)
+
+// taskPoll is how often the events stream looks for new progress, and
+// taskHeartbeat how long it may stay silent before proxies drop it.
+const (
+	taskPoll      = 500 * time.Millisecond
+	taskHeartbeat = 15 * time.Second
+)
+
+var errUnknownTask = errors.New("unknown task")
+
+// taskRoutes show the progress of tracked jobs, see queue.Report, either
+// polled or as server-sent events:
+//
+//	const events = new EventSource(`/tasks/${id}/events`)
+//	events.addEventListener("progress", e => bar.value = JSON.parse(e.data).percent)
+func taskRoutes(r app.Router) {
+	g := r.Group("/tasks")
+	g.UseBefore(signedIn)
+
+	g.Get("/{id}", func(c *app.Context) error {
+		p, ok := task(c)
+		if !ok {
+			return c.NotFound(errUnknownTask)
+		}
+		return jsonx.OK(c, p)
+	})
+
+	// Sends a progress event on every change, until the job is finished
+	g.Get("/{id}/events", func(c *app.Context) error {
+		p, ok := task(c)
+		if !ok {
+			return c.NotFound(errUnknownTask)
+		}
+
+		w := c.ResponseWriter()
+		rc := http.NewResponseController(w)
+		// The stream outlives the write timeout of the server
+		_ = rc.SetWriteDeadline(time.Time{})
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		ticker := time.NewTicker(taskPoll)
+		defer ticker.Stop()
+		last, silent := time.Time{}, time.Now()
+		for {
+			if p.UpdatedAt.After(last) {
+				data, err := json.Marshal(p)
+				if err != nil {
+					return err
+				}
+				if _, err := fmt.Fprintf(w, "event: progress\ndata: %s\n\n", data); err != nil {
+					return nil
+				}
+				last, silent = p.UpdatedAt, time.Now()
+			} else if time.Since(silent) >= taskHeartbeat {
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return nil
+				}
+				silent = time.Now()
+			}
+			if err := rc.Flush(); err != nil {
+				return nil
+			}
+			if p.Finished() {
+				return nil
+			}
+
+			select {
+			case <-c.RequestContext().Done():
+				return nil
+			case <-ticker.C:
+			}
+			// Progress expired under a job that never finished
+			if p, ok = task(c); !ok {
+				return nil
+			}
+		}
+	})
+}
+
+// task returns the progress of the job of the id parameter, unless it was
+// dispatched for another user.
+func task(c *app.Context) (*queue.Progress, bool) {
+	p, ok := queue.ProgressOf(c.Param("id"))
+	if !ok || (p.Owner != "" && p.Owner != c.GetSessionString(auth.UserKey)) {
+		return nil, false
+	}
+	return p, true
+}
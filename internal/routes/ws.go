@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"strings"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/config"
+
+	"github.com/lemmego/lemmego/framework/auth"
+	"github.com/lemmego/lemmego/framework/ws"
+)
+
+// wsRoutes connects the pages of signed in users to the WebSocket layer,
+// see ws.Send.
+func wsRoutes(r app.Router) {
+	r.Get("/ws", func(c *app.Context) error {
+		// Behind a proxy the host of the request may not be the public one
+		ws.Serve(c.ResponseWriter(), c.Request(), c.GetSessionString(auth.UserKey), strings.TrimSuffix(config.Get("app.url", "").(string), "/"))
+		return nil
+	}).UseBefore(signedIn)
+}
@@ -0,0 +1,320 @@
+// Package scaffold creates new applications from this one, as
+// create-lemmego does: the application code is copied under a module path
+// of their own, requiring the framework module rather than copying it, with
+// the front end of a preset and without the files of a checkout such as the
+// database or the .env.
+//
+//	err := scaffold.New(&scaffold.Options{
+//		Dir:    "shop",
+//		Module: "github.com/acme/shop",
+//		Preset: scaffold.React,
+//		Source: ".",
+//	})
+package scaffold
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Module is the module path of this application, rewritten in the new ones.
+const Module = "github.com/lemmego/lemmego"
+
+// Framework is the module path of the framework, required by the new
+// applications instead of being copied.
+const Framework = Module + "/framework"
+
+// Presets pick the front end of new applications.
+const (
+	// React renders Inertia pages with React.
+	React = "react"
+	// Vue renders Inertia pages with Vue.
+	Vue = "vue"
+	// HTMX renders Go templates enhanced by htmx, without a bundler.
+	HTMX = "htmx"
+)
+
+var (
+	ErrUnknownPreset = errors.New("scaffold: unknown preset")
+	ErrExists        = errors.New("scaffold: directory exists and is not empty")
+)
+
+//go:embed presets
+var presets embed.FS
+
+// removed are the files of each preset's source left out, those of the
+// other front ends.
+var removed = map[string][]string{
+	React: {"resources/js/app.js", "resources/js/ssr.js", "resources/js/Pages/IndexVue.vue"},
+	Vue:   {"resources/js/app.tsx", "resources/js/ssr.tsx", "resources/js/Pages/IndexReact.tsx", "tsconfig.json"},
+	HTMX:  {"resources/js", "vite.config.js", "tsconfig.json"},
+}
+
+// skipped are the files of a checkout that are not part of applications:
+// its state, secrets, dependencies and build output, and the scaffolding.
+var skipped = []string{
+	".git", ".env", ".idea", ".vscode", "node_modules", "vendor", "tmp", "public/build", "bootstrap/ssr",
+	"storage", "cmd/create-lemmego", "internal/scaffold", "framework",
+}
+
+// kept are the directories applications start with empty, for the
+// generators to fill.
+var kept = []string{"storage", "public", "bootstrap", "internal/handlers", "internal/inputs", "internal/plugins"}
+
+// Options configure New.
+type Options struct {
+	// Dir is created for the application, its base the name of the app.
+	Dir string
+	// Module is the module path of the application, the base of Dir when
+	// empty.
+	Module string
+	// Preset is React, Vue or HTMX, React when empty.
+	Preset string
+	// Source is the directory of the lemmego module copied, see Download.
+	Source string
+	// Version is that of the framework required, e.g. v0.2.0, left to
+	// go mod tidy, the latest, when empty.
+	Version string
+}
+
+var modulePath = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9._~/-]*[a-zA-Z0-9_~-])?$`)
+
+// New creates an application in opts.Dir.
+func New(opts *Options) error {
+	preset := opts.Preset
+	if preset == "" {
+		preset = React
+	}
+	if _, ok := removed[preset]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownPreset, preset)
+	}
+	name := filepath.Base(filepath.Clean(opts.Dir))
+	module := opts.Module
+	if module == "" {
+		module = name
+	}
+	if !modulePath.MatchString(module) || strings.Contains(module, "//") {
+		return fmt.Errorf("scaffold: invalid module path %q", module)
+	}
+	if entries, err := os.ReadDir(opts.Dir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("%w: %s", ErrExists, opts.Dir)
+	}
+
+	src := os.DirFS(opts.Source)
+	if _, err := fs.Stat(src, "go.mod"); err != nil {
+		return fmt.Errorf("scaffold: %s is not the lemmego module: %w", opts.Source, err)
+	}
+	err := fs.WalkDir(src, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == "." {
+			return os.MkdirAll(opts.Dir, 0o755)
+		}
+		if matches(p, skipped) || matches(p, removed[preset]) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		dst := filepath.Join(opts.Dir, filepath.FromSlash(p))
+		if d.IsDir() {
+			return os.MkdirAll(dst, 0o755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		data, err := fs.ReadFile(src, p)
+		if err != nil {
+			return err
+		}
+		if p == "go.mod" {
+			data = requireFramework(data, opts.Version)
+		}
+		return write(dst, rewrite(p, data, module))
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := overlay(opts.Dir, preset, module); err != nil {
+		return err
+	}
+	for _, dir := range kept {
+		if err := write(filepath.Join(opts.Dir, filepath.FromSlash(dir), ".gitkeep"), nil); err != nil {
+			return err
+		}
+	}
+	if err := ignore(opts.Dir); err != nil {
+		return err
+	}
+	return env(opts.Dir, name)
+}
+
+// matches reports whether p is one of paths or within one of them.
+func matches(p string, paths []string) bool {
+	for _, m := range paths {
+		if p == m || strings.HasPrefix(p, m+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+var importPath = regexp.MustCompile(`"` + regexp.QuoteMeta(Module) + `(/[^"]*)?"`)
+
+// rewrite moves the imports of Go and templ files, and the module
+// directive, to module. Those of the framework stay.
+func rewrite(p string, data []byte, module string) []byte {
+	switch {
+	case p == "go.mod":
+		return bytes.Replace(data, []byte("module "+Module+"\n"), []byte("module "+module+"\n"), 1)
+	case strings.HasSuffix(p, ".go"), strings.HasSuffix(p, ".templ"):
+		return importPath.ReplaceAllFunc(data, func(m []byte) []byte {
+			sub := importPath.FindSubmatch(m)[1]
+			if bytes.Equal(sub, []byte("/framework")) || bytes.HasPrefix(sub, []byte("/framework/")) {
+				return m
+			}
+			return []byte(`"` + module + string(sub) + `"`)
+		})
+	}
+	return data
+}
+
+// requireFramework makes go.mod require version of the framework, in place
+// of the copy the checkout replaces it with.
+func requireFramework(data []byte, version string) []byte {
+	var out []string
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) > 0 && fields[0] == "replace" && strings.Contains(line, Framework+" "):
+			// With the comment above it
+			if n := len(out); n > 0 && strings.HasPrefix(out[n-1], "//") {
+				out = out[:n-1]
+			}
+			continue
+		case len(fields) == 2 && fields[0] == Framework:
+			if version == "" {
+				continue
+			}
+			line = strings.Replace(line, fields[1], version, 1)
+		}
+		out = append(out, line)
+	}
+	return []byte(strings.Join(out, "\n"))
+}
+
+// overlay writes the files of the preset over those copied.
+func overlay(dir, preset, module string) error {
+	root := path.Join("presets", preset)
+	return fs.WalkDir(presets, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := presets.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel := strings.TrimSuffix(strings.TrimPrefix(p, root+"/"), ".tmpl")
+		return write(filepath.Join(dir, filepath.FromSlash(rel)), rewrite(rel, data, module))
+	})
+}
+
+// ignored are kept out of the repositories of applications.
+var ignored = []string{".env", "/node_modules", "/tmp", "/public/build", "/bin", "/storage/*", "!/storage/.gitkeep"}
+
+// ignore adds the entries of ignored missing from the .gitignore.
+func ignore(dir string) error {
+	p := filepath.Join(dir, ".gitignore")
+	data, err := os.ReadFile(p)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	have := map[string]bool{}
+	for _, line := range strings.Split(string(data), "\n") {
+		have[strings.TrimSpace(line)] = true
+	}
+	if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	for _, entry := range ignored {
+		if !have[entry] {
+			data = append(data, entry+"\n"...)
+		}
+	}
+	return write(p, data)
+}
+
+// env writes the .env of the application from .env.example, with its name
+// and a fresh application key.
+func env(dir, name string) error {
+	example, err := os.ReadFile(filepath.Join(dir, ".env.example"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	sc := bufio.NewScanner(bytes.NewReader(example))
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "APP_NAME="):
+			line = "APP_NAME=" + name
+		case strings.HasPrefix(line, "APP_KEY="):
+			line = "APP_KEY=base64:" + base64.StdEncoding.EncodeToString(key)
+		}
+		out.WriteString(line + "\n")
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, ".env"), out.Bytes(), 0o600)
+}
+
+// write writes a file, the files of the module cache being read-only.
+func write(dst string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0o644)
+}
+
+// Download fetches version of the lemmego module, e.g. latest or v0.2.0,
+// into the module cache and returns its directory.
+func Download(version string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("go", "mod", "download", "-json", Module+"@"+version)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	var info struct {
+		Dir   string
+		Error string
+	}
+	if jsonErr := json.Unmarshal(out, &info); jsonErr == nil && info.Error != "" {
+		return "", fmt.Errorf("scaffold: downloading %s@%s: %s", Module, version, info.Error)
+	}
+	if err != nil {
+		return "", fmt.Errorf("scaffold: downloading %s@%s: %w: %s", Module, version, err, strings.TrimSpace(stderr.String()))
+	}
+	return info.Dir, nil
+}
@@ -2,6 +2,7 @@
 module.exports = {
   content: [
     "./templates/**/*.{gohtml,templ,html,js}",
+    "./internal/**/*.templ",
     "./resources/views/**/*.html",
     "./resources/js/**/*.vue",
     "./resources/js/**/*.jsx",